@@ -39,6 +39,15 @@ var DefaultBaseDerivationPath = DerivationPath{0x80000000 + 44, 0x80000000 + 60,
 // at m/44'/60'/0'/1, etc.
 var DefaultLedgerBaseDerivationPath = DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000000 + 0, 0}
 
+// BerithCoinType is the hardened BIP-44 coin type under which Berith accounts are
+// derived apart from the Ethereum ones of the same seed. It is not registered in
+// SLIP-44.
+const BerithCoinType = 0x80000000 + 1234
+
+// BerithRootDerivationPath is the root path of the Berith accounts, the first one
+// being at m/44'/1234'/0'/0/0, the second at m/44'/1234'/0'/0/1, etc.
+var BerithRootDerivationPath = DerivationPath{0x80000000 + 44, BerithCoinType, 0x80000000 + 0, 0}
+
 // DerivationPath represents the computer friendly version of a hierarchical
 // deterministic wallet account derivaion path.
 //
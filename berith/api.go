@@ -35,6 +35,7 @@ import (
 	"github.com/BerithFoundation/berith-chain/core/state"
 	"github.com/BerithFoundation/berith-chain/core/types"
 	"berith-chain/internals/berithapi"
+	"github.com/BerithFoundation/berith-chain/miner"
 	"github.com/BerithFoundation/berith-chain/params"
 	"github.com/BerithFoundation/berith-chain/rlp"
 	"github.com/BerithFoundation/berith-chain/rpc"
@@ -76,6 +77,16 @@ func (api *PublicBerithAPI) ChainId() hexutil.Uint64 {
 	return (hexutil.Uint64)(chainID.Uint64())
 }
 
+// FinalizedBlock returns the newest block that can no longer be reorganised
+// under the finality horizon of the consensus engine.
+func (api *PublicBerithAPI) FinalizedBlock() (map[string]interface{}, error) {
+	block := api.e.blockchain.FinalizedBlock()
+	if block == nil {
+		return nil, errors.New("finality horizon disabled")
+	}
+	return berithapi.RPCMarshalBlock(block, true, false)
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only methods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
@@ -150,11 +161,82 @@ func (api *PrivateMinerAPI) SetRecommitInterval(interval int) {
 	api.e.Miner().SetRecommitInterval(time.Duration(interval) * time.Millisecond)
 }
 
+// SetTargetBlockInterval sets the interval in milliseconds the blocks are sealed
+// at in the soak mode, or restores the timing of the chain if zero.
+func (api *PrivateMinerAPI) SetTargetBlockInterval(interval int) (bool, error) {
+	if err := api.e.Miner().SetTargetBlockInterval(time.Duration(interval) * time.Millisecond); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetTxOrdering switches the strategy used to order pending transactions in
+// mined blocks ("price", "fifo" or "roundrobin").
+func (api *PrivateMinerAPI) SetTxOrdering(ordering string) (bool, error) {
+	txOrdering, err := miner.ParseTxOrdering(ordering)
+	if err != nil {
+		return false, err
+	}
+	api.e.Miner().SetTxOrdering(txOrdering)
+	return true, nil
+}
+
+// GetTxOrdering returns the strategy used to order pending transactions in
+// mined blocks.
+func (api *PrivateMinerAPI) GetTxOrdering() string {
+	return string(api.e.Miner().TxOrdering())
+}
+
+// Stats returns the cumulative statistics of the blocks sealed by the local
+// miner since startup.
+func (api *PrivateMinerAPI) Stats() map[string]interface{} {
+	stats := api.e.Miner().Stats()
+	return map[string]interface{}{
+		"blocks":         hexutil.Uint64(stats.Blocks),
+		"txs":            hexutil.Uint64(stats.Txs),
+		"fees":           (*hexutil.Big)(stats.Fees),
+		"avgTxsPerBlock": stats.AvgTxs(),
+	}
+}
+
+// RecentlyDropped returns the transactions recently left out of the mined blocks
+// with the reason, oldest first. Recording them is enabled by --miner.droppedtxs.
+func (api *PrivateMinerAPI) RecentlyDropped() []miner.DroppedTx {
+	return api.e.Miner().RecentlyDropped()
+}
+
 // GetHashrate returns the current hashrate of the miner.
 func (api *PrivateMinerAPI) GetHashrate() uint64 {
 	return api.e.miner.HashRate()
 }
 
+// PrivateStakeIntentAPI provides private RPC methods to follow up the stake
+// transactions submitted through the node.
+type PrivateStakeIntentAPI struct {
+	e *Berith
+}
+
+// NewPrivateStakeIntentAPI creates a new RPC service which follows up the stake
+// transactions submitted through the node.
+func NewPrivateStakeIntentAPI(e *Berith) *PrivateStakeIntentAPI {
+	return &PrivateStakeIntentAPI{e: e}
+}
+
+// StakeIntents returns the stake transactions submitted through the node which
+// aren't mined yet, either still pending or lost by the transaction pool.
+func (api *PrivateStakeIntentAPI) StakeIntents() []*StakeIntent {
+	return api.e.intents.outstanding()
+}
+
+// RebroadcastIntent resubmits the stake transaction of the given intent to the
+// transaction pool, announcing it to the peers again.
+func (api *PrivateStakeIntentAPI) RebroadcastIntent(id common.Hash) (common.Hash, error) {
+	if err := api.e.intents.rebroadcast(id); err != nil {
+		return common.Hash{}, err
+	}
+	return id, nil
+}
+
 // PrivateAdminAPI is the collection of Berith full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -336,6 +418,17 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs,
 	return results, nil
 }
 
+// BlockTimings returns the time spent on the stages of the last n blocks sealed
+// or imported by the node, oldest first. All retained timings are returned if
+// n is not given.
+func (api *PrivateDebugAPI) BlockTimings(n *int) []*core.BlockTiming {
+	last := -1
+	if n != nil {
+		last = *n
+	}
+	return api.e.BlockChain().BlockTimings(last)
+}
+
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
 type StorageRangeResult struct {
 	Storage storageMap   `json:"storage"`
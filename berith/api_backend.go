@@ -154,7 +154,17 @@ func (b *BerAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 }
 
 func (b *BerAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
-	return b.e.txPool.AddLocal(signedTx)
+	if err := b.e.txPool.AddLocal(signedTx); err != nil {
+		return err
+	}
+	// Journal the stake transactions to follow them up until they are mined
+	if isStakeTransaction(signedTx) {
+		signer := types.MakeSigner(b.e.chainConfig, b.e.blockchain.CurrentBlock().Number())
+		if from, err := types.Sender(signer, signedTx); err == nil {
+			b.e.intents.record(signedTx, from)
+		}
+	}
+	return nil
 }
 
 func (b *BerAPIBackend) GetPoolTransactions() (types.Transactions, error) {
@@ -50,6 +50,7 @@ import (
 	"github.com/BerithFoundation/berith-chain/miner"
 	"github.com/BerithFoundation/berith-chain/node"
 	"github.com/BerithFoundation/berith-chain/p2p"
+	"github.com/BerithFoundation/berith-chain/p2p/discover"
 	"github.com/BerithFoundation/berith-chain/params"
 	"github.com/BerithFoundation/berith-chain/rlp"
 	"github.com/BerithFoundation/berith-chain/rpc"
@@ -101,6 +102,11 @@ type Berith struct {
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and berithbase)
 
 	stakingDB *staking.StakingDB // [Berith] database for staker infos
+
+	intents *intentJournal // [Berith] stake transactions submitted through the node
+
+	voteHistorySub  event.Subscription // [Berith] canonical heads the vote history is recorded on
+	voteHistoryDone chan struct{}      // [Berith] closed once the vote history stopped recording
 }
 
 // New creates a new Berith object (including the
@@ -117,6 +123,10 @@ func New(ctx *node.ServiceContext, config *Config) (*Berith, error) {
 		log.Warn("Sanitizing invalid miner gas price", "provided", config.MinerGasPrice, "updated", DefaultConfig.MinerGasPrice)
 		config.MinerGasPrice = new(big.Int).Set(DefaultConfig.MinerGasPrice)
 	}
+	minerConfig, err := config.MinerConfig()
+	if err != nil {
+		return nil, err
+	}
 	// Assemble the Berith object
 	chainDb, err := CreateDB(ctx, config, "chaindata")
 	if err != nil {
@@ -128,12 +138,17 @@ func New(ctx *node.ServiceContext, config *Config) (*Berith, error) {
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
-	stakingDB := &staking.StakingDB{NoPruning: config.NoPruning}
+	// [BERITH] The soak mode seals without the delays the main network relies on
+	if config.MinerSoak && chainConfig.ChainID != nil && chainConfig.ChainID.Cmp(params.MainnetChainConfig.ChainID) == 0 {
+		return nil, errors.New("can't run the miner soak mode on the main network")
+	}
+
+	stakingDB := &staking.StakingDB{NoPruning: config.NoPruning, CommitMode: config.StakingCommit, ReadOnly: config.StakingReadOnly}
 	stakingDBPath := ctx.ResolvePath("stakingDB")
 	if stkErr := stakingDB.CreateDB(stakingDBPath, staking.NewStakers); stkErr != nil {
 		return nil, stkErr
 	}
-	engine := CreateConsensusEngine(chainConfig, chainDb, stakingDB)
+	engine := CreateConsensusEngine(chainConfig, config, chainDb, stakingDB)
 	ber := &Berith{
 		config:         config,
 		chainDb:        chainDb,
@@ -183,13 +198,13 @@ func New(ctx *node.ServiceContext, config *Config) (*Berith, error) {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
 	ber.txPool = core.NewTxPool(config.TxPool, ber.chainConfig, ber.blockchain)
+	ber.intents = newIntentJournal(chainDb, ber.txPool, config.StakeIntentHorizon)
 
 	if ber.protocolManager, err = NewProtocolManager(ber.chainConfig, config.SyncMode, config.NetworkId, ber.eventMux, ber.txPool, ber.engine, ber.blockchain, chainDb, config.Whitelist); err != nil {
 		return nil, err
 	}
 
-	ber.miner = miner.New(ber, ber.chainConfig, ber.EventMux(), ber.engine, config.MinerRecommit, config.MinerGasFloor, config.MinerGasCeil, ber.isLocalBlock)
-	ber.miner.SetExtra(makeExtraData(config.MinerExtraData))
+	ber.miner = miner.New(ber, minerConfig, ber.chainConfig, ber.EventMux(), ber.engine, ber.isLocalBlock)
 
 	ber.APIBackend = &BerAPIBackend{ber, nil}
 	gpoParams := config.GPO
@@ -231,8 +246,15 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (berithdb.D
 }
 
 // CreateConsensusEngine creates the required type of consensus engine instance for an Berith service
-func CreateConsensusEngine(chainConfig *params.ChainConfig, db berithdb.Database, stakingDB *staking.StakingDB) consensus.Engine {
-	return bsrr.NewCliqueWithStakingDB(stakingDB, chainConfig.Bsrr, db)
+func CreateConsensusEngine(chainConfig *params.ChainConfig, config *Config, db berithdb.Database, stakingDB *staking.StakingDB) consensus.Engine {
+	engine := bsrr.NewCliqueWithStakingDB(stakingDB, chainConfig.Bsrr, db)
+	if config.VoteHistory {
+		engine.EnableVoteHistory(config.VoteHistoryRetention)
+	}
+	if config.MinerSoak {
+		engine.EnableSoloSealing()
+	}
+	return engine
 }
 
 func (s *Berith) AddLesServer(ls LesServer) {
@@ -248,11 +270,21 @@ Registration of each service implementation
 services : miner, admin, berith, etc...
 */
 func (s *Berith) APIs() []rpc.API {
-	apis := berithapi.GetAPIs(s.APIBackend)
+	apis := berithapi.GetAPIs(s.APIBackend, s.config.PasswordGuard)
 	apis = append(apis, brtapi.GetAPIs(s.APIBackend, s.miner)...)
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Serve the eth namespace for Ethereum tooling if requested
+	filterAPI := filters.NewPublicFilterAPI(s.APIBackend, false, s.config.LogLimits)
+	if s.config.EthCompat {
+		apis = append(apis, rpc.API{
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewPublicEthCompatAPI(s, filterAPI),
+			Public:    true,
+		})
+	}
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -273,8 +305,18 @@ func (s *Berith) APIs() []rpc.API {
 		}, {
 			Namespace: "berith",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.APIBackend, false),
+			Service:   filterAPI,
+			Public:    true,
+		}, {
+			Namespace: "berith",
+			Version:   "1.0",
+			Service:   gasprice.NewPublicOracleAPI(s.APIBackend.gpo),
 			Public:    true,
+		}, {
+			Namespace: "berith",
+			Version:   "1.0",
+			Service:   NewPrivateStakeIntentAPI(s),
+			Public:    false,
 		}, {
 			Namespace: "miner",
 			Version:   "1.0",
@@ -480,12 +522,50 @@ func (s *Berith) Start(srvr *p2p.Server) error {
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+	// Follow up the stake transactions submitted through the node
+	s.intents.start()
+
+	// Warn about a skewed clock, which keeps the blocks from being timed right
+	if engine, ok := s.engine.(*bsrr.BSRR); ok {
+		go engine.CheckClockSkew(discover.ClockDrift)
+
+		// Record the election results of the epochs as the canonical chain moves
+		if s.config.VoteHistory {
+			s.startVoteHistory(engine)
+		}
+	}
 	return nil
 }
 
+// startVoteHistory records the election results of the canonical epoch boundary
+// blocks in the vote history of the engine whenever the chain head changes.
+func (s *Berith) startVoteHistory(engine *bsrr.BSRR) {
+	heads := make(chan core.ChainHeadEvent, 10)
+	s.voteHistorySub = s.blockchain.SubscribeChainHeadEvent(heads)
+	s.voteHistoryDone = make(chan struct{})
+
+	go func() {
+		defer close(s.voteHistoryDone)
+
+		engine.RecordVotes(s.blockchain, s.blockchain.CurrentHeader())
+		for {
+			select {
+			case ev := <-heads:
+				engine.RecordVotes(s.blockchain, ev.Block.Header())
+			case <-s.voteHistorySub.Err():
+				return
+			}
+		}
+	}()
+}
+
 // Stop implements node.Service, terminating all internals goroutines used by the
 // Berith protocol.
 func (s *Berith) Stop() error {
+	if s.voteHistorySub != nil {
+		s.voteHistorySub.Unsubscribe()
+		<-s.voteHistoryDone
+	}
 	s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	s.engine.Close()
@@ -493,6 +573,7 @@ func (s *Berith) Stop() error {
 	if s.lesServer != nil {
 		s.lesServer.Stop()
 	}
+	s.intents.stop()
 	s.txPool.Stop()
 	s.miner.Stop()
 	s.eventMux.Stop()
@@ -22,11 +22,16 @@ import (
 	"os/user"
 	"time"
 
+	"berith-chain/internals/berithapi"
+
 	"github.com/BerithFoundation/berith-chain/berith/downloader"
+	"github.com/BerithFoundation/berith-chain/berith/filters"
 	"github.com/BerithFoundation/berith-chain/berith/gasprice"
+	"github.com/BerithFoundation/berith-chain/berith/staking"
 	"github.com/BerithFoundation/berith-chain/common"
 	"github.com/BerithFoundation/berith-chain/common/hexutil"
 	"github.com/BerithFoundation/berith-chain/core"
+	"github.com/BerithFoundation/berith-chain/miner"
 	"github.com/BerithFoundation/berith-chain/params"
 )
 
@@ -38,16 +43,27 @@ var DefaultConfig = Config{
 	TrieCleanCache: 256,
 	TrieDirtyCache: 256,
 	TrieTimeout:    60 * time.Minute,
-	MinerGasFloor:  8000000,
-	MinerGasCeil:   8000000,
+	MinerGasFloor:  miner.DefaultConfig.GasFloor,
+	MinerGasCeil:   miner.DefaultConfig.GasCeil,
 	MinerGasPrice:  big.NewInt(params.Gmin),
-	MinerRecommit:  3 * time.Second,
+	MinerRecommit:  miner.DefaultConfig.Recommit,
+
+	StakeIntentHorizon: 24 * time.Hour,
+
+	LightMaxServers:      5,
+	LightRetrieveTimeout: 30 * time.Second,
+
+	MinerTxOrdering:      string(miner.DefaultConfig.Ordering),
+	MinerMaxUncles:       miner.DefaultConfig.MaxUncles,
+	MinerGasLimitDivisor: miner.DefaultConfig.GasLimitDivisor,
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
 		Percentile: 60,
+		MaxPrice:   gasprice.DefaultMaxPrice,
 	},
+	PasswordGuard: berithapi.DefaultPasswordGuardConfig,
 }
 
 func init() {
@@ -71,12 +87,34 @@ type Config struct {
 	SyncMode  downloader.SyncMode
 	NoPruning bool
 
+	// How staking lists are written to the staking database
+	StakingCommit staking.CommitMode
+
+	// Keep staking lists in memory only if the staking database can't be written,
+	// for archive and verifier nodes with read-only storage
+	StakingReadOnly bool
+
+	// Store the election results of every epoch for bsrr.getHistoricalVoteResults,
+	// keeping those of the given number of most recent epochs (0 = all)
+	VoteHistory          bool
+	VoteHistoryRetention uint64
+
+	// Time the stake transactions submitted through the node are followed up
+	// after being mined
+	StakeIntentHorizon time.Duration
+
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
 	// Light client options
-	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
-	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
+	LightServ       int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
+	LightPeers      int `toml:",omitempty"` // Maximum number of LES client peers
+	LightMinServers int `toml:",omitempty"` // Minimum number of LES servers connected before on-demand retrievals are made
+	LightMaxServers int `toml:",omitempty"` // Maximum number of LES servers connected at once, the others discovered kept as backups
+
+	// Time an on-demand retrieval of the light client may take if the caller
+	// didn't set a deadline, zero for no limit
+	LightRetrieveTimeout time.Duration `toml:",omitempty"`
 
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
@@ -96,6 +134,24 @@ type Config struct {
 	MinerRecommit  time.Duration
 	MinerNoverify  bool
 
+	// Transaction ordering used to fill mined blocks ("price", "fifo" or "roundrobin")
+	MinerTxOrdering string `toml:",omitempty"`
+
+	// Skip sealing an empty block before the pending transactions are executed
+	MinerNoEmptyPrecommit bool `toml:",omitempty"`
+
+	// Maximum number of side blocks kept as possible uncles, per local and remote set
+	MinerMaxUncles int `toml:",omitempty"`
+
+	// Number of transactions left out of the mined blocks recorded with the reason, zero to disable
+	MinerDroppedTxs int `toml:",omitempty"`
+
+	// Divisor of the parent gas limit bounding the gas limit drift per block, at least the protocol's bound divisor
+	MinerGasLimitDivisor uint64 `toml:",omitempty"`
+
+	// Seal the blocks of a single signer development chain without delays, refused on the main network
+	MinerSoak bool `toml:",omitempty"`
+
 	// Transaction pool options
 	TxPool core.TxPoolConfig
 
@@ -105,6 +161,15 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Serve the eth namespace, aliasing the berith methods of the same semantics
+	EthCompat bool `toml:",omitempty"`
+
+	// Limits of a single log query served over RPC
+	LogLimits filters.LogLimits
+
+	// Limits of the password attempts of the personal methods
+	PasswordGuard berithapi.PasswordGuardConfig
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 
@@ -121,3 +186,23 @@ type Config struct {
 type configMarshaling struct {
 	MinerExtraData hexutil.Bytes
 }
+
+// MinerConfig assembles the configuration of the miner from the mining-related
+// options.
+func (c *Config) MinerConfig() (miner.Config, error) {
+	ordering, err := miner.ParseTxOrdering(c.MinerTxOrdering)
+	if err != nil {
+		return miner.Config{}, err
+	}
+	return miner.Config{
+		Recommit:         c.MinerRecommit,
+		GasFloor:         c.MinerGasFloor,
+		GasCeil:          c.MinerGasCeil,
+		ExtraData:        makeExtraData(c.MinerExtraData),
+		Ordering:         ordering,
+		NoEmptyPrecommit: c.MinerNoEmptyPrecommit,
+		MaxUncles:        c.MinerMaxUncles,
+		DroppedTxs:       c.MinerDroppedTxs,
+		GasLimitDivisor:  c.MinerGasLimitDivisor,
+	}, nil
+}
@@ -0,0 +1,183 @@
+package berith
+
+import (
+	"context"
+
+	"berith-chain/internals/berithapi"
+	"github.com/BerithFoundation/berith-chain/berith/filters"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+// PublicEthCompatAPI serves the eth namespace for wallets and libraries which
+// hard-code the Ethereum method names. It is only registered if enabled in the
+// configuration, and only offers the methods whose semantics match those of
+// the berith namespace, delegating to the very same implementations:
+//
+//	eth_chainId, eth_protocolVersion, eth_syncing, eth_gasPrice, eth_accounts,
+//	eth_coinbase, eth_blockNumber, eth_getBalance, eth_getCode, eth_getStorageAt,
+//	eth_getTransactionCount, eth_getBlockByNumber, eth_getBlockByHash,
+//	eth_getBlockTransactionCountByNumber, eth_getBlockTransactionCountByHash,
+//	eth_getTransactionByHash, eth_getTransactionByBlockNumberAndIndex,
+//	eth_getTransactionByBlockHashAndIndex, eth_getTransactionReceipt,
+//	eth_sendRawTransaction, eth_call, eth_estimateGas, eth_getLogs
+//	  -> berith_* of the same name
+//
+// The mining methods answer for the BSRR engine: eth_mining reports whether
+// the node is sealing blocks and eth_hashrate is always zero, as there is no
+// proof-of-work. The methods taking transaction arguments to be signed by the
+// node (eth_sendTransaction, eth_signTransaction) are omitted, as Berith
+// transactions carry the balance types to transfer between, and so are the
+// proof-of-work methods (eth_getWork, eth_submitWork, eth_submitHashrate).
+// Addresses are returned in the Berith format as by the berith namespace.
+type PublicEthCompatAPI struct {
+	berith     *PublicBerithAPI
+	miner      *PublicMinerAPI
+	node       *berithapi.PublicBerithAPI
+	accounts   *berithapi.PublicAccountAPI
+	blockchain *berithapi.PublicBlockChainAPI
+	txpool     *berithapi.PublicTransactionPoolAPI
+	filters    *filters.PublicFilterAPI
+}
+
+// NewPublicEthCompatAPI creates the eth namespace delegating to the berith
+// namespace, sharing the filter API of the node.
+func NewPublicEthCompatAPI(e *Berith, filterAPI *filters.PublicFilterAPI) *PublicEthCompatAPI {
+	return &PublicEthCompatAPI{
+		berith:     NewPublicBerithAPI(e),
+		miner:      NewPublicMinerAPI(e),
+		node:       berithapi.NewPublicBerithAPI(e.APIBackend),
+		accounts:   berithapi.NewPublicAccountAPI(e.AccountManager()),
+		blockchain: berithapi.NewPublicBlockChainAPI(e.APIBackend),
+		txpool:     berithapi.NewPublicTransactionPoolAPI(e.APIBackend, new(berithapi.AddrLocker)),
+		filters:    filterAPI,
+	}
+}
+
+// ChainId is the EIP-155 replay-protection chain id for the current chain config.
+func (api *PublicEthCompatAPI) ChainId() hexutil.Uint64 {
+	return api.berith.ChainId()
+}
+
+// ProtocolVersion returns the current Berith protocol version.
+func (api *PublicEthCompatAPI) ProtocolVersion() hexutil.Uint {
+	return api.node.ProtocolVersion()
+}
+
+// Syncing returns false if the node is not syncing, otherwise the sync progress.
+func (api *PublicEthCompatAPI) Syncing() (interface{}, error) {
+	return api.node.Syncing()
+}
+
+// GasPrice returns a suggestion for a gas price.
+func (api *PublicEthCompatAPI) GasPrice(ctx context.Context) (*hexutil.Big, error) {
+	return api.node.GasPrice(ctx)
+}
+
+// Accounts returns the addresses of the accounts of the node.
+func (api *PublicEthCompatAPI) Accounts() []common.Address {
+	return api.accounts.Accounts()
+}
+
+// Coinbase is the address that sealing rewards will be send to.
+func (api *PublicEthCompatAPI) Coinbase() (common.Address, error) {
+	return api.berith.Coinbase()
+}
+
+// Mining returns whether the node is sealing blocks.
+func (api *PublicEthCompatAPI) Mining() bool {
+	return api.miner.Mining()
+}
+
+// Hashrate is always zero, as BSRR seals blocks without proof-of-work.
+func (api *PublicEthCompatAPI) Hashrate() hexutil.Uint64 {
+	return 0
+}
+
+// BlockNumber returns the number of the most recent block.
+func (api *PublicEthCompatAPI) BlockNumber() hexutil.Uint64 {
+	return api.blockchain.BlockNumber()
+}
+
+// GetBalance returns the main balance of the address at the given block.
+func (api *PublicEthCompatAPI) GetBalance(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*hexutil.Big, error) {
+	return api.blockchain.GetBalance(ctx, address, blockNr)
+}
+
+// GetCode returns the code stored at the address at the given block.
+func (api *PublicEthCompatAPI) GetCode(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	return api.blockchain.GetCode(ctx, address, blockNr)
+}
+
+// GetStorageAt returns the storage of the address at the given key and block.
+func (api *PublicEthCompatAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	return api.blockchain.GetStorageAt(ctx, address, key, blockNr)
+}
+
+// GetTransactionCount returns the nonce of the address at the given block.
+func (api *PublicEthCompatAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*hexutil.Uint64, error) {
+	return api.txpool.GetTransactionCount(ctx, address, blockNr)
+}
+
+// GetBlockByNumber returns the block of the given number.
+func (api *PublicEthCompatAPI) GetBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+	return api.blockchain.GetBlockByNumber(ctx, blockNr, fullTx)
+}
+
+// GetBlockByHash returns the block of the given hash.
+func (api *PublicEthCompatAPI) GetBlockByHash(ctx context.Context, blockHash common.Hash, fullTx bool) (map[string]interface{}, error) {
+	return api.blockchain.GetBlockByHash(ctx, blockHash, fullTx)
+}
+
+// GetBlockTransactionCountByNumber returns the number of transactions in the block of the given number.
+func (api *PublicEthCompatAPI) GetBlockTransactionCountByNumber(ctx context.Context, blockNr rpc.BlockNumber) *hexutil.Uint {
+	return api.txpool.GetBlockTransactionCountByNumber(ctx, blockNr)
+}
+
+// GetBlockTransactionCountByHash returns the number of transactions in the block of the given hash.
+func (api *PublicEthCompatAPI) GetBlockTransactionCountByHash(ctx context.Context, blockHash common.Hash) *hexutil.Uint {
+	return api.txpool.GetBlockTransactionCountByHash(ctx, blockHash)
+}
+
+// GetTransactionByHash returns the transaction of the given hash.
+func (api *PublicEthCompatAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) *berithapi.RPCTransaction {
+	return api.txpool.GetTransactionByHash(ctx, hash)
+}
+
+// GetTransactionByBlockNumberAndIndex returns the transaction at the index of the block of the given number.
+func (api *PublicEthCompatAPI) GetTransactionByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) *berithapi.RPCTransaction {
+	return api.txpool.GetTransactionByBlockNumberAndIndex(ctx, blockNr, index)
+}
+
+// GetTransactionByBlockHashAndIndex returns the transaction at the index of the block of the given hash.
+func (api *PublicEthCompatAPI) GetTransactionByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint) *berithapi.RPCTransaction {
+	return api.txpool.GetTransactionByBlockHashAndIndex(ctx, blockHash, index)
+}
+
+// GetTransactionReceipt returns the receipt of the transaction of the given hash.
+func (api *PublicEthCompatAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	return api.txpool.GetTransactionReceipt(ctx, hash)
+}
+
+// SendRawTransaction submits the signed transaction to the transaction pool.
+func (api *PublicEthCompatAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
+	return api.txpool.SendRawTransaction(ctx, encodedTx)
+}
+
+// Call executes the given transaction on the state of the given block without
+// creating a transaction.
+func (api *PublicEthCompatAPI) Call(ctx context.Context, args berithapi.CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	return api.blockchain.Call(ctx, args, blockNr)
+}
+
+// EstimateGas returns an estimate of the gas needed to execute the given transaction.
+func (api *PublicEthCompatAPI) EstimateGas(ctx context.Context, args berithapi.CallArgs) (hexutil.Uint64, error) {
+	return api.blockchain.EstimateGas(ctx, args)
+}
+
+// GetLogs returns the logs matching the given filter criteria.
+func (api *PublicEthCompatAPI) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([]*types.Log, error) {
+	return api.filters.GetLogs(ctx, crit)
+}
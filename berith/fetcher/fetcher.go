@@ -76,6 +76,8 @@ type announce struct {
 	header *types.Header // Header of the block partially reassembled (new protocol)
 	time   time.Time     // Timestamp of the announcement
 
+	announced time.Time // Timestamp of the first announcement of the block by any peer
+
 	origin string // Identifier of the peer originating the notification
 
 	fetchHeader headerRequesterFn // Fetcher function to retrieve the header of an announced block
@@ -197,6 +199,7 @@ func (f *Fetcher) Notify(peer string, hash common.Hash, number uint64, time time
 		hash:        hash,
 		number:      number,
 		time:        time,
+		announced:   time,
 		origin:      peer,
 		fetchHeader: headerFetcher,
 		fetchBodies: bodyFetcher,
@@ -375,6 +378,7 @@ func (f *Fetcher) loop() {
 				if time.Since(announces[0].time) > arriveTimeout-gatherSlack {
 					// Pick a random peer to retrieve from, reset all others
 					announce := announces[rand.Intn(len(announces))]
+					announce.announced = announces[0].time
 					f.forgetHash(hash)
 
 					// If the block still didn't arrive, queue for fetching
@@ -470,6 +474,7 @@ func (f *Fetcher) loop() {
 
 							block := types.NewBlockWithHeader(header)
 							block.ReceivedAt = task.time
+							block.AnnouncedAt = announce.announced
 
 							complete = append(complete, block)
 							f.completing[hash] = announce
@@ -537,6 +542,7 @@ func (f *Fetcher) loop() {
 							if f.getBlock(hash) == nil {
 								block := types.NewBlockWithHeader(announce.header).WithBody(task.transactions[i], task.uncles[i])
 								block.ReceivedAt = task.time
+								block.AnnouncedAt = announce.announced
 
 								blocks = append(blocks, block)
 							} else {
@@ -59,12 +59,15 @@ type PublicFilterAPI struct {
 	events    *EventSystem
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
+	limits    LogLimits
 }
 
-// NewPublicFilterAPI returns a new PublicFilterAPI instance.
-func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
+// NewPublicFilterAPI returns a new PublicFilterAPI instance, serving the log
+// queries within the given limits.
+func NewPublicFilterAPI(backend Backend, lightMode bool, limits LogLimits) *PublicFilterAPI {
 	api := &PublicFilterAPI{
 		backend: backend,
+		limits:  limits,
 		mux:     backend.EventMux(),
 		chainDb: backend.ChainDb(),
 		events:  NewEventSystem(backend.EventMux(), backend, lightMode),
@@ -321,32 +324,35 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 }
 
 // GetLogs returns logs matching the given argument that are stored within the state.
+// Queries exceeding the limits of the node are refused, GetLogsPage serving them.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_getlogs
 func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
-	var filter *Filter
-	if crit.BlockHash != nil {
-		// Block filter requested, construct a single-shot filter
-		filter = NewBlockFilter(api.backend, *crit.BlockHash, crit.Addresses, crit.Topics)
-	} else {
-		// Convert the RPC block numbers into internals representations
-		begin := rpc.LatestBlockNumber.Int64()
-		if crit.FromBlock != nil {
-			begin = crit.FromBlock.Int64()
-		}
-		end := rpc.LatestBlockNumber.Int64()
-		if crit.ToBlock != nil {
-			end = crit.ToBlock.Int64()
-		}
-		// Construct the range filter
-		filter = NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics)
+	return api.queryLogs(ctx, crit)
+}
+
+// BloomStatus is the progress of the bloom bits indexing of the chain.
+type BloomStatus struct {
+	SectionSize    hexutil.Uint64  `json:"sectionSize"`
+	Sections       hexutil.Uint64  `json:"sections"`
+	HighestSection *hexutil.Uint64 `json:"highestSection"` // nil if no section is indexed yet
+	IndexedBlocks  hexutil.Uint64  `json:"indexedBlocks"`  // number of blocks covered by the indexed sections
+}
+
+// BloomStatus reports the progress of the bloom bits indexing. Log queries
+// beyond the indexed blocks are served without the index, block by block.
+func (api *PublicFilterAPI) BloomStatus() *BloomStatus {
+	size, sections := api.backend.BloomStatus()
+	status := &BloomStatus{
+		SectionSize:   hexutil.Uint64(size),
+		Sections:      hexutil.Uint64(sections),
+		IndexedBlocks: hexutil.Uint64(size * sections),
 	}
-	// Run the filter and return all the logs
-	logs, err := filter.Logs(ctx)
-	if err != nil {
-		return nil, err
+	if sections > 0 {
+		highest := hexutil.Uint64(sections - 1)
+		status.HighestSection = &highest
 	}
-	return returnLogs(logs), err
+	return status
 }
 
 // UninstallFilter removes the filter with the given filter id.
@@ -378,30 +384,7 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 	if !found || f.typ != LogsSubscription {
 		return nil, fmt.Errorf("filter not found")
 	}
-
-	var filter *Filter
-	if f.crit.BlockHash != nil {
-		// Block filter requested, construct a single-shot filter
-		filter = NewBlockFilter(api.backend, *f.crit.BlockHash, f.crit.Addresses, f.crit.Topics)
-	} else {
-		// Convert the RPC block numbers into internals representations
-		begin := rpc.LatestBlockNumber.Int64()
-		if f.crit.FromBlock != nil {
-			begin = f.crit.FromBlock.Int64()
-		}
-		end := rpc.LatestBlockNumber.Int64()
-		if f.crit.ToBlock != nil {
-			end = f.crit.ToBlock.Int64()
-		}
-		// Construct the range filter
-		filter = NewRangeFilter(api.backend, begin, end, f.crit.Addresses, f.crit.Topics)
-	}
-	// Run the filter and return all the logs
-	logs, err := filter.Logs(ctx)
-	if err != nil {
-		return nil, err
-	}
-	return returnLogs(logs), nil
+	return api.queryLogs(ctx, f.crit)
 }
 
 // GetFilterChanges returns the logs for the filter with the given id since
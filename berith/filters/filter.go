@@ -27,6 +27,7 @@ import (
 	"github.com/BerithFoundation/berith-chain/core/bloombits"
 	"github.com/BerithFoundation/berith-chain/core/types"
 	"github.com/BerithFoundation/berith-chain/event"
+	"github.com/BerithFoundation/berith-chain/log"
 	"github.com/BerithFoundation/berith-chain/rpc"
 )
 
@@ -57,6 +58,7 @@ type Filter struct {
 
 	block      common.Hash // Block hash if filtering a single block
 	begin, end int64       // Range interval if filtering multiple blocks
+	limit      int         // Number of logs after which no further blocks are searched, zero for no limit
 
 	matcher *bloombits.Matcher
 }
@@ -148,28 +150,42 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		err  error
 	)
 	size, sections := f.backend.BloomStatus()
-	if indexed := sections * size; indexed > uint64(f.begin) {
+	indexed := sections * size
+	if end >= indexed+size {
+		// More than the section being filled is unindexed, the indexer is still catching
+		// up. The range is served by scanning the blocks, callers can tell from the
+		// bloom status, so this is no reason to alarm the node operator.
+		log.Debug("Filter range extends beyond indexed bloom sections", "begin", f.begin, "end", end, "indexed", indexed)
+	}
+	if indexed > uint64(f.begin) {
 		if indexed > end {
 			logs, err = f.indexedLogs(ctx, end)
 		} else {
 			logs, err = f.indexedLogs(ctx, indexed-1)
 		}
-		if err != nil {
+		if err != nil || f.reached(len(logs)) {
 			return logs, err
 		}
 	}
-	rest, err := f.unindexedLogs(ctx, end)
+	rest, err := f.unindexedLogs(ctx, end, len(logs))
 	logs = append(logs, rest...)
 	return logs, err
 }
 
+// reached returns whether the given number of logs found reaches the limit of
+// the filter, the blocks after the one completing them not being searched.
+func (f *Filter) reached(found int) bool {
+	return f.limit > 0 && found >= f.limit
+}
+
 // indexedLogs returns the logs matching the filter criteria based on the bloom
 // bits indexed available locally or via the network.
 func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
 	// Create a matcher session and request servicing from the backend
 	matches := make(chan uint64, 64)
 
-	session, err := f.matcher.Start(ctx, uint64(f.begin), end, matches)
+	begin := uint64(f.begin)
+	session, err := f.matcher.Start(ctx, begin, end, matches)
 	if err != nil {
 		return nil, err
 	}
@@ -178,8 +194,10 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 	f.backend.ServiceFilter(ctx, session)
 
 	// Iterate over the matches until exhausted or context closed
-	var logs []*types.Log
-
+	var (
+		logs []*types.Log
+		hits uint64
+	)
 	for {
 		select {
 		case number, ok := <-matches:
@@ -188,10 +206,13 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 				err := session.Error()
 				if err == nil {
 					f.begin = int64(end) + 1
+					bloomMissCounter.Inc(int64(end - begin + 1 - hits))
 				}
 				return logs, err
 			}
 			f.begin = int64(number) + 1
+			hits++
+			bloomHitCounter.Inc(1)
 
 			// Retrieve the suggested block and pull any truly matching logs
 			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
@@ -204,27 +225,41 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 			}
 			logs = append(logs, found...)
 
+			if f.reached(len(logs)) {
+				bloomMissCounter.Inc(int64(number - begin + 1 - hits))
+				return logs, nil
+			}
+
 		case <-ctx.Done():
 			return logs, ctx.Err()
 		}
 	}
 }
 
-// indexedLogs returns the logs matching the filter criteria based on raw block
-// iteration and bloom matching.
-func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
+// unindexedLogs returns the logs matching the filter criteria based on raw block
+// iteration and bloom matching, counting the given logs already found towards
+// the limit of the filter.
+func (f *Filter) unindexedLogs(ctx context.Context, end uint64, found int) ([]*types.Log, error) {
 	var logs []*types.Log
 
 	for ; f.begin <= int64(end); f.begin++ {
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
 		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.begin))
 		if header == nil || err != nil {
 			return logs, err
 		}
-		found, err := f.blockLogs(ctx, header)
+		matched, err := f.blockLogs(ctx, header)
 		if err != nil {
 			return logs, err
 		}
-		logs = append(logs, found...)
+		logs = append(logs, matched...)
+
+		if f.reached(found + len(logs)) {
+			f.begin++
+			return logs, nil
+		}
 	}
 	return logs, nil
 }
@@ -232,11 +267,14 @@ func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, e
 // blockLogs returns the logs matching the filter criteria within a single block.
 func (f *Filter) blockLogs(ctx context.Context, header *types.Header) (logs []*types.Log, err error) {
 	if bloomFilter(header.Bloom, f.addresses, f.topics) {
+		bloomHitCounter.Inc(1)
 		found, err := f.checkMatches(ctx, header)
 		if err != nil {
 			return logs, err
 		}
 		logs = append(logs, found...)
+	} else {
+		bloomMissCounter.Inc(1)
 	}
 	return logs, nil
 }
@@ -269,6 +307,7 @@ func (f *Filter) checkMatches(ctx context.Context, header *types.Header) (logs [
 		}
 		return logs, nil
 	}
+	bloomFalsePositiveCounter.Inc(1)
 	return nil, nil
 }
 
@@ -0,0 +1,141 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/core"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+// testBackend is a chain of empty headers whose bloom status is reported by a
+// bloom indexer, the way the light client reports it.
+type testBackend struct {
+	Backend
+	db      berithdb.Database
+	head    uint64
+	size    uint64
+	indexer *core.ChainIndexer
+}
+
+func (b *testBackend) ChainDb() berithdb.Database {
+	return b.db
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	number := uint64(blockNr)
+	if blockNr == rpc.LatestBlockNumber {
+		number = b.head
+	}
+	if number > b.head {
+		return nil, nil
+	}
+	return &types.Header{Number: new(big.Int).SetUint64(number)}, nil
+}
+
+func (b *testBackend) GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error) {
+	return nil, nil
+}
+
+func (b *testBackend) BloomStatus() (uint64, uint64) {
+	sections, _, _ := b.indexer.Sections()
+	return b.size, sections
+}
+
+// newTestBackend creates a backend whose bloom indexer already indexed the
+// given number of sections.
+func newTestBackend(head, size, sections uint64) *testBackend {
+	db, indexDb := berithdb.NewMemDatabase(), berithdb.NewMemDatabase()
+
+	var count [8]byte
+	binary.BigEndian.PutUint64(count[:], sections)
+	indexDb.Put([]byte("count"), count[:])
+
+	return &testBackend{
+		db:      db,
+		head:    head,
+		size:    size,
+		indexer: core.NewChainIndexer(db, indexDb, nil, size, 0, 0, "bloombits"),
+	}
+}
+
+// Tests that the bloom status reports the highest indexed section of a
+// partially indexed chain.
+func TestBloomStatus(t *testing.T) {
+	for _, sections := range []uint64{0, 1, 3} {
+		backend := newTestBackend(100, 16, sections)
+		defer backend.indexer.Close()
+
+		status := (&PublicFilterAPI{backend: backend}).BloomStatus()
+		if uint64(status.Sections) != sections {
+			t.Errorf("%d sections: section count mismatch: have %d, want %d", sections, status.Sections, sections)
+		}
+		if uint64(status.IndexedBlocks) != sections*16 {
+			t.Errorf("%d sections: indexed blocks mismatch: have %d, want %d", sections, status.IndexedBlocks, sections*16)
+		}
+		switch {
+		case sections == 0 && status.HighestSection != nil:
+			t.Errorf("%d sections: highest section reported: %d", sections, *status.HighestSection)
+		case sections > 0 && (status.HighestSection == nil || uint64(*status.HighestSection) != sections-1):
+			t.Errorf("%d sections: highest section mismatch: have %v, want %d", sections, status.HighestSection, sections-1)
+		}
+	}
+}
+
+// Tests that log queries report if their range extends beyond the indexed
+// sections by more than the section being indexed.
+func TestFilterUnindexedReport(t *testing.T) {
+	backend := newTestBackend(100, 16, 3)
+	defer backend.indexer.Close()
+
+	var reported bool
+	log.Root().SetHandler(log.FuncHandler(func(r *log.Record) error {
+		if r.Lvl == log.LvlDebug && r.Msg == "Filter range extends beyond indexed bloom sections" {
+			reported = true
+		}
+		return nil
+	}))
+	defer log.Root().SetHandler(log.DiscardHandler())
+
+	tests := []struct {
+		begin, end int64
+		report     bool
+	}{
+		{48, 63, false}, // section being indexed
+		{50, 60, false},
+		{48, 64, true},
+		{60, -1, true}, // up to the head
+		{-1, -1, true}, // head only
+	}
+	for i, tt := range tests {
+		reported = false
+		if _, err := NewRangeFilter(backend, tt.begin, tt.end, nil, nil).Logs(context.Background()); err != nil {
+			t.Fatalf("test %d: failed to filter logs: %v", i, err)
+		}
+		if reported != tt.report {
+			t.Errorf("test %d: report mismatch: have %v, want %v", i, reported, tt.report)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package filters
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/metrics"
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+const (
+	// defaultPageRange is the number of blocks searched at once by a paged log
+	// query if the block range of the queries isn't limited.
+	defaultPageRange = 2048
+
+	// defaultPageResults is the number of logs returned by a page if the results
+	// of the queries aren't limited.
+	defaultPageResults = 10000
+)
+
+var (
+	// The bloom filter effectiveness of the log queries. A block is a hit if its
+	// bloom filter reports a possible match, its receipts then being read, and a
+	// false positive if they hold no matching log after all. Queries without a
+	// miss only degenerate to scans of all the receipts in their range.
+	bloomHitCounter           = metrics.NewRegisteredCounter("filters/bloom/hit", nil)
+	bloomMissCounter          = metrics.NewRegisteredCounter("filters/bloom/miss", nil)
+	bloomFalsePositiveCounter = metrics.NewRegisteredCounter("filters/bloom/falsepositive", nil)
+)
+
+var errInvalidCursor = errors.New("invalid log cursor")
+
+// LogLimits are the limits of a single log query served by the node, zero
+// values disabling the limits.
+type LogLimits struct {
+	MaxBlockRange uint64        `toml:",omitempty"` // Maximum number of blocks searched by a log query
+	MaxResults    int           `toml:",omitempty"` // Maximum number of logs returned by a log query
+	Timeout       time.Duration `toml:",omitempty"` // Maximum execution time of a log query
+}
+
+// logCursor is the position in the chain of the next log of a paged query. It
+// is encoded as the block number and the transaction and log indexes in the
+// block, which don't depend on the state of the node serving the query.
+type logCursor struct {
+	Block   uint64
+	TxIndex uint32
+	Index   uint32
+}
+
+// cursorAt returns the cursor of the given log.
+func cursorAt(log *types.Log) *logCursor {
+	return &logCursor{Block: log.BlockNumber, TxIndex: uint32(log.TxIndex), Index: uint32(log.Index)}
+}
+
+// encode returns the hex encoding of the cursor handed out to the clients.
+func (c *logCursor) encode() string {
+	enc := make([]byte, 16)
+	binary.BigEndian.PutUint64(enc[:8], c.Block)
+	binary.BigEndian.PutUint32(enc[8:12], c.TxIndex)
+	binary.BigEndian.PutUint32(enc[12:], c.Index)
+	return hexutil.Encode(enc)
+}
+
+// decodeLogCursor parses a cursor handed out to a client.
+func decodeLogCursor(s string) (*logCursor, error) {
+	enc, err := hexutil.Decode(s)
+	if err != nil || len(enc) != 16 {
+		return nil, errInvalidCursor
+	}
+	return &logCursor{
+		Block:   binary.BigEndian.Uint64(enc[:8]),
+		TxIndex: binary.BigEndian.Uint32(enc[8:12]),
+		Index:   binary.BigEndian.Uint32(enc[12:]),
+	}, nil
+}
+
+// LogsPage is a page of the logs matching a query, with the cursor continuing
+// the query if more logs may follow.
+type LogsPage struct {
+	Logs   []*types.Log `json:"logs"`
+	Cursor *string      `json:"cursor"` // nil if the query is complete
+}
+
+// timeoutError returns the error of a log query stopped by the time limit.
+func (l LogLimits) timeoutError() error {
+	return fmt.Errorf("log query exceeded the time limit of %v, narrow the block range or page through the logs with berith_getLogsPage", l.Timeout)
+}
+
+// withTimeout returns the context of a log query, limited to the execution time
+// of the queries.
+func (l LogLimits) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, l.Timeout)
+}
+
+// resolveRange returns the block range of the query, resolving the latest and
+// pending blocks to the head of the chain and ending the range at the head. It
+// returns false if no block of the chain is in the range.
+func (api *PublicFilterAPI) resolveRange(ctx context.Context, crit FilterCriteria) (uint64, uint64, bool, error) {
+	header, err := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if header == nil || err != nil {
+		return 0, 0, false, err
+	}
+	head := header.Number.Uint64()
+
+	resolve := func(number *big.Int) uint64 {
+		if number == nil || number.Sign() < 0 {
+			return head
+		}
+		return number.Uint64()
+	}
+	begin, end := resolve(crit.FromBlock), resolve(crit.ToBlock)
+	if end > head {
+		end = head
+	}
+	return begin, end, begin <= end, nil
+}
+
+// queryLogs runs the log query of the given criteria within the limits of the
+// queries, refusing the ones exceeding them with errors advising to page.
+func (api *PublicFilterAPI) queryLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
+	var filter *Filter
+	if crit.BlockHash != nil {
+		// Block filter requested, construct a single-shot filter
+		filter = NewBlockFilter(api.backend, *crit.BlockHash, crit.Addresses, crit.Topics)
+	} else {
+		begin, end, ok, err := api.resolveRange(ctx, crit)
+		if !ok {
+			return []*types.Log{}, err
+		}
+		if limit := api.limits.MaxBlockRange; limit > 0 && end-begin+1 > limit {
+			return nil, fmt.Errorf("block range of %d blocks exceeds the limit of %d blocks, split the range or page through the logs with berith_getLogsPage", end-begin+1, limit)
+		}
+		// Construct the range filter
+		filter = NewRangeFilter(api.backend, int64(begin), int64(end), crit.Addresses, crit.Topics)
+	}
+	if api.limits.MaxResults > 0 {
+		filter.limit = api.limits.MaxResults + 1
+	}
+	ctx, cancel := api.limits.withTimeout(ctx)
+	defer cancel()
+
+	// Run the filter and return all the logs
+	logs, err := filter.Logs(ctx)
+	if err == context.DeadlineExceeded {
+		return nil, api.limits.timeoutError()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if limit := api.limits.MaxResults; limit > 0 && len(logs) > limit {
+		return nil, fmt.Errorf("log query returns more than %d logs, narrow the block range or page through the logs with berith_getLogsPage", limit)
+	}
+	return returnLogs(logs), nil
+}
+
+// GetLogsPage returns a page of the logs matching the given criteria, searching
+// the blocks range by range from the given cursor, or from the start of the
+// block range if nil. The page holds at most the maximum results of a query and
+// ends at the time limit, the cursor of the page continuing the query. A page
+// cut within a block continues within the same block.
+func (api *PublicFilterAPI) GetLogsPage(ctx context.Context, crit FilterCriteria, cursor *string) (*LogsPage, error) {
+	if crit.BlockHash != nil {
+		return nil, errors.New("paged log queries are only supported over block ranges")
+	}
+	begin, end, ok, err := api.resolveRange(ctx, crit)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &LogsPage{Logs: []*types.Log{}}, nil
+	}
+	var from *logCursor
+	if cursor != nil {
+		if from, err = decodeLogCursor(*cursor); err != nil {
+			return nil, err
+		}
+		if from.Block < begin || from.Block > end {
+			return nil, fmt.Errorf("log cursor at block %d is outside of the block range %d-%d", from.Block, begin, end)
+		}
+		begin = from.Block
+	}
+	var (
+		span    = uint64(defaultPageRange)
+		results = defaultPageResults
+	)
+	if api.limits.MaxBlockRange > 0 {
+		span = api.limits.MaxBlockRange
+	}
+	if api.limits.MaxResults > 0 {
+		results = api.limits.MaxResults
+	}
+	ctx, cancel := api.limits.withTimeout(ctx)
+	defer cancel()
+
+	// Search the range by parts until the page is full, an interrupted part
+	// being searched again by the next page
+	page := &LogsPage{Logs: []*types.Log{}}
+	next := begin
+	for next <= end && len(page.Logs) < results {
+		last := end
+		if next+span-1 < end {
+			last = next + span - 1
+		}
+		filter := NewRangeFilter(api.backend, int64(next), int64(last), crit.Addresses, crit.Topics)
+		filter.limit = results - len(page.Logs)
+		if from != nil && next == from.Block {
+			filter.limit += int(from.Index)
+		}
+		logs, err := filter.Logs(ctx)
+		if err == context.DeadlineExceeded {
+			if len(page.Logs) == 0 && next == begin {
+				return nil, api.limits.timeoutError()
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range logs {
+			// Skip the logs of the cursor's block returned by the previous page
+			if from != nil && log.BlockNumber == from.Block && uint32(log.Index) < from.Index {
+				continue
+			}
+			page.Logs = append(page.Logs, log)
+		}
+		next = uint64(filter.begin)
+	}
+	switch {
+	case len(page.Logs) > results:
+		enc := cursorAt(page.Logs[results]).encode()
+		page.Logs, page.Cursor = page.Logs[:results], &enc
+	case next <= end:
+		enc := (&logCursor{Block: next}).encode()
+		page.Cursor = &enc
+	}
+	return page, nil
+}
@@ -0,0 +1,193 @@
+package filters
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/metrics"
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+var (
+	logAddress   = common.Address{1} // Address of all the logs of the chain
+	bloomAddress = common.Address{2} // Address in the blooms of all the blocks, but of no log
+)
+
+// logsBackend is an unindexed chain of blocks holding two logs each, of two
+// transactions.
+type logsBackend struct {
+	Backend
+	db      berithdb.Database
+	headers []*types.Header
+	logs    map[common.Hash][][]*types.Log
+}
+
+func newLogsBackend(blocks int) *logsBackend {
+	b := &logsBackend{db: berithdb.NewMemDatabase(), logs: make(map[common.Hash][][]*types.Log)}
+	for i := 0; i < blocks; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i))}
+		header.Bloom.Add(new(big.Int).SetBytes(logAddress.Bytes()))
+		header.Bloom.Add(new(big.Int).SetBytes(bloomAddress.Bytes()))
+		b.headers = append(b.headers, header)
+
+		hash := header.Hash()
+		for tx := 0; tx < 2; tx++ {
+			log := &types.Log{Address: logAddress, BlockNumber: uint64(i), BlockHash: hash, TxHash: common.Hash{byte(i), byte(tx), 1}, TxIndex: uint(tx), Index: uint(tx)}
+			b.logs[hash] = append(b.logs[hash], []*types.Log{log})
+		}
+	}
+	return b
+}
+
+func (b *logsBackend) ChainDb() berithdb.Database { return b.db }
+
+func (b *logsBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	if blockNr == rpc.LatestBlockNumber {
+		return b.headers[len(b.headers)-1], nil
+	}
+	if int(blockNr) >= len(b.headers) {
+		return nil, nil
+	}
+	return b.headers[blockNr], nil
+}
+
+func (b *logsBackend) GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error) {
+	return b.logs[blockHash], nil
+}
+
+func (b *logsBackend) BloomStatus() (uint64, uint64) { return 16, 0 }
+
+// newRangeCriteria returns the criteria of the logs of the chain in the range.
+func newRangeCriteria(begin, end int64) FilterCriteria {
+	return FilterCriteria{FromBlock: big.NewInt(begin), ToBlock: big.NewInt(end), Addresses: []common.Address{logAddress}}
+}
+
+// Tests that log queries over more blocks than the limit are refused, advising
+// to page through the logs.
+func TestLogQueryRangeLimit(t *testing.T) {
+	api := &PublicFilterAPI{backend: newLogsBackend(32), limits: LogLimits{MaxBlockRange: 10}}
+
+	_, err := api.GetLogs(context.Background(), newRangeCriteria(0, 20))
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit of 10 blocks") || !strings.Contains(err.Error(), "berith_getLogsPage") {
+		t.Errorf("range limit error mismatch: have %v", err)
+	}
+	// The range up to the head is limited as well
+	if _, err := api.GetLogs(context.Background(), FilterCriteria{FromBlock: big.NewInt(0)}); err == nil {
+		t.Errorf("query up to the head exceeding the range limit succeeded")
+	}
+	logs, err := api.GetLogs(context.Background(), newRangeCriteria(5, 14))
+	if err != nil || len(logs) != 20 {
+		t.Errorf("query within the range limit: have %d logs, %v, want 20", len(logs), err)
+	}
+}
+
+// Tests that log queries returning more logs than the limit are refused, stopping
+// the search once the limit is exceeded.
+func TestLogQueryResultLimit(t *testing.T) {
+	hits := bloomHitCounter
+	defer func() { bloomHitCounter = hits }()
+	bloomHitCounter = metrics.NewCounterForced()
+
+	api := &PublicFilterAPI{backend: newLogsBackend(32), limits: LogLimits{MaxResults: 5}}
+
+	_, err := api.GetLogs(context.Background(), newRangeCriteria(0, 31))
+	if err == nil || !strings.Contains(err.Error(), "more than 5 logs") {
+		t.Errorf("result limit error mismatch: have %v", err)
+	}
+	if searched := bloomHitCounter.Count(); searched != 3 {
+		t.Errorf("searched blocks mismatch: have %d, want 3", searched)
+	}
+	logs, err := api.GetLogs(context.Background(), newRangeCriteria(0, 1))
+	if err != nil || len(logs) != 4 {
+		t.Errorf("query within the result limit: have %d logs, %v, want 4", len(logs), err)
+	}
+}
+
+// Tests that the logs are paged through with cursors, the pages being cut within
+// the blocks at the result limit and the ranges split at the range limit.
+func TestLogsPageCursor(t *testing.T) {
+	backend := newLogsBackend(20)
+	api := &PublicFilterAPI{backend: backend, limits: LogLimits{MaxBlockRange: 4, MaxResults: 3}}
+
+	var (
+		crit   = newRangeCriteria(0, 19)
+		cursor *string
+		logs   []*types.Log
+		pages  int
+	)
+	for {
+		page, err := api.GetLogsPage(context.Background(), crit, cursor)
+		if err != nil {
+			t.Fatalf("page %d: failed to get logs: %v", pages, err)
+		}
+		if len(page.Logs) > 3 {
+			t.Fatalf("page %d: result limit exceeded: have %d logs", pages, len(page.Logs))
+		}
+		// The first page is cut within the second block
+		if pages == 0 && (page.Cursor == nil || *page.Cursor != "0x00000000000000010000000100000001") {
+			t.Errorf("first cursor mismatch: have %v", page.Cursor)
+		}
+		logs = append(logs, page.Logs...)
+		pages++
+
+		if cursor = page.Cursor; cursor == nil {
+			break
+		}
+	}
+	if pages != 14 {
+		t.Errorf("page count mismatch: have %d, want 14", pages)
+	}
+	if len(logs) != 40 {
+		t.Fatalf("log count mismatch: have %d, want 40", len(logs))
+	}
+	for i, log := range logs {
+		if log.BlockNumber != uint64(i/2) || log.Index != uint(i%2) {
+			t.Errorf("log %d: position mismatch: have block %d index %d, want block %d index %d", i, log.BlockNumber, log.Index, i/2, i%2)
+		}
+	}
+	// A page completed at a block boundary continues with the next block
+	api.limits.MaxResults = 4
+	page, err := api.GetLogsPage(context.Background(), crit, nil)
+	if err != nil || len(page.Logs) != 4 || page.Cursor == nil || *page.Cursor != "0x00000000000000020000000000000000" {
+		t.Errorf("page at block boundary mismatch: have %d logs, cursor %v, %v", len(page.Logs), page.Cursor, err)
+	}
+	// Invalid cursors and cursors outside the range are refused
+	for _, invalid := range []string{"0x01", "cursor", "0x00000000000000ff0000000000000000"} {
+		if _, err := api.GetLogsPage(context.Background(), crit, &invalid); err == nil {
+			t.Errorf("cursor %s accepted", invalid)
+		}
+	}
+}
+
+// Tests that the blocks are counted as bloom hits, misses and false positives.
+func TestLogQueryBloomCounters(t *testing.T) {
+	defer func(hit, miss, falsePositive metrics.Counter) {
+		bloomHitCounter, bloomMissCounter, bloomFalsePositiveCounter = hit, miss, falsePositive
+	}(bloomHitCounter, bloomMissCounter, bloomFalsePositiveCounter)
+
+	api := &PublicFilterAPI{backend: newLogsBackend(10)}
+	tests := []struct {
+		address             common.Address
+		hit, miss, falsePos int64
+	}{
+		{logAddress, 10, 0, 0},
+		{bloomAddress, 10, 0, 10},
+		{common.Address{3}, 0, 10, 0},
+	}
+	for i, tt := range tests {
+		bloomHitCounter, bloomMissCounter, bloomFalsePositiveCounter = metrics.NewCounterForced(), metrics.NewCounterForced(), metrics.NewCounterForced()
+
+		crit := FilterCriteria{FromBlock: big.NewInt(0), ToBlock: big.NewInt(9), Addresses: []common.Address{tt.address}}
+		if _, err := api.GetLogs(context.Background(), crit); err != nil {
+			t.Fatalf("test %d: failed to get logs: %v", i, err)
+		}
+		if hit, miss, falsePos := bloomHitCounter.Count(), bloomMissCounter.Count(), bloomFalsePositiveCounter.Count(); hit != tt.hit || miss != tt.miss || falsePos != tt.falsePos {
+			t.Errorf("test %d: counters mismatch: have %d/%d/%d hit/miss/false positive, want %d/%d/%d", i, hit, miss, falsePos, tt.hit, tt.miss, tt.falsePos)
+		}
+	}
+}
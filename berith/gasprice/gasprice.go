@@ -23,18 +23,23 @@ import (
 	"sync"
 
 	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
 	"github.com/BerithFoundation/berith-chain/core/types"
 	"berith-chain/internals/berithapi"
+	"github.com/BerithFoundation/berith-chain/log"
 	"github.com/BerithFoundation/berith-chain/params"
 	"github.com/BerithFoundation/berith-chain/rpc"
 )
 
-var maxPrice = big.NewInt(500 * params.Gmin)
+// DefaultMaxPrice is the highest gas price suggested if no other limit is
+// configured.
+var DefaultMaxPrice = big.NewInt(500 * params.Gmin)
 
 type Config struct {
 	Blocks     int
 	Percentile int
 	Default    *big.Int `toml:",omitempty"`
+	MaxPrice   *big.Int `toml:",omitempty"`
 }
 
 // Oracle recommends gas prices based on the content of recent
@@ -48,29 +53,56 @@ type Oracle struct {
 
 	checkBlocks, maxEmpty, maxBlocks int
 	percentile                       int
+	defaultPrice, maxPrice           *big.Int
 }
 
-// NewOracle returns a new oracle.
+// NewOracle returns a new oracle. Invalid settings are replaced by the nearest
+// valid ones, and a missing maximum price by DefaultMaxPrice.
 func NewOracle(backend berithapi.Backend, params Config) *Oracle {
 	blocks := params.Blocks
 	if blocks < 1 {
 		blocks = 1
+		log.Warn("Sanitizing invalid gas price oracle sample blocks", "provided", params.Blocks, "updated", blocks)
 	}
 	percent := params.Percentile
 	if percent < 0 {
 		percent = 0
+		log.Warn("Sanitizing invalid gas price oracle percentile", "provided", params.Percentile, "updated", percent)
 	}
 	if percent > 100 {
 		percent = 100
+		log.Warn("Sanitizing invalid gas price oracle percentile", "provided", params.Percentile, "updated", percent)
+	}
+	maxPrice := params.MaxPrice
+	if maxPrice == nil || maxPrice.Sign() <= 0 {
+		if maxPrice != nil {
+			log.Warn("Sanitizing invalid gas price oracle maximum price", "provided", maxPrice, "updated", DefaultMaxPrice)
+		}
+		maxPrice = DefaultMaxPrice
 	}
 	return &Oracle{
-		backend:     backend,
-		lastPrice:   params.Default,
-		checkBlocks: blocks,
-		maxEmpty:    blocks / 2,
-		maxBlocks:   blocks * 5,
-		percentile:  percent,
+		backend:      backend,
+		lastPrice:    params.Default,
+		checkBlocks:  blocks,
+		maxEmpty:     blocks / 2,
+		maxBlocks:    blocks * 5,
+		percentile:   percent,
+		defaultPrice: params.Default,
+		maxPrice:     new(big.Int).Set(maxPrice),
+	}
+}
+
+// Config returns the settings the oracle is running with, after sanitizing.
+func (gpo *Oracle) Config() Config {
+	config := Config{
+		Blocks:     gpo.checkBlocks,
+		Percentile: gpo.percentile,
+		MaxPrice:   new(big.Int).Set(gpo.maxPrice),
+	}
+	if gpo.defaultPrice != nil {
+		config.Default = new(big.Int).Set(gpo.defaultPrice)
 	}
+	return config
 }
 
 // SuggestPrice returns the recommended gas price.
@@ -136,8 +168,8 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 		sort.Sort(bigIntArray(blockPrices))
 		price = blockPrices[(len(blockPrices)-1)*gpo.percentile/100]
 	}
-	if price.Cmp(maxPrice) > 0 {
-		price = new(big.Int).Set(maxPrice)
+	if price.Cmp(gpo.maxPrice) > 0 {
+		price = new(big.Int).Set(gpo.maxPrice)
 	}
 
 	gpo.cacheLock.Lock()
@@ -187,3 +219,33 @@ type bigIntArray []*big.Int
 func (s bigIntArray) Len() int           { return len(s) }
 func (s bigIntArray) Less(i, j int) bool { return s[i].Cmp(s[j]) < 0 }
 func (s bigIntArray) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// OracleSettings are the effective settings of a gas price oracle.
+type OracleSettings struct {
+	Blocks     int          `json:"blocks"`     // Number of recent blocks sampled
+	Percentile int          `json:"percentile"` // Percentile of the sampled prices suggested
+	Default    *hexutil.Big `json:"default"`    // Price suggested before any block is sampled
+	MaxPrice   *hexutil.Big `json:"maxPrice"`   // Highest price suggested
+}
+
+// PublicOracleAPI offers the settings of the gas price oracle of the node.
+type PublicOracleAPI struct {
+	oracle *Oracle
+}
+
+// NewPublicOracleAPI creates an API reporting the settings of the given oracle.
+func NewPublicOracleAPI(oracle *Oracle) *PublicOracleAPI {
+	return &PublicOracleAPI{oracle: oracle}
+}
+
+// GasPriceOracle returns the settings the gas price oracle suggests the gas
+// prices with.
+func (api *PublicOracleAPI) GasPriceOracle() *OracleSettings {
+	config := api.oracle.Config()
+	return &OracleSettings{
+		Blocks:     config.Blocks,
+		Percentile: config.Percentile,
+		Default:    (*hexutil.Big)(config.Default),
+		MaxPrice:   (*hexutil.Big)(config.MaxPrice),
+	}
+}
@@ -0,0 +1,106 @@
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/crypto"
+	"berith-chain/internals/berithapi"
+	"github.com/BerithFoundation/berith-chain/params"
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+// testBackend is a chain of blocks each holding a single transaction.
+type testBackend struct {
+	berithapi.Backend
+	blocks []*types.Block
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	block, err := b.BlockByNumber(ctx, number)
+	if block == nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber {
+		number = rpc.BlockNumber(len(b.blocks) - 1)
+	}
+	return b.blocks[number], nil
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return params.TestnetChainConfig
+}
+
+// newTestBackend creates a chain whose blocks after the genesis pay the given
+// gas prices in gmin.
+func newTestBackend(t *testing.T, prices ...int64) *testBackend {
+	key, _ := crypto.GenerateKey()
+	backend := &testBackend{blocks: []*types.Block{types.NewBlockWithHeader(&types.Header{Number: new(big.Int)})}}
+	for i, price := range prices {
+		number := big.NewInt(int64(i + 1))
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), [20]byte{1}, new(big.Int), params.TxGas, big.NewInt(price*params.Gmin), nil, types.Main, types.Main), types.MakeSigner(params.TestnetChainConfig, number), key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		header := &types.Header{Number: number, ParentHash: backend.blocks[i].Hash()}
+		backend.blocks = append(backend.blocks, types.NewBlock(header, []*types.Transaction{tx}, nil, nil))
+	}
+	return backend
+}
+
+// Tests that the oracle suggests the configured percentile of the sampled gas
+// prices, capped at the configured maximum price.
+func TestSuggestPricePercentile(t *testing.T) {
+	tests := []struct {
+		percentile int
+		maxPrice   int64
+		want       int64
+	}{
+		{0, 0, 10},
+		{50, 0, 30},
+		{60, 0, 30},
+		{100, 0, 50},
+		{100, 40, 40},
+	}
+	for _, tt := range tests {
+		config := Config{Blocks: 5, Percentile: tt.percentile, Default: big.NewInt(params.Gmin)}
+		if tt.maxPrice != 0 {
+			config.MaxPrice = big.NewInt(tt.maxPrice * params.Gmin)
+		}
+		oracle := NewOracle(newTestBackend(t, 50, 10, 40, 20, 30), config)
+
+		price, err := oracle.SuggestPrice(context.Background())
+		if err != nil {
+			t.Fatalf("percentile %d: failed to suggest price: %v", tt.percentile, err)
+		}
+		if want := big.NewInt(tt.want * params.Gmin); price.Cmp(want) != 0 {
+			t.Errorf("percentile %d, max %d: price mismatch: have %v, want %v", tt.percentile, tt.maxPrice, price, want)
+		}
+	}
+}
+
+// Tests that invalid settings are sanitized, and that the effective settings are
+// reported over the API.
+func TestOracleSettings(t *testing.T) {
+	oracle := NewOracle(newTestBackend(t), Config{Blocks: 0, Percentile: 120, MaxPrice: big.NewInt(-1)})
+	settings := NewPublicOracleAPI(oracle).GasPriceOracle()
+	if settings.Blocks != 1 || settings.Percentile != 100 || settings.Default != nil || settings.MaxPrice.ToInt().Cmp(DefaultMaxPrice) != 0 {
+		t.Errorf("sanitized settings mismatch: %+v", settings)
+	}
+	oracle = NewOracle(newTestBackend(t), Config{Blocks: 10, Percentile: 25, Default: big.NewInt(3), MaxPrice: big.NewInt(7)})
+	settings = NewPublicOracleAPI(oracle).GasPriceOracle()
+	if settings.Blocks != 10 || settings.Percentile != 25 || settings.Default.ToInt().Int64() != 3 || settings.MaxPrice.ToInt().Int64() != 7 {
+		t.Errorf("custom settings mismatch: %+v", settings)
+	}
+	// The reported settings don't alias the oracle's
+	settings.MaxPrice.ToInt().SetInt64(100)
+	if have := oracle.Config().MaxPrice.Int64(); have != 7 {
+		t.Errorf("maximum price modified through settings: have %d, want 7", have)
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"math/big"
 	"time"
 
+	"berith-chain/internals/berithapi"
+
 	"github.com/BerithFoundation/berith-chain/berith/downloader"
+	"github.com/BerithFoundation/berith-chain/berith/filters"
 	"github.com/BerithFoundation/berith-chain/berith/gasprice"
+	"github.com/BerithFoundation/berith-chain/berith/staking"
 	"github.com/BerithFoundation/berith-chain/common"
 	"github.com/BerithFoundation/berith-chain/common/hexutil"
 	"github.com/BerithFoundation/berith-chain/core"
@@ -22,10 +26,18 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
 		NoPruning               bool
-		LightServ               int  `toml:",omitempty"`
-		LightPeers              int  `toml:",omitempty"`
-		SkipBcVersionCheck      bool `toml:"-"`
-		DatabaseHandles         int  `toml:"-"`
+		StakingCommit           staking.CommitMode
+		StakingReadOnly         bool
+		VoteHistory             bool
+		VoteHistoryRetention    uint64
+		StakeIntentHorizon      time.Duration
+		LightServ               int           `toml:",omitempty"`
+		LightPeers              int           `toml:",omitempty"`
+		LightMinServers         int           `toml:",omitempty"`
+		LightMaxServers         int           `toml:",omitempty"`
+		LightRetrieveTimeout    time.Duration `toml:",omitempty"`
+		SkipBcVersionCheck      bool          `toml:"-"`
+		DatabaseHandles         int           `toml:"-"`
 		DatabaseCache           int
 		TrieCleanCache          int
 		TrieDirtyCache          int
@@ -38,9 +50,18 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		MinerGasPrice           *big.Int
 		MinerRecommit           time.Duration
 		MinerNoverify           bool
+		MinerTxOrdering         string `toml:",omitempty"`
+		MinerNoEmptyPrecommit   bool   `toml:",omitempty"`
+		MinerMaxUncles          int    `toml:",omitempty"`
+		MinerDroppedTxs         int    `toml:",omitempty"`
+		MinerGasLimitDivisor    uint64 `toml:",omitempty"`
+		MinerSoak               bool   `toml:",omitempty"`
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
+		EthCompat               bool `toml:",omitempty"`
+		LogLimits               filters.LogLimits
+		PasswordGuard           berithapi.PasswordGuardConfig
 		DocRoot                 string `toml:"-"`
 		EWASMInterpreter        string
 		EVMInterpreter          string
@@ -50,8 +71,16 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
 	enc.NoPruning = c.NoPruning
+	enc.StakingCommit = c.StakingCommit
+	enc.StakingReadOnly = c.StakingReadOnly
+	enc.VoteHistory = c.VoteHistory
+	enc.VoteHistoryRetention = c.VoteHistoryRetention
+	enc.StakeIntentHorizon = c.StakeIntentHorizon
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
+	enc.LightMinServers = c.LightMinServers
+	enc.LightMaxServers = c.LightMaxServers
+	enc.LightRetrieveTimeout = c.LightRetrieveTimeout
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
@@ -66,9 +95,18 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.MinerGasPrice = c.MinerGasPrice
 	enc.MinerRecommit = c.MinerRecommit
 	enc.MinerNoverify = c.MinerNoverify
+	enc.MinerTxOrdering = c.MinerTxOrdering
+	enc.MinerNoEmptyPrecommit = c.MinerNoEmptyPrecommit
+	enc.MinerMaxUncles = c.MinerMaxUncles
+	enc.MinerDroppedTxs = c.MinerDroppedTxs
+	enc.MinerGasLimitDivisor = c.MinerGasLimitDivisor
+	enc.MinerSoak = c.MinerSoak
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.EthCompat = c.EthCompat
+	enc.LogLimits = c.LogLimits
+	enc.PasswordGuard = c.PasswordGuard
 	enc.DocRoot = c.DocRoot
 	enc.EWASMInterpreter = c.EWASMInterpreter
 	enc.EVMInterpreter = c.EVMInterpreter
@@ -82,10 +120,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
 		NoPruning               *bool
-		LightServ               *int  `toml:",omitempty"`
-		LightPeers              *int  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool `toml:"-"`
-		DatabaseHandles         *int  `toml:"-"`
+		StakingCommit           *staking.CommitMode
+		StakingReadOnly         *bool
+		VoteHistory             *bool
+		VoteHistoryRetention    *uint64
+		StakeIntentHorizon      *time.Duration
+		LightServ               *int           `toml:",omitempty"`
+		LightPeers              *int           `toml:",omitempty"`
+		LightMinServers         *int           `toml:",omitempty"`
+		LightMaxServers         *int           `toml:",omitempty"`
+		LightRetrieveTimeout    *time.Duration `toml:",omitempty"`
+		SkipBcVersionCheck      *bool          `toml:"-"`
+		DatabaseHandles         *int           `toml:"-"`
 		DatabaseCache           *int
 		TrieCleanCache          *int
 		TrieDirtyCache          *int
@@ -98,9 +144,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		MinerGasPrice           *big.Int
 		MinerRecommit           *time.Duration
 		MinerNoverify           *bool
+		MinerTxOrdering         *string `toml:",omitempty"`
+		MinerNoEmptyPrecommit   *bool   `toml:",omitempty"`
+		MinerMaxUncles          *int    `toml:",omitempty"`
+		MinerDroppedTxs         *int    `toml:",omitempty"`
+		MinerGasLimitDivisor    *uint64 `toml:",omitempty"`
+		MinerSoak               *bool   `toml:",omitempty"`
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
+		EthCompat               *bool `toml:",omitempty"`
+		LogLimits               *filters.LogLimits
+		PasswordGuard           *berithapi.PasswordGuardConfig
 		DocRoot                 *string `toml:"-"`
 		EWASMInterpreter        *string
 		EVMInterpreter          *string
@@ -121,12 +176,36 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPruning != nil {
 		c.NoPruning = *dec.NoPruning
 	}
+	if dec.StakingCommit != nil {
+		c.StakingCommit = *dec.StakingCommit
+	}
+	if dec.StakingReadOnly != nil {
+		c.StakingReadOnly = *dec.StakingReadOnly
+	}
+	if dec.VoteHistory != nil {
+		c.VoteHistory = *dec.VoteHistory
+	}
+	if dec.VoteHistoryRetention != nil {
+		c.VoteHistoryRetention = *dec.VoteHistoryRetention
+	}
+	if dec.StakeIntentHorizon != nil {
+		c.StakeIntentHorizon = *dec.StakeIntentHorizon
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
 	if dec.LightPeers != nil {
 		c.LightPeers = *dec.LightPeers
 	}
+	if dec.LightMinServers != nil {
+		c.LightMinServers = *dec.LightMinServers
+	}
+	if dec.LightMaxServers != nil {
+		c.LightMaxServers = *dec.LightMaxServers
+	}
+	if dec.LightRetrieveTimeout != nil {
+		c.LightRetrieveTimeout = *dec.LightRetrieveTimeout
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
@@ -169,6 +248,24 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.MinerNoverify != nil {
 		c.MinerNoverify = *dec.MinerNoverify
 	}
+	if dec.MinerTxOrdering != nil {
+		c.MinerTxOrdering = *dec.MinerTxOrdering
+	}
+	if dec.MinerNoEmptyPrecommit != nil {
+		c.MinerNoEmptyPrecommit = *dec.MinerNoEmptyPrecommit
+	}
+	if dec.MinerMaxUncles != nil {
+		c.MinerMaxUncles = *dec.MinerMaxUncles
+	}
+	if dec.MinerDroppedTxs != nil {
+		c.MinerDroppedTxs = *dec.MinerDroppedTxs
+	}
+	if dec.MinerGasLimitDivisor != nil {
+		c.MinerGasLimitDivisor = *dec.MinerGasLimitDivisor
+	}
+	if dec.MinerSoak != nil {
+		c.MinerSoak = *dec.MinerSoak
+	}
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
@@ -178,6 +275,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.EthCompat != nil {
+		c.EthCompat = *dec.EthCompat
+	}
+	if dec.LogLimits != nil {
+		c.LogLimits = *dec.LogLimits
+	}
+	if dec.PasswordGuard != nil {
+		c.PasswordGuard = *dec.PasswordGuard
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
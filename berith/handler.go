@@ -77,6 +77,8 @@ type ProtocolManager struct {
 	chainconfig *params.ChainConfig
 	maxPeers    int
 
+	legacyWarn sync.Once // Warns once of the peers not advertising their consensus identifier
+
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
@@ -267,10 +269,19 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		number  = head.Number.Uint64()
 		td      = pm.blockchain.GetTd(hash, number)
 	)
-	if err := p.Handshake(pm.networkID, td, hash, genesis.Hash()); err != nil {
+	filter := func(id params.ConsensusID) error {
+		return pm.chainconfig.CheckConsensusID(pm.blockchain.CurrentHeader().Number.Uint64(), id)
+	}
+	if err := p.Handshake(pm.networkID, td, hash, genesis.Hash(), pm.chainconfig.ConsensusID(number), filter); err != nil {
 		p.Log().Debug("Berith handshake failed", "err", err)
 		return err
 	}
+	if p.version < ber64 {
+		pm.legacyWarn.Do(func() {
+			log.Warn("Peers before berith/64 don't advertise their consensus parameters, they can't be checked")
+		})
+		p.Log().Debug("Peer doesn't advertise its consensus parameters", "version", p.version)
+	}
 	if rw, ok := p.rw.(*meteredMsgReadWriter); ok {
 		rw.Init(p.version)
 	}
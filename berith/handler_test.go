@@ -0,0 +1,218 @@
+package berith
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/berith/downloader"
+	"github.com/BerithFoundation/berith-chain/berith/staking"
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/consensus/bsrr"
+	"github.com/BerithFoundation/berith-chain/core"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/core/vm"
+	"github.com/BerithFoundation/berith-chain/event"
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/p2p"
+	"github.com/BerithFoundation/berith-chain/p2p/enode"
+	"github.com/BerithFoundation/berith-chain/params"
+)
+
+// testTxPool is a transaction pool without transactions.
+type testTxPool struct {
+	feed event.Feed
+}
+
+func (p *testTxPool) AddRemotes(txs []*types.Transaction) []error { return make([]error, len(txs)) }
+
+func (p *testTxPool) Pending() (map[common.Address]types.Transactions, error) {
+	return make(map[common.Address]types.Transactions), nil
+}
+
+func (p *testTxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return p.feed.Subscribe(ch)
+}
+
+// warnRecorder collects the warnings logged.
+type warnRecorder struct {
+	warnings []string
+	lock     sync.Mutex
+}
+
+func (r *warnRecorder) Log(rec *log.Record) error {
+	if rec.Lvl == log.LvlWarn {
+		r.lock.Lock()
+		r.warnings = append(r.warnings, rec.Msg)
+		r.lock.Unlock()
+	}
+	return nil
+}
+
+func (r *warnRecorder) count(msg string) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	n := 0
+	for _, warning := range r.warnings {
+		if warning == msg {
+			n++
+		}
+	}
+	return n
+}
+
+// newTestProtocolManager creates a protocol manager over a chain of the testnet
+// genesis with the configuration modified by configure, returning a function
+// releasing it.
+func newTestProtocolManager(t *testing.T, configure func(*params.ChainConfig)) (*ProtocolManager, func()) {
+	bsrrConfig := *params.TestnetChainConfig.Bsrr
+	config := *params.TestnetChainConfig
+	config.Bsrr = &bsrrConfig
+	configure(&config)
+
+	dir, err := ioutil.TempDir("", "berith-stakingdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stakingDB := new(staking.StakingDB)
+	if err := stakingDB.CreateDB(dir, staking.NewStakers); err != nil {
+		t.Fatal(err)
+	}
+	db := berithdb.NewMemDatabase()
+	(&core.Genesis{Config: &config, GasLimit: 10000000}).MustCommit(db)
+	engine := bsrr.NewCliqueWithStakingDB(stakingDB, config.Bsrr, db)
+	chain, err := core.NewBlockChain(stakingDB, db, nil, &config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, err := NewProtocolManager(&config, downloader.FullSync, 1, new(event.TypeMux), new(testTxPool), engine, chain, db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm.maxPeers = 10
+	return pm, func() {
+		chain.Stop()
+		stakingDB.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// Tests that two nodes sharing a genesis block but with different epochs refuse
+// to peer, both logging why.
+func TestHandshakeConsensusMismatch(t *testing.T) {
+	recorder := new(warnRecorder)
+	log.Root().SetHandler(recorder)
+	defer log.Root().SetHandler(log.DiscardHandler())
+
+	local, teardown := newTestProtocolManager(t, func(c *params.ChainConfig) { c.Bsrr.Epoch = 40 })
+	defer teardown()
+	remote, teardown := newTestProtocolManager(t, func(c *params.ChainConfig) { c.Bsrr.Epoch = 360 })
+	defer teardown()
+
+	if local.blockchain.Genesis().Hash() != remote.blockchain.Genesis().Hash() {
+		t.Fatalf("genesis blocks differ")
+	}
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	defer net.Close()
+
+	errc := make(chan error, 2)
+	go func() {
+		errc <- local.handle(local.newPeer(ber64, p2p.NewPeer(enode.ID{1}, "remote", nil), app))
+	}()
+	go func() {
+		errc <- remote.handle(remote.newPeer(ber64, p2p.NewPeer(enode.ID{2}, "local", nil), net))
+	}()
+	for i := 0; i < 2; i++ {
+		if err := <-errc; err == nil || !strings.Contains(err.Error(), errorToString[ErrConsensusMismatch]) {
+			t.Errorf("handshake error mismatch: have %v, want %v", err, errorToString[ErrConsensusMismatch])
+		}
+	}
+	if have := recorder.count("Refusing peer with different consensus parameters"); have != 2 {
+		t.Errorf("refusals logged: have %d, want 2", have)
+	}
+	if local.peers.Len() != 0 || remote.peers.Len() != 0 {
+		t.Errorf("peers registered despite the mismatch")
+	}
+}
+
+// Tests that the peers advertising their consensus identifiers connect if they
+// agree on the chain up to their heads, even if they schedule different forks
+// past them, and that the older peers connect regardless.
+func TestHandshakeConsensusID(t *testing.T) {
+	forked := *params.TestnetChainConfig
+	forked.BIP5Block = big.NewInt(100)
+
+	genesis := common.Hash{0xff}
+	tests := []struct {
+		version       int
+		local, remote *params.ChainConfig
+		head          uint64
+		fail          bool
+	}{
+		{ber64, params.TestnetChainConfig, params.TestnetChainConfig, 0, false},
+		{ber64, params.TestnetChainConfig, &forked, 0, false},
+		{ber64, params.TestnetChainConfig, &forked, 100, true},
+		{ber64, params.TestnetChainConfig, params.MainnetChainConfig, 0, true},
+		{ber63, params.TestnetChainConfig, params.MainnetChainConfig, 0, false},
+	}
+	for i, tt := range tests {
+		app, net := p2p.MsgPipe()
+		local := newPeer(tt.version, p2p.NewPeer(enode.ID{1}, "remote", nil), app)
+		remote := newPeer(tt.version, p2p.NewPeer(enode.ID{2}, "local", nil), net)
+
+		handshake := func(p *peer, config *params.ChainConfig) error {
+			filter := func(id params.ConsensusID) error { return config.CheckConsensusID(tt.head, id) }
+			return p.Handshake(1, big.NewInt(1), genesis, genesis, config.ConsensusID(tt.head), filter)
+		}
+		errc := make(chan error, 2)
+		go func() { errc <- handshake(local, tt.local) }()
+		go func() { errc <- handshake(remote, tt.remote) }()
+		for j := 0; j < 2; j++ {
+			if err := <-errc; (err != nil) != tt.fail {
+				t.Errorf("test %d: handshake error mismatch: have %v, want failure %v", i, err, tt.fail)
+			}
+		}
+		app.Close()
+		net.Close()
+	}
+}
+
+// Tests that the peers not advertising their consensus parameters are warned
+// about once, not on every connection.
+func TestHandshakeLegacyWarning(t *testing.T) {
+	recorder := new(warnRecorder)
+	log.Root().SetHandler(recorder)
+	defer log.Root().SetHandler(log.DiscardHandler())
+
+	pm, teardown := newTestProtocolManager(t, func(*params.ChainConfig) {})
+	defer teardown()
+
+	var (
+		genesis = pm.blockchain.Genesis().Hash()
+		id      = pm.chainconfig.ConsensusID(0)
+		td      = pm.blockchain.GetTd(genesis, 0)
+	)
+	for i := 0; i < 3; i++ {
+		app, net := p2p.MsgPipe()
+		local := pm.newPeer(ber63, p2p.NewPeer(enode.ID{byte(i + 1)}, "remote", nil), app)
+
+		errc := make(chan error, 1)
+		go func() { errc <- pm.handle(local) }()
+		remote := newPeer(ber63, p2p.NewPeer(enode.ID{0xff}, "local", nil), net)
+		if err := remote.Handshake(1, td, genesis, genesis, id, nil); err != nil {
+			t.Fatalf("connection %d: handshake failed: %v", i, err)
+		}
+		net.Close()
+		<-errc
+		app.Close()
+	}
+	if have := recorder.count("Peers before berith/64 don't advertise their consensus parameters, they can't be checked"); have != 1 {
+		t.Errorf("warnings logged: have %d, want 1", have)
+	}
+}
@@ -0,0 +1,330 @@
+package berith
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/core/rawdb"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/metrics"
+	"github.com/BerithFoundation/berith-chain/rlp"
+)
+
+// stakeIntentCheckInterval is the time between two checks of the journaled
+// stake transactions.
+const stakeIntentCheckInterval = time.Minute
+
+var (
+	stakeIntentPrefix   = []byte("berith-intent-") // stakeIntentPrefix + tx hash -> RLP(stakeIntent)
+	stakeIntentIndexKey = []byte("berith-intents") // RLP([]common.Hash) of the journaled transactions
+
+	lostIntentCounter = metrics.NewRegisteredCounter("berith/intents/lost", nil)
+
+	// errUnknownIntent is returned if a transaction isn't in the intent journal,
+	// as it was never submitted locally or expired after being mined.
+	errUnknownIntent = errors.New("unknown stake intent")
+
+	// errIntentMined is returned when rebroadcasting an intent already mined.
+	errIntentMined = errors.New("stake intent already mined")
+)
+
+// Statuses of a stake intent.
+const (
+	intentPending = "pending" // Waiting in the transaction pool
+	intentMined   = "mined"   // Included in a canonical block
+	intentLost    = "lost"    // Neither in the transaction pool nor mined
+)
+
+// stakeIntent is a stake or unstake transaction submitted through the local
+// node, as stored in the intent journal.
+type stakeIntent struct {
+	Tx        *types.Transaction
+	From      common.Address
+	Submitted uint64 // Unix time the transaction was submitted at
+	Mined     uint64 // Unix time the transaction was first seen mined, 0 if not
+	Lost      bool   // Whether the transaction was found lost since submitted
+}
+
+// StakeIntent is a stake transaction submitted through the local node which
+// isn't mined yet.
+type StakeIntent struct {
+	ID        common.Hash    `json:"id"` // Hash of the transaction
+	From      common.Address `json:"from"`
+	Nonce     hexutil.Uint64 `json:"nonce"`
+	Value     *hexutil.Big   `json:"value"`
+	Direction string         `json:"direction"` // "stake" or "unstake"
+	Submitted hexutil.Uint64 `json:"submitted"` // Unix time the transaction was submitted at
+	Status    string         `json:"status"`
+}
+
+// isStakeTransaction returns whether the transaction moves funds from or to the
+// stake of its sender.
+func isStakeTransaction(tx *types.Transaction) bool {
+	return tx.Base() == types.Stake || tx.Target() == types.Stake
+}
+
+// intentPool is the part of the transaction pool the intent journal checks the
+// pending transactions in and rebroadcasts the lost ones through.
+type intentPool interface {
+	Get(hash common.Hash) *types.Transaction
+	AddLocal(tx *types.Transaction) error
+}
+
+/*
+[BERITH]
+intentJournal records the stake and unstake transactions submitted through the
+local node, and checks in the background that each of them eventually lands in
+a canonical block. Transactions dropped by the transaction pool, e.g. evicted
+on a restart or replaced by fee, are warned about and counted as lost, so that
+they can be rebroadcast before the election status changes epochs later.
+*/
+type intentJournal struct {
+	db      berithdb.Database // Database holding the journal and the transaction lookups
+	pool    intentPool
+	horizon time.Duration    // Time mined intents are kept for
+	now     func() time.Time // Clock of the journal, replaceable for tests
+
+	lock sync.Mutex // Serialises the updates of the journaled intents
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newIntentJournal creates a journal of the stake transactions in the given
+// database, keeping the mined ones for the given horizon.
+func newIntentJournal(db berithdb.Database, pool intentPool, horizon time.Duration) *intentJournal {
+	return &intentJournal{
+		db:      db,
+		pool:    pool,
+		horizon: horizon,
+		now:     time.Now,
+		quit:    make(chan struct{}),
+	}
+}
+
+// stakeIntentKey = stakeIntentPrefix + tx hash
+func stakeIntentKey(hash common.Hash) []byte {
+	return append(append([]byte{}, stakeIntentPrefix...), hash.Bytes()...)
+}
+
+// record journals the transaction if it is a stake transaction.
+func (j *intentJournal) record(tx *types.Transaction, from common.Address) {
+	if !isStakeTransaction(tx) {
+		return
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	index := j.index()
+	if _, err := j.read(tx.Hash()); err != nil {
+		index = append(index, tx.Hash())
+	}
+	j.write(&stakeIntent{Tx: tx, From: from, Submitted: uint64(j.now().Unix())})
+	j.writeIndex(index)
+}
+
+// index retrieves the hashes of the journaled transactions.
+func (j *intentJournal) index() []common.Hash {
+	var hashes []common.Hash
+	if blob, err := j.db.Get(stakeIntentIndexKey); err == nil {
+		if err := rlp.DecodeBytes(blob, &hashes); err != nil {
+			log.Error("Failed to decode stake intent index", "err", err)
+		}
+	}
+	return hashes
+}
+
+// writeIndex stores the hashes of the journaled transactions.
+func (j *intentJournal) writeIndex(hashes []common.Hash) {
+	blob, err := rlp.EncodeToBytes(hashes)
+	if err != nil {
+		log.Error("Failed to encode stake intent index", "err", err)
+		return
+	}
+	if err := j.db.Put(stakeIntentIndexKey, blob); err != nil {
+		log.Error("Failed to store stake intent index", "err", err)
+	}
+}
+
+// write stores the intent in the journal.
+func (j *intentJournal) write(intent *stakeIntent) {
+	blob, err := rlp.EncodeToBytes(intent)
+	if err != nil {
+		log.Error("Failed to encode stake intent", "hash", intent.Tx.Hash(), "err", err)
+		return
+	}
+	if err := j.db.Put(stakeIntentKey(intent.Tx.Hash()), blob); err != nil {
+		log.Error("Failed to store stake intent", "hash", intent.Tx.Hash(), "err", err)
+	}
+}
+
+// read retrieves the intent of the given transaction from the journal.
+func (j *intentJournal) read(hash common.Hash) (*stakeIntent, error) {
+	blob, err := j.db.Get(stakeIntentKey(hash))
+	if err != nil {
+		return nil, errUnknownIntent
+	}
+	intent := new(stakeIntent)
+	if err := rlp.DecodeBytes(blob, intent); err != nil {
+		return nil, err
+	}
+	return intent, nil
+}
+
+// intents retrieves all the journaled intents, ordered by submission.
+func (j *intentJournal) intents() []*stakeIntent {
+	var intents []*stakeIntent
+	for _, hash := range j.index() {
+		intent, err := j.read(hash)
+		if err != nil {
+			log.Error("Failed to read stake intent", "hash", hash, "err", err)
+			continue
+		}
+		intents = append(intents, intent)
+	}
+	return intents
+}
+
+// mined returns whether the transaction is included in a canonical block.
+func (j *intentJournal) mined(hash common.Hash) bool {
+	blockHash, number, _ := rawdb.ReadTxLookupEntry(j.db, hash)
+	return blockHash != (common.Hash{}) && rawdb.ReadCanonicalHash(j.db, number) == blockHash
+}
+
+// status returns the status of the intent, as last checked.
+func (intent *stakeIntent) status() string {
+	switch {
+	case intent.Mined != 0:
+		return intentMined
+	case intent.Lost:
+		return intentLost
+	default:
+		return intentPending
+	}
+}
+
+// check updates the status of every journaled intent, warning about the newly
+// lost ones and dropping the ones mined longer than the horizon ago.
+func (j *intentJournal) check() {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	var (
+		now  = j.now()
+		kept []common.Hash
+	)
+	intents := j.intents()
+	for _, intent := range intents {
+		hash := intent.Tx.Hash()
+		switch {
+		case j.mined(hash):
+			if intent.Mined == 0 {
+				intent.Mined, intent.Lost = uint64(now.Unix()), false
+				j.write(intent)
+			} else if now.Sub(time.Unix(int64(intent.Mined), 0)) >= j.horizon {
+				if err := j.db.Delete(stakeIntentKey(hash)); err != nil {
+					log.Error("Failed to delete stake intent", "hash", hash, "err", err)
+				}
+				continue
+			}
+		case j.pool.Get(hash) != nil:
+			if intent.Mined != 0 || intent.Lost {
+				intent.Mined, intent.Lost = 0, false
+				j.write(intent)
+			}
+		default:
+			if intent.Mined != 0 || !intent.Lost {
+				log.Warn("Stake transaction lost", "hash", hash, "from", intent.From, "nonce", intent.Tx.Nonce(), "submitted", time.Unix(int64(intent.Submitted), 0))
+				lostIntentCounter.Inc(1)
+
+				intent.Mined, intent.Lost = 0, true
+				j.write(intent)
+			}
+		}
+		kept = append(kept, hash)
+	}
+	if len(kept) != len(intents) {
+		j.writeIndex(kept)
+	}
+}
+
+// outstanding returns the intents not mined as of the last check.
+func (j *intentJournal) outstanding() []*StakeIntent {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	outstanding := make([]*StakeIntent, 0)
+	for _, intent := range j.intents() {
+		if intent.Mined != 0 {
+			continue
+		}
+		direction := "stake"
+		if intent.Tx.Base() == types.Stake {
+			direction = "unstake"
+		}
+		outstanding = append(outstanding, &StakeIntent{
+			ID:        intent.Tx.Hash(),
+			From:      intent.From,
+			Nonce:     hexutil.Uint64(intent.Tx.Nonce()),
+			Value:     (*hexutil.Big)(new(big.Int).Set(intent.Tx.Value())),
+			Direction: direction,
+			Submitted: hexutil.Uint64(intent.Submitted),
+			Status:    intent.status(),
+		})
+	}
+	return outstanding
+}
+
+// rebroadcast resubmits the transaction of a journaled intent to the transaction
+// pool, which announces it to the peers again.
+func (j *intentJournal) rebroadcast(hash common.Hash) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	intent, err := j.read(hash)
+	if err != nil {
+		return err
+	}
+	if j.mined(hash) {
+		return errIntentMined
+	}
+	if err := j.pool.AddLocal(intent.Tx); err != nil {
+		return err
+	}
+	intent.Mined, intent.Lost = 0, false
+	j.write(intent)
+
+	log.Info("Rebroadcast stake transaction", "hash", hash, "from", intent.From, "nonce", intent.Tx.Nonce())
+	return nil
+}
+
+// start checks the journaled intents periodically until stopped.
+func (j *intentJournal) start() {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(stakeIntentCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			j.check()
+			select {
+			case <-ticker.C:
+			case <-j.quit:
+				return
+			}
+		}
+	}()
+}
+
+// stop terminates the checks of the journaled intents.
+func (j *intentJournal) stop() {
+	close(j.quit)
+	j.wg.Wait()
+}
@@ -0,0 +1,119 @@
+package berith
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/core/rawdb"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/metrics"
+)
+
+// testIntentPool is a transaction pool dropping transactions on demand.
+type testIntentPool struct {
+	txs map[common.Hash]*types.Transaction
+}
+
+func (p *testIntentPool) Get(hash common.Hash) *types.Transaction { return p.txs[hash] }
+
+func (p *testIntentPool) AddLocal(tx *types.Transaction) error {
+	p.txs[tx.Hash()] = tx
+	return nil
+}
+
+// Tests that the stake transactions dropped by the transaction pool are detected
+// as lost once, that they are pending again once rebroadcast, and that the mined
+// ones are dropped from the journal after the horizon.
+func TestIntentJournal(t *testing.T) {
+	defer func(counter metrics.Counter) { lostIntentCounter = counter }(lostIntentCounter)
+	lostIntentCounter = metrics.NewCounterForced()
+
+	var (
+		db    = berithdb.NewMemDatabase()
+		pool  = &testIntentPool{txs: make(map[common.Hash]*types.Transaction)}
+		now   = time.Unix(1000000, 0)
+		from  = common.Address{1}
+		stake = types.NewTransaction(0, from, big.NewInt(100), 21000, big.NewInt(1), nil, types.Main, types.Stake)
+		stop  = types.NewTransaction(1, from, new(big.Int), 21000, big.NewInt(1), nil, types.Stake, types.Main)
+		plain = types.NewTransaction(2, from, big.NewInt(5), 21000, big.NewInt(1), nil, types.Main, types.Main)
+	)
+	journal := newIntentJournal(db, pool, time.Hour)
+	journal.now = func() time.Time { return now }
+
+	for _, tx := range []*types.Transaction{stake, stop, plain} {
+		pool.AddLocal(tx)
+		journal.record(tx, from)
+	}
+	statuses := func() map[common.Hash]string {
+		statuses := make(map[common.Hash]string)
+		for _, intent := range journal.outstanding() {
+			statuses[intent.ID] = intent.Status
+		}
+		return statuses
+	}
+	journal.check()
+	if have := statuses(); len(have) != 2 || have[stake.Hash()] != intentPending || have[stop.Hash()] != intentPending {
+		t.Fatalf("intents mismatch after submission: %v", have)
+	}
+	if intents := journal.outstanding(); intents[0].Direction != "stake" || intents[1].Direction != "unstake" {
+		t.Errorf("directions mismatch: have %s, %s", intents[0].Direction, intents[1].Direction)
+	}
+	// The pool drops the stake transaction, which is reported lost once
+	delete(pool.txs, stake.Hash())
+	journal.check()
+	journal.check()
+	if have := statuses()[stake.Hash()]; have != intentLost {
+		t.Errorf("dropped intent status mismatch: have %s, want %s", have, intentLost)
+	}
+	if count := lostIntentCounter.Count(); count != 1 {
+		t.Errorf("lost intents mismatch: have %d, want 1", count)
+	}
+	// The lost intent is still known after a restart, and rebroadcast to the pool
+	journal = newIntentJournal(db, pool, time.Hour)
+	journal.now = func() time.Time { return now }
+	if err := journal.rebroadcast(stake.Hash()); err != nil {
+		t.Fatalf("failed to rebroadcast intent: %v", err)
+	}
+	if pool.Get(stake.Hash()) == nil {
+		t.Errorf("rebroadcast transaction not in pool")
+	}
+	journal.check()
+	if have := statuses()[stake.Hash()]; have != intentPending {
+		t.Errorf("rebroadcast intent status mismatch: have %s, want %s", have, intentPending)
+	}
+	if err := journal.rebroadcast(plain.Hash()); err != errUnknownIntent {
+		t.Errorf("plain transaction rebroadcast error mismatch: have %v, want %v", err, errUnknownIntent)
+	}
+	// The unstake transaction is mined, and only dropped after the horizon
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, []*types.Transaction{stop}, nil, nil)
+	rawdb.WriteCanonicalHash(db, block.Hash(), 1)
+	rawdb.WriteTxLookupEntries(db, block)
+	delete(pool.txs, stop.Hash())
+
+	journal.check()
+	if have := statuses(); len(have) != 1 || have[stake.Hash()] != intentPending {
+		t.Errorf("intents mismatch after mining: %v", have)
+	}
+	if err := journal.rebroadcast(stop.Hash()); err != errIntentMined {
+		t.Errorf("mined intent rebroadcast error mismatch: have %v, want %v", err, errIntentMined)
+	}
+	now = now.Add(time.Hour - time.Second)
+	journal.check()
+	if _, err := journal.read(stop.Hash()); err != nil {
+		t.Errorf("mined intent dropped before the horizon: %v", err)
+	}
+	now = now.Add(time.Second)
+	journal.check()
+	if _, err := journal.read(stop.Hash()); err != errUnknownIntent {
+		t.Errorf("mined intent kept after the horizon: %v", err)
+	}
+	if index := journal.index(); len(index) != 1 || index[0] != stake.Hash() {
+		t.Errorf("index mismatch after expiry: %v", index)
+	}
+	if count := lostIntentCounter.Count(); count != 1 {
+		t.Errorf("lost intents mismatch: have %d, want 1", count)
+	}
+}
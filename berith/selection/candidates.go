@@ -11,7 +11,7 @@ import (
 )
 
 const (
-	MaxMiner = 10000
+	MaxMiner = 10000 // Default maximum number of candidates ranked by an election
 )
 
 var (
@@ -19,6 +19,15 @@ var (
 	minElectScore = int64(10000)
 )
 
+// MaxCandidates returns the maximum number of candidates ranked by an election,
+// MaxMiner unless configured otherwise.
+func MaxCandidates(config *params.BSRRConfig) int {
+	if config == nil || config.MaxCandidates == 0 {
+		return MaxMiner
+	}
+	return int(config.MaxCandidates)
+}
+
 type Candidates struct {
 	selections []Candidate
 	total      uint64 // Total Selection Point: Staking  + Advantage
@@ -49,6 +58,19 @@ func (cs *Candidates) Add(c Candidate) {
 	cs.selections = append(cs.selections, c)
 }
 
+// equalWeights returns the candidates with a selection point of one each, in
+// the same order.
+func (cs *Candidates) equalWeights() *Candidates {
+	equal := NewCandidates()
+	for _, c := range cs.selections {
+		equal.Add(Candidate{
+			point:   1,
+			address: c.address,
+		})
+	}
+	return equal
+}
+
 /*
 [Berith]
 The block constructor is selected and the result is returned in VoteResults.
@@ -79,7 +101,8 @@ func (cs *Candidates) selectBlockCreator(config *params.ChainConfig, number uint
 		return result
 	}
 
-	for count := 1; count <= MaxMiner && queue.front != queue.rear; count++ {
+	max := MaxCandidates(config.Bsrr)
+	for count := 1; count <= max && queue.front != queue.rear; count++ {
 		r, err := queue.dequeue()
 		if err != nil {
 			fmt.Println(err)
@@ -98,6 +121,8 @@ func (cs *Candidates) selectBlockCreator(config *params.ChainConfig, number uint
 /*
 [Berith]
 The block constructor is selected and the result is returned in VoteResults.
+Every candidate is ranked unless the maximum number of candidates is configured,
+as the rank of the candidates beyond it is never allowed to create blocks.
 */
 func (cs *Candidates) selectBIP3BlockCreator(config *params.ChainConfig, number uint64) VoteResults {
 	fmt.Println("Candidates.selectBIP3BlockCreator () 호출 / Canditates : ")
@@ -110,10 +135,15 @@ func (cs *Candidates) selectBIP3BlockCreator(config *params.ChainConfig, number
 	electScoreGap := (maxElectScore - minElectScore) / int64(len(cs.selections))
 	rank := 1
 
+	max := len(cs.selections)
+	if config.Bsrr != nil && config.Bsrr.MaxCandidates != 0 && int(config.Bsrr.MaxCandidates) < max {
+		max = int(config.Bsrr.MaxCandidates)
+	}
+
 	// Block number is used as a seed so that all nodes have the same random value
 	rand.Seed(cs.GetSeed(config, number))
 
-	for len(cs.selections) > 0 {
+	for len(cs.selections) > 0 && rank <= max {
 		// The random number below the total elected point is taken and used as the number to select the elected person.
 		electedNumber := uint64(rand.Int63n(int64(cs.total))) // 산출되는 랜덤값에 따라 결과가 달라짐
 
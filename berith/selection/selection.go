@@ -10,6 +10,8 @@ import (
 	"math/big"
 	"sort"
 
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/metrics"
 	"github.com/BerithFoundation/berith-chain/params"
 
 	"github.com/BerithFoundation/berith-chain/berith/staking"
@@ -18,6 +20,10 @@ import (
 	"github.com/BerithFoundation/berith-chain/common"
 )
 
+// equalWeightsCounter counts the elections falling back to equal weights as none
+// of the stakers had a selection point.
+var equalWeightsCounter = metrics.NewRegisteredCounter("selection/equalweights", nil)
+
 /*
 [BERITH]
 Entry function to elect Block Creator
@@ -63,6 +69,19 @@ func SelectBlockCreator(config *params.ChainConfig, number uint64, hash common.H
 		})
 	}
 
+	/*
+		[Berith]
+		Without any selection point, e.g. on a state restored without the points, the
+		elected number can't be drawn and every staker is elected with equal chance.
+		No block was ever elected this way before: drawing from a zero total panics,
+		except for a single staker before BIP3, who is elected alike with equal weights.
+	*/
+	if cddts.total == 0 {
+		log.Warn("Stakers have no selection points, electing them with equal weights", "number", number, "stakers", len(list))
+		equalWeightsCounter.Inc(1)
+		cddts = cddts.equalWeights()
+	}
+
 	// Call block creator function
 	if config.IsBIP3(big.NewInt(int64(number))) {
 		result = cddts.selectBIP3BlockCreator(config, number)
@@ -161,3 +161,66 @@ func TestScore(t *testing.T) {
 	}
 
 }
+
+// Tests that stakers without any selection point, as on a state restored without
+// the points, are elected as if every staker had a point of one, before and
+// after BIP3.
+func TestSelectBlockCreatorZeroPoints(t *testing.T) {
+	configs := []*params.ChainConfig{
+		{BIP2Block: big.NewInt(0)},
+		{BIP2Block: big.NewInt(0), BIP3Block: big.NewInt(0)},
+	}
+	for i, config := range configs {
+		var (
+			zero, _  = state.New(common.Hash{}, state.NewDatabase(berithdb.NewMemDatabase()))
+			equal, _ = state.New(common.Hash{}, state.NewDatabase(berithdb.NewMemDatabase()))
+			stks     = staking.NewStakers()
+		)
+		for j := 0; j < 5; j++ {
+			addr := common.BigToAddress(big.NewInt(int64(j)))
+			stks.Put(addr)
+			equal.SetPoint(addr, big.NewInt(1))
+		}
+		have := SelectBlockCreator(config, 100, common.Hash{}, stks, zero)
+		want := SelectBlockCreator(config, 100, common.Hash{}, stks, equal)
+		if len(have) != 5 {
+			t.Fatalf("config %d: elected count mismatch: have %d, want 5", i, len(have))
+		}
+		for addr, result := range want {
+			if have[addr].Rank != result.Rank || have[addr].Score.Cmp(result.Score) != 0 {
+				t.Errorf("config %d: %x: result mismatch: have [%d, %v], want [%d, %v]", i, addr, have[addr].Rank, have[addr].Score, result.Rank, result.Score)
+			}
+		}
+	}
+}
+
+// Tests that the elections rank no more candidates than the configured maximum,
+// before and after BIP3, without changing the ranks of the ones elected.
+func TestSelectBlockCreatorMaxCandidates(t *testing.T) {
+	st, _ := state.New(common.Hash{}, state.NewDatabase(berithdb.NewMemDatabase()))
+	stks := staking.NewStakers()
+	for i := 0; i < 20; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i)))
+		stks.Put(addr)
+		st.SetPoint(addr, big.NewInt(int64(100*(i+1))))
+	}
+	for i, bip3 := range []*big.Int{nil, big.NewInt(0)} {
+		var (
+			uncapped = &params.ChainConfig{BIP2Block: big.NewInt(0), BIP3Block: bip3, Bsrr: &params.BSRRConfig{}}
+			capped   = &params.ChainConfig{BIP2Block: big.NewInt(0), BIP3Block: bip3, Bsrr: &params.BSRRConfig{MaxCandidates: 5}}
+		)
+		want := SelectBlockCreator(uncapped, 100, common.Hash{}, stks, st)
+		have := SelectBlockCreator(capped, 100, common.Hash{}, stks, st)
+		if len(want) != 20 {
+			t.Fatalf("config %d: uncapped elected count mismatch: have %d, want 20", i, len(want))
+		}
+		if len(have) != 5 {
+			t.Fatalf("config %d: capped elected count mismatch: have %d, want 5", i, len(have))
+		}
+		for addr, result := range have {
+			if result.Rank > 5 || want[addr].Rank != result.Rank || want[addr].Score.Cmp(result.Score) != 0 {
+				t.Errorf("config %d: %x: result mismatch: have [%d, %v], want [%d, %v]", i, addr, result.Rank, result.Score, want[addr].Rank, want[addr].Score)
+			}
+		}
+	}
+}
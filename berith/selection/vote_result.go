@@ -1,9 +1,15 @@
 package selection
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
 )
 
 /*
@@ -12,7 +18,142 @@ Object that stores voting results for election
 */
 type VoteResults map[common.Address]VoteResult
 
+/*
+[Berith]
+VoteResult is the outcome of the election for a single staker. Its canonical JSON
+form, shared by every API returning election results, is
+
+	{"rank": 1, "score": "0x4c4b40"}
+
+with the rank as an integer and the score as a 0x-prefixed hex quantity, so that
+JSON parsers limited to float64 numbers don't round it. Consumers of the former
+form, the score as a plain JSON number, have to decode it as a hex quantity.
+*/
 type VoteResult struct {
-	Score *big.Int `json:"score"`
-	Rank  int      `json:"rank"`
+	Score *big.Int
+	Rank  int
+}
+
+// voteResultJSON is the canonical JSON form of a VoteResult.
+type voteResultJSON struct {
+	Rank  int          `json:"rank"`
+	Score *hexutil.Big `json:"score"`
+}
+
+// MarshalJSON encodes the vote result in its canonical JSON form.
+func (r VoteResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(voteResultJSON{Rank: r.Rank, Score: scoreJSON(r.Score)})
+}
+
+// UnmarshalJSON decodes a vote result from its canonical JSON form.
+func (r *VoteResult) UnmarshalJSON(input []byte) error {
+	var dec voteResultJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if dec.Score == nil {
+		return errors.New("missing required field 'score' for VoteResult")
+	}
+	r.Rank, r.Score = dec.Rank, dec.Score.ToInt()
+	return nil
+}
+
+/*
+[Berith]
+Vote is the vote result of a staker along with its address, the element of the
+canonical JSON form of the election results:
+
+	{"address": "Bx...", "rank": 1, "score": "0x4c4b40"}
+
+with the address checksummed as per EIP-55 behind the Berith address prefix.
+*/
+type Vote struct {
+	Address common.Address
+	Rank    int
+	Score   *big.Int
+}
+
+// voteJSON is the canonical JSON form of a Vote.
+type voteJSON struct {
+	Address string       `json:"address"`
+	Rank    int          `json:"rank"`
+	Score   *hexutil.Big `json:"score"`
+}
+
+// MarshalJSON encodes the vote in its canonical JSON form.
+func (v Vote) MarshalJSON() ([]byte, error) {
+	return json.Marshal(voteJSON{Address: v.Address.Hex(), Rank: v.Rank, Score: scoreJSON(v.Score)})
+}
+
+// UnmarshalJSON decodes a vote from its canonical JSON form, accepting addresses
+// regardless of their case and of either the Bx or 0x prefix.
+func (v *Vote) UnmarshalJSON(input []byte) error {
+	var dec voteJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	var addr common.Address
+	if err := addr.UnmarshalText([]byte(dec.Address)); err != nil {
+		return fmt.Errorf("invalid address %q for Vote: %v", dec.Address, err)
+	}
+	if dec.Score == nil {
+		return errors.New("missing required field 'score' for Vote")
+	}
+	v.Address, v.Rank, v.Score = addr, dec.Rank, dec.Score.ToInt()
+	return nil
+}
+
+// Sorted returns the vote results as a list of votes ordered by ascending rank,
+// the stakers of the same rank by address.
+func (r VoteResults) Sorted() []Vote {
+	votes := make([]Vote, 0, len(r))
+	for addr, result := range r {
+		votes = append(votes, Vote{Address: addr, Rank: result.Rank, Score: result.Score})
+	}
+	sort.Slice(votes, func(i, j int) bool {
+		if votes[i].Rank != votes[j].Rank {
+			return votes[i].Rank < votes[j].Rank
+		}
+		return bytes.Compare(votes[i].Address[:], votes[j].Address[:]) < 0
+	})
+	return votes
+}
+
+/*
+[Berith]
+VoteResultsJSON wraps VoteResults to encode them in their canonical JSON form, a
+list of votes sorted by rank, so that the same results always encode the same.
+The plain VoteResults map encodes as an object keyed by lowercase address in
+random order, which is not to be returned by the APIs.
+*/
+type VoteResultsJSON VoteResults
+
+// MarshalJSON encodes the vote results as a list of votes sorted by rank.
+func (r VoteResultsJSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(VoteResults(r).Sorted())
+}
+
+// UnmarshalJSON decodes the vote results from a list of votes.
+func (r *VoteResultsJSON) UnmarshalJSON(input []byte) error {
+	var votes []Vote
+	if err := json.Unmarshal(input, &votes); err != nil {
+		return err
+	}
+	results := make(VoteResultsJSON, len(votes))
+	for _, vote := range votes {
+		if _, ok := results[vote.Address]; ok {
+			return fmt.Errorf("duplicate vote of %s", vote.Address.Hex())
+		}
+		results[vote.Address] = VoteResult{Score: vote.Score, Rank: vote.Rank}
+	}
+	*r = results
+	return nil
+}
+
+// scoreJSON returns the score as a hex quantity, zero if it is missing.
+func scoreJSON(score *big.Int) *hexutil.Big {
+	if score == nil {
+		return (*hexutil.Big)(new(big.Int))
+	}
+	return (*hexutil.Big)(score)
 }
@@ -0,0 +1,89 @@
+package selection
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/common"
+)
+
+// voteResultsFixture are the vote results encoded in testdata/vote_results.json.
+var voteResultsFixture = VoteResults{
+	common.HexToAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"): {Score: big.NewInt(4002000), Rank: 2},
+	common.HexToAddress("0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"): {Score: big.NewInt(5000000), Rank: 1},
+	common.HexToAddress("0xdbf03b407c01e7cd3cbea99509d93f8dddc8c6fb"): {Score: new(big.Int).Exp(big.NewInt(10), big.NewInt(24), nil), Rank: 3},
+}
+
+// Tests that vote results encode to the canonical JSON form of the golden fixture,
+// sorted by rank regardless of the map order, and decode back from it.
+func TestVoteResultsJSON(t *testing.T) {
+	golden, err := ioutil.ReadFile(filepath.Join("testdata", "vote_results.json"))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		blob, err := json.MarshalIndent(VoteResultsJSON(voteResultsFixture), "", "\t")
+		if err != nil {
+			t.Fatalf("failed to encode vote results: %v", err)
+		}
+		if !bytes.Equal(blob, bytes.TrimSpace(golden)) {
+			t.Fatalf("encoding mismatch:\nhave %s\nwant %s", blob, golden)
+		}
+	}
+	var decoded VoteResultsJSON
+	if err := json.Unmarshal(golden, &decoded); err != nil {
+		t.Fatalf("failed to decode fixture: %v", err)
+	}
+	if !reflect.DeepEqual(VoteResults(decoded), voteResultsFixture) {
+		t.Errorf("decoding mismatch: have %v, want %v", decoded, voteResultsFixture)
+	}
+	// A staker can't be voted twice
+	if err := json.Unmarshal([]byte(`[{"address":"Bx0000000000000000000000000000000000000001","rank":1,"score":"0x1"},{"address":"Bx0000000000000000000000000000000000000001","rank":2,"score":"0x1"}]`), &decoded); err == nil {
+		t.Error("decoded duplicate votes")
+	}
+}
+
+// Tests the canonical JSON form of a single vote result and vote, and that their
+// decoding rejects a missing score or a malformed address.
+func TestVoteJSON(t *testing.T) {
+	result := VoteResult{Score: big.NewInt(5000000), Rank: 1}
+	if blob, err := json.Marshal(result); err != nil || string(blob) != `{"rank":1,"score":"0x4c4b40"}` {
+		t.Errorf("vote result encoding mismatch: have %s, %v", blob, err)
+	}
+	if blob, err := json.Marshal(VoteResult{Rank: 1}); err != nil || string(blob) != `{"rank":1,"score":"0x0"}` {
+		t.Errorf("missing score encoding mismatch: have %s, %v", blob, err)
+	}
+	if err := json.Unmarshal([]byte(`{"rank":1}`), &result); err == nil {
+		t.Error("decoded vote result without score")
+	}
+	vote := Vote{Address: common.HexToAddress("0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"), Rank: 1, Score: big.NewInt(5000000)}
+	blob, err := json.Marshal(vote)
+	if err != nil || string(blob) != `{"address":"BxfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359","rank":1,"score":"0x4c4b40"}` {
+		t.Errorf("vote encoding mismatch: have %s, %v", blob, err)
+	}
+	for _, input := range []string{
+		`{"address":"BxfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359","rank":1,"score":"0x4c4b40"}`,
+		`{"address":"0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359","rank":1,"score":"0x4c4b40"}`,
+	} {
+		var decoded Vote
+		if err := json.Unmarshal([]byte(input), &decoded); err != nil || !reflect.DeepEqual(decoded, vote) {
+			t.Errorf("%s: decoding mismatch: have %v, %v, want %v", input, decoded, err, vote)
+		}
+	}
+	for _, input := range []string{
+		`{"address":"Bx01","rank":1,"score":"0x1"}`,
+		`{"rank":1,"score":"0x1"}`,
+		`{"address":"Bx0000000000000000000000000000000000000001","rank":1}`,
+		`{"address":"Bx0000000000000000000000000000000000000001","rank":1,"score":5}`,
+	} {
+		var decoded Vote
+		if err := json.Unmarshal([]byte(input), &decoded); err == nil {
+			t.Errorf("%s: decoded invalid vote", input)
+		}
+	}
+}
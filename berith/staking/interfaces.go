@@ -38,6 +38,7 @@ Interface for stakingDB
 type DataBase interface {
 	GetStakers(key string) (Stakers, error)
 	Commit(key string, stks Stakers) error
+	Flush() error
 	NewStakers() Stakers
 	Close()
 	Clean(chain consensus.ChainReader, header *types.Header) error
@@ -0,0 +1,153 @@
+package staking
+
+import (
+	"fmt"
+
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/rlp"
+)
+
+/*
+[Berith]
+SchemaVersion is the layout version of the staking database written by this node.
+The staking databases written before the version was recorded are of version 0.
+*/
+const SchemaVersion = 1
+
+// schemaVersionKey is the key of the schema version record, which can't collide
+// with the block hashes keying the staking lists.
+var schemaVersionKey = []byte("StakingSchemaVersion")
+
+// SchemaError is returned when opening a staking database of a newer schema than
+// the node supports, which it would misread or corrupt.
+type SchemaError struct {
+	Stored    uint64 // Schema version of the database
+	Supported uint64 // Newest schema version supported by the node
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("staking database schema version %d is newer than the supported version %d, upgrade the node or remove the staking database to resync it", e.Stored, e.Supported)
+}
+
+/*
+[Berith]
+Migration upgrades the staking database from the previous schema version to its
+own. Up stages the changes in the batch without writing it, and describes them.
+The batch is written together with the new schema version, or discarded on a
+dry run.
+*/
+type Migration struct {
+	Version uint64 // Schema version the migration upgrades to
+	Name    string // Short description of the layout change
+	Up      func(db *berithdb.LDBDatabase, batch berithdb.Batch) ([]string, error)
+}
+
+// MigrationResult reports the changes of a migration applied or, on a dry run,
+// that would be applied.
+type MigrationResult struct {
+	Version uint64
+	Name    string
+	Changes []string
+}
+
+// migrations are the upgrades of the staking database, ordered by version.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "record the schema version",
+		Up: func(db *berithdb.LDBDatabase, batch berithdb.Batch) ([]string, error) {
+			// The version record is written along with every migration
+			return nil, nil
+		},
+	},
+}
+
+// readSchemaVersion returns the schema version of the staking database, and
+// whether the database is empty and thus of no version yet.
+func readSchemaVersion(db *berithdb.LDBDatabase) (uint64, bool, error) {
+	enc, err := db.Get(schemaVersionKey)
+	if err != nil {
+		it := db.NewIterator()
+		defer it.Release()
+		return 0, !it.First(), it.Error()
+	}
+	var version uint64
+	if err := rlp.DecodeBytes(enc, &version); err != nil {
+		return 0, false, fmt.Errorf("invalid staking database schema version: %v", err)
+	}
+	return version, false, nil
+}
+
+// writeSchemaVersion stages the schema version record in the batch.
+func writeSchemaVersion(batch berithdb.Putter, version uint64) error {
+	enc, err := rlp.EncodeToBytes(version)
+	if err != nil {
+		return err
+	}
+	return batch.Put(schemaVersionKey, enc)
+}
+
+/*
+[Berith]
+Upgrade the staking database to SchemaVersion by applying the migrations after
+its version in order, each in a batch written along with its version. A new
+database is only marked with the current version, and a database of a newer
+schema is refused. Nothing is written on a dry run, in which the migrations
+don't see the changes of the ones before them. Running the upgrade again is a
+no-op.
+*/
+func migrate(db *berithdb.LDBDatabase, dryRun bool) ([]MigrationResult, error) {
+	version, fresh, err := readSchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case version > SchemaVersion:
+		return nil, &SchemaError{Stored: version, Supported: SchemaVersion}
+	case fresh:
+		if dryRun {
+			return nil, nil
+		}
+		return nil, writeSchemaVersion(db, SchemaVersion)
+	}
+	var results []MigrationResult
+	for _, m := range migrations {
+		if m.Version <= version {
+			continue
+		}
+		batch := db.NewBatch()
+		changes, err := m.Up(db, batch)
+		if err != nil {
+			return results, fmt.Errorf("staking database migration to version %d failed: %v", m.Version, err)
+		}
+		changes = append(changes, fmt.Sprintf("set schema version %d", m.Version))
+		results = append(results, MigrationResult{Version: m.Version, Name: m.Name, Changes: changes})
+		if dryRun {
+			continue
+		}
+		if err := writeSchemaVersion(batch, m.Version); err != nil {
+			return results, err
+		}
+		if err := batch.Write(); err != nil {
+			return results, fmt.Errorf("staking database migration to version %d failed: %v", m.Version, err)
+		}
+		log.Info("Migrated staking database", "version", m.Version, "migration", m.Name)
+	}
+	return results, nil
+}
+
+/*
+[Berith]
+MigrateDB upgrades the staking database at the given path to SchemaVersion, or
+only reports the migrations it would apply on a dry run.
+*/
+func MigrateDB(path string, dryRun bool) ([]MigrationResult, error) {
+	db, err := berithdb.NewLDBDatabase(path, 16, 16)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return migrate(db, dryRun)
+}
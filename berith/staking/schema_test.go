@@ -0,0 +1,119 @@
+package staking
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+)
+
+// Tests that a new staking database is marked with the current schema version
+// and opened again as it is.
+func TestSchemaFreshCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stakingdb-schema-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 2; i++ {
+		db := new(StakingDB)
+		if err := db.CreateDB(dir, NewStakers); err != nil {
+			t.Fatalf("run %d: failed to create staking database: %v", i, err)
+		}
+		version, fresh, err := readSchemaVersion(db.stakeDB)
+		if err != nil || fresh || version != SchemaVersion {
+			t.Errorf("run %d: schema version mismatch: have %d (fresh %v, %v), want %d", i, version, fresh, err, SchemaVersion)
+		}
+		db.Close()
+	}
+	if results, err := MigrateDB(dir, false); err != nil || len(results) != 0 {
+		t.Errorf("migrations of a current database: have %v, %v", results, err)
+	}
+}
+
+// Tests that an unversioned staking database is reported by a dry run without
+// being changed, upgraded keeping its staking lists, and left alone afterwards.
+func TestSchemaUpgrade(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stakingdb-schema-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Write a staking list the way the nodes did before the schema version
+	ldb, err := berithdb.NewLDBDatabase(dir, 16, 16)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	v0 := &StakingDB{stakeDB: ldb, creator: NewStakers}
+	stakers := NewStakers()
+	stakers.Put(common.Address{1})
+	if err := v0.Commit("list", stakers); err != nil {
+		t.Fatalf("failed to commit staking list: %v", err)
+	}
+	ldb.Close()
+
+	results, err := MigrateDB(dir, true)
+	if err != nil {
+		t.Fatalf("failed to dry run migrations: %v", err)
+	}
+	if len(results) != 1 || results[0].Version != 1 || len(results[0].Changes) == 0 {
+		t.Errorf("dry run results mismatch: %+v", results)
+	}
+	if results, err := MigrateDB(dir, true); err != nil || len(results) != 1 {
+		t.Errorf("dry run changed the database: have %v, %v", results, err)
+	}
+	// Opening the database applies the migrations, which aren't applied again
+	db := new(StakingDB)
+	if err := db.CreateDB(dir, NewStakers); err != nil {
+		t.Fatalf("failed to open staking database: %v", err)
+	}
+	if version, _, err := readSchemaVersion(db.stakeDB); err != nil || version != SchemaVersion {
+		t.Errorf("schema version mismatch: have %d, %v, want %d", version, err, SchemaVersion)
+	}
+	if list, err := db.GetStakers("list"); err != nil || !list.IsContain(common.Address{1}) {
+		t.Errorf("staking list lost by migration: %v", err)
+	}
+	db.Close()
+
+	for i := 0; i < 2; i++ {
+		if results, err := MigrateDB(dir, i == 0); err != nil || len(results) != 0 {
+			t.Errorf("run %d: migrations of an upgraded database: have %v, %v", i, results, err)
+		}
+	}
+}
+
+// Tests that a staking database of a newer schema is refused, also in the
+// read-only mode.
+func TestSchemaFutureVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stakingdb-schema-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "stakingDB")
+	ldb, err := berithdb.NewLDBDatabase(path, 16, 16)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	if err := writeSchemaVersion(ldb, SchemaVersion+1); err != nil {
+		t.Fatalf("failed to write schema version: %v", err)
+	}
+	ldb.Close()
+
+	for _, readOnly := range []bool{false, true} {
+		db := &StakingDB{ReadOnly: readOnly}
+		err := db.CreateDB(path, NewStakers)
+		if schemaErr, ok := err.(*SchemaError); !ok || schemaErr.Stored != SchemaVersion+1 || schemaErr.Supported != SchemaVersion {
+			t.Errorf("read-only %v: open error mismatch: have %v", readOnly, err)
+		}
+	}
+	if _, err := MigrateDB(path, true); err == nil {
+		t.Errorf("dry run of a newer schema succeeded")
+	}
+}
@@ -1,10 +1,18 @@
 package staking
 
 import (
+	"errors"
 	"fmt"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	lerrors "github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/syndtr/goleveldb/leveldb/util"
 
 	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/log"
 	"github.com/BerithFoundation/berith-chain/rlp"
 
 	"github.com/BerithFoundation/berith-chain/berithdb"
@@ -17,9 +25,14 @@ import (
 Database that stores staker information
 */
 type StakingDB struct {
-	creator   createFunc
-	stakeDB   *berithdb.LDBDatabase
-	NoPruning bool // When gc mode is archive, this value is true or false.
+	creator    createFunc
+	stakeDB    *berithdb.LDBDatabase
+	writer     *asyncWriter // Background writer of the asynchronous commit modes
+	NoPruning  bool         // When gc mode is archive, this value is true or false.
+	CommitMode CommitMode   // How staking lists are written by Commit
+	ReadOnly   bool         // Whether failed writes are ignored, keeping the lists in the engine's cache only
+
+	degraded uint32 // Set once a write failed persistently in the read-only mode (atomic)
 }
 
 // staker type creation function
@@ -35,9 +48,24 @@ func (s *StakingDB) CreateDB(filename string, creator createFunc) error {
 		fmt.Println(err.Error())
 		return err
 	}
+	// Lists of an older layout are migrated before being read, while a newer
+	// layout is refused. A read-only database is used as it is.
+	if _, err := migrate(db, false); err != nil {
+		if _, ok := err.(*SchemaError); ok || !s.ReadOnly {
+			db.Close()
+			return err
+		}
+		// Lists can't be written next to an unmigrated layout, whatever the cause
+		s.setDegraded(err)
+	}
 
 	s.stakeDB = db
 	s.creator = creator
+	// A database degraded by the failed migration is never written, so no writer
+	// is started that would never be closed
+	if s.CommitMode != SyncCommit && !s.isDegraded() {
+		s.writer = newAsyncWriter(&ldbStore{db.LDB()}, s.CommitMode == AsyncCommit)
+	}
 	return nil
 }
 
@@ -46,6 +74,11 @@ func (s *StakingDB) CreateDB(filename string, creator createFunc) error {
 Get staker data of a specific block.
 */
 func (s *StakingDB) getValue(key string) ([]byte, error) {
+	if s.writer != nil {
+		if stakers, ok := s.writer.get(key); ok {
+			return stakers, nil
+		}
+	}
 	k := []byte(key)
 
 	stakers, err := s.stakeDB.Get(k)
@@ -66,7 +99,73 @@ func (s *StakingDB) pushValue(k string, stakers Stakers) error {
 	if err != nil {
 		return err
 	}
-	return s.stakeDB.Put(key, v)
+	if s.writer != nil {
+		return s.writer.put(k, v)
+	}
+	return s.stakeDB.LDB().Put(key, v, &opt.WriteOptions{Sync: true})
+}
+
+/*
+[Berith]
+Write the staking lists queued by the asynchronous commit modes.
+*/
+func (s *StakingDB) Flush() error {
+	if s.writer == nil || s.isDegraded() {
+		return nil
+	}
+	return s.degrade(s.writer.write(s.writer.flush))
+}
+
+/*
+[Berith]
+Downgrade a failed write in the read-only mode. A persistent failure is logged
+once and stops all further writes, the staking lists then only being kept in the
+cache of the engine and rebuilt from the nearest stored list once evicted. Any
+other failure is only logged, the lists queued by the asynchronous commit modes
+being written again by the next flush.
+*/
+func (s *StakingDB) degrade(err error) error {
+	if err == nil || !s.ReadOnly {
+		return err
+	}
+	if !isPersistentWriteError(err) {
+		log.Warn("Failed to write staking lists, retrying later", "err", err)
+		return nil
+	}
+	s.setDegraded(err)
+	return nil
+}
+
+// setDegraded stops all further writes.
+func (s *StakingDB) setDegraded(err error) {
+	if atomic.CompareAndSwapUint32(&s.degraded, 0, 1) {
+		log.Warn("Staking database is not writable, keeping staking lists in memory only", "err", err)
+		// Lists queued but not written yet are still served
+		if s.writer != nil {
+			close(s.writer.kill)
+		}
+	}
+}
+
+// isPersistentWriteError returns whether a write failed in a way that a retry
+// won't fix: a closed, read-only, corrupted or full database.
+func isPersistentWriteError(err error) bool {
+	if err == leveldb.ErrClosed || err == leveldb.ErrReadOnly || lerrors.IsCorrupted(err) {
+		return true
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		switch errno {
+		case syscall.ENOSPC, syscall.EROFS, syscall.EACCES, syscall.EPERM:
+			return true
+		}
+	}
+	return false
+}
+
+// isDegraded returns whether the writes are skipped after a failed one.
+func (s *StakingDB) isDegraded() bool {
+	return atomic.LoadUint32(&s.degraded) == 1
 }
 
 /**
@@ -76,6 +175,11 @@ func (s *StakingDB) Close() {
 	if s.stakeDB == nil {
 		return
 	}
+	if s.writer != nil && !s.isDegraded() {
+		if err := s.writer.close(); err != nil {
+			log.Error("Failed to write staking lists", "err", err)
+		}
+	}
 	s.stakeDB.Close()
 }
 
@@ -105,8 +209,11 @@ func (s *StakingDB) GetStakers(key string) (Stakers, error) {
 Save stakers data in database with block number as key
 */
 func (s *StakingDB) Commit(key string, value Stakers) error {
+	if s.isDegraded() {
+		return nil
+	}
 	if err := s.pushValue(key, value); err != nil {
-		return err
+		return s.degrade(err)
 	}
 	return nil
 }
@@ -117,9 +224,18 @@ func (s *StakingDB) NewStakers() Stakers {
 
 func (s *StakingDB) Clean(chain consensus.ChainReader, header *types.Header) error {
 	// If GC Mode is archive, stakingdb is not deleted.
-	if s.NoPruning {
+	if s.NoPruning || s.isDegraded() {
 		return nil
 	}
+	return s.degrade(s.prune(chain, header))
+}
+
+// prune deletes the staking lists of the given block and its ancestors.
+func (s *StakingDB) prune(chain consensus.ChainReader, header *types.Header) error {
+	// Queued lists are written first, so they are not written again after being deleted
+	if err := s.Flush(); err != nil {
+		return err
+	}
 
 	for {
 		key := []byte(header.Hash().Hex())
@@ -0,0 +1,236 @@
+package staking
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+/*
+[Berith]
+CommitMode selects how Commit writes staking lists to the database.
+
+The asynchronous modes keep at most commitQueueSize lists in memory. They are lost
+if the node crashes before they are written, which only costs time: a missing
+staking list is rebuilt by replaying the blocks after the nearest list on disk.
+*/
+type CommitMode int
+
+const (
+	SyncCommit        CommitMode = iota // Lists are written and fsynced before Commit returns
+	AsyncCommit                         // Lists are written and fsynced in batches by a background writer
+	AsyncUnsafeCommit                   // Lists are written in batches by a background writer without fsync
+)
+
+const (
+	commitQueueSize     = 256             // Maximum number of lists waiting to be written
+	commitFlushInterval = 3 * time.Second // Interval in which queued lists are written
+)
+
+var errWriterClosed = errors.New("staking writer closed")
+
+func (mode CommitMode) IsValid() bool {
+	return mode >= SyncCommit && mode <= AsyncUnsafeCommit
+}
+
+// String implements the stringer interface.
+func (mode CommitMode) String() string {
+	switch mode {
+	case SyncCommit:
+		return "sync"
+	case AsyncCommit:
+		return "async"
+	case AsyncUnsafeCommit:
+		return "async-unsafe"
+	default:
+		return "unknown"
+	}
+}
+
+func (mode CommitMode) MarshalText() ([]byte, error) {
+	if !mode.IsValid() {
+		return nil, fmt.Errorf("unknown commit mode %d", mode)
+	}
+	return []byte(mode.String()), nil
+}
+
+func (mode *CommitMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "sync":
+		*mode = SyncCommit
+	case "async":
+		*mode = AsyncCommit
+	case "async-unsafe":
+		*mode = AsyncUnsafeCommit
+	default:
+		return fmt.Errorf(`unknown commit mode %q, want "sync", "async" or "async-unsafe"`, text)
+	}
+	return nil
+}
+
+// keyValue is a staking list encoded for the database.
+type keyValue struct {
+	key   string
+	value []byte
+}
+
+// batchStore is the storage the staking lists are written to.
+type batchStore interface {
+	writeBatch(kvs []*keyValue, sync bool) error
+}
+
+// ldbStore writes staking lists to a leveldb database.
+type ldbStore struct {
+	db *leveldb.DB
+}
+
+func (s *ldbStore) writeBatch(kvs []*keyValue, sync bool) error {
+	batch := new(leveldb.Batch)
+	for _, kv := range kvs {
+		batch.Put([]byte(kv.key), kv.value)
+	}
+	return s.db.Write(batch, &opt.WriteOptions{Sync: sync})
+}
+
+/*
+[Berith]
+Background writer of staking lists.
+Lists are queued by put and written in a single batch when the flush interval
+elapses, when the queue is full or when flush is called. Lists which are queued
+but not written yet are served by get.
+*/
+type asyncWriter struct {
+	store batchStore
+	sync  bool
+
+	lock    sync.RWMutex
+	pending map[string]*keyValue // Lists queued but not written yet
+	order   []string             // Keys of the pending lists in commit order
+
+	flush chan chan error // Requests to write the queue
+	quit  chan chan error // Request to write the queue and stop
+	kill  chan struct{}   // Stops the writer leaving the queue unwritten
+	done  chan struct{}   // Closed when the writer stopped
+}
+
+func newAsyncWriter(store batchStore, sync bool) *asyncWriter {
+	w := &asyncWriter{
+		store:   store,
+		sync:    sync,
+		pending: make(map[string]*keyValue),
+		flush:   make(chan chan error),
+		quit:    make(chan chan error),
+		kill:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+// put queues a list, blocking while the queue is full.
+func (w *asyncWriter) put(key string, value []byte) error {
+	w.lock.Lock()
+	if _, ok := w.pending[key]; !ok {
+		w.order = append(w.order, key)
+	}
+	w.pending[key] = &keyValue{key, value}
+	full := len(w.order) >= commitQueueSize
+	w.lock.Unlock()
+
+	if full {
+		return w.write(w.flush)
+	}
+	return nil
+}
+
+// get returns a list queued but not written yet.
+func (w *asyncWriter) get(key string) ([]byte, bool) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	kv, ok := w.pending[key]
+	if !ok {
+		return nil, false
+	}
+	return kv.value, true
+}
+
+// write sends a request to the writer and waits until the queue is written.
+func (w *asyncWriter) write(req chan chan error) error {
+	errc := make(chan error)
+	select {
+	case req <- errc:
+		return <-errc
+	case <-w.done:
+		return errWriterClosed
+	}
+}
+
+func (w *asyncWriter) loop() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(commitFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.writeQueue(); err != nil {
+				log.Error("Failed to write staking lists", "err", err)
+			}
+		case errc := <-w.flush:
+			errc <- w.writeQueue()
+		case errc := <-w.quit:
+			errc <- w.writeQueue()
+			return
+		case <-w.kill:
+			return
+		}
+	}
+}
+
+// writeQueue writes all queued lists in a single batch.
+func (w *asyncWriter) writeQueue() error {
+	w.lock.RLock()
+	queued := make([]*keyValue, len(w.order))
+	for i, key := range w.order {
+		queued[i] = w.pending[key]
+	}
+	w.lock.RUnlock()
+
+	if len(queued) == 0 {
+		return nil
+	}
+	if err := w.store.writeBatch(queued, w.sync); err != nil {
+		return err
+	}
+	// Drop the written lists unless they were committed again in the meantime
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	written := make(map[string]bool, len(queued))
+	for _, kv := range queued {
+		if w.pending[kv.key] == kv {
+			delete(w.pending, kv.key)
+			written[kv.key] = true
+		}
+	}
+	order := w.order[:0]
+	for _, key := range w.order {
+		if !written[key] {
+			order = append(order, key)
+		}
+	}
+	w.order = order
+	return nil
+}
+
+// close writes the queue and stops the writer.
+func (w *asyncWriter) close() error {
+	return w.write(w.quit)
+}
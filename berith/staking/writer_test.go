@@ -0,0 +1,292 @@
+package staking
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// newTestStakers creates a staking list of n stakers.
+func newTestStakers(n int) Stakers {
+	stks := NewStakers()
+	for i := 0; i < n; i++ {
+		stks.Put(common.BytesToAddress([]byte{byte(i + 1)}))
+	}
+	return stks
+}
+
+func sortedList(stks Stakers) []common.Address {
+	list := stks.AsList()
+	sort.Slice(list, func(i, j int) bool { return list[i].Hex() < list[j].Hex() })
+	return list
+}
+
+// Tests that staking lists are readable right after being committed and are
+// persisted when the database is closed, in every commit mode.
+func TestCommitModes(t *testing.T) {
+	for _, mode := range []CommitMode{SyncCommit, AsyncCommit, AsyncUnsafeCommit} {
+		dir, err := ioutil.TempDir("", "stakingdb")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		db := &StakingDB{CommitMode: mode}
+		if err := db.CreateDB(dir, NewStakers); err != nil {
+			t.Fatalf("%v: failed to create database: %v", mode, err)
+		}
+		for i := 0; i < 10; i++ {
+			if err := db.Commit(fmt.Sprint(i), newTestStakers(i)); err != nil {
+				t.Fatalf("%v: list %d: failed to commit: %v", mode, i, err)
+			}
+			stks, err := db.GetStakers(fmt.Sprint(i))
+			if err != nil {
+				t.Fatalf("%v: list %d: failed to read committed list: %v", mode, i, err)
+			}
+			if have, want := sortedList(stks), sortedList(newTestStakers(i)); !reflect.DeepEqual(have, want) {
+				t.Errorf("%v: list %d: mismatch: have %v, want %v", mode, i, have, want)
+			}
+		}
+		db.Close()
+
+		db = new(StakingDB)
+		if err := db.CreateDB(dir, NewStakers); err != nil {
+			t.Fatalf("%v: failed to reopen database: %v", mode, err)
+		}
+		for i := 0; i < 10; i++ {
+			stks, err := db.GetStakers(fmt.Sprint(i))
+			if err != nil {
+				t.Fatalf("%v: list %d: not persisted: %v", mode, i, err)
+			}
+			if have, want := sortedList(stks), sortedList(newTestStakers(i)); !reflect.DeepEqual(have, want) {
+				t.Errorf("%v: list %d: persisted mismatch: have %v, want %v", mode, i, have, want)
+			}
+		}
+		db.Close()
+	}
+}
+
+// Tests that if the writer dies with lists queued, the lists written by the
+// last flush survive intact and the queued ones are missing, not corrupted.
+func TestAsyncWriterKilled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stakingdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db := &StakingDB{CommitMode: AsyncCommit}
+	if err := db.CreateDB(dir, NewStakers); err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		if i == 10 {
+			if err := db.Flush(); err != nil {
+				t.Fatalf("failed to flush: %v", err)
+			}
+		}
+		if err := db.Commit(fmt.Sprint(i), newTestStakers(i)); err != nil {
+			t.Fatalf("list %d: failed to commit: %v", i, err)
+		}
+	}
+	// Kill the writer as a crash would, before the queue is written
+	close(db.writer.kill)
+	<-db.writer.done
+	if err := db.Flush(); err != errWriterClosed {
+		t.Errorf("flush after kill: error mismatch: have %v, want %v", err, errWriterClosed)
+	}
+	db.writer = nil
+	db.Close()
+
+	db = new(StakingDB)
+	if err := db.CreateDB(dir, NewStakers); err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 20; i++ {
+		stks, err := db.GetStakers(fmt.Sprint(i))
+		if i >= 10 {
+			if err == nil {
+				t.Errorf("list %d: queued list written", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("list %d: flushed list lost: %v", i, err)
+		}
+		if have, want := sortedList(stks), sortedList(newTestStakers(i)); !reflect.DeepEqual(have, want) {
+			t.Errorf("list %d: mismatch: have %v, want %v", i, have, want)
+		}
+	}
+}
+
+// slowStore is a store simulating a disk with a high write and fsync latency.
+type slowStore struct {
+	write, sync time.Duration
+}
+
+func (s *slowStore) writeBatch(kvs []*keyValue, sync bool) error {
+	time.Sleep(s.write)
+	if sync {
+		time.Sleep(s.sync)
+	}
+	return nil
+}
+
+// Benchmarks committing the staking list of every imported block on a slow
+// disk, writing each list synchronously or in batches in the background.
+func BenchmarkCommitSlowStore(b *testing.B) {
+	var (
+		store = &slowStore{write: 100 * time.Microsecond, sync: time.Millisecond}
+		value = make([]byte, 20*1000)
+	)
+	b.Run("sync", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			store.writeBatch([]*keyValue{{fmt.Sprint(i), value}}, true)
+		}
+	})
+	b.Run("async", func(b *testing.B) {
+		w := newAsyncWriter(store, true)
+		for i := 0; i < b.N; i++ {
+			w.put(fmt.Sprint(i), value)
+		}
+		w.close()
+	})
+	b.Run("async-unsafe", func(b *testing.B) {
+		w := newAsyncWriter(store, false)
+		for i := 0; i < b.N; i++ {
+			w.put(fmt.Sprint(i), value)
+		}
+		w.close()
+	})
+}
+
+// Tests that failing writes are reported, unless in the read-only mode where the
+// first failure stops further writes and the queued lists are still served.
+func TestReadOnly(t *testing.T) {
+	for _, mode := range []CommitMode{SyncCommit, AsyncCommit} {
+		for _, readOnly := range []bool{false, true} {
+			dir, err := ioutil.TempDir("", "stakingdb")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			db := &StakingDB{CommitMode: mode, ReadOnly: readOnly}
+			if err := db.CreateDB(dir, NewStakers); err != nil {
+				t.Fatalf("%v, read-only %v: failed to create database: %v", mode, readOnly, err)
+			}
+			defer db.Close()
+
+			// Closing the underlying database fails all writes from now on
+			db.stakeDB.LDB().Close()
+
+			err = db.Commit("0", newTestStakers(1))
+			if err == nil {
+				err = db.Flush()
+			}
+			if !readOnly {
+				if err == nil {
+					t.Errorf("%v: write to closed database succeeded", mode)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%v, read-only: write failure not ignored: %v", mode, err)
+			}
+			if !db.isDegraded() {
+				t.Errorf("%v, read-only: writes not stopped", mode)
+			}
+			if err := db.Commit("1", newTestStakers(2)); err != nil {
+				t.Errorf("%v, read-only: commit after failure: %v", mode, err)
+			}
+			if mode == AsyncCommit {
+				if _, err := db.GetStakers("0"); err != nil {
+					t.Errorf("%v, read-only: queued list not served: %v", mode, err)
+				}
+			}
+		}
+	}
+}
+
+// failingStore is a store failing its writes with the given errors in turn,
+// succeeding once they are used up.
+type failingStore struct {
+	errs    []error
+	written map[string]bool
+}
+
+func (s *failingStore) writeBatch(kvs []*keyValue, sync bool) error {
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return err
+	}
+	for _, kv := range kvs {
+		s.written[kv.key] = true
+	}
+	return nil
+}
+
+// Tests that in the read-only mode a transient write failure is ignored without
+// stopping further writes, the queued lists being written by the next flush,
+// while a persistent one stops them.
+func TestReadOnlyTransient(t *testing.T) {
+	tests := []struct {
+		err      error
+		degraded bool
+	}{
+		{syscall.EAGAIN, false},
+		{&os.PathError{Op: "write", Path: "000001.log", Err: syscall.EINTR}, false},
+		{errors.New("unknown failure"), false},
+		{&os.PathError{Op: "write", Path: "000001.log", Err: syscall.ENOSPC}, true},
+		{syscall.EROFS, true},
+		{leveldb.ErrClosed, true},
+	}
+	for i, tt := range tests {
+		dir, err := ioutil.TempDir("", "stakingdb")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		db := &StakingDB{CommitMode: AsyncCommit, ReadOnly: true}
+		if err := db.CreateDB(dir, NewStakers); err != nil {
+			t.Fatalf("test %d: failed to create database: %v", i, err)
+		}
+		defer db.Close()
+
+		store := &failingStore{errs: []error{tt.err}, written: make(map[string]bool)}
+		db.writer.close()
+		db.writer = newAsyncWriter(store, true)
+
+		if err := db.Commit("0", newTestStakers(1)); err != nil {
+			t.Fatalf("test %d: failed to commit: %v", i, err)
+		}
+		if err := db.Flush(); err != nil {
+			t.Errorf("test %d: write failure not ignored: %v", i, err)
+		}
+		if db.isDegraded() != tt.degraded {
+			t.Errorf("test %d: degraded mismatch: have %v, want %v", i, db.isDegraded(), tt.degraded)
+		}
+		if _, err := db.GetStakers("0"); err != nil {
+			t.Errorf("test %d: queued list not served: %v", i, err)
+		}
+		if err := db.Flush(); err != nil {
+			t.Errorf("test %d: failed to flush: %v", i, err)
+		}
+		if store.written["0"] == tt.degraded {
+			t.Errorf("test %d: list written %v after failure", i, store.written["0"])
+		}
+	}
+}
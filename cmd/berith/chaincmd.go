@@ -26,9 +26,11 @@ import (
 	"time"
 
 	"github.com/BerithFoundation/berith-chain/berith/downloader"
+	"github.com/BerithFoundation/berith-chain/berith/staking"
 	"github.com/BerithFoundation/berith-chain/berithdb"
 	"github.com/BerithFoundation/berith-chain/cmd/utils"
 	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/consensus/bsrr"
 	"github.com/BerithFoundation/berith-chain/console"
 	"github.com/BerithFoundation/berith-chain/core"
 	"github.com/BerithFoundation/berith-chain/core/state"
@@ -48,6 +50,7 @@ var (
 		ArgsUsage: "<genesisPath>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.ValidateOnlyFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -55,7 +58,8 @@ The init command initializes a new genesis block and definition for the network.
 This is a destructive action and changes the network in which you will be
 participating.
 
-It expects the genesis file as argument.`,
+It expects the genesis file as argument. With --validate-only, the genesis is
+checked and its signers are reported instead, without writing anything.`,
 	}
 	importCommand = cli.Command{
 		Action:    utils.MigrateFlags(importChain),
@@ -123,6 +127,36 @@ be gzipped.`,
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The export-preimages command export hash preimages to an RLP encoded stream`,
+	}
+	importVotesCommand = cli.Command{
+		Action:    utils.MigrateFlags(importVotes),
+		Name:      "import-votes",
+		Usage:     "Import the election results of the epochs from an RLP stream",
+		ArgsUsage: "<datafile>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The import-votes command imports the vote history written by export-votes,
+replacing the stored election results of the same epochs.`,
+	}
+	exportVotesCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportVotes),
+		Name:      "export-votes",
+		Usage:     "Export the election results of the epochs into an RLP stream",
+		ArgsUsage: "<dumpfile>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The export-votes command exports the vote history recorded with --bsrr.votehistory
+to an RLP encoded stream. If the file ends with .gz, the output will be gzipped.`,
 	}
 	copydbCommand = cli.Command{
 		Action:    utils.MigrateFlags(copyDb),
@@ -152,6 +186,30 @@ The first argument must be the directory containing the blockchain to download f
 		Description: `
 Remove blockchain and state databases`,
 	}
+	stakingdbCommand = cli.Command{
+		Name:     "stakingdb",
+		Usage:    "Manage the staking database",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Action: utils.MigrateFlags(migrateStakingDB),
+				Name:   "migrate",
+				Usage:  "Upgrade the staking database to the current schema version",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					stakingDryRunFlag,
+				},
+				Description: `
+The stakingdb migrate command applies the migrations upgrading the layout of the
+staking database to the schema version of this release, which also happens
+when the node starts. With --dry-run, the migrations are only reported.`,
+			},
+		},
+	}
+	stakingDryRunFlag = cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Report the migrations without applying them",
+	}
 	dumpCommand = cli.Command{
 		Action:    utils.MigrateFlags(dump),
 		Name:      "dump",
@@ -187,6 +245,16 @@ func initGenesis(ctx *cli.Context) error {
 	if err := json.NewDecoder(file).Decode(genesis); err != nil {
 		utils.Fatalf("invalid genesis file: %v", err)
 	}
+	if ctx.Bool(utils.ValidateOnlyFlag.Name) {
+		return validateGenesis(genesis)
+	}
+	writeGenesis(ctx, genesis)
+	return nil
+}
+
+// writeGenesis writes the genesis block into both the full and light databases
+// of the node, or fails hard if it can't succeed.
+func writeGenesis(ctx *cli.Context, genesis *core.Genesis) {
 	// Open an initialise both full and light databases
 	stack := makeFullNode(ctx)
 	for _, name := range []string{"chaindata", "lightchaindata"} {
@@ -198,8 +266,34 @@ func initGenesis(ctx *cli.Context) error {
 		if err != nil {
 			utils.Fatalf("Failed to write genesis block: %v", err)
 		}
+		chaindb.Close()
 		log.Info("Successfully wrote genesis state", "database", name, "hash", hash)
 	}
+}
+
+// validateGenesis checks that a network can be launched from the genesis and
+// prints its signers.
+func validateGenesis(genesis *core.Genesis) error {
+	report, err := bsrr.ValidateGenesis(genesis)
+	if err != nil {
+		utils.Fatalf("Invalid genesis: %v", err)
+	}
+	fmt.Printf("Epoch:         %d blocks\n", report.Config.Epoch)
+	fmt.Printf("Period:        %d seconds\n", report.Config.Period)
+	fmt.Printf("Stake minimum: %v\n", report.Config.StakeMinimum)
+	fmt.Printf("Signers:       %d\n\n", len(report.Signers))
+	for i, signer := range report.Signers {
+		fmt.Printf("%3d. %s  balance %v\n", i+1, signer.Address.Hex(), signer.Balance)
+	}
+	warnings := report.Warnings()
+	for _, warning := range warnings {
+		fmt.Printf("\nWARNING: %s\n", warning)
+	}
+	if len(warnings) == 0 {
+		fmt.Println("\nGenesis is valid")
+	} else {
+		fmt.Printf("\nGenesis is valid with %d warning(s)\n", len(warnings))
+	}
 	return nil
 }
 
@@ -361,6 +455,38 @@ func exportPreimages(ctx *cli.Context) error {
 	return nil
 }
 
+// importVotes imports the vote history from the specified file.
+func importVotes(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	diskdb := utils.MakeChainDatabase(ctx, stack)
+
+	start := time.Now()
+	if err := utils.ImportVoteHistory(diskdb, ctx.Args().First()); err != nil {
+		utils.Fatalf("Import error: %v\n", err)
+	}
+	fmt.Printf("Import done in %v\n", time.Since(start))
+	return nil
+}
+
+// exportVotes dumps the vote history to the specified file.
+func exportVotes(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	diskdb := utils.MakeChainDatabase(ctx, stack)
+
+	start := time.Now()
+	if err := utils.ExportVoteHistory(diskdb, ctx.Args().First()); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
 func copyDb(ctx *cli.Context) error {
 	fmt.Println("cmd/berith/chaincmd.go > copyDb() 호출")
 
@@ -440,6 +566,34 @@ func removeDB(ctx *cli.Context) error {
 	return nil
 }
 
+func migrateStakingDB(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+
+	path := stack.ResolvePath("stakingDB")
+	if !common.FileExist(path) {
+		utils.Fatalf("Staking database doesn't exist: %s", path)
+	}
+	dryRun := ctx.Bool(stakingDryRunFlag.Name)
+	results, err := staking.MigrateDB(path, dryRun)
+	if err != nil {
+		utils.Fatalf("Migration failed: %v", err)
+	}
+	if len(results) == 0 {
+		fmt.Printf("Staking database is up to date at schema version %d\n", staking.SchemaVersion)
+		return nil
+	}
+	for _, result := range results {
+		fmt.Printf("Migration to version %d: %s\n", result.Version, result.Name)
+		for _, change := range result.Changes {
+			fmt.Printf("  - %s\n", change)
+		}
+	}
+	if dryRun {
+		fmt.Println("Dry run, no changes written")
+	}
+	return nil
+}
+
 func dump(ctx *cli.Context) error {
 	stack := makeFullNode(ctx)
 	chain, chainDb := utils.MakeChain(ctx, stack)
@@ -32,7 +32,7 @@ import (
 )
 
 var (
-	consoleFlags = []cli.Flag{utils.JSpathFlag, utils.ExecFlag, utils.PreloadJSFlag}
+	consoleFlags = []cli.Flag{utils.JSpathFlag, utils.ExecFlag, utils.PreloadJSFlag, utils.ConsoleIndentFlag, utils.ConsolePrecisionFlag, utils.ConsoleMaxScriptSizeFlag, utils.ConsoleScriptTimeoutFlag, utils.ConsoleStrictPreloadFlag, utils.ConsoleHistoryFlag}
 
 	consoleCommand = cli.Command{
 		Action:   utils.MigrateFlags(localConsole),
@@ -88,10 +88,18 @@ func localConsole(ctx *cli.Context) error {
 		utils.Fatalf("Failed to attach to the inproc ber: %v", err)
 	}
 	config := console.Config{
-		DataDir: utils.MakeDataDir(ctx),
-		DocRoot: ctx.GlobalString(utils.JSpathFlag.Name),
-		Client:  client,
-		Preload: utils.MakeConsolePreloads(ctx),
+		DataDir:      utils.MakeDataDir(ctx),
+		DocRoot:      ctx.GlobalString(utils.JSpathFlag.Name),
+		Client:       client,
+		Endpoint:     node.IPCEndpoint(),
+		Preload:      utils.MakeConsolePreloads(ctx),
+		OutputIndent: ctx.GlobalInt(utils.ConsoleIndentFlag.Name),
+
+		PrecisionGuard: ctx.GlobalString(utils.ConsolePrecisionFlag.Name),
+		MaxScriptSize:  ctx.GlobalInt64(utils.ConsoleMaxScriptSizeFlag.Name),
+		ScriptTimeout:  ctx.GlobalDuration(utils.ConsoleScriptTimeoutFlag.Name),
+		StrictPreload:  ctx.GlobalBool(utils.ConsoleStrictPreloadFlag.Name),
+		HistoryPath:    consoleHistoryPath(ctx),
 	}
 
 	console, err := console.New(config)
@@ -135,10 +143,18 @@ func remoteConsole(ctx *cli.Context) error {
 		utils.Fatalf("Unable to attach to remote ber: %v", err)
 	}
 	config := console.Config{
-		DataDir: utils.MakeDataDir(ctx),
-		DocRoot: ctx.GlobalString(utils.JSpathFlag.Name),
-		Client:  client,
-		Preload: utils.MakeConsolePreloads(ctx),
+		DataDir:      utils.MakeDataDir(ctx),
+		DocRoot:      ctx.GlobalString(utils.JSpathFlag.Name),
+		Client:       client,
+		Endpoint:     endpoint,
+		Preload:      utils.MakeConsolePreloads(ctx),
+		OutputIndent: ctx.GlobalInt(utils.ConsoleIndentFlag.Name),
+
+		PrecisionGuard: ctx.GlobalString(utils.ConsolePrecisionFlag.Name),
+		MaxScriptSize:  ctx.GlobalInt64(utils.ConsoleMaxScriptSizeFlag.Name),
+		ScriptTimeout:  ctx.GlobalDuration(utils.ConsoleScriptTimeoutFlag.Name),
+		StrictPreload:  ctx.GlobalBool(utils.ConsoleStrictPreloadFlag.Name),
+		HistoryPath:    consoleHistoryPath(ctx),
 	}
 
 	console, err := console.New(config)
@@ -159,6 +175,16 @@ func remoteConsole(ctx *cli.Context) error {
 	return nil
 }
 
+// consoleHistoryPath returns the history file location requested by the user,
+// empty if the default one within the data directory should be used.
+func consoleHistoryPath(ctx *cli.Context) string {
+	path := ctx.GlobalString(utils.ConsoleHistoryFlag.Name)
+	if path == "xdg" {
+		return console.XDGHistoryPath()
+	}
+	return path
+}
+
 // dialRPC returns a RPC client which connects to the given endpoint.
 // The check for empty endpoint implements the defaulting logic
 // for "berith attach" and "berith monitor" with no argument.
@@ -189,10 +215,18 @@ func ephemeralConsole(ctx *cli.Context) error {
 		utils.Fatalf("Failed to attach to the inproc berith: %v", err)
 	}
 	config := console.Config{
-		DataDir: utils.MakeDataDir(ctx),
-		DocRoot: ctx.GlobalString(utils.JSpathFlag.Name),
-		Client:  client,
-		Preload: utils.MakeConsolePreloads(ctx),
+		DataDir:      utils.MakeDataDir(ctx),
+		DocRoot:      ctx.GlobalString(utils.JSpathFlag.Name),
+		Client:       client,
+		Endpoint:     node.IPCEndpoint(),
+		Preload:      utils.MakeConsolePreloads(ctx),
+		OutputIndent: ctx.GlobalInt(utils.ConsoleIndentFlag.Name),
+
+		PrecisionGuard: ctx.GlobalString(utils.ConsolePrecisionFlag.Name),
+		MaxScriptSize:  ctx.GlobalInt64(utils.ConsoleMaxScriptSizeFlag.Name),
+		ScriptTimeout:  ctx.GlobalDuration(utils.ConsoleScriptTimeoutFlag.Name),
+		StrictPreload:  ctx.GlobalBool(utils.ConsoleStrictPreloadFlag.Name),
+		HistoryPath:    consoleHistoryPath(ctx),
 	}
 
 	console, err := console.New(config)
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/BerithFoundation/berith-chain/accounts/keystore"
+	"github.com/BerithFoundation/berith-chain/cmd/utils"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/consensus/bsrr"
+	"github.com/BerithFoundation/berith-chain/core"
+	"github.com/BerithFoundation/berith-chain/node"
+	"github.com/BerithFoundation/berith-chain/p2p/enode"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// devnetPassword is the password of the signer keys generated for a development
+// network, also written to the password file of its data directory.
+const devnetPassword = "berith-devnet"
+
+var (
+	devnetCommand = cli.Command{
+		Name:     "devnet",
+		Usage:    "Set up a local development network",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Action: utils.MigrateFlags(devnetInit),
+				Name:   "init",
+				Usage:  "Generate the signers and genesis of a development network",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					devnetSignersFlag,
+					devnetPeriodFlag,
+					devnetEpochFlag,
+					devnetStartFlag,
+				},
+				Description: `
+The devnet init command generates the keys of the signers of a new development
+network into the keystore of the data directory, encrypted with the password
+"` + devnetPassword + `" which is also written to the password file of the data
+directory. The genesis of the network lists the signers in its extra-data and
+funds them above the stake minimum. It is written to genesis.json and into the
+databases of the node.
+
+The command prints how to start the node and how to wire additional nodes to
+it. With --start, the node is started mining right away as the first signer.`,
+			},
+		},
+	}
+	devnetSignersFlag = cli.IntFlag{
+		Name:  "signers",
+		Usage: "Number of signers of the development network",
+		Value: 1,
+	}
+	devnetPeriodFlag = cli.Uint64Flag{
+		Name:  "period",
+		Usage: "Number of seconds between the blocks of the development network",
+		Value: 2,
+	}
+	devnetEpochFlag = cli.Uint64Flag{
+		Name:  "epoch",
+		Usage: "Number of blocks of an epoch of the development network",
+		Value: 30,
+	}
+	devnetStartFlag = cli.BoolFlag{
+		Name:  "start",
+		Usage: "Start the node mining once the development network is set up",
+	}
+)
+
+// devnet is a development network generated into a data directory.
+type devnet struct {
+	genesis      *core.Genesis
+	signers      []common.Address // Signers of the network, the first one run by the node of the data directory
+	genesisFile  string
+	passwordFile string
+	enode        *enode.Node // Node of the data directory
+}
+
+// makeDevnet generates the signer keys and the genesis of a development network
+// into the data directory, along with the node key of the node which will run
+// the first signer.
+func makeDevnet(datadir string, signers int, period, epoch uint64, port int) (*devnet, error) {
+	if signers < 1 {
+		return nil, errors.New("a development network needs at least one signer")
+	}
+	d := &devnet{
+		genesisFile:  filepath.Join(datadir, "genesis.json"),
+		passwordFile: filepath.Join(datadir, "password"),
+	}
+	if common.FileExist(d.genesisFile) {
+		return nil, fmt.Errorf("data directory already holds a genesis: %s", d.genesisFile)
+	}
+	keydir := filepath.Join(datadir, "keystore")
+	for i := 0; i < signers; i++ {
+		signer, err := keystore.StoreKey(keydir, devnetPassword, keystore.LightScryptN, keystore.LightScryptP)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate signer key: %v", err)
+		}
+		d.signers = append(d.signers, signer)
+	}
+	genesis, err := bsrr.DevnetGenesis(d.signers, period, epoch)
+	if err != nil {
+		return nil, err
+	}
+	d.genesis = genesis
+
+	blob, err := json.MarshalIndent(d.genesis, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(d.genesisFile, blob, 0644); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(d.passwordFile, []byte(devnetPassword+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	config := &node.Config{DataDir: datadir, Name: clientIdentifier}
+	key := config.NodeKey()
+	d.enode = enode.NewV4(&key.PublicKey, net.ParseIP("127.0.0.1"), port, port)
+	return d, nil
+}
+
+// devnetInit sets up a development network in the data directory and prints how
+// to run it, or runs it right away if requested.
+func devnetInit(ctx *cli.Context) error {
+	if !ctx.GlobalIsSet(utils.DataDirFlag.Name) {
+		utils.Fatalf("Must supply the data directory of the development network with --%s", utils.DataDirFlag.Name)
+	}
+	datadir := ctx.GlobalString(utils.DataDirFlag.Name)
+	if err := os.MkdirAll(datadir, 0700); err != nil {
+		utils.Fatalf("Failed to create data directory: %v", err)
+	}
+	d, err := makeDevnet(datadir, ctx.Int(devnetSignersFlag.Name), ctx.Uint64(devnetPeriodFlag.Name), ctx.Uint64(devnetEpochFlag.Name), ctx.GlobalInt(utils.ListenPortFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to set up development network: %v", err)
+	}
+	writeGenesis(ctx, d.genesis)
+
+	signer := d.signers[0].Hex()
+	fmt.Printf("Development network of %d signer(s) set up in %s\n\n", len(d.signers), datadir)
+	fmt.Printf("Chain id:      %d\n", bsrr.DevnetChainID)
+	fmt.Printf("Period:        %d seconds\n", d.genesis.Config.Bsrr.Period)
+	fmt.Printf("Epoch:         %d blocks\n", d.genesis.Config.Bsrr.Epoch)
+	fmt.Printf("Genesis:       %s\n", d.genesisFile)
+	fmt.Printf("Password file: %s\n", d.passwordFile)
+	fmt.Printf("Signers:       %d\n\n", len(d.signers))
+	for i, signer := range d.signers {
+		fmt.Printf("%3d. %s\n", i+1, signer.Hex())
+	}
+	fmt.Printf("\nStart the node mining as the first signer with:\n\n")
+	fmt.Printf("  berith --datadir %s --networkid %d --mine --miner.berithbase %s --unlock %s --password %s\n\n",
+		datadir, bsrr.DevnetChainID, signer, signer, d.passwordFile)
+
+	static, _ := json.MarshalIndent([]string{d.enode.String()}, "", "  ")
+	fmt.Printf("Wire additional nodes by initialising them with the genesis, importing the keys\n")
+	fmt.Printf("of their signers from the keystore, and listing this node in the static-nodes.json\n")
+	fmt.Printf("of their instance directory (<datadir>/%s/static-nodes.json):\n\n", clientIdentifier)
+	fmt.Printf("%s\n", static)
+
+	if !ctx.Bool(devnetStartFlag.Name) {
+		return nil
+	}
+	ctx.GlobalSet(utils.NetworkIdFlag.Name, strconv.Itoa(bsrr.DevnetChainID))
+	ctx.GlobalSet(utils.MiningEnabledFlag.Name, "true")
+	ctx.GlobalSet(utils.MinerBerithbaseFlag.Name, signer)
+	ctx.GlobalSet(utils.UnlockedAccountFlag.Name, signer)
+	ctx.GlobalSet(utils.PasswordFileFlag.Name, d.passwordFile)
+
+	stack := makeFullNode(ctx)
+	startNode(ctx, stack)
+	stack.Wait()
+	return nil
+}
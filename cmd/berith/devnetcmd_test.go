@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/accounts"
+	"github.com/BerithFoundation/berith-chain/accounts/keystore"
+	"github.com/BerithFoundation/berith-chain/berith"
+	"github.com/BerithFoundation/berith-chain/consensus/bsrr"
+	"github.com/BerithFoundation/berith-chain/core"
+	"github.com/BerithFoundation/berith-chain/node"
+	"github.com/BerithFoundation/berith-chain/p2p"
+)
+
+// Tests that a node booted from the output of the development network generator
+// seals blocks as the first signer.
+func TestDevnetSealing(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "berith-devnet-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+
+	d, err := makeDevnet(datadir, 3, 1, 30, 40404)
+	if err != nil {
+		t.Fatalf("failed to generate development network: %v", err)
+	}
+	if _, err := makeDevnet(datadir, 3, 1, 30, 40404); err == nil {
+		t.Errorf("development network generated over an existing one")
+	}
+	// Boot a node from the written genesis, password and keys
+	blob, err := ioutil.ReadFile(d.genesisFile)
+	if err != nil {
+		t.Fatalf("failed to read genesis: %v", err)
+	}
+	genesis := new(core.Genesis)
+	if err := genesis.UnmarshalJSON(blob); err != nil {
+		t.Fatalf("failed to decode genesis: %v", err)
+	}
+	if report, err := bsrr.ValidateGenesis(genesis); err != nil || len(report.Signers) != 3 || len(report.Warnings()) != 0 {
+		t.Fatalf("invalid genesis: %v", err)
+	}
+	password, err := ioutil.ReadFile(d.passwordFile)
+	if err != nil {
+		t.Fatalf("failed to read password: %v", err)
+	}
+	stack, err := node.New(&node.Config{DataDir: datadir, Name: clientIdentifier, P2P: p2p.Config{ListenAddr: ":0", NoDiscovery: true}})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	config := berith.DefaultConfig
+	config.Genesis = genesis
+	config.NetworkId = bsrr.DevnetChainID
+	config.Berithbase = d.signers[0]
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) { return berith.New(ctx, &config) }); err != nil {
+		t.Fatalf("failed to register Berith protocol: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	defer stack.Stop()
+
+	if id := stack.Server().Self().ID(); id != d.enode.ID() {
+		t.Errorf("enode mismatch: have %v, want %v", id, d.enode.ID())
+	}
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	if len(ks.Accounts()) != 3 {
+		t.Fatalf("keystore account count mismatch: have %d, want 3", len(ks.Accounts()))
+	}
+	if err := ks.Unlock(accounts.Account{Address: d.signers[0]}, strings.TrimSpace(string(password))); err != nil {
+		t.Fatalf("failed to unlock signer: %v", err)
+	}
+	var ber *berith.Berith
+	if err := stack.Service(&ber); err != nil {
+		t.Fatalf("berith service not running: %v", err)
+	}
+	if err := ber.StartMining(1); err != nil {
+		t.Fatalf("failed to start mining: %v", err)
+	}
+	for deadline := time.Now().Add(30 * time.Second); ber.BlockChain().CurrentBlock().NumberU64() < 2; time.Sleep(100 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("blocks not sealed: head at %d", ber.BlockChain().CurrentBlock().NumberU64())
+		}
+	}
+}
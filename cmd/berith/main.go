@@ -75,8 +75,16 @@ var (
 		utils.TxPoolLifetimeFlag,
 		utils.SyncModeFlag,
 		utils.GCModeFlag,
+		utils.StakingCommitFlag,
+		utils.StakingReadOnlyFlag,
+		utils.VoteHistoryFlag,
+		utils.VoteHistoryRetentionFlag,
+		utils.StakeIntentHorizonFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
+		utils.LightMinServersFlag,
+		utils.LightMaxServersFlag,
+		utils.LightRetrieveTimeoutFlag,
 		utils.LightKDFFlag,
 		utils.WhitelistFlag,
 		utils.CacheFlag,
@@ -102,6 +110,12 @@ var (
 		utils.MinerLegacyExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerfiyFlag,
+		utils.MinerTxOrderingFlag,
+		utils.MinerNoEmptyPrecommitFlag,
+		utils.MinerDroppedTxsFlag,
+		utils.MinerGasLimitDivisorFlag,
+		utils.MinerMaxUnclesFlag,
+		utils.MinerSoakFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
@@ -122,6 +136,7 @@ var (
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
+		utils.GpoMaxPriceFlag,
 		utils.EWASMInterpreterFlag,
 		utils.EVMInterpreterFlag,
 		configFileFlag,
@@ -144,6 +159,14 @@ var (
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.RPCEthCompatFlag,
+		utils.RPCLogsMaxRangeFlag,
+		utils.RPCLogsMaxResultsFlag,
+		utils.RPCLogsTimeoutFlag,
+		utils.PersonalMaxFailuresFlag,
+		utils.PersonalBackoffFlag,
+		utils.PersonalMaxBackoffFlag,
+		utils.PersonalGuardLocalFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 	}
@@ -170,8 +193,11 @@ func init() {
 		exportCommand,
 		importPreimagesCommand,
 		exportPreimagesCommand,
+		importVotesCommand,
+		exportVotesCommand,
 		copydbCommand,
 		removedbCommand,
+		stakingdbCommand,
 		dumpCommand,
 
 		// See accountcmd.go:
@@ -184,6 +210,8 @@ func init() {
 		bugCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See devnetcmd.go:
+		devnetCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -76,10 +76,18 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TestnetFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
+			utils.StakingCommitFlag,
+			utils.StakingReadOnlyFlag,
+			utils.VoteHistoryFlag,
+			utils.VoteHistoryRetentionFlag,
+			utils.StakeIntentHorizonFlag,
 			utils.BerithStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
+			utils.LightMinServersFlag,
+			utils.LightMaxServersFlag,
+			utils.LightRetrieveTimeoutFlag,
 			utils.LightKDFFlag,
 			utils.WhitelistFlag,
 		},
@@ -146,6 +154,14 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.RPCEthCompatFlag,
+			utils.RPCLogsMaxRangeFlag,
+			utils.RPCLogsMaxResultsFlag,
+			utils.RPCLogsTimeoutFlag,
+			utils.PersonalMaxFailuresFlag,
+			utils.PersonalBackoffFlag,
+			utils.PersonalMaxBackoffFlag,
+			utils.PersonalGuardLocalFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
@@ -153,6 +169,12 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
+			utils.ConsoleIndentFlag,
+			utils.ConsolePrecisionFlag,
+			utils.ConsoleMaxScriptSizeFlag,
+			utils.ConsoleScriptTimeoutFlag,
+			utils.ConsoleStrictPreloadFlag,
+			utils.ConsoleHistoryFlag,
 			utils.HTTPEnabledFlag,
 			utils.HTTPListenAddrFlag,
 			utils.HTTPPortFlag,
@@ -192,6 +214,12 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MinerExtraDataFlag,
 			utils.MinerRecommitIntervalFlag,
 			utils.MinerNoVerfiyFlag,
+			utils.MinerTxOrderingFlag,
+			utils.MinerNoEmptyPrecommitFlag,
+			utils.MinerDroppedTxsFlag,
+			utils.MinerGasLimitDivisorFlag,
+			utils.MinerMaxUnclesFlag,
+			utils.MinerSoakFlag,
 		},
 	},
 	{
@@ -199,6 +227,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.GpoBlocksFlag,
 			utils.GpoPercentileFlag,
+			utils.GpoMaxPriceFlag,
 		},
 	},
 	{
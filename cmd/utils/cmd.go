@@ -31,6 +31,7 @@ import (
 
 	"github.com/BerithFoundation/berith-chain/berithdb"
 	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/consensus/bsrr"
 	"github.com/BerithFoundation/berith-chain/core"
 	"github.com/BerithFoundation/berith-chain/core/rawdb"
 	"github.com/BerithFoundation/berith-chain/core/types"
@@ -311,3 +312,54 @@ func ExportPreimages(db *berithdb.LDBDatabase, fn string) error {
 	log.Info("Exported preimages", "file", fn)
 	return nil
 }
+
+// ImportVoteHistory imports the election results of the epochs from the specified
+// file, as written by ExportVoteHistory.
+func ImportVoteHistory(db berithdb.Database, fn string) error {
+	log.Info("Importing vote history", "file", fn)
+
+	// Open the file handle and potentially unwrap the gzip stream
+	fh, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return err
+		}
+	}
+	imported, err := bsrr.ImportVoteHistory(db, reader)
+	if err != nil {
+		return err
+	}
+	log.Info("Imported vote history", "file", fn, "epochs", imported)
+	return nil
+}
+
+// ExportVoteHistory exports the stored election results of the epochs into the
+// specified file, compressed if the file name ends with .gz.
+func ExportVoteHistory(db berithdb.Database, fn string) error {
+	log.Info("Exporting vote history", "file", fn)
+
+	// Open the file handle and potentially wrap with a gzip stream
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	exported, err := bsrr.ExportVoteHistory(db, writer)
+	if err != nil {
+		return err
+	}
+	log.Info("Exported vote history", "file", fn, "epochs", exported)
+	return nil
+}
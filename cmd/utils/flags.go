@@ -169,6 +169,33 @@ var (
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "archive",
 	}
+	ValidateOnlyFlag = cli.BoolFlag{
+		Name:  "validate-only",
+		Usage: "Validate the genesis file and report its signers without writing anything",
+	}
+	defaultStakingCommit = berith.DefaultConfig.StakingCommit
+	StakingCommitFlag    = TextMarshalerFlag{
+		Name:  "stakingdb.commit",
+		Usage: `Staking list write mode ("sync" = fsync every list, "async" = fsync in batches, "async-unsafe" = no fsync)`,
+		Value: &defaultStakingCommit,
+	}
+	StakingReadOnlyFlag = cli.BoolFlag{
+		Name:  "stakingdb.readonly",
+		Usage: "Keep staking lists in memory only if the staking database can't be written",
+	}
+	VoteHistoryFlag = cli.BoolFlag{
+		Name:  "bsrr.votehistory",
+		Usage: "Store the election results of every epoch for bsrr.getHistoricalVoteResults",
+	}
+	VoteHistoryRetentionFlag = cli.Uint64Flag{
+		Name:  "bsrr.votehistory.retention",
+		Usage: "Number of most recent epochs to keep the election results of (0 = all)",
+	}
+	StakeIntentHorizonFlag = cli.DurationFlag{
+		Name:  "stakeintents.horizon",
+		Usage: "Time the stake transactions submitted through the node are followed up after being mined",
+		Value: berith.DefaultConfig.StakeIntentHorizon,
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
@@ -179,6 +206,21 @@ var (
 		Usage: "Maximum number of LES client peers",
 		Value: berith.DefaultConfig.LightPeers,
 	}
+	LightMinServersFlag = cli.IntFlag{
+		Name:  "lightminservers",
+		Usage: "Minimum number of LES servers connected before the light client retrieves data on demand",
+		Value: berith.DefaultConfig.LightMinServers,
+	}
+	LightMaxServersFlag = cli.IntFlag{
+		Name:  "lightmaxservers",
+		Usage: "Maximum number of LES servers the light client connects to at once, keeping the others discovered as backups",
+		Value: berith.DefaultConfig.LightMaxServers,
+	}
+	LightRetrieveTimeoutFlag = cli.DurationFlag{
+		Name:  "lightretrievetimeout",
+		Usage: "Time an on-demand retrieval of the light client may take if the request set no deadline (0 = no limit)",
+		Value: berith.DefaultConfig.LightRetrieveTimeout,
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -338,6 +380,33 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerTxOrderingFlag = cli.StringFlag{
+		Name:  "miner.txordering",
+		Usage: `Ordering of pending transactions in mined blocks ("price", "fifo" or "roundrobin")`,
+		Value: berith.DefaultConfig.MinerTxOrdering,
+	}
+	MinerNoEmptyPrecommitFlag = cli.BoolFlag{
+		Name:  "miner.noemptyprecommit",
+		Usage: "Don't seal an empty block while the pending transactions are executed",
+	}
+	MinerDroppedTxsFlag = cli.IntFlag{
+		Name:  "miner.droppedtxs",
+		Usage: "Number of transactions left out of the mined blocks recorded with the reason (0 = disabled)",
+	}
+	MinerGasLimitDivisorFlag = cli.Uint64Flag{
+		Name:  "miner.gaslimitdivisor",
+		Usage: "Divisor of the parent gas limit bounding the gas limit drift of mined blocks per block (at least the protocol's)",
+		Value: berith.DefaultConfig.MinerGasLimitDivisor,
+	}
+	MinerMaxUnclesFlag = cli.IntFlag{
+		Name:  "miner.maxuncles",
+		Usage: "Maximum number of side blocks kept as possible uncles, per local and remote set",
+		Value: berith.DefaultConfig.MinerMaxUncles,
+	}
+	MinerSoakFlag = cli.BoolFlag{
+		Name:  "miner.soak",
+		Usage: "Seal the blocks of a single signer development chain without delays, at the interval set by miner.setTargetBlockInterval",
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -397,6 +466,41 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCEthCompatFlag = cli.BoolFlag{
+		Name:  "rpc.ethcompat",
+		Usage: "Serve the eth namespace aliasing the berith methods for Ethereum tooling (add eth to the offered APIs)",
+	}
+	RPCLogsMaxRangeFlag = cli.Uint64Flag{
+		Name:  "rpc.logs.maxrange",
+		Usage: "Maximum number of blocks searched by a log query (0 = no limit)",
+	}
+	RPCLogsMaxResultsFlag = cli.IntFlag{
+		Name:  "rpc.logs.maxresults",
+		Usage: "Maximum number of logs returned by a log query (0 = no limit)",
+	}
+	RPCLogsTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.logs.timeout",
+		Usage: "Maximum execution time of a log query (0 = no limit)",
+	}
+	PersonalMaxFailuresFlag = cli.IntFlag{
+		Name:  "personal.maxfailures",
+		Usage: "Failed password attempts of an account or remote host tolerated before backing off (0 = no limit)",
+		Value: berith.DefaultConfig.PasswordGuard.MaxFailures,
+	}
+	PersonalBackoffFlag = cli.DurationFlag{
+		Name:  "personal.backoff",
+		Usage: "Time the password attempts are refused after the tolerated failures, doubled with every further failure",
+		Value: berith.DefaultConfig.PasswordGuard.Backoff,
+	}
+	PersonalMaxBackoffFlag = cli.DurationFlag{
+		Name:  "personal.maxbackoff",
+		Usage: "Maximum time the password attempts are refused after failures",
+		Value: berith.DefaultConfig.PasswordGuard.MaxBackoff,
+	}
+	PersonalGuardLocalFlag = cli.BoolFlag{
+		Name:  "personal.guardlocal",
+		Usage: "Limit the password attempts over IPC and in-process connections too",
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -471,6 +575,32 @@ var (
 		Name:  "preload",
 		Usage: "Comma separated list of JavaScript files to preload into the console",
 	}
+	ConsoleIndentFlag = cli.IntFlag{
+		Name:  "console.indent",
+		Usage: "Print console results as JSON indented by this many spaces (-1 = pretty print, 0 = compact)",
+		Value: -1,
+	}
+	ConsolePrecisionFlag = cli.StringFlag{
+		Name:  "console.precision",
+		Usage: "Reaction to BigNumbers beyond 2^53 implicitly converted to numbers in the console (warn, throw, off)",
+		Value: "warn",
+	}
+	ConsoleMaxScriptSizeFlag = cli.Int64Flag{
+		Name:  "console.maxscriptsize",
+		Usage: "Size limit in bytes of the JavaScript files run by the console (-1 = unlimited)",
+	}
+	ConsoleScriptTimeoutFlag = cli.DurationFlag{
+		Name:  "console.scripttimeout",
+		Usage: "Time the JavaScript files run by the console may run for (-1s = unlimited)",
+	}
+	ConsoleStrictPreloadFlag = cli.BoolFlag{
+		Name:  "console.strictpreload",
+		Usage: "Abort the console on failing preloaded JavaScript files instead of skipping them",
+	}
+	ConsoleHistoryFlag = cli.StringFlag{
+		Name:  "console.history",
+		Usage: `Path of the console history file, "xdg" for $XDG_STATE_HOME/berith/history (default = datadir/history)`,
+	}
 
 	// Network Settings
 	MaxPeersFlag = cli.IntFlag{
@@ -547,6 +677,11 @@ var (
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: berith.DefaultConfig.GPO.Percentile,
 	}
+	GpoMaxPriceFlag = BigFlag{
+		Name:  "gpomaxprice",
+		Usage: "Maximum gas price suggested by the gas price oracle",
+		Value: berith.DefaultConfig.GPO.MaxPrice,
+	}
 
 	// Metrics flags
 	MetricsEnabledFlag = cli.BoolFlag{
@@ -997,6 +1132,60 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	if ctx.GlobalIsSet(GpoPercentileFlag.Name) {
 		cfg.Percentile = ctx.GlobalInt(GpoPercentileFlag.Name)
 	}
+	if ctx.GlobalIsSet(GpoMaxPriceFlag.Name) {
+		cfg.MaxPrice = GlobalBig(ctx, GpoMaxPriceFlag.Name)
+	}
+}
+
+func setMiner(ctx *cli.Context, cfg *berith.Config) {
+	if ctx.GlobalIsSet(MinerNotifyFlag.Name) {
+		cfg.MinerNotify = strings.Split(ctx.GlobalString(MinerNotifyFlag.Name), ",")
+	}
+	if ctx.GlobalIsSet(MinerLegacyExtraDataFlag.Name) {
+		cfg.MinerExtraData = []byte(ctx.GlobalString(MinerLegacyExtraDataFlag.Name))
+	}
+	if ctx.GlobalIsSet(MinerExtraDataFlag.Name) {
+		cfg.MinerExtraData = []byte(ctx.GlobalString(MinerExtraDataFlag.Name))
+	}
+	if ctx.GlobalIsSet(MinerLegacyGasTargetFlag.Name) {
+		cfg.MinerGasFloor = ctx.GlobalUint64(MinerLegacyGasTargetFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerGasTargetFlag.Name) {
+		cfg.MinerGasFloor = ctx.GlobalUint64(MinerGasTargetFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerGasLimitFlag.Name) {
+		cfg.MinerGasCeil = ctx.GlobalUint64(MinerGasLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerLegacyGasPriceFlag.Name) {
+		cfg.MinerGasPrice = GlobalBig(ctx, MinerLegacyGasPriceFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerGasPriceFlag.Name) {
+		cfg.MinerGasPrice = GlobalBig(ctx, MinerGasPriceFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerRecommitIntervalFlag.Name) {
+		cfg.MinerRecommit = ctx.Duration(MinerRecommitIntervalFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerNoVerfiyFlag.Name) {
+		cfg.MinerNoverify = ctx.Bool(MinerNoVerfiyFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerTxOrderingFlag.Name) {
+		cfg.MinerTxOrdering = ctx.GlobalString(MinerTxOrderingFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerNoEmptyPrecommitFlag.Name) {
+		cfg.MinerNoEmptyPrecommit = ctx.GlobalBool(MinerNoEmptyPrecommitFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerDroppedTxsFlag.Name) {
+		cfg.MinerDroppedTxs = ctx.GlobalInt(MinerDroppedTxsFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerGasLimitDivisorFlag.Name) {
+		cfg.MinerGasLimitDivisor = ctx.GlobalUint64(MinerGasLimitDivisorFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerMaxUnclesFlag.Name) {
+		cfg.MinerMaxUncles = ctx.GlobalInt(MinerMaxUnclesFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerSoakFlag.Name) {
+		cfg.MinerSoak = ctx.GlobalBool(MinerSoakFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
@@ -1116,6 +1305,7 @@ func SetBerithConfig(ctx *cli.Context, stack *node.Node, cfg *berith.Config) {
 	setBerithbase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
+	setMiner(ctx, cfg)
 	setWhitelist(ctx, cfg)
 
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
@@ -1127,6 +1317,15 @@ func SetBerithConfig(ctx *cli.Context, stack *node.Node, cfg *berith.Config) {
 	if ctx.GlobalIsSet(LightPeersFlag.Name) {
 		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(LightMinServersFlag.Name) {
+		cfg.LightMinServers = ctx.GlobalInt(LightMinServersFlag.Name)
+	}
+	if ctx.GlobalIsSet(LightMaxServersFlag.Name) {
+		cfg.LightMaxServers = ctx.GlobalInt(LightMaxServersFlag.Name)
+	}
+	if ctx.GlobalIsSet(LightRetrieveTimeoutFlag.Name) {
+		cfg.LightRetrieveTimeout = ctx.GlobalDuration(LightRetrieveTimeoutFlag.Name)
+	}
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -1140,48 +1339,58 @@ func SetBerithConfig(ctx *cli.Context, stack *node.Node, cfg *berith.Config) {
 	}
 	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
 
+	if ctx.GlobalIsSet(StakingCommitFlag.Name) {
+		cfg.StakingCommit = *GlobalTextMarshaler(ctx, StakingCommitFlag.Name).(*staking.CommitMode)
+	}
+	if ctx.GlobalIsSet(StakingReadOnlyFlag.Name) {
+		cfg.StakingReadOnly = ctx.GlobalBool(StakingReadOnlyFlag.Name)
+	}
+	if ctx.GlobalIsSet(VoteHistoryFlag.Name) {
+		cfg.VoteHistory = ctx.GlobalBool(VoteHistoryFlag.Name)
+	}
+	if ctx.GlobalIsSet(VoteHistoryRetentionFlag.Name) {
+		cfg.VoteHistoryRetention = ctx.GlobalUint64(VoteHistoryRetentionFlag.Name)
+	}
+	if ctx.GlobalIsSet(StakeIntentHorizonFlag.Name) {
+		cfg.StakeIntentHorizon = ctx.GlobalDuration(StakeIntentHorizonFlag.Name)
+	}
+
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieDirtyCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
-	if ctx.GlobalIsSet(MinerNotifyFlag.Name) {
-		cfg.MinerNotify = strings.Split(ctx.GlobalString(MinerNotifyFlag.Name), ",")
-	}
 	if ctx.GlobalIsSet(DocRootFlag.Name) {
 		cfg.DocRoot = ctx.GlobalString(DocRootFlag.Name)
 	}
-	if ctx.GlobalIsSet(MinerLegacyExtraDataFlag.Name) {
-		cfg.MinerExtraData = []byte(ctx.GlobalString(MinerLegacyExtraDataFlag.Name))
-	}
-	if ctx.GlobalIsSet(MinerExtraDataFlag.Name) {
-		cfg.MinerExtraData = []byte(ctx.GlobalString(MinerExtraDataFlag.Name))
+	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
+		// TODO(fjl): force-enable this in --dev mode
+		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
-	if ctx.GlobalIsSet(MinerLegacyGasTargetFlag.Name) {
-		cfg.MinerGasFloor = ctx.GlobalUint64(MinerLegacyGasTargetFlag.Name)
+	if ctx.GlobalIsSet(RPCEthCompatFlag.Name) {
+		cfg.EthCompat = ctx.GlobalBool(RPCEthCompatFlag.Name)
 	}
-	if ctx.GlobalIsSet(MinerGasTargetFlag.Name) {
-		cfg.MinerGasFloor = ctx.GlobalUint64(MinerGasTargetFlag.Name)
+	if ctx.GlobalIsSet(RPCLogsMaxRangeFlag.Name) {
+		cfg.LogLimits.MaxBlockRange = ctx.GlobalUint64(RPCLogsMaxRangeFlag.Name)
 	}
-	if ctx.GlobalIsSet(MinerGasLimitFlag.Name) {
-		cfg.MinerGasCeil = ctx.GlobalUint64(MinerGasLimitFlag.Name)
+	if ctx.GlobalIsSet(RPCLogsMaxResultsFlag.Name) {
+		cfg.LogLimits.MaxResults = ctx.GlobalInt(RPCLogsMaxResultsFlag.Name)
 	}
-	if ctx.GlobalIsSet(MinerLegacyGasPriceFlag.Name) {
-		cfg.MinerGasPrice = GlobalBig(ctx, MinerLegacyGasPriceFlag.Name)
+	if ctx.GlobalIsSet(RPCLogsTimeoutFlag.Name) {
+		cfg.LogLimits.Timeout = ctx.GlobalDuration(RPCLogsTimeoutFlag.Name)
 	}
-	if ctx.GlobalIsSet(MinerGasPriceFlag.Name) {
-		cfg.MinerGasPrice = GlobalBig(ctx, MinerGasPriceFlag.Name)
+	if ctx.GlobalIsSet(PersonalMaxFailuresFlag.Name) {
+		cfg.PasswordGuard.MaxFailures = ctx.GlobalInt(PersonalMaxFailuresFlag.Name)
 	}
-	if ctx.GlobalIsSet(MinerRecommitIntervalFlag.Name) {
-		cfg.MinerRecommit = ctx.Duration(MinerRecommitIntervalFlag.Name)
+	if ctx.GlobalIsSet(PersonalBackoffFlag.Name) {
+		cfg.PasswordGuard.Backoff = ctx.GlobalDuration(PersonalBackoffFlag.Name)
 	}
-	if ctx.GlobalIsSet(MinerNoVerfiyFlag.Name) {
-		cfg.MinerNoverify = ctx.Bool(MinerNoVerfiyFlag.Name)
+	if ctx.GlobalIsSet(PersonalMaxBackoffFlag.Name) {
+		cfg.PasswordGuard.MaxBackoff = ctx.GlobalDuration(PersonalMaxBackoffFlag.Name)
 	}
-	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
-		// TODO(fjl): force-enable this in --dev mode
-		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
+	if ctx.GlobalIsSet(PersonalGuardLocalFlag.Name) {
+		cfg.PasswordGuard.GuardLocal = ctx.GlobalBool(PersonalGuardLocalFlag.Name)
 	}
 
 	if ctx.GlobalIsSet(EWASMInterpreterFlag.Name) {
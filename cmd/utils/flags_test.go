@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/berith"
+	"github.com/BerithFoundation/berith-chain/miner"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// newMinerContext creates a command line context with the miner flags parsed
+// from the given arguments.
+func newMinerContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range []cli.Flag{
+		MinerGasTargetFlag,
+		MinerGasLimitFlag,
+		MinerExtraDataFlag,
+		MinerRecommitIntervalFlag,
+		MinerTxOrderingFlag,
+		MinerNoEmptyPrecommitFlag,
+		MinerGasLimitDivisorFlag,
+		MinerMaxUnclesFlag,
+	} {
+		f.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+// Tests that without miner flags the worker runs with the miner defaults.
+func TestMinerConfigDefaults(t *testing.T) {
+	cfg := berith.DefaultConfig
+	setMiner(newMinerContext(t), &cfg)
+
+	have, err := cfg.MinerConfig()
+	if err != nil {
+		t.Fatalf("failed to create miner config: %v", err)
+	}
+	want := miner.DefaultConfig
+	want.ExtraData = have.ExtraData // Filled with the client version
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("miner config mismatch:\nhave %+v\nwant %+v", have, want)
+	}
+}
+
+// Tests that the miner flags land in the miner config.
+func TestMinerConfigFlags(t *testing.T) {
+	cfg := berith.DefaultConfig
+	setMiner(newMinerContext(t,
+		"--miner.gastarget", "1000",
+		"--miner.gaslimit", "2000",
+		"--miner.extradata", "extra",
+		"--miner.recommit", "5s",
+		"--miner.txordering", "fifo",
+		"--miner.noemptyprecommit",
+		"--miner.gaslimitdivisor", "2048",
+		"--miner.maxuncles", "16",
+	), &cfg)
+
+	have, err := cfg.MinerConfig()
+	if err != nil {
+		t.Fatalf("failed to create miner config: %v", err)
+	}
+	want := miner.Config{
+		Recommit:         5 * time.Second,
+		GasFloor:         1000,
+		GasCeil:          2000,
+		ExtraData:        []byte("extra"),
+		Ordering:         miner.TxOrderingFIFO,
+		NoEmptyPrecommit: true,
+		MaxUncles:        16,
+		GasLimitDivisor:  2048,
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("miner config mismatch:\nhave %+v\nwant %+v", have, want)
+	}
+
+	setMiner(newMinerContext(t, "--miner.txordering", "random"), &cfg)
+	if _, err := cfg.MinerConfig(); err == nil {
+		t.Errorf("invalid ordering accepted")
+	}
+}
@@ -16,13 +16,51 @@ Consensus algorithm related API
 package bsrr
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
 	"github.com/BerithFoundation/berith-chain/berith/selection"
+	"github.com/BerithFoundation/berith-chain/berith/staking"
 	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
 	"github.com/BerithFoundation/berith-chain/consensus"
+	"github.com/BerithFoundation/berith-chain/core/state"
 	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/rlp"
 	"github.com/BerithFoundation/berith-chain/rpc"
 )
 
+// rejectionReasons maps the errors of the header verification to a description
+// of the check that failed.
+var rejectionReasons = map[error]string{
+	errUnknownBlock:              "header has no block number",
+	consensus.ErrFutureBlock:     "timestamp is too far in the future",
+	errMissingVanity:             "extra-data is missing the 32 byte vanity prefix",
+	errMissingSignature:          "extra-data is missing the 65 byte signature suffix",
+	errExtraSigners:              "extra-data of a non-checkpoint block contains a signer list",
+	errInvalidCheckpointSigners:  "signer list of the checkpoint block is not a multiple of 20 bytes",
+	errInvalidMixDigest:          "mix digest is not zero",
+	errInvalidUncleHash:          "uncle hash is not the hash of an empty uncle list",
+	errInvalidNonce:              "nonce is zero or does not match the rank of the coinbase",
+	consensus.ErrUnknownAncestor: "parent block or stake target block is unknown",
+	ErrInvalidTimestamp:          "timestamp is earlier than the parent's timestamp plus the block period",
+	errUnauthorizedSigner:        "coinbase is not an elected block creator",
+	errOutOfRank:                 "coinbase is ranked beyond the number of allowed block creators",
+	errInvalidDifficulty:         "difficulty does not match the score of the coinbase",
+}
+
+// compareStakersLimit is the maximum number of stakers compared in a single
+// compareStakers call, larger staker sets are paginated.
+const compareStakersLimit = 1000
+
+// rankStabilityLimit is the maximum number of target blocks whose elections are
+// replayed by a single RankStability call.
+const rankStabilityLimit = 1000
+
 // API is a user facing RPC API to allow controlling the signer and voting
 // mechanisms of the proof-of-authority scheme.
 type API struct {
@@ -154,6 +192,50 @@ func (api *API) GetJoinRatio(address common.Address, number *rpc.BlockNumber) (f
 	return roi, nil
 }
 
+// Rewards is the block reward credited to the behind balance of an account in
+// a block and the behind balance released to its main balance in the block.
+type Rewards struct {
+	Credited *hexutil.Big `json:"credited"`
+	Released *hexutil.Big `json:"released"`
+}
+
+/*
+[BERITH]
+Function that returns the reward credited to the behind balance of the account
+in the given block and the behind balance released to its main balance
+*/
+func (api *API) GetRewards(address common.Address, number rpc.BlockNumber) (*Rewards, error) {
+	header, err := headerByNumberOrHash(api.chain, rpc.BlockNumberOrHashWithNumber(number))
+	if err != nil {
+		return nil, err
+	}
+	rewards := &Rewards{Credited: new(hexutil.Big), Released: new(hexutil.Big)}
+	if header.Coinbase == address {
+		rewards.Credited = (*hexutil.Big)(getReward(api.chain.Config(), header))
+	}
+	if header.Number.Sign() == 0 {
+		return rewards, nil
+	}
+	parent := api.chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	before, err := api.chain.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	after, err := api.chain.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	// At most the oldest behind balance is released per block
+	prev, cur := before.GetBehindBalance(address), after.GetBehindBalance(address)
+	if len(prev) > 0 && (len(cur) == 0 || cur[0].Number.Cmp(prev[0].Number) != 0) {
+		rewards.Released = (*hexutil.Big)(prev[0].Balance)
+	}
+	return rewards, nil
+}
+
 // GetSignersAtHash retrieves the list of authorized signers at the specified block.
 func (api *API) GetSigners() ([]common.Address, error) {
 	header := api.chain.CurrentHeader()
@@ -174,3 +256,686 @@ func (api *API) GetSigners() ([]common.Address, error) {
 	}
 	return signers, nil
 }
+
+/*
+[BERITH]
+Function that returns the signers listed in the extra-data of the genesis block,
+in the order they are listed
+*/
+func (api *API) GenesisSigners() ([]common.Address, error) {
+	genesis := api.chain.GetHeaderByNumber(0)
+	if genesis == nil {
+		return nil, errUnknownBlock
+	}
+	return api.bsrr.getSignersFromExtraData(genesis)
+}
+
+/*
+[BERITH]
+Function that runs the header verification on the given RLP encoded header
+and describes the first check it fails
+*/
+func (api *API) ExplainRejection(headerRLP hexutil.Bytes) (string, error) {
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(headerRLP, header); err != nil {
+		return "", err
+	}
+	return api.bsrr.explainRejection(api.chain, header), nil
+}
+
+// explainRejection verifies the header the same way importing its block would,
+// and returns a human readable description of the first failing check.
+func (c *BSRR) explainRejection(chain consensus.ChainReader, header *types.Header) string {
+	err := c.verifyHeader(chain, header, nil)
+	if err == nil && header.Number.Sign() > 0 && header.Coinbase != common.HexToAddress("0") {
+		parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		_, err = c.verifyCreator(chain, header, parent)
+	}
+	if err == nil {
+		return "header passes all checks"
+	}
+	if reason, ok := rejectionReasons[err]; ok {
+		return fmt.Sprintf("%s (%v)", reason, err)
+	}
+	return err.Error()
+}
+
+// StakersDiff is the difference between the staking lists of two blocks.
+type StakersDiff struct {
+	Added        []common.Address `json:"added"`
+	Removed      []common.Address `json:"removed"`
+	PointChanges []PointChange    `json:"pointChanges"`
+	Note         string           `json:"note,omitempty"`
+	Next         *hexutil.Uint64  `json:"next,omitempty"` // Cursor of the next page, nil on the last page
+}
+
+// PointChange is the selection point of a staker in two blocks. A point is nil
+// if the state of its block is not available.
+type PointChange struct {
+	Address common.Address `json:"address"`
+	Before  *hexutil.Big   `json:"before"`
+	After   *hexutil.Big   `json:"after"`
+}
+
+/*
+[BERITH]
+Function that compares the staking lists and selection points of two blocks.
+The stakers of both blocks are compared in address order, at most compareStakersLimit
+of them per call. The returned cursor continues the comparison in the next call.
+*/
+func (api *API) CompareStakers(blockNrOrHashA, blockNrOrHashB rpc.BlockNumberOrHash, cursor *hexutil.Uint64) (*StakersDiff, error) {
+	headerA, err := headerByNumberOrHash(api.chain, blockNrOrHashA)
+	if err != nil {
+		return nil, err
+	}
+	headerB, err := headerByNumberOrHash(api.chain, blockNrOrHashB)
+	if err != nil {
+		return nil, err
+	}
+
+	stksA, err := api.bsrr.peekStakers(api.chain, headerA.Number.Uint64(), headerA.Hash())
+	if err != nil {
+		return nil, err
+	}
+	stksB, err := api.bsrr.peekStakers(api.chain, headerB.Number.Uint64(), headerB.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the stakers of both blocks in address order
+	inA, inB := make(map[common.Address]bool), make(map[common.Address]bool)
+	var stakers []common.Address
+	for _, addr := range stksA.AsList() {
+		inA[addr] = true
+		stakers = append(stakers, addr)
+	}
+	for _, addr := range stksB.AsList() {
+		inB[addr] = true
+		if !inA[addr] {
+			stakers = append(stakers, addr)
+		}
+	}
+	sort.Slice(stakers, func(i, j int) bool {
+		return bytes.Compare(stakers[i][:], stakers[j][:]) < 0
+	})
+
+	start := uint64(0)
+	if cursor != nil {
+		start = uint64(*cursor)
+	}
+	if start > uint64(len(stakers)) {
+		start = uint64(len(stakers))
+	}
+	end := start + compareStakersLimit
+	if end > uint64(len(stakers)) {
+		end = uint64(len(stakers))
+	}
+
+	diff := &StakersDiff{
+		Added:        make([]common.Address, 0),
+		Removed:      make([]common.Address, 0),
+		PointChanges: make([]PointChange, 0),
+	}
+	if end < uint64(len(stakers)) {
+		next := hexutil.Uint64(end)
+		diff.Next = &next
+	}
+
+	// The points are only compared if both states are available
+	stateA, errA := api.chain.StateAt(headerA.Root)
+	stateB, errB := api.chain.StateAt(headerB.Root)
+	switch {
+	case errA != nil && errB != nil:
+		diff.Note = fmt.Sprintf("states of blocks %d and %d are not available, points are omitted", headerA.Number, headerB.Number)
+	case errA != nil:
+		diff.Note = fmt.Sprintf("state of block %d is not available, points before are omitted", headerA.Number)
+	case errB != nil:
+		diff.Note = fmt.Sprintf("state of block %d is not available, points after are omitted", headerB.Number)
+	}
+
+	for _, addr := range stakers[start:end] {
+		switch {
+		case !inA[addr]:
+			diff.Added = append(diff.Added, addr)
+		case !inB[addr]:
+			diff.Removed = append(diff.Removed, addr)
+		}
+		before, after := pointAt(stateA, addr), pointAt(stateB, addr)
+		if before != nil && after != nil && before.ToInt().Cmp(after.ToInt()) == 0 {
+			continue
+		}
+		diff.PointChanges = append(diff.PointChanges, PointChange{Address: addr, Before: before, After: after})
+	}
+	return diff, nil
+}
+
+// StakersChurn is the stakers who joined and left the staking list between
+// two blocks.
+type StakersChurn struct {
+	Added   []common.Address `json:"added"`
+	Removed []common.Address `json:"removed"`
+}
+
+/*
+[BERITH]
+Function that returns the stakers who joined and left the staking list
+between two blocks
+*/
+func (api *API) StakersDiff(from, to rpc.BlockNumber) (*StakersChurn, error) {
+	added, removed, err := api.bsrr.StakersDiff(api.chain, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &StakersChurn{Added: added, Removed: removed}, nil
+}
+
+// StakersDiff returns the stakers in the staking list of block to but not of
+// block from, and those in the list of block from but not of block to, both
+// in address order. Block from must not be after block to.
+func (c *BSRR) StakersDiff(chain consensus.ChainReader, from, to rpc.BlockNumber) (added, removed []common.Address, err error) {
+	fromHeader, err := headerByNumberOrHash(chain, rpc.BlockNumberOrHashWithNumber(from))
+	if err != nil {
+		return nil, nil, err
+	}
+	toHeader, err := headerByNumberOrHash(chain, rpc.BlockNumberOrHashWithNumber(to))
+	if err != nil {
+		return nil, nil, err
+	}
+	if fromHeader.Number.Cmp(toHeader.Number) > 0 {
+		return nil, nil, fmt.Errorf("invalid range: block %d is after block %d", fromHeader.Number, toHeader.Number)
+	}
+	fromStks, err := c.peekStakers(chain, fromHeader.Number.Uint64(), fromHeader.Hash())
+	if err != nil {
+		return nil, nil, err
+	}
+	toStks, err := c.peekStakers(chain, toHeader.Number.Uint64(), toHeader.Hash())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	added, removed = make([]common.Address, 0), make([]common.Address, 0)
+	for _, addr := range toStks.AsList() {
+		if !fromStks.IsContain(addr) {
+			added = append(added, addr)
+		}
+	}
+	for _, addr := range fromStks.AsList() {
+		if !toStks.IsContain(addr) {
+			removed = append(removed, addr)
+		}
+	}
+	for _, list := range [][]common.Address{added, removed} {
+		sort.Slice(list, func(i, j int) bool {
+			return bytes.Compare(list[i][:], list[j][:]) < 0
+		})
+	}
+	return added, removed, nil
+}
+
+// StakerChange is a change of the staker set caused by a transaction.
+type StakerChange struct {
+	Tx      common.Hash    `json:"tx"`
+	Address common.Address `json:"address"`
+	Added   bool           `json:"added"` // Whether the sender was added or removed
+}
+
+/*
+[BERITH]
+Function that replays the staker set changes caused by the transactions of a
+block, in the order of the transactions, as the body verification applies them
+*/
+func (api *API) TraceStakerChanges(blockNrOrHash rpc.BlockNumberOrHash) ([]StakerChange, error) {
+	header, err := headerByNumberOrHash(api.chain, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	block := api.chain.GetBlock(header.Hash(), header.Number.Uint64())
+	if block == nil {
+		return nil, errUnknownBlock
+	}
+	changes := make([]StakerChange, 0)
+	_, err = stakerChanges(api.chain, block.Transactions(), block.Number(), func(tx common.Hash, addr common.Address, added bool) {
+		changes = append(changes, StakerChange{Tx: tx, Address: addr, Added: added})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+/*
+[BERITH]
+Function that returns how often the rank 1 block creator changed between the
+last target blocks
+*/
+func (api *API) RankStability(window int) (float64, error) {
+	return api.bsrr.RankStability(api.chain, window)
+}
+
+// RankStability returns the share of the consecutive pairs among the last window
+// target blocks, up to the one of the block on top of the current head, that
+// elected different rank 1 block creators. A rate of 0 means the same staker was
+// elected on every target block, a rate of 1 that the rank 1 creator changed on
+// every block.
+func (c *BSRR) RankStability(chain consensus.ChainReader, window int) (churnRate float64, err error) {
+	if window < 2 || window > rankStabilityLimit {
+		return 0, fmt.Errorf("invalid window %d, must be between 2 and %d", window, rankStabilityLimit)
+	}
+	head := chain.CurrentHeader()
+	if head == nil {
+		return 0, errUnknownBlock
+	}
+	target, exist := c.getStakeTargetBlock(chain, head)
+	if !exist {
+		return 0, consensus.ErrUnknownAncestor
+	}
+	// The creators are elected on the staking lists from the first epoch on
+	last := target.Number.Uint64()
+	if last < c.config.Epoch || last-c.config.Epoch+1 < uint64(window) {
+		return 0, fmt.Errorf("window %d exceeds the target blocks", window)
+	}
+	var (
+		creators = make([]common.Address, 0, window)
+		failure  error
+	)
+	err = consensus.HeadersBetween(chain, last-uint64(window)+1, last, func(header *types.Header) bool {
+		var creator common.Address
+		if creator, failure = c.rankOneCreator(chain, header); failure != nil {
+			return false
+		}
+		creators = append(creators, creator)
+		return true
+	})
+	switch {
+	case err == consensus.ErrUnknownHeader:
+		return 0, errUnknownBlock
+	case err != nil:
+		return 0, err
+	case failure != nil:
+		return 0, failure
+	}
+	return calcChurnRate(creators), nil
+}
+
+// rankOneCreator returns the staker elected with rank 1 on the target block, or
+// the zero address if there are no stakers.
+func (c *BSRR) rankOneCreator(chain consensus.ChainReader, target *types.Header) (common.Address, error) {
+	stks, err := c.peekStakers(chain, target.Number.Uint64(), target.Hash())
+	if err != nil {
+		return common.Address{}, err
+	}
+	states, err := chain.StateAt(target.Root)
+	if err != nil {
+		return common.Address{}, err
+	}
+	for addr, result := range selection.SelectBlockCreator(chain.Config(), target.Number.Uint64(), target.Hash(), stks, states) {
+		if result.Rank == 1 {
+			return addr, nil
+		}
+	}
+	return common.Address{}, nil
+}
+
+// calcChurnRate returns the share of the consecutive creators that differ.
+func calcChurnRate(creators []common.Address) float64 {
+	if len(creators) < 2 {
+		return 0
+	}
+	changes := 0
+	for i := 1; i < len(creators); i++ {
+		if creators[i] != creators[i-1] {
+			changes++
+		}
+	}
+	return float64(changes) / float64(len(creators)-1)
+}
+
+// BlockPreview is the outcome of the election for the block on top of the
+// current head, as Prepare and Seal would compute it for a signer.
+type BlockPreview struct {
+	Number       hexutil.Uint64 `json:"number"`
+	ParentHash   common.Hash    `json:"parentHash"`
+	TargetNumber hexutil.Uint64 `json:"targetNumber"`
+	TargetHash   common.Hash    `json:"targetHash"`
+	Signer       common.Address `json:"signer"`
+	Elected      bool           `json:"elected"`
+	Rank         int            `json:"rank"`       // Rank of the signer, 0 if not elected
+	Difficulty   *hexutil.Big   `json:"difficulty"` // Score of the signer, 0 if not elected
+	Time         hexutil.Uint64 `json:"timestamp"`  // Timestamp Prepare would set now
+	RankDelay    int64          `json:"rankDelay"`  // Delay of the rank in milliseconds
+	Delay        int64          `json:"delay"`      // Total delay Seal would wait in milliseconds
+}
+
+/*
+[BERITH]
+Function that previews the difficulty, rank and sealing delay of the block on
+top of the current head for the given address, or the configured signer if no
+address is given. Nothing is mined and no staking list is stored.
+*/
+func (api *API) PreviewNextBlock(signer *common.Address) (*BlockPreview, error) {
+	if signer == nil {
+		api.bsrr.lock.RLock()
+		configured := api.bsrr.signer
+		api.bsrr.lock.RUnlock()
+
+		if configured == (common.Address{}) {
+			return nil, errNoSigner
+		}
+		signer = &configured
+	}
+	return api.bsrr.PreviewNextBlock(api.chain, *signer)
+}
+
+// PreviewNextBlock runs the election of Prepare and Seal for the block on top of
+// the current head read-only: the header fields and the sealing delay for the
+// signer are computed but nothing is sealed, and staking lists rebuilt on the way
+// are neither cached nor stored.
+func (c *BSRR) PreviewNextBlock(chain consensus.ChainReader, signer common.Address) (*BlockPreview, error) {
+	parent := chain.CurrentHeader()
+	if parent == nil {
+		return nil, errUnknownBlock
+	}
+	target, exist := c.getStakeTargetBlock(chain, parent)
+	if !exist {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	preview := &BlockPreview{
+		Number:       hexutil.Uint64(parent.Number.Uint64() + 1),
+		ParentHash:   parent.Hash(),
+		TargetNumber: hexutil.Uint64(target.Number.Uint64()),
+		TargetHash:   target.Hash(),
+		Signer:       signer,
+		Difficulty:   new(hexutil.Big),
+	}
+	// The timestamp is the one of Prepare, the wait until it the one of Seal
+	now := time.Now()
+	timestamp := parent.Time.Uint64() + c.config.Period
+	if timestamp < uint64(now.Unix()) {
+		timestamp = uint64(now.Unix())
+	}
+	preview.Time = hexutil.Uint64(timestamp)
+
+	signers, err := c.signersAt(chain, target, c.peekStakers)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := signers.signersMap()[signer]; !ok {
+		return preview, nil
+	}
+	diff, rank := c.electSigner(signer, chain, target, c.peekStakers)
+	if rank < 1 {
+		return preview, nil
+	}
+	rankDelay, err := c.getDelay(rank)
+	if err != nil {
+		return nil, err
+	}
+	delay := time.Unix(int64(timestamp), 0).Sub(now) + rankDelay
+	if delay < 0 {
+		delay = 0
+	}
+	preview.Elected = true
+	preview.Rank = rank
+	preview.Difficulty = (*hexutil.Big)(diff)
+	preview.RankDelay = int64(rankDelay / time.Millisecond)
+	preview.Delay = int64(delay / time.Millisecond)
+	return preview, nil
+}
+
+// ProducerPrediction is a signer elected for the block on top of the current
+// head, with the rank and score of its election. It is encoded in the canonical
+// JSON form of selection.Vote.
+type ProducerPrediction struct {
+	Rank    int
+	Address common.Address
+	Score   *hexutil.Big // Difficulty the signer would seal the block with
+}
+
+// MarshalJSON encodes the prediction in the canonical JSON form of selection.Vote.
+func (p ProducerPrediction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(selection.Vote{Address: p.Address, Rank: p.Rank, Score: p.Score.ToInt()})
+}
+
+// UnmarshalJSON decodes the prediction from the canonical JSON form of
+// selection.Vote.
+func (p *ProducerPrediction) UnmarshalJSON(input []byte) error {
+	var vote selection.Vote
+	if err := json.Unmarshal(input, &vote); err != nil {
+		return err
+	}
+	p.Rank, p.Address, p.Score = vote.Rank, vote.Address, (*hexutil.Big)(vote.Score)
+	return nil
+}
+
+/*
+[BERITH]
+Function that predicts the producers of the block on top of the current head,
+returning the given number of elected signers by ascending rank, or all of them
+if the count isn't positive
+*/
+func (api *API) PredictProducers(count int) ([]ProducerPrediction, error) {
+	return api.bsrr.PredictProducers(api.chain, count)
+}
+
+// PredictProducers runs the election for the block on top of the current head
+// read-only, like PreviewNextBlock does for a single signer, and returns the
+// first count elected signers by ascending rank.
+func (c *BSRR) PredictProducers(chain consensus.ChainReader, count int) ([]ProducerPrediction, error) {
+	parent := chain.CurrentHeader()
+	if parent == nil {
+		return nil, errUnknownBlock
+	}
+	target, exist := c.getStakeTargetBlock(chain, parent)
+	if !exist {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	signers, err := c.signersAt(chain, target, c.peekStakers)
+	if err != nil {
+		return nil, err
+	}
+	var stks staking.Stakers
+	if target.Number.Sign() != 0 {
+		if stks, err = c.peekStakers(chain, target.Number.Uint64(), target.Hash()); err != nil {
+			return nil, err
+		}
+	}
+	predictions := make([]ProducerPrediction, 0, len(signers))
+	if stks == nil || len(stks.AsList()) == 0 {
+		// The signers of the genesis, also falling back for missing stakers, are
+		// all elected first with the default score
+		for _, signer := range signers {
+			predictions = append(predictions, ProducerPrediction{Rank: 1, Address: signer, Score: (*hexutil.Big)(big.NewInt(diffWithoutStaker))})
+		}
+	} else {
+		stateDB, err := chain.StateAt(target.Root)
+		if err != nil {
+			return nil, err
+		}
+		results := selection.SelectBlockCreator(chain.Config(), target.Number.Uint64(), target.Hash(), stks, stateDB)
+		max := c.getMaxMiningCandidates(len(results))
+
+		elected := signers.signersMap()
+		for signer, result := range results {
+			if _, ok := elected[signer]; !ok || result.Rank < 1 || result.Rank > max {
+				continue
+			}
+			predictions = append(predictions, ProducerPrediction{Rank: result.Rank, Address: signer, Score: (*hexutil.Big)(result.Score)})
+		}
+	}
+	sort.Slice(predictions, func(i, j int) bool {
+		if predictions[i].Rank != predictions[j].Rank {
+			return predictions[i].Rank < predictions[j].Rank
+		}
+		return bytes.Compare(predictions[i].Address[:], predictions[j].Address[:]) < 0
+	})
+	if count > 0 && len(predictions) > count {
+		predictions = predictions[:count]
+	}
+	return predictions, nil
+}
+
+// NetworkParams are the consensus parameters the engine is running with.
+type NetworkParams struct {
+	Period       uint64       `json:"period"`       // Seconds between blocks
+	Epoch        uint64       `json:"epoch"`        // Blocks between staking list snapshots
+	ForkFactor   float64      `json:"forkFactor"`   // Share of the stakers elected as block creators
+	Rewards      *hexutil.Big `json:"rewards"`      // Block number the sealing rewards start at
+	StakeMinimum *hexutil.Big `json:"stakeMinimum"` // Minimum stake in wei to be elected
+}
+
+/*
+[BERITH]
+Function that returns the block period, epoch and election parameters of the
+engine, with the defaults filled in for the values missing from the genesis
+*/
+func (api *API) NetworkParams() *NetworkParams {
+	config := api.bsrr.config
+	return &NetworkParams{
+		Period:       config.Period,
+		Epoch:        config.Epoch,
+		ForkFactor:   config.ForkFactor,
+		Rewards:      (*hexutil.Big)(new(big.Int).Set(config.Rewards)),
+		StakeMinimum: (*hexutil.Big)(new(big.Int).Set(config.StakeMinimum)),
+	}
+}
+
+// EpochInfo is the position of a block within its epoch, along with the target
+// block the creators of the block on top of it are elected on.
+type EpochInfo struct {
+	Number               uint64       `json:"number"`               // Block the information is about
+	EpochLength          uint64       `json:"epochLength"`          // Blocks between staking list snapshots
+	CurrentEpochNumber   uint64       `json:"currentEpochNumber"`   // Epoch the block belongs to
+	EpochStartBlock      uint64       `json:"epochStartBlock"`      // Checkpoint block starting the epoch
+	NextCheckpointBlock  uint64       `json:"nextCheckpointBlock"`  // Checkpoint block starting the next epoch
+	BlocksUntilNextEpoch uint64       `json:"blocksUntilNextEpoch"` // Blocks to seal until the next checkpoint
+	StakeTargetBlock     *StakeTarget `json:"stakeTargetBlock"`     // Target block of the next block
+}
+
+// StakeTarget identifies the block whose staking list and state an election is
+// held on.
+type StakeTarget struct {
+	Number    uint64      `json:"number"`
+	Hash      common.Hash `json:"hash"`
+	Available bool        `json:"available"` // Whether the state of the target is available
+}
+
+/*
+[BERITH]
+Function that returns the epoch of the given block, or the current block if no
+block is given, and the stake target block of the block on top of it as the
+engine determines it when preparing and verifying that block
+*/
+func (api *API) EpochInfo(number *rpc.BlockNumber) (*EpochInfo, error) {
+	var header *types.Header
+	if number == nil || *number < 0 {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.bsrr.EpochInfo(api.chain, header)
+}
+
+// EpochInfo returns the epoch of the given header, and the stake target block of
+// the block on top of it. Checkpoint blocks start their epoch.
+func (c *BSRR) EpochInfo(chain consensus.ChainReader, header *types.Header) (*EpochInfo, error) {
+	var (
+		number = header.Number.Uint64()
+		epoch  = number / c.config.Epoch
+		next   = (epoch + 1) * c.config.Epoch
+	)
+	target, available := c.getStakeTargetBlock(chain, header)
+	if target == nil || target.Number == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	return &EpochInfo{
+		Number:               number,
+		EpochLength:          c.config.Epoch,
+		CurrentEpochNumber:   epoch,
+		EpochStartBlock:      epoch * c.config.Epoch,
+		NextCheckpointBlock:  next,
+		BlocksUntilNextEpoch: next - number,
+		StakeTargetBlock: &StakeTarget{
+			Number:    target.Number.Uint64(),
+			Hash:      target.Hash(),
+			Available: available,
+		},
+	}, nil
+}
+
+/*
+[BERITH]
+Function that returns the election results of the boundary block of the given
+epoch from the vote history, answered even if the state of the election target
+is pruned. Only the epochs processed with the vote history enabled are stored.
+*/
+func (api *API) GetHistoricalVoteResults(epoch hexutil.Uint64) (*EpochVotes, error) {
+	return api.bsrr.voteHistory().read(uint64(epoch))
+}
+
+/*
+[BERITH]
+Function that returns the election results of the stored epochs between from
+and to inclusive from the vote history, skipping the epochs that aren't stored
+*/
+func (api *API) GetHistoricalVoteResultsRange(from, to hexutil.Uint64) ([]*EpochVotes, error) {
+	return api.bsrr.voteHistory().readRange(uint64(from), uint64(to))
+}
+
+/*
+[BERITH]
+Function that returns the hash signed by the block creator of the given header,
+in the JSON form returned by getBlock
+*/
+func (api *API) SealHash(header json.RawMessage) (common.Hash, error) {
+	return headerSealHash(header)
+}
+
+// headerSealHash reconstructs a header from its JSON form and returns its seal
+// hash. If the JSON carries the hash of the header, it must match the hash of
+// the reconstructed header, so that fields lost on the way are detected.
+func headerSealHash(input []byte) (common.Hash, error) {
+	header := new(types.Header)
+	if err := json.Unmarshal(input, header); err != nil {
+		return common.Hash{}, fmt.Errorf("invalid header: %v", err)
+	}
+	var fields struct {
+		Hash *common.Hash `json:"hash"`
+	}
+	if err := json.Unmarshal(input, &fields); err != nil {
+		return common.Hash{}, fmt.Errorf("invalid header: %v", err)
+	}
+	if fields.Hash != nil && *fields.Hash != header.Hash() {
+		return common.Hash{}, fmt.Errorf("header hash mismatch: given %x, reconstructed %x", *fields.Hash, header.Hash())
+	}
+	return sealHash(header)
+}
+
+// pointAt returns the selection point of the address, or nil without a state.
+func pointAt(states *state.StateDB, addr common.Address) *hexutil.Big {
+	if states == nil {
+		return nil
+	}
+	return (*hexutil.Big)(states.GetPoint(addr))
+}
+
+// headerByNumberOrHash retrieves the header of the given block, the latest
+// block if it is identified by the latest or pending tag.
+func headerByNumberOrHash(chain consensus.ChainReader, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	var header *types.Header
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		header = chain.GetHeaderByHash(hash)
+	} else if number, ok := blockNrOrHash.Number(); ok {
+		if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+			header = chain.CurrentHeader()
+		} else {
+			header = chain.GetHeaderByNumber(uint64(number.Int64()))
+		}
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return header, nil
+}
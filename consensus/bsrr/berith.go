@@ -22,11 +22,14 @@ package bsrr
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"math/big"
+	"runtime"
+	"sort"
 	"sync"
 	"time"
 
@@ -38,12 +41,13 @@ import (
 	"github.com/BerithFoundation/berith-chain/berithdb"
 	"github.com/BerithFoundation/berith-chain/common"
 	"github.com/BerithFoundation/berith-chain/consensus"
+	"github.com/BerithFoundation/berith-chain/consensus/bsrr/extradata"
 	"github.com/BerithFoundation/berith-chain/consensus/misc"
 	"github.com/BerithFoundation/berith-chain/core/state"
 	"github.com/BerithFoundation/berith-chain/core/types"
 	"github.com/BerithFoundation/berith-chain/crypto"
-	"github.com/BerithFoundation/berith-chain/crypto/sha3"
 	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/metrics"
 	"github.com/BerithFoundation/berith-chain/params"
 	"github.com/BerithFoundation/berith-chain/rlp"
 	lru "github.com/hashicorp/golang-lru"
@@ -53,10 +57,17 @@ const (
 	inmemorySnapshots  = 128     // Number of recent vote snapshots to keep in memory
 	inmemorySigners    = 128 * 3 // Number of recent vote snapshots to keep in memory
 	inmemorySignatures = 4096    // Number of recent block signatures to keep in memory
+	inmemorySealIDs    = 128     // Number of sealing attempt correlation ids to keep in memory
 
 	termDelay  = 100 * time.Millisecond // Delay per signer in the same group
 	groupDelay = 1 * time.Second        // Delay per groups
 
+	defaultFutureBlockDrift = 2 * time.Second // Default clock drift tolerated for blocks from the future
+
+	defaultSignedRetention = 1024 // Heights below a released block its signer's records are kept for without a finality horizon
+
+	droppedLogInterval = 10 * time.Second // Minimum interval between the warnings about sealed blocks not read by the miner
+
 	commonDiff = 3 // A constant that specifies the maximum number of people in a group when dividing a signer's candidates into multiple groups
 )
 
@@ -69,12 +80,15 @@ var (
 
 	epochLength = uint64(360) // Default number of blocks after which to checkpoint and reset the pending votes
 
-	extraVanity = 32 // Fixed number of extra-data prefix bytes reserved for signer vanity
-	extraSeal   = 65 // Fixed number of extra-data suffix bytes reserved for signer seal
+	extraVanity = extradata.VanityLength // Fixed number of extra-data prefix bytes reserved for signer vanity
+	extraSeal   = extradata.SealLength   // Fixed number of extra-data suffix bytes reserved for signer seal
 
 	uncleHash = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
 
 	diffWithoutStaker = int64(1234)
+
+	sealDroppedCounter = metrics.NewRegisteredCounter("bsrr/seal/dropped", nil) // Sealed blocks not read by the miner
+	electionCounter    = metrics.NewRegisteredCounter("bsrr/elections", nil)    // Elections held on the staking lists of target blocks
 )
 
 // Various error messages to mark blocks invalid. These should be private to
@@ -88,21 +102,21 @@ var (
 
 	// errMissingVanity is returned if a block's extra-data section is shorter than
 	// 32 bytes, which is required to store the signer vanity.
-	errMissingVanity = errors.New("extra-data 32 byte vanity prefix missing")
+	errMissingVanity = extradata.ErrMissingVanity
 
 	// errMissingSignature is returned if a block's extra-data section doesn't seem
 	// to contain a 65 byte secp256k1 signature.
-	errMissingSignature = errors.New("extra-data 65 byte signature suffix missing")
+	errMissingSignature = extradata.ErrMissingSignature
 
 	// errExtraSigners is returned if non-checkpoint block contain signer data in
 	// their extra-data fields.
 	// 체크포인트 블록이 아닌데 서명자 목록을 포함하고 있을 경우
-	errExtraSigners = errors.New("non-checkpoint block contains extra signer list")
+	errExtraSigners = extradata.ErrExtraSigners
 
 	// errInvalidCheckpointSigners is returned if a checkpoint block contains an
 	// invalid list of signers (i.e. non divisible by 20 bytes).
 	// 체크포인트 블록이 유효하지 않은 서명자 목록을 포함하고 있을경우 (주소 길이인 20byte로 나누어 떨어져야 함)
-	errInvalidCheckpointSigners = errors.New("invalid signer list on checkpoint block")
+	errInvalidCheckpointSigners = extradata.ErrInvalidSigners
 
 	// errInvalidMixDigest is returned if a block's mix digest is non-zero.
 	errInvalidMixDigest = errors.New("non-zero mix digest")
@@ -126,15 +140,34 @@ var (
 
 	errNoData = errors.New("no data")
 
+	// errNoSigner is returned if a block is previewed without an address while
+	// the node has no signer configured.
+	errNoSigner = errors.New("no signer configured, an address is required")
+
+	// errDoubleSign is returned if the signer already sealed a different block at
+	// the same height, as signing both would be slashable equivocation.
+	errDoubleSign = errors.New("refusing to sign a second block at an already signed height")
+
 	// errInvalidNonce is returned if a nonce is less than or equals to 0.
 	errInvalidNonce = errors.New("invalid nonce")
 
+	// errOutOfRank is returned by verifyCreator if the coinbase of a block is a
+	// signer, but its rank is beyond the number of candidates allowed to create the
+	// block. Finalize reports it as errUnauthorizedSigner.
+	errOutOfRank = errors.New("signer out of rank")
+
 	errStakingList = errors.New("not found staking list")
 
 	errMissingState = errors.New("state missing")
 
+	// errReorgTooDeep is returned if rebuilding a staking list would replay more
+	// than MaxReorgDepth blocks, e.g. for a long side chain fed by a peer.
+	errReorgTooDeep = errors.New("staking list too far from the nearest stored list")
+
 	errCleanStakingDB = errors.New("fail to clean stakingDB")
 
+	errCommitStakingDB = errors.New("fail to commit stakingDB")
+
 	errBIP1 = errors.New("error when fork network to BIP1")
 )
 
@@ -142,46 +175,44 @@ var (
 // backing account.
 type SignerFn func(accounts.Account, []byte) ([]byte, error)
 
-// sigHash returns the hash which is used as input for the proof-of-authority
+// StakerTraceFn is a debug callback invoked for every change of the staker set
+// caused by a transaction, with whether the sender was added or removed.
+type StakerTraceFn func(tx common.Hash, addr common.Address, added bool)
+
+// sealHash returns the hash which is used as input for the proof-of-authority
 // signing. It is the hash of the entire header apart from the 65 byte signature
-// contained at the end of the extra data.
+// contained at the end of the extra data, implemented by the extradata package
+// for the engine, the sealHash API and the decoders of the extra-data alike. It
+// returns errMissingSignature if the extra data is shorter than 65 bytes.
+//
+// sealHash는 권한 증명 서명을 위한 입력으로 사용되는 해시를 반환한다.
+// extra data 끝에 포함된 65바이트 시그니처를 제외한 전체 헤더의 해시이다.
+func sealHash(header *types.Header) (hash common.Hash, err error) {
+	return extradata.SealHash(header)
+}
+
+// sigHash returns the seal hash of a header within the engine.
 //
 // Note, the method requires the extra data to be at least 65 bytes, otherwise it
 // panics. This is done to avoid accidentally using both forms (signature present
 // or not), which could be abused to produce different hashes for the same header.
-//
-// sigHash는 권한 증명 서명을 위한 입력으로 사용되는 해시를 반환한다.
-// extra data 끝에 포함된 65바이트 시그니처를 제외한 전체 헤더의 해시이다.
-func sigHash(header *types.Header) (hash common.Hash) {
-	hasher := sha3.NewKeccak256()
-
-	_ = rlp.Encode(hasher, []interface{}{
-		header.ParentHash,
-		header.UncleHash,
-		header.Coinbase,
-		header.Root,
-		header.TxHash,
-		header.ReceiptHash,
-		header.Bloom,
-		header.Difficulty,
-		header.Number,
-		header.GasLimit,
-		header.GasUsed,
-		header.Time,
-		header.Extra[:len(header.Extra)-65], // Yes, this will panic if extra is too short
-		header.MixDigest,
-		header.Nonce,
-	})
-	hasher.Sum(hash[:0])
+func sigHash(header *types.Header) common.Hash {
+	hash, err := sealHash(header)
+	if err != nil {
+		panic(err)
+	}
 	return hash
 }
 
-// ecrecover extracts the Berith account address from a signed header.
+// ecrecover extracts the Berith account address from a signed header, caching
+// it in the signature cache if one is given.
 func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, error) {
 	// If the signature's already cached, return that
 	hash := header.Hash()
-	if address, known := sigcache.Get(hash); known {
-		return address.(common.Address), nil
+	if sigcache != nil {
+		if address, known := sigcache.Get(hash); known {
+			return address.(common.Address), nil
+		}
 	}
 	// Retrieve the signature from the header extra-data
 	if len(header.Extra) < extraSeal {
@@ -197,7 +228,9 @@ func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, er
 	var signer common.Address
 	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
 
-	sigcache.Add(hash, signer)
+	if sigcache != nil {
+		sigcache.Add(hash, signer)
+	}
 	return signer, nil
 }
 
@@ -217,16 +250,39 @@ type BSRR struct {
 
 	proposals map[common.Address]bool // Current list of proposals we are pushing
 
+	signedLock sync.Mutex // Serialises the double sign guard over released blocks
+
+	sealIDs *lru.ARCCache // Correlation ids of the miner's sealing attempts by seal hash
+	states  *stateCache   // Availability of the states of the target blocks at the current head
+
+	selection     *pendingSelection // Election of the local signer for the pending block
+	selectionLock sync.Mutex        // Protects the pending selection
+
+	history *voteHistory // Store of the election results of the epochs, nil if disabled
+
+	fallbackWarned common.Hash // Last target block warned about falling back to the genesis signers
+	fallbackLock   sync.Mutex  // Protects the fallback warning
+
+	droppedWarned     common.Hash // Seal hash of the last dropped result warned about
+	droppedLogged     time.Time   // Time of the last warning about a dropped result
+	droppedSuppressed int         // Dropped results not warned about since the last warning
+	droppedLock       sync.Mutex  // Protects the dropped result warnings
+
+	stakerTrace StakerTraceFn // Debug callback of the staker set changes, nil if disabled
+	traceLock   sync.RWMutex  // Protects the staker trace callback
+
+	solo uint32 // Whether the blocks of a single signer chain are sealed without delays (atomic)
+
 	// The fields below are for testing only
 	rankGroup common.SequenceGroup // grouped by rank
 }
 
 /*
 [BERITH]
-Function to create a new BSRR structure
+Function that fills the unset or unworkable values of the configuration with
+the defaults, modifying and returning the given configuration
 */
-func New(config *params.BSRRConfig, db berithdb.Database) *BSRR {
-	conf := config
+func SanitizeConfig(conf *params.BSRRConfig) *params.BSRRConfig {
 	if conf.Epoch == 0 {
 		conf.Epoch = epochLength
 	}
@@ -258,11 +314,21 @@ func New(config *params.BSRRConfig, db berithdb.Database) *BSRR {
 	if conf.ForkFactor <= 0.0 || conf.ForkFactor > 1.0 {
 		conf.ForkFactor = ForkFactor
 	}
+	return conf
+}
+
+/*
+[BERITH]
+Function to create a new BSRR structure
+*/
+func New(config *params.BSRRConfig, db berithdb.Database) *BSRR {
+	conf := SanitizeConfig(config)
 
 	recents, _ := lru.NewARC(inmemorySnapshots)
 	signatures, _ := lru.NewARC(inmemorySignatures)
 	//[BERITH] Cache instance creation and sizing
 	cache, _ := lru.NewARC(inmemorySigners)
+	sealIDs, _ := lru.NewARC(inmemorySealIDs)
 
 	return &BSRR{
 		config:     conf,
@@ -270,6 +336,8 @@ func New(config *params.BSRRConfig, db berithdb.Database) *BSRR {
 		recents:    recents,
 		signatures: signatures,
 		cache:      cache,
+		sealIDs:    sealIDs,
+		states:     newStateCache(),
 		proposals:  make(map[common.Address]bool),
 		rankGroup:  &common.ArithmeticGroup{CommonDiff: commonDiff},
 	}
@@ -300,7 +368,64 @@ func (c *BSRR) VerifyHeader(chain consensus.ChainReader, header *types.Header, s
 // VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers. The
 // method returns a quit channel to abort the operations and a results channel to
 // retrieve the async verifications (the order is that of the input slice).
+//
+// Headers are verified concurrently by a pool of up to GOMAXPROCS workers, as
+// each of them only depends on its immediate parent from the batch or the chain.
 func (c *BSRR) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	workers := runtime.GOMAXPROCS(0)
+	if len(headers) < workers {
+		workers = len(headers)
+	}
+	if workers <= 1 {
+		return c.verifyHeadersSequential(chain, headers)
+	}
+	var (
+		inputs = make(chan int)
+		done   = make(chan int, workers)
+		errs   = make([]error, len(headers))
+		abort  = make(chan struct{})
+	)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for index := range inputs {
+				errs[index] = c.verifyHeader(chain, headers[index], headers[:index])
+				done <- index
+			}
+		}()
+	}
+	results := make(chan error, len(headers))
+	go func() {
+		defer close(inputs)
+		var (
+			in, out = 0, 0
+			checked = make([]bool, len(headers))
+			inputs  = inputs
+		)
+		for {
+			select {
+			case inputs <- in:
+				if in++; in == len(headers) {
+					// Reached end of headers. Stop sending to workers.
+					inputs = nil
+				}
+			case index := <-done:
+				for checked[index] = true; checked[out]; out++ {
+					results <- errs[out]
+					if out == len(headers)-1 {
+						return
+					}
+				}
+			case <-abort:
+				return
+			}
+		}
+	}()
+	return abort, results
+}
+
+// verifyHeadersSequential verifies a batch of headers one after the other in a
+// single goroutine, returning the results in the order of the headers.
+func (c *BSRR) verifyHeadersSequential(chain consensus.ChainReader, headers []*types.Header) (chan<- struct{}, <-chan error) {
 	abort := make(chan struct{})
 	results := make(chan error, len(headers))
 
@@ -318,6 +443,39 @@ func (c *BSRR) VerifyHeaders(chain consensus.ChainReader, headers []*types.Heade
 	return abort, results
 }
 
+// futureBlockDrift returns how far ahead of the local clock the timestamp of a
+// header may be, to tolerate signers with slightly fast clocks.
+func (c *BSRR) futureBlockDrift() time.Duration {
+	if c.config.FutureBlockDrift == 0 {
+		return defaultFutureBlockDrift
+	}
+	return time.Duration(c.config.FutureBlockDrift) * time.Second
+}
+
+// verifyTime checks that the header is not further in the future than the
+// allowed clock drift, returning consensus.ErrFutureBlock otherwise so that the
+// chain queues the block for delayed processing instead of discarding it.
+func (c *BSRR) verifyTime(header *types.Header, now time.Time) error {
+	if header.Time.Cmp(big.NewInt(now.Add(c.futureBlockDrift()).Unix())) > 0 {
+		return consensus.ErrFutureBlock
+	}
+	return nil
+}
+
+// sealDelay returns how long to wait before releasing the sealed header, which
+// Prepare timestamped at most a block period ahead of its clock. A longer delay
+// means the clock went backwards in between, so it is clamped to the period
+// instead of stalling the sealing until the clock catches up.
+func (c *BSRR) sealDelay(header *types.Header, now time.Time) time.Duration {
+	delay := time.Unix(header.Time.Int64(), 0).Sub(now)
+	period := time.Duration(c.config.Period) * time.Second
+	if delay > period+c.futureBlockDrift() {
+		log.Warn("Clock went backwards while sealing, clamping delay", "number", header.Number, "skew", common.PrettyDuration(delay-period), "delay", common.PrettyDuration(period))
+		return period
+	}
+	return delay
+}
+
 // verifyHeader checks whether a header conforms to the consensus rules.The
 // caller may optionally pass in a batch of parents (ascending order) to avoid
 // looking those up from the database. This is useful for concurrently verifying
@@ -334,8 +492,8 @@ func (c *BSRR) verifyHeader(chain consensus.ChainReader, header *types.Header, p
 
 	// Don't waste time checking blocks from the future
 	// Future block 검증 안함
-	if header.Time.Cmp(big.NewInt(time.Now().Unix())) > 0 {
-		return consensus.ErrFutureBlock
+	if err := c.verifyTime(header, time.Now()); err != nil {
+		return err
 	}
 	// Checkpoint blocks need to enforce zero beneficiary
 	// 체크포이트 블록은 수혜자가 0명이어야 한다
@@ -469,7 +627,7 @@ func (c *BSRR) Prepare(chain consensus.ChainReader, header *types.Header) error
 	// 타겟블록에서 berithBase의 스코어와 순위를 반환.
 	// berithBase는 노드에서 지정한 채굴자이다. 여러 노드들 중 현재 노드의 채굴자는
 	// 몇위인지, 스코어는 몇점인지 알아내는 것이다.
-	diff, rank := c.calcDifficultyAndRank(c.signer, chain, 0, target)
+	diff, rank := c.pendingDifficultyAndRank(c.signer, chain, target)
 	if rank < 1 {
 		return errUnauthorizedSigner
 	}
@@ -513,10 +671,17 @@ func (c *BSRR) Finalize(chain consensus.ChainReader, header *types.Header, state
 	if err != nil {
 		return nil, errStakingList
 	}
+	if err = c.commitStakers(header, stks); err != nil {
+		return nil, errCommitStakingDB
+	}
+	// [BERITH] Write the staking lists queued by the asynchronous commit modes at every epoch boundary.
+	if header.Number.Uint64()%c.config.Epoch == 0 {
+		if err = c.stakingDB.Flush(); err != nil {
+			return nil, errCommitStakingDB
+		}
+	}
 
 	if header.Coinbase != common.HexToAddress("0") {
-		var signers signers
-
 		parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
 		if parent == nil {
 			log.Warn("unknown ancestor", "parent", "nil")
@@ -528,31 +693,13 @@ func (c *BSRR) Finalize(chain consensus.ChainReader, header *types.Header, state
 				return nil, errBIP1
 			}
 		}
-		target, exist := c.getStakeTargetBlock(chain, parent)
-		if !exist {
-			return nil, consensus.ErrUnknownAncestor
+		target, err := c.verifyCreator(chain, header, parent)
+		if err == errOutOfRank {
+			// Only explainRejection tells the rank apart
+			err = errUnauthorizedSigner
 		}
-
-		signers, err := c.getSigners(chain, target)
 		if err != nil {
-			return nil, errUnauthorizedSigner
-		}
-
-		signerMap := signers.signersMap()
-		if _, ok := signerMap[header.Coinbase]; !ok {
-			return nil, errUnauthorizedSigner
-		}
-
-		predicted, rank := c.calcDifficultyAndRank(header.Coinbase, chain, 0, target)
-		if rank < 1 {
-			return nil, errUnauthorizedSigner
-		}
-
-		if predicted.Cmp(header.Difficulty) != 0 {
-			return nil, errInvalidDifficulty
-		}
-		if header.Nonce.Uint64() != uint64(rank) {
-			return nil, errInvalidNonce
+			return nil, err
 		}
 
 		/*
@@ -571,6 +718,9 @@ func (c *BSRR) Finalize(chain consensus.ChainReader, header *types.Header, state
 		return nil, errStakingList
 	}
 
+	// [BERITH] Refresh the selection points of all stakers at every epoch boundary after BIP5.
+	c.recalculatePoints(chain.Config(), state, stks, header.Number)
+
 	// Reward
 	c.accumulateRewards(chain, state, header)
 
@@ -582,6 +732,39 @@ func (c *BSRR) Finalize(chain consensus.ChainReader, header *types.Header, state
 	return types.NewBlock(header, txs, nil, receipts), nil
 }
 
+// verifyCreator checks that the coinbase of the header was elected on the stake
+// target block of its parent, and that the difficulty and nonce of the header
+// match the score and rank of the coinbase. It returns the stake target block.
+func (c *BSRR) verifyCreator(chain consensus.ChainReader, header, parent *types.Header) (*types.Header, error) {
+	target, exist := c.getStakeTargetBlock(chain, parent)
+	if !exist {
+		return nil, consensus.ErrUnknownAncestor
+	}
+
+	signers, err := c.getSigners(chain, target)
+	if err != nil {
+		return nil, errUnauthorizedSigner
+	}
+
+	signerMap := signers.signersMap()
+	if _, ok := signerMap[header.Coinbase]; !ok {
+		return nil, errUnauthorizedSigner
+	}
+
+	predicted, rank := c.calcDifficultyAndRank(header.Coinbase, chain, 0, target)
+	if rank < 1 {
+		return nil, errOutOfRank
+	}
+
+	if predicted.Cmp(header.Difficulty) != 0 {
+		return nil, errInvalidDifficulty
+	}
+	if header.Nonce.Uint64() != uint64(rank) {
+		return nil, errInvalidNonce
+	}
+	return target, nil
+}
+
 // Authorize injects a private key into the consensus engine to mint new blocks
 // with.
 //
@@ -596,6 +779,16 @@ func (c *BSRR) Authorize(signer common.Address, signFn SignerFn) {
 	c.signFn = signFn
 }
 
+// SetStakerTrace sets the debug callback invoked for every staker change while
+// the staker sets are updated with the transactions of the blocks. A nil
+// callback disables the trace.
+func (c *BSRR) SetStakerTrace(trace StakerTraceFn) {
+	c.traceLock.Lock()
+	defer c.traceLock.Unlock()
+
+	c.stakerTrace = trace
+}
+
 // Seal implements consensus.Engine, attempting to create a sealed block using
 // the local signing credentials.
 //
@@ -633,6 +826,12 @@ func (c *BSRR) Seal(chain consensus.ChainReader, block *types.Block, results cha
 	if _, authorized := signers.signersMap()[signer]; !authorized {
 		return errUnauthorizedSigner
 	}
+	// Refuse to sign a different block at a height we already released one at
+	sealHash := c.SealHash(header)
+	sealID := c.sealID(sealHash)
+	if prev, ok := c.lastSigned(signer, number); ok && prev != sealHash {
+		return errDoubleSign
+	}
 
 	// Prepare에서 header.Time에 미리 period만큼 시간을 더해 놓았다.
 	// 그러나 1번 블록은 제네시스 JSON 파일을 생성하고 Period 안에 채굴될 일이 거의 없기 때문에
@@ -643,20 +842,25 @@ func (c *BSRR) Seal(chain consensus.ChainReader, block *types.Block, results cha
 	// interrupt에 1을 치환해 버리기 때문에 commitTransactions가 return 되는 것이다.
 	//
 	// Sweet, the protocol permits us to sign the block, wait for our time
-	delay := time.Unix(header.Time.Int64(), 0).Sub(time.Now()) // nolint: gosimple
-	_, rank := c.calcDifficultyAndRank(header.Coinbase, chain, 0, target)
-	fmt.Printf("BSRR.Seal() / rank : %v, delay : %v\n", rank, delay.Milliseconds())
-	if rank == -1 {
-		return errUnauthorizedSigner
-	}
+	delay := c.sealDelay(header, time.Now())
+
+	// [BERITH] The only signer of a solo sealing chain is always ranked first, so
+	// the election isn't run again and its block is released on its timestamp.
+	if !c.isSoloSigner(signers, signer) {
+		_, rank := c.pendingDifficultyAndRank(header.Coinbase, chain, target)
+		fmt.Printf("BSRR.Seal() / rank : %v, delay : %v\n", rank, delay.Milliseconds())
+		if rank == -1 {
+			return errUnauthorizedSigner
+		}
 
-	//delay += c.getDelay(rank)
-	temp, err := c.getDelay(rank)
-	if err != nil {
-		return err
+		//delay += c.getDelay(rank)
+		temp, err := c.getDelay(rank)
+		if err != nil {
+			return err
+		}
+		delay += temp
+		fmt.Println("Seal() / delay + temp : ", delay)
 	}
-	delay += temp
-	fmt.Println("Seal() / delay + temp : ", delay)
 
 	// Sign all the things!
 	sighash, err := signFn(accounts.Account{Address: signer}, sigHash(header).Bytes())
@@ -665,7 +869,7 @@ func (c *BSRR) Seal(chain consensus.ChainReader, block *types.Block, results cha
 	}
 	copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
 	// Wait until sealing is terminated or delay timeout.
-	log.Trace("Waiting for slot to sign and propagate", "delay", common.PrettyDuration(delay))
+	log.Trace("Waiting for slot to sign and propagate", "seal", sealID, "delay", common.PrettyDuration(delay))
 	go func() {
 		select {
 		case <-stop:
@@ -673,17 +877,149 @@ func (c *BSRR) Seal(chain consensus.ChainReader, block *types.Block, results cha
 			// rank만큼 딜레이 시간이 늘어난다.
 		case <-time.After(delay):
 		}
-
+		// Only the block actually released counts as signed, resubmitted work
+		// stopped before its delay elapsed is never propagated.
+		if err := c.recordSigned(signer, number, sealHash); err != nil {
+			log.Error("Refused to release sealed block", "seal", sealID, "number", number, "sealhash", sealHash, "err", err)
+			return
+		}
 		select {
 		case results <- block.WithSeal(header):
 			fmt.Println("resultCh로 데이터 삽입")
+			log.Debug("Submitted sealing result", "seal", sealID, "number", number, "sealhash", sealHash)
 		default:
-			log.Warn("Sealing result is not read by miner", "sealhash", c.SealHash(header))
+			c.reportDropped(sealID, number, sealHash, time.Now())
 		}
 	}()
 	return nil
 }
 
+// reportDropped counts a sealed block the miner didn't read. The drop is warned
+// about unless the same block was the last one warned about, as resubmitted work
+// is sealed again, or another drop was warned about within droppedLogInterval.
+// The next warning reports how many drops were suppressed.
+func (c *BSRR) reportDropped(id, number uint64, sealHash common.Hash, now time.Time) {
+	sealDroppedCounter.Inc(1)
+
+	c.droppedLock.Lock()
+	defer c.droppedLock.Unlock()
+
+	if sealHash == c.droppedWarned || now.Sub(c.droppedLogged) < droppedLogInterval {
+		c.droppedSuppressed++
+		log.Debug("Sealing result is not read by miner", "seal", id, "number", number, "sealhash", sealHash)
+		return
+	}
+	log.Warn("Sealing result is not read by miner", "seal", id, "number", number, "sealhash", sealHash, "suppressed", c.droppedSuppressed)
+	c.droppedWarned, c.droppedLogged, c.droppedSuppressed = sealHash, now, 0
+}
+
+// TrackSeal implements consensus.SealTracker, remembering the correlation id of
+// the miner's sealing attempt for the block with the given seal hash.
+func (c *BSRR) TrackSeal(sealHash common.Hash, id uint64) {
+	c.sealIDs.Add(sealHash, id)
+}
+
+// sealID returns the correlation id tracked for the given seal hash, or 0 if the
+// block was not announced by the miner.
+func (c *BSRR) sealID(sealHash common.Hash) uint64 {
+	if id, ok := c.sealIDs.Get(sealHash); ok {
+		return id.(uint64)
+	}
+	return 0
+}
+
+// signedKey returns the database key under which the seal hash of the block the
+// signer released at the given height is stored.
+func signedKey(signer common.Address, number uint64) []byte {
+	key := append([]byte("bsrr-signed-"), signer.Bytes()...)
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	return append(key, enc...)
+}
+
+// signedIndexKey returns the database key under which the heights of the blocks
+// released by the signer and still recorded are stored.
+func signedIndexKey(signer common.Address) []byte {
+	return append([]byte("bsrr-signed-index-"), signer.Bytes()...)
+}
+
+// signedRetention returns how many heights below a released block the records
+// of its signer are kept for. Sealing resumes at most as deep as the chain can
+// reorganise, so the records below the finality horizon are never consulted.
+func (c *BSRR) signedRetention() uint64 {
+	if c.config.FinalityHorizon > 0 {
+		return c.config.FinalityHorizon
+	}
+	return defaultSignedRetention
+}
+
+// lastSigned returns the seal hash of the block the signer released at the given
+// height, if any.
+func (c *BSRR) lastSigned(signer common.Address, number uint64) (common.Hash, bool) {
+	blob, err := c.db.Get(signedKey(signer, number))
+	if err != nil || len(blob) != common.HashLength {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(blob), true
+}
+
+// recordSigned persists the seal hash of a block released by the signer, failing
+// with errDoubleSign if a different block was already released at its height.
+// The records of the signer below the retention are deleted along.
+func (c *BSRR) recordSigned(signer common.Address, number uint64, sealHash common.Hash) error {
+	c.signedLock.Lock()
+	defer c.signedLock.Unlock()
+
+	if prev, ok := c.lastSigned(signer, number); ok {
+		if prev != sealHash {
+			return errDoubleSign
+		}
+		return nil
+	}
+	var heights []uint64
+	if blob, err := c.db.Get(signedIndexKey(signer)); err == nil {
+		if err := rlp.DecodeBytes(blob, &heights); err != nil {
+			log.Warn("Dropping corrupt signed block index", "signer", signer, "err", err)
+			heights = nil
+		}
+	}
+	batch := c.db.NewBatch()
+	kept := []uint64{number}
+	for _, height := range heights {
+		if height+c.signedRetention() < number {
+			batch.Delete(signedKey(signer, height))
+			continue
+		}
+		kept = append(kept, height)
+	}
+	index, err := rlp.EncodeToBytes(kept)
+	if err != nil {
+		return err
+	}
+	batch.Put(signedKey(signer, number), sealHash.Bytes())
+	batch.Put(signedIndexKey(signer), index)
+	return batch.Write()
+}
+
+// IsStaker implements consensus.Eligibility, returning whether the given address
+// is among the signers allowed to create the block on top of the current head.
+func (c *BSRR) IsStaker(chain consensus.ChainReader, address common.Address) (bool, error) {
+	parent := chain.CurrentHeader()
+	if parent == nil {
+		return false, errUnknownBlock
+	}
+	target, exist := c.getStakeTargetBlock(chain, parent)
+	if !exist {
+		return false, consensus.ErrUnknownAncestor
+	}
+	signers, err := c.getSigners(chain, target)
+	if err != nil {
+		return false, err
+	}
+	_, ok := signers.signersMap()[address]
+	return ok, nil
+}
+
 // CalcDifficulty is the difficulty adjustment algorithm. It returns the difficulty
 // that a new block should have ( based on the previous blocks in the chain and the
 // current signer. )
@@ -696,7 +1032,7 @@ func (c *BSRR) CalcDifficulty(chain consensus.ChainReader, time uint64, parent *
 	if !exist {
 		return big.NewInt(0)
 	}
-	diff, _ := c.calcDifficultyAndRank(c.signer, chain, time, target)
+	diff, _ := c.pendingDifficultyAndRank(c.signer, chain, target)
 	return diff
 }
 
@@ -713,7 +1049,7 @@ func (c *BSRR) getAncestor(chain consensus.ChainReader, n int64, header *types.H
 		return &types.Header{}, false
 	}
 
-	return target, chain.HasBlockAndState(target.Hash(), target.Number.Uint64())
+	return target, c.states.hasBlockAndState(chain, target)
 }
 
 /*
@@ -749,7 +1085,7 @@ func (c *BSRR) getStakeTargetBlock(chain consensus.ChainReader, parent *types.He
 
 	target := chain.GetHeaderByNumber(targetNumber)
 	if target != nil {
-		return target, chain.HasBlockAndState(target.Hash(), targetNumber)
+		return target, c.states.hasBlockAndState(chain, target)
 	}
 	return target, false
 }
@@ -771,6 +1107,12 @@ Method to return the difficulty and rank when creating a block for a given addre
 [epoch+1, ~] -> 타겟블록의 스테이킹 리스트 반환
 */
 func (c *BSRR) calcDifficultyAndRank(signer common.Address, chain consensus.ChainReader, time uint64, target *types.Header) (*big.Int, int) {
+	return c.electSigner(signer, chain, target, c.getStakers)
+}
+
+// electSigner runs the election of calcDifficultyAndRank on the staking list of
+// the target block retrieved by the given function.
+func (c *BSRR) electSigner(signer common.Address, chain consensus.ChainReader, target *types.Header, getStakers stakersFn) (*big.Int, int) {
 	fmt.Println("CalcDifficultyAndRank / Target : ", target.Number.Int64())
 	// extract diff and rank from genesis's extra data
 	if target.Number.Cmp(big.NewInt(0)) == 0 {
@@ -778,11 +1120,22 @@ func (c *BSRR) calcDifficultyAndRank(signer common.Address, chain consensus.Chai
 		return big.NewInt(diffWithoutStaker), 1
 	}
 
-	stks, err := c.getStakers(chain, target.Number.Uint64(), target.Hash())
+	stks, err := getStakers(chain, target.Number.Uint64(), target.Hash())
 	if err != nil {
 		log.Error("failed to get stakers", "err", err.Error())
 		return big.NewInt(0), -1
 	}
+	// elect the genesis signers like on the first epoch if they fall back for the missing stakers
+	if len(stks.AsList()) == 0 {
+		fallback, err := c.fallbackSigners(chain, target)
+		if err != nil {
+			return big.NewInt(0), -1
+		}
+		if _, ok := fallback.signersMap()[signer]; !ok {
+			return big.NewInt(0), -1
+		}
+		return big.NewInt(diffWithoutStaker), 1
+	}
 
 	stateDB, err := chain.StateAt(target.Root)
 	if err != nil {
@@ -791,6 +1144,7 @@ func (c *BSRR) calcDifficultyAndRank(signer common.Address, chain consensus.Chai
 	}
 
 	results := selection.SelectBlockCreator(chain.Config(), target.Number.Uint64(), target.Hash(), stks, stateDB)
+	electionCounter.Inc(1)
 
 	//후보자가 10000명 이하라면, ForkFactor가 1.0이기 때문에 그대로 반환됨
 	max := c.getMaxMiningCandidates(len(results))
@@ -833,7 +1187,10 @@ func (c *BSRR) getDelay(rank int) (time.Duration, error) {
 
 // Close implements consensus.Engine. It's a noop for clique as there are no background threads.
 func (c *BSRR) Close() error {
-	return nil
+	if c.stakingDB == nil {
+		return nil
+	}
+	return c.stakingDB.Flush()
 }
 
 func getReward(config *params.ChainConfig, header *types.Header) *big.Int {
@@ -893,6 +1250,7 @@ func (c *BSRR) accumulateRewards(chain consensus.ChainReader, state *state.State
 	}
 
 	//all node block result
+	//[BERITH] The signers are sorted, so every node releases the behind balances in the same order
 	for _, addr := range signers {
 		behind, err := state.GetFirstBehindBalance(addr)
 		fmt.Printf("%v's Behind balance : %v\n", addr.Hex(), behind.Balance)
@@ -916,6 +1274,16 @@ func (c *BSRR) accumulateRewards(chain consensus.ChainReader, state *state.State
 	}
 }
 
+// commitStakers persists the staking list of the parent of the given header
+// every CommitEvery blocks, regardless of it being cached, so that at most that
+// many blocks need to be replayed to rebuild the staking list after a restart.
+func (c *BSRR) commitStakers(header *types.Header, stks staking.Stakers) error {
+	if c.config.CommitEvery == 0 || header.Number.Uint64()%c.config.CommitEvery != 0 {
+		return nil
+	}
+	return c.stakingDB.Commit(header.ParentHash.Hex(), stks)
+}
+
 func (c *BSRR) supportBIP1(chain consensus.ChainReader, parent *types.Header, stks staking.Stakers) (staking.Stakers, error) {
 	st, err := chain.StateAt(parent.Root)
 	if err != nil {
@@ -941,8 +1309,24 @@ func (c *BSRR) supportBIP1(chain consensus.ChainReader, parent *types.Header, st
 	return stks, nil
 }
 
+// stakersFn retrieves the staking list of the given block.
+type stakersFn func(chain consensus.ChainReader, number uint64, hash common.Hash) (staking.Stakers, error)
+
 //[BERITH] Method to call stakingList from cache or db
 func (c *BSRR) getStakers(chain consensus.ChainReader, number uint64, hash common.Hash) (staking.Stakers, error) {
+	return c.loadStakers(chain, number, hash, true)
+}
+
+// peekStakers retrieves the staking list of the given block like getStakers, but
+// without storing a rebuilt list in the cache or the staking database.
+func (c *BSRR) peekStakers(chain consensus.ChainReader, number uint64, hash common.Hash) (staking.Stakers, error) {
+	return c.loadStakers(chain, number, hash, false)
+}
+
+// loadStakers retrieves the staking list of the given block, rebuilding it from
+// the nearest stored list if needed. The rebuilt list is only stored if commit
+// is set.
+func (c *BSRR) loadStakers(chain consensus.ChainReader, number uint64, hash common.Hash, commit bool) (staking.Stakers, error) {
 	var (
 		list   staking.Stakers
 		blocks []*types.Block
@@ -961,7 +1345,9 @@ func (c *BSRR) getStakers(chain consensus.ChainReader, number uint64, hash commo
 				break
 			}
 			list = nil
-			c.cache.Remove(prevHash)
+			if commit {
+				c.cache.Remove(prevHash)
+			}
 		}
 
 		//[BERITH] StakingList is not saved
@@ -978,6 +1364,10 @@ func (c *BSRR) getStakers(chain consensus.ChainReader, number uint64, hash commo
 		}
 		list = nil
 
+		//[BERITH] Bound the number of blocks replayed on top of the stored list
+		if max := c.config.MaxReorgDepth; max != 0 && uint64(len(blocks)) >= max {
+			return nil, errReorgTooDeep
+		}
 		block := chain.GetBlock(prevHash, prevNum)
 		if block == nil {
 			return nil, errors.New("unknown anccesstor")
@@ -1005,6 +1395,9 @@ func (c *BSRR) getStakers(chain consensus.ChainReader, number uint64, hash commo
 	if err != nil {
 		return nil, err
 	}
+	if !commit {
+		return list, nil
+	}
 
 	bytes, err := json.Marshal(list)
 	if err != nil {
@@ -1050,27 +1443,13 @@ func (c *BSRR) setStakersWithTxs(state *state.StateDB, chain consensus.ChainRead
 		return errMissingState
 	}
 
-	stkChanged := make(map[common.Address]bool)
+	c.traceLock.RLock()
+	trace := c.stakerTrace
+	c.traceLock.RUnlock()
 
-	for _, tx := range txs {
-		msg, err := tx.AsMessage(types.MakeSigner(chain.Config(), number))
-		if err != nil {
-			return err
-		}
-
-		// General Transaction
-		if msg.Base() == types.Main && msg.Target() == types.Main {
-			continue
-		}
-
-		//[BERITH] 2019-09-03
-		// Fix to save the last staking block number
-		// Stake or Unstake in case of not normal Tx
-		if chain.Config().IsBIP1(number) && msg.Base() == types.Stake && msg.Target() == types.Main {
-			stkChanged[msg.From()] = false
-		} else if msg.Base() == types.Main && msg.Target() == types.Stake {
-			stkChanged[msg.From()] = true
-		}
+	stkChanged, err := stakerChanges(chain, txs, number, trace)
+	if err != nil {
+		return err
 	}
 
 	for addr, isAdd := range stkChanged {
@@ -1099,6 +1478,70 @@ func (c *BSRR) setStakersWithTxs(state *state.StateDB, chain consensus.ChainRead
 	return nil
 }
 
+/*
+[BERITH]
+Function that returns the stakers added (true) or removed (false) by the stake
+and unstake transactions of the block of the given number. The trace, if not
+nil, is invoked for every change in the order of the transactions, including
+the ones overridden by a later transaction of the same sender.
+*/
+func stakerChanges(chain consensus.ChainReader, txs []*types.Transaction, number *big.Int, trace StakerTraceFn) (map[common.Address]bool, error) {
+	stkChanged := make(map[common.Address]bool)
+
+	for _, tx := range txs {
+		msg, err := tx.AsMessage(types.MakeSigner(chain.Config(), number))
+		if err != nil {
+			return nil, err
+		}
+
+		// General Transaction
+		if msg.Base() == types.Main && msg.Target() == types.Main {
+			continue
+		}
+
+		//[BERITH] 2019-09-03
+		// Fix to save the last staking block number
+		// Stake or Unstake in case of not normal Tx
+		var added bool
+		if chain.Config().IsBIP1(number) && msg.Base() == types.Stake && msg.Target() == types.Main {
+			added = false
+		} else if msg.Base() == types.Main && msg.Target() == types.Stake {
+			added = true
+		} else {
+			continue
+		}
+		stkChanged[msg.From()] = added
+		if trace != nil {
+			trace(tx.Hash(), msg.From(), added)
+		}
+	}
+	return stkChanged, nil
+}
+
+/*
+[BERITH]
+Recalculates the selection point of every staker from its current stake balance and last staking block.
+Without it, a point is only updated when the staker sends a stake transaction,
+so long-standing stakers keep the point computed at the time they staked.
+Does nothing before BIP5 or off the epoch boundary.
+*/
+func (c *BSRR) recalculatePoints(config *params.ChainConfig, state *state.StateDB, stks staking.Stakers, number *big.Int) {
+	if !config.IsBIP5(number) || number.Uint64()%c.config.Epoch != 0 {
+		return
+	}
+	for _, addr := range stks.AsList() {
+		point := big.NewInt(0)
+		stkBal := new(big.Int).Div(state.GetStakeBalance(addr), common.UnitForBer)
+		if stkBal.Cmp(big.NewInt(0)) == 1 {
+			lastStkBlock := new(big.Int).Set(state.GetStakeUpdated(addr))
+			point = staking.CalcPointBigint(stkBal, big.NewInt(0), number, lastStkBlock, c.config.Period)
+		}
+		state.SetPoint(addr, point)
+	}
+}
+
+// signers is the list of accounts allowed to create a block, in ascending order
+// of the addresses.
 type signers []common.Address
 
 func (s signers) signersMap() map[common.Address]struct{} {
@@ -1111,43 +1554,85 @@ func (s signers) signersMap() map[common.Address]struct{} {
 
 //[BERITH] Method that returns a list of accounts that can create a block of the received block number
 // 1) [0, epoch number) -> Return signers extracted from extra data of genesis
-// 2) [epoch nunber ~ ) -> Return signers extracted from staking list
+// 2) [epoch nunber ~ ) -> Return signers extracted from staking list, or those of
+//    the genesis if the staking list is empty (see fallbackSigners)
+//
+// The signers are sorted by ascending address, as the order of the staking list
+// depends on its implementation. The consumers of the list are:
+//   - verifyCreator, Seal and IsStaker only check the membership of an address
+//   - accumulateRewards releases the behind balances of the signers in order
+//   - the getBlockCreators and getSigners APIs return the list as is
 func (c *BSRR) getSigners(chain consensus.ChainReader, target *types.Header) (signers, error) {
+	return c.signersAt(chain, target, c.getStakers)
+}
+
+// signersAt returns the signers of getSigners, retrieving the staking list of
+// the target block with the given function.
+func (c *BSRR) signersAt(chain consensus.ChainReader, target *types.Header, getStakers stakersFn) (signers, error) {
+	var (
+		result signers
+		err    error
+	)
+	switch {
 	// extract signers from genesis block's extra data if block number equals to 0
-	if target.Number.Cmp(big.NewInt(0)) == 0 {
-		return c.getSignersFromExtraData(target)
-	}
+	case target.Number.Cmp(big.NewInt(0)) == 0:
+		result, err = c.getSignersFromExtraData(target)
 
 	// extract signers from genesis block's extra data if block number is less than epoch
-	if target.Number.Cmp(big.NewInt(int64(c.config.Epoch))) < 0 {
-		return c.getSignersFromExtraData(chain.GetHeaderByNumber(0))
-	}
+	case target.Number.Cmp(big.NewInt(int64(c.config.Epoch))) < 0:
+		result, err = c.getSignersFromExtraData(chain.GetHeaderByNumber(0))
 
 	// extract signers from staking list if block number is greater than or equals to epoch
-	list, err := c.getStakers(chain, target.Number.Uint64(), target.Hash())
+	default:
+		list, stkErr := getStakers(chain, target.Number.Uint64(), target.Hash())
+		if stkErr != nil {
+			return nil, errors.New("failed to get staking list")
+		}
+		result = list.AsList()
+		if len(result) == 0 {
+			result, err = c.fallbackSigners(chain, target)
+		}
+	}
 	if err != nil {
-		return nil, errors.New("failed to get staking list")
+		return nil, err
 	}
+	result.sort()
+	return result, nil
+}
 
-	result := list.AsList()
-	if len(result) == 0 {
-		return make([]common.Address, 0), nil
+/*
+[BERITH]
+Function that returns the genesis signers in place of the empty staking list of
+a target block past the first epoch. Without any staker no one may create the
+blocks on top of the target and the chain halts, so the genesis signers take
+over, elected like on the first epoch, until staking resumes. No block relied on
+an empty staking list before, as none could be created on it.
+*/
+func (c *BSRR) fallbackSigners(chain consensus.ChainReader, target *types.Header) (signers, error) {
+	genesis := chain.GetHeaderByNumber(0)
+	if genesis == nil {
+		return nil, consensus.ErrUnknownAncestor
 	}
-	return result, nil
+	c.fallbackLock.Lock()
+	if c.fallbackWarned != target.Hash() {
+		c.fallbackWarned = target.Hash()
+		log.Warn("No stakers on the stake target block, falling back to the genesis signers", "number", target.Number, "hash", target.Hash())
+	}
+	c.fallbackLock.Unlock()
+
+	return c.getSignersFromExtraData(genesis)
+}
+
+// sort orders the signers by ascending address.
+func (s signers) sort() {
+	sort.Slice(s, func(i, j int) bool {
+		return bytes.Compare(s[i][:], s[j][:]) < 0
+	})
 }
 
 //[BERITH] Returns signers from the extra data field.
 func (c *BSRR) getSignersFromExtraData(header *types.Header) (signers, error) {
-	n := (len(header.Extra) - extraVanity - extraSeal) / common.AddressLength
-	if n < 1 {
-		return nil, errExtraSigners
-	}
-
-	signers := make([]common.Address, n)
-	for i := 0; i < len(signers); i++ {
-		copy(signers[i][:], header.Extra[extraVanity+i*common.AddressLength:])
-	}
-	return signers, nil
+	return extradata.ParseSigners(header.Extra)
 }
 
 // [BERITH] Returns the number of candidates who can create a block at a given number of stakers.
@@ -1162,8 +1647,8 @@ func (c *BSRR) getMaxMiningCandidates(holders int) int {
 		t = 1
 	}
 
-	if t > selection.MaxMiner {
-		t = selection.MaxMiner
+	if max := selection.MaxCandidates(c.config); t > max {
+		t = max
 	}
 	return t
 }
@@ -1176,7 +1661,8 @@ func (c *BSRR) getJoinRatio(stks staking.Stakers, address common.Address, hash c
 	var total float64
 	var n float64
 
-	for _, stk := range stks.AsList() {
+	list := stks.AsList()
+	for _, stk := range list {
 		point := float64(states.GetPoint(stk).Int64())
 		if address == stk {
 			n = point
@@ -1184,8 +1670,12 @@ func (c *BSRR) getJoinRatio(stks staking.Stakers, address common.Address, hash c
 		total += point
 	}
 
+	//[BERITH] Without any points the stakers are elected with equal weights
 	if total == 0 {
-		return 0, nil
+		if !stks.IsContain(address) {
+			return 0, nil
+		}
+		return 1 / float64(len(list)), nil
 	}
 
 	return n / total, nil
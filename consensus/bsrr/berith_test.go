@@ -1,12 +1,35 @@
 package bsrr
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/BerithFoundation/berith-chain/accounts"
 	"github.com/BerithFoundation/berith-chain/berith/selection"
+	"github.com/BerithFoundation/berith-chain/berith/staking"
+	"github.com/BerithFoundation/berith-chain/berithdb"
 	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/consensus"
+	"github.com/BerithFoundation/berith-chain/core/state"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/crypto"
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/metrics"
 	"github.com/BerithFoundation/berith-chain/params"
+	"github.com/BerithFoundation/berith-chain/rlp"
+	"github.com/BerithFoundation/berith-chain/rpc"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 func TestGetMaxMiningCandidates(t *testing.T) {
@@ -39,6 +62,11 @@ func TestGetMaxMiningCandidates(t *testing.T) {
 			t.Errorf("test #%d: expected : %d but %d", i, test.expected, result)
 		}
 	}
+	// A lowered maximum number of candidates caps the block creators alike
+	c.config.MaxCandidates = 100
+	if result := c.getMaxMiningCandidates(35000); result != 100 {
+		t.Errorf("capped: expected : %d but %d", 100, result)
+	}
 }
 
 func TestGetDelay(t *testing.T) {
@@ -79,3 +107,1708 @@ func TestGetDelay(t *testing.T) {
 		}
 	}
 }
+
+// testChainReader is a chain consisting of a single genesis block, enough for
+// sealing the first block on top of it.
+type testChainReader struct {
+	consensus.ChainReader
+	genesis *types.Header
+}
+
+func (r *testChainReader) Config() *params.ChainConfig {
+	return params.TestnetChainConfig
+}
+
+func (r *testChainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if hash == r.genesis.Hash() && number == 0 {
+		return r.genesis
+	}
+	return nil
+}
+
+func (r *testChainReader) GetHeaderByNumber(number uint64) *types.Header {
+	if number == 0 {
+		return r.genesis
+	}
+	return nil
+}
+
+func (r *testChainReader) CurrentHeader() *types.Header {
+	return r.genesis
+}
+
+func (r *testChainReader) HasBlockAndState(hash common.Hash, number uint64) bool {
+	return hash == r.genesis.Hash() && number == 0
+}
+
+// Tests that once a block was sealed at some height, sealing a different block
+// at the same height is refused while resealing the same one is permitted.
+func TestSealDoubleSign(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	genesis := &types.Header{
+		Number:     big.NewInt(0),
+		Difficulty: big.NewInt(1),
+		Extra:      make([]byte, extraVanity+common.AddressLength+extraSeal),
+	}
+	copy(genesis.Extra[extraVanity:], signer.Bytes())
+	chain := &testChainReader{genesis: genesis}
+
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 360}, berithdb.NewMemDatabase())
+	c.Authorize(signer, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	newBlock := func(extra byte) *types.Block {
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			Number:     big.NewInt(1),
+			Difficulty: big.NewInt(1),
+			Coinbase:   signer,
+			Time:       big.NewInt(time.Now().Unix()),
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		header.Extra[0] = extra
+		return types.NewBlockWithHeader(header)
+	}
+	seal := func(block *types.Block) error {
+		results := make(chan *types.Block, 1)
+		if err := c.Seal(chain, block, results, make(chan struct{})); err != nil {
+			return err
+		}
+		select {
+		case <-results:
+			return nil
+		case <-time.After(time.Second):
+			t.Fatalf("sealed block not released")
+		}
+		return nil
+	}
+	first, second := newBlock(1), newBlock(2)
+	if err := seal(first); err != nil {
+		t.Fatalf("failed to seal first block: %v", err)
+	}
+	if err := seal(first); err != nil {
+		t.Errorf("failed to reseal the same block: %v", err)
+	}
+	if err := seal(second); err != errDoubleSign {
+		t.Errorf("second block at the same height: error mismatch: have %v, want %v", err, errDoubleSign)
+	}
+	// Concurrent work racing the first release is dropped when its delay elapses
+	if err := c.recordSigned(signer, 1, c.SealHash(second.Header())); err != errDoubleSign {
+		t.Errorf("release of second block: error mismatch: have %v, want %v", err, errDoubleSign)
+	}
+}
+
+// Tests that the records of the released blocks are only kept down to the
+// finality horizon below the latest one.
+func TestRecordSignedPruning(t *testing.T) {
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 360, FinalityHorizon: 10}, berithdb.NewMemDatabase())
+	signer := common.Address{0x01}
+
+	for number := uint64(1); number <= 30; number++ {
+		if err := c.recordSigned(signer, number, common.Hash{byte(number)}); err != nil {
+			t.Fatalf("block %d: failed to record: %v", number, err)
+		}
+	}
+	for number := uint64(1); number <= 30; number++ {
+		_, have := c.lastSigned(signer, number)
+		if want := number >= 20; have != want {
+			t.Errorf("block %d: record kept mismatch: have %v, want %v", number, have, want)
+		}
+	}
+	// The kept records still guard against double signing
+	if err := c.recordSigned(signer, 30, common.Hash{0xff}); err != errDoubleSign {
+		t.Errorf("second block at a kept height: error mismatch: have %v, want %v", err, errDoubleSign)
+	}
+}
+
+// Tests that a sealed block the miner does not read is dropped with a warning
+// carrying the correlation id of the sealing attempt, and counted.
+func TestSealDroppedResult(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	genesis := &types.Header{
+		Number:     big.NewInt(0),
+		Difficulty: big.NewInt(1),
+		Extra:      make([]byte, extraVanity+common.AddressLength+extraSeal),
+	}
+	copy(genesis.Extra[extraVanity:], signer.Bytes())
+	chain := &testChainReader{genesis: genesis}
+
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 360}, berithdb.NewMemDatabase())
+	c.Authorize(signer, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	block := types.NewBlockWithHeader(&types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		Difficulty: big.NewInt(1),
+		Coinbase:   signer,
+		Time:       big.NewInt(time.Now().Unix()),
+		Extra:      make([]byte, extraVanity+extraSeal),
+	})
+	defer func(counter metrics.Counter) { sealDroppedCounter = counter }(sealDroppedCounter)
+	sealDroppedCounter = metrics.NewCounterForced()
+
+	dropped := make(chan *log.Record, 1)
+	log.Root().SetHandler(log.FuncHandler(func(r *log.Record) error {
+		if r.Lvl == log.LvlWarn {
+			dropped <- r
+		}
+		return nil
+	}))
+	defer log.Root().SetHandler(log.DiscardHandler())
+
+	c.TrackSeal(c.SealHash(block.Header()), 42)
+	if err := c.Seal(chain, block, make(chan *types.Block), make(chan struct{})); err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	select {
+	case r := <-dropped:
+		var id interface{}
+		for i := 0; i+1 < len(r.Ctx); i += 2 {
+			if r.Ctx[i] == "seal" {
+				id = r.Ctx[i+1]
+			}
+		}
+		if id != uint64(42) {
+			t.Errorf("correlation id mismatch: have %v, want %v", id, 42)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("dropped result not logged")
+	}
+	if count := sealDroppedCounter.Count(); count != 1 {
+		t.Errorf("dropped result count mismatch: have %d, want %d", count, 1)
+	}
+}
+
+// Tests that the warnings about dropped results are deduplicated per block and
+// throttled, the next warning reporting the suppressed drops, while all drops are
+// counted.
+func TestSealDroppedThrottle(t *testing.T) {
+	defer func(counter metrics.Counter) { sealDroppedCounter = counter }(sealDroppedCounter)
+	sealDroppedCounter = metrics.NewCounterForced()
+
+	var warnings []*log.Record
+	log.Root().SetHandler(log.FuncHandler(func(r *log.Record) error {
+		if r.Lvl == log.LvlWarn {
+			warnings = append(warnings, r)
+		}
+		return nil
+	}))
+	defer log.Root().SetHandler(log.DiscardHandler())
+
+	var (
+		c     = &BSRR{}
+		start = time.Unix(1000000, 0)
+	)
+	tests := []struct {
+		hash       common.Hash
+		after      time.Duration
+		warned     bool
+		suppressed int
+	}{
+		{common.Hash{1}, 0, true, 0},
+		{common.Hash{1}, time.Second, false, 0},                     // Same block resubmitted
+		{common.Hash{2}, time.Second, false, 0},                     // Within the interval
+		{common.Hash{2}, droppedLogInterval + time.Second, true, 2}, // Reports the suppressed drops
+		{common.Hash{2}, 3 * droppedLogInterval, false, 0},          // Same block after the interval
+		{common.Hash{3}, 3 * droppedLogInterval, true, 1},
+	}
+	for i, tt := range tests {
+		warnings = nil
+		c.reportDropped(uint64(i), 1, tt.hash, start.Add(tt.after))
+
+		if (len(warnings) == 1) != tt.warned {
+			t.Errorf("test %d: warnings mismatch: have %d, want warned %v", i, len(warnings), tt.warned)
+			continue
+		}
+		if tt.warned {
+			var suppressed interface{}
+			for j := 0; j+1 < len(warnings[0].Ctx); j += 2 {
+				if warnings[0].Ctx[j] == "suppressed" {
+					suppressed = warnings[0].Ctx[j+1]
+				}
+			}
+			if suppressed != tt.suppressed {
+				t.Errorf("test %d: suppressed count mismatch: have %v, want %d", i, suppressed, tt.suppressed)
+			}
+		}
+	}
+	if count := sealDroppedCounter.Count(); count != int64(len(tests)) {
+		t.Errorf("dropped result count mismatch: have %d, want %d", count, len(tests))
+	}
+}
+
+// Tests that headers slightly ahead of the local clock are accepted within the
+// allowed drift, and reported as future blocks beyond it.
+func TestVerifyTimeDrift(t *testing.T) {
+	c := &BSRR{config: &params.BSRRConfig{Period: 5, Epoch: 360, FutureBlockDrift: 2}}
+	now := time.Unix(1000000, 0)
+
+	tests := []struct {
+		ahead int64
+		err   error
+	}{
+		{0, nil},
+		{1, nil},
+		{2, nil},
+		{3, consensus.ErrFutureBlock},
+		{30, consensus.ErrFutureBlock},
+	}
+	for _, test := range tests {
+		header := &types.Header{Time: big.NewInt(now.Unix() + test.ahead)}
+		if err := c.verifyTime(header, now); err != test.err {
+			t.Errorf("%ds ahead: error mismatch: have %v, want %v", test.ahead, err, test.err)
+		}
+	}
+	// An unset drift falls back to the default
+	c.config.FutureBlockDrift = 0
+	if drift := c.futureBlockDrift(); drift != defaultFutureBlockDrift {
+		t.Errorf("default drift mismatch: have %v, want %v", drift, defaultFutureBlockDrift)
+	}
+}
+
+// Tests that the seal delay of a header is kept if the clock advanced normally
+// since Prepare, and clamped to the block period if it went backwards.
+func TestSealDelayClockSkew(t *testing.T) {
+	c := &BSRR{config: &params.BSRRConfig{Period: 5, Epoch: 360, FutureBlockDrift: 2}}
+	prepared := time.Unix(1000000, 0)
+	parent := &types.Header{Number: big.NewInt(9), Time: big.NewInt(prepared.Unix() - 2)}
+
+	// Timestamp the header as Prepare does, a period after the parent
+	header := &types.Header{Number: big.NewInt(10), Time: new(big.Int).Add(parent.Time, big.NewInt(5))}
+
+	tests := []struct {
+		elapsed time.Duration // Clock movement between Prepare and Seal
+		delay   time.Duration
+	}{
+		{0, 3 * time.Second},
+		{time.Second, 2 * time.Second},
+		{5 * time.Second, -2 * time.Second},
+		{-3 * time.Second, 6 * time.Second}, // Within the tolerated drift
+		{-5 * time.Second, 5 * time.Second}, // Clamped to the period
+		{-time.Hour, 5 * time.Second},       // Clamped to the period
+		{-24 * time.Hour, 5 * time.Second},  // Clamped to the period
+	}
+	for _, test := range tests {
+		if delay := c.sealDelay(header, prepared.Add(test.elapsed)); delay != test.delay {
+			t.Errorf("clock moved %v: delay mismatch: have %v, want %v", test.elapsed, delay, test.delay)
+		}
+	}
+}
+
+// newTestHeaders creates a valid batch of n headers on top of the given genesis.
+func newTestHeaders(genesis *types.Header, period uint64, n int) []*types.Header {
+	headers := make([]*types.Header, n)
+	parent := genesis
+	for i := range headers {
+		headers[i] = &types.Header{
+			ParentHash: parent.Hash(),
+			UncleHash:  uncleHash,
+			Number:     new(big.Int).Add(parent.Number, big.NewInt(1)),
+			Difficulty: big.NewInt(1),
+			Time:       new(big.Int).Add(parent.Time, new(big.Int).SetUint64(period)),
+			Extra:      make([]byte, extraVanity+extraSeal),
+			Nonce:      types.EncodeNonce(1),
+		}
+		parent = headers[i]
+	}
+	return headers
+}
+
+// collectResults reads the verification results of n headers.
+func collectResults(t testing.TB, results <-chan error, n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		select {
+		case errs[i] = <-results:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("header %d: verification timed out", i)
+		}
+	}
+	return errs
+}
+
+// Tests that concurrently verifying a batch of headers yields the same results,
+// in the same order, as verifying them one after the other.
+func TestVerifyHeadersParallel(t *testing.T) {
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(0), Difficulty: big.NewInt(1)}
+	chain := &testChainReader{genesis: genesis}
+	c := New(&params.BSRRConfig{Period: 5, Epoch: 360}, berithdb.NewMemDatabase())
+
+	headers := newTestHeaders(genesis, 5, 360)
+	headers[99].MixDigest = common.Hash{1}      // invalid mix digest
+	headers[199].Nonce = types.BlockNonce{}     // invalid nonce
+	headers[299].Extra = headers[299].Extra[:1] // missing vanity
+
+	_, results := c.verifyHeadersSequential(chain, headers)
+	want := collectResults(t, results, len(headers))
+
+	_, results = c.VerifyHeaders(chain, headers, make([]bool, len(headers)))
+	have := collectResults(t, results, len(headers))
+
+	for i := range want {
+		if have[i] != want[i] {
+			t.Errorf("header %d: result mismatch: have %v, want %v", i, have[i], want[i])
+		}
+	}
+	if have[0] != nil {
+		t.Errorf("valid header rejected: %v", have[0])
+	}
+	for _, i := range []int{99, 199, 299} {
+		if have[i] == nil {
+			t.Errorf("header %d: invalid header accepted", i)
+		}
+	}
+	// Aborting must stop the verification without blocking
+	abort, _ := c.VerifyHeaders(chain, headers, make([]bool, len(headers)))
+	close(abort)
+}
+
+func BenchmarkVerifyHeadersSequential(b *testing.B) {
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(0), Difficulty: big.NewInt(1)}
+	chain := &testChainReader{genesis: genesis}
+	c := New(&params.BSRRConfig{Period: 5, Epoch: 360}, berithdb.NewMemDatabase())
+	headers := newTestHeaders(genesis, 5, 360)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, results := c.verifyHeadersSequential(chain, headers)
+		collectResults(b, results, len(headers))
+	}
+}
+
+func BenchmarkVerifyHeadersParallel(b *testing.B) {
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(0), Difficulty: big.NewInt(1)}
+	chain := &testChainReader{genesis: genesis}
+	c := New(&params.BSRRConfig{Period: 5, Epoch: 360}, berithdb.NewMemDatabase())
+	headers := newTestHeaders(genesis, 5, 360)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, results := c.VerifyHeaders(chain, headers, make([]bool, len(headers)))
+		collectResults(b, results, len(headers))
+	}
+}
+
+// testStakingDB is a staking database recording the keys committed to it and
+// serving copies of the staking lists it was created with.
+type testStakingDB struct {
+	staking.DataBase
+	commits []string
+	lists   map[string]staking.Stakers
+}
+
+func (db *testStakingDB) GetStakers(key string) (staking.Stakers, error) {
+	if stks, ok := db.lists[key]; ok {
+		list := staking.NewStakers()
+		list.FetchFromList(stks.AsList())
+		return list, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (db *testStakingDB) Commit(key string, stks staking.Stakers) error {
+	db.commits = append(db.commits, key)
+	return nil
+}
+
+func (db *testStakingDB) NewStakers() staking.Stakers { return staking.NewStakers() }
+
+func (db *testStakingDB) Flush() error { return nil }
+
+// Tests that staking lists are forcibly committed at the configured interval,
+// and not at all when it is unset.
+func TestCommitStakers(t *testing.T) {
+	for _, every := range []uint64{0, 1, 10, 36} {
+		db := new(testStakingDB)
+		c := &BSRR{config: &params.BSRRConfig{Epoch: 360, CommitEvery: every}, stakingDB: db}
+
+		var want []string
+		parent := common.Hash{}
+		for number := uint64(1); number <= 360; number++ {
+			header := &types.Header{Number: new(big.Int).SetUint64(number), ParentHash: parent}
+			if err := c.commitStakers(header, staking.NewStakers()); err != nil {
+				t.Fatalf("commit every %d: block %d: failed to commit: %v", every, number, err)
+			}
+			if every != 0 && number%every == 0 {
+				want = append(want, parent.Hex())
+			}
+			parent = header.Hash()
+		}
+		if len(db.commits) != len(want) {
+			t.Fatalf("commit every %d: commit count mismatch: have %d, want %d", every, len(db.commits), len(want))
+		}
+		for i := range want {
+			if db.commits[i] != want[i] {
+				t.Errorf("commit every %d: commit %d: key mismatch: have %s, want %s", every, i, db.commits[i], want[i])
+			}
+		}
+	}
+}
+
+// newPointTestState creates a state holding the given stakers. Every staker is
+// staked at the given block with the point it would have received at the time.
+func newPointTestState(t testing.TB, stakes map[common.Address][2]int64) (*state.StateDB, staking.Stakers) {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(berithdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	stks := staking.NewStakers()
+	for addr, stake := range stakes {
+		amount, number := big.NewInt(stake[0]), big.NewInt(stake[1])
+		statedb.AddStakeBalance(addr, new(big.Int).Mul(amount, common.UnitForBer), number)
+		statedb.SetPoint(addr, staking.CalcPointBigint(big.NewInt(0), amount, number, number, 10))
+		stks.Put(addr)
+	}
+	return statedb, stks
+}
+
+// Tests that selection points are only recalculated at epoch boundaries after
+// BIP5, and that a long-standing staker then outweighs a recent, larger one.
+func TestRecalculatePoints(t *testing.T) {
+	var (
+		old    = common.HexToAddress("0x01")
+		recent = common.HexToAddress("0x02")
+		number = big.NewInt(staking.BlockYear)
+		next   = new(big.Int).Add(number, common.Big1)
+		prev   = new(big.Int).Sub(number, common.Big1)
+	)
+	tests := []struct {
+		fork   *big.Int
+		number *big.Int
+		winner common.Address
+	}{
+		{nil, number, recent},         // not forked
+		{next, number, recent},        // fork scheduled later
+		{big.NewInt(0), prev, recent}, // not an epoch boundary
+		{big.NewInt(0), number, old},  // forked at genesis
+		{number, number, old},         // forked at this block
+	}
+	for i, tt := range tests {
+		statedb, stks := newPointTestState(t, map[common.Address][2]int64{
+			old:    {100, 1},
+			recent: {120, staking.BlockYear - 1},
+		})
+		c := &BSRR{config: &params.BSRRConfig{Period: 10, Epoch: 360}}
+		c.recalculatePoints(&params.ChainConfig{BIP5Block: tt.fork}, statedb, stks, tt.number)
+
+		oldRatio, err := c.getJoinRatio(stks, old, common.Hash{}, tt.number.Uint64(), statedb)
+		if err != nil {
+			t.Fatalf("test %d: failed to get join ratio: %v", i, err)
+		}
+		recentRatio, err := c.getJoinRatio(stks, recent, common.Hash{}, tt.number.Uint64(), statedb)
+		if err != nil {
+			t.Fatalf("test %d: failed to get join ratio: %v", i, err)
+		}
+		winner := old
+		if recentRatio > oldRatio {
+			winner = recent
+		}
+		if winner != tt.winner {
+			t.Errorf("test %d: winner mismatch: have %x, want %x (ratios %v / %v)", i, winner, tt.winner, oldRatio, recentRatio)
+		}
+	}
+}
+
+func BenchmarkRecalculatePoints(b *testing.B) {
+	stakes := make(map[common.Address][2]int64)
+	for i := 0; i < 10000; i++ {
+		stakes[common.BigToAddress(big.NewInt(int64(i+1)))] = [2]int64{int64(100000 + i), int64(i + 1)}
+	}
+	statedb, stks := newPointTestState(b, stakes)
+	c := &BSRR{config: &params.BSRRConfig{Period: 10, Epoch: 360}}
+	config := &params.ChainConfig{BIP5Block: big.NewInt(0)}
+	number := big.NewInt(staking.BlockYear)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.recalculatePoints(config, statedb, stks, number)
+	}
+}
+
+// Tests that rejected headers are explained by the check they fail first.
+func TestExplainRejection(t *testing.T) {
+	signer := common.HexToAddress("0x01")
+	genesis := &types.Header{
+		Number:     big.NewInt(0),
+		Difficulty: big.NewInt(1),
+		Time:       big.NewInt(0),
+		Extra:      make([]byte, extraVanity+common.AddressLength+extraSeal),
+	}
+	copy(genesis.Extra[extraVanity:], signer.Bytes())
+	api := &API{
+		chain: &testChainReader{genesis: genesis},
+		bsrr:  &BSRR{config: &params.BSRRConfig{Period: 10, Epoch: 360}},
+	}
+
+	tests := []struct {
+		modify func(header *types.Header)
+		err    error
+	}{
+		{func(header *types.Header) {}, nil},
+		{func(header *types.Header) { header.Time = big.NewInt(time.Now().Add(time.Hour).Unix()) }, consensus.ErrFutureBlock},
+		{func(header *types.Header) { header.Extra = header.Extra[:extraVanity-1] }, errMissingVanity},
+		{func(header *types.Header) { header.Extra = header.Extra[:extraVanity] }, errMissingSignature},
+		{func(header *types.Header) { header.Extra = make([]byte, extraVanity+1+extraSeal) }, errExtraSigners},
+		{func(header *types.Header) { header.MixDigest = common.HexToHash("0x01") }, errInvalidMixDigest},
+		{func(header *types.Header) { header.UncleHash = common.Hash{} }, errInvalidUncleHash},
+		{func(header *types.Header) { header.Nonce = types.EncodeNonce(0) }, errInvalidNonce},
+		{func(header *types.Header) { header.ParentHash = common.HexToHash("0x01") }, consensus.ErrUnknownAncestor},
+		{func(header *types.Header) { header.Time = big.NewInt(5) }, ErrInvalidTimestamp},
+		{func(header *types.Header) { header.Coinbase = common.HexToAddress("0x02") }, errUnauthorizedSigner},
+		{func(header *types.Header) { header.Difficulty = big.NewInt(1) }, errInvalidDifficulty},
+		{func(header *types.Header) { header.Nonce = types.EncodeNonce(2) }, errInvalidNonce},
+	}
+	for i, tt := range tests {
+		header := newTestHeaders(genesis, 10, 1)[0]
+		header.Coinbase = signer
+		header.Difficulty = big.NewInt(diffWithoutStaker)
+		tt.modify(header)
+
+		enc, err := rlp.EncodeToBytes(header)
+		if err != nil {
+			t.Fatalf("test %d: failed to encode header: %v", i, err)
+		}
+		have, err := api.ExplainRejection(enc)
+		if err != nil {
+			t.Fatalf("test %d: failed to explain rejection: %v", i, err)
+		}
+		want := "header passes all checks"
+		if tt.err != nil {
+			want = fmt.Sprintf("%s (%v)", rejectionReasons[tt.err], tt.err)
+		}
+		if have != want {
+			t.Errorf("test %d: explanation mismatch: have %q, want %q", i, have, want)
+		}
+	}
+	if _, err := api.ExplainRejection([]byte{0x01}); err == nil {
+		t.Errorf("undecodable header explained")
+	}
+}
+
+// testStakersChain is a chain of headers whose states and transactions are
+// held in memory.
+type testStakersChain struct {
+	consensus.ChainReader
+	config  *params.ChainConfig
+	headers []*types.Header
+	txs     map[common.Hash]types.Transactions
+	db      state.Database
+}
+
+func (c *testStakersChain) Config() *params.ChainConfig {
+	return c.config
+}
+
+func (c *testStakersChain) CurrentHeader() *types.Header {
+	return c.headers[len(c.headers)-1]
+}
+
+func (c *testStakersChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.headers)) {
+		return c.headers[number]
+	}
+	return nil
+}
+
+func (c *testStakersChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+
+func (c *testStakersChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+
+func (c *testStakersChain) HasBlockAndState(hash common.Hash, number uint64) bool {
+	return c.GetHeader(hash, number) != nil
+}
+
+func (c *testStakersChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if header := c.GetHeader(hash, number); header != nil {
+		return types.NewBlockWithHeader(header).WithBody(c.txs[hash], nil)
+	}
+	return nil
+}
+
+func (c *testStakersChain) StateAt(root common.Hash) (*state.StateDB, error) {
+	return state.New(root, c.db)
+}
+
+// Tests that the staking lists of two blocks are compared including the points
+// of the stakers, that points of pruned states are omitted and that large
+// differences are paginated.
+func TestCompareStakers(t *testing.T) {
+	var (
+		unstaker  = common.HexToAddress("0x01")
+		holder    = common.HexToAddress("0x02")
+		staker    = common.HexToAddress("0x03")
+		db        = state.NewDatabase(berithdb.NewMemDatabase())
+		stakingDB = &testStakingDB{lists: make(map[string]staking.Stakers)}
+		chain     = &testStakersChain{db: db}
+	)
+	// Block 1 has the unstaker and holder staking, block 2 the holder and staker
+	// with an increased point for the holder.
+	statedb, _ := state.New(common.Hash{}, db)
+	statedb.SetPoint(unstaker, big.NewInt(100))
+	statedb.SetPoint(holder, big.NewInt(200))
+	root1, _ := statedb.Commit(false)
+	statedb.SetPoint(unstaker, big.NewInt(0))
+	statedb.SetPoint(holder, big.NewInt(300))
+	statedb.SetPoint(staker, big.NewInt(400))
+	root2, _ := statedb.Commit(false)
+
+	// Block 3 has many more stakers but its state is pruned
+	many := []common.Address{holder, staker}
+	for i := 0; i < compareStakersLimit+500; i++ {
+		many = append(many, common.BigToAddress(big.NewInt(int64(0x100+i))))
+	}
+	lists := [][]common.Address{nil, {unstaker, holder}, {holder, staker}, many}
+	roots := []common.Hash{{}, root1, root2, common.HexToHash("0xdead")}
+	for i, list := range lists {
+		header := &types.Header{Number: big.NewInt(int64(i)), Root: roots[i]}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+
+		stks := staking.NewStakers()
+		stks.FetchFromList(list)
+		stakingDB.lists[header.Hash().Hex()] = stks
+	}
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 360}, berithdb.NewMemDatabase())
+	c.stakingDB = stakingDB
+	api := &API{chain: chain, bsrr: c}
+
+	point := func(n int64) *hexutil.Big { return (*hexutil.Big)(big.NewInt(n)) }
+	diff, err := api.CompareStakers(rpc.BlockNumberOrHashWithNumber(1), rpc.BlockNumberOrHashWithHash(chain.headers[2].Hash()), nil)
+	if err != nil {
+		t.Fatalf("failed to compare stakers: %v", err)
+	}
+	want := &StakersDiff{
+		Added:   []common.Address{staker},
+		Removed: []common.Address{unstaker},
+		PointChanges: []PointChange{
+			{unstaker, point(100), point(0)},
+			{holder, point(200), point(300)},
+			{staker, point(0), point(400)},
+		},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("diff mismatch:\nhave %+v\nwant %+v", diff, want)
+	}
+
+	// Comparing against the pruned block omits its points and pages through the added stakers
+	var (
+		added   []common.Address
+		changes int
+		cursor  *hexutil.Uint64
+		pages   int
+	)
+	for {
+		diff, err = api.CompareStakers(rpc.BlockNumberOrHashWithNumber(2), rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), cursor)
+		if err != nil {
+			t.Fatalf("page %d: failed to compare stakers: %v", pages, err)
+		}
+		if diff.Note == "" {
+			t.Errorf("page %d: missing note on pruned state", pages)
+		}
+		if len(diff.Removed) != 0 {
+			t.Errorf("page %d: unexpected removed stakers: %v", pages, diff.Removed)
+		}
+		for _, change := range diff.PointChanges {
+			if change.Before == nil || change.After != nil {
+				t.Errorf("page %d: point change mismatch for %x: have %v -> %v, want value -> nil", pages, change.Address, change.Before, change.After)
+			}
+		}
+		added = append(added, diff.Added...)
+		changes += len(diff.PointChanges)
+		pages++
+		if cursor = diff.Next; cursor == nil {
+			break
+		}
+	}
+	if pages != 2 {
+		t.Errorf("page count mismatch: have %d, want 2", pages)
+	}
+	if len(added) != len(many)-2 {
+		t.Errorf("added stakers mismatch: have %d, want %d", len(added), len(many)-2)
+	}
+	if changes != len(many) {
+		t.Errorf("point changes mismatch: have %d, want %d", changes, len(many))
+	}
+
+	if _, err := api.CompareStakers(rpc.BlockNumberOrHashWithNumber(1), rpc.BlockNumberOrHashWithNumber(4), nil); err != errUnknownBlock {
+		t.Errorf("unknown block: error mismatch: have %v, want %v", err, errUnknownBlock)
+	}
+}
+
+// Tests that the rewards of an account report the block reward credited to its
+// behind balance and the behind balance released to its main balance.
+func TestGetRewards(t *testing.T) {
+	var (
+		sealer = common.HexToAddress("0x01")
+		other  = common.HexToAddress("0x02")
+		db     = state.NewDatabase(berithdb.NewMemDatabase())
+		config = *params.TestnetChainConfig
+		bsrr   = *config.Bsrr
+	)
+	bsrr.Rewards = big.NewInt(0)
+	config.Bsrr = &bsrr
+	chain := &testStakersChain{config: &config, db: db}
+
+	// Block 1 credits the sealer, block 2 releases that reward and credits it
+	// again, block 3 is sealed by another account.
+	statedb, _ := state.New(common.Hash{}, db)
+	root0, _ := statedb.Commit(false)
+	statedb.AddBehindBalance(sealer, big.NewInt(1), big.NewInt(100))
+	root1, _ := statedb.Commit(false)
+	statedb.AddBalance(sealer, big.NewInt(100))
+	statedb.RemoveFirstBehindBalance(sealer)
+	statedb.AddBehindBalance(sealer, big.NewInt(2), big.NewInt(100))
+	root2, _ := statedb.Commit(false)
+
+	coinbases := []common.Address{{}, sealer, sealer, other}
+	for i, root := range []common.Hash{root0, root1, root2, root2} {
+		header := &types.Header{Number: big.NewInt(int64(i)), Root: root, Coinbase: coinbases[i]}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	api := &API{chain: chain, bsrr: New(config.Bsrr, berithdb.NewMemDatabase())}
+
+	tests := []struct {
+		number   int64
+		credited bool
+		released int64
+	}{
+		{0, false, 0},
+		{1, true, 0},
+		{2, true, 100},
+		{3, false, 0},
+	}
+	for _, tt := range tests {
+		rewards, err := api.GetRewards(sealer, rpc.BlockNumber(tt.number))
+		if err != nil {
+			t.Fatalf("block %d: failed to get rewards: %v", tt.number, err)
+		}
+		want := new(big.Int)
+		if tt.credited {
+			want = getReward(&config, chain.headers[tt.number])
+		}
+		if rewards.Credited.ToInt().Cmp(want) != 0 {
+			t.Errorf("block %d: credited mismatch: have %v, want %v", tt.number, rewards.Credited, want)
+		}
+		if rewards.Released.ToInt().Int64() != tt.released {
+			t.Errorf("block %d: released mismatch: have %v, want %d", tt.number, rewards.Released, tt.released)
+		}
+	}
+	if _, err := api.GetRewards(sealer, 4); err != errUnknownBlock {
+		t.Errorf("unknown block: error mismatch: have %v, want %v", err, errUnknownBlock)
+	}
+}
+
+// Tests that a staking list lost before being written, as with the asynchronous
+// commit modes on a crash, is rebuilt from the nearest list on disk.
+func TestGetStakersMissingList(t *testing.T) {
+	db := state.NewDatabase(berithdb.NewMemDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+	root, _ := statedb.Commit(false)
+
+	chain := &testStakersChain{db: db}
+	for i := 0; i < 4; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Root: root}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	// Only the list of block 1 made it to disk
+	stks := staking.NewStakers()
+	stks.FetchFromList([]common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")})
+	stakingDB := &testStakingDB{lists: map[string]staking.Stakers{chain.headers[1].Hash().Hex(): stks}}
+
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 360}, berithdb.NewMemDatabase())
+	c.stakingDB = stakingDB
+
+	head := chain.CurrentHeader()
+	rebuilt, err := c.getStakers(chain, head.Number.Uint64(), head.Hash())
+	if err != nil {
+		t.Fatalf("failed to rebuild staking list: %v", err)
+	}
+	if have, want := len(rebuilt.AsList()), len(stks.AsList()); have != want {
+		t.Errorf("staker count mismatch: have %d, want %d", have, want)
+	}
+	for _, addr := range stks.AsList() {
+		if !rebuilt.IsContain(addr) {
+			t.Errorf("staker %x missing from rebuilt list", addr)
+		}
+	}
+	if len(stakingDB.commits) != 1 || stakingDB.commits[0] != head.Hash().Hex() {
+		t.Errorf("rebuilt list not committed: commits %v", stakingDB.commits)
+	}
+}
+
+// Tests that the stakers joining and leaving the staking list between two
+// blocks are reported, replaying the stake and unstake transactions mined in
+// between.
+func TestStakersDiff(t *testing.T) {
+	var (
+		stakerKey, _   = crypto.GenerateKey()
+		unstakerKey, _ = crypto.GenerateKey()
+		staker         = crypto.PubkeyToAddress(stakerKey.PublicKey)
+		unstaker       = crypto.PubkeyToAddress(unstakerKey.PublicKey)
+		db             = state.NewDatabase(berithdb.NewMemDatabase())
+		chain          = &testStakersChain{config: params.MainnetChainConfig, txs: make(map[common.Hash]types.Transactions), db: db}
+	)
+	statedb, _ := state.New(common.Hash{}, db)
+	root, _ := statedb.Commit(false)
+
+	// Block 2 mines a stake of the staker, block 3 an unstake of the unstaker
+	signer := types.MakeSigner(chain.config, big.NewInt(2))
+	stake, _ := types.SignTx(types.NewTransaction(0, staker, common.UnitForBer, 21000, big.NewInt(1), nil, types.Main, types.Stake), signer, stakerKey)
+	unstake, _ := types.SignTx(types.NewTransaction(0, unstaker, common.UnitForBer, 21000, big.NewInt(1), nil, types.Stake, types.Main), signer, unstakerKey)
+	for i, txs := range []types.Transactions{nil, nil, {stake}, {unstake}} {
+		header := &types.Header{Number: big.NewInt(int64(i)), Root: root}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+		chain.txs[header.Hash()] = txs
+	}
+	// Only the staking list of block 1 is on disk
+	stks := staking.NewStakers()
+	stks.Put(unstaker)
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 360}, berithdb.NewMemDatabase())
+	c.stakingDB = &testStakingDB{lists: map[string]staking.Stakers{chain.headers[1].Hash().Hex(): stks}}
+	api := &API{chain: chain, bsrr: c}
+
+	tests := []struct {
+		from, to rpc.BlockNumber
+		added    []common.Address
+		removed  []common.Address
+	}{
+		{1, 1, []common.Address{}, []common.Address{}},
+		{1, 2, []common.Address{staker}, []common.Address{}},
+		{2, 3, []common.Address{}, []common.Address{unstaker}},
+		{1, rpc.LatestBlockNumber, []common.Address{staker}, []common.Address{unstaker}},
+	}
+	for _, tt := range tests {
+		diff, err := api.StakersDiff(tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("blocks %d-%d: failed to diff stakers: %v", tt.from, tt.to, err)
+		}
+		if !reflect.DeepEqual(diff.Added, tt.added) {
+			t.Errorf("blocks %d-%d: added mismatch: have %x, want %x", tt.from, tt.to, diff.Added, tt.added)
+		}
+		if !reflect.DeepEqual(diff.Removed, tt.removed) {
+			t.Errorf("blocks %d-%d: removed mismatch: have %x, want %x", tt.from, tt.to, diff.Removed, tt.removed)
+		}
+	}
+	if _, err := api.StakersDiff(3, 1); err == nil || !strings.Contains(err.Error(), "invalid range") {
+		t.Errorf("reversed range: error mismatch: have %v", err)
+	}
+	if _, err := api.StakersDiff(1, 4); err != errUnknownBlock {
+		t.Errorf("unknown block: error mismatch: have %v, want %v", err, errUnknownBlock)
+	}
+}
+
+// Tests that the staker trace is invoked for the stake and unstake transactions
+// of a block while updating its staker set, and that the changes are replayed.
+func TestStakerTrace(t *testing.T) {
+	var (
+		stakerKey, _   = crypto.GenerateKey()
+		unstakerKey, _ = crypto.GenerateKey()
+		staker         = crypto.PubkeyToAddress(stakerKey.PublicKey)
+		unstaker       = crypto.PubkeyToAddress(unstakerKey.PublicKey)
+		db             = state.NewDatabase(berithdb.NewMemDatabase())
+		chain          = &testStakersChain{config: params.MainnetChainConfig, txs: make(map[common.Hash]types.Transactions), db: db}
+	)
+	statedb, _ := state.New(common.Hash{}, db)
+	root, _ := statedb.Commit(false)
+
+	// Block 1 mines a transfer, a stake of the staker and an unstake of the unstaker
+	signer := types.MakeSigner(chain.config, big.NewInt(1))
+	transfer, _ := types.SignTx(types.NewTransaction(0, staker, common.UnitForBer, 21000, big.NewInt(1), nil, types.Main, types.Main), signer, unstakerKey)
+	stake, _ := types.SignTx(types.NewTransaction(0, staker, common.UnitForBer, 21000, big.NewInt(1), nil, types.Main, types.Stake), signer, stakerKey)
+	unstake, _ := types.SignTx(types.NewTransaction(1, unstaker, common.UnitForBer, 21000, big.NewInt(1), nil, types.Stake, types.Main), signer, unstakerKey)
+	for i, txs := range []types.Transactions{nil, {transfer, stake, unstake}} {
+		header := &types.Header{Number: big.NewInt(int64(i)), Root: root}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+		chain.txs[header.Hash()] = txs
+	}
+	want := []StakerChange{{stake.Hash(), staker, true}, {unstake.Hash(), unstaker, false}}
+
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 360}, berithdb.NewMemDatabase())
+	var have []StakerChange
+	c.SetStakerTrace(func(tx common.Hash, addr common.Address, added bool) {
+		have = append(have, StakerChange{tx, addr, added})
+	})
+	stks := staking.NewStakers()
+	stks.Put(unstaker)
+	block := chain.GetBlock(chain.headers[1].Hash(), 1)
+	if err := c.setStakersWithTxs(nil, chain, stks, block.Transactions(), block.Header()); err != nil {
+		t.Fatalf("failed to set stakers: %v", err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("traced changes mismatch: have %v, want %v", have, want)
+	}
+	if !stks.IsContain(staker) || stks.IsContain(unstaker) {
+		t.Errorf("stakers mismatch: have %x", stks.AsList())
+	}
+	// Disabled traces are not invoked
+	c.SetStakerTrace(nil)
+	have = nil
+	c.setStakersWithTxs(nil, chain, staking.NewStakers(), block.Transactions(), block.Header())
+	if len(have) != 0 {
+		t.Errorf("disabled trace invoked: %v", have)
+	}
+	// The changes of the block are replayed over the API
+	api := &API{chain: chain, bsrr: c}
+	changes, err := api.TraceStakerChanges(rpc.BlockNumberOrHashWithNumber(1))
+	if err != nil {
+		t.Fatalf("failed to trace staker changes: %v", err)
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("replayed changes mismatch: have %v, want %v", changes, want)
+	}
+	if _, err := api.TraceStakerChanges(rpc.BlockNumberOrHashWithNumber(2)); err != errUnknownBlock {
+		t.Errorf("unknown block: error mismatch: have %v, want %v", err, errUnknownBlock)
+	}
+}
+
+// Tests that rebuilding a staking list replays at most MaxReorgDepth blocks on
+// top of the nearest stored list.
+func TestGetStakersMaxReorgDepth(t *testing.T) {
+	db := state.NewDatabase(berithdb.NewMemDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+	root, _ := statedb.Commit(false)
+
+	chain := &testStakersChain{config: params.MainnetChainConfig, db: db}
+	for i := 0; i < 5; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Root: root}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	// Only the list of block 1 is stored, block 4 is 3 blocks above it
+	stks := staking.NewStakers()
+	stks.Put(common.HexToAddress("0x01"))
+	lists := map[string]staking.Stakers{chain.headers[1].Hash().Hex(): stks}
+
+	for _, depth := range []uint64{0, 2, 3, 4} {
+		c := New(&params.BSRRConfig{Period: 10, Epoch: 360, MaxReorgDepth: depth}, berithdb.NewMemDatabase())
+		c.stakingDB = &testStakingDB{lists: lists}
+
+		head := chain.headers[4]
+		_, err := c.getStakers(chain, head.Number.Uint64(), head.Hash())
+		if depth == 0 || depth >= 3 {
+			if err != nil {
+				t.Errorf("depth %d: failed to rebuild staking list: %v", depth, err)
+			}
+			continue
+		}
+		if err != errReorgTooDeep {
+			t.Errorf("depth %d: error mismatch: have %v, want %v", depth, err, errReorgTooDeep)
+		}
+	}
+}
+
+// Tests that a block whose staking lists have to be rebuilt passes verification
+// in the read-only mode although the staking database can't be written, and is
+// rejected otherwise.
+func TestVerifyStakingReadOnly(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	// Block 1 mines a stake of the signer, no staking list is stored for block 2,
+	// the target block of block 5
+	db := state.NewDatabase(berithdb.NewMemDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+	root, _ := statedb.Commit(false)
+
+	chain := &testStakersChain{config: params.MainnetChainConfig, txs: make(map[common.Hash]types.Transactions), db: db}
+	stake, _ := types.SignTx(types.NewTransaction(0, signer, common.UnitForBer, 21000, big.NewInt(1), nil, types.Main, types.Stake), types.MakeSigner(chain.config, big.NewInt(1)), key)
+	for i, txs := range []types.Transactions{nil, {stake}, nil, nil, nil} {
+		header := &types.Header{Number: big.NewInt(int64(i)), Root: root, Time: big.NewInt(int64(10 * i))}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+		chain.txs[header.Hash()] = txs
+	}
+	header := newTestHeaders(chain.CurrentHeader(), 10, 1)[0]
+	header.Coinbase = signer
+
+	for _, readOnly := range []bool{true, false} {
+		dir, err := ioutil.TempDir("", "stakingdb")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		stakingDB := &staking.StakingDB{ReadOnly: readOnly}
+		if err := stakingDB.CreateDB(dir, staking.NewStakers); err != nil {
+			t.Fatalf("read-only %v: failed to create staking database: %v", readOnly, err)
+		}
+		// Closing the database fails all reads and writes from now on
+		stakingDB.Close()
+
+		c := NewCliqueWithStakingDB(stakingDB, &params.BSRRConfig{Period: 10, Epoch: 2}, berithdb.NewMemDatabase())
+		target := chain.headers[2]
+		if readOnly {
+			diff, rank := c.calcDifficultyAndRank(signer, chain, 0, target)
+			if rank != 1 {
+				t.Fatalf("signer not elected: rank %d", rank)
+			}
+			header.Difficulty = diff
+		}
+		if err := c.VerifyHeader(chain, header, true); err != nil {
+			t.Fatalf("read-only %v: failed to verify header: %v", readOnly, err)
+		}
+		// The creator is verified when finalizing the block on top of its parent
+		statedb, _ := state.New(root, db)
+		_, err = c.Finalize(chain, types.CopyHeader(header), statedb, nil, nil, nil)
+		switch {
+		case readOnly && err != nil:
+			t.Errorf("read-only: failed to finalize block: %v", err)
+		case !readOnly && err != errStakingList:
+			t.Errorf("writable: error mismatch: have %v, want %v", err, errStakingList)
+		}
+	}
+}
+
+// Tests that the join ratio of a staker is its share of the selection points,
+// and an equal share if none of the stakers has a point.
+func TestGetJoinRatio(t *testing.T) {
+	var (
+		c        = New(&params.BSRRConfig{Period: 10, Epoch: 360}, berithdb.NewMemDatabase())
+		stakerA  = common.HexToAddress("0x01")
+		stakerB  = common.HexToAddress("0x02")
+		stakerC  = common.HexToAddress("0x03")
+		outsider = common.HexToAddress("0x04")
+	)
+	stks := staking.NewStakers()
+	stks.FetchFromList([]common.Address{stakerA, stakerB, stakerC})
+
+	tests := []struct {
+		points map[common.Address]int64
+		want   map[common.Address]float64
+	}{
+		{
+			points: map[common.Address]int64{stakerA: 1, stakerB: 3},
+			want:   map[common.Address]float64{stakerA: 0.25, stakerB: 0.75, stakerC: 0, outsider: 0},
+		},
+		{
+			points: nil, // e.g. a state restored without the points
+			want:   map[common.Address]float64{stakerA: 1.0 / 3, stakerB: 1.0 / 3, stakerC: 1.0 / 3, outsider: 0},
+		},
+	}
+	for i, tt := range tests {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(berithdb.NewMemDatabase()))
+		for addr, point := range tt.points {
+			statedb.SetPoint(addr, big.NewInt(point))
+		}
+		for addr, want := range tt.want {
+			ratio, err := c.getJoinRatio(stks, addr, common.Hash{}, 1, statedb)
+			if err != nil {
+				t.Fatalf("test %d: %x: failed to get join ratio: %v", i, addr, err)
+			}
+			if ratio != want {
+				t.Errorf("test %d: %x: join ratio mismatch: have %v, want %v", i, addr, ratio, want)
+			}
+		}
+	}
+}
+
+// Tests that the signers of a block are sorted by address, so that two engines
+// fed the same staking list return identical slices.
+func TestGetSignersOrder(t *testing.T) {
+	// The genesis signers are listed in descending order
+	extra := make([]byte, extraVanity)
+	for i := 3; i > 0; i-- {
+		extra = append(extra, common.BigToAddress(big.NewInt(int64(i))).Bytes()...)
+	}
+	extra = append(extra, make([]byte, extraSeal)...)
+
+	chain := &testStakersChain{config: params.MainnetChainConfig}
+	for i := 0; i < 3; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i))}
+		if i == 0 {
+			header.Extra = extra
+		} else {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+
+	stks := staking.NewStakers()
+	for i := 0; i < 32; i++ {
+		stks.Put(common.BytesToAddress(crypto.Keccak256([]byte{byte(i)})))
+	}
+	lists := map[string]staking.Stakers{chain.headers[2].Hash().Hex(): stks}
+
+	var results [2][]signers
+	for i := range results {
+		c := New(&params.BSRRConfig{Period: 10, Epoch: 2}, berithdb.NewMemDatabase())
+		c.stakingDB = &testStakingDB{lists: lists}
+
+		for _, header := range chain.headers {
+			signers, err := c.getSigners(chain, header)
+			if err != nil {
+				t.Fatalf("engine %d, block %d: failed to get signers: %v", i, header.Number, err)
+			}
+			for j := 1; j < len(signers); j++ {
+				if bytes.Compare(signers[j-1][:], signers[j][:]) >= 0 {
+					t.Fatalf("engine %d, block %d: signers not sorted: %x", i, header.Number, signers)
+				}
+			}
+			results[i] = append(results[i], signers)
+		}
+	}
+	if len(results[0][2]) != 32 {
+		t.Errorf("signer count mismatch: have %d, want 32", len(results[0][2]))
+	}
+	if !reflect.DeepEqual(results[0], results[1]) {
+		t.Errorf("signers mismatch between engines:\n%x\n%x", results[0], results[1])
+	}
+}
+
+// Tests that the genesis signers fall back for an empty staking list past the
+// first epoch, elected like on the first epoch, so that the chain can recover.
+func TestGetSignersEmptyStakers(t *testing.T) {
+	genesisSigners := signers{common.BigToAddress(big.NewInt(1)), common.BigToAddress(big.NewInt(2))}
+	extra := make([]byte, extraVanity)
+	for _, signer := range genesisSigners {
+		extra = append(extra, signer.Bytes()...)
+	}
+	extra = append(extra, make([]byte, extraSeal)...)
+
+	chain := &testStakersChain{config: params.MainnetChainConfig}
+	for i := 0; i < 3; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i))}
+		if i == 0 {
+			header.Extra = extra
+		} else {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	target := chain.headers[2]
+
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 2}, berithdb.NewMemDatabase())
+	c.stakingDB = &testStakingDB{lists: map[string]staking.Stakers{target.Hash().Hex(): staking.NewStakers()}}
+
+	have, err := c.getSigners(chain, target)
+	if err != nil {
+		t.Fatalf("failed to get signers: %v", err)
+	}
+	if !reflect.DeepEqual(have, genesisSigners) {
+		t.Errorf("signers mismatch: have %x, want %x", have, genesisSigners)
+	}
+	for _, signer := range genesisSigners {
+		if diff, rank := c.calcDifficultyAndRank(signer, chain, 0, target); rank != 1 || diff.Int64() != diffWithoutStaker {
+			t.Errorf("signer %x: election mismatch: have %v, %d, want %d, 1", signer, diff, rank, diffWithoutStaker)
+		}
+	}
+	if _, rank := c.calcDifficultyAndRank(common.BigToAddress(big.NewInt(3)), chain, 0, target); rank != -1 {
+		t.Errorf("non genesis signer elected with rank %d", rank)
+	}
+}
+
+// Tests that the churn rate is the share of consecutive rank 1 creators that
+// differ, and that windows beyond the target blocks are refused.
+func TestRankStability(t *testing.T) {
+	var (
+		a = common.HexToAddress("0x01")
+		b = common.HexToAddress("0x02")
+		c = common.HexToAddress("0x03")
+	)
+	tests := []struct {
+		creators []common.Address
+		rate     float64
+	}{
+		{[]common.Address{a, a}, 0},
+		{[]common.Address{a, a, a, a, a}, 0},
+		{[]common.Address{a, b}, 1},
+		{[]common.Address{a, b, a, b, a}, 1},
+		{[]common.Address{a, a, b, b, c}, 0.5},
+		{[]common.Address{a, a, a, a, b}, 0.25},
+		{[]common.Address{{}, a, a}, 0.5},
+	}
+	for i, tt := range tests {
+		if rate := calcChurnRate(tt.creators); rate != tt.rate {
+			t.Errorf("test %d: churn rate mismatch: have %v, want %v", i, rate, tt.rate)
+		}
+	}
+
+	// A chain of a single staker with 3 target blocks past the first epoch, 2,
+	// 3 and 4, the staker being elected on all of them
+	db := state.NewDatabase(berithdb.NewMemDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+	root, _ := statedb.Commit(false)
+
+	chain := &testStakersChain{config: params.MainnetChainConfig, db: db}
+	for i := 0; i < 7; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Root: root}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	stks := staking.NewStakers()
+	stks.Put(a)
+
+	engine := New(&params.BSRRConfig{Period: 10, Epoch: 2}, berithdb.NewMemDatabase())
+	engine.stakingDB = &testStakingDB{lists: map[string]staking.Stakers{chain.headers[1].Hash().Hex(): stks}}
+
+	for _, window := range []int{-1, 0, 1, 4, rankStabilityLimit + 1} {
+		if _, err := engine.RankStability(chain, window); err == nil {
+			t.Errorf("window %d: no error", window)
+		}
+	}
+	if rate, err := engine.RankStability(chain, 3); err != nil || rate != 0 {
+		t.Errorf("churn rate mismatch: have %v (%v), want 0", rate, err)
+	}
+}
+
+// Tests that the preview of the next block matches the block subsequently
+// prepared and sealed on top of the head, and that previewing stores none of
+// the staking lists rebuilt for it.
+func TestPreviewNextBlock(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	// A chain of a single staker whose staking list is only stored for block 1,
+	// the list of target block 4 of the next block being rebuilt on the way
+	db := state.NewDatabase(berithdb.NewMemDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+	root, _ := statedb.Commit(false)
+
+	chain := &testStakersChain{config: params.MainnetChainConfig, db: db}
+	for i := 0; i < 7; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Root: root, Time: big.NewInt(int64(1000 + 10*i))}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	stks := staking.NewStakers()
+	stks.Put(signer)
+	stakingDB := &testStakingDB{lists: map[string]staking.Stakers{chain.headers[1].Hash().Hex(): stks}}
+
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 2}, berithdb.NewMemDatabase())
+	c.stakingDB = stakingDB
+	api := &API{chain: chain, bsrr: c}
+
+	// Without a configured signer an address is required
+	if _, err := api.PreviewNextBlock(nil); err != errNoSigner {
+		t.Errorf("error mismatch: have %v, want %v", err, errNoSigner)
+	}
+	outsider := common.HexToAddress("0x01")
+	preview, err := api.PreviewNextBlock(&outsider)
+	if err != nil {
+		t.Fatalf("failed to preview block for outsider: %v", err)
+	}
+	if preview.Elected || preview.Rank != 0 || preview.Difficulty.ToInt().Sign() != 0 {
+		t.Errorf("outsider elected: %+v", preview)
+	}
+	c.Authorize(signer, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	if preview, err = api.PreviewNextBlock(nil); err != nil {
+		t.Fatalf("failed to preview block: %v", err)
+	}
+	if len(stakingDB.commits) != 0 || c.cache.Len() != 0 {
+		t.Errorf("staking list stored by preview: commits %v, cached %d", stakingDB.commits, c.cache.Len())
+	}
+	if preview.Signer != signer || !preview.Elected || preview.Rank != 1 {
+		t.Fatalf("signer not elected: %+v", preview)
+	}
+	head := chain.CurrentHeader()
+	if uint64(preview.Number) != 7 || preview.ParentHash != head.Hash() {
+		t.Errorf("block mismatch: have %d %x, want 7 %x", preview.Number, preview.ParentHash, head.Hash())
+	}
+	if target := chain.headers[4]; uint64(preview.TargetNumber) != 4 || preview.TargetHash != target.Hash() {
+		t.Errorf("target mismatch: have %d %x, want 4 %x", preview.TargetNumber, preview.TargetHash, target.Hash())
+	}
+	// The parent is long past, so the block is due right away
+	if preview.RankDelay != 0 || preview.Delay < 0 || preview.Delay > 1000 {
+		t.Errorf("delay mismatch: rank delay %dms, delay %dms", preview.RankDelay, preview.Delay)
+	}
+
+	// Prepare and seal the block, it must carry the previewed values
+	header := &types.Header{ParentHash: head.Hash(), Number: big.NewInt(7), Coinbase: signer}
+	if err := c.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare block: %v", err)
+	}
+	results := make(chan *types.Block, 1)
+	if err := c.Seal(chain, types.NewBlockWithHeader(header), results, make(chan struct{})); err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	var sealed *types.Header
+	select {
+	case block := <-results:
+		sealed = block.Header()
+	case <-time.After(2 * time.Second):
+		t.Fatalf("sealed block not released")
+	}
+	if sealed.Difficulty.Cmp(preview.Difficulty.ToInt()) != 0 {
+		t.Errorf("difficulty mismatch: have %v, previewed %v", sealed.Difficulty, preview.Difficulty)
+	}
+	if sealed.Nonce.Uint64() != uint64(preview.Rank) {
+		t.Errorf("rank mismatch: have %d, previewed %d", sealed.Nonce.Uint64(), preview.Rank)
+	}
+	if time := sealed.Time.Uint64(); time < uint64(preview.Time) || time > uint64(preview.Time)+1 {
+		t.Errorf("timestamp mismatch: have %d, previewed %d", time, preview.Time)
+	}
+}
+
+// Tests that the predicted producers of the next block are ranked in ascending
+// order, each with the rank and score previewed for it, and that predicting
+// stores none of the staking lists rebuilt for it.
+func TestPredictProducers(t *testing.T) {
+	db := state.NewDatabase(berithdb.NewMemDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+	stakers := []common.Address{{1}, {2}, {3}}
+	for i, staker := range stakers {
+		statedb.AddStakeBalance(staker, new(big.Int).Mul(StakeMinimum, big.NewInt(int64(i+1))), big.NewInt(0))
+	}
+	root, _ := statedb.Commit(false)
+
+	chain := &testStakersChain{config: params.MainnetChainConfig, db: db}
+	for i := 0; i < 7; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Root: root, Time: big.NewInt(int64(1000 + 10*i))}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	stks := staking.NewStakers()
+	for _, staker := range stakers {
+		stks.Put(staker)
+	}
+	stakingDB := &testStakingDB{lists: map[string]staking.Stakers{chain.headers[1].Hash().Hex(): stks}}
+
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 2}, berithdb.NewMemDatabase())
+	c.stakingDB = stakingDB
+	api := &API{chain: chain, bsrr: c}
+
+	predictions, err := api.PredictProducers(0)
+	if err != nil {
+		t.Fatalf("failed to predict producers: %v", err)
+	}
+	if len(predictions) != len(stakers) {
+		t.Fatalf("prediction count mismatch: have %d, want %d", len(predictions), len(stakers))
+	}
+	for i, prediction := range predictions {
+		if prediction.Rank != i+1 {
+			t.Errorf("prediction %d: rank mismatch: have %d, want %d", i, prediction.Rank, i+1)
+		}
+		preview, err := api.PreviewNextBlock(&prediction.Address)
+		if err != nil {
+			t.Fatalf("failed to preview block: %v", err)
+		}
+		if preview.Rank != prediction.Rank || preview.Difficulty.ToInt().Cmp(prediction.Score.ToInt()) != 0 {
+			t.Errorf("prediction %d: have rank %d score %v, previewed rank %d score %v", i, prediction.Rank, prediction.Score, preview.Rank, preview.Difficulty)
+		}
+	}
+	if len(stakingDB.commits) != 0 || c.cache.Len() != 0 {
+		t.Errorf("staking list stored by prediction: commits %v, cached %d", stakingDB.commits, c.cache.Len())
+	}
+	if top, err := api.PredictProducers(2); err != nil || len(top) != 2 || top[0].Address != predictions[0].Address || top[1].Address != predictions[1].Address {
+		t.Errorf("top producers mismatch: have %v (%v), want %v", top, err, predictions[:2])
+	}
+}
+
+// sealHashFixture is the content of testdata/sealhash.json, the seal hashes of
+// headers documented for the implementers of external signers.
+type sealHashFixture struct {
+	Key     string `json:"key"`
+	Vectors []struct {
+		Description string          `json:"description"`
+		Header      json.RawMessage `json:"header"`
+		SealHash    common.Hash     `json:"sealHash"`
+		Signer      common.Address  `json:"signer"`
+	} `json:"vectors"`
+}
+
+func loadSealHashFixture(t *testing.T) *sealHashFixture {
+	blob, err := ioutil.ReadFile(filepath.Join("testdata", "sealhash.json"))
+	if err != nil {
+		t.Fatalf("failed to read test vectors: %v", err)
+	}
+	fixture := new(sealHashFixture)
+	if err := json.Unmarshal(blob, fixture); err != nil {
+		t.Fatalf("failed to parse test vectors: %v", err)
+	}
+	return fixture
+}
+
+// Tests that the network parameters report the configured values of the engine,
+// with the defaults filled in for the missing ones.
+func TestNetworkParams(t *testing.T) {
+	config := &params.BSRRConfig{Period: 7, Epoch: 20, ForkFactor: 0.5, Rewards: big.NewInt(100), StakeMinimum: big.NewInt(1000)}
+	api := &API{bsrr: New(config, berithdb.NewMemDatabase())}
+
+	have := api.NetworkParams()
+	want := &NetworkParams{
+		Period:       7,
+		Epoch:        20,
+		ForkFactor:   0.5,
+		Rewards:      (*hexutil.Big)(big.NewInt(100)),
+		StakeMinimum: (*hexutil.Big)(big.NewInt(1000)),
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("params mismatch: have %+v, want %+v", have, want)
+	}
+	// The reported values don't alias the configuration of the engine
+	have.StakeMinimum.ToInt().SetInt64(1)
+	if api.bsrr.config.StakeMinimum.Int64() != 1000 {
+		t.Errorf("engine config modified: %v", api.bsrr.config.StakeMinimum)
+	}
+	// Missing values are reported as defaulted by the engine
+	api = &API{bsrr: New(&params.BSRRConfig{Period: 5}, berithdb.NewMemDatabase())}
+	have = api.NetworkParams()
+	if have.Epoch != epochLength || have.ForkFactor != ForkFactor || have.Rewards.ToInt().Cmp(RewardBlock) != 0 || have.StakeMinimum.ToInt().Cmp(StakeMinimum) != 0 {
+		t.Errorf("defaults mismatch: %+v", have)
+	}
+}
+
+// Tests that the epoch information of the blocks around the epoch boundaries
+// reports checkpoint blocks as starting their epoch, and the stake target of the
+// next block as the engine determines it.
+func TestEpochInfo(t *testing.T) {
+	chain, _ := newVoteHistoryChain(nil, 14)
+	api := &API{chain: chain, bsrr: New(&params.BSRRConfig{Period: 10, Epoch: 4}, berithdb.NewMemDatabase())}
+
+	tests := []struct {
+		number                   uint64
+		epoch, start, next, left uint64
+		target                   uint64
+	}{
+		{0, 0, 0, 4, 4, 0},
+		{3, 0, 0, 4, 1, 0},
+		{4, 1, 4, 8, 4, 4},
+		{5, 1, 4, 8, 3, 4},
+		{7, 1, 4, 8, 1, 4},
+		{8, 2, 8, 12, 4, 4},
+		{9, 2, 8, 12, 3, 5},
+		{11, 2, 8, 12, 1, 7},
+		{12, 3, 12, 16, 4, 8},
+		{13, 3, 12, 16, 3, 9},
+	}
+	for _, tt := range tests {
+		number := rpc.BlockNumber(tt.number)
+		info, err := api.EpochInfo(&number)
+		if err != nil {
+			t.Errorf("block %d: failed to get epoch info: %v", tt.number, err)
+			continue
+		}
+		want := &EpochInfo{
+			Number:               tt.number,
+			EpochLength:          4,
+			CurrentEpochNumber:   tt.epoch,
+			EpochStartBlock:      tt.start,
+			NextCheckpointBlock:  tt.next,
+			BlocksUntilNextEpoch: tt.left,
+			StakeTargetBlock:     &StakeTarget{Number: tt.target, Hash: chain.headers[tt.target].Hash(), Available: true},
+		}
+		if !reflect.DeepEqual(info, want) {
+			t.Errorf("block %d: epoch info mismatch: have %+v, want %+v", tt.number, info, want)
+		}
+		// The target is the one the next block is prepared on
+		if target, _ := api.bsrr.getStakeTargetBlock(chain, chain.headers[tt.number]); target.Hash() != info.StakeTargetBlock.Hash {
+			t.Errorf("block %d: target mismatch with engine: have %d, want %d", tt.number, info.StakeTargetBlock.Number, target.Number)
+		}
+	}
+	// Without a block number, the current block is reported
+	for _, number := range []*rpc.BlockNumber{nil, new(rpc.BlockNumber)} {
+		if number != nil {
+			*number = rpc.LatestBlockNumber
+		}
+		if info, err := api.EpochInfo(number); err != nil || info.Number != 13 {
+			t.Errorf("current epoch info mismatch: have %+v (err %v), want block 13", info, err)
+		}
+	}
+	// Unknown blocks are refused
+	number := rpc.BlockNumber(14)
+	if _, err := api.EpochInfo(&number); err != errUnknownBlock {
+		t.Errorf("unknown block error mismatch: have %v, want %v", err, errUnknownBlock)
+	}
+}
+
+// Tests that the sealHash API and the engine compute the seal hashes of the test
+// vectors, and that the headers are sealed by the test key.
+func TestSealHashVectors(t *testing.T) {
+	fixture := loadSealHashFixture(t)
+	key, err := crypto.HexToECDSA(fixture.Key)
+	if err != nil {
+		t.Fatalf("invalid test key: %v", err)
+	}
+	sigcache, _ := lru.NewARC(inmemorySignatures)
+
+	api := new(API)
+	for _, vector := range fixture.Vectors {
+		hash, err := api.SealHash(vector.Header)
+		if err != nil {
+			t.Errorf("%s: failed to compute seal hash: %v", vector.Description, err)
+			continue
+		}
+		if hash != vector.SealHash {
+			t.Errorf("%s: seal hash mismatch: have %x, want %x", vector.Description, hash, vector.SealHash)
+		}
+		header := new(types.Header)
+		if err := json.Unmarshal(vector.Header, header); err != nil {
+			t.Fatalf("%s: invalid header: %v", vector.Description, err)
+		}
+		if engineHash := new(BSRR).SealHash(header); engineHash != hash {
+			t.Errorf("%s: engine seal hash mismatch: have %x, want %x", vector.Description, engineHash, hash)
+		}
+		seal, err := crypto.Sign(hash.Bytes(), key)
+		if err != nil {
+			t.Fatalf("%s: failed to sign: %v", vector.Description, err)
+		}
+		if !bytes.Equal(header.Extra[len(header.Extra)-extraSeal:], seal) {
+			t.Errorf("%s: seal mismatch: have %x, want %x", vector.Description, header.Extra[len(header.Extra)-extraSeal:], seal)
+		}
+		signer, err := ecrecover(header, sigcache)
+		if err != nil {
+			t.Errorf("%s: failed to recover signer: %v", vector.Description, err)
+		}
+		if signer != vector.Signer || signer != crypto.PubkeyToAddress(key.PublicKey) {
+			t.Errorf("%s: signer mismatch: have %x, want %x", vector.Description, signer, vector.Signer)
+		}
+	}
+}
+
+// Tests that the sealHash API rejects headers it can't compute the seal hash of,
+// instead of panicking on extra-data shorter than the seal.
+func TestSealHashInvalid(t *testing.T) {
+	vector := loadSealHashFixture(t).Vectors[1]
+
+	// modify sets or, given nil, deletes a field of the header, dropping the hash
+	// of the header unless it is to be checked
+	modify := func(field string, value interface{}, keepHash bool) json.RawMessage {
+		var header map[string]interface{}
+		if err := json.Unmarshal(vector.Header, &header); err != nil {
+			t.Fatal(err)
+		}
+		if value == nil {
+			delete(header, field)
+		} else {
+			header[field] = value
+		}
+		if !keepHash {
+			delete(header, "hash")
+		}
+		blob, err := json.Marshal(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return blob
+	}
+	tests := []struct {
+		name   string
+		header json.RawMessage
+	}{
+		{"extra-data shorter than the seal", modify("extraData", hexutil.Bytes(make([]byte, extraSeal-1)).String(), false)},
+		{"empty extra-data", modify("extraData", "0x", false)},
+		{"modified header", modify("number", "0x16a", true)},
+		{"missing field", modify("stateRoot", nil, false)},
+		{"malformed JSON", json.RawMessage(`{"number":`)},
+	}
+	for _, tt := range tests {
+		if _, err := new(API).SealHash(tt.header); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+	if _, err := new(API).SealHash(modify("extraData", "0x", false)); err != errMissingSignature {
+		t.Errorf("short extra-data: error mismatch: have %v, want %v", err, errMissingSignature)
+	}
+}
+
+// Tests that solo sealing only applies to the only signer of a chain, releasing
+// its blocks without running the election again, and that chains of several
+// signers are sealed as usual.
+func TestSoloSealing(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	tests := []struct {
+		signers []common.Address
+		enable  bool
+		solo    bool
+	}{
+		{signers: []common.Address{signer}, enable: false, solo: false},
+		{signers: []common.Address{signer}, enable: true, solo: true},
+		{signers: []common.Address{signer, {1}}, enable: true, solo: false},
+		{signers: []common.Address{{1}}, enable: true, solo: false},
+	}
+	for i, tt := range tests {
+		genesis := &types.Header{
+			Number:     big.NewInt(0),
+			Difficulty: big.NewInt(1),
+			Extra:      make([]byte, extraVanity, extraVanity+len(tt.signers)*common.AddressLength+extraSeal),
+		}
+		for _, s := range tt.signers {
+			genesis.Extra = append(genesis.Extra, s.Bytes()...)
+		}
+		genesis.Extra = append(genesis.Extra, make([]byte, extraSeal)...)
+		chain := &testChainReader{genesis: genesis}
+
+		c := New(&params.BSRRConfig{Period: 0, Epoch: 360}, berithdb.NewMemDatabase())
+		c.Authorize(signer, func(account accounts.Account, hash []byte) ([]byte, error) {
+			return crypto.Sign(hash, key)
+		})
+		if tt.enable {
+			c.EnableSoloSealing()
+		}
+		if have := c.SoloSealing(chain); have != tt.solo {
+			t.Errorf("test %d: solo sealing mismatch: have %v, want %v", i, have, tt.solo)
+		}
+		block := types.NewBlockWithHeader(&types.Header{
+			ParentHash: genesis.Hash(),
+			Number:     big.NewInt(1),
+			Difficulty: big.NewInt(1),
+			Coinbase:   signer,
+			Time:       big.NewInt(time.Now().Unix()),
+			Extra:      make([]byte, extraVanity+extraSeal),
+		})
+		results := make(chan *types.Block, 1)
+		err := c.Seal(chain, block, results, make(chan struct{}))
+		if tt.signers[0] != signer {
+			if err != errUnauthorizedSigner {
+				t.Errorf("test %d: unauthorized seal error mismatch: have %v, want %v", i, err, errUnauthorizedSigner)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: failed to seal block: %v", i, err)
+		}
+		select {
+		case <-results:
+		case <-time.After(time.Second):
+			t.Fatalf("test %d: sealed block not released", i)
+		}
+		if ran := c.selection != nil; ran == tt.solo {
+			t.Errorf("test %d: election run mismatch: have %v, want %v", i, ran, !tt.solo)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package bsrr
+
+import (
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/log"
+)
+
+// ClockSkewSource measures how far the local clock is ahead of a reference time,
+// negative if it is behind.
+type ClockSkewSource func() (time.Duration, error)
+
+/*
+[BERITH]
+Function that measures the skew of the local clock against the source at startup,
+warning if it exceeds the future block drift tolerated by the header verification:
+the blocks sealed by a fast node are deferred by the others as future blocks, and
+a slow node defers the blocks of the others, so neither mines nor verifies in time.
+It returns the skew measured.
+*/
+func (c *BSRR) CheckClockSkew(source ClockSkewSource) (time.Duration, error) {
+	skew, err := source()
+	if err != nil {
+		log.Debug("Failed to measure clock skew", "err", err)
+		return 0, err
+	}
+	drift := c.futureBlockDrift()
+	switch {
+	case skew > drift:
+		log.Warn("System clock is ahead, the sealed blocks are deferred by the network as future blocks", "skew", common.PrettyDuration(skew), "tolerance", common.PrettyDuration(drift))
+	case skew < -drift:
+		log.Warn("System clock is behind, the blocks of the network are deferred as future blocks", "skew", common.PrettyDuration(-skew), "tolerance", common.PrettyDuration(drift))
+	default:
+		log.Debug("Clock skew check done", "skew", skew)
+		return skew, nil
+	}
+	log.Warn("Please enable network time synchronisation in system settings")
+	return skew, nil
+}
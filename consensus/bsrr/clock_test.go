@@ -0,0 +1,55 @@
+package bsrr
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/params"
+)
+
+// Tests that a clock skewed beyond the allowed future block drift is warned
+// about in either direction, and a clock within it isn't.
+func TestCheckClockSkew(t *testing.T) {
+	c := &BSRR{config: &params.BSRRConfig{Period: 5, Epoch: 360, FutureBlockDrift: 2}}
+
+	var warnings int
+	log.Root().SetHandler(log.FuncHandler(func(r *log.Record) error {
+		if r.Lvl == log.LvlWarn {
+			warnings++
+		}
+		return nil
+	}))
+	defer log.Root().SetHandler(log.DiscardHandler())
+
+	tests := []struct {
+		skew time.Duration
+		warn bool
+	}{
+		{0, false},
+		{2 * time.Second, false},
+		{-2 * time.Second, false},
+		{3 * time.Second, true},
+		{-time.Minute, true},
+	}
+	for _, test := range tests {
+		warnings = 0
+		skew, err := c.CheckClockSkew(func() (time.Duration, error) { return test.skew, nil })
+		if err != nil || skew != test.skew {
+			t.Errorf("skew %v: result mismatch: have %v, %v", test.skew, skew, err)
+		}
+		if warned := warnings > 0; warned != test.warn {
+			t.Errorf("skew %v: warning mismatch: have %v, want %v", test.skew, warned, test.warn)
+		}
+	}
+	// A failed measurement is no reason for a warning
+	warnings = 0
+	failure := errors.New("unreachable")
+	if _, err := c.CheckClockSkew(func() (time.Duration, error) { return 0, failure }); err != failure {
+		t.Errorf("error mismatch: have %v, want %v", err, failure)
+	}
+	if warnings != 0 {
+		t.Errorf("warned about a failed measurement")
+	}
+}
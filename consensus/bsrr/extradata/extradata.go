@@ -0,0 +1,134 @@
+// Package extradata implements the layout of the extra-data of the bsrr headers,
+// so that it can be decoded without importing the whole engine.
+package extradata
+
+import (
+	"errors"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/crypto"
+	"github.com/BerithFoundation/berith-chain/crypto/sha3"
+	"github.com/BerithFoundation/berith-chain/rlp"
+)
+
+const (
+	VanityLength = 32 // Fixed number of extra-data prefix bytes reserved for signer vanity
+	SealLength   = 65 // Fixed number of extra-data suffix bytes reserved for signer seal
+)
+
+var (
+	// ErrMissingVanity is returned if a block's extra-data section is shorter than
+	// 32 bytes, which is required to store the signer vanity.
+	ErrMissingVanity = errors.New("extra-data 32 byte vanity prefix missing")
+
+	// ErrMissingSignature is returned if a block's extra-data section doesn't seem
+	// to contain a 65 byte secp256k1 signature.
+	ErrMissingSignature = errors.New("extra-data 65 byte signature suffix missing")
+
+	// ErrExtraSigners is returned if non-checkpoint block contain signer data in
+	// their extra-data fields, or if a signer list is expected but empty.
+	ErrExtraSigners = errors.New("non-checkpoint block contains extra signer list")
+
+	// ErrInvalidSigners is returned if a checkpoint block contains an invalid list
+	// of signers (i.e. non divisible by 20 bytes).
+	ErrInvalidSigners = errors.New("invalid signer list on checkpoint block")
+)
+
+// Fields is the extra-data section of a header split into its parts.
+type Fields struct {
+	Vanity  hexutil.Bytes    `json:"vanity"`
+	Signers []common.Address `json:"signers"` // Signers listed by checkpoint blocks, empty otherwise
+	Seal    hexutil.Bytes    `json:"seal"`
+	Author  *common.Address  `json:"author"` // Signer recovered from the seal, nil if unsealed
+}
+
+// ParseSigners decodes the signer list of a checkpoint extra-data, which consists
+// of the vanity, the addresses of the signers and the seal. The list must hold at
+// least one signer and no truncated entry.
+func ParseSigners(extra []byte) ([]common.Address, error) {
+	if len(extra) < VanityLength {
+		return nil, ErrMissingVanity
+	}
+	if len(extra) < VanityLength+SealLength {
+		return nil, ErrMissingSignature
+	}
+	list := extra[VanityLength : len(extra)-SealLength]
+	if len(list)%common.AddressLength != 0 {
+		return nil, ErrInvalidSigners
+	}
+	if len(list) == 0 {
+		return nil, ErrExtraSigners
+	}
+	signers := make([]common.Address, len(list)/common.AddressLength)
+	for i := range signers {
+		copy(signers[i][:], list[i*common.AddressLength:])
+	}
+	return signers, nil
+}
+
+// SealHash returns the hash which is used as input for the proof-of-authority
+// signing. It is the hash of the entire header apart from the 65 byte signature
+// contained at the end of the extra data. It returns ErrMissingSignature if the
+// extra data is shorter than 65 bytes.
+func SealHash(header *types.Header) (hash common.Hash, err error) {
+	if len(header.Extra) < SealLength {
+		return common.Hash{}, ErrMissingSignature
+	}
+	hasher := sha3.NewKeccak256()
+
+	err = rlp.Encode(hasher, []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra[:len(header.Extra)-SealLength],
+		header.MixDigest,
+		header.Nonce,
+	})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	hasher.Sum(hash[:0])
+	return hash, nil
+}
+
+// Decode splits the extra-data of the header into the vanity prefix, the signer
+// list and the seal suffix, and recovers the signer of the header from the seal.
+func Decode(header *types.Header) (*Fields, error) {
+	if len(header.Extra) < VanityLength {
+		return nil, ErrMissingVanity
+	}
+	if len(header.Extra) < VanityLength+SealLength {
+		return nil, ErrMissingSignature
+	}
+	fields := &Fields{
+		Vanity:  common.CopyBytes(header.Extra[:VanityLength]),
+		Signers: []common.Address{},
+		Seal:    common.CopyBytes(header.Extra[len(header.Extra)-SealLength:]),
+	}
+	if len(header.Extra) > VanityLength+SealLength {
+		signers, err := ParseSigners(header.Extra)
+		if err != nil {
+			return nil, err
+		}
+		fields.Signers = signers
+	}
+	if hash, err := SealHash(header); err == nil {
+		if pubkey, err := crypto.Ecrecover(hash.Bytes(), fields.Seal); err == nil {
+			var author common.Address
+			copy(author[:], crypto.Keccak256(pubkey[1:])[12:])
+			fields.Author = &author
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,82 @@
+package extradata
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/crypto"
+)
+
+// Tests that signer lists without any signer, with a truncated entry or without
+// room for the vanity and the seal are rejected.
+func TestParseSignersInvalid(t *testing.T) {
+	truncated := append(make([]byte, VanityLength), common.Address{1}.Bytes()...)
+	truncated = append(truncated, make([]byte, common.AddressLength-1+SealLength)...)
+
+	tests := []struct {
+		extra []byte
+		err   error
+	}{
+		{nil, ErrMissingVanity},
+		{make([]byte, VanityLength), ErrMissingSignature},
+		{make([]byte, VanityLength+SealLength), ErrExtraSigners},
+		{truncated, ErrInvalidSigners},
+	}
+	for i, tt := range tests {
+		if _, err := ParseSigners(tt.extra); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that the extra-data of a sealed checkpoint is split into its parts, with
+// the signer recovered from the seal, and that the one of a plain block lists no
+// signers.
+func TestDecode(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	author := crypto.PubkeyToAddress(key.PublicKey)
+	signers := []common.Address{author, {0x02}}
+
+	vanity := bytes.Repeat([]byte{0x01}, VanityLength)
+	extra := append(common.CopyBytes(vanity), author.Bytes()...)
+	extra = append(extra, common.Address{0x02}.Bytes()...)
+	extra = append(extra, make([]byte, SealLength)...)
+
+	header := &types.Header{Number: big.NewInt(360), Difficulty: big.NewInt(1), Time: big.NewInt(1000), Extra: extra}
+	hash, err := SealHash(header)
+	if err != nil {
+		t.Fatalf("failed to hash header: %v", err)
+	}
+	seal, _ := crypto.Sign(hash.Bytes(), key)
+	copy(header.Extra[len(header.Extra)-SealLength:], seal)
+
+	fields, err := Decode(header)
+	if err != nil {
+		t.Fatalf("failed to decode extra-data: %v", err)
+	}
+	if !bytes.Equal(fields.Vanity, vanity) || !bytes.Equal(fields.Seal, seal) {
+		t.Errorf("vanity or seal mismatch: have %x, %x", fields.Vanity, fields.Seal)
+	}
+	if !reflect.DeepEqual(fields.Signers, signers) {
+		t.Errorf("signers mismatch: have %v, want %v", fields.Signers, signers)
+	}
+	if fields.Author == nil || *fields.Author != author {
+		t.Errorf("author mismatch: have %v, want %v", fields.Author, author)
+	}
+
+	plain := &types.Header{Number: big.NewInt(7), Difficulty: big.NewInt(1), Time: big.NewInt(70), Extra: make([]byte, VanityLength+SealLength)}
+	if fields, err = Decode(plain); err != nil {
+		t.Fatalf("failed to decode plain extra-data: %v", err)
+	}
+	if len(fields.Signers) != 0 || fields.Author != nil {
+		t.Errorf("plain block: have signers %v, author %v", fields.Signers, fields.Author)
+	}
+	header.Extra = header.Extra[1:]
+	if _, err := Decode(header); err != ErrInvalidSigners {
+		t.Errorf("truncated signer list: error mismatch: have %v, want %v", err, ErrInvalidSigners)
+	}
+}
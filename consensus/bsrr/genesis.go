@@ -0,0 +1,150 @@
+package bsrr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/consensus/bsrr/extradata"
+	"github.com/BerithFoundation/berith-chain/core"
+	"github.com/BerithFoundation/berith-chain/params"
+)
+
+// errNoBSRRConfig is returned if a genesis doesn't configure the BSRR engine.
+var errNoBSRRConfig = errors.New("genesis has no bsrr configuration")
+
+const (
+	// DevnetChainID is the chain id of the development networks created by
+	// DevnetGenesis, also used as their network id.
+	DevnetChainID = 2337
+
+	// devnetFunding is the balance allocated to the signers of a development
+	// network, in multiples of the stake minimum.
+	devnetFunding = 1000000
+)
+
+// GenesisSigner is a signer listed in the extra-data of a genesis.
+type GenesisSigner struct {
+	Address   common.Address `json:"address"`
+	Balance   *big.Int       `json:"balance"`   // Balance allocated to the signer in the genesis
+	Electable bool           `json:"electable"` // Whether the balance reaches the stake minimum
+}
+
+// GenesisReport is the outcome of validating a genesis of a BSRR network.
+type GenesisReport struct {
+	Config  *params.BSRRConfig `json:"config"` // Consensus configuration with the defaults filled in
+	Signers []GenesisSigner    `json:"signers"`
+}
+
+// Warnings describes the problems of the genesis that don't prevent the network
+// from starting.
+func (r *GenesisReport) Warnings() []string {
+	var warnings []string
+	for _, signer := range r.Signers {
+		if !signer.Electable {
+			warnings = append(warnings, fmt.Sprintf("signer %s has a balance of %v below the stake minimum of %v, it won't be electable after the first epoch of %d blocks",
+				signer.Address.Hex(), signer.Balance, r.Config.StakeMinimum, r.Config.Epoch))
+		}
+	}
+	return warnings
+}
+
+// BuildGenesisExtra encodes the extra-data of the genesis of a BSRR chain, which
+// consists of the vanity padded to its reserved length, the addresses of the
+// signers sorted in ascending order and the space of the seal.
+func BuildGenesisExtra(vanity []byte, signers []common.Address) ([]byte, error) {
+	if len(vanity) > extraVanity {
+		return nil, fmt.Errorf("vanity of %d bytes exceeds the %d bytes reserved", len(vanity), extraVanity)
+	}
+	if len(signers) == 0 {
+		return nil, errors.New("genesis needs at least one signer")
+	}
+	sorted := make([]common.Address, len(signers))
+	copy(sorted, signers)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return nil, fmt.Errorf("duplicate signer %s", sorted[i].Hex())
+		}
+	}
+	extra := make([]byte, extraVanity, extraVanity+len(sorted)*common.AddressLength+extraSeal)
+	copy(extra, vanity)
+	for _, signer := range sorted {
+		extra = append(extra, signer[:]...)
+	}
+	return append(extra, make([]byte, extraSeal)...), nil
+}
+
+// DevnetGenesis creates the genesis of a development network sealed by the given
+// signers, every block period seconds and with epochs of the given length. The
+// signers are listed in ascending order and funded well above the stake minimum,
+// to remain electable once they staked.
+func DevnetGenesis(signers []common.Address, period, epoch uint64) (*core.Genesis, error) {
+	extra, err := BuildGenesisExtra(nil, signers)
+	if err != nil {
+		return nil, err
+	}
+	config := *params.TestnetChainConfig
+	config.ChainID = big.NewInt(DevnetChainID)
+	config.Bsrr = &params.BSRRConfig{
+		Period:            period,
+		Epoch:             epoch,
+		Rewards:           params.TestnetChainConfig.Bsrr.Rewards,
+		StakeMinimum:      StakeMinimum,
+		LimitStakeBalance: LimitStakeBalance,
+		SlashRound:        params.TestnetChainConfig.Bsrr.SlashRound,
+		ForkFactor:        ForkFactor,
+	}
+	alloc := make(core.GenesisAlloc)
+	for _, signer := range signers {
+		alloc[signer] = core.GenesisAccount{Balance: new(big.Int).Mul(StakeMinimum, big.NewInt(devnetFunding))}
+	}
+	return &core.Genesis{
+		Config:     &config,
+		Timestamp:  uint64(time.Now().Unix()),
+		ExtraData:  extra,
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}, nil
+}
+
+// ValidateGenesis checks that a network can be launched from the genesis,
+// decoding its signers and the balances allocated to them.
+func ValidateGenesis(genesis *core.Genesis) (*GenesisReport, error) {
+	if genesis.Config == nil || genesis.Config.Bsrr == nil {
+		return nil, errNoBSRRConfig
+	}
+	config := *genesis.Config.Bsrr
+	SanitizeConfig(&config)
+
+	signers, err := extradata.ParseSigners(genesis.ExtraData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signer list: %v", err)
+	}
+	report := &GenesisReport{Config: &config}
+	seen := make(map[common.Address]bool)
+	for _, signer := range signers {
+		if seen[signer] {
+			return nil, fmt.Errorf("invalid signer list: duplicate signer %s", signer.Hex())
+		}
+		seen[signer] = true
+
+		balance := new(big.Int)
+		if account, ok := genesis.Alloc[signer]; ok && account.Balance != nil {
+			balance.Set(account.Balance)
+		}
+		report.Signers = append(report.Signers, GenesisSigner{
+			Address:   signer,
+			Balance:   balance,
+			Electable: balance.Cmp(config.StakeMinimum) >= 0,
+		})
+	}
+	return report, nil
+}
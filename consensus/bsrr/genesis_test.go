@@ -0,0 +1,184 @@
+package bsrr
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/core"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/params"
+)
+
+// newTestGenesis creates a genesis listing the signers in its extra-data as is,
+// unlike BuildGenesisExtra, and allocating the balances to them.
+func newTestGenesis(signers []common.Address, balances ...*big.Int) *core.Genesis {
+	alloc := make(core.GenesisAlloc)
+	for i, balance := range balances {
+		alloc[signers[i]] = core.GenesisAccount{Balance: balance}
+	}
+	extra := make([]byte, extraVanity)
+	for _, signer := range signers {
+		extra = append(extra, signer[:]...)
+	}
+	return &core.Genesis{
+		Config:    &params.ChainConfig{Bsrr: &params.BSRRConfig{Period: 5, Epoch: 360}},
+		ExtraData: append(extra, make([]byte, extraSeal)...),
+		Alloc:     alloc,
+	}
+}
+
+// Tests that the signers of a correct genesis are reported electable, with the
+// defaults filled into the configuration of the genesis copy only.
+func TestValidateGenesis(t *testing.T) {
+	signers := []common.Address{{2}, {1}}
+	genesis := newTestGenesis(signers, StakeMinimum, new(big.Int).Mul(StakeMinimum, big.NewInt(2)))
+
+	report, err := ValidateGenesis(genesis)
+	if err != nil {
+		t.Fatalf("failed to validate genesis: %v", err)
+	}
+	if len(report.Signers) != len(signers) {
+		t.Fatalf("signer count mismatch: have %d, want %d", len(report.Signers), len(signers))
+	}
+	for i, signer := range report.Signers {
+		if signer.Address != signers[i] {
+			t.Errorf("signer %d mismatch: have %x, want %x", i, signer.Address, signers[i])
+		}
+		if !signer.Electable {
+			t.Errorf("signer %d not electable with balance %v", i, signer.Balance)
+		}
+	}
+	if warnings := report.Warnings(); len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	if report.Config.StakeMinimum.Cmp(StakeMinimum) != 0 || report.Config.Epoch != 360 {
+		t.Errorf("config not sanitized: %+v", report.Config)
+	}
+	if genesis.Config.Bsrr.StakeMinimum != nil {
+		t.Errorf("genesis config modified")
+	}
+}
+
+// Tests that a signer list with a truncated entry is rejected, by the genesis
+// validation and the engine alike.
+func TestValidateGenesisTruncatedSigner(t *testing.T) {
+	signers := []common.Address{{1}, {2}}
+	genesis := newTestGenesis(signers, StakeMinimum, StakeMinimum)
+
+	// Drop the last byte of the second signer
+	extra := append(make([]byte, extraVanity), signers[0][:]...)
+	extra = append(extra, signers[1][:common.AddressLength-1]...)
+	genesis.ExtraData = append(extra, make([]byte, extraSeal)...)
+	if _, err := ValidateGenesis(genesis); err == nil || !strings.Contains(err.Error(), errInvalidCheckpointSigners.Error()) {
+		t.Errorf("error mismatch: have %v, want %v", err, errInvalidCheckpointSigners)
+	}
+	// The engine must reject it the same way
+	if _, err := new(BSRR).getSignersFromExtraData(&types.Header{Extra: genesis.ExtraData}); err != errInvalidCheckpointSigners {
+		t.Errorf("engine error mismatch: have %v, want %v", err, errInvalidCheckpointSigners)
+	}
+}
+
+// Tests that signers without a balance are reported with a warning, as they
+// can't stake and won't be electable after the first epoch.
+func TestValidateGenesisZeroBalance(t *testing.T) {
+	signers := []common.Address{{1}, {2}}
+	genesis := newTestGenesis(signers, StakeMinimum)
+
+	report, err := ValidateGenesis(genesis)
+	if err != nil {
+		t.Fatalf("failed to validate genesis: %v", err)
+	}
+	if !report.Signers[0].Electable {
+		t.Errorf("funded signer not electable")
+	}
+	if signer := report.Signers[1]; signer.Electable || signer.Balance.Sign() != 0 {
+		t.Errorf("unfunded signer mismatch: electable %v, balance %v", signer.Electable, signer.Balance)
+	}
+	warnings := report.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], signers[1].Hex()) {
+		t.Errorf("warnings mismatch: %v", warnings)
+	}
+}
+
+// Tests that the genesis of a development network lists its signers in ascending
+// order, funded above the stake minimum, with the requested period and epoch.
+func TestDevnetGenesis(t *testing.T) {
+	signers := []common.Address{{3}, {1}, {2}}
+	genesis, err := DevnetGenesis(signers, 2, 30)
+	if err != nil {
+		t.Fatalf("failed to create genesis: %v", err)
+	}
+	report, err := ValidateGenesis(genesis)
+	if err != nil {
+		t.Fatalf("failed to validate genesis: %v", err)
+	}
+	if len(report.Signers) != len(signers) {
+		t.Fatalf("signer count mismatch: have %d, want %d", len(report.Signers), len(signers))
+	}
+	sorted := []common.Address{{1}, {2}, {3}}
+	for i, signer := range report.Signers {
+		if signer.Address != sorted[i] {
+			t.Errorf("signer %d mismatch: have %x, want %x", i, signer.Address, sorted[i])
+		}
+		if !signer.Electable || signer.Balance.Cmp(report.Config.StakeMinimum) <= 0 {
+			t.Errorf("signer %d not funded above the stake minimum: balance %v", i, signer.Balance)
+		}
+	}
+	if warnings := report.Warnings(); len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	if report.Config.Period != 2 || report.Config.Epoch != 30 {
+		t.Errorf("config mismatch: period %d, epoch %d", report.Config.Period, report.Config.Epoch)
+	}
+	if genesis.Config.ChainID.Int64() != DevnetChainID || params.TestnetChainConfig.ChainID.Int64() == DevnetChainID {
+		t.Errorf("chain id mismatch: have %v, want %d", genesis.Config.ChainID, DevnetChainID)
+	}
+	if _, err := DevnetGenesis([]common.Address{{1}, {1}}, 2, 30); err == nil {
+		t.Errorf("genesis with duplicate signers created")
+	}
+}
+
+// Tests that the signers packed into a genesis extra-data are recovered in
+// ascending order, after the vanity.
+func TestBuildGenesisExtra(t *testing.T) {
+	signers := []common.Address{{3}, {1}, {2}}
+	extra, err := BuildGenesisExtra([]byte("berith"), signers)
+	if err != nil {
+		t.Fatalf("failed to build extra-data: %v", err)
+	}
+	if len(extra) != extraVanity+len(signers)*common.AddressLength+extraSeal {
+		t.Fatalf("extra-data length mismatch: have %d", len(extra))
+	}
+	if !bytes.Equal(extra[:extraVanity], append([]byte("berith"), make([]byte, extraVanity-6)...)) {
+		t.Errorf("vanity mismatch: have %x", extra[:extraVanity])
+	}
+	recovered, err := new(BSRR).getSignersFromExtraData(&types.Header{Extra: extra})
+	if err != nil {
+		t.Fatalf("failed to recover signers: %v", err)
+	}
+	want := []common.Address{{1}, {2}, {3}}
+	if len(recovered) != len(want) {
+		t.Fatalf("signer count mismatch: have %d, want %d", len(recovered), len(want))
+	}
+	for i := range want {
+		if recovered[i] != want[i] {
+			t.Errorf("signer %d mismatch: have %x, want %x", i, recovered[i], want[i])
+		}
+	}
+	if signers[0] != (common.Address{3}) {
+		t.Errorf("signers of the caller reordered")
+	}
+	// Oversized vanities, empty and duplicate signer lists are rejected
+	if _, err := BuildGenesisExtra(make([]byte, extraVanity+1), signers); err == nil {
+		t.Errorf("oversized vanity accepted")
+	}
+	if _, err := BuildGenesisExtra(nil, nil); err == nil {
+		t.Errorf("empty signer list accepted")
+	}
+	if _, err := BuildGenesisExtra(nil, []common.Address{{1}, {2}, {1}}); err == nil {
+		t.Errorf("duplicate signers accepted")
+	}
+}
@@ -0,0 +1,325 @@
+package bsrr
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/BerithFoundation/berith-chain/berith/selection"
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/consensus"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/rlp"
+)
+
+// maxVoteHistoryRange is the maximum number of epochs returned by a single range
+// query of the vote history.
+const maxVoteHistoryRange = 128
+
+var (
+	voteHistoryPrefix   = []byte("bsrr-votes-")     // voteHistoryPrefix + epoch (uint64 big endian) -> RLP(EpochVotes)
+	voteHistoryRangeKey = []byte("bsrr-vote-range") // RLP(voteHistoryRange) of the stored epochs
+
+	// errUnknownEpoch is returned if the vote results of an epoch aren't stored,
+	// as they were pruned, never recorded or the vote history isn't enabled.
+	errUnknownEpoch = errors.New("vote results of epoch not stored")
+)
+
+// EpochVote is the outcome of the election of an epoch for a single staker. It
+// is stored as RLP and encoded in the canonical JSON form of selection.Vote.
+type EpochVote struct {
+	Address common.Address
+	Rank    uint64
+	Score   *big.Int
+}
+
+// MarshalJSON encodes the vote in the canonical JSON form of selection.Vote.
+func (v EpochVote) MarshalJSON() ([]byte, error) {
+	return json.Marshal(selection.Vote{Address: v.Address, Rank: int(v.Rank), Score: v.Score})
+}
+
+// UnmarshalJSON decodes the vote from the canonical JSON form of selection.Vote.
+func (v *EpochVote) UnmarshalJSON(input []byte) error {
+	var vote selection.Vote
+	if err := json.Unmarshal(input, &vote); err != nil {
+		return err
+	}
+	v.Address, v.Rank, v.Score = vote.Address, uint64(vote.Rank), vote.Score
+	return nil
+}
+
+// EpochVotes are the results of the election held for the boundary block of an
+// epoch, as stored in the vote history.
+type EpochVotes struct {
+	Epoch        hexutil.Uint64 `json:"epoch"`
+	Number       hexutil.Uint64 `json:"number"`       // Boundary block of the epoch
+	TargetNumber hexutil.Uint64 `json:"targetNumber"` // Block the stakers and their points are taken from
+	TargetHash   common.Hash    `json:"targetHash"`
+	Votes        []EpochVote    `json:"votes"` // Ordered by rank
+}
+
+// voteHistoryRange is the first and last epoch in the vote history.
+type voteHistoryRange struct {
+	First, Last uint64
+}
+
+// voteHistory stores the election results of the epoch boundary blocks, so that
+// they can be answered after the states they were drawn from are pruned.
+type voteHistory struct {
+	db        berithdb.Database
+	retention uint64     // Number of epochs kept, 0 to keep all
+	lock      sync.Mutex // Serialises the writes updating the stored range
+}
+
+// voteHistoryKey = voteHistoryPrefix + epoch (uint64 big endian)
+func voteHistoryKey(epoch uint64) []byte {
+	key := make([]byte, len(voteHistoryPrefix)+8)
+	copy(key, voteHistoryPrefix)
+	binary.BigEndian.PutUint64(key[len(voteHistoryPrefix):], epoch)
+	return key
+}
+
+// bounds returns the range of the stored epochs, false if none is stored.
+func (h *voteHistory) bounds() (voteHistoryRange, bool) {
+	var bounds voteHistoryRange
+	blob, err := h.db.Get(voteHistoryRangeKey)
+	if err != nil || rlp.DecodeBytes(blob, &bounds) != nil {
+		return voteHistoryRange{}, false
+	}
+	return bounds, true
+}
+
+// write stores the vote results of an epoch, dropping the epochs which fell out
+// of the retention.
+func (h *voteHistory) write(votes *EpochVotes) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	blob, err := rlp.EncodeToBytes(votes)
+	if err != nil {
+		return err
+	}
+	epoch := uint64(votes.Epoch)
+	batch := h.db.NewBatch()
+	if err := batch.Put(voteHistoryKey(epoch), blob); err != nil {
+		return err
+	}
+	bounds, ok := h.bounds()
+	if !ok {
+		bounds = voteHistoryRange{First: epoch, Last: epoch}
+	}
+	if epoch < bounds.First {
+		bounds.First = epoch
+	}
+	if epoch > bounds.Last {
+		bounds.Last = epoch
+	}
+	if h.retention > 0 {
+		for ; bounds.Last-bounds.First >= h.retention; bounds.First++ {
+			if err := batch.Delete(voteHistoryKey(bounds.First)); err != nil {
+				return err
+			}
+		}
+	}
+	blob, err = rlp.EncodeToBytes(bounds)
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(voteHistoryRangeKey, blob); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// read retrieves the vote results of an epoch.
+func (h *voteHistory) read(epoch uint64) (*EpochVotes, error) {
+	blob, err := h.db.Get(voteHistoryKey(epoch))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %d", errUnknownEpoch, epoch)
+	}
+	votes := new(EpochVotes)
+	if err := rlp.DecodeBytes(blob, votes); err != nil {
+		return nil, fmt.Errorf("corrupted vote results of epoch %d: %v", epoch, err)
+	}
+	return votes, nil
+}
+
+// readRange retrieves the vote results of the stored epochs between from and to
+// inclusive, at most maxVoteHistoryRange of them.
+func (h *voteHistory) readRange(from, to uint64) ([]*EpochVotes, error) {
+	if from > to {
+		return nil, fmt.Errorf("invalid epoch range %d-%d", from, to)
+	}
+	if to-from >= maxVoteHistoryRange {
+		return nil, fmt.Errorf("epoch range %d-%d exceeds the limit of %d epochs", from, to, maxVoteHistoryRange)
+	}
+	results := make([]*EpochVotes, 0)
+	bounds, ok := h.bounds()
+	if !ok {
+		return results, nil
+	}
+	if from < bounds.First {
+		from = bounds.First
+	}
+	if to > bounds.Last {
+		to = bounds.Last
+	}
+	for epoch := from; epoch <= to; epoch++ {
+		votes, err := h.read(epoch)
+		if err != nil {
+			continue
+		}
+		results = append(results, votes)
+	}
+	return results, nil
+}
+
+/*
+[BERITH]
+Function that stores the elections held for the canonical epoch boundary blocks
+up to the given head in the vote history, if the history is enabled. It is meant
+to be called whenever the head of the canonical chain changes: the epochs since
+the last one stored are caught up with, and the stored epochs drawn from blocks
+reorganised away are replaced. The history is a convenience of the node,
+failures are logged and don't fail the processing of the chain.
+*/
+func (c *BSRR) RecordVotes(chain consensus.ChainReader, head *types.Header) {
+	if c.history == nil {
+		return
+	}
+	last := head.Number.Uint64() / c.config.Epoch
+	first := last
+	if bounds, ok := c.history.bounds(); ok {
+		// Step back over the stored epochs no longer drawn from the canonical chain
+		for first = bounds.Last + 1; first > bounds.First; first-- {
+			votes, err := c.history.read(first - 1)
+			if err != nil {
+				break
+			}
+			if target := chain.GetHeaderByNumber(uint64(votes.TargetNumber)); target != nil && target.Hash() == votes.TargetHash {
+				break
+			}
+		}
+	}
+	if last >= maxVoteHistoryRange && first <= last-maxVoteHistoryRange {
+		first = last - maxVoteHistoryRange + 1
+	}
+	for epoch := first; epoch <= last; epoch++ {
+		header := chain.GetHeaderByNumber(epoch * c.config.Epoch)
+		if header == nil {
+			return
+		}
+		c.recordEpoch(chain, header)
+	}
+}
+
+// recordEpoch stores the election held for the given epoch boundary block in the
+// vote history.
+func (c *BSRR) recordEpoch(chain consensus.ChainReader, header *types.Header) {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return
+	}
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return
+	}
+	// The genesis signers of the first epochs aren't elected
+	target, exist := c.getStakeTargetBlock(chain, parent)
+	if !exist || target.Number.Sign() == 0 {
+		return
+	}
+	stks, err := c.peekStakers(chain, target.Number.Uint64(), target.Hash())
+	if err != nil {
+		log.Warn("Failed to record vote results", "number", number, "err", err)
+		return
+	}
+	states, err := chain.StateAt(target.Root)
+	if err != nil {
+		log.Warn("Failed to record vote results", "number", number, "err", err)
+		return
+	}
+	votes := &EpochVotes{
+		Epoch:        hexutil.Uint64(number / c.config.Epoch),
+		Number:       hexutil.Uint64(number),
+		TargetNumber: hexutil.Uint64(target.Number.Uint64()),
+		TargetHash:   target.Hash(),
+	}
+	for _, vote := range selection.SelectBlockCreator(chain.Config(), target.Number.Uint64(), target.Hash(), stks, states).Sorted() {
+		votes.Votes = append(votes.Votes, EpochVote{Address: vote.Address, Rank: uint64(vote.Rank), Score: vote.Score})
+	}
+
+	if err := c.history.write(votes); err != nil {
+		log.Warn("Failed to record vote results", "number", number, "err", err)
+	}
+}
+
+// EnableVoteHistory makes the engine store the election results of every epoch
+// boundary block of the canonical chain passed to RecordVotes, keeping those of
+// the given number of most recent epochs, or all of them if zero.
+func (c *BSRR) EnableVoteHistory(retention uint64) {
+	c.history = &voteHistory{db: c.db, retention: retention}
+}
+
+// voteHistory returns the vote history of the engine, reading the vote results
+// stored in its database even if recording them isn't enabled.
+func (c *BSRR) voteHistory() *voteHistory {
+	if c.history != nil {
+		return c.history
+	}
+	return &voteHistory{db: c.db}
+}
+
+// ExportVoteHistory writes the vote results stored in the database to the writer
+// as a stream of RLP encoded EpochVotes, oldest epoch first. It returns the number
+// of epochs written.
+func ExportVoteHistory(db berithdb.Database, w io.Writer) (int, error) {
+	history := &voteHistory{db: db}
+	bounds, ok := history.bounds()
+	if !ok {
+		return 0, nil
+	}
+	exported := 0
+	for epoch := bounds.First; epoch <= bounds.Last; epoch++ {
+		blob, err := db.Get(voteHistoryKey(epoch))
+		if err != nil {
+			continue
+		}
+		if _, err := w.Write(blob); err != nil {
+			return exported, err
+		}
+		exported++
+	}
+	return exported, nil
+}
+
+// ImportVoteHistory stores the vote results of a stream written by
+// ExportVoteHistory in the database, replacing those of the same epochs. It
+// returns the number of epochs imported.
+func ImportVoteHistory(db berithdb.Database, r io.Reader) (int, error) {
+	var (
+		history  = &voteHistory{db: db}
+		stream   = rlp.NewStream(r, 0)
+		imported = 0
+	)
+	for {
+		votes := new(EpochVotes)
+		if err := stream.Decode(votes); err != nil {
+			if err == io.EOF {
+				return imported, nil
+			}
+			return imported, err
+		}
+		if err := history.write(votes); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+}
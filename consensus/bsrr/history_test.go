@@ -0,0 +1,249 @@
+package bsrr
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/berith/selection"
+	"github.com/BerithFoundation/berith-chain/berith/staking"
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/core/state"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/params"
+)
+
+// newVoteHistoryChain creates a chain of the given number of blocks staked by
+// the given stakers, with their points set in the state of every block.
+func newVoteHistoryChain(points map[common.Address]int64, blocks int) (*testStakersChain, *testStakingDB) {
+	db := state.NewDatabase(berithdb.NewMemDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+	stks := staking.NewStakers()
+	for addr, point := range points {
+		statedb.SetPoint(addr, big.NewInt(point))
+		stks.Put(addr)
+	}
+	root, _ := statedb.Commit(false)
+	db.TrieDB().Commit(root, false)
+
+	chain := &testStakersChain{config: params.MainnetChainConfig, db: db}
+	stakingDB := &testStakingDB{lists: make(map[string]staking.Stakers)}
+	for i := 0; i < blocks; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Root: root, Time: big.NewInt(int64(10 * i)), Difficulty: new(big.Int)}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+		stakingDB.lists[header.Hash().Hex()] = stks
+	}
+	return chain, stakingDB
+}
+
+// Tests that the election results of the epoch boundary blocks are recorded as
+// the canonical head moves, and that they are answered from the vote history
+// after the states they were drawn from are pruned, up to the retention.
+func TestVoteHistory(t *testing.T) {
+	points := map[common.Address]int64{{1}: 100, {2}: 5000, {3}: 20000}
+	chain, stakingDB := newVoteHistoryChain(points, 10)
+
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 2}, berithdb.NewMemDatabase())
+	c.stakingDB = stakingDB
+	c.EnableVoteHistory(2)
+	api := &API{chain: chain, bsrr: c}
+
+	// Epochs 2, 3 and 4 are elected on blocks 2, 3 and 5, block 2 of epoch 1
+	// takes the genesis signers. Only the last two epochs are retained.
+	want := make(map[uint64]*EpochVotes)
+	for epoch, target := range map[uint64]int{2: 2, 3: 3, 4: 5} {
+		header := chain.headers[target]
+		states, _ := chain.StateAt(header.Root)
+		votes := &EpochVotes{Epoch: hexutil.Uint64(epoch), Number: hexutil.Uint64(epoch * 2), TargetNumber: hexutil.Uint64(uint64(target)), TargetHash: header.Hash()}
+		for addr, result := range selection.SelectBlockCreator(chain.config, uint64(target), header.Hash(), stakingDB.lists[header.Hash().Hex()], states) {
+			votes.Votes = append(votes.Votes, EpochVote{Address: addr, Rank: uint64(result.Rank), Score: result.Score})
+		}
+		sort.Slice(votes.Votes, func(i, j int) bool { return votes.Votes[i].Rank < votes.Votes[j].Rank })
+		want[epoch] = votes
+	}
+	for _, header := range chain.headers[1:] {
+		c.RecordVotes(chain, header)
+	}
+	// Prune the states, the elections can't be held anymore
+	chain.db = state.NewDatabase(berithdb.NewMemDatabase())
+	if _, rank := c.calcDifficultyAndRank(common.Address{3}, chain, 0, chain.headers[5]); rank != -1 {
+		t.Fatalf("election held on pruned state: rank %d", rank)
+	}
+	for epoch := uint64(0); epoch <= 5; epoch++ {
+		have, err := api.GetHistoricalVoteResults(hexutil.Uint64(epoch))
+		if epoch < 3 || epoch > 4 {
+			if err == nil {
+				t.Errorf("epoch %d: unexpected vote results: %+v", epoch, have)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("epoch %d: failed to get vote results: %v", epoch, err)
+		}
+		if !reflect.DeepEqual(have, want[epoch]) {
+			t.Errorf("epoch %d: vote results mismatch:\nhave %+v\nwant %+v", epoch, have, want[epoch])
+		}
+		if len(have.Votes) != len(points) || have.Votes[0].Rank != 1 {
+			t.Errorf("epoch %d: votes not ordered by rank: %+v", epoch, have.Votes)
+		}
+	}
+	// Range queries skip the epochs not stored
+	votes, err := api.GetHistoricalVoteResultsRange(0, 10)
+	if err != nil {
+		t.Fatalf("failed to get vote results range: %v", err)
+	}
+	if len(votes) != 2 || !reflect.DeepEqual(votes[0], want[3]) || !reflect.DeepEqual(votes[1], want[4]) {
+		t.Errorf("vote results range mismatch: %+v", votes)
+	}
+	if _, err := api.GetHistoricalVoteResultsRange(5, 4); err == nil {
+		t.Errorf("inverted range accepted")
+	}
+	if _, err := api.GetHistoricalVoteResultsRange(0, maxVoteHistoryRange); err == nil {
+		t.Errorf("range exceeding the limit accepted")
+	}
+}
+
+// Tests that nothing is recorded unless the vote history is enabled.
+func TestVoteHistoryDisabled(t *testing.T) {
+	chain, stakingDB := newVoteHistoryChain(map[common.Address]int64{{1}: 100}, 5)
+
+	db := berithdb.NewMemDatabase()
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 2}, db)
+	c.stakingDB = stakingDB
+	for _, header := range chain.headers[1:] {
+		c.RecordVotes(chain, header)
+	}
+	if db.Len() != 0 {
+		t.Errorf("vote history recorded while disabled: %d entries", db.Len())
+	}
+}
+
+// Tests that finalizing blocks, as done for every sealing attempt and side block,
+// leaves the vote history alone, and that the epochs drawn from blocks which
+// were reorganised away are recorded anew once the canonical head moves.
+func TestVoteHistoryReorg(t *testing.T) {
+	points := map[common.Address]int64{{1}: 100, {2}: 5000, {3}: 20000}
+	chain, stakingDB := newVoteHistoryChain(points, 10)
+
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 2}, berithdb.NewMemDatabase())
+	c.stakingDB = stakingDB
+	c.EnableVoteHistory(0)
+	api := &API{chain: chain, bsrr: c}
+
+	for _, header := range chain.headers[1:] {
+		c.RecordVotes(chain, header)
+	}
+	before, err := api.GetHistoricalVoteResults(4)
+	if err != nil {
+		t.Fatalf("failed to get vote results: %v", err)
+	}
+	// Fork off a side chain replacing the target block of epoch 4 onwards
+	side := make([]*types.Header, 0, 5)
+	for i := 5; i < 10; i++ {
+		header := types.CopyHeader(chain.headers[i])
+		header.Time = new(big.Int).Add(header.Time, big.NewInt(1))
+		if i == 5 {
+			header.ParentHash = chain.headers[4].Hash()
+		} else {
+			header.ParentHash = side[len(side)-1].Hash()
+		}
+		side = append(side, header)
+		stakingDB.lists[header.Hash().Hex()] = stakingDB.lists[chain.headers[i].Hash().Hex()]
+	}
+	// Finalizing the side blocks doesn't touch the history, even with their
+	// ancestors known to the chain
+	canonical := chain.headers
+	chain.headers = append(append([]*types.Header{}, canonical[:5]...), side...)
+	for _, header := range side {
+		statedb, _ := chain.StateAt(header.Root)
+		if _, err := c.Finalize(chain, types.CopyHeader(header), statedb, nil, nil, nil); err != nil {
+			t.Fatalf("block %d: failed to finalize: %v", header.Number, err)
+		}
+	}
+	sidechain := chain.headers
+	chain.headers = canonical
+
+	if have, _ := api.GetHistoricalVoteResults(4); !reflect.DeepEqual(have, before) {
+		t.Fatalf("vote results changed by finalizing side blocks: %+v", have)
+	}
+	// Once the side chain is canonical, its elections replace the stored ones
+	chain.headers = sidechain
+	c.RecordVotes(chain, chain.CurrentHeader())
+
+	after, err := api.GetHistoricalVoteResults(4)
+	if err != nil {
+		t.Fatalf("failed to get vote results: %v", err)
+	}
+	if after.TargetNumber != 5 || after.TargetHash != side[0].Hash() {
+		t.Errorf("reorganised epoch not recorded anew: target #%d %x, want #5 %x", after.TargetNumber, after.TargetHash, side[0].Hash())
+	}
+	if have, err := api.GetHistoricalVoteResults(3); err != nil || have.TargetHash != chain.headers[3].Hash() {
+		t.Errorf("epoch before the fork mismatch: %+v, %v", have, err)
+	}
+}
+
+// Tests that the vote history survives an export and import into another database.
+func TestVoteHistoryExport(t *testing.T) {
+	source := &voteHistory{db: berithdb.NewMemDatabase()}
+	for epoch := uint64(3); epoch <= 5; epoch++ {
+		votes := &EpochVotes{
+			Epoch:        hexutil.Uint64(epoch),
+			Number:       hexutil.Uint64(epoch * 360),
+			TargetNumber: hexutil.Uint64(epoch*360 - 361),
+			Votes:        []EpochVote{{Address: common.Address{1}, Rank: 1, Score: big.NewInt(int64(epoch))}},
+		}
+		if err := source.write(votes); err != nil {
+			t.Fatalf("epoch %d: failed to write vote results: %v", epoch, err)
+		}
+	}
+	var dump bytes.Buffer
+	if exported, err := ExportVoteHistory(source.db, &dump); err != nil || exported != 3 {
+		t.Fatalf("export mismatch: exported %d, error %v", exported, err)
+	}
+	target := &voteHistory{db: berithdb.NewMemDatabase()}
+	if imported, err := ImportVoteHistory(target.db, &dump); err != nil || imported != 3 {
+		t.Fatalf("import mismatch: imported %d, error %v", imported, err)
+	}
+	have, _ := target.readRange(0, 10)
+	want, _ := source.readRange(0, 10)
+	if len(have) != 3 || !reflect.DeepEqual(have, want) {
+		t.Errorf("imported history mismatch:\nhave %+v\nwant %+v", have, want)
+	}
+	if _, err := ImportVoteHistory(target.db, bytes.NewReader([]byte{0xc1})); err == nil {
+		t.Errorf("corrupted dump imported")
+	}
+}
+
+// Tests that the election results returned by the APIs share the canonical JSON
+// form of the votes of the selection package.
+func TestVoteResultsJSON(t *testing.T) {
+	addr := common.HexToAddress("0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359")
+	want, err := json.Marshal(selection.Vote{Address: addr, Rank: 1, Score: big.NewInt(5000000)})
+	if err != nil {
+		t.Fatalf("failed to encode vote: %v", err)
+	}
+	vote := EpochVote{Address: addr, Rank: 1, Score: big.NewInt(5000000)}
+	if blob, err := json.Marshal(vote); err != nil || !bytes.Equal(blob, want) {
+		t.Errorf("epoch vote encoding mismatch: have %s, %v, want %s", blob, err, want)
+	}
+	var decodedVote EpochVote
+	if err := json.Unmarshal(want, &decodedVote); err != nil || !reflect.DeepEqual(decodedVote, vote) {
+		t.Errorf("epoch vote decoding mismatch: have %v, %v, want %v", decodedVote, err, vote)
+	}
+	prediction := ProducerPrediction{Rank: 1, Address: addr, Score: (*hexutil.Big)(big.NewInt(5000000))}
+	if blob, err := json.Marshal(prediction); err != nil || !bytes.Equal(blob, want) {
+		t.Errorf("prediction encoding mismatch: have %s, %v, want %s", blob, err, want)
+	}
+	var decodedPrediction ProducerPrediction
+	if err := json.Unmarshal(want, &decodedPrediction); err != nil || !reflect.DeepEqual(decodedPrediction, prediction) {
+		t.Errorf("prediction decoding mismatch: have %v, %v, want %v", decodedPrediction, err, prediction)
+	}
+}
@@ -0,0 +1,49 @@
+package bsrr
+
+import (
+	"math/big"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/consensus"
+	"github.com/BerithFoundation/berith-chain/core/types"
+)
+
+// pendingSelection is the outcome of the election of a signer on the target
+// block of the block pending on top of the head.
+type pendingSelection struct {
+	head   common.Hash // Head of the chain the election was held at
+	target common.Hash // Target block the election was held on
+	signer common.Address
+	diff   *big.Int
+	rank   int
+}
+
+/*
+[BERITH]
+Function that returns the difficulty and rank of the signer for the block pending
+on top of the head, as calcDifficultyAndRank does. The selection only depends on
+the target block, so the outcome is reused by the recommits of the miner until
+the head of the chain changes instead of running the election every time.
+*/
+func (c *BSRR) pendingDifficultyAndRank(signer common.Address, chain consensus.ChainReader, target *types.Header) (*big.Int, int) {
+	var head common.Hash
+	if current := chain.CurrentHeader(); current != nil {
+		head = current.Hash()
+	}
+	hash := target.Hash()
+
+	c.selectionLock.Lock()
+	if sel := c.selection; sel != nil && sel.head == head && sel.target == hash && sel.signer == signer {
+		c.selectionLock.Unlock()
+		return new(big.Int).Set(sel.diff), sel.rank
+	}
+	c.selectionLock.Unlock()
+
+	diff, rank := c.calcDifficultyAndRank(signer, chain, 0, target)
+
+	c.selectionLock.Lock()
+	c.selection = &pendingSelection{head: head, target: hash, signer: signer, diff: new(big.Int).Set(diff), rank: rank}
+	c.selectionLock.Unlock()
+
+	return diff, rank
+}
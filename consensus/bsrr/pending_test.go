@@ -0,0 +1,109 @@
+package bsrr
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/metrics"
+	"github.com/BerithFoundation/berith-chain/params"
+)
+
+// newPendingSelectionEngine creates an engine signing with the given staker on a
+// chain of ten blocks staked by three stakers.
+func newPendingSelectionEngine(signer common.Address) (*BSRR, *testStakersChain) {
+	points := map[common.Address]int64{{1}: 100, {2}: 5000, {3}: 20000}
+	chain, stakingDB := newVoteHistoryChain(points, 10)
+
+	c := New(&params.BSRRConfig{Period: 10, Epoch: 2}, berithdb.NewMemDatabase())
+	c.stakingDB = stakingDB
+	c.signer = signer
+	return c, chain
+}
+
+// Tests that the election of the local signer for the pending block is held once
+// per head, and held again once the head changes.
+func TestPendingSelection(t *testing.T) {
+	c, chain := newPendingSelectionEngine(common.Address{3})
+
+	parent := chain.CurrentHeader()
+	target, _ := c.getStakeTargetBlock(chain, parent)
+	wantDiff, wantRank := c.calcDifficultyAndRank(c.signer, chain, 0, target)
+	if wantRank < 1 {
+		t.Fatalf("signer not elected: rank %d", wantRank)
+	}
+	defer func(counter metrics.Counter) { electionCounter = counter }(electionCounter)
+	electionCounter = metrics.NewCounterForced()
+
+	// Recommits at the same head reuse the election
+	for i := 0; i < 5; i++ {
+		if diff := c.CalcDifficulty(chain, 0, parent); diff.Cmp(wantDiff) != 0 {
+			t.Errorf("recommit %d: difficulty mismatch: have %v, want %v", i, diff, wantDiff)
+		}
+		if diff, rank := c.pendingDifficultyAndRank(c.signer, chain, target); diff.Cmp(wantDiff) != 0 || rank != wantRank {
+			t.Errorf("recommit %d: selection mismatch: have (%v, %d), want (%v, %d)", i, diff, rank, wantDiff, wantRank)
+		}
+	}
+	if count := electionCounter.Count(); count != 1 {
+		t.Errorf("elections mismatch: have %d, want 1", count)
+	}
+	// Modifying the returned difficulty doesn't corrupt the cached one
+	c.CalcDifficulty(chain, 0, parent).SetInt64(0)
+	if diff := c.CalcDifficulty(chain, 0, parent); diff.Cmp(wantDiff) != 0 {
+		t.Errorf("cached difficulty modified: have %v, want %v", diff, wantDiff)
+	}
+	// Other signers are elected on their own
+	if _, rank := c.pendingDifficultyAndRank(common.Address{1}, chain, target); rank == wantRank {
+		t.Errorf("rank of other signer reused")
+	}
+	if count := electionCounter.Count(); count != 2 {
+		t.Errorf("elections mismatch: have %d, want 2", count)
+	}
+	// A new head invalidates the election
+	chain.headers = append(chain.headers, &types.Header{Number: big.NewInt(10), ParentHash: parent.Hash(), Root: parent.Root, Difficulty: new(big.Int)})
+	c.pendingDifficultyAndRank(common.Address{1}, chain, target)
+	if count := electionCounter.Count(); count != 3 {
+		t.Errorf("elections mismatch: have %d, want 3", count)
+	}
+}
+
+// Benchmarks the elections held across the recommits of the miner at the same
+// head, each of which prepares, calculates the difficulty of and seals the block.
+func BenchmarkPendingSelection(b *testing.B) {
+	const recommits = 10
+
+	for _, bench := range []struct {
+		name   string
+		cached bool
+	}{{"recompute", false}, {"cached", true}} {
+		b.Run(bench.name, func(b *testing.B) {
+			c, chain := newPendingSelectionEngine(common.Address{3})
+			parent := chain.CurrentHeader()
+			target, _ := c.getStakeTargetBlock(chain, parent)
+
+			defer func(counter metrics.Counter) { electionCounter = counter }(electionCounter)
+			electionCounter = metrics.NewCounterForced()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Every round starts at a new head
+				c.selectionLock.Lock()
+				c.selection = nil
+				c.selectionLock.Unlock()
+
+				for j := 0; j < recommits; j++ {
+					for k := 0; k < 3; k++ { // Prepare, CalcDifficulty and Seal
+						if bench.cached {
+							c.pendingDifficultyAndRank(c.signer, chain, target)
+						} else {
+							c.calcDifficultyAndRank(c.signer, chain, 0, target)
+						}
+					}
+				}
+			}
+			b.ReportMetric(float64(electionCounter.Count())/float64(b.N*recommits), "elections/recommit")
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package bsrr
+
+import (
+	"sync/atomic"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/consensus"
+)
+
+/*
+[BERITH]
+EnableSoloSealing makes the engine seal the blocks of a development chain without
+the rank delay and without running the election again, as long as the local
+signer is the only signer of the chain. It is meant for benchmarking on a single
+node devnet, the blocks still being valid for the other nodes of the chain.
+*/
+func (c *BSRR) EnableSoloSealing() {
+	atomic.StoreUint32(&c.solo, 1)
+}
+
+// SoloSealing implements consensus.SoloSealer, returning whether the block on
+// top of the current head is sealed without delays.
+func (c *BSRR) SoloSealing(chain consensus.ChainReader) bool {
+	if atomic.LoadUint32(&c.solo) == 0 {
+		return false
+	}
+	parent := chain.CurrentHeader()
+	if parent == nil {
+		return false
+	}
+	target, exist := c.getStakeTargetBlock(chain, parent)
+	if !exist {
+		return false
+	}
+	signers, err := c.getSigners(chain, target)
+	if err != nil {
+		return false
+	}
+	c.lock.RLock()
+	signer := c.signer
+	c.lock.RUnlock()
+
+	return c.isSoloSigner(signers, signer)
+}
+
+// isSoloSigner returns whether solo sealing is enabled and the given signer is
+// the only one of the signers.
+func (c *BSRR) isSoloSigner(signers signers, signer common.Address) bool {
+	return atomic.LoadUint32(&c.solo) == 1 && len(signers) == 1 && signers[0] == signer
+}
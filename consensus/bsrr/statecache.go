@@ -0,0 +1,77 @@
+package bsrr
+
+import (
+	"sync"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/consensus"
+	"github.com/BerithFoundation/berith-chain/core/types"
+)
+
+const (
+	stateCacheLimit = 64              // Number of blocks whose state availability is cached at most
+	stateMissTTL    = 3 * time.Second // Time a missing state is cached, as it may be synced meanwhile
+)
+
+// stateEntry is the cached availability of the block and state of a header.
+type stateEntry struct {
+	available bool
+	expires   time.Time // Time a missing state is probed again, zero if available
+}
+
+// stateCache remembers which target blocks have their block and state available,
+// as probing the trie root is expensive on pruned nodes and the same target is
+// checked over and over while a height is prepared, finalized and sealed. The
+// states are only pruned, or rewound, when the head of the chain changes, which
+// is why all entries are dropped then.
+type stateCache struct {
+	head    common.Hash                // Head of the chain the entries were cached at
+	entries map[common.Hash]stateEntry // Availability of the cached blocks by hash
+	now     func() time.Time           // Clock expiring the missing states, replaceable for tests
+	lock    sync.Mutex
+}
+
+// newStateCache creates an empty cache of state availabilities.
+func newStateCache() *stateCache {
+	return &stateCache{
+		entries: make(map[common.Hash]stateEntry),
+		now:     time.Now,
+	}
+}
+
+// hasBlockAndState returns whether the block and state of the header are
+// available in the chain, probing the chain only if not cached.
+func (sc *stateCache) hasBlockAndState(chain consensus.ChainReader, header *types.Header) bool {
+	hash, number := header.Hash(), header.Number.Uint64()
+	if sc == nil {
+		return chain.HasBlockAndState(hash, number)
+	}
+	var head common.Hash
+	if current := chain.CurrentHeader(); current != nil {
+		head = current.Hash()
+	}
+	sc.lock.Lock()
+	if head != sc.head || len(sc.entries) >= stateCacheLimit {
+		sc.head, sc.entries = head, make(map[common.Hash]stateEntry)
+	}
+	entry, ok := sc.entries[hash]
+	now := sc.now()
+	sc.lock.Unlock()
+
+	if ok && (entry.available || now.Before(entry.expires)) {
+		return entry.available
+	}
+	available := chain.HasBlockAndState(hash, number)
+
+	entry = stateEntry{available: available}
+	if !available {
+		entry.expires = now.Add(stateMissTTL)
+	}
+	sc.lock.Lock()
+	if sc.head == head {
+		sc.entries[hash] = entry
+	}
+	sc.lock.Unlock()
+	return available
+}
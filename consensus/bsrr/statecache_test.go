@@ -0,0 +1,153 @@
+package bsrr
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/consensus"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/params"
+)
+
+// prunedChain is a chain keeping the states of its most recent blocks only,
+// counting the probes of the state availability.
+type prunedChain struct {
+	consensus.ChainReader
+	headers []*types.Header
+	head    int                  // Number of the current head
+	kept    int                  // Number of recent blocks whose state is kept
+	synced  map[common.Hash]bool // Blocks whose state was synced regardless of pruning
+	probes  int
+}
+
+func newPrunedChain(n, kept int) *prunedChain {
+	chain := &prunedChain{kept: kept, synced: make(map[common.Hash]bool)}
+	for i := 0; i < n; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Time: big.NewInt(int64(10 * i)), Difficulty: new(big.Int)}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	chain.head = n - 1
+	return chain
+}
+
+func (c *prunedChain) Config() *params.ChainConfig { return params.TestnetChainConfig }
+
+func (c *prunedChain) CurrentHeader() *types.Header { return c.headers[c.head] }
+
+func (c *prunedChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.headers)) {
+		return c.headers[number]
+	}
+	return nil
+}
+
+func (c *prunedChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+
+func (c *prunedChain) HasBlockAndState(hash common.Hash, number uint64) bool {
+	c.probes++
+	if c.GetHeader(hash, number) == nil {
+		return false
+	}
+	return c.synced[hash] || int(number) > c.head-c.kept
+}
+
+// Tests that missing states are cached for a short while only, so that states
+// synced meanwhile are picked up, while available states are cached until the
+// head of the chain changes.
+func TestStateCacheMissTTL(t *testing.T) {
+	var (
+		chain  = newPrunedChain(20, 4)
+		cache  = newStateCache()
+		now    = time.Unix(1000000, 0)
+		target = chain.headers[5]
+	)
+	cache.now = func() time.Time { return now }
+
+	check := func(available bool, probes int) {
+		t.Helper()
+		if have := cache.hasBlockAndState(chain, target); have != available {
+			t.Errorf("availability mismatch: have %v, want %v", have, available)
+		}
+		if chain.probes != probes {
+			t.Errorf("probes mismatch: have %d, want %d", chain.probes, probes)
+		}
+	}
+	// The missing state is probed once within the TTL
+	check(false, 1)
+	check(false, 1)
+
+	// The state synced meanwhile is only seen once the TTL expired
+	chain.synced[target.Hash()] = true
+	now = now.Add(stateMissTTL - time.Millisecond)
+	check(false, 1)
+	now = now.Add(time.Millisecond)
+	check(true, 2)
+
+	// Available states don't expire
+	now = now.Add(time.Hour)
+	check(true, 2)
+}
+
+// Tests that the cached availabilities are dropped when the head of the chain
+// changes, as the states may have been pruned meanwhile.
+func TestStateCacheHeadChange(t *testing.T) {
+	var (
+		chain  = newPrunedChain(20, 4)
+		cache  = newStateCache()
+		target = chain.headers[12]
+	)
+	chain.head = 14
+	if !cache.hasBlockAndState(chain, target) || !cache.hasBlockAndState(chain, target) || chain.probes != 1 {
+		t.Fatalf("available state not cached: %d probes", chain.probes)
+	}
+	// Advancing the head prunes the state of the target
+	chain.head = 19
+	if cache.hasBlockAndState(chain, target) || chain.probes != 2 {
+		t.Errorf("pruned state reported available: %d probes", chain.probes)
+	}
+	// Rewinding the head again makes it available without waiting for the TTL
+	chain.head = 14
+	if !cache.hasBlockAndState(chain, target) || chain.probes != 3 {
+		t.Errorf("rewound state reported missing: %d probes", chain.probes)
+	}
+}
+
+// Benchmarks the state availability probes made per sealed block on a pruned
+// node, whose target blocks all lack their states, by the target lookups of
+// Prepare, Finalize, Seal and accumulateRewards.
+func BenchmarkStakeTargetProbes(b *testing.B) {
+	for _, bench := range []struct {
+		name   string
+		cached bool
+	}{{"uncached", false}, {"cached", true}} {
+		b.Run(bench.name, func(b *testing.B) {
+			chain := newPrunedChain(64, 4)
+			c := New(&params.BSRRConfig{Period: 10, Epoch: 8}, nil)
+			if !bench.cached {
+				c.states = nil
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Seal every block on top of the first epochs in turn
+				chain.head = 16 + i%(len(chain.headers)-17)
+				parent, header := chain.headers[chain.head], chain.headers[chain.head+1]
+
+				c.getStakeTargetBlock(chain, parent) // Prepare
+				c.getStakeTargetBlock(chain, parent) // Finalize
+				c.getAncestor(chain, int64(c.config.Epoch), header)
+				c.getStakeTargetBlock(chain, parent) // Seal
+			}
+			b.ReportMetric(float64(chain.probes)/float64(b.N), "probes/block")
+		})
+	}
+}
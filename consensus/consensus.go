@@ -53,6 +53,34 @@ type ChainReader interface {
 	HasBlockAndState(hash common.Hash, number uint64) bool
 }
 
+// HeaderIterator is implemented by the chains iterating over ranges of canonical
+// headers more efficiently than by looking them up one by one.
+type HeaderIterator interface {
+	// HeadersBetween calls fn with the canonical headers between from and to
+	// inclusive in ascending order, until fn returns false.
+	HeadersBetween(from, to uint64, fn func(*types.Header) bool) error
+}
+
+// HeadersBetween calls fn with the canonical headers of the chain between from
+// and to inclusive in ascending order, until fn returns false. Chains which are
+// HeaderIterators iterate on their own, the headers of the others are looked up
+// one by one. ErrUnknownHeader is returned if a header of the range is unknown.
+func HeadersBetween(chain ChainReader, from, to uint64, fn func(*types.Header) bool) error {
+	if iterator, ok := chain.(HeaderIterator); ok {
+		return iterator.HeadersBetween(from, to, fn)
+	}
+	for number := from; number <= to; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return ErrUnknownHeader
+		}
+		if !fn(header) || number == to {
+			break
+		}
+	}
+	return nil
+}
+
 // Engine is an algorithm agnostic consensus engine.
 type Engine interface {
 	// Author retrieves the Ethereum address of the account that minted the given
@@ -120,3 +148,27 @@ type PoW interface {
 	// Hashrate returns the current mining hashrate of a PoW consensus engine.
 	Hashrate() float64
 }
+
+// Eligibility is implemented by consensus engines that restrict block production
+// to a set of eligible accounts.
+type Eligibility interface {
+	// IsStaker returns whether the given address is allowed to produce the block
+	// following the current head of the chain.
+	IsStaker(chain ChainReader, address common.Address) (bool, error)
+}
+
+// SealTracker is implemented by consensus engines able to tag the log output of
+// a sealing operation with the correlation id of the miner's sealing attempt.
+type SealTracker interface {
+	// TrackSeal associates the given correlation id with the block identified by
+	// its seal hash, ahead of the block being passed to Seal.
+	TrackSeal(sealHash common.Hash, id uint64)
+}
+
+// SoloSealer is implemented by consensus engines able to seal the blocks of a
+// development chain of a single signer without delays, for benchmarking.
+type SoloSealer interface {
+	// SoloSealing returns whether the block following the current head of the
+	// chain is sealed without delays, the local signer being the only signer.
+	SoloSealing(chain ChainReader) bool
+}
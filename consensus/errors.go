@@ -34,4 +34,8 @@ var (
 	// ErrInvalidNumber is returned if a block's number doesn't equal it's parent's
 	// plus one.
 	ErrInvalidNumber = errors.New("invalid block number")
+
+	// ErrUnknownHeader is returned when iterating over a range of canonical headers
+	// reaches a header that is unknown.
+	ErrUnknownHeader = errors.New("unknown header")
 )
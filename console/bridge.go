@@ -17,14 +17,23 @@
 package console
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/big"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/BerithFoundation/berith-chain/accounts/usbwallet"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/common/math"
 	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/params"
 	"github.com/BerithFoundation/berith-chain/rpc"
 	"github.com/robertkrimen/otto"
 )
@@ -38,6 +47,14 @@ type bridge struct {
 	client   *rpc.Client  // RPC client to execute Berith requests through
 	prompter UserPrompter // Input prompter to allow interactive user feedback
 	printer  io.Writer    // Output writer to serialize any display strings to
+
+	ctxLock sync.RWMutex    // Protects the context of the running evaluation
+	ctx     context.Context // Context of the running evaluation, cancelled on interrupt
+
+	nonceLock sync.Mutex                // Protects the nonces handed out to the scripts
+	nonces    map[common.Address]uint64 // Next nonce of the accounts sending through a nonce manager
+
+	defaults *sessionDefaults // Transaction defaults of the session, nil if none
 }
 
 // newBridge creates a new JavaScript wrapper around an RPC client.
@@ -46,6 +63,40 @@ func newBridge(client *rpc.Client, prompter UserPrompter, printer io.Writer) *br
 		client:   client,
 		prompter: prompter,
 		printer:  printer,
+		nonces:   make(map[common.Address]uint64),
+	}
+}
+
+// setContext sets the context the RPC calls of the running evaluation are
+// executed with, nil resetting it to the background context.
+func (b *bridge) setContext(ctx context.Context) {
+	b.ctxLock.Lock()
+	defer b.ctxLock.Unlock()
+
+	b.ctx = ctx
+}
+
+// context returns the context of the running evaluation.
+func (b *bridge) context() context.Context {
+	b.ctxLock.RLock()
+	defer b.ctxLock.RUnlock()
+
+	if b.ctx == nil {
+		return context.Background()
+	}
+	return b.ctx
+}
+
+// wait blocks for the given duration, throwing a JavaScript exception if the
+// running evaluation is interrupted meanwhile.
+func (b *bridge) wait(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-b.context().Done():
+		throwJSException(b.context().Err().Error())
 	}
 }
 
@@ -215,7 +266,7 @@ func (b *bridge) Sign(call otto.FunctionCall) (response otto.Value) {
 func (b *bridge) Sleep(call otto.FunctionCall) (response otto.Value) {
 	if call.Argument(0).IsNumber() {
 		sleep, _ := call.Argument(0).ToInteger()
-		time.Sleep(time.Duration(sleep) * time.Second)
+		b.wait(time.Duration(sleep) * time.Second)
 		return otto.TrueValue()
 	}
 	return throwJSException("usage: sleep(<number of seconds>)")
@@ -268,11 +319,238 @@ func (b *bridge) SleepBlocks(call otto.FunctionCall) (response otto.Value) {
 		if blockNumber() >= targetBlockNr {
 			return otto.TrueValue()
 		}
-		time.Sleep(time.Second)
+		b.wait(time.Second)
 	}
 	return otto.FalseValue()
 }
 
+// validatorUnlockDuration is the number of seconds the account is unlocked for
+// by the validator wizard to send the staking transaction.
+const validatorUnlockDuration = 60
+
+// errValidatorAborted is returned by the validator wizard if the user declined
+// one of the steps.
+var errValidatorAborted = errors.New("validator setup aborted")
+
+// SetupValidator is an interactive wizard walking first-time validators through
+// creating an account, staking, setting the berithbase and starting to mine. It
+// checks every prerequisite over RPC and only executes the missing steps, so it
+// can be re-run after being aborted. An optional account to set up may be given.
+func (b *bridge) SetupValidator(call otto.FunctionCall) (response otto.Value) {
+	var account string
+	switch {
+	case len(call.ArgumentList) == 0:
+	case len(call.ArgumentList) == 1 && call.Argument(0).IsString():
+		account, _ = call.Argument(0).ToString()
+	default:
+		throwJSException("expected 0 or 1 string argument")
+	}
+	err := b.setupValidator(account)
+	switch err {
+	case nil:
+		return otto.TrueValue()
+	case errValidatorAborted:
+		fmt.Fprintln(b.printer, "Aborted, re-run berith.setupValidator() to continue where you left off")
+		return otto.FalseValue()
+	default:
+		throwJSException(err.Error())
+	}
+	return otto.FalseValue()
+}
+
+// setupValidator runs the steps of the validator wizard, returning
+// errValidatorAborted if the user declined any of them.
+func (b *bridge) setupValidator(account string) error {
+	minimum, epoch := b.stakingConfig()
+
+	// Pick or create the account to validate with
+	address, err := b.validatorAccount(account)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(b.printer, "Validator account: %s\n", address.Hex())
+
+	// Stake the missing amount, accounting for stakes pending inclusion
+	var staked hexutil.Big
+	if err := b.client.CallContext(b.context(), &staked, "berith_getStakeBalance", address, "pending"); err != nil {
+		return err
+	}
+	if missing := new(big.Int).Sub(minimum, staked.ToInt()); missing.Sign() > 0 {
+		if err := b.stake(address, missing); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(b.printer, "Already staked %s BER\n", formatBer(staked.ToInt()))
+	}
+	// Direct the rewards to the validator account
+	var berithbase common.Address
+	if err := b.client.CallContext(b.context(), &berithbase, "berith_berithbase"); err != nil {
+		berithbase = common.Address{}
+	}
+	if berithbase != address {
+		if err := b.confirm(fmt.Sprintf("Set berithbase to %s?", address.Hex())); err != nil {
+			return err
+		}
+		if err := b.client.CallContext(b.context(), nil, "miner_setBerithbase", address); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(b.printer, "Berithbase already set")
+	}
+	// Start mining if not running yet
+	var mining bool
+	if err := b.client.CallContext(b.context(), &mining, "berith_mining"); err != nil {
+		return err
+	}
+	if !mining {
+		if err := b.confirm("Start mining?"); err != nil {
+			return err
+		}
+		if err := b.client.CallContext(b.context(), nil, "miner_start"); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(b.printer, "Miner already running")
+	}
+	// Stakes count towards elections once their block is an epoch old
+	var head hexutil.Uint64
+	if err := b.client.CallContext(b.context(), &head, "berith_blockNumber"); err != nil {
+		return err
+	}
+	eligible := uint64(head) + epoch + 1
+
+	fmt.Fprintf(b.printer, "\nValidator set up\n")
+	fmt.Fprintf(b.printer, "  Account:    %s\n", address.Hex())
+	fmt.Fprintf(b.printer, "  Stake:      %s BER (minimum %s BER)\n", formatBer(math.BigMax(staked.ToInt(), minimum)), formatBer(minimum))
+	fmt.Fprintf(b.printer, "  Elections:  from block %d (epoch %d)\n", eligible, eligible/epoch)
+	return nil
+}
+
+// stakingConfig retrieves the stake minimum and epoch length of the chain from
+// the node info, falling back to the mainnet values if unavailable.
+func (b *bridge) stakingConfig() (*big.Int, uint64) {
+	var info struct {
+		Protocols struct {
+			Berith *struct {
+				Config *params.ChainConfig `json:"config"`
+			} `json:"berith"`
+		} `json:"protocols"`
+	}
+	if err := b.client.CallContext(b.context(), &info, "admin_nodeInfo"); err == nil && info.Protocols.Berith != nil {
+		if config := info.Protocols.Berith.Config; config != nil && config.Bsrr != nil && config.Bsrr.StakeMinimum != nil && config.Bsrr.Epoch > 0 {
+			return config.Bsrr.StakeMinimum, config.Bsrr.Epoch
+		}
+	}
+	return params.MainnetChainConfig.Bsrr.StakeMinimum, params.MainnetChainConfig.Bsrr.Epoch
+}
+
+// validatorAccount returns the given account, or the berithbase, or the only
+// local account, or lets the user pick or create one.
+func (b *bridge) validatorAccount(account string) (common.Address, error) {
+	if account != "" {
+		if !common.IsHexAddress(account) {
+			return common.Address{}, fmt.Errorf("invalid account %q", account)
+		}
+		return common.HexToAddress(account), nil
+	}
+	var accounts []common.Address
+	if err := b.client.CallContext(b.context(), &accounts, "berith_accounts"); err != nil {
+		return common.Address{}, err
+	}
+	var berithbase common.Address
+	if err := b.client.CallContext(b.context(), &berithbase, "berith_berithbase"); err == nil {
+		for _, account := range accounts {
+			if account == berithbase {
+				return account, nil
+			}
+		}
+	}
+	switch len(accounts) {
+	case 0:
+		if err := b.confirm("No account found. Create a new account?"); err != nil {
+			return common.Address{}, err
+		}
+		password, err := b.prompter.PromptPassword("Passphrase: ")
+		if err != nil {
+			return common.Address{}, err
+		}
+		confirm, err := b.prompter.PromptPassword("Repeat passphrase: ")
+		if err != nil {
+			return common.Address{}, err
+		}
+		if password != confirm {
+			return common.Address{}, errors.New("passphrases don't match!")
+		}
+		var address common.Address
+		if err := b.client.CallContext(b.context(), &address, "personal_newAccount", password); err != nil {
+			return common.Address{}, err
+		}
+		return address, nil
+	case 1:
+		return accounts[0], nil
+	default:
+		for i, account := range accounts {
+			fmt.Fprintf(b.printer, "%d. %s\n", i+1, account.Hex())
+		}
+		input, err := b.prompter.PromptInput("Account to validate with: ")
+		if err != nil {
+			return common.Address{}, err
+		}
+		index, err := strconv.Atoi(strings.TrimSpace(input))
+		if err != nil || index < 1 || index > len(accounts) {
+			return common.Address{}, fmt.Errorf("invalid account choice %q", input)
+		}
+		return accounts[index-1], nil
+	}
+}
+
+// stake sends a staking transaction of the given amount from the account after
+// checking its balance covers the stake and the transaction fee.
+func (b *bridge) stake(address common.Address, amount *big.Int) error {
+	var balance, gasPrice hexutil.Big
+	if err := b.client.CallContext(b.context(), &balance, "berith_getBalance", address, "latest"); err != nil {
+		return err
+	}
+	if err := b.client.CallContext(b.context(), &gasPrice, "berith_gasPrice"); err != nil {
+		return err
+	}
+	fee := new(big.Int).Mul(gasPrice.ToInt(), new(big.Int).SetUint64(params.TxGas))
+	if required := new(big.Int).Add(amount, fee); balance.ToInt().Cmp(required) < 0 {
+		return fmt.Errorf("insufficient balance: have %s BER, need %s BER to stake, fund %s and re-run",
+			formatBer(balance.ToInt()), formatBer(required), address.Hex())
+	}
+	if err := b.confirm(fmt.Sprintf("Stake %s BER from %s?", formatBer(amount), address.Hex())); err != nil {
+		return err
+	}
+	password, err := b.prompter.PromptPassword("Passphrase: ")
+	if err != nil {
+		return err
+	}
+	if err := b.client.CallContext(b.context(), nil, "personal_unlockAccount", address, password, validatorUnlockDuration); err != nil {
+		return err
+	}
+	var hash common.Hash
+	if err := b.client.CallContext(b.context(), &hash, "berith_stake", map[string]interface{}{"from": address, "value": (*hexutil.Big)(amount)}); err != nil {
+		return err
+	}
+	fmt.Fprintf(b.printer, "Staking transaction sent: %s\n", hash.Hex())
+	return nil
+}
+
+// confirm asks the user to confirm a step, returning errValidatorAborted if not.
+func (b *bridge) confirm(prompt string) error {
+	ok, err := b.prompter.PromptConfirm(prompt)
+	if err != nil || !ok {
+		return errValidatorAborted
+	}
+	return nil
+}
+
+// formatBer formats an amount of wei in BER.
+func formatBer(wei *big.Int) string {
+	return formatUnits(wei, berDecimals)
+}
+
 type jsonrpcCall struct {
 	ID     int64
 	Method string
@@ -309,7 +587,8 @@ func (b *bridge) Send(call otto.FunctionCall) (response otto.Value) {
 		resp, _ := call.Otto.Object(`({"jsonrpc":"2.0"})`)
 		resp.Set("id", req.ID)
 		var result json.RawMessage
-		err = b.client.Call(&result, req.Method, req.Params...)
+		b.defaults.complete(req.Method, req.Params)
+		err = b.client.CallContext(b.context(), &result, req.Method, req.Params...)
 		switch err := err.(type) {
 		case nil:
 			if result == nil {
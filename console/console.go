@@ -17,24 +17,39 @@
 package console
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"berith-chain/internals/jsre"
 	"berith-chain/internals/web3ext"
 
+	"github.com/BerithFoundation/berith-chain/accounts/abi"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/common/math"
+	"github.com/BerithFoundation/berith-chain/consensus/bsrr/extradata"
+	"github.com/BerithFoundation/berith-chain/core/types"
 	"github.com/BerithFoundation/berith-chain/log"
 	"github.com/BerithFoundation/berith-chain/rpc"
 	"github.com/mattn/go-colorable"
 	"github.com/peterh/liner"
+	"github.com/prometheus/prometheus/util/flock"
 	"github.com/robertkrimen/otto"
 )
 
@@ -42,37 +57,135 @@ var (
 	passwordRegexp = regexp.MustCompile(`personal.[nus]`)
 	onlyWhitespace = regexp.MustCompile(`^\s*$`)
 	exit           = regexp.MustCompile(`^\s*exit\s*;*\s*$`)
+
+	// chainMismatch matches the rejection of a transaction signed for another
+	// chain, as reported by types.ChainIdMismatchError.
+	chainMismatch = regexp.MustCompile(`transaction was signed for chain [^,]+, this node is chain \d+`)
 )
 
 // HistoryFile is the file within the data directory to store input scrollback.
 const HistoryFile = "history"
 
+// DefaultHistorySize is the default number of entries kept in the scrollback
+// history, the limit of the terminal prompter's own history.
+const DefaultHistorySize = liner.HistoryLimit
+
 // DefaultPrompt is the default prompt line prefix to use for user input querying.
 const DefaultPrompt = "> "
 
+// exitInterruptWindow is the time within which a second interrupt of a running
+// evaluation exits the console instead of only cancelling the evaluation.
+const exitInterruptWindow = time.Second
+
+// methodNotFoundCode is the JSON-RPC error code of a call to a method the node
+// doesn't offer.
+const methodNotFoundCode = -32601
+
+// healthCheckTimeout is the time each call of a health check may take.
+const healthCheckTimeout = 5 * time.Second
+
+// healthChecks are the read-only calls run by admin.healthCheck, keyed by the
+// name under which their outcome is reported.
+var healthChecks = []struct {
+	name   string
+	method string
+}{
+	{"blockNumber", "berith_blockNumber"},
+	{"peerCount", "net_peerCount"},
+	{"syncing", "berith_syncing"},
+	{"chainId", "berith_chainId"},
+}
+
+// healthCheckResult is the outcome of a single call of a health check.
+type healthCheckResult struct {
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Duration float64         `json:"duration"` // Milliseconds the call took
+}
+
+// benchmarkMaxIterations is the maximum number of calls made by admin.benchmark.
+const benchmarkMaxIterations = 10000
+
+// benchmarkRefusedNamespaces are the RPC namespaces whose methods admin.benchmark
+// refuses to call, as they change the node or handle keys.
+var benchmarkRefusedNamespaces = map[string]bool{
+	"personal": true,
+	"admin":    true,
+	"miner":    true,
+	"debug":    true,
+}
+
+// benchmarkRefusedPrefixes are the prefixes of the method names, within any
+// namespace, sending, signing or submitting data.
+var benchmarkRefusedPrefixes = []string{"send", "sign", "resend", "submit"}
+
+// benchmarkResult is the latency of the calls made by admin.benchmark.
+type benchmarkResult struct {
+	Method     string  `json:"method"`
+	Iterations int     `json:"iterations"`
+	Min        float64 `json:"min"` // Milliseconds of the fastest call
+	Avg        float64 `json:"avg"` // Average milliseconds per call
+	Max        float64 `json:"max"` // Milliseconds of the slowest call
+	P95        float64 `json:"p95"` // Milliseconds within which 95% of the calls completed
+}
+
+const (
+	// DefaultMaxScriptSize is the default size limit of preloaded, executed and
+	// loaded JavaScript files.
+	DefaultMaxScriptSize = 4 * 1024 * 1024
+
+	// DefaultScriptTimeout is the default time a preloaded or executed JavaScript
+	// file may run for before being interrupted.
+	DefaultScriptTimeout = 30 * time.Second
+)
+
 // Config is the collection of configurations to fine tune the behavior of the
 // JavaScript console.
 type Config struct {
-	DataDir  string       // Data directory to store the console history at
-	DocRoot  string       // Filesystem path from where to load JavaScript files from
-	Client   *rpc.Client  // RPC client to execute Ethereum requests through
-	Prompt   string       // Input prompt prefix string (defaults to DefaultPrompt)
-	Prompter UserPrompter // Input prompter to allow interactive user feedback (defaults to TerminalPrompter)
-	Printer  io.Writer    // Output writer to serialize any display strings to (defaults to os.Stdout)
-	Preload  []string     // Absolute paths to JavaScript files to preload
+	DataDir      string       // Data directory to store the console history at
+	HistoryPath  string       // Path of the history file overriding DataDir/history (supports ~ expansion)
+	HistorySize  int          // Number of scrollback entries kept (defaults to DefaultHistorySize)
+	Endpoint     string       // Endpoint the client is attached to, namespacing the storage of scripts and the persisted defaults
+	DocRoot      string       // Filesystem path from where to load JavaScript files from
+	Client       *rpc.Client  // RPC client to execute Ethereum requests through
+	Prompt       string       // Input prompt prefix string (defaults to DefaultPrompt)
+	Prompter     UserPrompter // Input prompter to allow interactive user feedback (defaults to TerminalPrompter)
+	Printer      io.Writer    // Output writer to serialize any display strings to (defaults to os.Stdout)
+	Preload      []string     // Absolute paths to JavaScript files to preload
+	OutputIndent int          // Indentation of printed results as JSON (-1 = pretty print, 0 = compact)
+
+	PrecisionGuard string // Reaction to BigNumbers beyond 2^53 converted to numbers ("warn", "throw" or "off", defaults to "warn")
+
+	MaxScriptSize int64         // Size limit of preloaded, executed and loaded files (defaults to DefaultMaxScriptSize, negative = unlimited)
+	ScriptTimeout time.Duration // Runtime limit of preloaded and executed files (defaults to DefaultScriptTimeout, negative = unlimited)
+	StrictPreload bool          // Whether a failing preload file aborts the console instead of being skipped
 }
 
 // Console is a JavaScript interpreted runtime environment. It is a fully fledged
 // JavaScript console attached to a running node via an external or in-process RPC
 // client.
 type Console struct {
-	client   *rpc.Client  // RPC client to execute Ethereum requests through
-	jsre     *jsre.JSRE   // JavaScript runtime environment running the interpreter
-	prompt   string       // Input prompt prefix string
-	prompter UserPrompter // Input prompter to allow interactive user feedback
-	histPath string       // Absolute path to the console scrollback history
-	history  []string     // Scroll history maintained by the console
-	printer  io.Writer    // Output writer to serialize any display strings to
+	client   *rpc.Client      // RPC client to execute Ethereum requests through
+	jsre     *jsre.JSRE       // JavaScript runtime environment running the interpreter
+	prompt   string           // Input prompt prefix string
+	prompter UserPrompter     // Input prompter to allow interactive user feedback
+	strict   bool             // Whether a failing preload file aborts the console
+	histPath string           // Absolute path to the console scrollback history
+	histLock flock.Releaser   // Lock of the history file, nil if another console holds it
+	history  *scrollback      // Scroll history maintained by the console
+	printer  io.Writer        // Output writer to serialize any display strings to
+	indent   int              // Indentation of printed results as JSON, negative to pretty print
+	bridge   *bridge          // JavaScript <-> Go RPC bridge executing the calls of evaluations
+	store    *scriptStore     // Persistent storage of the scripts run against the endpoint
+	defaults *sessionDefaults // Block parameter and transaction defaults of the session
+
+	protected   map[string]bool // Global namespaces the statements are checked not to overwrite
+	shadowing   bool            // Whether overwriting the protected namespaces is allowed
+	interactive bool            // Whether the statements are entered by a user who may confirm them
+
+	signal     chan os.Signal     // Interrupt signals exiting the console or cancelling evaluations
+	evalLock   sync.Mutex         // Protects the cancellation of the running evaluation
+	evalCancel context.CancelFunc // Cancels the RPC calls of the running evaluation
 }
 
 // New initializes a JavaScript interpreted runtime environment and sets defaults
@@ -88,6 +201,30 @@ func New(config Config) (*Console, error) {
 	if config.Printer == nil {
 		config.Printer = colorable.NewColorableStdout()
 	}
+	switch config.PrecisionGuard {
+	case "":
+		config.PrecisionGuard = precisionWarn
+	case precisionWarn, precisionThrow, precisionOff:
+	default:
+		return nil, fmt.Errorf("invalid precision guard %q, want %s, %s or %s", config.PrecisionGuard, precisionWarn, precisionThrow, precisionOff)
+	}
+	if err := os.MkdirAll(config.DataDir, 0700); err != nil {
+		return nil, err
+	}
+	histPath, err := resolveHistoryPath(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHistoryPath(histPath); err != nil {
+		return nil, err
+	}
+	// Only the first console using the history file may overwrite it, the others
+	// merge their entries into it on exit
+	histLock, err := lockHistory(histPath)
+	if err != nil {
+		log.Debug("History file in use by another console", "path", histPath, "err", err)
+		histLock = nil
+	}
 	// Initialize the console and return
 	console := &Console{
 		client:   config.Client,
@@ -95,23 +232,49 @@ func New(config Config) (*Console, error) {
 		prompt:   config.Prompt,
 		prompter: config.Prompter,
 		printer:  config.Printer,
-		histPath: filepath.Join(config.DataDir, HistoryFile),
+		indent:   config.OutputIndent,
+		strict:   config.StrictPreload,
+		histPath: histPath,
+		histLock: histLock,
+		history:  newScrollback(config.HistorySize),
+		store:    newScriptStore(filepath.Join(config.DataDir, StoreDir), config.Endpoint),
+		defaults: newSessionDefaults(filepath.Join(config.DataDir, DefaultsDir), config.Endpoint),
+		signal:   make(chan os.Signal, 1),
 	}
-	if err := os.MkdirAll(config.DataDir, 0700); err != nil {
-		return nil, err
-	}
-	if err := console.init(config.Preload); err != nil {
+	maxSize := scriptLimit(config.MaxScriptSize, DefaultMaxScriptSize)
+	timeout := scriptLimit(int64(config.ScriptTimeout), int64(DefaultScriptTimeout))
+	console.jsre.SetScriptLimits(maxSize, time.Duration(timeout))
+	console.jsre.SetErrorHint(consensusErrorHint)
+	if err := console.init(config.Preload, config.PrecisionGuard); err != nil {
+		if histLock != nil {
+			histLock.Release()
+		}
 		return nil, err
 	}
 	return console, nil
 }
 
+// scriptLimit returns the script limit to enforce for a configured one, the
+// default if unset and zero (none) if negative.
+func scriptLimit(configured, def int64) int64 {
+	switch {
+	case configured < 0:
+		return 0
+	case configured == 0:
+		return def
+	default:
+		return configured
+	}
+}
+
 // init retrieves the available APIs from the remote RPC provider and initializes
 // the console's JavaScript namespaces based on the exposed modules.
-func (c *Console) init(preload []string) error {
+func (c *Console) init(preload []string, precision string) error {
 	fmt.Println("Console.init() 호출")
 	// Initialize the JavaScript <-> Go RPC bridge
 	bridge := newBridge(c.client, c.prompter, c.printer)
+	bridge.defaults = c.defaults
+	c.bridge = bridge
 	c.jsre.Set("jeth", struct{}{})
 
 	jethObj, _ := c.jsre.Get("jeth")
@@ -122,6 +285,27 @@ func (c *Console) init(preload []string) error {
 	consoleObj.Object().Set("log", c.consoleOutput)
 	consoleObj.Object().Set("error", c.consoleOutput)
 
+	// The console.store keeps small values of scripts across sessions
+	if c.store != nil {
+		if _, err := c.jsre.Run("console.store = {};"); err != nil {
+			return fmt.Errorf("console.store: %v", err)
+		}
+		storeObj, _ := consoleObj.Object().Get("store")
+		storeObj.Object().Set("get", c.store.Get)
+		storeObj.Object().Set("set", c.store.Set)
+		storeObj.Object().Set("delete", c.store.Delete)
+		storeObj.Object().Set("keys", c.store.Keys)
+	}
+	consoleObj.Object().Set("historyStats", c.historyStats)
+	consoleObj.Object().Set("allowShadowing", c.allowShadowing)
+	consoleObj.Object().Set("explainError", c.explainError)
+	consoleObj.Object().Set("setDefaultBlock", c.setDefaultBlock)
+	consoleObj.Object().Set("setTxDefaults", c.setTxDefaults)
+	consoleObj.Object().Set("defaults", c.showDefaults)
+	consoleObj.Object().Set("resetDefaults", c.resetDefaults)
+	consoleObj.Object().Set("persistDefaults", c.persistDefaults)
+	consoleObj.Object().Set("dashboard", c.dashboard)
+
 	// Load all the internals utility JavaScript libraries
 	if err := c.jsre.Compile("bignumber.js", jsre.BigNumber_JS); err != nil {
 		return fmt.Errorf("bignumber.js: %v", err)
@@ -135,12 +319,19 @@ func (c *Console) init(preload []string) error {
 	if _, err := c.jsre.Run("var web3 = new Web3(jeth);"); err != nil {
 		return fmt.Errorf("web3 provider: %v", err)
 	}
+	if err := c.guardPrecision(precision); err != nil {
+		return fmt.Errorf("precision guard: %v", err)
+	}
 	// Load the supported APIs into the JavaScript runtime environment
 	apis, err := c.client.SupportedModules()
 	if err != nil {
 		return fmt.Errorf("api modules: %v", err)
 	}
 	flatten := "var berith = web3.berith; var personal = web3.personal; "
+	c.protected = make(map[string]bool)
+	for _, name := range consoleNamespaces {
+		c.protected[name] = true
+	}
 	for api := range apis {
 		if api == "web3" {
 			continue // manually mapped or ignore
@@ -151,14 +342,21 @@ func (c *Console) init(preload []string) error {
 				return fmt.Errorf("%s.js: %v", api, err)
 			}
 			flatten += fmt.Sprintf("var %s = web3.%s; ", api, api)
+			c.protected[api] = true
 		} else if obj, err := c.jsre.Run("web3." + api); err == nil && obj.IsObject() {
 			// Enable web3.js built-in extension if available.
 			flatten += fmt.Sprintf("var %s = web3.%s; ", api, api)
+			c.protected[api] = true
 		}
 	}
 	if _, err = c.jsre.Run(flatten); err != nil {
 		return fmt.Errorf("namespace flattening: %v", err)
 	}
+	// Reapply the defaults of the session to the freshly loaded web3 library
+	c.jsre.Do(func(vm *otto.Otto) { err = c.defaults.apply(vm) })
+	if err != nil {
+		return fmt.Errorf("session defaults: %v", err)
+	}
 	// 빠른 테스트 초기 설정을 위한 임시 명령어
 	_, err = c.jsre.Run(`
 			var u1 = "";
@@ -208,6 +406,15 @@ func (c *Console) init(preload []string) error {
 			obj.Set("unlockAccount", bridge.UnlockAccount)
 			obj.Set("newAccount", bridge.NewAccount)
 			obj.Set("sign", bridge.Sign)
+			obj.Set("unlockAll", c.unlockAll)
+		}
+		// The validator wizard walks the user through the staking steps
+		berith, err := c.jsre.Get("berith")
+		if err != nil {
+			return err
+		}
+		if obj := berith.Object(); obj != nil {
+			obj.Set("setupValidator", bridge.SetupValidator)
 		}
 	}
 	// The admin.sleep and admin.sleepBlocks are offered by the console and not by the RPC layer.
@@ -219,6 +426,63 @@ func (c *Console) init(preload []string) error {
 		obj.Set("sleepBlocks", bridge.SleepBlocks)
 		obj.Set("sleep", bridge.Sleep)
 		obj.Set("clearHistory", c.clearHistory)
+		obj.Set("healthCheck", c.healthCheck)
+		obj.Set("benchmark", c.benchmark)
+	}
+	// The berith.decodeLogs, berith.estimateFee, berith.nonceManager, the amount conversions and the CSV exports are offered by the console and not by the RPC layer.
+	berith, err := c.jsre.Get("berith")
+	if err != nil {
+		return err
+	}
+	if obj := berith.Object(); obj != nil { // make sure the berith api is enabled over the interface
+		// Wrap berith.sendRawTransaction to point out chain id mismatches
+		if _, err = c.jsre.Run(`jeth.sendRawTransaction = berith.sendRawTransaction;`); err != nil {
+			return fmt.Errorf("berith.sendRawTransaction: %v", err)
+		}
+		obj.Set("sendRawTransaction", c.sendRawTransaction)
+
+		// Wrap berith.sendTransaction to assign the nonces of a nonce manager
+		if _, err = c.jsre.Run(`jeth.sendTransaction = berith.sendTransaction;`); err != nil {
+			return fmt.Errorf("berith.sendTransaction: %v", err)
+		}
+		obj.Set("sendTransaction", bridge.SendTransaction)
+		obj.Set("nonceManager", bridge.NonceManager)
+		obj.Set("decodeLogs", c.decodeLogs)
+		obj.Set("estimateFee", c.estimateFee)
+		obj.Set("formatBer", c.formatAmount)
+		obj.Set("parseBer", c.parseAmount)
+		obj.Set("exportRewards", bridge.ExportRewards)
+		obj.Set("exportElections", bridge.ExportElections)
+	}
+	// The bsrr.decodeExtra and bsrr.inspectBlock are offered by the console and not by the RPC layer.
+	engine, err := c.jsre.Get("bsrr")
+	if err != nil {
+		return err
+	}
+	if obj := engine.Object(); obj != nil { // make sure the bsrr api is enabled over the interface
+		// Wrap bsrr.epochInfo to make the block number optional
+		if _, err = c.jsre.Run(`
+			jeth.epochInfo = bsrr.epochInfo;
+			bsrr.epochInfo = function(number) { return jeth.epochInfo(number); };
+		`); err != nil {
+			return fmt.Errorf("bsrr.epochInfo: %v", err)
+		}
+		obj.Set("decodeExtra", c.decodeExtra)
+		obj.Set("nextProducers", c.nextProducers)
+		obj.Set("inspectBlock", c.inspectBlock)
+	}
+	debug, err := c.jsre.Get("debug")
+	if err != nil {
+		return err
+	}
+	if obj := debug.Object(); obj != nil { // make sure the debug api is enabled over the interface
+		// Wrap debug.blockTimings to make the number of blocks optional
+		if _, err = c.jsre.Run(`
+			jeth.blockTimings = debug.blockTimings;
+			debug.blockTimings = function(n) { return jeth.blockTimings(n); };
+		`); err != nil {
+			return fmt.Errorf("debug.blockTimings: %v", err)
+		}
 	}
 	// Preload any JavaScript files before starting the console
 	for _, path := range preload {
@@ -227,30 +491,749 @@ func (c *Console) init(preload []string) error {
 			if ottoErr, ok := err.(*otto.Error); ok {
 				failure = ottoErr.String()
 			}
-			return fmt.Errorf("%s: %v", path, failure)
+			if c.strict {
+				return fmt.Errorf("%s: %v", path, failure)
+			}
+			log.Warn("Skipping failed preload script", "file", path, "err", failure)
 		}
 	}
 	// Configure the console's input prompter for scrollback and tab completion
 	if c.prompter != nil {
-		if content, err := ioutil.ReadFile(c.histPath); err != nil {
+		if history, err := readHistory(c.histPath); err != nil {
 			c.prompter.SetHistory(nil)
 		} else {
-			c.history = strings.Split(string(content), "\n")
-			c.prompter.SetHistory(c.history)
+			c.history.load(history)
+			c.prompter.SetHistory(c.history.list())
 		}
 		c.prompter.SetWordCompleter(c.AutoCompleteInput)
+		if hinter, ok := c.prompter.(HintPrompter); ok {
+			hinter.SetHintCompleter(c.AutoCompleteHint)
+		}
 	}
 	return nil
 }
 
-func (c *Console) clearHistory() {
-	c.history = nil
-	c.prompter.ClearHistory()
-	if err := os.Remove(c.histPath); err != nil {
+// clearHistory deletes the history file and clears the scrollback history,
+// returning whether it succeeded. If the file can't be deleted, the history is
+// left intact so that the in-memory and the prompter's history stay in sync.
+func (c *Console) clearHistory(call otto.FunctionCall) otto.Value {
+	if err := os.Remove(c.histPath); err != nil && !os.IsNotExist(err) {
 		fmt.Fprintln(c.printer, "can't delete history file:", err)
+		return otto.FalseValue()
+	} else if err != nil {
+		fmt.Fprintln(c.printer, "history was already empty")
 	} else {
 		fmt.Fprintln(c.printer, "history file deleted")
 	}
+	c.history.clear()
+	if c.prompter != nil {
+		c.prompter.ClearHistory()
+	}
+	return otto.TrueValue()
+}
+
+// unlockEntry is an account and its password read from a password file.
+type unlockEntry struct {
+	account  string
+	password string
+}
+
+// unlockResult reports the outcome of unlocking an account of a password file.
+type unlockResult struct {
+	Account  string `json:"account"`
+	Unlocked bool   `json:"unlocked"`
+	Error    string `json:"error,omitempty"`
+}
+
+// readUnlockFile reads the accounts to unlock from a file of lines holding an
+// account and its password separated by whitespace. Empty lines and those
+// starting with # are skipped. Malformed lines are reported by their number
+// only, so that no password ends up in the output.
+func readUnlockFile(path string) ([]unlockEntry, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []unlockEntry
+	for i, line := range strings.Split(string(blob), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sep := strings.IndexAny(line, " \t")
+		if sep < 0 {
+			return nil, fmt.Errorf("%s:%d: missing password", path, i+1)
+		}
+		entries = append(entries, unlockEntry{
+			account:  line[:sep],
+			password: strings.TrimLeft(line[sep:], " \t"),
+		})
+	}
+	return entries, nil
+}
+
+// unlockAll unlocks every account of a password file for the given duration in
+// seconds, returning whether each of them was unlocked. The passwords are sent
+// to the node only, and the call itself isn't kept in the history.
+func (c *Console) unlockAll(call otto.FunctionCall) otto.Value {
+	if !call.Argument(0).IsString() {
+		throwJSException("usage: personal.unlockAll(passwordFile, [duration])")
+	}
+	var duration *uint64
+	if arg := call.Argument(1); arg.IsDefined() && !arg.IsNull() {
+		seconds, err := arg.ToInteger()
+		if !arg.IsNumber() || err != nil || seconds < 0 {
+			throwJSException("unlock duration must be a non-negative number")
+		}
+		duration = new(uint64)
+		*duration = uint64(seconds)
+	}
+	entries, err := readUnlockFile(call.Argument(0).String())
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	results := make([]unlockResult, 0, len(entries))
+	for _, entry := range entries {
+		result := unlockResult{Account: entry.account}
+		if err := c.client.CallContext(c.context(), &result.Unlocked, "personal_unlockAccount", entry.account, entry.password, duration); err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	blob, err := json.Marshal(results)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	JSON, _ := call.Otto.Object("JSON")
+	resultsVal, err := JSON.Call("parse", string(blob))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return resultsVal
+}
+
+// historyStats returns an object reporting the number of entries kept in the
+// scrollback history, their limit and the approximate memory they take.
+func (c *Console) historyStats(call otto.FunctionCall) otto.Value {
+	blob, err := json.Marshal(map[string]int{
+		"entries": c.history.len(),
+		"limit":   c.history.limit,
+		"memory":  c.history.memory(),
+	})
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	JSON, _ := call.Otto.Object("JSON")
+	stats, err := JSON.Call("parse", string(blob))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return stats
+}
+
+// allowShadowing toggles whether statements may overwrite the namespaces of the
+// console without confirmation, allowing it if called without arguments. It
+// returns whether shadowing is allowed.
+func (c *Console) allowShadowing(call otto.FunctionCall) otto.Value {
+	allow := true
+	if arg := call.Argument(0); !arg.IsUndefined() {
+		if !arg.IsBoolean() {
+			throwJSException("argument must be a boolean")
+		}
+		allow, _ = arg.ToBoolean()
+	}
+	c.shadowing = allow
+
+	result, _ := otto.ToValue(allow)
+	return result
+}
+
+// confirmShadowing warns if the statement overwrites any of the namespaces of
+// the console, which breaks the commands using them for the rest of the session.
+// In interactive mode the user has to confirm the statement, otherwise it is
+// evaluated regardless. It returns whether the statement should be evaluated.
+func (c *Console) confirmShadowing(statement string) bool {
+	if c.shadowing || len(c.protected) == 0 {
+		return true
+	}
+	names := shadowedNames(statement, c.protected)
+	if len(names) == 0 {
+		return true
+	}
+	fmt.Fprintf(c.printer, "WARNING: the statement overwrites the console namespace %s, commands using it will fail for the rest of the session!\n", strings.Join(names, ", "))
+	if !c.interactive || c.prompter == nil {
+		return true
+	}
+	if confirm, err := c.prompter.PromptConfirm("Evaluate the statement anyway?"); err != nil || !confirm {
+		fmt.Fprintln(c.printer, "Statement not evaluated, run console.allowShadowing() to skip the confirmation")
+		return false
+	}
+	return true
+}
+
+// healthCheck runs a battery of read-only calls against the node, returning an
+// object reporting the outcome and duration of each and whether all succeeded.
+func (c *Console) healthCheck(call otto.FunctionCall) otto.Value {
+	var (
+		healthy = true
+		checks  = make(map[string]*healthCheckResult)
+		start   = time.Now()
+	)
+	for _, check := range healthChecks {
+		ctx, cancel := context.WithTimeout(c.context(), healthCheckTimeout)
+		begin := time.Now()
+
+		result := new(healthCheckResult)
+		if err := c.client.CallContext(ctx, &result.Result, check.method); err != nil {
+			result.Error, healthy = err.Error(), false
+		}
+		result.Duration = float64(time.Since(begin)) / float64(time.Millisecond)
+		checks[check.name] = result
+		cancel()
+	}
+	blob, err := json.Marshal(map[string]interface{}{
+		"healthy":  healthy,
+		"checks":   checks,
+		"duration": float64(time.Since(start)) / float64(time.Millisecond),
+	})
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	JSON, _ := call.Otto.Object("JSON")
+	health, err := JSON.Call("parse", string(blob))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return health
+}
+
+// benchmark calls an RPC method the given number of times one after the other,
+// returning an object with the minimum, average, maximum and 95th percentile
+// latency of the calls in milliseconds. Methods changing the node or handling
+// keys are refused.
+func (c *Console) benchmark(call otto.FunctionCall) otto.Value {
+	if len(call.ArgumentList) != 3 || !call.Argument(0).IsString() || !call.Argument(2).IsNumber() {
+		throwJSException("usage: benchmark(<method>, <params>, <iterations>)")
+	}
+	method, _ := call.Argument(0).ToString()
+	if benchmarkRefused(method) {
+		throwJSException(fmt.Sprintf("refusing to benchmark %s, it may change the node or handle keys", method))
+	}
+	iterations, _ := call.Argument(2).ToInteger()
+	if iterations < 1 || iterations > benchmarkMaxIterations {
+		throwJSException(fmt.Sprintf("iterations must be between 1 and %d", benchmarkMaxIterations))
+	}
+	// Pass the parameters through as they are
+	var params []json.RawMessage
+	if arg := call.Argument(1); arg.IsDefined() && !arg.IsNull() {
+		JSON, _ := call.Otto.Object("JSON")
+		blob, err := JSON.Call("stringify", arg)
+		if err != nil {
+			throwJSException(err.Error())
+		}
+		if err := json.Unmarshal([]byte(blob.String()), &params); err != nil {
+			throwJSException("params must be an array")
+		}
+	}
+	args := make([]interface{}, len(params))
+	for i, param := range params {
+		args[i] = param
+	}
+	durations := make([]time.Duration, iterations)
+	for i := range durations {
+		var result json.RawMessage
+		begin := time.Now()
+		if err := c.client.CallContext(c.context(), &result, method, args...); err != nil {
+			throwJSException(fmt.Sprintf("call %d: %v", i+1, err))
+		}
+		durations[i] = time.Since(begin)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	var total time.Duration
+	for _, duration := range durations {
+		total += duration
+	}
+	millis := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	result := benchmarkResult{
+		Method:     method,
+		Iterations: len(durations),
+		Min:        millis(durations[0]),
+		Avg:        millis(total / time.Duration(len(durations))),
+		Max:        millis(durations[len(durations)-1]),
+		P95:        millis(durations[(len(durations)*95+99)/100-1]),
+	}
+	blob, err := json.Marshal(result)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	JSON, _ := call.Otto.Object("JSON")
+	stats, err := JSON.Call("parse", string(blob))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return stats
+}
+
+// benchmarkRefused reports whether admin.benchmark refuses to call the method.
+func benchmarkRefused(method string) bool {
+	parts := strings.SplitN(method, "_", 2)
+	if len(parts) != 2 || benchmarkRefusedNamespaces[parts[0]] {
+		return true
+	}
+	name := strings.ToLower(parts[1])
+	for _, prefix := range benchmarkRefusedPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// feeQuantities are the fields of a transaction object holding quantities, which
+// berith.estimateFee accepts as numbers and decimal strings too.
+var feeQuantities = []string{"gas", "gasPrice", "value"}
+
+// feeEstimate is the cost preview of a transaction returned by berith.estimateFee.
+// If the gas can't be estimated, only the error and the revert reason are set.
+type feeEstimate struct {
+	Gas      uint64 `json:"gas,omitempty"`
+	GasPrice string `json:"gasPrice,omitempty"` // Suggested gas price in wei
+	Fee      string `json:"fee,omitempty"`      // Fee in wei
+	FeeBer   string `json:"feeBer,omitempty"`   // Fee in BER
+	Error    string `json:"error,omitempty"`    // Why the gas couldn't be estimated
+	Reason   string `json:"reason,omitempty"`   // Revert reason of the transaction
+}
+
+// sendRawTransaction submits a signed transaction through the web3 method. If
+// the node rejects it for being signed for another chain, the chain ids are
+// printed before the error is thrown, so that the mismatch isn't overlooked.
+func (c *Console) sendRawTransaction(call otto.FunctionCall) otto.Value {
+	args := make([]interface{}, len(call.ArgumentList))
+	for i, arg := range call.ArgumentList {
+		args[i] = arg
+	}
+	val, err := call.Otto.Call("jeth.sendRawTransaction", nil, args...)
+	if err != nil {
+		if hint := chainMismatch.FindString(err.Error()); hint != "" {
+			fmt.Fprintf(c.printer, "\nWARNING: %s!\nSign the transaction with the chain id of this node to submit it.\n\n", hint)
+		}
+		throwJSException(err.Error())
+	}
+	return val
+}
+
+// estimateFee estimates the gas of a transaction and multiplies it with the
+// suggested gas price, previewing the fee of sending it.
+func (c *Console) estimateFee(call otto.FunctionCall) otto.Value {
+	if len(call.ArgumentList) != 1 || !call.Argument(0).IsObject() {
+		throwJSException("usage: berith.estimateFee(<txObject>)")
+	}
+	JSON, _ := call.Otto.Object("JSON")
+	blob, err := JSON.Call("stringify", call.Argument(0))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	args, err := feeCallArgs([]byte(blob.String()))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	estimate, err := c.estimateTxFee(args)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	result, err := json.Marshal(estimate)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	estimateVal, err := JSON.Call("parse", string(result))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return estimateVal
+}
+
+// estimateTxFee estimates the fee of the transaction of the given call arguments.
+// If the gas can't be estimated, the call is replayed to learn the revert reason.
+func (c *Console) estimateTxFee(args map[string]interface{}) (*feeEstimate, error) {
+	var gas hexutil.Uint64
+	if err := c.client.CallContext(c.context(), &gas, "berith_estimateGas", args); err != nil {
+		estimate := &feeEstimate{Error: err.Error()}
+
+		var output hexutil.Bytes
+		if err := c.client.CallContext(c.context(), &output, "berith_call", args, "latest"); err == nil {
+			if reason, err := abi.UnpackRevert(output); err == nil {
+				estimate.Reason = reason
+			}
+		}
+		return estimate, nil
+	}
+	var price hexutil.Big
+	if err := c.client.CallContext(c.context(), &price, "berith_gasPrice"); err != nil {
+		return nil, fmt.Errorf("failed to retrieve gas price: %v", err)
+	}
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(uint64(gas)), price.ToInt())
+	return &feeEstimate{
+		Gas:      uint64(gas),
+		GasPrice: price.ToInt().String(),
+		Fee:      fee.String(),
+		FeeBer:   formatBer(fee),
+	}, nil
+}
+
+// feeCallArgs converts a transaction object of the console into call arguments,
+// encoding its quantities in hex.
+func feeCallArgs(blob []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(blob))
+	dec.UseNumber()
+
+	var args map[string]interface{}
+	if err := dec.Decode(&args); err != nil {
+		return nil, fmt.Errorf("invalid transaction: %v", err)
+	}
+	for _, field := range feeQuantities {
+		var text string
+		switch value := args[field].(type) {
+		case nil:
+			continue
+		case json.Number:
+			text = value.String()
+		case string:
+			text = value
+		default:
+			return nil, fmt.Errorf("invalid %s: %v", field, value)
+		}
+		quantity, ok := math.ParseBig256(text)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s: %s", field, text)
+		}
+		args[field] = (*hexutil.Big)(quantity)
+	}
+	return args, nil
+}
+
+// decodedLog is a log decoded against the events of a contract ABI.
+type decodedLog struct {
+	Event   string                 `json:"event"`
+	Address common.Address         `json:"address"`
+	Args    map[string]interface{} `json:"args"`
+}
+
+// consoleLog is the subset of the fields of a receipt log needed to decode it.
+type consoleLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+// decodeLogs decodes the logs of a transaction receipt (or a plain array of logs)
+// against the events of a contract ABI, given as a JSON string or an array. Logs
+// not matching any event are returned as they are.
+func (c *Console) decodeLogs(call otto.FunctionCall) otto.Value {
+	if len(call.ArgumentList) != 2 {
+		throwJSException("expected receipt and abi arguments")
+	}
+	JSON, _ := call.Otto.Object("JSON")
+
+	// Remarshal the receipt logs and the ABI into Go values
+	logsVal := call.Argument(0)
+	if logsVal.IsObject() && logsVal.Class() != "Array" {
+		logsVal, _ = logsVal.Object().Get("logs")
+	}
+	rawLogs, err := JSON.Call("stringify", logsVal)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	var logs []json.RawMessage
+	if err := json.Unmarshal([]byte(rawLogs.String()), &logs); err != nil {
+		throwJSException(fmt.Sprintf("invalid logs: %v", err))
+	}
+	abiVal := call.Argument(1)
+	if !abiVal.IsString() {
+		if abiVal, err = JSON.Call("stringify", abiVal); err != nil {
+			throwJSException(err.Error())
+		}
+	}
+	contract, err := abi.JSON(strings.NewReader(abiVal.String()))
+	if err != nil {
+		throwJSException(fmt.Sprintf("invalid abi: %v", err))
+	}
+	// Decode the logs and hand them back to JavaScript
+	blob, err := json.Marshal(decodeLogs(logs, contract))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	decoded, err := JSON.Call("parse", string(blob))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return decoded
+}
+
+// decodeLogs decodes the given logs against the events of the ABI, leaving the
+// ones not matching any event raw.
+func decodeLogs(logs []json.RawMessage, contract abi.ABI) []interface{} {
+	results := make([]interface{}, len(logs))
+	for i, raw := range logs {
+		results[i] = raw
+
+		var entry consoleLog
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			continue
+		}
+		if decoded := decodeLog(&entry, contract); decoded != nil {
+			results[i] = decoded
+		}
+	}
+	return results
+}
+
+// decodeLog decodes a log against the events of the ABI, matching the first
+// topic against the event ids, or trying the anonymous events that fit the log
+// otherwise. Nil is returned if no event matches.
+func decodeLog(entry *consoleLog, contract abi.ABI) *decodedLog {
+	if len(entry.Topics) > 0 {
+		for _, event := range contract.Events {
+			if !event.Anonymous && event.ID == entry.Topics[0] {
+				if args, err := decodeEventArgs(event, entry.Topics[1:], entry.Data); err == nil {
+					return &decodedLog{Event: event.Name, Address: entry.Address, Args: args}
+				}
+			}
+		}
+	}
+	for _, event := range contract.Events {
+		if event.Anonymous {
+			if args, err := decodeEventArgs(event, entry.Topics, entry.Data); err == nil {
+				return &decodedLog{Event: event.Name, Address: entry.Address, Args: args}
+			}
+		}
+	}
+	return nil
+}
+
+// decodeEventArgs decodes the indexed arguments of an event from the topics and
+// the others from the data. Dynamic indexed arguments are only stored as their
+// hash in the topics, so that is what they are decoded to.
+func decodeEventArgs(event abi.Event, topics []common.Hash, data []byte) (map[string]interface{}, error) {
+	var indexed abi.Arguments
+	for _, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if len(indexed) != len(topics) {
+		return nil, errors.New("topic/field count mismatch")
+	}
+	values, err := event.Inputs.NonIndexed().UnpackValues(data)
+	if err != nil {
+		return nil, err
+	}
+	args := make(map[string]interface{})
+	for i, arg := range event.Inputs.NonIndexed() {
+		args[arg.Name] = formatABIValue(values[i])
+	}
+	for i, arg := range indexed {
+		switch arg.Type.T {
+		case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+			args[arg.Name] = topics[i]
+		default:
+			// Static values are stored in topics as in the data, as a single word
+			value, err := abi.Arguments{{Type: arg.Type}}.UnpackValues(topics[i].Bytes())
+			if err != nil {
+				return nil, err
+			}
+			args[arg.Name] = formatABIValue(value[0])
+		}
+	}
+	return args, nil
+}
+
+// formatABIValue converts a decoded ABI value into a form representable in
+// JavaScript without loss: integers as decimal strings and bytes as hex.
+func formatABIValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case common.Address, common.Hash, bool, string:
+		return v
+	case []byte:
+		return hexutil.Bytes(v)
+	}
+	val := reflect.ValueOf(value)
+	switch val.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprint(value)
+	case reflect.Array, reflect.Slice:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			bytes := make([]byte, val.Len())
+			reflect.Copy(reflect.ValueOf(bytes), val)
+			return hexutil.Bytes(bytes)
+		}
+		items := make([]interface{}, val.Len())
+		for i := range items {
+			items[i] = formatABIValue(val.Index(i).Interface())
+		}
+		return items
+	}
+	return value
+}
+
+// decodeExtra fetches the header of a block, by number or tag (defaulting to the
+// latest block), and returns its extra-data split into the vanity, the signers
+// listed on checkpoints and the seal, along with the signer recovered from it.
+func (c *Console) decodeExtra(call otto.FunctionCall) otto.Value {
+	header := c.headerArgument(call, "usage: bsrr.decodeExtra(<block number>)")
+	extra, err := extradata.Decode(header)
+	if err != nil {
+		throwJSException(fmt.Sprintf("invalid extra-data of block %d: %v", header.Number, err))
+	}
+	blob, err := json.Marshal(extra)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	JSON, _ := call.Otto.Object("JSON")
+	decoded, err := JSON.Call("parse", string(blob))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return decoded
+}
+
+// headerArgument fetches the header of the block requested by the first argument
+// of the call, by number or tag, defaulting to the latest block. It throws the
+// usage if the argument is neither.
+func (c *Console) headerArgument(call otto.FunctionCall, usage string) *types.Header {
+	var block interface{}
+	switch arg := call.Argument(0); {
+	case arg.IsUndefined():
+		block = "latest"
+	case arg.IsNumber():
+		number, err := arg.ToInteger()
+		if err != nil || number < 0 {
+			throwJSException(fmt.Sprintf("invalid block number %v", arg))
+		}
+		block = hexutil.Uint64(number)
+	case arg.IsString():
+		block = arg.String()
+	default:
+		throwJSException(usage)
+	}
+	var header *types.Header
+	if err := c.client.CallContext(c.context(), &header, "berith_getBlockByNumber", block, false); err != nil {
+		throwJSException(err.Error())
+	}
+	if header == nil {
+		throwJSException(fmt.Sprintf("block %v not found", block))
+	}
+	return header
+}
+
+// networkParams is the part of the bsrr_networkParams result used by the console.
+type networkParams struct {
+	Epoch uint64 `json:"epoch"` // Blocks between staking list snapshots
+}
+
+// inspectBlock prints the header of a block, by number or tag (defaulting to the
+// latest block), along with the fields the bsrr engine encodes in it: the author
+// recovered from the seal, the rank of the author stored in the nonce and whether
+// the block is the checkpoint of an epoch. Nodes not reporting the epoch length
+// have the checkpoints told by the signers listed in the extra-data.
+func (c *Console) inspectBlock(call otto.FunctionCall) otto.Value {
+	header := c.headerArgument(call, "usage: bsrr.inspectBlock(<block number>)")
+	extra, err := extradata.Decode(header)
+	if err != nil {
+		throwJSException(fmt.Sprintf("invalid extra-data of block %d: %v", header.Number, err))
+	}
+	var network *networkParams
+	if err := c.client.CallContext(c.context(), &network, "bsrr_networkParams"); err != nil {
+		if rpcErr, ok := err.(rpc.Error); !ok || rpcErr.ErrorCode() != methodNotFoundCode {
+			throwJSException(err.Error())
+		}
+	}
+	epoch := uint64(0)
+	if network != nil {
+		epoch = network.Epoch
+	}
+	printBlockInspection(c.printer, header, extra, epoch)
+	return otto.UndefinedValue()
+}
+
+// printBlockInspection writes the header along with its decoded consensus fields,
+// the checkpoint told by the epoch length, or by the listed signers if it is 0.
+func printBlockInspection(w io.Writer, header *types.Header, extra *extradata.Fields, epoch uint64) {
+	number := header.Number.Uint64()
+
+	fmt.Fprintf(w, "Block:       %d (%s)\n", number, header.Hash().Hex())
+	fmt.Fprintf(w, "Parent:      %s\n", header.ParentHash.Hex())
+	fmt.Fprintf(w, "Time:        %v (%s)\n", header.Time, time.Unix(header.Time.Int64(), 0).UTC().Format(time.RFC3339))
+	if extra.Author != nil {
+		fmt.Fprintf(w, "Author:      %s\n", extra.Author.Hex())
+	} else {
+		fmt.Fprintf(w, "Author:      unknown (unsealed)\n")
+	}
+	fmt.Fprintf(w, "Rank:        %d\n", header.Nonce.Uint64())
+	fmt.Fprintf(w, "Difficulty:  %v\n", header.Difficulty)
+	fmt.Fprintf(w, "Coinbase:    %s\n", header.Coinbase.Hex())
+	fmt.Fprintf(w, "Gas:         %d / %d\n", header.GasUsed, header.GasLimit)
+
+	if epoch > 0 {
+		fmt.Fprintf(w, "Checkpoint:  %v (epoch %d, %d blocks each)\n", number%epoch == 0, number/epoch, epoch)
+	} else {
+		fmt.Fprintf(w, "Checkpoint:  %v (epoch length unknown)\n", len(extra.Signers) > 0)
+	}
+	if len(extra.Signers) > 0 {
+		fmt.Fprintf(w, "Signers:     %d listed\n", len(extra.Signers))
+		for _, signer := range extra.Signers {
+			fmt.Fprintf(w, "             %s\n", signer.Hex())
+		}
+	}
+}
+
+// producerPrediction is a signer predicted by bsrr_predictProducers to produce
+// the next block.
+type producerPrediction struct {
+	Rank    int            `json:"rank"`
+	Address common.Address `json:"address"`
+	Score   *hexutil.Big   `json:"score"` // Difficulty the signer would seal the block with
+}
+
+// nextProducers prints the signers predicted to produce the next block as a
+// table in ascending order of rank, with the scores of their election. Nodes
+// not predicting the producers are reported instead of failing.
+func (c *Console) nextProducers(call otto.FunctionCall) otto.Value {
+	count := int64(0)
+	if arg := call.Argument(0); !arg.IsUndefined() {
+		n, err := arg.ToInteger()
+		if !arg.IsNumber() || err != nil || n < 1 {
+			throwJSException("usage: bsrr.nextProducers(<count>)")
+		}
+		count = n
+	}
+	var producers []producerPrediction
+	if err := c.client.CallContext(c.context(), &producers, "bsrr_predictProducers", count); err != nil {
+		if rpcErr, ok := err.(rpc.Error); ok && rpcErr.ErrorCode() == methodNotFoundCode {
+			fmt.Fprintln(c.printer, "The node doesn't predict the producers of the next block (bsrr_predictProducers is not available)")
+			return otto.UndefinedValue()
+		}
+		throwJSException(err.Error())
+	}
+	printProducers(c.printer, producers)
+	return otto.UndefinedValue()
+}
+
+// printProducers writes the predicted producers as a table ranked in ascending
+// order.
+func printProducers(w io.Writer, producers []producerPrediction) {
+	if len(producers) == 0 {
+		fmt.Fprintln(w, "No producers elected for the next block")
+		return
+	}
+	sort.SliceStable(producers, func(i, j int) bool { return producers[i].Rank < producers[j].Rank })
+
+	fmt.Fprintf(w, "%-6s %-44s %s\n", "RANK", "ADDRESS", "SCORE")
+	for _, producer := range producers {
+		fmt.Fprintf(w, "%-6d %-44s %v\n", producer.Rank, producer.Address.Hex(), producer.Score.ToInt())
+	}
 }
 
 // consoleOutput is an override for the console.log and console.error methods to
@@ -291,6 +1274,14 @@ func (c *Console) AutoCompleteInput(line string, pos int) (string, []string, str
 	return line[:start], c.jsre.CompleteKeywords(line[start:pos]), line[pos:]
 }
 
+// AutoCompleteHint is a pre-assembled hint completer to be used by the user
+// input prompter to show the parameters of a method when the cursor is right
+// after its opening parenthesis, e.g. "getBlock(blockHashOrNumber, returnFullTxs)"
+// for berith.getBlock(<tab>. Unknown methods have no hint.
+func (c *Console) AutoCompleteHint(line string, pos int) string {
+	return signatureHint(signatures, line, pos)
+}
+
 // Welcome show summary of current Geth instance and some metadata about the
 // console's available modules.
 func (c *Console) Welcome() {
@@ -302,6 +1293,14 @@ func (c *Console) Welcome() {
 		console.log("at block: " + berith.blockNumber + " (" + new Date(1000 * berith.getBlock(berith.blockNumber).timestamp) + ")");
 		console.log(" datadir: " + admin.datadir);
 	`)
+	// Print the epoch of the current block if the bsrr api is enabled
+	c.jsre.Run(`
+		if (typeof bsrr !== "undefined") {
+			(function(info) {
+				console.log("   epoch: " + info.currentEpochNumber + " (next checkpoint at block " + info.nextCheckpointBlock + ", stake target block " + info.stakeTargetBlock.number + ")");
+			})(bsrr.epochInfo());
+		}
+	`)
 	// List all the supported modules for the user to call
 	if apis, err := c.client.SupportedModules(); err == nil {
 		modules := make([]string, 0, len(apis))
@@ -316,15 +1315,92 @@ func (c *Console) Welcome() {
 
 // Evaluate executes code and pretty prints the result to the specified output
 // stream.
+//
+// The RPC calls made by the statement are cancelled if the evaluation is
+// interrupted meanwhile. Statements overwriting the namespaces of the console
+// are warned about, and in interactive mode only evaluated once confirmed.
 func (c *Console) Evaluate(statement string) error {
+	if !c.confirmShadowing(statement) {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.evalLock.Lock()
+	c.evalCancel = cancel
+	c.evalLock.Unlock()
+	if c.bridge != nil {
+		c.bridge.setContext(ctx)
+	}
+	defer func() {
+		if c.bridge != nil {
+			c.bridge.setContext(nil)
+		}
+		c.evalLock.Lock()
+		c.evalCancel = nil
+		c.evalLock.Unlock()
+		cancel()
+	}()
 	defer func() {
 		if r := recover(); r != nil {
 			fmt.Fprintf(c.printer, "[native] error: %v\n", r)
 		}
 	}()
+	if c.indent >= 0 {
+		return c.jsre.EvaluateJSON(statement, c.printer, c.indent)
+	}
 	return c.jsre.Evaluate(statement, c.printer)
 }
 
+// context returns the context of the running evaluation.
+func (c *Console) context() context.Context {
+	if c.bridge == nil {
+		return context.Background()
+	}
+	return c.bridge.context()
+}
+
+// interrupt cancels the RPC calls of the running evaluation, if any.
+func (c *Console) interrupt() {
+	c.evalLock.Lock()
+	defer c.evalLock.Unlock()
+
+	if c.evalCancel != nil {
+		c.evalCancel()
+	}
+}
+
+// evaluateInterruptible evaluates the statement, cancelling its RPC calls on an
+// interrupt. It returns false if the console should exit, which is the case if
+// a second interrupt arrives within exitInterruptWindow of the first, or on any
+// other signal.
+func (c *Console) evaluateInterruptible(statement string) bool {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Evaluate(statement)
+	}()
+	var interrupted time.Time
+	for {
+		select {
+		case <-done:
+			return true
+
+		case sig := <-c.signal:
+			if sig != os.Interrupt {
+				fmt.Fprintln(c.printer, "caught terminate, exiting")
+				c.interrupt()
+				return false
+			}
+			if !interrupted.IsZero() && time.Since(interrupted) < exitInterruptWindow {
+				fmt.Fprintln(c.printer, "caught interrupt, exiting")
+				return false
+			}
+			interrupted = time.Now()
+			fmt.Fprintln(c.printer, "interrupted, press Ctrl-C again to exit")
+			c.interrupt()
+		}
+	}
+}
+
 // Interactive starts an interactive user session, where input is propted from
 // the configured user prompter.
 func (c *Console) Interactive() {
@@ -334,6 +1410,10 @@ func (c *Console) Interactive() {
 		input     = ""                // Current user input
 		scheduler = make(chan string) // Channel to send the next prompt on and receive the input
 	)
+	c.interactive = true
+	if c.histLock == nil {
+		fmt.Fprintf(c.printer, "History file %s is in use by another console, keeping the history of this session in memory\n", c.histPath)
+	}
 	// Start a goroutine to listen for prompt requests and send back inputs
 	go func() {
 		for {
@@ -354,16 +1434,17 @@ func (c *Console) Interactive() {
 			scheduler <- line
 		}
 	}()
-	// Monitor Ctrl-C too in case the input is empty and we need to bail
-	abort := make(chan os.Signal, 1)
-	signal.Notify(abort, syscall.SIGINT, syscall.SIGTERM)
+	// Monitor Ctrl-C too in case the input is empty and we need to bail, or to
+	// interrupt running evaluations
+	signal.Notify(c.signal, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(c.signal)
 
 	// Start sending prompts to the user and reading back inputs
 	for {
 		// Send the next prompt, triggering an input read and process the result
 		scheduler <- prompt
 		select {
-		case <-abort:
+		case <-c.signal:
 			// User forcefully quite the console
 			fmt.Fprintln(c.printer, "caught interrupt, exiting")
 			return
@@ -388,14 +1469,21 @@ func (c *Console) Interactive() {
 			// If all the needed lines are present, save the command and run
 			if indents <= 0 {
 				if len(input) > 0 && input[0] != ' ' && !passwordRegexp.MatchString(input) {
-					if command := strings.TrimSpace(input); len(c.history) == 0 || command != c.history[len(c.history)-1] {
-						c.history = append(c.history, command)
-						if c.prompter != nil {
+					command := strings.TrimSpace(input)
+					if added, compacted := c.history.add(command); added && c.prompter != nil {
+						// Rewrite the prompter's history whenever the console's is compacted,
+						// so that it is trimmed to the same entries
+						if compacted {
+							c.prompter.ClearHistory()
+							c.prompter.SetHistory(c.history.list())
+						} else {
 							c.prompter.AppendHistory(command)
 						}
 					}
 				}
-				c.Evaluate(input)
+				if !c.evaluateInterruptible(input) {
+					return
+				}
 				input = ""
 			}
 		}
@@ -452,12 +1540,33 @@ func (c *Console) Execute(path string) error {
 
 // Stop cleans up the console and terminates the runtime environment.
 func (c *Console) Stop(graceful bool) error {
-	if err := ioutil.WriteFile(c.histPath, []byte(strings.Join(c.history, "\n")), 0600); err != nil {
-		return err
-	}
-	if err := os.Chmod(c.histPath, 0600); err != nil { // Force 0600, even if it was different previously
+	if err := c.saveHistory(); err != nil {
 		return err
 	}
 	c.jsre.Stop(graceful)
 	return nil
 }
+
+// saveHistory persists the scrollback history. The console holding the lock of
+// the history file overwrites it, the others append the new entries of their
+// session if the lock was released meanwhile and drop them otherwise.
+func (c *Console) saveHistory() error {
+	if c.histLock != nil {
+		defer func() {
+			c.histLock.Release()
+			c.histLock = nil
+		}()
+		return writeHistory(c.histPath, c.history.list())
+	}
+	if len(c.history.session()) == 0 {
+		return nil
+	}
+	lock, err := lockHistory(c.histPath)
+	if err != nil {
+		fmt.Fprintf(c.printer, "History file %s is still in use by another console, the history of this session is not saved\n", c.histPath)
+		return nil
+	}
+	defer lock.Release()
+
+	return mergeHistory(c.histPath, c.history.session())
+}
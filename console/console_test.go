@@ -18,19 +18,33 @@ package console
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/BerithFoundation/berith-chain/accounts/abi"
 	"github.com/BerithFoundation/berith-chain/berith"
 	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/consensus/bsrr"
+	"github.com/BerithFoundation/berith-chain/consensus/bsrr/extradata"
 	"github.com/BerithFoundation/berith-chain/core"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/crypto"
 	"berith-chain/internals/jsre"
 	"github.com/BerithFoundation/berith-chain/node"
+	"github.com/BerithFoundation/berith-chain/params"
+	"github.com/BerithFoundation/berith-chain/rlp"
+	"github.com/BerithFoundation/berith-chain/rpc"
 )
 
 const (
@@ -116,12 +130,13 @@ func newTester(t *testing.T, confOverride func(*berith.Config)) *tester {
 	printer := new(bytes.Buffer)
 
 	console, err := New(Config{
-		DataDir:  stack.DataDir(),
-		DocRoot:  "testdata",
-		Client:   client,
-		Prompter: prompter,
-		Printer:  printer,
-		Preload:  []string{"preload.js"},
+		DataDir:      stack.DataDir(),
+		DocRoot:      "testdata",
+		Client:       client,
+		Prompter:     prompter,
+		Printer:      printer,
+		Preload:      []string{"preload.js"},
+		OutputIndent: -1,
 	})
 	if err != nil {
 		t.Fatalf("failed to create JavaScript console: %v", err)
@@ -219,6 +234,88 @@ func TestInteractive(t *testing.T) {
 	}
 }
 
+// Tests that pressing tab right after the opening parenthesis of a known method
+// hints its parameters, including in nested calls.
+func TestAutoCompleteHint(t *testing.T) {
+	tester := newTester(t, nil)
+	defer tester.Close(t)
+
+	tests := []struct {
+		line string
+		pos  int
+		hint string
+	}{
+		{"berith.getBlock(", 16, "getBlock(blockHashOrNumber, returnFullTxs)"},
+		{"web3.berith.getBlock(", 21, "getBlock(blockHashOrNumber, returnFullTxs)"},
+		{"bsrr.getJoinRatio(", 18, "getJoinRatio(address)"},
+		{"bsrr.compareStakers(", 20, "compareStakers(blockNumber, blockNumber, arg3)"},
+		{"bsrr.getSigners(", 16, "getSigners()"},
+		{"berith.getBalance(berith.getBlock(", 34, "getBlock(blockHashOrNumber, returnFullTxs)"},
+		{"berith.getBalance(berith.getBlock()", 34, "getBlock(blockHashOrNumber, returnFullTxs)"},
+		{"berith.getBalance(", 18, "getBalance(address, blockNumber)"},
+		{"berith.getBlokc(", 16, ""}, // unknown method
+		{"berith.getBlock", 15, ""},  // no parenthesis
+		{"berith.getBlock(1", 17, ""},
+		{"(", 1, ""},
+	}
+	for i, tt := range tests {
+		if hint := tester.console.AutoCompleteHint(tt.line, tt.pos); hint != tt.hint {
+			t.Errorf("test %d: hint mismatch for %q: have %q, want %q", i, tt.line, hint, tt.hint)
+		}
+	}
+}
+
+// Tests that clearing the history succeeds without a prompter attached and
+// when there is no history file to delete.
+func TestClearHistory(t *testing.T) {
+	tester := newTester(t, nil)
+	defer tester.Close(t)
+
+	// Nothing was saved yet, clearing is a no-op
+	tester.console.history.add("2+2")
+	tester.console.Evaluate("admin.clearHistory()")
+	if output := tester.output.String(); !strings.Contains(output, "history was already empty") || !strings.Contains(output, "true") {
+		t.Fatalf("missing file not reported: have %s", output)
+	}
+	if tester.console.history.len() != 0 {
+		t.Errorf("history not cleared: %v", tester.console.history.list())
+	}
+	// Delete a saved history without a prompter
+	if err := ioutil.WriteFile(tester.console.histPath, []byte("2+2"), 0600); err != nil {
+		t.Fatalf("failed to save history: %v", err)
+	}
+	tester.output.Reset()
+	tester.console.prompter = nil
+	tester.console.Evaluate("admin.clearHistory()")
+	if output := tester.output.String(); !strings.Contains(output, "history file deleted") || !strings.Contains(output, "true") {
+		t.Fatalf("deletion not reported: have %s", output)
+	}
+	if _, err := os.Stat(tester.console.histPath); !os.IsNotExist(err) {
+		t.Errorf("history file not deleted: %v", err)
+	}
+}
+
+// Tests that the history is left intact if the history file can't be deleted.
+func TestClearHistoryFailure(t *testing.T) {
+	tester := newTester(t, nil)
+	defer tester.Close(t)
+
+	// A non-empty directory in place of the history file can't be removed
+	if err := os.MkdirAll(filepath.Join(tester.console.histPath, "blocker"), 0700); err != nil {
+		t.Fatalf("failed to create blocker: %v", err)
+	}
+	defer os.RemoveAll(tester.console.histPath)
+
+	tester.console.history.add("2+2")
+	tester.console.Evaluate("admin.clearHistory()")
+	if output := tester.output.String(); !strings.Contains(output, "can't delete history file") || !strings.Contains(output, "false") {
+		t.Fatalf("failure not reported: have %s", output)
+	}
+	if tester.console.history.len() != 1 {
+		t.Errorf("history cleared despite failure: %v", tester.console.history.list())
+	}
+}
+
 // Tests that preloaded JavaScript files have been executed before user is given
 // input.
 func TestPreload(t *testing.T) {
@@ -333,3 +430,970 @@ func TestIndenting(t *testing.T) {
 		}
 	}
 }
+
+// Tests that results are printed as compact or indented JSON if requested.
+func TestOutputIndent(t *testing.T) {
+	tests := []struct {
+		indent int
+		want   string
+	}{
+		{0, "{\"a\":1,\"b\":{\"c\":[1,\"x\"]}}\n"},
+		{2, "{\n  \"a\": 1,\n  \"b\": {\n    \"c\": [\n      1,\n      \"x\"\n    ]\n  }\n}\n"},
+	}
+	for i, tt := range tests {
+		printer := new(bytes.Buffer)
+		console := &Console{jsre: jsre.New("", printer), printer: printer, indent: tt.indent}
+		console.Evaluate("({a: 1, b: {c: [1, 'x']}})")
+		console.jsre.Stop(false)
+
+		if output := printer.String(); output != tt.want {
+			t.Errorf("test %d: output mismatch: have %q, want %q", i, output, tt.want)
+		}
+	}
+}
+
+// Tests that an endless preload script is interrupted, aborting the console
+// startup only if preloading is strict.
+func TestPreloadTimeout(t *testing.T) {
+	workspace, err := ioutil.TempDir("", "console-preload-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	if err := ioutil.WriteFile(filepath.Join(workspace, "loop.js"), []byte(`while (true) {}`), 0600); err != nil {
+		t.Fatalf("failed to write preload script: %v", err)
+	}
+	client := rpc.DialInProc(rpc.NewServer())
+	defer client.Close()
+
+	for _, strict := range []bool{false, true} {
+		console, err := New(Config{
+			DataDir:       workspace,
+			DocRoot:       workspace,
+			Client:        client,
+			Printer:       ioutil.Discard,
+			Preload:       []string{"loop.js"},
+			ScriptTimeout: 100 * time.Millisecond,
+			StrictPreload: strict,
+		})
+		if strict {
+			if err == nil {
+				t.Errorf("console started with interrupted strict preload")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("console failed to start with interrupted preload: %v", err)
+		}
+		console.Stop(false)
+	}
+}
+
+// Tests that the script files are limited by default, unless explicitly
+// configured to be unlimited.
+func TestScriptLimit(t *testing.T) {
+	tests := []struct {
+		configured int64
+		limit      int64
+	}{
+		{0, DefaultMaxScriptSize},
+		{-1, 0},
+		{1024, 1024},
+	}
+	for i, tt := range tests {
+		if limit := scriptLimit(tt.configured, DefaultMaxScriptSize); limit != tt.limit {
+			t.Errorf("test %d: limit mismatch: have %d, want %d", i, limit, tt.limit)
+		}
+	}
+}
+
+// HealthBerithAPI and HealthNetAPI mock the RPC services queried by the health
+// check with fixed answers.
+type HealthBerithAPI struct{}
+
+func (HealthBerithAPI) BlockNumber() hexutil.Uint64   { return 0x10 }
+func (HealthBerithAPI) Syncing() (interface{}, error) { return false, nil }
+func (HealthBerithAPI) ChainId() hexutil.Uint64       { return 206 }
+
+type HealthNetAPI struct{}
+
+func (HealthNetAPI) PeerCount() hexutil.Uint { return 3 }
+
+// Tests that the health check reports the outcome of each call it runs.
+func TestHealthCheck(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("berith", HealthBerithAPI{}); err != nil {
+		t.Fatalf("failed to register berith service: %v", err)
+	}
+	if err := server.RegisterName("net", HealthNetAPI{}); err != nil {
+		t.Fatalf("failed to register net service: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	console := &Console{client: client, jsre: jsre.New("", ioutil.Discard)}
+	defer console.jsre.Stop(false)
+	console.jsre.Set("healthCheck", console.healthCheck)
+
+	val, err := console.jsre.Run("JSON.stringify(healthCheck())")
+	if err != nil {
+		t.Fatalf("failed to run health check: %v", err)
+	}
+	var health struct {
+		Healthy bool                          `json:"healthy"`
+		Checks  map[string]*healthCheckResult `json:"checks"`
+	}
+	if err := json.Unmarshal([]byte(val.String()), &health); err != nil {
+		t.Fatalf("failed to decode health %s: %v", val, err)
+	}
+	if !health.Healthy {
+		t.Errorf("node reported unhealthy: %s", val)
+	}
+	want := map[string]string{
+		"blockNumber": `"0x10"`,
+		"peerCount":   `"0x3"`,
+		"syncing":     `false`,
+		"chainId":     `"0xce"`,
+	}
+	for name, result := range want {
+		check, ok := health.Checks[name]
+		if !ok {
+			t.Errorf("check %s missing", name)
+			continue
+		}
+		if string(check.Result) != result || check.Error != "" {
+			t.Errorf("check %s mismatch: have %s (error %q), want %s", name, check.Result, check.Error, result)
+		}
+	}
+	// Failing calls mark the node unhealthy
+	server.Stop()
+	if val, err = console.jsre.Run("healthCheck().healthy"); err != nil {
+		t.Fatalf("failed to run health check: %v", err)
+	}
+	if healthy, _ := val.ToBoolean(); healthy {
+		t.Errorf("node with failing calls reported healthy")
+	}
+}
+
+// BenchAPI is a mock service answering every call after a fixed delay.
+type BenchAPI struct{ delay time.Duration }
+
+func (api BenchAPI) Echo(value int) int {
+	time.Sleep(api.delay)
+	return value
+}
+
+func (api BenchAPI) SendTransaction() error { return errors.New("mutating call made") }
+
+// Tests that the benchmark reports the latency of the calls it makes, and that
+// it refuses methods changing the node.
+func TestBenchmark(t *testing.T) {
+	const delay = 20 * time.Millisecond
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("bench", BenchAPI{delay}); err != nil {
+		t.Fatalf("failed to register bench service: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	console := &Console{client: client, jsre: jsre.New("", ioutil.Discard)}
+	defer console.jsre.Stop(false)
+	console.jsre.Set("benchmark", console.benchmark)
+
+	val, err := console.jsre.Run(`JSON.stringify(benchmark("bench_echo", [7], 10))`)
+	if err != nil {
+		t.Fatalf("failed to run benchmark: %v", err)
+	}
+	var stats benchmarkResult
+	if err := json.Unmarshal([]byte(val.String()), &stats); err != nil {
+		t.Fatalf("failed to decode stats %s: %v", val, err)
+	}
+	if stats.Method != "bench_echo" || stats.Iterations != 10 {
+		t.Errorf("benchmark mismatch: have %s x%d, want bench_echo x10", stats.Method, stats.Iterations)
+	}
+	want := float64(delay) / float64(time.Millisecond)
+	if stats.Avg < want || stats.Avg > 2*want {
+		t.Errorf("average latency mismatch: have %vms, want about %vms", stats.Avg, want)
+	}
+	if stats.Min > stats.Avg || stats.Avg > stats.Max || stats.P95 < stats.Min || stats.P95 > stats.Max {
+		t.Errorf("inconsistent latencies: %+v", stats)
+	}
+	// Failing and mutating calls are reported as errors
+	for _, call := range []string{
+		`benchmark("bench_echo", ["seven"], 1)`,
+		`benchmark("bench_sendTransaction", [], 1)`,
+		`benchmark("personal_listAccounts", [], 1)`,
+		`benchmark("bench_echo", [7], 0)`,
+	} {
+		if _, err := console.jsre.Run(call); err == nil {
+			t.Errorf("%s: no error", call)
+		}
+	}
+}
+
+// FeeBerithAPI mocks the RPC services queried by the fee estimate with fixed
+// answers, reverting the calls carrying data.
+type FeeBerithAPI struct{}
+
+type FeeCallArgs struct {
+	Gas   hexutil.Uint64 `json:"gas"`
+	Value hexutil.Big    `json:"value"`
+	Data  hexutil.Bytes  `json:"data"`
+}
+
+func (FeeBerithAPI) EstimateGas(args FeeCallArgs) (hexutil.Uint64, error) {
+	if len(args.Data) > 0 {
+		return 0, errors.New("gas required exceeds allowance or always failing transaction")
+	}
+	if args.Gas != 50000 || args.Value.ToInt().Cmp(big.NewInt(params.Ber)) != 0 {
+		return 0, fmt.Errorf("quantities mismatch: gas %d, value %v", args.Gas, args.Value.ToInt())
+	}
+	return 21000, nil
+}
+
+func (FeeBerithAPI) Call(args FeeCallArgs, block rpc.BlockNumber) hexutil.Bytes {
+	// Error("not enough stake")
+	reason := "not enough stake"
+	output := common.FromHex("0x08c379a0")
+	output = append(output, common.LeftPadBytes([]byte{0x20}, 32)...)
+	output = append(output, common.LeftPadBytes([]byte{byte(len(reason))}, 32)...)
+	return append(output, common.RightPadBytes([]byte(reason), 32)...)
+}
+
+func (FeeBerithAPI) GasPrice() *hexutil.Big { return (*hexutil.Big)(big.NewInt(2 * params.Gmin)) }
+
+// Tests that the fee estimate multiplies the estimated gas with the gas price,
+// and reports the revert reason of transactions failing the estimation.
+func TestEstimateFee(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("berith", FeeBerithAPI{}); err != nil {
+		t.Fatalf("failed to register berith service: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	console := &Console{client: client, jsre: jsre.New("", ioutil.Discard)}
+	defer console.jsre.Stop(false)
+	console.jsre.Set("estimateFee", console.estimateFee)
+
+	// Quantities are accepted as numbers and decimal strings
+	val, err := console.jsre.Run(`JSON.stringify(estimateFee({from: "` + testAddress + `", to: "` + testAddress + `", gas: 50000, value: "1000000000000000000"}))`)
+	if err != nil {
+		t.Fatalf("failed to estimate fee: %v", err)
+	}
+	var estimate feeEstimate
+	if err := json.Unmarshal([]byte(val.String()), &estimate); err != nil {
+		t.Fatalf("failed to decode estimate %s: %v", val, err)
+	}
+	want := feeEstimate{Gas: 21000, GasPrice: "2000000000", Fee: "42000000000000", FeeBer: "0.000042"}
+	if estimate != want {
+		t.Errorf("estimate mismatch: have %+v, want %+v", estimate, want)
+	}
+	// Reverting transactions report the reason instead
+	if val, err = console.jsre.Run(`JSON.stringify(estimateFee({from: "` + testAddress + `", data: "0x01"}))`); err != nil {
+		t.Fatalf("failed to estimate fee: %v", err)
+	}
+	estimate = feeEstimate{}
+	if err := json.Unmarshal([]byte(val.String()), &estimate); err != nil {
+		t.Fatalf("failed to decode estimate %s: %v", val, err)
+	}
+	if estimate.Reason != "not enough stake" || estimate.Error == "" || estimate.Gas != 0 {
+		t.Errorf("failed estimate mismatch: %+v", estimate)
+	}
+	for _, call := range []string{`estimateFee()`, `estimateFee({value: "lots"})`, `estimateFee({gas: true})`} {
+		if _, err := console.jsre.Run(call); err == nil {
+			t.Errorf("%s: no error", call)
+		}
+	}
+}
+
+// UnlockPersonalAPI mocks the personal RPC service, recording the unlock requests
+// and accepting the password "secret" only.
+type UnlockPersonalAPI struct {
+	requests map[common.Address]string
+	duration *uint64
+}
+
+func (api *UnlockPersonalAPI) UnlockAccount(addr common.Address, password string, duration *uint64) (bool, error) {
+	api.requests[addr] = password
+	api.duration = duration
+	if password != "secret" {
+		return false, errors.New("could not decrypt key with given passphrase")
+	}
+	return true, nil
+}
+
+// Tests that every account of a password file receives an unlock request with
+// its password, and that the outcome is reported per account.
+func TestUnlockAll(t *testing.T) {
+	api := &UnlockPersonalAPI{requests: make(map[common.Address]string)}
+	server := rpc.NewServer()
+	if err := server.RegisterName("personal", api); err != nil {
+		t.Fatalf("failed to register personal service: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	console := &Console{client: client, jsre: jsre.New("", ioutil.Discard)}
+	defer console.jsre.Stop(false)
+	console.jsre.Set("unlockAll", console.unlockAll)
+
+	dir, err := ioutil.TempDir("", "console-unlock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		first  = common.HexToAddress("0x01")
+		second = common.HexToAddress("0x02")
+		third  = common.HexToAddress("0x03")
+		file   = filepath.Join(dir, "passwords")
+	)
+	content := "# signers\n" + first.Hex() + " secret\n\n" + second.Hex() + "\twrong one\n" + third.Hex() + "  secret\n"
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	val, err := console.jsre.Run(`JSON.stringify(unlockAll("` + file + `", 60))`)
+	if err != nil {
+		t.Fatalf("failed to unlock accounts: %v", err)
+	}
+	want := map[common.Address]string{first: "secret", second: "wrong one", third: "secret"}
+	if !reflect.DeepEqual(api.requests, want) {
+		t.Errorf("unlock requests mismatch: have %v, want %v", api.requests, want)
+	}
+	if api.duration == nil || *api.duration != 60 {
+		t.Errorf("duration mismatch: have %v, want 60", api.duration)
+	}
+	var results []unlockResult
+	if err := json.Unmarshal([]byte(val.String()), &results); err != nil {
+		t.Fatalf("failed to decode results %s: %v", val, err)
+	}
+	if len(results) != 3 || !results[0].Unlocked || results[1].Unlocked || results[1].Error == "" || !results[2].Unlocked {
+		t.Errorf("results mismatch: %+v", results)
+	}
+	if strings.Contains(val.String(), "secret") || strings.Contains(val.String(), "wrong one") {
+		t.Errorf("password disclosed in results: %s", val)
+	}
+	// The call is kept out of the history, malformed lines are reported without their content
+	if !passwordRegexp.MatchString(`personal.unlockAll("` + file + `")`) {
+		t.Errorf("unlockAll kept in history")
+	}
+	if err := ioutil.WriteFile(file, []byte(first.Hex()+"-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := console.jsre.Run(`unlockAll("` + file + `")`); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("malformed file error mismatch: %v", err)
+	}
+}
+
+// Tests that raw transactions signed for another chain are rejected with the
+// chain they were signed for, pointed out in the console output.
+func TestSendRawTransactionChainMismatch(t *testing.T) {
+	tester := newTester(t, nil)
+	defer tester.Close(t)
+
+	key, _ := crypto.GenerateKey()
+	for _, test := range []struct {
+		chainID int64
+		want    string
+	}{
+		{1, "WARNING: transaction was signed for chain 1 (Ethereum mainnet), this node is chain 206!"},
+		{4242, "WARNING: transaction was signed for chain 4242, this node is chain 206!"},
+	} {
+		tx, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(params.Gmin), nil, types.Main, types.Main), types.NewEIP155Signer(big.NewInt(test.chainID)), key)
+		raw, _ := rlp.EncodeToBytes(tx)
+
+		tester.output.Reset()
+		tester.console.Evaluate(`berith.sendRawTransaction("` + hexutil.Encode(raw) + `")`)
+		if output := tester.output.String(); !strings.Contains(output, test.want) || !strings.Contains(output, "invalid sender") {
+			t.Errorf("chain %d: mismatch not pointed out: have %s, want %s", test.chainID, output, test.want)
+		}
+	}
+	// Transactions failing for other reasons carry no hint
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(params.Gmin), nil, types.Main, types.Main), types.NewEIP155Signer(big.NewInt(206)), key)
+	raw, _ := rlp.EncodeToBytes(tx)
+
+	tester.output.Reset()
+	tester.console.Evaluate(`berith.sendRawTransaction("` + hexutil.Encode(raw) + `")`)
+	if output := tester.output.String(); strings.Contains(output, "WARNING") || !strings.Contains(output, "Error") {
+		t.Errorf("unexpected output: %s", output)
+	}
+}
+
+// scriptedPrompter implements UserPrompter answering prompts from a script,
+// failing once the script runs out of answers.
+type scriptedPrompter struct {
+	hookedPrompter
+	confirms  []bool
+	passwords []string
+	prompts   []string
+}
+
+func (p *scriptedPrompter) PromptPassword(prompt string) (string, error) {
+	p.prompts = append(p.prompts, prompt)
+	if len(p.passwords) == 0 {
+		return "", errors.New("unexpected password prompt")
+	}
+	password := p.passwords[0]
+	p.passwords = p.passwords[1:]
+	return password, nil
+}
+
+func (p *scriptedPrompter) PromptConfirm(prompt string) (bool, error) {
+	p.prompts = append(p.prompts, prompt)
+	if len(p.confirms) == 0 {
+		return false, errors.New("unexpected confirmation prompt")
+	}
+	confirm := p.confirms[0]
+	p.confirms = p.confirms[1:]
+	return confirm, nil
+}
+
+// validatorNode is the simulated state of a node set up by the validator wizard.
+type validatorNode struct {
+	accounts   []common.Address
+	password   string
+	balance    *big.Int
+	staked     *big.Int
+	berithbase common.Address
+	mining     bool
+}
+
+// ValidatorBerithAPI, ValidatorPersonalAPI and ValidatorMinerAPI simulate the
+// RPC services used by the validator wizard on top of a validatorNode.
+type ValidatorBerithAPI struct{ n *validatorNode }
+
+func (api ValidatorBerithAPI) Accounts() []common.Address { return api.n.accounts }
+func (api ValidatorBerithAPI) Berithbase() (common.Address, error) {
+	if api.n.berithbase == (common.Address{}) {
+		return common.Address{}, errors.New("berithbase must be explicitly specified")
+	}
+	return api.n.berithbase, nil
+}
+func (api ValidatorBerithAPI) GetBalance(address common.Address, block string) *hexutil.Big {
+	return (*hexutil.Big)(api.n.balance)
+}
+func (api ValidatorBerithAPI) GetStakeBalance(address common.Address, block string) *hexutil.Big {
+	return (*hexutil.Big)(api.n.staked)
+}
+func (api ValidatorBerithAPI) GasPrice() *hexutil.Big      { return (*hexutil.Big)(big.NewInt(1e9)) }
+func (api ValidatorBerithAPI) Mining() bool                { return api.n.mining }
+func (api ValidatorBerithAPI) BlockNumber() hexutil.Uint64 { return 100 }
+func (api ValidatorBerithAPI) Stake(args struct {
+	From  common.Address `json:"from"`
+	Value *hexutil.Big   `json:"value"`
+}) (common.Hash, error) {
+	api.n.balance.Sub(api.n.balance, args.Value.ToInt())
+	api.n.staked.Add(api.n.staked, args.Value.ToInt())
+	return common.Hash{1}, nil
+}
+
+type ValidatorPersonalAPI struct{ n *validatorNode }
+
+func (api ValidatorPersonalAPI) NewAccount(password string) common.Address {
+	api.n.accounts = append(api.n.accounts, common.HexToAddress(testAddress))
+	api.n.password = password
+	return common.HexToAddress(testAddress)
+}
+func (api ValidatorPersonalAPI) UnlockAccount(address common.Address, password string, duration uint64) (bool, error) {
+	if password != api.n.password {
+		return false, errors.New("could not decrypt key with given passphrase")
+	}
+	return true, nil
+}
+
+type ValidatorMinerAPI struct{ n *validatorNode }
+
+func (api ValidatorMinerAPI) SetBerithbase(address common.Address) bool {
+	api.n.berithbase = address
+	return true
+}
+func (api ValidatorMinerAPI) Start() { api.n.mining = true }
+
+// Tests that the validator wizard walks through the missing steps, can be
+// aborted at any of them and resumes where it left off when re-run.
+func TestSetupValidator(t *testing.T) {
+	n := &validatorNode{balance: new(big.Int), staked: new(big.Int)}
+	server := rpc.NewServer()
+	for name, service := range map[string]interface{}{
+		"berith":   ValidatorBerithAPI{n},
+		"personal": ValidatorPersonalAPI{n},
+		"miner":    ValidatorMinerAPI{n},
+	} {
+		if err := server.RegisterName(name, service); err != nil {
+			t.Fatalf("failed to register %s service: %v", name, err)
+		}
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	prompter := new(scriptedPrompter)
+	b := newBridge(client, prompter, ioutil.Discard)
+
+	// A fresh node gets an account created, but cannot stake without funds
+	prompter.confirms, prompter.passwords = []bool{true}, []string{"secret", "secret"}
+	if err := b.setupValidator(""); err == nil || !strings.Contains(err.Error(), "insufficient balance") {
+		t.Fatalf("unfunded account: error mismatch: have %v, want insufficient balance", err)
+	}
+	if len(n.accounts) != 1 {
+		t.Fatalf("account not created")
+	}
+	// Once funded, the stake is sent and declining the berithbase aborts
+	n.balance.Mul(big.NewInt(10), common.UnitForBer)
+	prompter.confirms, prompter.passwords = []bool{true, false}, []string{"secret"}
+	if err := b.setupValidator(""); err != errValidatorAborted {
+		t.Fatalf("declined berithbase: error mismatch: have %v, want %v", err, errValidatorAborted)
+	}
+	if n.staked.Cmp(params.MainnetChainConfig.Bsrr.StakeMinimum) != 0 {
+		t.Fatalf("stake mismatch: have %v, want %v", n.staked, params.MainnetChainConfig.Bsrr.StakeMinimum)
+	}
+	if n.berithbase != (common.Address{}) {
+		t.Fatalf("berithbase set despite being declined")
+	}
+	// Re-running skips the completed steps
+	prompter.prompts, prompter.confirms = nil, []bool{true, true}
+	if err := b.setupValidator(""); err != nil {
+		t.Fatalf("failed to finish validator setup: %v", err)
+	}
+	if want := []string{"Set berithbase to " + n.accounts[0].Hex() + "?", "Start mining?"}; fmt.Sprint(prompter.prompts) != fmt.Sprint(want) {
+		t.Errorf("prompts mismatch: have %q, want %q", prompter.prompts, want)
+	}
+	if n.berithbase != n.accounts[0] || !n.mining {
+		t.Errorf("validator not set up: berithbase %x, mining %v", n.berithbase, n.mining)
+	}
+	// A fully set up validator needs no further steps
+	prompter.prompts = nil
+	if err := b.setupValidator(""); err != nil {
+		t.Fatalf("failed to rerun validator setup: %v", err)
+	}
+	if len(prompter.prompts) != 0 {
+		t.Errorf("set up validator prompted for %q", prompter.prompts)
+	}
+}
+
+// Tests that receipt logs are decoded against the events of an ABI, with the
+// ones not matching any event returned raw.
+func TestDecodeLogs(t *testing.T) {
+	const tokenABI = `[
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]},
+		{"type":"event","name":"Note","anonymous":true,"inputs":[{"name":"sig","type":"bytes4","indexed":true},{"name":"data","type":"string","indexed":false}]}
+	]`
+	contract, err := abi.JSON(strings.NewReader(tokenABI))
+	if err != nil {
+		t.Fatalf("failed to parse abi: %v", err)
+	}
+	var (
+		from  = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		to    = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		token = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	)
+	note, err := contract.Events["Note"].Inputs.NonIndexed().Pack("hello")
+	if err != nil {
+		t.Fatalf("failed to pack note: %v", err)
+	}
+	receipt := fmt.Sprintf(`({"status":"0x1","logs":[
+		{"address":"%s","topics":["%s","%s","%s"],"data":"%s"},
+		{"address":"%s","topics":["0x1234567800000000000000000000000000000000000000000000000000000000"],"data":"%s"},
+		{"address":"%s","topics":["0x%064x"],"data":"0x"}
+	]})`,
+		token.Hex(), contract.Events["Transfer"].ID.Hex(), common.BytesToHash(from.Bytes()).Hex(), common.BytesToHash(to.Bytes()).Hex(), hexutil.Encode(common.LeftPadBytes(big.NewInt(1000).Bytes(), 32)),
+		token.Hex(), hexutil.Encode(note),
+		token.Hex(), 42)
+
+	console := &Console{jsre: jsre.New("", ioutil.Discard)}
+	defer console.jsre.Stop(false)
+	console.jsre.Set("decodeLogs", console.decodeLogs)
+	console.jsre.Set("tokenABI", tokenABI)
+
+	val, err := console.jsre.Run("JSON.stringify(decodeLogs(" + receipt + ", JSON.parse(tokenABI)))")
+	if err != nil {
+		t.Fatalf("failed to decode logs: %v", err)
+	}
+	var logs []map[string]interface{}
+	if err := json.Unmarshal([]byte(val.String()), &logs); err != nil {
+		t.Fatalf("failed to parse decoded logs %s: %v", val, err)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("decoded log count mismatch: have %d, want 3", len(logs))
+	}
+	transfer := logs[0]
+	if transfer["event"] != "Transfer" {
+		t.Fatalf("transfer event mismatch: have %v", transfer["event"])
+	}
+	args := transfer["args"].(map[string]interface{})
+	wantFrom, _ := from.MarshalText()
+	wantTo, _ := to.MarshalText()
+	if args["from"] != string(wantFrom) || args["to"] != string(wantTo) || args["value"] != "1000" {
+		t.Errorf("transfer args mismatch: have %v", args)
+	}
+	if logs[1]["event"] != "Note" || logs[1]["args"].(map[string]interface{})["data"] != "hello" {
+		t.Errorf("anonymous event mismatch: have %v", logs[1])
+	}
+	if _, ok := logs[2]["event"]; ok || logs[2]["data"] != "0x" {
+		t.Errorf("unknown log not returned raw: have %v", logs[2])
+	}
+	// ABIs given as JSON strings are accepted too
+	if _, err := console.jsre.Run("decodeLogs(" + receipt + ".logs, tokenABI)"); err != nil {
+		t.Errorf("failed to decode logs with string abi: %v", err)
+	}
+}
+
+// HangingBerithAPI mocks a wedged node, never answering block number requests
+// until released.
+type HangingBerithAPI struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (api HangingBerithAPI) BlockNumber() hexutil.Uint64 {
+	api.entered <- struct{}{}
+	<-api.release
+	return 0
+}
+
+// Tests that interrupting an evaluation hung in an RPC call frees the prompt,
+// while a second interrupt right after or a termination exits the console.
+func TestInterruptEvaluation(t *testing.T) {
+	for _, exit := range [][]os.Signal{{os.Interrupt, os.Interrupt}, {syscall.SIGTERM}} {
+		testInterruptEvaluation(t, exit)
+	}
+}
+
+func testInterruptEvaluation(t *testing.T, exit []os.Signal) {
+	workspace, err := ioutil.TempDir("", "console-interrupt-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	api := HangingBerithAPI{entered: make(chan struct{}, 2), release: make(chan struct{})}
+	defer close(api.release)
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("berith", api); err != nil {
+		t.Fatalf("failed to register berith service: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	prompter := &hookedPrompter{scheduler: make(chan string)}
+	console, err := New(Config{
+		DataDir:  workspace,
+		DocRoot:  workspace,
+		Client:   client,
+		Prompter: prompter,
+		Printer:  ioutil.Discard,
+	})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	defer console.Stop(false)
+
+	done := make(chan struct{})
+	go func() {
+		console.Interactive()
+		close(done)
+	}()
+	hang := func() {
+		<-prompter.scheduler
+		prompter.scheduler <- "berith.blockNumber"
+		select {
+		case <-api.entered:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("request not sent")
+		}
+	}
+	// The first interrupt cancels the hung request and returns to the prompt
+	hang()
+	console.signal <- os.Interrupt
+	select {
+	case <-prompter.scheduler:
+	case <-time.After(time.Second):
+		t.Fatalf("prompt not freed by interrupt")
+	}
+	prompter.scheduler <- "" // skip empty line to re-prompt
+
+	// The exit signals exit the console
+	hang()
+	for _, sig := range exit {
+		console.signal <- sig
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("console not exited by %v", exit)
+	}
+}
+
+// ExtraBerithAPI mocks a node serving the headers of a few blocks.
+type ExtraBerithAPI struct {
+	headers map[rpc.BlockNumber]*types.Header
+}
+
+func (api ExtraBerithAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) *types.Header {
+	return api.headers[number]
+}
+
+// ExtraBsrrAPI mocks the bsrr namespace, for the console to offer it.
+type ExtraBsrrAPI struct{}
+
+func (ExtraBsrrAPI) GetSigners() []common.Address { return nil }
+
+// Tests that the extra-data of a checkpoint block is split into its parts, with
+// the signers listed and the author recovered from the seal.
+func TestDecodeExtra(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	author := crypto.PubkeyToAddress(key.PublicKey)
+	signers := []common.Address{author, {0x02}, {0x03}}
+
+	vanity := bytes.Repeat([]byte{0x01}, 32)
+	extra := append(append([]byte{}, vanity...), make([]byte, 65)...)
+	for i, signer := range signers {
+		extra = append(extra[:32+i*common.AddressLength], append(signer.Bytes(), extra[32+i*common.AddressLength:]...)...)
+	}
+	checkpoint := &types.Header{Number: big.NewInt(360), Difficulty: big.NewInt(1), Time: big.NewInt(1000), Extra: extra}
+	engine := bsrr.New(&params.BSRRConfig{Period: 10, Epoch: 360}, nil)
+	seal, _ := crypto.Sign(engine.SealHash(checkpoint).Bytes(), key)
+	copy(checkpoint.Extra[len(checkpoint.Extra)-65:], seal)
+
+	server := rpc.NewServer()
+	server.RegisterName("berith", ExtraBerithAPI{headers: map[rpc.BlockNumber]*types.Header{
+		360:                   checkpoint,
+		rpc.LatestBlockNumber: checkpoint,
+		7:                     {Number: big.NewInt(7), Difficulty: big.NewInt(1), Time: big.NewInt(70), Extra: make([]byte, 32+65+10)},
+	}})
+	server.RegisterName("bsrr", ExtraBsrrAPI{})
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	workspace, err := ioutil.TempDir("", "console-extra-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	console, err := New(Config{DataDir: workspace, DocRoot: workspace, Client: client, Prompter: new(scriptedPrompter), Printer: ioutil.Discard})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	defer console.Stop(false)
+
+	for _, query := range []string{"360", "", "'latest'"} {
+		var have extradata.Fields
+		if err := json.Unmarshal([]byte(run(t, console, "JSON.stringify(bsrr.decodeExtra("+query+"))")), &have); err != nil {
+			t.Fatalf("%q: failed to decode extra-data: %v", query, err)
+		}
+		if !bytes.Equal(have.Vanity, vanity) || !bytes.Equal(have.Seal, seal) {
+			t.Errorf("%q: vanity or seal mismatch: have %x, %x", query, have.Vanity, have.Seal)
+		}
+		if !reflect.DeepEqual(have.Signers, signers) {
+			t.Errorf("%q: signers mismatch: have %v, want %v", query, have.Signers, signers)
+		}
+		if have.Author == nil || *have.Author != author {
+			t.Errorf("%q: author mismatch: have %v, want %v", query, have.Author, author)
+		}
+	}
+	// Unknown blocks and malformed signer lists are refused
+	for _, query := range []string{"5", "7", "-1", "{}"} {
+		if _, err := console.jsre.Run("bsrr.decodeExtra(" + query + ")"); err == nil {
+			t.Errorf("%q: extra-data decoded", query)
+		}
+	}
+}
+
+// InspectBsrrAPI mocks the bsrr namespace, reporting the epoch length.
+type InspectBsrrAPI struct{}
+
+func (InspectBsrrAPI) NetworkParams() *bsrr.NetworkParams {
+	return &bsrr.NetworkParams{Period: 10, Epoch: 360}
+}
+
+// Tests that an inspected block is printed with the author recovered from the
+// seal, the rank decoded from the nonce and the checkpoint told by the epoch.
+func TestInspectBlock(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	author := crypto.PubkeyToAddress(key.PublicKey)
+	engine := bsrr.New(&params.BSRRConfig{Period: 10, Epoch: 360}, nil)
+
+	seal := func(header *types.Header) *types.Header {
+		sig, _ := crypto.Sign(engine.SealHash(header).Bytes(), key)
+		copy(header.Extra[len(header.Extra)-65:], sig)
+		return header
+	}
+	checkpoint := seal(&types.Header{Number: big.NewInt(720), Difficulty: big.NewInt(5000), Time: big.NewInt(1000), Nonce: types.EncodeNonce(2), Extra: append(append(make([]byte, 32), author.Bytes()...), make([]byte, 65)...)})
+	regular := seal(&types.Header{Number: big.NewInt(721), Difficulty: big.NewInt(4000), Time: big.NewInt(1010), Nonce: types.EncodeNonce(3), Extra: make([]byte, 32+65)})
+
+	server := rpc.NewServer()
+	server.RegisterName("berith", ExtraBerithAPI{headers: map[rpc.BlockNumber]*types.Header{
+		720:                   checkpoint,
+		721:                   regular,
+		rpc.LatestBlockNumber: regular,
+	}})
+	server.RegisterName("bsrr", InspectBsrrAPI{})
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	workspace, err := ioutil.TempDir("", "console-inspect-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	printer := new(bytes.Buffer)
+	console, err := New(Config{DataDir: workspace, DocRoot: workspace, Client: client, Prompter: new(scriptedPrompter), Printer: printer})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	defer console.Stop(false)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"720", []string{"Author:      " + author.Hex(), "Rank:        2", "Difficulty:  5000", "Checkpoint:  true (epoch 2, 360 blocks each)", "Signers:     1 listed"}},
+		{"721", []string{"Author:      " + author.Hex(), "Rank:        3", "Difficulty:  4000", "Checkpoint:  false (epoch 2, 360 blocks each)"}},
+		{"", []string{"Block:       721", "Rank:        3"}},
+	}
+	for _, test := range tests {
+		printer.Reset()
+		run(t, console, "bsrr.inspectBlock("+test.query+")")
+		for _, want := range test.want {
+			if !strings.Contains(printer.String(), want) {
+				t.Errorf("%q: missing %q in\n%s", test.query, want, printer.String())
+			}
+		}
+	}
+	if _, err := console.jsre.Run("bsrr.inspectBlock(5)"); err == nil {
+		t.Errorf("unknown block inspected")
+	}
+}
+
+// EpochBsrrAPI mocks the bsrr namespace, reporting the block number each epoch
+// information was requested for, -1 if none.
+type EpochBsrrAPI struct{}
+
+func (EpochBsrrAPI) EpochInfo(number *rpc.BlockNumber) map[string]interface{} {
+	requested := int64(-1)
+	if number != nil {
+		requested = number.Int64()
+	}
+	return map[string]interface{}{
+		"number":              requested,
+		"currentEpochNumber":  3,
+		"nextCheckpointBlock": 16,
+		"stakeTargetBlock":    map[string]interface{}{"number": 9},
+	}
+}
+
+// Tests that the epoch information is requested for the current block if no
+// block number is given, and that it is printed in the welcome banner.
+func TestEpochInfo(t *testing.T) {
+	server := rpc.NewServer()
+	server.RegisterName("bsrr", EpochBsrrAPI{})
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	workspace, err := ioutil.TempDir("", "console-epoch-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	printer := new(bytes.Buffer)
+	console, err := New(Config{DataDir: workspace, DocRoot: workspace, Client: client, Prompter: new(scriptedPrompter), Printer: printer})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	defer console.Stop(false)
+
+	for query, want := range map[string]string{"": "-1", "null": "-1", "12": "12", "'latest'": "-1", "'0xc'": "12"} {
+		if have := run(t, console, "bsrr.epochInfo("+query+").number"); have != want {
+			t.Errorf("%q: requested block mismatch: have %s, want %s", query, have, want)
+		}
+	}
+	console.Welcome()
+	if want := "epoch: 3 (next checkpoint at block 16, stake target block 9)"; !strings.Contains(printer.String(), want) {
+		t.Errorf("welcome banner missing epoch: have\n%s\nwant also %s", printer.String(), want)
+	}
+}
+
+// ProducersBsrrAPI mocks the bsrr namespace, predicting an unordered ranked set
+// of producers and recording the requested count.
+type ProducersBsrrAPI struct {
+	count *int
+}
+
+func (api ProducersBsrrAPI) PredictProducers(count int) []bsrr.ProducerPrediction {
+	*api.count = count
+	return []bsrr.ProducerPrediction{
+		{Rank: 3, Address: common.Address{0x03}, Score: (*hexutil.Big)(big.NewInt(100))},
+		{Rank: 1, Address: common.Address{0x01}, Score: (*hexutil.Big)(big.NewInt(300))},
+		{Rank: 2, Address: common.Address{0x02}, Score: (*hexutil.Big)(big.NewInt(200))},
+	}
+}
+
+// Tests that the predicted producers of the next block are printed as a table in
+// ascending order of rank, and that nodes not predicting them are reported.
+func TestNextProducers(t *testing.T) {
+	var count int
+	server := rpc.NewServer()
+	server.RegisterName("bsrr", ProducersBsrrAPI{count: &count})
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	workspace, err := ioutil.TempDir("", "console-producers-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	printer := new(bytes.Buffer)
+	console, err := New(Config{DataDir: workspace, DocRoot: workspace, Client: client, Prompter: new(scriptedPrompter), Printer: printer})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	defer console.Stop(false)
+
+	printer.Reset()
+	run(t, console, "bsrr.nextProducers(3)")
+	if count != 3 {
+		t.Errorf("requested count mismatch: have %d, want 3", count)
+	}
+	lines := strings.Split(strings.TrimSpace(printer.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "RANK") {
+		t.Fatalf("table mismatch: have\n%s", printer.String())
+	}
+	for i, line := range lines[1:] {
+		fields := strings.Fields(line)
+		want := []string{fmt.Sprint(i + 1), common.Address{byte(i + 1)}.Hex(), fmt.Sprint(300 - 100*i)}
+		if !reflect.DeepEqual(fields, want) {
+			t.Errorf("row %d mismatch: have %v, want %v", i, fields, want)
+		}
+	}
+	for _, query := range []string{"0", "'3'", "-1"} {
+		if _, err := console.jsre.Run("bsrr.nextProducers(" + query + ")"); err == nil {
+			t.Errorf("%s: invalid count accepted", query)
+		}
+	}
+	// A node without the prediction is reported without failing
+	server = rpc.NewServer()
+	server.RegisterName("bsrr", ExtraBsrrAPI{})
+	client = rpc.DialInProc(server)
+	defer client.Close()
+
+	printer = new(bytes.Buffer)
+	console, err = New(Config{DataDir: workspace, DocRoot: workspace, Client: client, Prompter: new(scriptedPrompter), Printer: printer})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	defer console.Stop(false)
+
+	printer.Reset()
+	run(t, console, "bsrr.nextProducers()")
+	if want := "bsrr_predictProducers is not available"; !strings.Contains(printer.String(), want) {
+		t.Errorf("missing prediction not reported: have %q", printer.String())
+	}
+}
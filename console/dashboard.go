@@ -0,0 +1,126 @@
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"berith-chain/internals/berithapi"
+
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/rpc"
+	"github.com/robertkrimen/otto"
+)
+
+// dashboardPollInterval is the interval the dashboard polls the node at if the
+// pending stats can't be subscribed to.
+const dashboardPollInterval = 2 * time.Second
+
+// dashboard shows the pending transactions, the gas price floor and the latest
+// block of the node on a single line updated until interrupted. The optional
+// argument holds the thresholds of the pendingStats subscription.
+func (c *Console) dashboard(call otto.FunctionCall) otto.Value {
+	opts := new(berithapi.PendingStatsOptions)
+	if arg := call.Argument(0); arg.IsDefined() && !arg.IsNull() {
+		JSON, _ := call.Otto.Object("JSON")
+		blob, err := JSON.Call("stringify", arg)
+		if err != nil {
+			throwJSException(err.Error())
+		}
+		if err := json.Unmarshal([]byte(blob.String()), opts); err != nil {
+			throwJSException("usage: dashboard({pendingDelta: <count>, gasPriceDelta: <hex wei>, coalesce: <ms>})")
+		}
+	}
+	fmt.Fprintln(c.printer, "Press Ctrl-C to stop the dashboard")
+	err := c.followPendingStats(c.context(), opts, func(stats *berithapi.PendingStats) {
+		fmt.Fprintf(c.printer, "\r%s\x1b[K", formatPendingStats(stats))
+	})
+	fmt.Fprintln(c.printer)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return otto.UndefinedValue()
+}
+
+// formatPendingStats renders the pending stats as a dashboard line.
+func formatPendingStats(stats *berithapi.PendingStats) string {
+	return fmt.Sprintf("block %d (%d txs) | pending %d | gas price floor %v wei", stats.LastBlockNumber, stats.LastBlockTxCount, stats.PendingCount, stats.GasPriceFloor.ToInt())
+}
+
+// followPendingStats passes the pending stats of the node to update until the
+// context is cancelled. They are notified by the pendingStats subscription, or
+// polled if the transport or the node doesn't offer it.
+func (c *Console) followPendingStats(ctx context.Context, opts *berithapi.PendingStatsOptions, update func(*berithapi.PendingStats)) error {
+	stats := make(chan *berithapi.PendingStats, 16)
+	sub, err := c.client.Subscribe(ctx, "berith", stats, "pendingStats", opts)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if rpcErr, ok := err.(rpc.Error); err == rpc.ErrNotificationsUnsupported || (ok && rpcErr.ErrorCode() == methodNotFoundCode) {
+			return c.pollPendingStats(ctx, update)
+		}
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case s := <-stats:
+			update(s)
+		case err := <-sub.Err():
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// pollPendingStats passes the pending stats of the node, gathered every
+// dashboardPollInterval, to update until the context is cancelled.
+func (c *Console) pollPendingStats(ctx context.Context, update func(*berithapi.PendingStats)) error {
+	ticker := time.NewTicker(dashboardPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var (
+			status struct {
+				Pending hexutil.Uint `json:"pending"`
+			}
+			price hexutil.Big
+			head  struct {
+				Number       hexutil.Uint64    `json:"number"`
+				Transactions []json.RawMessage `json:"transactions"`
+			}
+		)
+		batch := []rpc.BatchElem{
+			{Method: "txpool_status", Result: &status},
+			{Method: "berith_gasPrice", Result: &price},
+			{Method: "berith_getBlockByNumber", Args: []interface{}{"latest", false}, Result: &head},
+		}
+		err := c.client.BatchCallContext(ctx, batch)
+		for _, elem := range batch {
+			if err == nil {
+				err = elem.Error
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		update(&berithapi.PendingStats{
+			PendingCount:     status.Pending,
+			GasPriceFloor:    &price,
+			LastBlockNumber:  head.Number,
+			LastBlockTxCount: hexutil.Uint(len(head.Transactions)),
+		})
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
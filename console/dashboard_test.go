@@ -0,0 +1,86 @@
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"berith-chain/internals/berithapi"
+
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+// StatsBerithAPI mocks the pendingStats subscription, notifying the pending
+// count threshold it was asked for as the pending count.
+type StatsBerithAPI struct{}
+
+func (StatsBerithAPI) PendingStats(ctx context.Context, opts *berithapi.PendingStatsOptions) (*rpc.Subscription, error) {
+	notifier, _ := rpc.NotifierFromContext(ctx)
+	sub := notifier.CreateSubscription()
+	notifier.Notify(sub.ID, &berithapi.PendingStats{PendingCount: hexutil.Uint(opts.PendingDelta), GasPriceFloor: (*hexutil.Big)(hexutil.MustDecodeBig("0x64")), LastBlockNumber: 7})
+	return sub, nil
+}
+
+// PollBerithAPI and PollTxPoolAPI mock the calls polled by the dashboard.
+type PollBerithAPI struct{}
+
+func (PollBerithAPI) GasPrice() *hexutil.Big { return (*hexutil.Big)(hexutil.MustDecodeBig("0x3e8")) }
+func (PollBerithAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) map[string]interface{} {
+	return map[string]interface{}{"number": hexutil.Uint64(12), "transactions": []string{"0x01", "0x02"}}
+}
+
+type PollTxPoolAPI struct{}
+
+func (PollTxPoolAPI) Status() map[string]hexutil.Uint {
+	return map[string]hexutil.Uint{"pending": 5, "queued": 1}
+}
+
+// followFirst returns the first pending stats followed by the console.
+func followFirst(t *testing.T, server *rpc.Server, opts *berithapi.PendingStatsOptions) *berithapi.PendingStats {
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	console := &Console{client: client}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var first *berithapi.PendingStats
+	err := console.followPendingStats(ctx, opts, func(stats *berithapi.PendingStats) {
+		first = stats
+		cancel()
+	})
+	if err != nil {
+		t.Fatalf("failed to follow pending stats: %v", err)
+	}
+	return first
+}
+
+// Tests that the dashboard follows the pending stats through the subscription,
+// passing it the thresholds.
+func TestDashboardSubscription(t *testing.T) {
+	server := rpc.NewServer()
+	server.RegisterName("berith", StatsBerithAPI{})
+
+	var opts berithapi.PendingStatsOptions
+	if err := json.Unmarshal([]byte(`{"pendingDelta": 3}`), &opts); err != nil {
+		t.Fatalf("failed to decode options: %v", err)
+	}
+	stats := followFirst(t, server, &opts)
+	if have, want := formatPendingStats(stats), "block 7 (0 txs) | pending 3 | gas price floor 100 wei"; have != want {
+		t.Errorf("dashboard line mismatch: have %q, want %q", have, want)
+	}
+}
+
+// Tests that the dashboard polls the pending stats of nodes without the
+// subscription.
+func TestDashboardPolling(t *testing.T) {
+	server := rpc.NewServer()
+	server.RegisterName("berith", PollBerithAPI{})
+	server.RegisterName("txpool", PollTxPoolAPI{})
+
+	stats := followFirst(t, server, new(berithapi.PendingStatsOptions))
+	if have, want := formatPendingStats(stats), "block 12 (2 txs) | pending 5 | gas price floor 1000 wei"; have != want {
+		t.Errorf("dashboard line mismatch: have %q, want %q", have, want)
+	}
+}
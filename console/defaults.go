@@ -0,0 +1,361 @@
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/common/math"
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/robertkrimen/otto"
+)
+
+// DefaultsDir is the directory within the data directory persisting the session
+// defaults of the consoles which opted in, one file per endpoint.
+const DefaultsDir = "defaults"
+
+// txDefaultMethods are the RPC methods whose transaction argument is completed
+// with the transaction defaults of the session.
+var txDefaultMethods = map[string]bool{
+	"berith_call":              true,
+	"berith_sendTransaction":   true,
+	"berith_signTransaction":   true,
+	"personal_sendTransaction": true,
+	"personal_signTransaction": true,
+}
+
+// defaultValues are the block parameter and the transaction fields used by the
+// calls of the session which don't specify them.
+type defaultValues struct {
+	Block    string          `json:"block,omitempty"` // Block parameter of the calls, "latest" if empty
+	From     *common.Address `json:"from,omitempty"`
+	Gas      *hexutil.Uint64 `json:"gas,omitempty"`
+	GasPrice *hexutil.Big    `json:"gasPrice,omitempty"`
+}
+
+// sessionDefaults holds the defaults of a console session outside the JavaScript
+// runtime, so that they are reapplied whenever the runtime is initialized.
+type sessionDefaults struct {
+	path string // File persisting the defaults
+
+	lock    sync.RWMutex
+	values  defaultValues
+	persist bool // Whether the defaults are persisted, opted in by the user
+}
+
+// newSessionDefaults creates the defaults of a session attached to the endpoint,
+// loading the persisted ones if an earlier session against it opted in.
+func newSessionDefaults(dir string, endpoint string) *sessionDefaults {
+	path := filepath.Join(dir, endpointFile(endpoint))
+	d := &sessionDefaults{path: path}
+
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("Failed to load console defaults", "path", path, "err", err)
+		}
+		return d
+	}
+	if err := json.Unmarshal(blob, &d.values); err != nil {
+		log.Warn("Skipping corrupt console defaults", "path", path, "err", err)
+		d.values = defaultValues{}
+		return d
+	}
+	d.persist = true
+	return d
+}
+
+// save persists the defaults if the session opted in.
+func (d *sessionDefaults) save() error {
+	if !d.persist {
+		return nil
+	}
+	blob, err := json.Marshal(d.values)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(d.path), 0700); err != nil {
+		return err
+	}
+	// Replace the file at once so that a crash never leaves it half written
+	tmp := d.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.path)
+}
+
+// setPersist toggles whether the defaults are persisted, writing them out or
+// deleting the persisted ones.
+func (d *sessionDefaults) setPersist(persist bool) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.persist = persist
+	if persist {
+		return d.save()
+	}
+	if err := os.Remove(d.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// update modifies the defaults with fn and persists them.
+func (d *sessionDefaults) update(fn func(values *defaultValues)) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	fn(&d.values)
+	return d.save()
+}
+
+// current returns a copy of the defaults and whether they are persisted.
+func (d *sessionDefaults) current() (defaultValues, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.values, d.persist
+}
+
+// complete fills the fields of the transaction argument of the request which
+// are absent with the transaction defaults. Fields given explicitly are kept.
+func (d *sessionDefaults) complete(method string, params []interface{}) {
+	if d == nil || !txDefaultMethods[method] || len(params) == 0 {
+		return
+	}
+	args, ok := params[0].(map[string]interface{})
+	if !ok {
+		return
+	}
+	values, _ := d.current()
+	if _, ok := args["from"]; !ok && values.From != nil {
+		args["from"] = *values.From
+	}
+	if _, ok := args["gas"]; !ok && values.Gas != nil {
+		args["gas"] = *values.Gas
+	}
+	if _, ok := args["gasPrice"]; !ok && values.GasPrice != nil {
+		args["gasPrice"] = values.GasPrice
+	}
+}
+
+// apply injects the default block parameter and sender into the web3 library,
+// which uses them for the calls not specifying a block or a sender.
+func (d *sessionDefaults) apply(vm *otto.Otto) error {
+	values, _ := d.current()
+
+	block := values.Block
+	if block == "" {
+		block = "latest"
+	}
+	from := "undefined"
+	if values.From != nil {
+		from = fmt.Sprintf("%q", values.From.Hex())
+	}
+	_, err := vm.Run(fmt.Sprintf("web3.berith.defaultBlock = %q; web3.berith.defaultAccount = %s;", block, from))
+	return err
+}
+
+// parseDefaultBlock validates a block parameter given as a number, a hex or
+// decimal string or a block tag, returning it as accepted by the RPC methods.
+func parseDefaultBlock(block otto.Value) (string, error) {
+	switch {
+	case block.IsNumber():
+		number, _ := block.ToFloat()
+		if number < 0 || number != float64(uint64(number)) {
+			return "", fmt.Errorf("invalid block number %v", block)
+		}
+		return hexutil.EncodeUint64(uint64(number)), nil
+
+	case block.IsString():
+		switch tag := block.String(); tag {
+		case "latest", "pending", "earliest":
+			return tag, nil
+		default:
+			number, ok := math.ParseUint64(tag)
+			if !ok || tag == "" {
+				return "", fmt.Errorf("invalid block parameter %q", tag)
+			}
+			return hexutil.EncodeUint64(number), nil
+		}
+	}
+	return "", fmt.Errorf("invalid block parameter %v", block)
+}
+
+// parseTxDefaults decodes the transaction defaults from their JSON encoding.
+// Amounts may be given as numbers or hex or decimal strings.
+func parseTxDefaults(blob string) (defaultValues, error) {
+	var (
+		fields map[string]interface{}
+		values defaultValues
+	)
+	dec := json.NewDecoder(strings.NewReader(blob))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return values, err
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field := fields[key]
+		if field == nil {
+			continue
+		}
+		text := fmt.Sprint(field)
+		switch key {
+		case "from":
+			var from common.Address
+			if _, ok := field.(string); !ok || from.UnmarshalText([]byte(text)) != nil {
+				return values, fmt.Errorf("invalid from address %v", field)
+			}
+			values.From = &from
+
+		case "gas":
+			gas, ok := math.ParseUint64(text)
+			if !ok || text == "" {
+				return values, fmt.Errorf("invalid gas %v", field)
+			}
+			values.Gas = (*hexutil.Uint64)(&gas)
+
+		case "gasPrice":
+			price, ok := math.ParseBig256(text)
+			if !ok || text == "" {
+				return values, fmt.Errorf("invalid gasPrice %v", field)
+			}
+			values.GasPrice = (*hexutil.Big)(price)
+
+		default:
+			return values, fmt.Errorf("unsupported transaction default %q, want from, gas or gasPrice", key)
+		}
+	}
+	return values, nil
+}
+
+// setDefaultBlock sets the block parameter used by the calls of the session not
+// specifying one, like berith.getBalance or berith.call. Without arguments or
+// with null it is reset to "latest". It returns the block parameter set.
+func (c *Console) setDefaultBlock(call otto.FunctionCall) otto.Value {
+	var block string
+	if arg := call.Argument(0); !arg.IsUndefined() && !arg.IsNull() {
+		var err error
+		if block, err = parseDefaultBlock(arg); err != nil {
+			throwJSException(err.Error())
+		}
+	}
+	if err := c.defaults.update(func(values *defaultValues) { values.Block = block }); err != nil {
+		throwJSException(err.Error())
+	}
+	if err := c.defaults.apply(call.Otto); err != nil {
+		throwJSException(err.Error())
+	}
+	if block == "" {
+		block = "latest"
+	}
+	result, _ := otto.ToValue(block)
+	return result
+}
+
+// setTxDefaults sets the from, gas and gasPrice fields merged into the transaction
+// arguments of the calls and transactions of the session lacking them. Without
+// arguments or with null the transaction defaults are cleared.
+func (c *Console) setTxDefaults(call otto.FunctionCall) otto.Value {
+	var tx defaultValues
+	if arg := call.Argument(0); !arg.IsUndefined() && !arg.IsNull() {
+		if !arg.IsObject() || arg.Class() == "Array" {
+			throwJSException("usage: console.setTxDefaults({from: <address>, gas: <gas>, gasPrice: <price>})")
+		}
+		JSON, _ := call.Otto.Object("JSON")
+		blob, err := JSON.Call("stringify", arg)
+		if err != nil {
+			throwJSException(err.Error())
+		}
+		if tx, err = parseTxDefaults(blob.String()); err != nil {
+			throwJSException(err.Error())
+		}
+	}
+	if err := c.defaults.update(func(values *defaultValues) {
+		values.From, values.Gas, values.GasPrice = tx.From, tx.Gas, tx.GasPrice
+	}); err != nil {
+		throwJSException(err.Error())
+	}
+	if err := c.defaults.apply(call.Otto); err != nil {
+		throwJSException(err.Error())
+	}
+	return c.showDefaults(call)
+}
+
+// showDefaults returns an object reporting the defaults of the session and
+// whether they are persisted.
+func (c *Console) showDefaults(call otto.FunctionCall) otto.Value {
+	values, persist := c.defaults.current()
+
+	report := map[string]interface{}{
+		"block":   "latest",
+		"persist": persist,
+	}
+	if values.Block != "" {
+		report["block"] = values.Block
+	}
+	if values.From != nil {
+		report["from"] = values.From.Hex()
+	}
+	if values.Gas != nil {
+		report["gas"] = uint64(*values.Gas)
+	}
+	if values.GasPrice != nil {
+		report["gasPrice"] = values.GasPrice.ToInt().String()
+	}
+	blob, err := json.Marshal(report)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	JSON, _ := call.Otto.Object("JSON")
+	result, err := JSON.Call("parse", string(blob))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return result
+}
+
+// resetDefaults clears the block parameter and the transaction defaults of the
+// session, the persisted ones included if the session opted in. It returns the
+// defaults after the reset.
+func (c *Console) resetDefaults(call otto.FunctionCall) otto.Value {
+	if err := c.defaults.update(func(values *defaultValues) { *values = defaultValues{} }); err != nil {
+		throwJSException(err.Error())
+	}
+	if err := c.defaults.apply(call.Otto); err != nil {
+		throwJSException(err.Error())
+	}
+	return c.showDefaults(call)
+}
+
+// persistDefaults toggles whether the defaults of the session are persisted in
+// the data directory and loaded by the following sessions, enabling it if called
+// without arguments. It returns whether the defaults are persisted.
+func (c *Console) persistDefaults(call otto.FunctionCall) otto.Value {
+	persist := true
+	if arg := call.Argument(0); !arg.IsUndefined() {
+		if !arg.IsBoolean() {
+			throwJSException("argument must be a boolean")
+		}
+		persist, _ = arg.ToBoolean()
+	}
+	if err := c.defaults.setPersist(persist); err != nil {
+		throwJSException(err.Error())
+	}
+	result, _ := otto.ToValue(persist)
+	return result
+}
@@ -0,0 +1,187 @@
+package console
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+// DefaultsBerithAPI mocks the berith namespace, recording the block parameters
+// and transaction arguments of the calls.
+type DefaultsBerithAPI struct {
+	block *string
+	args  *map[string]interface{}
+}
+
+func (api DefaultsBerithAPI) GetBalance(address common.Address, block string) *hexutil.Big {
+	*api.block = block
+	return new(hexutil.Big)
+}
+
+func (api DefaultsBerithAPI) Call(args map[string]interface{}, block string) hexutil.Bytes {
+	*api.args, *api.block = args, block
+	return hexutil.Bytes{}
+}
+
+func (api DefaultsBerithAPI) SendTransaction(args map[string]interface{}) common.Hash {
+	*api.args = args
+	return common.Hash{}
+}
+
+// newDefaultsTester creates a console against the mocked berith namespace,
+// keeping its data in the given directory.
+func newDefaultsTester(t *testing.T, dir string, api DefaultsBerithAPI) *Console {
+	server := rpc.NewServer()
+	server.RegisterName("berith", api)
+	return newStoreTester(t, dir, "ipc:a", rpc.DialInProc(server))
+}
+
+// Tests that the defaults of the session are used by the calls not specifying
+// the block parameter or transaction fields, while explicit values win.
+func TestDefaultsPrecedence(t *testing.T) {
+	workspace, err := ioutil.TempDir("", "console-defaults-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	var (
+		block string
+		args  map[string]interface{}
+	)
+	console := newDefaultsTester(t, workspace, DefaultsBerithAPI{block: &block, args: &args})
+	defer console.Stop(false)
+
+	sender, other, recipient := common.Address{0x01}.Hex(), common.Address{0x02}.Hex(), common.Address{0x03}.Hex()
+	balance := `berith.getBalance("` + recipient + `")`
+
+	run(t, console, balance)
+	if block != "latest" {
+		t.Errorf("initial block mismatch: have %s, want latest", block)
+	}
+	if have := run(t, console, `console.setDefaultBlock(16)`); have != "0x10" {
+		t.Errorf("set block mismatch: have %s, want 0x10", have)
+	}
+	run(t, console, balance)
+	if block != "0x10" {
+		t.Errorf("default block mismatch: have %s, want 0x10", block)
+	}
+	run(t, console, `berith.getBalance("`+recipient+`", "pending")`)
+	if block != "pending" {
+		t.Errorf("explicit block mismatch: have %s, want pending", block)
+	}
+	// Transaction defaults fill the absent fields only
+	run(t, console, `console.setTxDefaults({from: "`+sender+`", gas: 21000, gasPrice: "1000000000"})`)
+	run(t, console, `berith.sendTransaction({to: "`+recipient+`"})`)
+	if args["from"] != sender || args["gas"] != "0x5208" || args["gasPrice"] != "0x3b9aca00" {
+		t.Errorf("defaulted transaction mismatch: have %v", args)
+	}
+	run(t, console, `berith.sendTransaction({from: "`+other+`", to: "`+recipient+`", gas: 50000})`)
+	if args["from"] != other || args["gas"] != "0xc350" || args["gasPrice"] != "0x3b9aca00" {
+		t.Errorf("explicit transaction mismatch: have %v", args)
+	}
+	run(t, console, `berith.call({to: "`+recipient+`"})`)
+	if args["from"] != sender || args["gas"] != "0x5208" || block != "0x10" {
+		t.Errorf("defaulted call mismatch: have %v at %s", args, block)
+	}
+	if have := run(t, console, `JSON.stringify(console.defaults())`); have != `{"block":"0x10","from":"`+sender+`","gas":21000,"gasPrice":"1000000000","persist":false}` {
+		t.Errorf("reported defaults mismatch: have %s", have)
+	}
+	// Invalid defaults are rejected, clearing them restores the web3 behavior
+	for _, statement := range []string{
+		`console.setDefaultBlock(-1)`,
+		`console.setDefaultBlock(1.5)`,
+		`console.setDefaultBlock("first")`,
+		`console.setTxDefaults({nonce: 1})`,
+		`console.setTxDefaults({from: "0x12"})`,
+		`console.setTxDefaults({gas: "lots"})`,
+		`console.setTxDefaults([])`,
+	} {
+		if _, err := console.jsre.Run(statement); err == nil {
+			t.Errorf("%s: no error", statement)
+		}
+	}
+	run(t, console, `console.setDefaultBlock(); console.setTxDefaults(null)`)
+	run(t, console, balance)
+	if block != "latest" {
+		t.Errorf("cleared block mismatch: have %s, want latest", block)
+	}
+	if _, err := console.jsre.Run(`berith.sendTransaction({to: "` + recipient + `"})`); err == nil {
+		t.Errorf("transaction without sender accepted after clearing the defaults")
+	}
+}
+
+// Tests that the defaults are persisted across sessions only if opted in.
+func TestDefaultsPersistence(t *testing.T) {
+	workspace, err := ioutil.TempDir("", "console-defaults-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	var (
+		block string
+		args  map[string]interface{}
+		api   = DefaultsBerithAPI{block: &block, args: &args}
+	)
+	console := newDefaultsTester(t, workspace, api)
+	run(t, console, `console.setDefaultBlock("0x20"); console.setTxDefaults({gas: "0x5208"})`)
+	console.Stop(false)
+
+	if _, err := os.Stat(filepath.Join(workspace, DefaultsDir)); !os.IsNotExist(err) {
+		t.Fatalf("defaults persisted without opting in: %v", err)
+	}
+	console = newDefaultsTester(t, workspace, api)
+	if have := run(t, console, `JSON.stringify(console.defaults())`); have != `{"block":"latest","persist":false}` {
+		t.Errorf("defaults of a new session mismatch: have %s", have)
+	}
+	run(t, console, `console.persistDefaults(); console.setDefaultBlock(32); console.setTxDefaults({gas: 21000})`)
+	console.Stop(false)
+
+	// The persisted defaults are loaded and applied by the next session
+	console = newDefaultsTester(t, workspace, api)
+	if have := run(t, console, `JSON.stringify(console.defaults())`); have != `{"block":"0x20","gas":21000,"persist":true}` {
+		t.Errorf("persisted defaults mismatch: have %s", have)
+	}
+	run(t, console, `berith.getBalance("`+common.Address{}.Hex()+`")`)
+	if block != "0x20" {
+		t.Errorf("persisted block not applied: have %s, want 0x20", block)
+	}
+	console.Stop(false)
+
+	// Other endpoints don't share the persisted defaults
+	server := rpc.NewServer()
+	server.RegisterName("berith", api)
+	console = newStoreTester(t, workspace, "ipc:b", rpc.DialInProc(server))
+	if have := run(t, console, `JSON.stringify(console.defaults())`); have != `{"block":"latest","persist":false}` {
+		t.Errorf("defaults of another endpoint mismatch: have %s", have)
+	}
+	console.Stop(false)
+
+	// Resetting the defaults clears the persisted ones too
+	console = newDefaultsTester(t, workspace, api)
+	if have := run(t, console, `JSON.stringify(console.resetDefaults())`); have != `{"block":"latest","persist":true}` {
+		t.Errorf("reset defaults mismatch: have %s", have)
+	}
+	console.Stop(false)
+
+	console = newDefaultsTester(t, workspace, api)
+	if have := run(t, console, `JSON.stringify(console.defaults())`); have != `{"block":"latest","persist":true}` {
+		t.Errorf("persisted defaults after reset mismatch: have %s", have)
+	}
+	if have := run(t, console, `console.persistDefaults(false)`); have != "false" {
+		t.Errorf("persistence toggle mismatch: have %s, want false", have)
+	}
+	console.Stop(false)
+
+	console = newDefaultsTester(t, workspace, api)
+	defer console.Stop(false)
+	if have := run(t, console, `JSON.stringify(console.defaults())`); have != `{"block":"latest","persist":false}` {
+		t.Errorf("defaults after opting out mismatch: have %s", have)
+	}
+}
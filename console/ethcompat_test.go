@@ -0,0 +1,88 @@
+package console
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/berith"
+)
+
+// Tests that with the compatibility layer enabled the eth methods answer as
+// their berith equivalents, and that the console flattens the eth module.
+func TestEthCompat(t *testing.T) {
+	tester := newTester(t, func(config *berith.Config) { config.EthCompat = true })
+	defer tester.Close(t)
+
+	var (
+		client  = tester.console.client
+		funded  = "Bx4BEb924F14C3681CAF654eeB8684a6434BF34B73"
+		genesis = tester.berith.BlockChain().Genesis().Hash().Hex()
+		call    = map[string]interface{}{"from": funded, "to": testAddress, "value": "0x1"}
+	)
+	for _, test := range []struct {
+		method string
+		args   []interface{}
+	}{
+		{"chainId", nil},
+		{"protocolVersion", nil},
+		{"syncing", nil},
+		{"gasPrice", nil},
+		{"accounts", nil},
+		{"coinbase", nil},
+		{"mining", nil},
+		{"blockNumber", nil},
+		{"getBalance", []interface{}{funded, "latest"}},
+		{"getCode", []interface{}{funded, "latest"}},
+		{"getStorageAt", []interface{}{funded, "0x0", "latest"}},
+		{"getTransactionCount", []interface{}{funded, "latest"}},
+		{"getBlockByNumber", []interface{}{"0x0", true}},
+		{"getBlockByHash", []interface{}{genesis, false}},
+		{"getBlockTransactionCountByNumber", []interface{}{"0x0"}},
+		{"getBlockTransactionCountByHash", []interface{}{genesis}},
+		{"getTransactionByHash", []interface{}{genesis}},
+		{"getTransactionReceipt", []interface{}{genesis}},
+		{"call", []interface{}{call, "latest"}},
+		{"estimateGas", []interface{}{call}},
+		{"getLogs", []interface{}{map[string]interface{}{"fromBlock": "0x0"}}},
+	} {
+		var have, want json.RawMessage
+		if err := client.Call(&have, "eth_"+test.method, test.args...); err != nil {
+			t.Errorf("eth_%s: failed to call: %v", test.method, err)
+			continue
+		}
+		if err := client.Call(&want, "berith_"+test.method, test.args...); err != nil {
+			t.Errorf("berith_%s: failed to call: %v", test.method, err)
+			continue
+		}
+		if !bytes.Equal(have, want) {
+			t.Errorf("eth_%s: result mismatch: have %s, want %s", test.method, have, want)
+		}
+	}
+	if have, err := tester.console.jsre.Run("eth.blockNumber === berith.blockNumber"); err != nil || have.String() != "true" {
+		t.Errorf("eth module not flattened: have %v, error %v", have, err)
+	}
+	// There is no proof-of-work to report
+	var hashrate string
+	if err := client.Call(&hashrate, "eth_hashrate"); err != nil || hashrate != "0x0" {
+		t.Errorf("eth_hashrate mismatch: have %q, error %v", hashrate, err)
+	}
+	// Methods of different semantics are not served. Keep this last, as the
+	// connection is dropped on calls of unknown methods.
+	if err := client.Call(nil, "eth_sendTransaction", call); err == nil {
+		t.Errorf("eth_sendTransaction served")
+	}
+}
+
+// Tests that without the compatibility layer no eth methods are served.
+func TestEthCompatDisabled(t *testing.T) {
+	tester := newTester(t, nil)
+	defer tester.Close(t)
+
+	if err := tester.console.client.Call(nil, "eth_blockNumber"); err == nil {
+		t.Errorf("eth_blockNumber served")
+	}
+	if have, err := tester.console.jsre.Run("typeof eth"); err != nil || have.String() != "undefined" {
+		t.Errorf("eth module flattened: have %v, error %v", have, err)
+	}
+}
@@ -0,0 +1,201 @@
+package console
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/robertkrimen/otto"
+)
+
+const (
+	exportRangeLimit       = 100000 // Maximum number of blocks exported by a single call
+	exportProgressInterval = 1000   // Number of blocks after which the progress is reported
+)
+
+// errExportAborted is returned if the user declined, or couldn't be asked, to
+// overwrite the file an export was written to.
+var errExportAborted = errors.New("export aborted")
+
+// exportHeader is the part of a block needed by the exports.
+type exportHeader struct {
+	Miner     common.Address   `json:"miner"`
+	Nonce     types.BlockNonce `json:"nonce"`
+	Timestamp *hexutil.Big     `json:"timestamp"`
+}
+
+// ExportRewards writes the block rewards credited to the behind balance of an
+// account and the behind balances released to its main balance in a range of
+// blocks to a CSV file, one row per block with a credit or release.
+func (b *bridge) ExportRewards(call otto.FunctionCall) (response otto.Value) {
+	if len(call.ArgumentList) != 4 || !call.Argument(0).IsString() {
+		throwJSException("usage: exportRewards(<address>, <from block>, <to block>, <path>)")
+	}
+	input, _ := call.Argument(0).ToString()
+	var address common.Address
+	if err := address.UnmarshalText([]byte(input)); err != nil {
+		throwJSException(fmt.Sprintf("invalid address %q: %v", input, err))
+	}
+	from, to, path := exportArguments(call)
+
+	columns := []string{"block", "timestamp", "credited", "released"}
+	return b.exportResult(b.exportCSV(path, from, to, columns, func(number uint64) ([]string, error) {
+		var rewards struct {
+			Credited *hexutil.Big `json:"credited"`
+			Released *hexutil.Big `json:"released"`
+		}
+		if err := b.client.CallContext(b.context(), &rewards, "bsrr_getRewards", address, hexutil.Uint64(number)); err != nil {
+			return nil, err
+		}
+		if rewards.Credited.ToInt().Sign() == 0 && rewards.Released.ToInt().Sign() == 0 {
+			return nil, nil
+		}
+		header, err := b.exportHeader(number)
+		if err != nil {
+			return nil, err
+		}
+		return []string{
+			strconv.FormatUint(number, 10),
+			header.Timestamp.ToInt().String(),
+			formatBer(rewards.Credited.ToInt()),
+			formatBer(rewards.Released.ToInt()),
+		}, nil
+	}))
+}
+
+// ExportElections writes the sealer of every block in a range and its rank in
+// the election of the block to a CSV file.
+func (b *bridge) ExportElections(call otto.FunctionCall) (response otto.Value) {
+	if len(call.ArgumentList) != 3 {
+		throwJSException("usage: exportElections(<from block>, <to block>, <path>)")
+	}
+	from, to, path := exportArguments(call)
+
+	columns := []string{"block", "timestamp", "sealer", "rank"}
+	return b.exportResult(b.exportCSV(path, from, to, columns, func(number uint64) ([]string, error) {
+		header, err := b.exportHeader(number)
+		if err != nil {
+			return nil, err
+		}
+		return []string{
+			strconv.FormatUint(number, 10),
+			header.Timestamp.ToInt().String(),
+			header.Miner.Hex(),
+			strconv.FormatUint(header.Nonce.Uint64(), 10),
+		}, nil
+	}))
+}
+
+// exportArguments parses the block range and the path, the last three
+// arguments of the exports.
+func exportArguments(call otto.FunctionCall) (uint64, uint64, string) {
+	args := call.ArgumentList[len(call.ArgumentList)-3:]
+	if !args[0].IsNumber() || !args[1].IsNumber() || !args[2].IsString() {
+		throwJSException("expected block numbers and a path as last arguments")
+	}
+	from, _ := args[0].ToInteger()
+	to, _ := args[1].ToInteger()
+	path, _ := args[2].ToString()
+	if from < 0 || to < 0 {
+		throwJSException("block numbers must not be negative")
+	}
+	return uint64(from), uint64(to), path
+}
+
+// exportResult converts the outcome of an export to its JavaScript result, true
+// if all blocks were exported.
+func (b *bridge) exportResult(complete bool, err error) otto.Value {
+	switch {
+	case err == errExportAborted:
+		fmt.Fprintln(b.printer, "Aborted, the existing file was left untouched")
+		return otto.FalseValue()
+	case err != nil:
+		throwJSException(err.Error())
+	case !complete:
+		return otto.FalseValue()
+	}
+	return otto.TrueValue()
+}
+
+// exportHeader retrieves the header fields of a block needed by the exports.
+func (b *bridge) exportHeader(number uint64) (*exportHeader, error) {
+	var header *exportHeader
+	if err := b.client.CallContext(b.context(), &header, "berith_getBlockByNumber", hexutil.Uint64(number), false); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	return header, nil
+}
+
+// exportCSV streams the rows of a range of blocks to a CSV file, asking before
+// overwriting an existing file. Blocks without a row are skipped. The rows are
+// flushed and the progress reported every exportProgressInterval blocks. If the
+// evaluation is interrupted, the rows of the blocks exported so far are kept and
+// false is returned.
+func (b *bridge) exportCSV(path string, from, to uint64, columns []string, row func(number uint64) ([]string, error)) (bool, error) {
+	if to < from {
+		return false, fmt.Errorf("invalid range: block %d is before block %d", to, from)
+	}
+	total := to - from + 1
+	if total > exportRangeLimit {
+		return false, fmt.Errorf("range of %d blocks exceeds the limit of %d, split the export", total, exportRangeLimit)
+	}
+	if _, err := os.Stat(path); err == nil {
+		// Without a prompter to ask, existing files are never overwritten
+		if b.prompter == nil {
+			return false, errExportAborted
+		}
+		if ok, err := b.prompter.PromptConfirm(fmt.Sprintf("Overwrite %s?", path)); err != nil || !ok {
+			return false, errExportAborted
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	out := csv.NewWriter(file)
+	if err := out.Write(columns); err != nil {
+		return false, err
+	}
+	for done := uint64(0); done < total; done++ {
+		number := from + done
+
+		record, err := row(number)
+		if err != nil {
+			out.Flush()
+			if b.context().Err() != nil {
+				if done == 0 {
+					fmt.Fprintf(b.printer, "Interrupted, no blocks exported to %s\n", path)
+				} else {
+					fmt.Fprintf(b.printer, "Interrupted, exported blocks %d-%d to %s\n", from, number-1, path)
+				}
+				return false, out.Error()
+			}
+			return false, fmt.Errorf("block %d: %v", number, err)
+		}
+		if record != nil {
+			if err := out.Write(record); err != nil {
+				return false, err
+			}
+		}
+		if (done+1)%exportProgressInterval == 0 {
+			out.Flush()
+			fmt.Fprintf(b.printer, "Exported %d of %d blocks\n", done+1, total)
+		}
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return false, err
+	}
+	fmt.Fprintf(b.printer, "Exported blocks %d-%d to %s\n", from, to, path)
+	return true, nil
+}
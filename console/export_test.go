@@ -0,0 +1,244 @@
+package console
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/rpc"
+	"github.com/robertkrimen/otto"
+)
+
+var (
+	exportSealerA = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	exportSealerB = common.HexToAddress("0x2222222222222222222222222222222222222222")
+)
+
+// exportChain is a simulated chain of blocks alternately sealed by two sealers,
+// every block crediting one BER to its sealer, released ten blocks later.
+type exportChain struct {
+	head   uint64
+	onRead func(number uint64) // Called on every block read, if set
+}
+
+// ExportBerithAPI and ExportBsrrAPI simulate the RPC services used by the
+// exports on top of an exportChain.
+type ExportBerithAPI struct{ c *exportChain }
+
+func (api ExportBerithAPI) GetBlockByNumber(number hexutil.Uint64, fullTx bool) map[string]interface{} {
+	if api.c.onRead != nil {
+		api.c.onRead(uint64(number))
+	}
+	if uint64(number) > api.c.head {
+		return nil
+	}
+	return map[string]interface{}{
+		"miner":     exportSealer(uint64(number)),
+		"nonce":     types.EncodeNonce(uint64(number)%3 + 1),
+		"timestamp": (*hexutil.Big)(new(big.Int).SetUint64(1000 + 10*uint64(number))),
+	}
+}
+
+type ExportBsrrAPI struct{ c *exportChain }
+
+func (api ExportBsrrAPI) GetRewards(address common.Address, number hexutil.Uint64) (map[string]*hexutil.Big, error) {
+	if uint64(number) > api.c.head {
+		return nil, fmt.Errorf("unknown block")
+	}
+	credited, released := new(big.Int), new(big.Int)
+	if exportSealer(uint64(number)) == address {
+		credited = common.UnitForBer
+	}
+	if number > 10 && exportSealer(uint64(number)-10) == address {
+		released = common.UnitForBer
+	}
+	return map[string]*hexutil.Big{"credited": (*hexutil.Big)(credited), "released": (*hexutil.Big)(released)}, nil
+}
+
+func exportSealer(number uint64) common.Address {
+	if number%2 == 0 {
+		return exportSealerA
+	}
+	return exportSealerB
+}
+
+// newExportTester creates a bridge to a simulated chain and a JavaScript VM
+// calling its exports.
+func newExportTester(t *testing.T, chain *exportChain, prompter UserPrompter) (*bridge, *otto.Otto, *strings.Builder) {
+	server := rpc.NewServer()
+	server.RegisterName("berith", ExportBerithAPI{chain})
+	server.RegisterName("bsrr", ExportBsrrAPI{chain})
+	client := rpc.DialInProc(server)
+
+	output := new(strings.Builder)
+	b := newBridge(client, prompter, output)
+
+	vm := otto.New()
+	vm.Set("exportRewards", b.ExportRewards)
+	vm.Set("exportElections", b.ExportElections)
+	return b, vm, output
+}
+
+// readCSV reads all records of a CSV file.
+func readCSV(t *testing.T, path string) [][]string {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open export: %v", err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not a valid CSV file: %v", err)
+	}
+	return records
+}
+
+// Tests that the sealer and rank of every block are exported, reporting the
+// progress every exportProgressInterval blocks.
+func TestExportElections(t *testing.T) {
+	dir, err := ioutil.TempDir("", "console-export-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "elections.csv")
+
+	_, vm, output := newExportTester(t, &exportChain{head: 3000}, new(scriptedPrompter))
+	result, err := vm.Run(fmt.Sprintf("exportElections(1, 2500, %q)", path))
+	if err != nil {
+		t.Fatalf("failed to export elections: %v", err)
+	}
+	if ok, _ := result.ToBoolean(); !ok {
+		t.Fatalf("export incomplete")
+	}
+	records := readCSV(t, path)
+	if len(records) != 2501 {
+		t.Fatalf("record count mismatch: have %d, want %d", len(records), 2501)
+	}
+	if want := []string{"block", "timestamp", "sealer", "rank"}; fmt.Sprint(records[0]) != fmt.Sprint(want) {
+		t.Errorf("header mismatch: have %v, want %v", records[0], want)
+	}
+	if want := []string{"5", "1050", exportSealerB.Hex(), "3"}; fmt.Sprint(records[5]) != fmt.Sprint(want) {
+		t.Errorf("record of block 5 mismatch: have %v, want %v", records[5], want)
+	}
+	if have := strings.Count(output.String(), " of 2500 blocks"); have != 2 {
+		t.Errorf("progress report count mismatch: have %d, want 2:\n%s", have, output)
+	}
+	// Ranges beyond the cap or the chain head are refused
+	if _, err := vm.Run(fmt.Sprintf("exportElections(0, %d, %q)", exportRangeLimit, path)); err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Errorf("range cap: error mismatch: have %v", err)
+	}
+	if _, err := vm.Run(fmt.Sprintf("exportElections(2990, 3010, %q)", filepath.Join(dir, "beyond.csv"))); err == nil || !strings.Contains(err.Error(), "block 3001 not found") {
+		t.Errorf("missing block: error mismatch: have %v", err)
+	}
+}
+
+// Tests that only the blocks crediting or releasing a reward to the account are
+// exported, and that an existing file is only overwritten if confirmed.
+func TestExportRewards(t *testing.T) {
+	dir, err := ioutil.TempDir("", "console-export-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rewards.csv")
+	if err := ioutil.WriteFile(path, []byte("keep"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	prompter := &scriptedPrompter{confirms: []bool{false, true}}
+	_, vm, _ := newExportTester(t, &exportChain{head: 100}, prompter)
+	call := fmt.Sprintf("exportRewards(%q, 1, 20, %q)", exportSealerB.Hex(), path)
+
+	// Declining to overwrite leaves the file untouched
+	if result, err := vm.Run(call); err != nil {
+		t.Fatalf("failed to run export: %v", err)
+	} else if ok, _ := result.ToBoolean(); ok {
+		t.Errorf("declined export reported complete")
+	}
+	if blob, _ := ioutil.ReadFile(path); string(blob) != "keep" {
+		t.Errorf("declined export overwrote the file: %q", blob)
+	}
+	// Confirming overwrites it with the rewards of the sealer
+	if result, err := vm.Run(call); err != nil {
+		t.Fatalf("failed to export rewards: %v", err)
+	} else if ok, _ := result.ToBoolean(); !ok {
+		t.Errorf("export incomplete")
+	}
+	records := readCSV(t, path)
+	want := [][]string{{"block", "timestamp", "credited", "released"}}
+	for number := 1; number <= 20; number += 2 {
+		released := "0"
+		if number > 10 {
+			released = "1"
+		}
+		want = append(want, []string{fmt.Sprint(number), fmt.Sprint(1000 + 10*number), "1", released})
+	}
+	if fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Errorf("records mismatch:\nhave %v\nwant %v", records, want)
+	}
+	// Without a prompter the file is left untouched instead of crashing
+	if err := ioutil.WriteFile(path, []byte("keep"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, vm, _ = newExportTester(t, &exportChain{head: 100}, nil)
+	if result, err := vm.Run(call); err != nil {
+		t.Fatalf("failed to run export: %v", err)
+	} else if ok, _ := result.ToBoolean(); ok {
+		t.Errorf("unconfirmed export reported complete")
+	}
+	if blob, _ := ioutil.ReadFile(path); string(blob) != "keep" {
+		t.Errorf("unconfirmed export overwrote the file: %q", blob)
+	}
+}
+
+// Tests that an interrupted export leaves a valid CSV file with the blocks
+// exported until the interrupt.
+func TestExportInterrupted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "console-export-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "elections.csv")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	chain := &exportChain{head: 3000, onRead: func(number uint64) {
+		if number == 1500 {
+			cancel()
+		}
+	}}
+	b, vm, output := newExportTester(t, chain, new(scriptedPrompter))
+	b.setContext(ctx)
+
+	result, err := vm.Run(fmt.Sprintf("exportElections(1, 2500, %q)", path))
+	if err != nil {
+		t.Fatalf("interrupted export failed: %v", err)
+	}
+	if ok, _ := result.ToBoolean(); ok {
+		t.Errorf("interrupted export reported complete")
+	}
+	if !strings.Contains(output.String(), "Interrupted, exported blocks 1-") {
+		t.Errorf("interrupt not reported: %s", output)
+	}
+	records := readCSV(t, path)
+	if len(records) < 1000 || len(records) > 1501 {
+		t.Fatalf("record count mismatch: have %d, want 1000-1501", len(records))
+	}
+	for i, record := range records[1:] {
+		if record[0] != fmt.Sprint(i+1) {
+			t.Fatalf("record %d: block mismatch: have %s, want %d", i+1, record[0], i+1)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package console
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/robertkrimen/otto"
+)
+
+// consensusError is an error of the bsrr consensus engine the console knows how
+// to remedy. The node returns the errors by their message only, so the console
+// recognises them by it and names them by a code of its own.
+type consensusError struct {
+	code    string // Name of the error for console.explainError
+	message string // Message of the error as returned by the node
+	hint    string // Short remediation printed below the error
+	explain string // Full explanation printed by console.explainError
+}
+
+// consensusErrors are the bsrr errors the console prints remediation hints for.
+// The hints may refer to console commands, as they are only shown in here.
+var consensusErrors = []consensusError{
+	{
+		code:    "unauthorized-signer",
+		message: "unauthorized signer",
+		hint:    "your berithbase is not in the current staker set, check bsrr.getBlockCreators() and bsrr.previewNextBlock(berith.coinbase)",
+		explain: "The block was signed by an address which isn't elected as a block creator on the stake target block of its epoch. " +
+			"The staker set is taken from the stake target block (see bsrr.epochInfo()), so a stake only makes its address eligible " +
+			"once the stake target block moved past the block the stake was mined in. Check that berith.coinbase is the staked " +
+			"account and that berith.getStakeBalance(berith.coinbase, 'latest') meets the stake minimum of bsrr.networkParams().",
+	},
+	{
+		code:    "out-of-rank",
+		message: "signer out of rank",
+		hint:    "your berithbase is elected but ranked beyond the allowed block creators, check bsrr.previewNextBlock(berith.coinbase)",
+		explain: "Only the best ranked stakers of an election may create a block, as many as the fork factor of bsrr.networkParams() " +
+			"allows out of all stakers. The rank is drawn anew for every block with chances weighted by the selection point, which " +
+			"berith.getSelectionPoint(berith.coinbase, 'latest') reports, so the signer may be ranked within the limit on later blocks.",
+	},
+	{
+		code:    "missing-staking-list",
+		message: "not found staking list",
+		hint:    "the staking list of the stake target block isn't stored, check bsrr.epochInfo() and wait for the node to sync",
+		explain: "The staking list of the stake target block is neither cached nor stored in the staking database, and couldn't be " +
+			"rebuilt from the blocks before it. This happens while the node is still syncing, or after the staking database was " +
+			"removed or written asynchronously before a crash. Let the node finish syncing, or resync it if the error persists.",
+	},
+	{
+		code:    "missing-state",
+		message: "state missing",
+		hint:    "the state of the stake target block was pruned, check bsrr.epochInfo() or query an archive node",
+		explain: "The elections are held on the state of the stake target block, which a pruning node only keeps for the most recent " +
+			"blocks. Historical elections are answered by archive nodes (--gcmode archive), or from the vote history of nodes " +
+			"storing it with bsrr.getHistoricalVoteResults(epoch).",
+	},
+	{
+		code:    "no-signer",
+		message: "no signer configured",
+		hint:    "the node has no signer, pass an address, e.g. bsrr.previewNextBlock(berith.coinbase), or start mining",
+		explain: "The engine only knows its signer once mining was started with miner.start(), which authorises berith.coinbase " +
+			"to seal blocks. Commands answering for the signer need an explicit address until then.",
+	},
+	{
+		code:    "double-sign",
+		message: "refusing to sign a second block at an already signed height",
+		hint:    "another block was already signed at this height, check that no other node seals with the same berithbase",
+		explain: "The engine refuses to seal two different blocks at the same height, which the other nodes would see as double " +
+			"signing. It happens when the same key seals on several nodes, or when the chain was reorganised below a height " +
+			"already signed. Make sure the berithbase account seals on a single node only.",
+	},
+	{
+		code:    "reorg-too-deep",
+		message: "staking list too far from the nearest stored list",
+		hint:    "the staking list would be rebuilt from too many blocks, check the maxReorgDepth of the genesis bsrr configuration",
+		explain: "Rebuilding a staking list replays the blocks since the nearest stored list, which the engine limits to the maxReorgDepth " +
+			"of the bsrr configuration in the genesis to stop long side chains from stalling the node. The blocks are rejected until " +
+			"a nearer staking list is stored.",
+	},
+	{
+		code:    "invalid-timestamp",
+		message: "invalid timestamp",
+		hint:    "the block was sealed earlier than the block period after its parent, check the clock of the sealing node",
+		explain: "A block must be sealed at least the block period of bsrr.networkParams() after its parent. The timestamps are taken " +
+			"from the clock of the sealing node, so keep it synchronised, e.g. with NTP.",
+	},
+	{
+		code:    "future-block",
+		message: "block in the future",
+		hint:    "the block is ahead of the local clock, check the clock of this node",
+		explain: "Blocks dated further ahead of the local clock than the tolerated drift are postponed until their time has come. " +
+			"If blocks of all peers are reported this way, the clock of this node is behind; synchronise it, e.g. with NTP.",
+	},
+}
+
+// lookupConsensusError returns the consensus error of the given code.
+func lookupConsensusError(code string) (consensusError, bool) {
+	for _, e := range consensusErrors {
+		if e.code == code {
+			return e, true
+		}
+	}
+	return consensusError{}, false
+}
+
+// consensusErrorHint returns the remediation hint of the consensus error the
+// failure is about, or an empty string if it isn't one the console knows.
+func consensusErrorHint(failure string) string {
+	for _, e := range consensusErrors {
+		if strings.Contains(failure, e.message) {
+			return fmt.Sprintf("hint: %s (see console.explainError('%s'))", e.hint, e.code)
+		}
+	}
+	return ""
+}
+
+// explainError prints the full explanation of the consensus error of the given
+// code, as named by the hints printed below the errors.
+func (c *Console) explainError(call otto.FunctionCall) otto.Value {
+	if !call.Argument(0).IsString() {
+		throwJSException("usage: console.explainError(<code>)")
+	}
+	code := call.Argument(0).String()
+
+	e, ok := lookupConsensusError(code)
+	if !ok {
+		codes := make([]string, 0, len(consensusErrors))
+		for _, e := range consensusErrors {
+			codes = append(codes, e.code)
+		}
+		sort.Strings(codes)
+		throwJSException(fmt.Sprintf("unknown error code %q, known codes: %s", code, strings.Join(codes, ", ")))
+	}
+	fmt.Fprintf(c.printer, "%s: %s\n", e.code, e.message)
+	fmt.Fprintln(c.printer, e.explain)
+	return otto.UndefinedValue()
+}
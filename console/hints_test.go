@@ -0,0 +1,76 @@
+package console
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+// HintBsrrAPI mocks the bsrr namespace, failing the block creator queries with
+// the error named by the argument.
+type HintBsrrAPI struct{}
+
+func (HintBsrrAPI) GetBlockCreators(failure string) (interface{}, error) {
+	return nil, errors.New(failure)
+}
+
+// Tests that the known consensus errors are printed with their remediation hint,
+// that other errors are printed as they are, and that the hints are explained.
+func TestConsensusErrorHints(t *testing.T) {
+	server := rpc.NewServer()
+	server.RegisterName("bsrr", HintBsrrAPI{})
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	workspace, err := ioutil.TempDir("", "console-hints-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	printer := new(bytes.Buffer)
+	console, err := New(Config{DataDir: workspace, DocRoot: workspace, Client: client, Prompter: new(scriptedPrompter), Printer: printer})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	defer console.Stop(false)
+
+	tests := []struct {
+		failure string
+		hint    string
+	}{
+		{"unauthorized signer", "console.explainError('unauthorized-signer')"},
+		{"not found staking list", "console.explainError('missing-staking-list')"},
+		{"unknown block", ""},
+	}
+	for _, tt := range tests {
+		printer.Reset()
+		console.Evaluate("bsrr.getBlockCreators('" + tt.failure + "')")
+
+		have := printer.String()
+		if !strings.HasPrefix(have, "Error: "+tt.failure) {
+			t.Errorf("%q: error not printed: have %q", tt.failure, have)
+		}
+		switch {
+		case tt.hint == "" && strings.Contains(have, "hint:"):
+			t.Errorf("%q: unexpected hint: have %q", tt.failure, have)
+		case tt.hint != "" && (!strings.Contains(have, "\nhint: ") || !strings.Contains(have, tt.hint)):
+			t.Errorf("%q: hint mismatch: have %q, want %q", tt.failure, have, tt.hint)
+		}
+	}
+	printer.Reset()
+	console.Evaluate("console.explainError('out-of-rank')")
+	if have := printer.String(); !strings.HasPrefix(have, "out-of-rank: signer out of rank\n") || !strings.Contains(have, "bsrr.networkParams()") {
+		t.Errorf("explanation mismatch: have %q", have)
+	}
+	printer.Reset()
+	console.Evaluate("console.explainError('out-of-luck')")
+	if have := printer.String(); !strings.Contains(have, `unknown error code "out-of-luck"`) || !strings.Contains(have, "unauthorized-signer") {
+		t.Errorf("unknown code error mismatch: have %q", have)
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/prometheus/util/flock"
+)
+
+// historyLockSuffix is appended to the path of the history file to get the
+// path of the lock file guarding it.
+const historyLockSuffix = ".lock"
+
+// XDGHistoryPath returns the location of the console history following the XDG
+// base directory layout: $XDG_STATE_HOME/berith/history, falling back to
+// ~/.local/state/berith/history if the variable is unset.
+func XDGHistoryPath() string {
+	if state := os.Getenv("XDG_STATE_HOME"); state != "" {
+		return filepath.Join(state, "berith", HistoryFile)
+	}
+	return filepath.Join(homeDir(), ".local", "state", "berith", HistoryFile)
+}
+
+// resolveHistoryPath returns the absolute path of the history file to use for
+// the given configuration, creating its parent directory if needed.
+func resolveHistoryPath(config Config) (string, error) {
+	if config.HistoryPath == "" {
+		return filepath.Join(config.DataDir, HistoryFile), nil
+	}
+	path, err := filepath.Abs(expandHome(config.HistoryPath))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// checkHistoryPath ensures the history file, if it exists, is not a symbolic link
+// leading outside of its own directory. Writing the history on Stop would follow
+// such a link and could overwrite arbitrary files.
+func checkHistoryPath(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return nil
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		// Dangling link, resolve it manually to see where a write would end up
+		link, lerr := os.Readlink(path)
+		if lerr != nil {
+			return lerr
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(dir, link)
+		}
+		target = filepath.Clean(link)
+	}
+	if filepath.Dir(target) != dir {
+		return fmt.Errorf("refusing to use history file %s: symlink points outside its directory to %s", path, target)
+	}
+	return nil
+}
+
+// lockHistory takes the advisory lock guarding the history file, failing if
+// another console holds it.
+func lockHistory(path string) (flock.Releaser, error) {
+	lock, _, err := flock.New(path + historyLockSuffix)
+	return lock, err
+}
+
+// readHistory loads the entries of the history file, none if it doesn't exist.
+func readHistory(path string) ([]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) || len(content) == 0 {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(content), "\n"), nil
+}
+
+// writeHistory overwrites the history file with the given entries.
+func writeHistory(path string, history []string) error {
+	if err := checkHistoryPath(path); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, []byte(strings.Join(history, "\n")), 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600) // Force 0600, even if it was different previously
+}
+
+// mergeHistory appends the entries not yet contained in the history file to it,
+// keeping the entries written by other consoles meanwhile.
+func mergeHistory(path string, entries []string) error {
+	history, err := readHistory(path)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(history))
+	for _, entry := range history {
+		known[entry] = true
+	}
+	for _, entry := range entries {
+		if !known[entry] {
+			history = append(history, entry)
+			known[entry] = true
+		}
+	}
+	return writeHistory(path, history)
+}
+
+// expandHome replaces a leading ~ in the path with the current user's home
+// directory.
+func expandHome(path string) string {
+	if path == "~" {
+		return homeDir()
+	}
+	if strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~\\") {
+		if home := homeDir(); home != "" {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+// homeDir returns the current user's home directory.
+func homeDir() string {
+	if home := os.Getenv("HOME"); home != "" {
+		return home
+	}
+	if usr, err := user.Current(); err == nil {
+		return usr.HomeDir
+	}
+	return ""
+}
+
+// stringHeaderSize is the size of a string header in a slice of strings.
+const stringHeaderSize = 2 * strconv.IntSize / 8
+
+// scrollback is the scrollback history of a console, keeping the newest limit
+// entries. The oldest entries are dropped by advancing the start of the live
+// entries, and the slice is compacted once as many entries were dropped as the
+// limit, so that adding an entry takes amortized constant time and the slice
+// never holds more than twice the limit.
+type scrollback struct {
+	entries []string // Entries of the history, the live ones starting at start
+	start   int      // Index of the oldest live entry
+	base    int      // Number of live entries loaded from the file, the rest are from this session
+	limit   int      // Maximum number of live entries
+	size    int      // Total length of the live entries in bytes
+}
+
+// newScrollback creates an empty scrollback history of the given limit, the
+// default if it's not positive.
+func newScrollback(limit int) *scrollback {
+	if limit <= 0 {
+		limit = DefaultHistorySize
+	}
+	return &scrollback{limit: limit}
+}
+
+// load replaces the history with the newest entries loaded from the file.
+func (s *scrollback) load(entries []string) {
+	if len(entries) > s.limit {
+		entries = entries[len(entries)-s.limit:]
+	}
+	s.entries, s.start, s.base, s.size = append([]string(nil), entries...), 0, len(entries), 0
+	for _, entry := range entries {
+		s.size += len(entry)
+	}
+}
+
+// add appends the command unless it repeats the newest entry, dropping the
+// oldest entry if the limit is exceeded. It returns whether the command was
+// added and whether the entries were compacted on the way.
+func (s *scrollback) add(command string) (added bool, compacted bool) {
+	if n := len(s.entries); n > s.start && s.entries[n-1] == command {
+		return false, false
+	}
+	s.entries = append(s.entries, command)
+	s.size += len(command)
+
+	if len(s.entries)-s.start <= s.limit {
+		return true, false
+	}
+	s.size -= len(s.entries[s.start])
+	s.entries[s.start] = "" // Release the dropped entry
+	s.start++
+	if s.base > 0 {
+		s.base--
+	}
+	if s.start < s.limit {
+		return true, false
+	}
+	n := copy(s.entries, s.entries[s.start:])
+	for i := n; i < len(s.entries); i++ {
+		s.entries[i] = ""
+	}
+	s.entries, s.start = s.entries[:n], 0
+	return true, true
+}
+
+// clear drops all entries.
+func (s *scrollback) clear() {
+	s.entries, s.start, s.base, s.size = nil, 0, 0, 0
+}
+
+// list returns the live entries, oldest first.
+func (s *scrollback) list() []string {
+	return s.entries[s.start:]
+}
+
+// session returns the live entries entered in this session.
+func (s *scrollback) session() []string {
+	return s.list()[s.base:]
+}
+
+// len returns the number of live entries.
+func (s *scrollback) len() int {
+	return len(s.entries) - s.start
+}
+
+// memory returns the approximate number of bytes held by the history.
+func (s *scrollback) memory() int {
+	return s.size + cap(s.entries)*stringHeaderSize
+}
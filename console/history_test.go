@@ -0,0 +1,349 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"berith-chain/internals/jsre"
+
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+// Tests that the history location defaults to the data directory and can be
+// overridden, creating the missing parent directories.
+func TestHistoryPathOverride(t *testing.T) {
+	workspace, err := ioutil.TempDir("", "console-history-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	path, err := resolveHistoryPath(Config{DataDir: workspace})
+	if err != nil {
+		t.Fatalf("failed to resolve default history path: %v", err)
+	}
+	if want := filepath.Join(workspace, HistoryFile); path != want {
+		t.Errorf("default history path mismatch: have %s, want %s", path, want)
+	}
+	override := filepath.Join(workspace, "state", "berith", "history")
+	if path, err = resolveHistoryPath(Config{DataDir: workspace, HistoryPath: override}); err != nil {
+		t.Fatalf("failed to resolve history path override: %v", err)
+	}
+	if path != override {
+		t.Errorf("overridden history path mismatch: have %s, want %s", path, override)
+	}
+	info, err := os.Stat(filepath.Dir(override))
+	if err != nil {
+		t.Fatalf("history directory not created: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0700 {
+		t.Errorf("history directory permission mismatch: have %v, want %v", info.Mode().Perm(), os.FileMode(0700))
+	}
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", workspace)
+	if path, err = resolveHistoryPath(Config{HistoryPath: "~/history"}); err != nil {
+		t.Fatalf("failed to resolve home relative history path: %v", err)
+	}
+	if want := filepath.Join(workspace, "history"); path != want {
+		t.Errorf("home relative history path mismatch: have %s, want %s", path, want)
+	}
+}
+
+// Tests that a history file symlinked outside of its directory is refused,
+// while links staying within the directory are accepted.
+func TestHistoryPathSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on windows")
+	}
+	workspace, err := ioutil.TempDir("", "console-history-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	dir := filepath.Join(workspace, "datadir")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatalf("failed to create data directory: %v", err)
+	}
+	path := filepath.Join(dir, HistoryFile)
+	if err := checkHistoryPath(path); err != nil {
+		t.Errorf("missing history file refused: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(workspace, "victim"), path); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	if err := checkHistoryPath(path); err == nil {
+		t.Errorf("history file linking outside of its directory accepted")
+	}
+	if _, err := New(Config{DataDir: dir}); err == nil {
+		t.Errorf("console created with history file linking outside of its directory")
+	}
+	os.Remove(path)
+	if err := os.Symlink("history.old", path); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	if err := checkHistoryPath(path); err != nil {
+		t.Errorf("history file linking within its directory refused: %v", err)
+	}
+}
+
+// Tests that a freshly written history file is only accessible by its owner.
+func TestHistoryFilePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permission bits are not supported on windows")
+	}
+	workspace, err := ioutil.TempDir("", "console-history-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	console := &Console{
+		jsre:     jsre.New("", ioutil.Discard),
+		histPath: filepath.Join(workspace, HistoryFile),
+		history:  newScrollback(0),
+	}
+	console.history.add("berith.blockNumber")
+	if err := console.Stop(false); err != nil {
+		t.Fatalf("failed to stop console: %v", err)
+	}
+	info, err := os.Stat(console.histPath)
+	if err != nil {
+		t.Fatalf("history file not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("history file permission mismatch: have %v, want %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+}
+
+// historySession is a console sharing its history file with other consoles.
+type historySession struct {
+	*Console
+	output *strings.Builder
+}
+
+// newHistorySession starts a console using the history file of the data
+// directory, simulating the commands entered into it.
+func newHistorySession(t *testing.T, dir string, client *rpc.Client) *historySession {
+	output := new(strings.Builder)
+	console, err := New(Config{
+		DataDir:  dir,
+		DocRoot:  dir,
+		Client:   client,
+		Prompter: new(scriptedPrompter),
+		Printer:  output,
+	})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	return &historySession{console, output}
+}
+
+func (s *historySession) enter(commands ...string) {
+	for _, command := range commands {
+		s.history.add(command)
+	}
+}
+
+func (s *historySession) stop(t *testing.T) {
+	if err := s.Stop(false); err != nil {
+		t.Fatalf("failed to stop console: %v", err)
+	}
+}
+
+// Tests that consoles sharing a history file don't lose each other's entries,
+// whether they run one after the other or overlap.
+func TestHistoryLock(t *testing.T) {
+	workspace, err := ioutil.TempDir("", "console-history-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	client := rpc.DialInProc(rpc.NewServer())
+	defer client.Close()
+
+	path := filepath.Join(workspace, HistoryFile)
+	check := func(step string, want ...string) {
+		t.Helper()
+		if have, _ := readHistory(path); !reflect.DeepEqual(have, want) {
+			t.Errorf("%s: history mismatch: have %q, want %q", step, have, want)
+		}
+	}
+	// Sequential sessions each take the lock and extend the history
+	first := newHistorySession(t, workspace, client)
+	first.enter("a", "b")
+	first.stop(t)
+	check("first session", "a", "b")
+
+	second := newHistorySession(t, workspace, client)
+	if second.histLock == nil {
+		t.Fatalf("second sequential session didn't take the lock")
+	}
+	second.enter("c")
+	second.stop(t)
+	check("second session", "a", "b", "c")
+
+	// Overlapping sessions merge their entries if the owner exits first
+	owner := newHistorySession(t, workspace, client)
+	guest := newHistorySession(t, workspace, client)
+	if owner.histLock == nil || guest.histLock != nil {
+		t.Fatalf("lock mismatch: owner locked %v, guest locked %v", owner.histLock != nil, guest.histLock != nil)
+	}
+	owner.enter("d")
+	guest.enter("e", "a", "d", "f")
+	owner.stop(t)
+	guest.stop(t)
+	check("overlapping sessions", "a", "b", "c", "d", "e", "f")
+
+	// A guest exiting before the owner leaves the file to the owner
+	owner = newHistorySession(t, workspace, client)
+	guest = newHistorySession(t, workspace, client)
+	owner.enter("g")
+	guest.enter("h")
+	guest.stop(t)
+	if !strings.Contains(guest.output.String(), "still in use by another console") {
+		t.Errorf("skipped merge not reported: %q", guest.output)
+	}
+	check("guest exited first", "a", "b", "c", "d", "e", "f")
+	owner.stop(t)
+	check("owner exited last", "a", "b", "c", "d", "e", "f", "g")
+}
+
+// historyPrompter feeds commands to the console, keeping the history set by the
+// console as the terminal prompter would.
+type historyPrompter struct {
+	hookedPrompter
+	commands []string
+	history  []string
+	peak     int // Maximum number of history entries held at once
+}
+
+func (p *historyPrompter) PromptInput(prompt string) (string, error) {
+	if len(p.commands) == 0 {
+		return "", io.EOF
+	}
+	command := p.commands[0]
+	p.commands = p.commands[1:]
+	return command, nil
+}
+
+func (p *historyPrompter) SetHistory(history []string) {
+	p.history = append(p.history, history...)
+	if len(p.history) > p.peak {
+		p.peak = len(p.history)
+	}
+}
+
+func (p *historyPrompter) AppendHistory(command string) {
+	p.SetHistory([]string{command})
+}
+
+func (p *historyPrompter) ClearHistory() {
+	p.history = nil
+}
+
+// Tests that the scrollback history of a long running console and the history
+// of its prompter stay bounded, while consecutive duplicates and password
+// related commands are still left out.
+func TestHistoryBounded(t *testing.T) {
+	workspace, err := ioutil.TempDir("", "console-history-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	client := rpc.DialInProc(rpc.NewServer())
+	defer client.Close()
+
+	// Every 10th command repeats the previous one, every 7th is password related
+	prompter := new(historyPrompter)
+	for i := 0; i < 100000; i++ {
+		switch {
+		case i%10 == 9:
+			prompter.commands = append(prompter.commands, prompter.commands[i-1])
+		case i%7 == 6:
+			prompter.commands = append(prompter.commands, fmt.Sprintf("personal.unlockAccount(%d)", i))
+		default:
+			prompter.commands = append(prompter.commands, fmt.Sprintf("%d", i))
+		}
+	}
+	const limit = 100
+	console, err := New(Config{
+		DataDir:     workspace,
+		DocRoot:     workspace,
+		Client:      client,
+		Prompter:    prompter,
+		Printer:     ioutil.Discard,
+		HistorySize: limit,
+	})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	console.Interactive()
+
+	history := console.history.list()
+	if len(history) != limit {
+		t.Fatalf("history length mismatch: have %d, want %d", len(history), limit)
+	}
+	if last := history[len(history)-1]; last != "99998" {
+		t.Errorf("newest entry mismatch: have %s, want 99998", last)
+	}
+	for i, entry := range history {
+		if strings.HasPrefix(entry, "personal.") {
+			t.Errorf("password related entry %d stored: %s", i, entry)
+		}
+		if i > 0 && entry == history[i-1] {
+			t.Errorf("consecutive duplicate entry %d stored: %s", i, entry)
+		}
+	}
+	// The history structures hold at most twice the limit
+	if size := cap(console.history.entries); size > 4*limit {
+		t.Errorf("history capacity unbounded: have %d, want at most %d", size, 4*limit)
+	}
+	if memory := console.history.memory(); memory > 4*limit*(stringHeaderSize+len("99998")) {
+		t.Errorf("history memory unbounded: %d bytes", memory)
+	}
+	if prompter.peak > 2*limit {
+		t.Errorf("prompter history unbounded: peak of %d entries, want at most %d", prompter.peak, 2*limit)
+	}
+	if tail := prompter.history[len(prompter.history)-limit:]; !reflect.DeepEqual(tail, history) {
+		t.Errorf("prompter history out of sync with the console's")
+	}
+	stats, err := console.jsre.Run("JSON.stringify(console.historyStats())")
+	if err != nil {
+		t.Fatalf("failed to retrieve history stats: %v", err)
+	}
+	if want := fmt.Sprintf(`{"entries":%d,"limit":%d,"memory":%d}`, limit, limit, console.history.memory()); stats.String() != want {
+		t.Errorf("history stats mismatch: have %s, want %s", stats, want)
+	}
+	if err := console.Stop(false); err != nil {
+		t.Fatalf("failed to stop console: %v", err)
+	}
+	if saved, _ := readHistory(filepath.Join(workspace, HistoryFile)); !reflect.DeepEqual(saved, history) {
+		t.Errorf("saved history mismatch: have %d entries, want %d", len(saved), len(history))
+	}
+}
@@ -0,0 +1,221 @@
+package console
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/robertkrimen/otto"
+)
+
+// nonceError matches the rejections of transactions whose nonce is already used,
+// after which the nonces handed out by the console are synced with the node.
+var nonceError = regexp.MustCompile(`nonce too low|replacement transaction underpriced`)
+
+// pendingNonce returns the nonce of the next transaction of the account as
+// suggested by the node, counting its pending transactions.
+func (b *bridge) pendingNonce(address common.Address) (uint64, error) {
+	var nonce hexutil.Uint64
+	if err := b.client.CallContext(b.context(), &nonce, "berith_getTransactionCount", address, "pending"); err != nil {
+		return 0, err
+	}
+	return uint64(nonce), nil
+}
+
+// nextNonce hands out the nonce of the next transaction of the account, the
+// larger of the pending nonce of the node and the nonce following the last one
+// handed out, so that transactions sent around the console are skipped.
+func (b *bridge) nextNonce(address common.Address) (uint64, error) {
+	b.nonceLock.Lock()
+	defer b.nonceLock.Unlock()
+
+	next, err := b.pendingNonce(address)
+	if err != nil {
+		return 0, err
+	}
+	if local, ok := b.nonces[address]; ok && local > next {
+		next = local
+	}
+	b.nonces[address] = next + 1
+	return next, nil
+}
+
+// releaseNonce takes back the nonce of a transaction which was rejected, unless
+// later nonces were handed out meanwhile.
+func (b *bridge) releaseNonce(address common.Address, nonce uint64) {
+	b.nonceLock.Lock()
+	defer b.nonceLock.Unlock()
+
+	if next, ok := b.nonces[address]; ok && next == nonce+1 {
+		b.nonces[address] = nonce
+	}
+}
+
+// syncNonce moves the local counter of the account up to the pending nonce of
+// the node, in case transactions were sent around the console.
+func (b *bridge) syncNonce(address common.Address) error {
+	b.nonceLock.Lock()
+	defer b.nonceLock.Unlock()
+
+	pending, err := b.pendingNonce(address)
+	if err != nil {
+		return err
+	}
+	if next, ok := b.nonces[address]; !ok || next < pending {
+		b.nonces[address] = pending
+	}
+	return nil
+}
+
+// resetNonce drops the local counter of the account, so that the next nonce is
+// the pending nonce of the node again.
+func (b *bridge) resetNonce(address common.Address) {
+	b.nonceLock.Lock()
+	defer b.nonceLock.Unlock()
+
+	delete(b.nonces, address)
+}
+
+// NonceManager returns an object handing out the nonces of the transactions of
+// the given account, for scripts sending many transactions in a row without
+// racing the nonce suggestion of the node. The nonces are counted by the bridge,
+// so all managers of the same account share them:
+//
+//	next()    - the nonce of the next transaction, counted up locally from the
+//	            pending nonce of the node unless the node is ahead
+//	pending() - the pending nonce of the account as suggested by the node
+//	reset()   - restarts counting from the pending nonce of the node
+//
+// The manager can be passed as the nonceManager option of berith.sendTransaction
+// to assign the nonces automatically.
+func (b *bridge) NonceManager(call otto.FunctionCall) (response otto.Value) {
+	if !call.Argument(0).IsString() {
+		throwJSException("first argument must be the account to manage the nonces of")
+	}
+	input, _ := call.Argument(0).ToString()
+	var address common.Address
+	if err := address.UnmarshalText([]byte(input)); err != nil {
+		throwJSException(fmt.Sprintf("invalid address %q: %v", input, err))
+	}
+
+	manager, _ := call.Otto.Object(`({})`)
+	manager.Set("address", address.Hex())
+	manager.Set("next", func(call otto.FunctionCall) otto.Value {
+		nonce, err := b.nextNonce(address)
+		if err != nil {
+			throwJSException(err.Error())
+		}
+		val, _ := otto.ToValue(nonce)
+		return val
+	})
+	manager.Set("pending", func(call otto.FunctionCall) otto.Value {
+		nonce, err := b.pendingNonce(address)
+		if err != nil {
+			throwJSException(err.Error())
+		}
+		val, _ := otto.ToValue(nonce)
+		return val
+	})
+	manager.Set("reset", func(call otto.FunctionCall) otto.Value {
+		b.resetNonce(address)
+		return otto.UndefinedValue()
+	})
+	return manager.Value()
+}
+
+// SendTransaction is a wrapper around the berith.sendTransaction RPC method
+// (saved in jeth.sendTransaction) assigning the nonce of the transaction if a
+// nonceManager is given in its options. A transaction rejected for a nonce
+// already used is retried once, after syncing the nonces with the node.
+func (b *bridge) SendTransaction(call otto.FunctionCall) (response otto.Value) {
+	manager := otto.UndefinedValue()
+	if tx := call.Argument(0); tx.IsObject() {
+		manager, _ = tx.Object().Get("nonceManager")
+	}
+	if manager.IsUndefined() || manager.IsNull() {
+		args := make([]interface{}, len(call.ArgumentList))
+		for i, arg := range call.ArgumentList {
+			args[i] = arg
+		}
+		val, err := call.Otto.Call("jeth.sendTransaction", nil, args...)
+		if err != nil {
+			throwJSException(err.Error())
+		}
+		return val
+	}
+	address, args := b.managedTransaction(call, manager)
+
+	var (
+		hash otto.Value
+		err  error
+	)
+	for retried := false; ; retried = true {
+		var nonce uint64
+		if nonce, err = b.nextNonce(address); err != nil {
+			break
+		}
+		args.Set("nonce", nonce)
+		if hash, err = call.Otto.Call("jeth.sendTransaction", nil, args); err == nil {
+			break
+		}
+		if !nonceError.MatchString(err.Error()) {
+			b.releaseNonce(address, nonce)
+			break
+		}
+		if retried || b.syncNonce(address) != nil {
+			break
+		}
+	}
+	// Report the outcome to the callback (if supplied) or directly
+	if fn := call.Argument(1); fn.Class() == "Function" {
+		if err != nil {
+			fn.Call(otto.NullValue(), call.Otto.MakeCustomError("Error", err.Error()))
+		} else {
+			fn.Call(otto.NullValue(), otto.NullValue(), hash)
+		}
+		return otto.UndefinedValue()
+	}
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return hash
+}
+
+// managedTransaction returns the account of the nonce manager and a copy of the
+// transaction options without the manager, sent from the account.
+func (b *bridge) managedTransaction(call otto.FunctionCall, manager otto.Value) (common.Address, *otto.Object) {
+	var (
+		account = otto.UndefinedValue()
+		address common.Address
+	)
+	if manager.IsObject() {
+		account, _ = manager.Object().Get("address")
+	}
+	if !account.IsString() || address.UnmarshalText([]byte(account.String())) != nil {
+		throwJSException("nonceManager must be created by berith.nonceManager")
+	}
+
+	tx := call.Argument(0).Object()
+	args, _ := call.Otto.Object(`({})`)
+	for _, key := range tx.Keys() {
+		switch key {
+		case "nonceManager":
+			continue
+		case "nonce":
+			throwJSException("nonce and nonceManager can't be given together")
+		}
+		val, _ := tx.Get(key)
+		args.Set(key, val)
+	}
+	from, _ := tx.Get("from")
+	if from.IsUndefined() {
+		args.Set("from", address.Hex())
+		return address, args
+	}
+	var sender common.Address
+	if !from.IsString() || sender.UnmarshalText([]byte(from.String())) != nil || sender != address {
+		throwJSException(fmt.Sprintf("nonceManager of %s can't send from %v", address.Hex(), from))
+	}
+	return address, args
+}
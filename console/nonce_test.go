@@ -0,0 +1,206 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/crypto"
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+// NonceBerithAPI mocks a node whose transaction pool reorders and promotes the
+// transactions of an account lazily, so the pending nonce it suggests lags
+// behind the transactions already sent.
+type NonceBerithAPI struct {
+	lock     sync.Mutex
+	pool     map[uint64]bool // Nonces of the transactions in the pool
+	sent     []uint64        // Nonces of the transactions accepted from the console
+	rejected int             // Number of transactions rejected for a used nonce
+}
+
+type NonceSendArgs struct {
+	From  common.Address  `json:"from"`
+	Nonce *hexutil.Uint64 `json:"nonce"`
+}
+
+func (api *NonceBerithAPI) Accounts() []common.Address { return nil }
+
+func (api *NonceBerithAPI) GetTransactionCount(address common.Address, block string) hexutil.Uint64 {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	return hexutil.Uint64(len(api.pool) / 2)
+}
+
+func (api *NonceBerithAPI) SendTransaction(args NonceSendArgs) (common.Hash, error) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	if args.Nonce == nil {
+		return common.Hash{}, errors.New("nonce not given")
+	}
+	nonce := uint64(*args.Nonce)
+	if api.pool[nonce] {
+		api.rejected++
+		return common.Hash{}, errors.New("replacement transaction underpriced")
+	}
+	api.pool[nonce] = true
+	api.sent = append(api.sent, nonce)
+	return crypto.Keccak256Hash([]byte(fmt.Sprint(nonce))), nil
+}
+
+// newNonceTester creates a console attached to a node answering the nonce and
+// transaction requests from the given mock.
+func newNonceTester(t *testing.T, api *NonceBerithAPI) (*Console, func()) {
+	workspace, err := ioutil.TempDir("", "console-nonce-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("berith", api); err != nil {
+		t.Fatalf("failed to register berith service: %v", err)
+	}
+	client := rpc.DialInProc(server)
+
+	console, err := New(Config{
+		DataDir:  workspace,
+		DocRoot:  workspace,
+		Client:   client,
+		Prompter: new(scriptedPrompter),
+		Printer:  ioutil.Discard,
+	})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	return console, func() {
+		console.Stop(false)
+		client.Close()
+		os.RemoveAll(workspace)
+	}
+}
+
+// Tests that a script sending transactions in rapid succession through a nonce
+// manager has them all land with sequential nonces, even though the node lags
+// behind in suggesting them and another transaction of the account was sent
+// around the console.
+func TestNonceManager(t *testing.T) {
+	// The transaction of nonce 20 is sent around the console
+	api := &NonceBerithAPI{pool: map[uint64]bool{20: true}}
+	console, stop := newNonceTester(t, api)
+	defer stop()
+
+	address := common.HexToAddress(testAddress).Hex()
+	run(t, console, fmt.Sprintf(`
+		var manager = berith.nonceManager("%s");
+		var hashes = {};
+		for (var i = 0; i < 50; i++) {
+			hashes[berith.sendTransaction({from: "%s", to: "%s", value: 1, nonceManager: manager})] = true;
+		}`, address, address, address))
+
+	if have := run(t, console, "Object.keys(hashes).length"); have != "50" {
+		t.Errorf("unique transaction hashes mismatch: have %s, want 50", have)
+	}
+	if api.rejected != 1 {
+		t.Errorf("rejected transactions mismatch: have %d, want 1", api.rejected)
+	}
+	sent := append([]uint64{}, api.sent...)
+	sort.Slice(sent, func(i, j int) bool { return sent[i] < sent[j] })
+	for i, want := 0, uint64(0); i < len(sent); i, want = i+1, want+1 {
+		if want == 20 {
+			want++
+		}
+		if sent[i] != want {
+			t.Fatalf("sent nonces not sequential: %v", api.sent)
+		}
+	}
+	if len(sent) != 50 {
+		t.Errorf("sent transactions mismatch: have %d, want 50", len(sent))
+	}
+	// The node's suggestion is reported as is, and taken up again on reset
+	if have := run(t, console, "manager.pending()"); have != "25" {
+		t.Errorf("pending nonce mismatch: have %s, want 25", have)
+	}
+	if have := run(t, console, "manager.next()"); have != "51" {
+		t.Errorf("next nonce mismatch: have %s, want 51", have)
+	}
+	if have := run(t, console, "manager.reset(); manager.next()"); have != "25" {
+		t.Errorf("next nonce after reset mismatch: have %s, want 25", have)
+	}
+	// Transactions sent around the console moving the pending nonce of the node
+	// past the local counter are skipped
+	api.lock.Lock()
+	for nonce := uint64(100); nonce < 250; nonce++ {
+		api.pool[nonce] = true
+	}
+	api.lock.Unlock()
+	if have := run(t, console, "manager.next()"); have != "100" {
+		t.Errorf("next nonce behind the node mismatch: have %s, want 100", have)
+	}
+	// Transactions without a manager are sent as given
+	run(t, console, fmt.Sprintf(`berith.sendTransaction({from: "%s", nonce: 70})`, address))
+	if last := api.sent[len(api.sent)-1]; last != 70 {
+		t.Errorf("unmanaged nonce mismatch: have %d, want 70", last)
+	}
+	// Transactions conflicting with the manager are refused
+	for _, tx := range []string{
+		fmt.Sprintf(`{from: "%s", nonce: 3, nonceManager: manager}`, address),
+		fmt.Sprintf(`{from: "%s", nonceManager: manager}`, common.Address{1}.Hex()),
+		`{nonceManager: {}}`,
+	} {
+		if _, err := console.jsre.Run("berith.sendTransaction(" + tx + ")"); err == nil {
+			t.Errorf("%s: transaction accepted", tx)
+		}
+	}
+}
+
+// Tests that the nonces handed out to concurrent senders of the same account are
+// unique and leave no gaps, whatever order they are sent in.
+func TestNextNonceConcurrent(t *testing.T) {
+	api := &NonceBerithAPI{pool: map[uint64]bool{0: true, 1: true}}
+	server := rpc.NewServer()
+	if err := server.RegisterName("berith", api); err != nil {
+		t.Fatalf("failed to register berith service: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	var (
+		b       = newBridge(client, nil, ioutil.Discard)
+		address = common.HexToAddress(testAddress)
+		nonces  = make(chan uint64, 50)
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := b.nextNonce(address)
+			if err != nil {
+				t.Errorf("failed to get nonce: %v", err)
+			}
+			nonces <- nonce
+		}()
+	}
+	wg.Wait()
+	close(nonces)
+
+	seen := make(map[uint64]bool)
+	for nonce := range nonces {
+		if seen[nonce] {
+			t.Errorf("nonce %d handed out twice", nonce)
+		}
+		seen[nonce] = true
+	}
+	for nonce := uint64(1); nonce < 51; nonce++ {
+		if !seen[nonce] {
+			t.Errorf("nonce %d not handed out", nonce)
+		}
+	}
+}
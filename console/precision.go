@@ -0,0 +1,161 @@
+package console
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/robertkrimen/otto"
+)
+
+const (
+	precisionWarn  = "warn"  // Print a warning on lossy BigNumber conversions
+	precisionThrow = "throw" // Throw an exception on lossy BigNumber conversions
+	precisionOff   = "off"   // Leave the BigNumber conversions alone
+
+	// berDecimals is the number of decimals of an amount of wei in BER.
+	berDecimals = 18
+)
+
+// precisionGuardJS patches the BigNumber.valueOf, which is invoked whenever a
+// BigNumber is implicitly converted to a primitive, to report the values beyond
+// the integers exactly representable by a JavaScript number. The returned
+// function is called with the Go callback reporting them.
+const precisionGuardJS = `(function(report) {
+	var valueOf = BigNumber.prototype.valueOf;
+	var limit = new BigNumber('9007199254740991');
+	BigNumber.prototype.valueOf = function() {
+		var value = valueOf.call(this);
+		if (this.abs().gt(limit)) {
+			report(this.toFixed());
+		}
+		return value;
+	};
+})`
+
+// guardPrecision installs the precision guard of the given mode on the
+// BigNumbers of bignumber.js, which web3 uses for all its quantities.
+func (c *Console) guardPrecision(mode string) error {
+	if mode == precisionOff {
+		return nil
+	}
+	guard, err := c.jsre.Run(precisionGuardJS)
+	if err != nil {
+		return err
+	}
+	report := func(call otto.FunctionCall) otto.Value {
+		msg := fmt.Sprintf("BigNumber %s converted to a number loses precision, use toString(10) or berith.formatBer instead", call.Argument(0).String())
+		if mode == precisionThrow {
+			throwJSException(msg)
+		}
+		fmt.Fprintf(c.printer, "WARNING: %s\n", msg)
+		return otto.UndefinedValue()
+	}
+	_, err = guard.Call(otto.NullValue(), report)
+	return err
+}
+
+// formatUnits formats an integer amount of the smallest unit as a decimal of the
+// given number of decimals, exactly and without trailing zeros.
+func formatUnits(value *big.Int, decimals int) string {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	quo, rem := new(big.Int).QuoRem(new(big.Int).Abs(value), unit, new(big.Int))
+
+	text := quo.String()
+	if rem.Sign() != 0 {
+		fraction := fmt.Sprintf("%0*s", decimals, rem.String())
+		text += "." + strings.TrimRight(fraction, "0")
+	}
+	if value.Sign() < 0 {
+		text = "-" + text
+	}
+	return text
+}
+
+// parseUnits parses a decimal of at most the given number of decimals into an
+// integer amount of the smallest unit.
+func parseUnits(text string, decimals int) (*big.Int, error) {
+	if strings.Contains(text, "/") {
+		return nil, fmt.Errorf("invalid amount %q", text)
+	}
+	amount, ok := new(big.Rat).SetString(strings.TrimSpace(text))
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", text)
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	amount.Mul(amount, new(big.Rat).SetInt(unit))
+	if !amount.IsInt() {
+		return nil, fmt.Errorf("amount %q has more than %d decimals", text, decimals)
+	}
+	return amount.Num(), nil
+}
+
+// unitsDecimals returns the number of decimals passed to berith.formatBer and
+// berith.parseBer, which default to the decimals of BER.
+func unitsDecimals(call otto.FunctionCall, usage string) int {
+	arg := call.Argument(1)
+	if arg.IsUndefined() {
+		return berDecimals
+	}
+	decimals, err := arg.ToInteger()
+	if !arg.IsNumber() || err != nil || decimals < 0 || decimals > 78 {
+		throwJSException(usage)
+	}
+	return int(decimals)
+}
+
+// formatAmount formats an integer amount of wei, passed as a BigNumber, a
+// decimal or hex string or a number, as an exact decimal string in BER or the
+// given number of decimals.
+func (c *Console) formatAmount(call otto.FunctionCall) otto.Value {
+	const usage = "usage: berith.formatBer(<weiValue>, [decimals])"
+
+	arg := call.Argument(0)
+	var text string
+	switch {
+	case arg.IsString():
+		text = arg.String()
+	case arg.IsNumber():
+		number, _ := arg.ToFloat()
+		text = strconv.FormatFloat(number, 'f', -1, 64)
+	case arg.IsObject():
+		// Render BigNumbers in full, as their toString switches to exponents
+		fixed, err := arg.Object().Call("toFixed")
+		if err != nil {
+			throwJSException(usage)
+		}
+		text = fixed.String()
+	default:
+		throwJSException(usage)
+	}
+	decimals := unitsDecimals(call, usage)
+
+	value, ok := new(big.Int).SetString(text, 0)
+	if !ok {
+		throwJSException(fmt.Sprintf("invalid integer amount %q", text))
+	}
+	result, _ := otto.ToValue(formatUnits(value, decimals))
+	return result
+}
+
+// parseAmount parses a decimal string in BER or the given number of decimals
+// into a BigNumber of wei, exactly.
+func (c *Console) parseAmount(call otto.FunctionCall) otto.Value {
+	const usage = "usage: berith.parseBer(<amount>, [decimals])"
+
+	if !call.Argument(0).IsString() && !call.Argument(0).IsNumber() {
+		throwJSException(usage)
+	}
+	decimals := unitsDecimals(call, usage)
+
+	value, err := parseUnits(call.Argument(0).String(), decimals)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	result, err := call.Otto.Call("new BigNumber", nil, value.String())
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return result
+}
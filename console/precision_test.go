@@ -0,0 +1,161 @@
+package console
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+// Tests that amounts are formatted exactly, however large they are.
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		value    string
+		decimals int
+		want     string
+	}{
+		{"0", 18, "0"},
+		{"1", 18, "0.000000000000000001"},
+		{"1500000000000000000", 18, "1.5"},
+		{"1000000000000000000000", 18, "1000"},
+		{"123456789012345678901234567890", 18, "123456789012.34567890123456789"},
+		{"-123456789012345678901234567891", 18, "-123456789012.345678901234567891"},
+		{"115792089237316195423570985008687907853269984665640564039457584007913129639935", 18, "115792089237316195423570985008687907853269984665640564039457.584007913129639935"},
+		{"12345", 0, "12345"},
+		{"12345", 2, "123.45"},
+	}
+	for _, tt := range tests {
+		value, _ := new(big.Int).SetString(tt.value, 10)
+		if have := formatUnits(value, tt.decimals); have != tt.want {
+			t.Errorf("%s (%d decimals): formatting mismatch: have %s, want %s", tt.value, tt.decimals, have, tt.want)
+		}
+	}
+}
+
+// Tests that decimal amounts are parsed exactly, and that the ones not fitting the
+// decimals are rejected.
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		text     string
+		decimals int
+		want     string
+		fail     bool
+	}{
+		{text: "1.5", decimals: 18, want: "1500000000000000000"},
+		{text: "0.000000000000000001", decimals: 18, want: "1"},
+		{text: "123456789012.34567890123456789", decimals: 18, want: "123456789012345678901234567890"},
+		{text: "-2", decimals: 18, want: "-2000000000000000000"},
+		{text: "1e3", decimals: 2, want: "100000"},
+		{text: "0.0000000000000000001", decimals: 18, fail: true},
+		{text: "1.5", decimals: 0, fail: true},
+		{text: "1/3", decimals: 18, fail: true},
+		{text: "ber", decimals: 18, fail: true},
+	}
+	for _, tt := range tests {
+		value, err := parseUnits(tt.text, tt.decimals)
+		switch {
+		case tt.fail && err == nil:
+			t.Errorf("%s (%d decimals): parsed invalid amount as %v", tt.text, tt.decimals, value)
+		case !tt.fail && err != nil:
+			t.Errorf("%s (%d decimals): failed to parse amount: %v", tt.text, tt.decimals, err)
+		case !tt.fail && value.String() != tt.want:
+			t.Errorf("%s (%d decimals): parsing mismatch: have %v, want %s", tt.text, tt.decimals, value, tt.want)
+		}
+	}
+}
+
+// PrecisionBerithAPI mocks the berith namespace, holding a balance beyond the
+// integers exactly representable by JavaScript numbers.
+type PrecisionBerithAPI struct{}
+
+func (PrecisionBerithAPI) GetBalance(address common.Address, block string) *hexutil.Big {
+	balance, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	return (*hexutil.Big)(balance)
+}
+
+// newPrecisionConsole creates a console with the given precision guard, attached
+// to the mocked berith namespace.
+func newPrecisionConsole(t *testing.T, mode string) (*Console, *bytes.Buffer, func()) {
+	server := rpc.NewServer()
+	server.RegisterName("berith", PrecisionBerithAPI{})
+	client := rpc.DialInProc(server)
+
+	workspace, err := ioutil.TempDir("", "console-precision-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	printer := new(bytes.Buffer)
+	console, err := New(Config{DataDir: workspace, DocRoot: workspace, Client: client, Prompter: new(scriptedPrompter), Printer: printer, PrecisionGuard: mode})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	return console, printer, func() {
+		console.Stop(false)
+		client.Close()
+		os.RemoveAll(workspace)
+	}
+}
+
+// Tests that large balances are formatted and parsed exactly by the console, and
+// that web3 keeps working with the precision guard installed.
+func TestPrecisionGuard(t *testing.T) {
+	const balance = "berith.getBalance('Bx0000000000000000000000000000000000000001', 'latest')"
+
+	for _, mode := range []string{precisionWarn, precisionThrow} {
+		console, printer, closer := newPrecisionConsole(t, mode)
+
+		// The exact conversions and the web3 calls don't trip the guard
+		exact := []struct {
+			expr string
+			want string
+		}{
+			{"berith.formatBer(" + balance + ")", `"123456789012.34567890123456789"`},
+			{"berith.formatBer('0x1bc16d674ec80000')", `"2"`},
+			{"berith.formatBer(" + balance + ", 20)", `"1234567890.1234567890123456789"`},
+			{"berith.parseBer('1.5').toString(10)", `"1500000000000000000"`},
+			{"berith.parseBer('123456789012.34567890123456789').eq(" + balance + ")", "true"},
+			{"web3.toHex(" + balance + ")", `"0x18ee90ff6c373e0ee4e3f0ad2"`},
+			{"web3.fromWei(" + balance + ", 'ber').toString(10)", `"123456789012.34567890123456789"`},
+			{"JSON.stringify(" + balance + ")", `"\"1.2345678901234567890123456789e+29\""`},
+			{balance + ".plus(1).toString(10)", `"123456789012345678901234567891"`},
+			{"new BigNumber(1000) / 10", "100"},
+		}
+		for _, tt := range exact {
+			printer.Reset()
+			console.Evaluate(tt.expr)
+			if have := strings.TrimSpace(printer.String()); have != tt.want {
+				t.Errorf("%s: %s: result mismatch: have %s, want %s", mode, tt.expr, have, tt.want)
+			}
+		}
+		// The implicit conversion of the balance trips the guard
+		printer.Reset()
+		console.Evaluate(balance + " / 1e18")
+
+		have := printer.String()
+		switch mode {
+		case precisionWarn:
+			if !strings.HasPrefix(have, "WARNING: BigNumber 123456789012345678901234567890 converted to a number loses precision") || !strings.Contains(have, "\n123456789012.34") {
+				t.Errorf("%s: lossy conversion output mismatch: have %q", mode, have)
+			}
+		case precisionThrow:
+			if !strings.HasPrefix(have, "BigNumber 123456789012345678901234567890 converted to a number loses precision") {
+				t.Errorf("%s: lossy conversion output mismatch: have %q", mode, have)
+			}
+		}
+		closer()
+	}
+	// Without the guard, the conversion is left alone
+	console, printer, closer := newPrecisionConsole(t, precisionOff)
+	defer closer()
+
+	console.Evaluate(balance + " / 1e18")
+	if have := printer.String(); strings.Contains(have, "WARNING") || strings.Contains(have, "Error") {
+		t.Errorf("%s: lossy conversion output mismatch: have %q", precisionOff, have)
+	}
+}
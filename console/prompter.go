@@ -66,6 +66,19 @@ type UserPrompter interface {
 // "Word"}, "!!!") to have "Hello, world!!!".
 type WordCompleter func(line string, pos int) (string, []string, string)
 
+// HintCompleter takes the currently edited line with the cursor position and
+// returns a hint to display to the user without changing the line, e.g. the
+// signature of the method being called, or an empty string if there is none.
+type HintCompleter func(line string, pos int) string
+
+// HintPrompter is implemented by the prompters which can display hints to the
+// user when tab is pressed, in addition to completing words.
+type HintPrompter interface {
+	// SetHintCompleter sets the function that the prompter will call to fetch a
+	// hint when the user presses tab. Hints take precedence over word completion.
+	SetHintCompleter(hinter HintCompleter)
+}
+
 // terminalPrompter is a UserPrompter backed by the liner package. It supports
 // prompting the user for various input, among others for non-echoing password
 // input.
@@ -75,6 +88,8 @@ type terminalPrompter struct {
 	supported  bool
 	normalMode liner.ModeApplier
 	rawMode    liner.ModeApplier
+	completer  WordCompleter
+	hinter     HintCompleter
 }
 
 // newTerminalPrompter creates a liner based user input prompter working off the
@@ -168,5 +183,31 @@ func (p *terminalPrompter) ClearHistory() {
 // SetWordCompleter sets the completion function that the prompter will call to
 // fetch completion candidates when the user presses tab.
 func (p *terminalPrompter) SetWordCompleter(completer WordCompleter) {
-	p.State.SetWordCompleter(liner.WordCompleter(completer))
+	p.completer = completer
+	p.State.SetWordCompleter(p.complete)
+}
+
+// SetHintCompleter sets the function that the prompter will call to fetch a
+// hint when the user presses tab.
+func (p *terminalPrompter) SetHintCompleter(hinter HintCompleter) {
+	p.hinter = hinter
+	p.State.SetWordCompleter(p.complete)
+}
+
+// complete prints the hint for the edited line dimmed below the prompt if there
+// is one, leaving the line untouched, or completes the word being edited.
+func (p *terminalPrompter) complete(line string, pos int) (string, []string, string) {
+	if p.hinter != nil {
+		if hint := p.hinter(line, pos); hint != "" {
+			if p.supported {
+				hint = "\x1b[2m" + hint + "\x1b[0m"
+			}
+			fmt.Print("\r\n" + hint + "\r\n")
+			return line[:pos], []string{""}, line[pos:]
+		}
+	}
+	if p.completer != nil {
+		return p.completer(line, pos)
+	}
+	return line[:pos], nil, line[pos:]
 }
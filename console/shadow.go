@@ -0,0 +1,179 @@
+package console
+
+import (
+	"sort"
+	"strings"
+)
+
+// consoleNamespaces are the globals of the console protected from being
+// overwritten regardless of the modules served, next to those flattened from web3.
+var consoleNamespaces = []string{"jeth", "web3", "console", "admin", "berith", "personal"}
+
+// jsPunctuators are the multi-character punctuators of JavaScript, longest first
+// so that the scanner picks the longest match.
+var jsPunctuators = []string{
+	">>>=", "...", "===", "!==", "**=", "<<=", ">>=", ">>>",
+	"=>", "==", "!=", "<=", ">=", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=",
+	"&&", "||", "++", "--", "?.", "**", "<<", ">>",
+}
+
+// jsAssignments are the operators assigning the target on their left.
+var jsAssignments = map[string]bool{
+	"=": true, "+=": true, "-=": true, "*=": true, "/=": true, "%=": true, "**=": true,
+	"<<=": true, ">>=": true, ">>>=": true, "&=": true, "|=": true, "^=": true,
+}
+
+// jsDeclarations are the keywords declaring the names following them.
+var jsDeclarations = map[string]bool{"var": true, "let": true, "const": true, "function": true}
+
+// tokenizeJS splits a JavaScript statement into identifiers, numbers and
+// punctuators, dropping comments, string and template literals. It is only
+// precise enough for the console to spot the names a statement assigns.
+func tokenizeJS(statement string) []string {
+	var tokens []string
+	for i := 0; i < len(statement); {
+		switch c := statement[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case strings.HasPrefix(statement[i:], "//"):
+			if end := strings.IndexByte(statement[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(statement)
+			}
+
+		case strings.HasPrefix(statement[i:], "/*"):
+			if end := strings.Index(statement[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(statement)
+			}
+
+		case c == '"' || c == '\'' || c == '`':
+			// Literals are kept as an opaque token, so they don't join their neighbours
+			for i++; i < len(statement) && statement[i] != c; i++ {
+				if statement[i] == '\\' {
+					i++
+				}
+			}
+			i++
+			tokens = append(tokens, string(c))
+
+		case isIdentChar(c) || (c >= '0' && c <= '9'):
+			start := i
+			for i < len(statement) && (isIdentChar(statement[i]) || (statement[i] >= '0' && statement[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, statement[start:i])
+
+		default:
+			token := statement[i : i+1]
+			for _, punct := range jsPunctuators {
+				if strings.HasPrefix(statement[i:], punct) {
+					token = punct
+					break
+				}
+			}
+			i += len(token)
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// isIdentChar reports whether the byte may start an identifier. Bytes of
+// multi-byte characters are taken as such, as they are mostly letters.
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c > 0x7f
+}
+
+// shadowedNames returns the protected names the statement declares or assigns,
+// be it directly, within a declaration list or by a destructuring assignment.
+// Properties of the protected names, like berith.defaultAccount, may be assigned.
+func shadowedNames(statement string, protected map[string]bool) []string {
+	tokens := tokenizeJS(statement)
+	token := func(i int) string {
+		if i < 0 || i >= len(tokens) {
+			return ""
+		}
+		return tokens[i]
+	}
+	found := make(map[string]bool)
+
+	// declDepth tracks the bracket depth of the declaration list being scanned,
+	// in which the names following a comma are declared too
+	depth, declDepth := 0, -1
+	for i, tok := range tokens {
+		switch tok {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+			if depth < declDepth {
+				declDepth = -1
+			}
+		case ";":
+			if depth <= declDepth {
+				declDepth = -1
+			}
+		}
+		if jsDeclarations[tok] && tok != "function" {
+			declDepth = depth
+		}
+		if !protected[tok] {
+			continue
+		}
+		prev, next := token(i-1), token(i+1)
+		switch {
+		case prev == "." || prev == "?.":
+			// Property access, not the protected name itself
+		case jsDeclarations[prev], jsAssignments[next], next == "++", next == "--", prev == "++", prev == "--":
+			found[tok] = true
+		case prev == "," && depth == declDepth:
+			found[tok] = true
+		}
+	}
+	// Destructuring patterns are the brackets on the left of a plain assignment,
+	// unless they are a computed member access like accounts[0] = ...
+	for i, tok := range tokens {
+		if tok != "=" || (token(i-1) != "]" && token(i-1) != "}") {
+			continue
+		}
+		start, nesting := i-1, 0
+		for ; start >= 0; start-- {
+			switch tokens[start] {
+			case "]", "}":
+				nesting++
+			case "[", "{":
+				nesting--
+			}
+			if nesting == 0 {
+				break
+			}
+		}
+		if start < 0 {
+			continue
+		}
+		if prev := token(start - 1); tokens[start] == "[" && (prev == ")" || prev == "]" || (prev != "" && isIdentChar(prev[0]) && !jsDeclarations[prev])) {
+			continue
+		}
+		for j := start + 1; j < i-1; j++ {
+			name := tokens[j]
+			if !protected[name] {
+				continue
+			}
+			prev, next := token(j-1), token(j+1)
+			if prev == "." || prev == "?." || prev == "=" || next == ":" {
+				continue // property access, default value or key of the source object
+			}
+			found[name] = true
+		}
+	}
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
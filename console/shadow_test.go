@@ -0,0 +1,110 @@
+package console
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests that the statements declaring or assigning the protected namespaces are
+// spotted, and that those only using them or assigning their properties aren't.
+func TestShadowedNames(t *testing.T) {
+	protected := map[string]bool{"berith": true, "personal": true, "admin": true}
+	tests := []struct {
+		statement string
+		shadowed  []string
+	}{
+		// Direct assignments
+		{"berith = 5", []string{"berith"}},
+		{"personal = null;", []string{"personal"}},
+		{"admin += 1", []string{"admin"}},
+		{"berith++", []string{"berith"}},
+		{"if (berith = undefined) {}", []string{"berith"}},
+		{"x = berith = 1", []string{"berith"}},
+
+		// Declarations
+		{"var berith = 5", []string{"berith"}},
+		{"let personal", []string{"personal"}},
+		{"const admin = {}", []string{"admin"}},
+		{"var a = 1, berith", []string{"berith"}},
+		{"var a, b, personal;", []string{"personal"}},
+		{"function admin() {}", []string{"admin"}},
+
+		// Destructuring
+		{"var {berith} = web3", []string{"berith"}},
+		{"let [a, personal] = list", []string{"personal"}},
+		{"({a: admin, b} = obj)", []string{"admin"}},
+		{"[berith, ...personal] = list", []string{"berith", "personal"}},
+		{"var {berith: eth} = web3", nil},
+		{"var [a = berith] = list", nil},
+
+		// Legitimate uses
+		{"berith.defaultAccount = berith.accounts[0]", nil},
+		{"personal.unlockAccount(berith.coinbase)", nil},
+		{"web3.admin = admin", nil},
+		{"accounts[berith.accounts.length - 1] = 1", nil},
+		{"var x = f(a, berith)", nil},
+		{"var a = 1; g(b, berith)", nil},
+		{"berith == 5", nil},
+		{"x = {berith: 1}", nil},
+		{"function f(berith) { return berith.blockNumber }", nil},
+		{"'berith = 5'", nil},
+		{"x = \"var personal = 1\"", nil},
+		{"// berith = 5", nil},
+		{"/* personal = null */ admin.peers", nil},
+		{"var berithTotal = 5", nil},
+	}
+	for _, tt := range tests {
+		shadowed := shadowedNames(tt.statement, protected)
+		if len(shadowed) == 0 {
+			shadowed = nil
+		}
+		if !reflect.DeepEqual(shadowed, tt.shadowed) {
+			t.Errorf("%q: shadowed names mismatch: have %v, want %v", tt.statement, shadowed, tt.shadowed)
+		}
+	}
+}
+
+// Tests that statements shadowing the console namespaces are only evaluated
+// once confirmed in interactive mode, while other modes warn and proceed.
+func TestConfirmShadowing(t *testing.T) {
+	tester := newTester(t, nil)
+	defer tester.Close(t)
+
+	// Non-interactive evaluations warn but proceed
+	if err := tester.console.Evaluate("var admin = 5"); err != nil {
+		t.Fatalf("failed to evaluate: %v", err)
+	}
+	if output := tester.output.String(); !strings.Contains(output, "WARNING") || !strings.Contains(output, "admin") {
+		t.Errorf("shadowing not warned about: %s", output)
+	}
+	if have := run(t, tester.console, "admin"); have != "5" {
+		t.Errorf("statement not evaluated: admin is %s", have)
+	}
+	// Interactive evaluations require a confirmation
+	prompter := &scriptedPrompter{confirms: []bool{false, true}}
+	tester.console.prompter = prompter
+	tester.console.interactive = true
+
+	tester.console.Evaluate("personal = null")
+	if have := run(t, tester.console, "typeof personal"); have != "object" || run(t, tester.console, "personal === null") != "false" {
+		t.Errorf("unconfirmed statement evaluated")
+	}
+	tester.console.Evaluate("[berith] = [7]")
+	if len(prompter.prompts) != 2 {
+		t.Errorf("confirmation prompts mismatch: have %d, want 2", len(prompter.prompts))
+	}
+	// Property assignments are not confirmed
+	tester.output.Reset()
+	tester.console.Evaluate("personal.custom = 1")
+	if len(prompter.prompts) != 2 || strings.Contains(tester.output.String(), "WARNING") {
+		t.Errorf("property assignment confirmed: %s", tester.output.String())
+	}
+	// Shadowing can be allowed for the rest of the session
+	run(t, tester.console, "console.allowShadowing()")
+	tester.output.Reset()
+	tester.console.Evaluate("personal = 3")
+	if have := run(t, tester.console, "personal"); have != "3" || strings.Contains(tester.output.String(), "WARNING") {
+		t.Errorf("shadowing not allowed: personal is %s, output %s", have, tester.output.String())
+	}
+}
@@ -0,0 +1,136 @@
+package console
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"berith-chain/internals/web3ext"
+)
+
+// coreSignatures are the parameter names of the methods built into web3.js,
+// which the web3ext modules don't describe.
+var coreSignatures = map[string][]string{
+	"berith.getBalance":               {"address", "blockNumber"},
+	"berith.getStorageAt":             {"address", "position", "blockNumber"},
+	"berith.getCode":                  {"address", "blockNumber"},
+	"berith.getBlock":                 {"blockHashOrNumber", "returnFullTxs"},
+	"berith.getUncle":                 {"blockHashOrNumber", "uncleIndex"},
+	"berith.getCompilers":             {},
+	"berith.getBlockTransactionCount": {"blockHashOrNumber"},
+	"berith.getBlockUncleCount":       {"blockHashOrNumber"},
+	"berith.getTransaction":           {"txHash"},
+	"berith.getTransactionFromBlock":  {"blockHashOrNumber", "txIndex"},
+	"berith.getTransactionReceipt":    {"txHash"},
+	"berith.getTransactionCount":      {"address", "blockNumber"},
+	"berith.sendRawTransaction":       {"signedTx"},
+	"berith.sendTransaction":          {"tx"},
+	"berith.vote":                     {"tx"},
+	"berith.signTransaction":          {"tx"},
+	"berith.sign":                     {"address", "data"},
+	"berith.call":                     {"call", "blockNumber"},
+	"berith.estimateGas":              {"call"},
+	"berith.submitWork":               {"nonce", "powHash", "digest"},
+	"berith.getWork":                  {},
+	"berith.decodeLogs":               {"receipt", "abi"},
+	"berith.setupValidator":           {"account"},
+	"berith.exportRewards":            {"address", "fromBlock", "toBlock", "path"},
+	"berith.exportElections":          {"fromBlock", "toBlock", "path"},
+	"bsrr.epochInfo":                  {"blockNumber"},
+	"debug.blockTimings":              {"lastN"},
+	"admin.benchmark":                 {"method", "params", "iterations"},
+	"personal.newAccount":             {"password"},
+	"personal.importRawKey":           {"privateKey", "password"},
+	"personal.sign":                   {"data", "address", "password"},
+	"personal.ecRecover":              {"data", "signature"},
+	"personal.unlockAccount":          {"address", "password", "duration"},
+	"personal.sendTransaction":        {"tx", "password"},
+	"personal.lockAccount":            {"address"},
+}
+
+// formatterParams names the parameters of the web3ext methods by the input
+// formatter applied to them.
+var formatterParams = map[string]string{
+	"web3._extend.formatters.inputAddressFormatter":            "address",
+	"web3._extend.formatters.inputBlockNumberFormatter":        "blockNumber",
+	"web3._extend.formatters.inputDefaultBlockNumberFormatter": "blockNumber",
+	"web3._extend.formatters.inputTransactionFormatter":        "tx",
+	"web3._extend.formatters.inputCallFormatter":               "call",
+	"web3._extend.utils.fromDecimal":                           "value",
+	"web3._extend.utils.toHex":                                 "value",
+}
+
+var (
+	moduleProperty  = regexp.MustCompile(`property:\s*'(\w+)'`)
+	moduleMethod    = regexp.MustCompile(`(?s)new web3\._extend\.Method\(\{(.*?)\}\)`)
+	methodName      = regexp.MustCompile(`name:\s*'([\w.]+)'`)
+	methodParams    = regexp.MustCompile(`params:\s*(\d+)`)
+	methodFormatter = regexp.MustCompile(`(?s)inputFormatter:\s*\[(.*?)\]`)
+)
+
+// signatures maps the known console methods to the names of their parameters.
+var signatures = loadSignatures(web3ext.Modules)
+
+// loadSignatures collects the parameters of the methods defined by the given
+// web3ext modules, on top of the methods built into web3.js.
+func loadSignatures(modules map[string]string) map[string][]string {
+	sigs := make(map[string][]string)
+	for _, module := range modules {
+		property := moduleProperty.FindStringSubmatch(module)
+		if property == nil {
+			continue
+		}
+		for _, method := range moduleMethod.FindAllStringSubmatch(module, -1) {
+			name := methodName.FindStringSubmatch(method[1])
+			if name == nil {
+				continue
+			}
+			params := 0
+			if match := methodParams.FindStringSubmatch(method[1]); match != nil {
+				params, _ = strconv.Atoi(match[1])
+			}
+			var formatters []string
+			if match := methodFormatter.FindStringSubmatch(method[1]); match != nil {
+				formatters = strings.Split(match[1], ",")
+			}
+			args := make([]string, params)
+			for i := range args {
+				args[i] = fmt.Sprintf("arg%d", i+1)
+				if i < len(formatters) {
+					if arg, ok := formatterParams[strings.TrimSpace(formatters[i])]; ok {
+						args[i] = arg
+					}
+				}
+			}
+			sigs[property[1]+"."+name[1]] = args
+		}
+	}
+	for method, args := range coreSignatures {
+		sigs[method] = args
+	}
+	return sigs
+}
+
+// signatureHint returns the signature of the method called by the line, if
+// the cursor is right after the opening parenthesis of a known method.
+func signatureHint(sigs map[string][]string, line string, pos int) string {
+	if pos < 2 || pos > len(line) || line[pos-1] != '(' {
+		return ""
+	}
+	// Find the start of the called method, e.g. in nested calls like
+	// berith.getBalance(berith.getBlock(<tab>
+	start := pos - 1
+	for ; start > 0; start-- {
+		if c := line[start-1]; c == '.' || c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			continue
+		}
+		break
+	}
+	method := strings.TrimPrefix(line[start:pos-1], "web3.")
+	args, ok := sigs[method]
+	if !ok {
+		return ""
+	}
+	return method[strings.LastIndex(method, ".")+1:] + "(" + strings.Join(args, ", ") + ")"
+}
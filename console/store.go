@@ -0,0 +1,206 @@
+package console
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/util/flock"
+	"github.com/robertkrimen/otto"
+)
+
+const (
+	// StoreDir is the directory within the data directory holding the storage
+	// of console scripts, one file per endpoint.
+	StoreDir = "scriptstore"
+
+	storeQuota       = 1024 * 1024           // Maximum size of the storage of an endpoint in bytes
+	storeLockTimeout = time.Second           // Time to wait for another console to release the storage
+	storeLockRetry   = 10 * time.Millisecond // Interval of the attempts to lock the storage
+)
+
+var (
+	errStoreQuota  = fmt.Errorf("storage quota of %d bytes exceeded", storeQuota)
+	errStoreLocked = errors.New("storage is locked by another console")
+)
+
+// scriptStore is the persistent key-value storage offered to console scripts
+// as console.store. Values are kept as JSON in a file shared by all consoles
+// attached to the same endpoint, re-read on every access so that no console
+// overwrites the keys set by another.
+type scriptStore struct {
+	path string     // Path of the JSON file holding the values
+	lock sync.Mutex // Serializes the accesses of this console
+}
+
+// newScriptStore creates the storage of the scripts run against the endpoint,
+// kept in the given directory.
+func newScriptStore(dir string, endpoint string) *scriptStore {
+	return &scriptStore{path: filepath.Join(dir, endpointFile(endpoint))}
+}
+
+// endpointFile returns the name of the JSON file holding the data of a console
+// attached to the endpoint.
+func endpointFile(endpoint string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(endpoint)))[:16] + ".json"
+}
+
+// access runs fn on the stored values while holding the storage locked, and
+// persists them if fn reports a change.
+func (s *scriptStore) access(fn func(values map[string]json.RawMessage) (bool, error)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	// Wait for other consoles to finish their access
+	var (
+		lock     flock.Releaser
+		err      error
+		deadline = time.Now().Add(storeLockTimeout)
+	)
+	for {
+		if lock, _, err = flock.New(s.path + ".lock"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return errStoreLocked
+		}
+		time.Sleep(storeLockRetry)
+	}
+	defer lock.Release()
+
+	values := make(map[string]json.RawMessage)
+	if blob, err := ioutil.ReadFile(s.path); err == nil {
+		if err := json.Unmarshal(blob, &values); err != nil {
+			return fmt.Errorf("corrupt storage %s: %v", s.path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	changed, err := fn(values)
+	if err != nil || !changed {
+		return err
+	}
+	blob, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	if len(blob) > storeQuota {
+		return errStoreQuota
+	}
+	// Replace the file at once so that a crash never leaves it half written
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Get returns a copy of the value stored under the key, undefined if none is.
+func (s *scriptStore) Get(call otto.FunctionCall) (response otto.Value) {
+	key := storeKey(call, 1, "get(<key>)")
+
+	var value json.RawMessage
+	if err := s.access(func(values map[string]json.RawMessage) (bool, error) {
+		value = values[key]
+		return false, nil
+	}); err != nil {
+		throwJSException(err.Error())
+	}
+	if value == nil {
+		return otto.UndefinedValue()
+	}
+	// Parsing the JSON hands out a fresh copy the script may modify freely
+	JSON, _ := call.Otto.Object("JSON")
+	parsed, err := JSON.Call("parse", string(value))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return parsed
+}
+
+// Set stores a copy of the value under the key. Only values serializable to JSON
+// are accepted.
+func (s *scriptStore) Set(call otto.FunctionCall) (response otto.Value) {
+	key := storeKey(call, 2, "set(<key>, <value>)")
+
+	JSON, _ := call.Otto.Object("JSON")
+	blob, err := JSON.Call("stringify", call.Argument(1))
+	if err != nil {
+		throwJSException(fmt.Sprintf("value is not serializable to JSON: %v", err))
+	}
+	if !blob.IsString() {
+		throwJSException("value is not serializable to JSON")
+	}
+	value := json.RawMessage(blob.String())
+	if err := s.access(func(values map[string]json.RawMessage) (bool, error) {
+		values[key] = value
+		return true, nil
+	}); err != nil {
+		throwJSException(err.Error())
+	}
+	return otto.UndefinedValue()
+}
+
+// Delete removes the value stored under the key, returning whether there was one.
+func (s *scriptStore) Delete(call otto.FunctionCall) (response otto.Value) {
+	key := storeKey(call, 1, "delete(<key>)")
+
+	var existed bool
+	if err := s.access(func(values map[string]json.RawMessage) (bool, error) {
+		_, existed = values[key]
+		delete(values, key)
+		return existed, nil
+	}); err != nil {
+		throwJSException(err.Error())
+	}
+	response, _ = call.Otto.ToValue(existed)
+	return response
+}
+
+// Keys returns the sorted keys of the stored values.
+func (s *scriptStore) Keys(call otto.FunctionCall) (response otto.Value) {
+	var keys []string
+	if err := s.access(func(values map[string]json.RawMessage) (bool, error) {
+		keys = make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		return false, nil
+	}); err != nil {
+		throwJSException(err.Error())
+	}
+	sort.Strings(keys)
+
+	blob, err := json.Marshal(keys)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	JSON, _ := call.Otto.Object("JSON")
+	response, err = JSON.Call("parse", string(blob))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return response
+}
+
+// storeKey validates the arguments of a storage call, returning the key.
+func storeKey(call otto.FunctionCall, args int, usage string) string {
+	if len(call.ArgumentList) != args || !call.Argument(0).IsString() {
+		throwJSException("usage: console.store." + usage)
+	}
+	key, _ := call.Argument(0).ToString()
+	if key == "" {
+		throwJSException("key must not be empty")
+	}
+	return key
+}
@@ -0,0 +1,158 @@
+package console
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+// newStoreTester creates a console attached to the given endpoint, storing the
+// values of its scripts in the data directory.
+func newStoreTester(t *testing.T, dir string, endpoint string, client *rpc.Client) *Console {
+	console, err := New(Config{
+		DataDir:  dir,
+		DocRoot:  dir,
+		Endpoint: endpoint,
+		Client:   client,
+		Prompter: new(scriptedPrompter),
+		Printer:  ioutil.Discard,
+	})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	return console
+}
+
+// run evaluates the statement in the console, returning its result as a string.
+func run(t *testing.T, console *Console, statement string) string {
+	t.Helper()
+	val, err := console.jsre.Run(statement)
+	if err != nil {
+		t.Fatalf("%s: failed to run: %v", statement, err)
+	}
+	return val.String()
+}
+
+// Tests that stored values persist across console restarts, separately for each
+// endpoint, and are copied across the Go/JavaScript boundary.
+func TestStorePersistence(t *testing.T) {
+	workspace, err := ioutil.TempDir("", "console-store-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	client := rpc.DialInProc(rpc.NewServer())
+	defer client.Close()
+
+	console := newStoreTester(t, workspace, "ipc:a", client)
+	run(t, console, `var state = {block: 5, alerts: [1, 2]}; console.store.set("state", state); console.store.set("threshold", 0.5)`)
+
+	// Modifying the stored object or a retrieved copy leaves the stored value intact
+	run(t, console, `state.block = 6; console.store.get("state").alerts.push(3)`)
+	if have := run(t, console, `JSON.stringify(console.store.get("state"))`); have != `{"alerts":[1,2],"block":5}` && have != `{"block":5,"alerts":[1,2]}` {
+		t.Errorf("stored value modified: %s", have)
+	}
+	if have := run(t, console, `console.store.delete("threshold")`); have != "true" {
+		t.Errorf("delete of existing key mismatch: have %s, want true", have)
+	}
+	if have := run(t, console, `console.store.delete("threshold")`); have != "false" {
+		t.Errorf("delete of missing key mismatch: have %s, want false", have)
+	}
+	console.Stop(false)
+
+	// A restarted console sees the values, a console of another endpoint doesn't
+	console = newStoreTester(t, workspace, "ipc:a", client)
+	if have := run(t, console, `console.store.get("state").block`); have != "5" {
+		t.Errorf("value not persisted: have %s, want 5", have)
+	}
+	if have := run(t, console, `JSON.stringify(console.store.keys())`); have != `["state"]` {
+		t.Errorf("keys mismatch: have %s, want [\"state\"]", have)
+	}
+	console.Stop(false)
+
+	console = newStoreTester(t, workspace, "ipc:b", client)
+	defer console.Stop(false)
+	if have := run(t, console, `console.store.get("state") === undefined`); have != "true" {
+		t.Errorf("value of another endpoint visible")
+	}
+}
+
+// Tests that values not serializable to JSON and values exceeding the quota are
+// rejected without affecting the stored ones.
+func TestStoreRejectedValues(t *testing.T) {
+	workspace, err := ioutil.TempDir("", "console-store-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	client := rpc.DialInProc(rpc.NewServer())
+	defer client.Close()
+
+	console := newStoreTester(t, workspace, "ipc:a", client)
+	defer console.Stop(false)
+
+	run(t, console, `console.store.set("kept", "value")`)
+	for _, statement := range []string{
+		`console.store.set("fn", function() {})`,
+		`console.store.set("undefined", undefined)`,
+		`var cycle = {}; cycle.self = cycle; console.store.set("cycle", cycle)`,
+		fmt.Sprintf(`console.store.set("big", "%s")`, strings.Repeat("x", storeQuota)),
+		`console.store.set("", 1)`,
+		`console.store.get()`,
+	} {
+		if _, err := console.jsre.Run(statement); err == nil {
+			t.Errorf("%.60s: no error", statement)
+		}
+	}
+	if have := run(t, console, `JSON.stringify(console.store.keys())`); have != `["kept"]` {
+		t.Errorf("keys mismatch: have %s, want [\"kept\"]", have)
+	}
+}
+
+// Tests that statements of two consoles storing values at the same time don't
+// overwrite each other's values.
+func TestStoreConcurrentAccess(t *testing.T) {
+	workspace, err := ioutil.TempDir("", "console-store-")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	client := rpc.DialInProc(rpc.NewServer())
+	defer client.Close()
+
+	consoles := []*Console{
+		newStoreTester(t, workspace, "ipc:a", client),
+		newStoreTester(t, workspace, "ipc:a", client),
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, len(consoles))
+	for i, console := range consoles {
+		wg.Add(1)
+		go func(i int, console *Console) {
+			defer wg.Done()
+			_, err := console.jsre.Run(fmt.Sprintf(`for (var j = 0; j < 50; j++) { console.store.set("%d-" + j, j) }`, i))
+			errs <- err
+		}(i, console)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("failed to store values: %v", err)
+		}
+	}
+	if have := run(t, consoles[0], `console.store.keys().length`); have != "100" {
+		t.Errorf("stored value count mismatch: have %s, want 100", have)
+	}
+	for _, console := range consoles {
+		console.Stop(false)
+	}
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/metrics"
+)
+
+// blockTimingsLimit is the number of the most recent block timings retained.
+const blockTimingsLimit = 1024
+
+var (
+	prepareHistogram      = newTimingHistogram("chain/timings/prepare")
+	packingHistogram      = newTimingHistogram("chain/timings/packing")
+	sealDelayHistogram    = newTimingHistogram("chain/timings/sealdelay")
+	propagationHistogram  = newTimingHistogram("chain/timings/propagation")
+	verificationHistogram = newTimingHistogram("chain/timings/verification")
+	executionHistogram    = newTimingHistogram("chain/timings/execution")
+	writeHistogram        = newTimingHistogram("chain/timings/write")
+)
+
+// newTimingHistogram registers a histogram of the durations of a block stage,
+// in microseconds.
+func newTimingHistogram(name string) metrics.Histogram {
+	return metrics.NewRegisteredHistogram(name, nil, metrics.NewExpDecaySample(1028, 0.015))
+}
+
+/*
+[BERITH]
+BlockTiming is the time spent on the stages of a block sealed or imported by
+the node, in nanoseconds. The stages of a locally sealed block are the header
+preparation, the packing of the transactions, the seal delay waited for the
+rank of the signer and the write to the database. The stages of an imported
+block are its propagation from the first announcement until the body arrived,
+the verification, the execution and the write to the database.
+*/
+type BlockTiming struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Mined  bool        `json:"mined"` // Whether the block was sealed by the node
+
+	Prepare   time.Duration `json:"prepare,omitempty"`
+	Packing   time.Duration `json:"packing,omitempty"`
+	SealDelay time.Duration `json:"sealDelay,omitempty"`
+
+	Propagation  time.Duration `json:"propagation,omitempty"` // Zero if the block wasn't announced
+	Verification time.Duration `json:"verification,omitempty"`
+	Execution    time.Duration `json:"execution,omitempty"`
+
+	Write time.Duration `json:"write"`
+}
+
+// blockTimings is a ring of the most recent block timings.
+type blockTimings struct {
+	ring []*BlockTiming
+	next int  // Position of the next timing in the ring
+	full bool // Whether the ring wrapped around
+	lock sync.Mutex
+}
+
+// newBlockTimings creates a ring retaining the given number of timings.
+func newBlockTimings(size int) *blockTimings {
+	return &blockTimings{ring: make([]*BlockTiming, size)}
+}
+
+// add retains the timing of a block, evicting the oldest one if the ring is
+// full, and updates the histograms of the stages.
+func (t *blockTimings) add(timing *BlockTiming) {
+	if timing.Mined {
+		prepareHistogram.Update(int64(timing.Prepare / time.Microsecond))
+		packingHistogram.Update(int64(timing.Packing / time.Microsecond))
+		sealDelayHistogram.Update(int64(timing.SealDelay / time.Microsecond))
+	} else {
+		if timing.Propagation != 0 {
+			propagationHistogram.Update(int64(timing.Propagation / time.Microsecond))
+		}
+		verificationHistogram.Update(int64(timing.Verification / time.Microsecond))
+		executionHistogram.Update(int64(timing.Execution / time.Microsecond))
+	}
+	writeHistogram.Update(int64(timing.Write / time.Microsecond))
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.ring[t.next] = timing
+	t.next = (t.next + 1) % len(t.ring)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// last returns the most recent n timings, oldest first.
+func (t *blockTimings) last(n int) []*BlockTiming {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	size := t.next
+	if t.full {
+		size = len(t.ring)
+	}
+	if n < 0 || n > size {
+		n = size
+	}
+	timings := make([]*BlockTiming, 0, n)
+	for i := n; i > 0; i-- {
+		timing := *t.ring[(t.next-i+len(t.ring))%len(t.ring)]
+		timings = append(timings, &timing)
+	}
+	return timings
+}
+
+// RecordBlockTiming retains the timing of a block sealed by the node, as the
+// timings of the imported blocks are recorded while inserting them.
+func (bc *BlockChain) RecordBlockTiming(timing *BlockTiming) {
+	bc.timings.add(timing)
+}
+
+// BlockTimings returns the timings of the most recent n blocks sealed or
+// imported by the node, oldest first. All retained timings are returned if n
+// is negative or exceeds them.
+func (bc *BlockChain) BlockTimings(n int) []*BlockTiming {
+	return bc.timings.last(n)
+}
@@ -142,6 +142,8 @@ type BlockChain struct {
 	stakingDB *staking.StakingDB
 
 	triesInMemory uint64 // Number of blocks to be saved in db without being erased when gc mode is not archive
+
+	timings *blockTimings // Timings of the most recent blocks sealed or imported
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -180,6 +182,7 @@ func NewBlockChain(stakingDB *staking.StakingDB, db berithdb.Database, cacheConf
 		badBlocks:      badBlocks,
 		stakingDB:      stakingDB,
 		triesInMemory:  chainConfig.Bsrr.Epoch,
+		timings:        newBlockTimings(blockTimingsLimit),
 	}
 	bc.SetValidator(NewBlockValidator(chainConfig, bc, engine))
 	bc.SetProcessor(NewStateProcessor(chainConfig, bc, engine))
@@ -957,6 +960,40 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	externTd := new(big.Int).Add(block.Difficulty(), ptd)
 	fmt.Println("Local current total difficulty : ", localTd, "External : ", externTd)
 
+	// If the total difficulty is higher than our known, add it to the canonical chain
+	// Second clause in the if statement reduces the vulnerability to selfish mining.
+	// Please refer to http://www.cs.cornell.edu/~ie53/publications/btcProcFC.pdf
+	// 외부 체인의 Td가 더 높으면 재편성
+	reorg := externTd.Cmp(localTd) > 0
+	fmt.Println("externTd > localTd ? ", reorg)
+	if !reorg && externTd.Cmp(localTd) == 0 {
+		// Split same-difficulty blocks by number, then preferentially select
+		// the block generated by the local miner as the canonical block.
+		if block.NumberU64() < currentBlock.NumberU64() {
+			//총 난이도는 같은데 새로 추가할 블럭의 체인이 더 짧으면 재편성
+			reorg = true
+			fmt.Printf("block.Number %d < currentBlock.Number %d\nreorg = true\n", block.NumberU64(), currentBlock.NumberU64())
+		} else if block.NumberU64() == currentBlock.NumberU64() {
+			var currentPreserve, blockPreserve bool
+			fmt.Printf("block.Number %d == currentBlock.Number %d\n", block.NumberU64(), currentBlock.NumberU64())
+			if bc.shouldPreserve != nil {
+				// 블록 채굴자와 체인의 코인베이스가 같은지 판단해 로컬블록인지 외부 블록인지 가려냄
+				currentPreserve, blockPreserve = bc.shouldPreserve(currentBlock), bc.shouldPreserve(block)
+				fmt.Printf(`currentPreserve : %v, blockPreserve : %v\n`, currentPreserve, blockPreserve)
+			}
+			reorg = !currentPreserve && (blockPreserve || mrand.Float64() < 0.5)
+			fmt.Printf("reorg = %v\n", reorg)
+		}
+	}
+	// Refuse to rewrite history below the finality horizon, before anything of
+	// the block is written
+	if reorg && block.ParentHash() != currentBlock.Hash() {
+		if err := bc.checkReorgDepth(currentBlock, block); err != nil {
+			log.Error("Rejected block reorganising beyond finality horizon", "number", block.Number(), "hash", block.Hash(),
+				"head", currentBlock.Number(), "horizon", bc.finalityHorizon())
+			return NonStatTy, err
+		}
+	}
 	// Irrelevant of the canonical status, write the block itself to the database
 	// 표준 상태와 무관하게 DB에 블록 자체를 기록한다.
 	if err := bc.hc.WriteTd(block.Hash(), block.NumberU64(), externTd); err != nil {
@@ -995,32 +1032,6 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	batch := bc.db.NewBatch()
 	rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
 
-	// If the total difficulty is higher than our known, add it to the canonical chain
-	// Second clause in the if statement reduces the vulnerability to selfish mining.
-	// Please refer to http://www.cs.cornell.edu/~ie53/publications/btcProcFC.pdf
-	// 외부 체인의 Td가 더 높으면 재편성
-	reorg := externTd.Cmp(localTd) > 0
-	fmt.Println("externTd > localTd ? ", reorg)
-	currentBlock = bc.CurrentBlock()
-	if !reorg && externTd.Cmp(localTd) == 0 {
-		// Split same-difficulty blocks by number, then preferentially select
-		// the block generated by the local miner as the canonical block.
-		if block.NumberU64() < currentBlock.NumberU64() {
-			//총 난이도는 같은데 새로 추가할 블럭의 체인이 더 짧으면 재편성
-			reorg = true
-			fmt.Printf("block.Number %d < currentBlock.Number %d\nreorg = true\n", block.NumberU64(), currentBlock.NumberU64())
-		} else if block.NumberU64() == currentBlock.NumberU64() {
-			var currentPreserve, blockPreserve bool
-			fmt.Printf("block.Number %d == currentBlock.Number %d\n", block.NumberU64(), currentBlock.NumberU64())
-			if bc.shouldPreserve != nil {
-				// 블록 채굴자와 체인의 코인베이스가 같은지 판단해 로컬블록인지 외부 블록인지 가려냄
-				currentPreserve, blockPreserve = bc.shouldPreserve(currentBlock), bc.shouldPreserve(block)
-				fmt.Printf(`currentPreserve : %v, blockPreserve : %v\n`, currentPreserve, blockPreserve)
-			}
-			reorg = !currentPreserve && (blockPreserve || mrand.Float64() < 0.5)
-			fmt.Printf("reorg = %v\n", reorg)
-		}
-	}
 	if reorg {
 		fmt.Println("WriteBlockWithState / Reorg : ", reorg)
 		// Reorganise the chain if the parent is not the head block
@@ -1242,6 +1253,18 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		blockExecutionTimer.Update(t1.Sub(t0))
 		blockValidationTimer.Update(t2.Sub(t1))
 		blockWriteTimer.Update(t3.Sub(t2))
+
+		timing := &BlockTiming{
+			Number:       block.NumberU64(),
+			Hash:         block.Hash(),
+			Verification: it.verified + t2.Sub(t1),
+			Execution:    t1.Sub(t0),
+			Write:        t3.Sub(t2),
+		}
+		if !block.AnnouncedAt.IsZero() && !block.ReceivedAt.IsZero() {
+			timing.Propagation = block.ReceivedAt.Sub(block.AnnouncedAt)
+		}
+		bc.timings.add(timing)
 		switch status {
 		case CanonStatTy:
 			log.Debug("Inserted new block", "number", block.Number(), "hash", block.Hash(),
@@ -1407,6 +1430,55 @@ func (bc *BlockChain) insertSidechain(it *insertIterator) (int, []interface{}, [
 	return 0, nil, nil, nil
 }
 
+// finalityHorizon returns the maximum depth below the current head a reorg may
+// reach, or 0 if reorgs are not restricted.
+func (bc *BlockChain) finalityHorizon() uint64 {
+	if bc.chainConfig.Bsrr == nil {
+		return 0
+	}
+	return bc.chainConfig.Bsrr.FinalityHorizon
+}
+
+// checkReorgDepth returns ErrReorgTooDeep if making the given block the head
+// would drop canonical blocks that are already final under the finality horizon.
+// The block itself doesn't have to be stored, its branch is walked from the
+// parent back to the canonical chain.
+func (bc *BlockChain) checkReorgDepth(head, block *types.Block) error {
+	horizon := bc.finalityHorizon()
+	if horizon == 0 {
+		return nil
+	}
+	ancestor := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	for ancestor != nil && rawdb.ReadCanonicalHash(bc.db, ancestor.Number.Uint64()) != ancestor.Hash() {
+		if head.NumberU64() > ancestor.Number.Uint64()+horizon {
+			return ErrReorgTooDeep
+		}
+		ancestor = bc.GetHeader(ancestor.ParentHash, ancestor.Number.Uint64()-1)
+	}
+	if ancestor == nil {
+		// Unknown branch, left for reorg to report
+		return nil
+	}
+	if head.NumberU64() > ancestor.Number.Uint64()+horizon {
+		return ErrReorgTooDeep
+	}
+	return nil
+}
+
+// FinalizedBlock returns the newest canonical block that can no longer be
+// reorganised under the finality horizon, or nil if the horizon is disabled.
+func (bc *BlockChain) FinalizedBlock() *types.Block {
+	horizon := bc.finalityHorizon()
+	if horizon == 0 {
+		return nil
+	}
+	head := bc.CurrentBlock().NumberU64()
+	if head < horizon {
+		return bc.genesisBlock
+	}
+	return bc.GetBlockByNumber(head - horizon)
+}
+
 // reorgs takes two blocks, an old chain and a new chain and will reconstruct the blocks and inserts them
 // to be part of the new canonical chain and accumulates potential missing transactions and post an
 // event about them
@@ -1725,6 +1797,12 @@ func (bc *BlockChain) GetHeaderByNumber(number uint64) *types.Header {
 	return bc.hc.GetHeaderByNumber(number)
 }
 
+// HeadersBetween calls fn with the canonical headers between from and to
+// inclusive in ascending order, until fn returns false.
+func (bc *BlockChain) HeadersBetween(from, to uint64, fn func(*types.Header) bool) error {
+	return bc.hc.HeadersBetween(from, to, fn)
+}
+
 // Config retrieves the blockchain's chain configuration.
 func (bc *BlockChain) Config() *params.ChainConfig { return bc.chainConfig }
 
@@ -85,6 +85,7 @@ type insertIterator struct {
 	results   <-chan error
 	index     int
 	validator Validator
+	verified  time.Duration // Time spent verifying the current block
 }
 
 // newInsertIterator creates a new iterator based on the given blocks, which are
@@ -116,6 +117,10 @@ func (it *insertIterator) next() (*types.Block, error) {
 		return nil, nil
 	}
 	it.index++
+
+	start := time.Now()
+	defer func() { it.verified = time.Since(start) }()
+
 	if err := <-it.results; err != nil {
 		return it.chain[it.index], err
 	}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/berith/staking"
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/consensus/bsrr"
+	"github.com/BerithFoundation/berith-chain/core/state"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/core/vm"
+	"github.com/BerithFoundation/berith-chain/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// newTestBlockChain creates a chain of the testnet genesis with the given
+// finality horizon, returning a function releasing it.
+func newTestBlockChain(t *testing.T, horizon uint64) (*BlockChain, func()) {
+	bsrrConfig := *params.TestnetChainConfig.Bsrr
+	bsrrConfig.FinalityHorizon = horizon
+	config := *params.TestnetChainConfig
+	config.Bsrr = &bsrrConfig
+
+	dir, err := ioutil.TempDir("", "berith-stakingdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stakingDB := new(staking.StakingDB)
+	if err := stakingDB.CreateDB(dir, staking.NewStakers); err != nil {
+		t.Fatal(err)
+	}
+	db := berithdb.NewMemDatabase()
+	(&Genesis{Config: &config, GasLimit: 10000000}).MustCommit(db)
+	engine := bsrr.NewCliqueWithStakingDB(stakingDB, config.Bsrr, db)
+	bc, err := NewBlockChain(stakingDB, db, nil, &config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bc, func() {
+		bc.Stop()
+		stakingDB.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// Tests that blocks reorganising the chain deeper than the finality horizon are
+// refused without anything of them written, while shallower reorgs, or any
+// reorg with the horizon disabled, are accepted.
+func TestReorgFinalityHorizon(t *testing.T) {
+	tests := []struct {
+		horizon uint64
+		fork    uint64 // Number of the canonical block the side branch forks off at
+		err     error
+	}{
+		{3, 8, nil},
+		{3, 7, nil},
+		{3, 6, ErrReorgTooDeep},
+		{3, 0, ErrReorgTooDeep},
+		{0, 0, nil},
+	}
+	for i, tt := range tests {
+		bc, teardown := newTestBlockChain(t, tt.horizon)
+
+		// write appends a block of the given difficulty to parent, the side
+		// blocks told apart from the canonical ones by their extra data
+		write := func(parent *types.Block, difficulty int64, side bool) (*types.Block, error) {
+			header := &types.Header{
+				ParentHash: parent.Hash(),
+				Number:     new(big.Int).Add(parent.Number(), big.NewInt(1)),
+				Time:       new(big.Int).Add(parent.Time(), big.NewInt(1)),
+				Difficulty: big.NewInt(difficulty),
+				GasLimit:   parent.GasLimit(),
+				Root:       parent.Root(),
+			}
+			if side {
+				header.Extra = []byte("side")
+			}
+			block := types.NewBlockWithHeader(header)
+			statedb, err := state.New(parent.Root(), bc.stateCache)
+			if err != nil {
+				t.Fatalf("test %d: failed to open state: %v", i, err)
+			}
+			_, err = bc.WriteBlockWithState(block, nil, statedb)
+			return block, err
+		}
+		// Canonical chain of 10 blocks, and a heavier side block forking off
+		parent := bc.Genesis()
+		for n := 0; n < 10; n++ {
+			block, err := write(parent, 2, false)
+			if err != nil {
+				t.Fatalf("test %d: failed to write block %d: %v", i, n+1, err)
+			}
+			parent = block
+		}
+		head := bc.CurrentBlock()
+
+		side, err := write(bc.GetBlockByNumber(tt.fork), 100, true)
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if tt.err == nil {
+			if bc.CurrentBlock().Hash() != side.Hash() {
+				t.Errorf("test %d: side block not made the head", i)
+			}
+		} else {
+			if bc.CurrentBlock().Hash() != head.Hash() {
+				t.Errorf("test %d: head changed by the refused block", i)
+			}
+			if bc.HasBlock(side.Hash(), side.NumberU64()) || bc.GetTd(side.Hash(), side.NumberU64()) != nil {
+				t.Errorf("test %d: refused block written", i)
+			}
+		}
+		teardown()
+	}
+}
+
+// Tests that blocks reported as future ones by the consensus engine are queued
+// for delayed processing as long as they are within the future block window.
+func TestAddFutureBlock(t *testing.T) {
+	futureBlocks, _ := lru.New(maxFutureBlocks)
+	bc := &BlockChain{futureBlocks: futureBlocks}
+
+	now := time.Now().Unix()
+	for _, ahead := range []int64{3, 30} {
+		block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(ahead), Time: big.NewInt(now + ahead)})
+		if err := bc.addFutureBlock(block); err != nil {
+			t.Errorf("%ds ahead: block not queued: %v", ahead, err)
+		}
+		if !bc.futureBlocks.Contains(block.Hash()) {
+			t.Errorf("%ds ahead: block missing from the future queue", ahead)
+		}
+	}
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(60), Time: big.NewInt(now + 60)})
+	if err := bc.addFutureBlock(block); err == nil {
+		t.Errorf("block beyond the future window queued")
+	}
+}
+
+// Tests that the block timings ring retains the most recent timings, oldest
+// first, and that the returned timings are copies.
+func TestBlockTimingsRing(t *testing.T) {
+	timings := newBlockTimings(3)
+	if have := timings.last(-1); len(have) != 0 {
+		t.Fatalf("fresh ring has timings: %v", have)
+	}
+	for i := uint64(1); i <= 5; i++ {
+		timings.add(&BlockTiming{Number: i, Write: time.Duration(i)})
+	}
+	for n, want := range map[int][]uint64{-1: {3, 4, 5}, 0: {}, 2: {4, 5}, 10: {3, 4, 5}} {
+		have := timings.last(n)
+		if len(have) != len(want) {
+			t.Errorf("last %d: length mismatch: have %d, want %d", n, len(have), len(want))
+			continue
+		}
+		for i, timing := range have {
+			if timing.Number != want[i] {
+				t.Errorf("last %d: timing %d mismatch: have block %d, want %d", n, i, timing.Number, want[i])
+			}
+		}
+	}
+	timings.last(1)[0].Number = 0
+	if have := timings.last(1)[0].Number; have != 5 {
+		t.Errorf("retained timing modified: have block %d, want 5", have)
+	}
+}
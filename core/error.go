@@ -29,6 +29,10 @@ var (
 	// ErrBlacklistedHash is returned if a block to import is on the blacklist.
 	ErrBlacklistedHash = errors.New("blacklisted hash")
 
+	// ErrReorgTooDeep is returned if importing a block would reorganise the chain
+	// deeper than the finality horizon of the consensus engine.
+	ErrReorgTooDeep = errors.New("reorg beyond finality horizon")
+
 	// ErrNonceTooHigh is returned if the nonce of a transaction is higher than the
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
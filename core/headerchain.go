@@ -41,6 +41,7 @@ const (
 	headerCacheLimit = 512
 	tdCacheLimit     = 1024
 	numberCacheLimit = 2048
+	headerBatchSize  = 2048 // Number of headers HeadersBetween reads at once
 )
 
 // HeaderChain implements the basic block header chain logic that is shared by
@@ -443,6 +444,49 @@ func (hc *HeaderChain) GetHeaderByNumber(number uint64) *types.Header {
 	return hc.GetHeader(hash, number)
 }
 
+// HeadersBetween calls fn with the canonical headers between from and to inclusive
+// in ascending order, until fn returns false. The headers are read in batches
+// walking back from the canonical hash of the last one of each batch, which saves
+// the lookups of the canonical hashes of the others. Headers missing from the
+// cache are decoded from the database without being added to it, so iterating
+// over large ranges doesn't evict the recent headers.
+func (hc *HeaderChain) HeadersBetween(from, to uint64, fn func(*types.Header) bool) error {
+	batch := make([]*types.Header, 0, headerBatchSize)
+	for first := from; first <= to; first += headerBatchSize {
+		last := to
+		if to-first >= headerBatchSize {
+			last = first + headerBatchSize - 1
+		}
+		hash := rawdb.ReadCanonicalHash(hc.chainDb, last)
+		if hash == (common.Hash{}) {
+			return consensus.ErrUnknownHeader
+		}
+		batch = batch[:last-first+1]
+		for number := last; ; number-- {
+			var header *types.Header
+			if cached, ok := hc.headerCache.Get(hash); ok {
+				header = cached.(*types.Header)
+			} else if header = rawdb.ReadHeader(hc.chainDb, hash, number); header == nil {
+				return consensus.ErrUnknownHeader
+			}
+			batch[number-first] = header
+			if number == first {
+				break
+			}
+			hash = header.ParentHash
+		}
+		for _, header := range batch {
+			if !fn(header) {
+				return nil
+			}
+		}
+		if last == to {
+			break
+		}
+	}
+	return nil
+}
+
 // CurrentHeader retrieves the current head header of the canonical chain. The
 // header is retrieved from the HeaderChain's internals cache.
 func (hc *HeaderChain) CurrentHeader() *types.Header {
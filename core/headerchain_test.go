@@ -0,0 +1,106 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/consensus"
+	"github.com/BerithFoundation/berith-chain/core/rawdb"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/params"
+)
+
+// newTestHeaderChain creates a header chain of the given number of headers on
+// top of the genesis, along with a side header at each height.
+func newTestHeaderChain(tb testing.TB, n int) *HeaderChain {
+	db := berithdb.NewMemDatabase()
+
+	var parent *types.Header
+	for number := 0; number <= n; number++ {
+		header := &types.Header{Number: big.NewInt(int64(number)), Time: big.NewInt(int64(number)), Difficulty: big.NewInt(2)}
+		if parent != nil {
+			header.ParentHash = parent.Hash()
+			side := &types.Header{ParentHash: parent.Hash(), Number: header.Number, Time: header.Time, Difficulty: big.NewInt(1)}
+			rawdb.WriteHeader(db, side)
+		}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), uint64(number))
+		parent = header
+	}
+	rawdb.WriteHeadHeaderHash(db, parent.Hash())
+
+	hc, err := NewHeaderChain(db, params.TestnetChainConfig, nil, func() bool { return false })
+	if err != nil {
+		tb.Fatalf("failed to create header chain: %v", err)
+	}
+	return hc
+}
+
+// Tests that the canonical headers of a range are iterated over in ascending
+// order across batches, stopping once the callback asks to.
+func TestHeadersBetween(t *testing.T) {
+	n := 3*headerBatchSize + 10
+	hc := newTestHeaderChain(t, n)
+
+	tests := []struct {
+		from, to uint64
+		limit    int // Number of headers after which the iteration stops, 0 = none
+		want     int
+	}{
+		{0, uint64(n), 0, n + 1},
+		{5, 5, 0, 1},
+		{headerBatchSize - 1, headerBatchSize + 1, 0, 3},
+		{10, uint64(n), headerBatchSize + 11, headerBatchSize + 11},
+		{10, 9, 0, 0},
+	}
+	for i, tt := range tests {
+		var headers []*types.Header
+		err := hc.HeadersBetween(tt.from, tt.to, func(header *types.Header) bool {
+			headers = append(headers, header)
+			return len(headers) != tt.limit
+		})
+		if err != nil {
+			t.Fatalf("test %d: failed to iterate: %v", i, err)
+		}
+		if len(headers) != tt.want {
+			t.Fatalf("test %d: header count mismatch: have %d, want %d", i, len(headers), tt.want)
+		}
+		for j, header := range headers {
+			number := tt.from + uint64(j)
+			if header.Number.Uint64() != number || header.Hash() != rawdb.ReadCanonicalHash(hc.chainDb, number) {
+				t.Fatalf("test %d: header %d mismatch: have #%d %x", i, j, header.Number, header.Hash())
+			}
+		}
+	}
+	// Ranges beyond the chain fail, the engine helper falls back to lookups
+	if err := hc.HeadersBetween(uint64(n)-5, uint64(n)+5, func(*types.Header) bool { return true }); err != consensus.ErrUnknownHeader {
+		t.Errorf("range beyond the chain: error mismatch: have %v, want %v", err, consensus.ErrUnknownHeader)
+	}
+}
+
+// Benchmarks iterating over 100k headers by looking them up one by one.
+func BenchmarkHeadersByNumber(b *testing.B) {
+	hc := newTestHeaderChain(b, 100000)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for number := uint64(0); number <= 100000; number++ {
+			if hc.GetHeaderByNumber(number) == nil {
+				b.Fatalf("header #%d missing", number)
+			}
+		}
+	}
+}
+
+// Benchmarks iterating over 100k headers in batches.
+func BenchmarkHeadersBetween(b *testing.B) {
+	hc := newTestHeaderChain(b, 100000)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := hc.HeadersBetween(0, 100000, func(*types.Header) bool { return true }); err != nil {
+			b.Fatalf("failed to iterate: %v", err)
+		}
+	}
+}
@@ -83,6 +83,31 @@ var (
 	ErrInvalidStakeReceiver = errors.New("berith account only can stake token on itself")
 )
 
+// InvalidSenderError is ErrInvalidSender enriched with the chain the signature
+// of the transaction was actually made for.
+type InvalidSenderError struct {
+	Mismatch *types.ChainIdMismatchError
+}
+
+func (err *InvalidSenderError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrInvalidSender, err.Mismatch)
+}
+
+// Unwrap returns ErrInvalidSender, the error being enriched.
+func (err *InvalidSenderError) Unwrap() error {
+	return ErrInvalidSender
+}
+
+// SenderError returns the error rejecting a transaction whose sender couldn't be
+// recovered, telling which chain the signature was made for if it was signed for
+// another one than the given chain.
+func SenderError(tx *types.Transaction, chainID *big.Int) error {
+	if mismatch := types.DiagnoseChainId(tx, chainID); mismatch != nil {
+		return &InvalidSenderError{Mismatch: mismatch}
+	}
+	return ErrInvalidSender
+}
+
 var (
 	evictionInterval    = time.Minute     // Time interval to check for evictable transactions
 	statsReportInterval = 8 * time.Second // Time interval to report transaction pool stats
@@ -613,7 +638,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	// 트랜잭션이 제대로 서명되었는지 보증한다.
 	from, err := types.Sender(pool.signer, tx)
 	if err != nil {
-		return ErrInvalidSender
+		return SenderError(tx, pool.chainconfig.ChainID)
 	}
 	// Drop non-local transactions under our own minimal accepted gas price
 	//
@@ -995,6 +1020,12 @@ func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 	return pool.all.Get(hash)
 }
 
+// FirstSeen returns the time the transaction with the given hash first entered
+// the pool, or the zero time if the pool does not know about it.
+func (pool *TxPool) FirstSeen(hash common.Hash) time.Time {
+	return pool.all.Seen(hash)
+}
+
 // removeTx removes a single transaction from the queue, moving all subsequent
 // transactions back to the future queue.
 func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
@@ -1357,13 +1388,15 @@ func (as *accountSet) flatten() []common.Address {
 // txLookup은 TxPool에서 내부적으로 트랜잭션을 추적하는 데 사용되며 뮤텍스 경합 없이 조회를 허용한다.
 type txLookup struct {
 	all  map[common.Hash]*types.Transaction
+	seen map[common.Hash]time.Time
 	lock sync.RWMutex
 }
 
 // newTxLookup returns a new txLookup structure.
 func newTxLookup() *txLookup {
 	return &txLookup{
-		all: make(map[common.Hash]*types.Transaction),
+		all:  make(map[common.Hash]*types.Transaction),
+		seen: make(map[common.Hash]time.Time),
 	}
 }
 
@@ -1387,6 +1420,15 @@ func (t *txLookup) Get(hash common.Hash) *types.Transaction {
 	return t.all[hash]
 }
 
+// Seen returns the time a transaction was first added to the lookup, or the
+// zero time if it is not tracked.
+func (t *txLookup) Seen(hash common.Hash) time.Time {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.seen[hash]
+}
+
 // Count returns the current number of items in the lookup.
 func (t *txLookup) Count() int {
 	t.lock.RLock()
@@ -1400,7 +1442,11 @@ func (t *txLookup) Add(tx *types.Transaction) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	t.all[tx.Hash()] = tx
+	hash := tx.Hash()
+	t.all[hash] = tx
+	if _, ok := t.seen[hash]; !ok {
+		t.seen[hash] = time.Now()
+	}
 }
 
 // Remove removes a transaction from the lookup.
@@ -1409,4 +1455,5 @@ func (t *txLookup) Remove(hash common.Hash) {
 	defer t.lock.Unlock()
 
 	delete(t.all, hash)
+	delete(t.seen, hash)
 }
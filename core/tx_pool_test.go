@@ -2,6 +2,7 @@ package core
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"math/big"
 	"os"
 	"testing"
@@ -10,6 +11,7 @@ import (
 	"github.com/BerithFoundation/berith-chain/berithdb"
 	"github.com/BerithFoundation/berith-chain/common/hexutil"
 	"github.com/BerithFoundation/berith-chain/consensus/bsrr"
+	"github.com/BerithFoundation/berith-chain/crypto"
 	"github.com/BerithFoundation/berith-chain/crypto/secp256k1"
 	"github.com/BerithFoundation/berith-chain/params"
 
@@ -147,3 +149,58 @@ func TestTransactionValidate(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the lookup remembers when a transaction was first added, keeps the
+// original timestamp on re-insertion and forgets it on removal.
+func TestTxLookupFirstSeen(t *testing.T) {
+	lookup := newTxLookup()
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil, types.Main, types.Main)
+
+	if seen := lookup.Seen(tx.Hash()); !seen.IsZero() {
+		t.Fatalf("unknown transaction reported as seen at %v", seen)
+	}
+	lookup.Add(tx)
+	first := lookup.Seen(tx.Hash())
+	if first.IsZero() {
+		t.Fatalf("added transaction not reported as seen")
+	}
+	lookup.Add(tx)
+	if seen := lookup.Seen(tx.Hash()); !seen.Equal(first) {
+		t.Errorf("first seen time changed on re-insertion: have %v, want %v", seen, first)
+	}
+	lookup.Remove(tx.Hash())
+	if seen := lookup.Seen(tx.Hash()); !seen.IsZero() {
+		t.Errorf("removed transaction still reported as seen at %v", seen)
+	}
+}
+
+// Tests that the pool rejects transactions signed for other chains as of invalid
+// sender, telling the chain the signature was made for.
+func TestTransactionChainIdMismatch(t *testing.T) {
+	pool := &TxPool{
+		chainconfig:   params.TestnetChainConfig,
+		signer:        types.NewEIP155Signer(params.TestnetChainConfig.ChainID),
+		currentMaxGas: 8000000,
+	}
+	key, _ := crypto.GenerateKey()
+
+	tests := []struct {
+		chainID *big.Int
+		want    string
+	}{
+		{big.NewInt(1), "invalid sender: transaction was signed for chain 1 (Ethereum mainnet), this node is chain 206"},
+		{big.NewInt(5), "invalid sender: transaction was signed for chain 5 (Goerli), this node is chain 206"},
+		{big.NewInt(2020), "invalid sender: transaction was signed for chain 2020, this node is chain 206"},
+	}
+	for _, tt := range tests {
+		tx, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil, types.Main, types.Main), types.NewEIP155Signer(tt.chainID), key)
+
+		err := pool.validateTx(tx, false)
+		if !errors.Is(err, ErrInvalidSender) {
+			t.Errorf("chain %v: error mismatch: have %v, want %v", tt.chainID, err, ErrInvalidSender)
+		}
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("chain %v: diagnosis mismatch: have %v, want %q", tt.chainID, err, tt.want)
+		}
+	}
+}
@@ -142,6 +142,7 @@ type Block struct {
 	// inter-peer block relay.
 	ReceivedAt   time.Time
 	ReceivedFrom interface{}
+	AnnouncedAt  time.Time // Time the block was first announced at, zero if it was propagated whole
 }
 
 // DeprecatedTd is an old relic for extracting the TD of a block. It is in the
@@ -17,11 +17,14 @@
 package types
 
 import (
+	"bytes"
 	"container/heap"
 	"errors"
 	"io"
 	"math/big"
+	"sort"
 	"sync/atomic"
+	"time"
 
 	"github.com/BerithFoundation/berith-chain/common"
 	"github.com/BerithFoundation/berith-chain/common/hexutil"
@@ -398,6 +401,152 @@ func (t *TransactionsByPriceAndNonce) Pop() {
 	heap.Pop(&t.heads)
 }
 
+// TxByTime implements the heap interface, ordering transactions by the time
+// they were first seen. Transactions seen at the same instant fall back to a
+// price ordering.
+type TxByTime struct {
+	txs  Transactions
+	seen func(common.Hash) time.Time
+}
+
+func (s TxByTime) Len() int { return len(s.txs) }
+func (s TxByTime) Less(i, j int) bool {
+	ti, tj := s.seen(s.txs[i].Hash()), s.seen(s.txs[j].Hash())
+	if ti.Equal(tj) {
+		return s.txs[i].data.Price.Cmp(s.txs[j].data.Price) > 0
+	}
+	return ti.Before(tj)
+}
+func (s TxByTime) Swap(i, j int) { s.txs[i], s.txs[j] = s.txs[j], s.txs[i] }
+
+func (s *TxByTime) Push(x interface{}) {
+	s.txs = append(s.txs, x.(*Transaction))
+}
+
+func (s *TxByTime) Pop() interface{} {
+	old := s.txs
+	n := len(old)
+	x := old[n-1]
+	s.txs = old[0 : n-1]
+	return x
+}
+
+// TransactionsByTimeAndNonce represents a set of transactions that can return
+// transactions in first-come-first-served order, while honouring the nonce
+// ordering of every account.
+type TransactionsByTimeAndNonce struct {
+	txs    map[common.Address]Transactions // Per account nonce-sorted list of transactions
+	heads  TxByTime                        // Next transaction for each unique account (first-seen heap)
+	signer Signer                          // Signer for the set of transactions
+}
+
+// NewTransactionsByTimeAndNonce creates a transaction set that can retrieve
+// transactions sorted by the time returned by seen in a nonce-honouring way.
+//
+// Note, the input map is reowned so the caller should not interact any more with
+// if after providing it to the constructor.
+func NewTransactionsByTimeAndNonce(signer Signer, txs map[common.Address]Transactions, seen func(common.Hash) time.Time) *TransactionsByTimeAndNonce {
+	heads := TxByTime{txs: make(Transactions, 0, len(txs)), seen: seen}
+	for from, accTxs := range txs {
+		heads.txs = append(heads.txs, accTxs[0])
+		acc, _ := Sender(signer, accTxs[0])
+		txs[acc] = accTxs[1:]
+		if from != acc {
+			delete(txs, from)
+		}
+	}
+	heap.Init(&heads)
+
+	return &TransactionsByTimeAndNonce{
+		txs:    txs,
+		heads:  heads,
+		signer: signer,
+	}
+}
+
+// Peek returns the oldest executable transaction.
+func (t *TransactionsByTimeAndNonce) Peek() *Transaction {
+	if len(t.heads.txs) == 0 {
+		return nil
+	}
+	return t.heads.txs[0]
+}
+
+// Shift replaces the current head with the next one from the same account.
+func (t *TransactionsByTimeAndNonce) Shift() {
+	acc, _ := Sender(t.signer, t.heads.txs[0])
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
+		t.heads.txs[0], t.txs[acc] = txs[0], txs[1:]
+		heap.Fix(&t.heads, 0)
+	} else {
+		heap.Pop(&t.heads)
+	}
+}
+
+// Pop removes the current head, *not* replacing it with the next one from the
+// same account.
+func (t *TransactionsByTimeAndNonce) Pop() {
+	heap.Pop(&t.heads)
+}
+
+// TransactionsByRoundRobin represents a set of transactions that hands out one
+// transaction per account in turn, so that no single sender can crowd out the
+// others regardless of the gas price it offers.
+type TransactionsByRoundRobin struct {
+	txs    map[common.Address]Transactions // Per account nonce-sorted list of transactions
+	order  []common.Address                // Accounts still having executable transactions, in turn order
+	signer Signer                          // Signer for the set of transactions
+}
+
+// NewTransactionsByRoundRobin creates a transaction set that cycles through the
+// accounts in address order, yielding their transactions by nonce.
+func NewTransactionsByRoundRobin(signer Signer, txs map[common.Address]Transactions) *TransactionsByRoundRobin {
+	// Ensure the sender addresses are from the signer
+	accounts := make(map[common.Address]Transactions, len(txs))
+	order := make([]common.Address, 0, len(txs))
+	for _, accTxs := range txs {
+		acc, _ := Sender(signer, accTxs[0])
+		accounts[acc] = accTxs
+		order = append(order, acc)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return bytes.Compare(order[i][:], order[j][:]) < 0
+	})
+	return &TransactionsByRoundRobin{
+		txs:    accounts,
+		order:  order,
+		signer: signer,
+	}
+}
+
+// Peek returns the next transaction of the account whose turn it is.
+func (t *TransactionsByRoundRobin) Peek() *Transaction {
+	if len(t.order) == 0 {
+		return nil
+	}
+	return t.txs[t.order[0]][0]
+}
+
+// Shift consumes the current transaction and hands the turn to the next
+// account, re-queueing the current one if it has more transactions.
+func (t *TransactionsByRoundRobin) Shift() {
+	acc := t.order[0]
+	if txs := t.txs[acc][1:]; len(txs) > 0 {
+		t.txs[acc] = txs
+		t.order = append(t.order[1:], acc)
+	} else {
+		delete(t.txs, acc)
+		t.order = t.order[1:]
+	}
+}
+
+// Pop removes the account whose turn it is together with all its remaining
+// transactions.
+func (t *TransactionsByRoundRobin) Pop() {
+	delete(t.txs, t.order[0])
+	t.order = t.order[1:]
+}
+
 // Message is a fully derived transaction and implements core.Message
 //
 // NOTE: In a future PR this will be removed.
@@ -6,6 +6,7 @@ import (
 	"sync/atomic"
 
 	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
 	"github.com/BerithFoundation/berith-chain/crypto"
 	"github.com/BerithFoundation/berith-chain/rlp"
 )
@@ -35,6 +36,8 @@ type TransactionInterface interface {
 	RawSignatureValues() (*big.Int, *big.Int, *big.Int)
 }
 
+//go:generate gencodec -type originTxdata -field-override originTxdataMarshaling -out gen_origin_tx_json.go
+
 type originTxdata struct {
 	// From의 Nonce
 	AccountNonce uint64          `json:"nonce"    gencodec:"required"`
@@ -53,8 +56,24 @@ type originTxdata struct {
 	Hash *common.Hash `json:"hash" rlp:"-"`
 }
 
-func (o *originTxdata) MarshalJSON() ([]byte, error)     { return nil, nil }
-func (o *originTxdata) UnmarshalJSON(input []byte) error { return nil }
+// computeHash hashes the RLP encoding of the signed fields, caching the hash
+// into the Hash field.
+func (o *originTxdata) computeHash() common.Hash {
+	hash := rlpHash(o)
+	o.Hash = &hash
+	return hash
+}
+
+type originTxdataMarshaling struct {
+	AccountNonce hexutil.Uint64
+	Price        *hexutil.Big
+	GasLimit     hexutil.Uint64
+	Amount       *hexutil.Big
+	Payload      hexutil.Bytes
+	V            *hexutil.Big
+	R            *hexutil.Big
+	S            *hexutil.Big
+}
 
 type OriginTransaction struct {
 	data originTxdata
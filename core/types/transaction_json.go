@@ -0,0 +1,116 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/BerithFoundation/berith-chain/common"
+)
+
+var (
+	ErrTxTypeNotSupported = errors.New("transaction type not supported")
+	errUnknownTxShape     = errors.New("unknown transaction shape")
+)
+
+// TxdataInterface is the consensus data of a transaction in one of the JSON
+// forms accepted by NormalizeTxJSON.
+type TxdataInterface interface {
+	MarshalJSON() ([]byte, error)
+	UnmarshalJSON(input []byte) error
+
+	// Tx returns the transaction carrying the data.
+	Tx() TransactionInterface
+
+	// TxHash returns the hash of the transaction, computing it on first use.
+	TxHash() common.Hash
+
+	// RawSignatureValues returns the V, R, S signature values of the transaction.
+	RawSignatureValues() (v, r, s *big.Int)
+}
+
+func (t *txdata) Tx() TransactionInterface       { return &Transaction{data: *t} }
+func (o *originTxdata) Tx() TransactionInterface { return &OriginTransaction{data: *o} }
+
+func (t *txdata) RawSignatureValues() (v, r, s *big.Int)       { return t.V, t.R, t.S }
+func (o *originTxdata) RawSignatureValues() (v, r, s *big.Int) { return o.V, o.R, o.S }
+
+// computeHash hashes the RLP encoding of the signed fields, caching the hash
+// into the Hash field.
+func (t *txdata) computeHash() common.Hash {
+	hash := rlpHash(t)
+	t.Hash = &hash
+	return hash
+}
+
+func (t *txdata) TxHash() common.Hash {
+	if t.Hash != nil {
+		return *t.Hash
+	}
+	return t.computeHash()
+}
+
+func (o *originTxdata) TxHash() common.Hash {
+	if o.Hash != nil {
+		return *o.Hash
+	}
+	return o.computeHash()
+}
+
+/*
+[Berith]
+NormalizeTxJSON decodes a transaction in any of the supported JSON forms.
+Transactions carrying job wallets ("base" and "target") are decoded as Berith
+transactions, those without as legacy Ethereum transactions. Typed
+transactions ("type" or "accessList" fields) are recognized but rejected
+with ErrTxTypeNotSupported, as no typed form exists yet.
+The "hash" field of the input is not trusted, TxHash computes it.
+*/
+func NormalizeTxJSON(input []byte) (TxdataInterface, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(input, &fields); err != nil {
+		return nil, fmt.Errorf("%v: %v", errUnknownTxShape, err)
+	}
+	if typ, ok := fields["type"]; ok {
+		return nil, fmt.Errorf("%v: type %s", ErrTxTypeNotSupported, typ)
+	}
+	if _, ok := fields["accessList"]; ok {
+		return nil, fmt.Errorf("%v: access list present", ErrTxTypeNotSupported)
+	}
+	_, hasBase := fields["base"]
+	_, hasTarget := fields["target"]
+
+	switch {
+	case hasBase && hasTarget:
+		tx := new(Transaction)
+		if err := tx.UnmarshalJSON(input); err != nil {
+			return nil, fmt.Errorf("invalid berith transaction: %v", err)
+		}
+		// The generated decoder skips the job wallets
+		var wallets struct {
+			Base   JobWallet `json:"base"`
+			Target JobWallet `json:"target"`
+		}
+		if err := json.Unmarshal(input, &wallets); err != nil {
+			return nil, fmt.Errorf("invalid berith transaction: %v", err)
+		}
+		if err := ValidateJobWallet(wallets.Base, wallets.Target); err != nil {
+			return nil, fmt.Errorf("invalid berith transaction: %v (base %d, target %d)", err, wallets.Base, wallets.Target)
+		}
+		tx.data.Base, tx.data.Target = wallets.Base, wallets.Target
+		tx.data.Hash = nil
+		return &tx.data, nil
+
+	case hasBase || hasTarget:
+		return nil, fmt.Errorf("%v: job wallets must include both base and target", errUnknownTxShape)
+
+	default:
+		tx := new(OriginTransaction)
+		if err := tx.UnmarshalJSON(input); err != nil {
+			return nil, fmt.Errorf("invalid legacy transaction: %v", err)
+		}
+		tx.data.Hash = nil
+		return &tx.data, nil
+	}
+}
@@ -29,8 +29,40 @@ import (
 
 var (
 	ErrInvalidChainId = errors.New("invalid chain id for signer")
+	ErrMalleableSig   = errors.New("malleable transaction signature, s in the upper half of the curve order")
 )
 
+// ValidateSignatureValues checks that the signature of the transaction data has
+// a valid recovery id and R and S values within the curve order. Under the
+// homestead rules S must also be in the lower half of the curve order (EIP-2),
+// the signatures with the S value flipped are rejected with ErrMalleableSig.
+func ValidateSignatureValues(data TxdataInterface, homestead bool) error {
+	v, r, s := data.RawSignatureValues()
+	if v == nil || r == nil || s == nil {
+		return ErrInvalidSig
+	}
+	var recovery *big.Int
+	if isProtectedV(v) {
+		if v.Cmp(big.NewInt(35)) < 0 {
+			return ErrInvalidSig
+		}
+		recovery = new(big.Int).Sub(v, new(big.Int).Lsh(deriveChainId(v), 1))
+		recovery.Sub(recovery, big.NewInt(35))
+	} else {
+		recovery = new(big.Int).Sub(v, big.NewInt(27))
+	}
+	if !recovery.IsUint64() || recovery.Uint64() > 1 {
+		return ErrInvalidSig
+	}
+	if !crypto.ValidateSignatureValues(byte(recovery.Uint64()), r, s, false) {
+		return ErrInvalidSig
+	}
+	if homestead && !crypto.ValidateSignatureValues(byte(recovery.Uint64()), r, s, true) {
+		return ErrMalleableSig
+	}
+	return nil
+}
+
 // sigCache is used to cache the derived sender and contains
 // the signer used to derive it.
 type sigCache struct {
@@ -104,6 +136,71 @@ func Sender(signer Signer, tx *Transaction) (common.Address, error) {
 	return addr, nil
 }
 
+// knownChains are the well-known chains whose tooling is commonly reused to
+// sign Berith transactions, tried in order when diagnosing chain id mismatches.
+var knownChains = []struct {
+	id   int64
+	name string
+}{
+	{1, "Ethereum mainnet"},
+	{3, "Ropsten"},
+	{4, "Rinkeby"},
+	{5, "Goerli"},
+	{42, "Kovan"},
+	{56, "BNB Smart Chain"},
+	{137, "Polygon"},
+	{11155111, "Sepolia"},
+}
+
+// ChainIdMismatchError reports a transaction whose signature was made for
+// another chain than the expected one.
+type ChainIdMismatchError struct {
+	Signed   *big.Int // Chain id the signature was made for
+	Name     string   // Name of the signing chain, empty if not well-known
+	Expected *big.Int // Chain id the signature was expected for
+}
+
+func (err *ChainIdMismatchError) Error() string {
+	if err.Name != "" {
+		return fmt.Sprintf("transaction was signed for chain %v (%s), this node is chain %v", err.Signed, err.Name, err.Expected)
+	}
+	return fmt.Sprintf("transaction was signed for chain %v, this node is chain %v", err.Signed, err.Expected)
+}
+
+// DiagnoseChainId finds out whether the signature of a transaction was made for
+// another chain than the given one. The sender is recovered under the unprotected
+// signer, the signers of the well-known chains and lastly the signer of the chain
+// id encoded in the signature. Nil is returned if the signature is valid for the
+// given chain, or for none of those tried.
+func DiagnoseChainId(tx TransactionInterface, chainID *big.Int) *ChainIdMismatchError {
+	// Unprotected signatures are valid on any chain
+	if _, err := tx.AsMessage(HomesteadSigner{}); err == nil {
+		return nil
+	}
+	candidates := make([]*big.Int, 0, len(knownChains)+1)
+	for _, chain := range knownChains {
+		candidates = append(candidates, big.NewInt(chain.id))
+	}
+	candidates = append(candidates, tx.ChainId())
+
+	for _, id := range candidates {
+		if id.Sign() == 0 || id.Cmp(chainID) == 0 {
+			continue
+		}
+		if _, err := tx.AsMessage(NewEIP155Signer(id)); err != nil {
+			continue
+		}
+		mismatch := &ChainIdMismatchError{Signed: id, Expected: chainID}
+		for _, chain := range knownChains {
+			if id.Cmp(big.NewInt(chain.id)) == 0 {
+				mismatch.Name = chain.name
+			}
+		}
+		return mismatch
+	}
+	return nil
+}
+
 // Signer encapsulates transaction signature handling. Note that this interface is not a
 // stable API and may change at any time to accommodate new protocol rules.
 //
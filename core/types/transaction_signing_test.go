@@ -136,3 +136,83 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+// Tests that signatures with an S value in the upper half of the curve order are
+// rejected under the homestead rules only, and invalid recovery ids always.
+func TestValidateSignatureValues(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tx, err := SignTx(NewTransaction(0, common.Address{1}, new(big.Int), 0, new(big.Int), nil, Main, Main), NewEIP155Signer(big.NewInt(18)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := tx.data
+	origin := NewOriginTransaction(tx).data
+
+	// Flipping S to the upper half and the recovery id keeps the signature valid
+	malleable := valid
+	malleable.S = new(big.Int).Sub(crypto.S256().Params().N, valid.S)
+	if malleable.V.Bit(0) == 1 {
+		malleable.V = new(big.Int).Add(valid.V, common.Big1)
+	} else {
+		malleable.V = new(big.Int).Sub(valid.V, common.Big1)
+	}
+	invalidV := valid
+	invalidV.V = big.NewInt(29)
+
+	tests := []struct {
+		data      TxdataInterface
+		homestead bool
+		err       error
+	}{
+		{&valid, true, nil},
+		{&valid, false, nil},
+		{&origin, true, nil},
+		{&malleable, true, ErrMalleableSig},
+		{&malleable, false, nil},
+		{&invalidV, false, ErrInvalidSig},
+	}
+	for i, tt := range tests {
+		if err := ValidateSignatureValues(tt.data, tt.homestead); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that transactions signed for other chains are diagnosed with the chain
+// they were signed for, while those valid for the expected chain aren't.
+func TestDiagnoseChainId(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	expected := big.NewInt(206)
+
+	tests := []struct {
+		signer Signer
+		want   string // Empty if no mismatch is expected
+	}{
+		{NewEIP155Signer(expected), ""},
+		{HomesteadSigner{}, ""},
+		{NewEIP155Signer(big.NewInt(1)), "transaction was signed for chain 1 (Ethereum mainnet), this node is chain 206"},
+		{NewEIP155Signer(big.NewInt(56)), "transaction was signed for chain 56 (BNB Smart Chain), this node is chain 206"},
+		{NewEIP155Signer(big.NewInt(777)), "transaction was signed for chain 777, this node is chain 206"},
+	}
+	for i, tt := range tests {
+		tx, err := SignTx(NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil, Main, Main), tt.signer, key)
+		if err != nil {
+			t.Fatalf("test %d: failed to sign transaction: %v", i, err)
+		}
+		mismatch := DiagnoseChainId(tx, expected)
+		switch {
+		case tt.want == "" && mismatch != nil:
+			t.Errorf("test %d: unexpected mismatch: %v", i, mismatch)
+		case tt.want != "" && mismatch == nil:
+			t.Errorf("test %d: mismatch not diagnosed", i)
+		case tt.want != "" && mismatch.Error() != tt.want:
+			t.Errorf("test %d: diagnosis mismatch: have %q, want %q", i, mismatch, tt.want)
+		}
+	}
+	// Signatures that are invalid for any chain aren't blamed on the chain id
+	tx, _ := SignTx(NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil, Main, Main), NewEIP155Signer(big.NewInt(1)), key)
+	tx.data.S = new(big.Int)
+	if mismatch := DiagnoseChainId(tx, expected); mismatch != nil {
+		t.Errorf("invalid signature diagnosed: %v", mismatch)
+	}
+}
@@ -21,7 +21,9 @@ import (
 	"crypto/ecdsa"
 	"encoding/json"
 	"math/big"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/BerithFoundation/berith-chain/common"
 	"github.com/BerithFoundation/berith-chain/crypto"
@@ -181,6 +183,106 @@ func TestTransactionPriceNonceSort(t *testing.T) {
 	}
 }
 
+// Tests that the first-seen ordering yields transactions oldest first while
+// still honouring the nonce order of every account.
+func TestTransactionTimeNonceSort(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 5)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	signer := HomesteadSigner{}
+
+	// Higher prices are seen later, so price ordering would be the exact reverse
+	var (
+		groups = map[common.Address]Transactions{}
+		seen   = make(map[common.Hash]time.Time)
+		base   = time.Now()
+	)
+	for start, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		for i := 0; i < 5; i++ {
+			tx, _ := SignTx(NewTransaction(uint64(i), common.Address{}, big.NewInt(100), 100, big.NewInt(int64(start*5+i)), nil, Main, Main), signer, key)
+			groups[addr] = append(groups[addr], tx)
+			seen[tx.Hash()] = base.Add(time.Duration(start*5+i) * time.Second)
+		}
+	}
+	txset := NewTransactionsByTimeAndNonce(signer, groups, func(hash common.Hash) time.Time { return seen[hash] })
+
+	txs := Transactions{}
+	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+		txs = append(txs, tx)
+		txset.Shift()
+	}
+	if len(txs) != 5*5 {
+		t.Fatalf("expected %d transactions, found %d", 5*5, len(txs))
+	}
+	for i := 1; i < len(txs); i++ {
+		if seen[txs[i-1].Hash()].After(seen[txs[i].Hash()]) {
+			t.Errorf("invalid time ordering: tx #%d seen after tx #%d", i-1, i)
+		}
+	}
+}
+
+// Tests that the round-robin ordering hands out one transaction per account
+// in turn and drops an account entirely on Pop.
+func TestTransactionRoundRobinSort(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	signer := HomesteadSigner{}
+
+	// The first account floods the set with expensive transactions
+	groups := map[common.Address]Transactions{}
+	for start, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		count := 1
+		if start == 0 {
+			count = 4
+		}
+		for i := 0; i < count; i++ {
+			tx, _ := SignTx(NewTransaction(uint64(i), common.Address{}, big.NewInt(100), 100, big.NewInt(int64(1000-start)), nil, Main, Main), signer, key)
+			groups[addr] = append(groups[addr], tx)
+		}
+	}
+	txset := NewTransactionsByRoundRobin(signer, groups)
+
+	var senders []common.Address
+	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+		from, _ := Sender(signer, tx)
+		senders = append(senders, from)
+		txset.Shift()
+	}
+	if len(senders) != 6 {
+		t.Fatalf("expected %d transactions, found %d", 6, len(senders))
+	}
+	// Every account must be served before any account is served twice
+	served := make(map[common.Address]bool)
+	for _, from := range senders[:3] {
+		if served[from] {
+			t.Fatalf("account %x served twice in the first round", from[:4])
+		}
+		served[from] = true
+	}
+	flooder := crypto.PubkeyToAddress(keys[0].PublicKey)
+	for i, from := range senders[3:] {
+		if from != flooder {
+			t.Errorf("tx #%d: sender mismatch: have %x, want %x", i+3, from[:4], flooder[:4])
+		}
+	}
+	// Popping drops the account together with all its remaining transactions
+	groups = map[common.Address]Transactions{}
+	for i := 0; i < 3; i++ {
+		tx, _ := SignTx(NewTransaction(uint64(i), common.Address{}, big.NewInt(100), 100, big.NewInt(1), nil, Main, Main), signer, keys[0])
+		groups[flooder] = append(groups[flooder], tx)
+	}
+	txset = NewTransactionsByRoundRobin(signer, groups)
+	txset.Pop()
+	if tx := txset.Peek(); tx != nil {
+		t.Errorf("expected empty set after pop, found tx with nonce %d", tx.Nonce())
+	}
+}
+
 // TestTransactionJSON tests serializing/de-serializing to/from JSON.
 func TestTransactionJSON(t *testing.T) {
 	key, err := crypto.GenerateKey()
@@ -229,6 +331,100 @@ func TestTransactionJSON(t *testing.T) {
 	}
 }
 
+// Tests that NormalizeTxJSON decodes legacy and Berith transactions into their
+// own data types and rejects typed or unknown shapes.
+func TestNormalizeTxJSON(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := NewEIP155Signer(common.Big1)
+
+	tx, err := SignTx(NewTransaction(1, common.Address{1}, common.Big1, 21000, common.Big2, []byte("abcdef"), Main, Stake), signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	berith, _ := json.Marshal(tx)
+	legacy, _ := json.Marshal(NewOriginTransaction(tx))
+
+	// Decode both shapes and ensure they match the originals
+	data, err := NormalizeTxJSON(berith)
+	if err != nil {
+		t.Fatalf("failed to normalize berith transaction: %v", err)
+	}
+	if _, ok := data.(*txdata); !ok {
+		t.Errorf("berith transaction: type mismatch: have %T, want %T", data, new(txdata))
+	}
+	if have, want := data.Tx().Hash(), tx.Hash(); have != want {
+		t.Errorf("berith transaction: hash mismatch: have %x, want %x", have, want)
+	}
+	data, err = NormalizeTxJSON(legacy)
+	if err != nil {
+		t.Fatalf("failed to normalize legacy transaction: %v", err)
+	}
+	if _, ok := data.(*originTxdata); !ok {
+		t.Errorf("legacy transaction: type mismatch: have %T, want %T", data, new(originTxdata))
+	}
+	origin := &OriginTransaction{data: NewOriginTransaction(tx).data} // drop the cached berith hash
+	if have, want := data.Tx().Hash(), origin.Hash(); have != want {
+		t.Errorf("legacy transaction: hash mismatch: have %x, want %x", have, want)
+	}
+	// Reshape the legacy transaction and ensure the shapes are rejected
+	reshape := func(field, value string) []byte {
+		var fields map[string]json.RawMessage
+		json.Unmarshal(legacy, &fields)
+		fields[field] = json.RawMessage(value)
+		blob, _ := json.Marshal(fields)
+		return blob
+	}
+	tests := []struct {
+		input []byte
+		err   error
+	}{
+		{reshape("type", `"0x1"`), ErrTxTypeNotSupported},
+		{reshape("accessList", `[]`), ErrTxTypeNotSupported},
+		{reshape("base", `1`), errUnknownTxShape},
+		{reshape("nonce", `null`), nil},
+		{[]byte(`[1, 2]`), errUnknownTxShape},
+	}
+	for i, tt := range tests {
+		data, err := NormalizeTxJSON(tt.input)
+		if err == nil {
+			t.Errorf("test %d: shape accepted as %T", i, data)
+			continue
+		}
+		if tt.err != nil && !strings.HasPrefix(err.Error(), tt.err.Error()) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that the hash of the legacy transaction data is cached, stable and
+// matches the keccak of its RLP encoding.
+func TestOriginTxdataHash(t *testing.T) {
+	data := NewOriginTransaction(rightvrsTx).data
+	data.Hash = nil
+
+	blob, err := rlp.EncodeToBytes(&data)
+	if err != nil {
+		t.Fatalf("failed to encode transaction data: %v", err)
+	}
+	want := crypto.Keccak256Hash(blob)
+
+	if have := data.computeHash(); have != want {
+		t.Errorf("hash mismatch: have %x, want %x", have, want)
+	}
+	if data.Hash == nil || *data.Hash != want {
+		t.Errorf("hash not cached: have %v, want %x", data.Hash, want)
+	}
+	if have := data.computeHash(); have != want {
+		t.Errorf("recomputed hash mismatch: have %x, want %x", have, want)
+	}
+	if have := data.TxHash(); have != want {
+		t.Errorf("accessor hash mismatch: have %x, want %x", have, want)
+	}
+	if have := (&OriginTransaction{data: data}).Hash(); have != want {
+		t.Errorf("transaction hash mismatch: have %x, want %x", have, want)
+	}
+}
+
 type originTxData struct {
 	AccountNonce uint64          `json:"nonce"    gencodec:"required"`
 	Price        *big.Int        `json:"gasPrice" gencodec:"required"`
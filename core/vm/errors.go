@@ -31,4 +31,6 @@ var (
 	ErrWriteProtection          = errors.New("write protection")
 	ErrReturnDataOutOfBounds    = errors.New("return data out of bounds")
 	ErrGasUintOverflow          = errors.New("gas uint64 overflow")
+	ErrMaxMemorySizeExceeded    = errors.New("max memory size exceeded")
+	ErrExecutionAborted         = errors.New("execution aborted")
 )
@@ -24,9 +24,14 @@ import (
 	"github.com/BerithFoundation/berith-chain/common"
 	"github.com/BerithFoundation/berith-chain/common/math"
 	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/metrics"
 	"github.com/BerithFoundation/berith-chain/params"
 )
 
+// abortCounter counts the executions cancelled through EVM.Cancel, once per
+// top level call.
+var abortCounter = metrics.NewRegisteredCounter("vm/aborts", nil)
+
 // Config are the configuration options for the Interpreter
 type Config struct {
 	// Debug enabled debugging Interpreter options
@@ -50,6 +55,15 @@ type Config struct {
 
 	ExtraEips []int // Additional EIPS that are to be enabled
 
+	// GasOverrides replaces the constant gas of the given opcodes, applied on
+	// top of the jump table and the enabled EIPs. Meant for experiments on
+	// private chains, undefined opcodes are ignored.
+	GasOverrides map[OpCode]uint64
+
+	// MaxMemorySize caps the memory of a call frame in bytes, independently of
+	// the gas metering (0 = unlimited). Capping it changes which transactions
+	// succeed, so every node of a chain has to agree on it.
+	MaxMemorySize uint64
 }
 
 // Interpreter is used to run Berith based contracts and will utilise the
@@ -93,6 +107,8 @@ type EVMInterpreter struct {
 
 	readOnly   bool   // Whether to throw on stateful modifications
 	returnData []byte // Last CALL's return data for subsequent reuse
+
+	refund int64 // Refund counter change of the last top level call, only tracked in debug mode
 }
 
 // NewEVMInterpreter returns a new instance of the Interpreter.
@@ -113,14 +129,30 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 			cfg.JumpTable = frontierInstructionSet
 		}
 	}
-	cfg.ExtraEips = append(cfg.ExtraEips, []int{2929, 2200, 1884, 1344}...)
-	log.Warn("NewEVMInterpreter", "ExtraEips", cfg.ExtraEips)
-	for i, eip := range cfg.ExtraEips {
+	requested := append(append([]int{}, cfg.ExtraEips...), 2929, 2200, 1884, 1344)
+	log.Warn("NewEVMInterpreter", "ExtraEips", requested)
+
+	// Keep only the activated EIPs, so caller can check if it's activated or not.
+	// A fresh slice is filled, as removing the failed ones while iterating would
+	// skip the EIP following each of them.
+	cfg.ExtraEips = make([]int, 0, len(requested))
+	for _, eip := range requested {
+		if containsEIP(cfg.ExtraEips, eip) {
+			continue
+		}
 		if err := EnableEIP(eip, &cfg.JumpTable); err != nil {
-			// Disable it, so caller can check if it's activated or not
-			cfg.ExtraEips = append(cfg.ExtraEips[:i], cfg.ExtraEips[i+1:]...)
 			log.Error("EIP activation failed", "eip", eip, "error", err)
+			continue
 		}
+		cfg.ExtraEips = append(cfg.ExtraEips, eip)
+	}
+	// Apply the gas overrides last, as the EIPs reprice some of the opcodes
+	for op, gas := range cfg.GasOverrides {
+		if cfg.JumpTable[op].execute == nil {
+			log.Error("Gas override of undefined opcode", "opcode", op, "gas", gas)
+			continue
+		}
+		cfg.JumpTable[op].constantGas = gas
 	}
 
 	return &EVMInterpreter{
@@ -130,6 +162,22 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 	}
 }
 
+// containsEIP reports whether the EIP is in the list.
+func containsEIP(eips []int, eip int) bool {
+	for _, e := range eips {
+		if e == eip {
+			return true
+		}
+	}
+	return false
+}
+
+// EnabledEIPs returns the EIPs successfully applied to the jump table of the
+// interpreter, in the order they were applied.
+func (in *EVMInterpreter) EnabledEIPs() []int {
+	return append([]int{}, in.cfg.ExtraEips...)
+}
+
 func (in *EVMInterpreter) enforceRestrictions(op OpCode, operation operation, stack *Stack) error {
 	if in.evm.chainRules.IsByzantium {
 		if in.readOnly {
@@ -199,6 +247,10 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	contract.Input = input
 
 	if in.cfg.Debug {
+		// Track the refunds granted during the call, including its child calls
+		refund := in.evm.StateDB.GetRefund()
+		defer func() { in.captureRefund(contract, refund) }()
+
 		defer func() {
 			if err != nil {
 				if !logged {
@@ -270,6 +322,10 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		// 	return nil, ErrOutOfGas
 		// }
 		if memorySize > 0 {
+			// Refuse the expansion beyond the cap, as the gas doesn't bound it
+			if in.cfg.MaxMemorySize > 0 && memorySize > in.cfg.MaxMemorySize {
+				return nil, ErrMaxMemorySizeExceeded
+			}
 			mem.Resize(memorySize)
 		}
 
@@ -303,7 +359,12 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 			pc++
 		}
 	}
-	return nil, nil
+	// The loop only ends without halting if the execution was cancelled
+	log.Debug("EVM execution aborted", "depth", in.evm.depth, "contract", contract.Address(), "pc", pc, "op", op)
+	if in.evm.depth == 1 {
+		abortCounter.Inc(1)
+	}
+	return nil, ErrExecutionAborted
 }
 
 // CanRun tells if the contract, passed as an argument, can be
@@ -311,3 +372,23 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 func (in *EVMInterpreter) CanRun(code []byte) bool {
 	return true
 }
+
+// captureRefund logs the change of the refund counter since the start of the
+// call and keeps it for Refund if the call is a top level one.
+func (in *EVMInterpreter) captureRefund(contract *Contract, start uint64) {
+	refund := int64(in.evm.StateDB.GetRefund()) - int64(start)
+	if refund != 0 {
+		log.Debug("Gas refund", "depth", in.evm.depth, "contract", contract.Address(), "refund", refund, "total", in.evm.StateDB.GetRefund())
+	}
+	if in.evm.depth == 1 {
+		in.refund = refund
+	}
+}
+
+// Refund returns the change of the gas refund counter during the last top level
+// call, including the refunds of its child calls. It is only tracked if the
+// interpreter runs in debug mode. Note that the gas metering of the interpreter
+// is disabled, so that no refunds are granted until it is restored.
+func (in *EVMInterpreter) Refund() int64 {
+	return in.refund
+}
@@ -0,0 +1,214 @@
+package vm
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/core/state"
+	"github.com/BerithFoundation/berith-chain/metrics"
+	"github.com/BerithFoundation/berith-chain/params"
+)
+
+// Tests that in debug mode the interpreter reports the refund of a call clearing
+// a storage slot, and that no refund is tracked otherwise.
+func TestInterpreterRefund(t *testing.T) {
+	var (
+		db      = state.NewDatabase(berithdb.NewMemDatabase())
+		address = common.BytesToAddress([]byte("contract"))
+		slot    = common.BigToHash(big.NewInt(1))
+		// PUSH1 0x00 PUSH1 0x01 SSTORE STOP
+		code = []byte{byte(PUSH1), 0x00, byte(PUSH1), 0x01, byte(SSTORE), byte(STOP)}
+	)
+	statedb, _ := state.New(common.Hash{}, db)
+	statedb.SetCode(address, code)
+	statedb.SetState(address, slot, common.BigToHash(big.NewInt(1)))
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	// The interpreter doesn't meter gas, charge the dynamic gas of SSTORE as
+	// the restored metering would
+	jumpTable := constantinopleInstructionSet
+	jumpTable[SSTORE].execute = func(pc *uint64, in *EVMInterpreter, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+		if _, err := in.cfg.JumpTable[SSTORE].dynamicGas(in.gasTable, in.evm, contract, stack, memory, 0); err != nil {
+			return nil, err
+		}
+		return opSstore(pc, in, contract, memory, stack)
+	}
+	for _, debug := range []bool{false, true} {
+		statedb, _ := state.New(root, db)
+		evm := NewEVM(Context{BlockNumber: big.NewInt(1)}, statedb, params.MainnetChainConfig, Config{
+			Debug:     debug,
+			Tracer:    NewStructLogger(nil),
+			JumpTable: jumpTable,
+		})
+		in := evm.Interpreter().(*EVMInterpreter)
+
+		contract := NewContract(AccountRef(common.Address{}), AccountRef(address), new(big.Int), 100000)
+		contract.SetCallCode(&address, statedb.GetCodeHash(address), code)
+		if _, err := in.Run(contract, nil, false); err != nil {
+			t.Fatalf("debug %v: failed to run contract: %v", debug, err)
+		}
+		if have := statedb.GetState(address, slot); have != (common.Hash{}) {
+			t.Fatalf("debug %v: slot not cleared: %x", debug, have)
+		}
+		want := int64(0)
+		if debug {
+			want = int64(params.SstoreClearsScheduleRefundEIP2200)
+		}
+		if have := in.Refund(); have != want {
+			t.Errorf("debug %v: refund mismatch: have %d, want %d", debug, have, want)
+		}
+	}
+}
+
+// Tests that the interpreter reports the EIPs it activated, leaving out the
+// unknown ones and those requested twice.
+func TestInterpreterEnabledEIPs(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(berithdb.NewMemDatabase()))
+
+	requested := []int{2200, 9999, 1344, 8888, 2929}
+	evm := NewEVM(Context{BlockNumber: big.NewInt(1)}, statedb, params.MainnetChainConfig, Config{ExtraEips: requested})
+	have := evm.Interpreter().(*EVMInterpreter).EnabledEIPs()
+
+	// The EIPs enabled by default follow the requested ones
+	want := []int{2200, 1344, 2929, 1884}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("enabled EIPs mismatch: have %v, want %v", have, want)
+	}
+	if !reflect.DeepEqual(requested, []int{2200, 9999, 1344, 8888, 2929}) {
+		t.Errorf("requested EIPs modified: %v", requested)
+	}
+}
+
+// Tests that the gas overrides replace the constant gas of the opcodes after
+// the EIPs are applied, and that overrides of undefined opcodes are ignored.
+func TestInterpreterGasOverrides(t *testing.T) {
+	var (
+		address = common.BytesToAddress([]byte("contract"))
+		// PUSH1 0x01 SLOAD STOP
+		code = []byte{byte(PUSH1), 0x01, byte(SLOAD), byte(STOP)}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(berithdb.NewMemDatabase()))
+	statedb.SetCode(address, code)
+
+	tracer := NewStructLogger(nil)
+	evm := NewEVM(Context{BlockNumber: big.NewInt(1)}, statedb, params.MainnetChainConfig, Config{
+		Debug:        true,
+		Tracer:       tracer,
+		GasOverrides: map[OpCode]uint64{SLOAD: 1234, OpCode(0x0c): 1},
+	})
+	in := evm.Interpreter().(*EVMInterpreter)
+	if have := in.cfg.JumpTable[SLOAD].constantGas; have != 1234 {
+		t.Fatalf("SLOAD gas mismatch: have %d, want %d", have, 1234)
+	}
+	if in.cfg.JumpTable[OpCode(0x0c)].execute != nil {
+		t.Fatalf("undefined opcode defined by gas override")
+	}
+	// The interpreter doesn't meter gas, check the cost the tracer is reported
+	contract := NewContract(AccountRef(common.Address{}), AccountRef(address), new(big.Int), 100000)
+	contract.SetCallCode(&address, statedb.GetCodeHash(address), code)
+	if _, err := in.Run(contract, nil, false); err != nil {
+		t.Fatalf("failed to run contract: %v", err)
+	}
+	var found bool
+	for _, step := range tracer.StructLogs() {
+		if step.Op != SLOAD {
+			continue
+		}
+		found = true
+		if step.GasCost != 1234 {
+			t.Errorf("SLOAD cost mismatch: have %d, want %d", step.GasCost, 1234)
+		}
+	}
+	if !found {
+		t.Errorf("SLOAD not traced")
+	}
+}
+
+// Tests that memory expansions beyond the configured cap are refused, while
+// those up to the cap succeed.
+func TestInterpreterMaxMemorySize(t *testing.T) {
+	address := common.BytesToAddress([]byte("contract"))
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(berithdb.NewMemDatabase()))
+
+	tests := []struct {
+		offset uint32 // Offset of the byte stored, expanding the memory up to it
+		err    error
+	}{
+		{0xffff, nil},
+		{0x10000, ErrMaxMemorySizeExceeded},
+		{0xffffff, ErrMaxMemorySizeExceeded},
+	}
+	for i, tt := range tests {
+		// PUSH1 0x01 PUSH3 <offset> MSTORE8 STOP
+		code := []byte{byte(PUSH1), 0x01, byte(PUSH3), byte(tt.offset >> 16), byte(tt.offset >> 8), byte(tt.offset), byte(MSTORE8), byte(STOP)}
+		statedb.SetCode(address, code)
+
+		evm := NewEVM(Context{BlockNumber: big.NewInt(1)}, statedb, params.MainnetChainConfig, Config{MaxMemorySize: 0x10000})
+		contract := NewContract(AccountRef(common.Address{}), AccountRef(address), new(big.Int), 100000)
+		contract.SetCallCode(&address, statedb.GetCodeHash(address), code)
+		if _, err := evm.Interpreter().Run(contract, nil, false); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// Without a configured cap the memory is unlimited
+	code := []byte{byte(PUSH1), 0x01, byte(PUSH3), 0xff, 0xff, 0xff, byte(MSTORE8), byte(STOP)}
+	statedb.SetCode(address, code)
+
+	evm := NewEVM(Context{BlockNumber: big.NewInt(1)}, statedb, params.MainnetChainConfig, Config{})
+	contract := NewContract(AccountRef(common.Address{}), AccountRef(address), new(big.Int), 100000)
+	contract.SetCallCode(&address, statedb.GetCodeHash(address), code)
+	if _, err := evm.Interpreter().Run(contract, nil, false); err != nil {
+		t.Errorf("uncapped expansion failed: %v", err)
+	}
+}
+
+// Tests that cancelling the EVM midway aborts the execution of an endless loop
+// with a distinct error, counted once for the top level call.
+func TestInterpreterAbort(t *testing.T) {
+	var (
+		db      = state.NewDatabase(berithdb.NewMemDatabase())
+		address = common.BytesToAddress([]byte("contract"))
+		// JUMPDEST PUSH1 0x00 JUMP
+		code = []byte{byte(JUMPDEST), byte(PUSH1), 0x00, byte(JUMP)}
+	)
+	statedb, _ := state.New(common.Hash{}, db)
+	statedb.SetCode(address, code)
+
+	evm := NewEVM(Context{BlockNumber: big.NewInt(1)}, statedb, params.MainnetChainConfig, Config{})
+	contract := NewContract(AccountRef(common.Address{}), AccountRef(address), new(big.Int), 100000)
+	contract.SetCallCode(&address, statedb.GetCodeHash(address), code)
+
+	// Count the aborts even though the metrics are disabled
+	defer func(counter metrics.Counter) { abortCounter = counter }(abortCounter)
+	abortCounter = metrics.NewCounterForced()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		evm.Cancel()
+	}()
+	done := make(chan error, 1)
+	go func() {
+		_, err := evm.Interpreter().Run(contract, nil, false)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != ErrExecutionAborted {
+			t.Errorf("error mismatch: have %v, want %v", err, ErrExecutionAborted)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("execution not aborted")
+	}
+	if !evm.Cancelled() {
+		t.Errorf("evm not reported cancelled")
+	}
+	if have := abortCounter.Count(); have != 1 {
+		t.Errorf("aborts counted: have %d, want 1", have)
+	}
+}
@@ -203,14 +203,17 @@ func (s *PublicAccountAPI) Accounts() []common.Address {
 type PrivateAccountAPI struct {
 	am        *accounts.Manager
 	nonceLock *AddrLocker
+	guard     *passwordGuard
 	b         Backend
 }
 
-// NewPrivateAccountAPI create a new PrivateAccountAPI.
-func NewPrivateAccountAPI(b Backend, nonceLock *AddrLocker) *PrivateAccountAPI {
+// NewPrivateAccountAPI create a new PrivateAccountAPI, limiting the password
+// attempts as configured.
+func NewPrivateAccountAPI(b Backend, nonceLock *AddrLocker, guard PasswordGuardConfig) *PrivateAccountAPI {
 	return &PrivateAccountAPI{
 		am:        b.AccountManager(),
 		nonceLock: nonceLock,
+		guard:     newPasswordGuard(guard),
 		b:         b,
 	}
 }
@@ -325,7 +328,8 @@ func (s *PrivateAccountAPI) ImportRawKey(privkey string, password string) (commo
 // UnlockAccount will unlock the account associated with the given address with
 // the given password for duration seconds. If duration is nil it will use a
 // default of 300 seconds. It returns an indication if the account was unlocked.
-func (s *PrivateAccountAPI) UnlockAccount(addr common.Address, password string, duration *uint64) (bool, error) {
+// After too many failed attempts, the attempts are refused for a while.
+func (s *PrivateAccountAPI) UnlockAccount(ctx context.Context, addr common.Address, password string, duration *uint64) (bool, error) {
 	const max = uint64(time.Duration(math.MaxInt64) / time.Second)
 	var d time.Duration
 	if duration == nil {
@@ -335,13 +339,19 @@ func (s *PrivateAccountAPI) UnlockAccount(addr common.Address, password string,
 	} else {
 		d = time.Duration(*duration) * time.Second
 	}
-	err := fetchKeystore(s.am).TimedUnlock(accounts.Account{Address: addr}, password, d)
-	if err != nil {
-		log.Warn("Failed account unlock attempt", "address", addr, "err", err)
-	}
+	err := s.guardPassword(ctx, "personal_unlockAccount", addr, func() error {
+		return fetchKeystore(s.am).TimedUnlock(accounts.Account{Address: addr}, password, d)
+	})
 	return err == nil, err
 }
 
+// LockoutStatus returns the failed password attempts of the account and of the
+// connection asking, along with the time left until their attempts are allowed
+// again if they are locked out.
+func (s *PrivateAccountAPI) LockoutStatus(ctx context.Context, addr common.Address) *LockoutStatus {
+	return s.guard.status(rpc.PeerInfoFromContext(ctx), addr)
+}
+
 // LockAccount will lock the account associated with the given address when it's unlocked.
 func (s *PrivateAccountAPI) LockAccount(addr common.Address) bool {
 	return fetchKeystore(s.am).Lock(addr) == nil
@@ -381,9 +391,12 @@ func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args BerithSend
 		s.nonceLock.LockAddr(args.From)
 		defer s.nonceLock.UnlockAddr(args.From)
 	}
-	signed, err := s.signTransaction(ctx, &args, passwd)
+	var signed *types.Transaction
+	err := s.guardPassword(ctx, "personal_sendTransaction", args.From, func() (err error) {
+		signed, err = s.signTransaction(ctx, &args, passwd)
+		return err
+	})
 	if err != nil {
-		log.Warn("Failed transaction send attempt", "from", args.From, "to", args.To, "value", args.Value.ToInt(), "err", err)
 		return common.Hash{}, err
 	}
 	return submitTransaction(ctx, s.b, signed)
@@ -405,9 +418,12 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args BerithSend
 	if args.Nonce == nil {
 		return nil, fmt.Errorf("nonce not specified")
 	}
-	signed, err := s.signTransaction(ctx, &args, passwd)
+	var signed *types.Transaction
+	err := s.guardPassword(ctx, "personal_signTransaction", args.From, func() (err error) {
+		signed, err = s.signTransaction(ctx, &args, passwd)
+		return err
+	})
 	if err != nil {
-		log.Warn("Failed transaction sign attempt", "from", args.From, "to", args.To, "value", args.Value.ToInt(), "err", err)
 		return nil, err
 	}
 	data, err := rlp.EncodeToBytes(signed)
@@ -448,9 +464,12 @@ func (s *PrivateAccountAPI) Sign(ctx context.Context, data hexutil.Bytes, addr c
 		return nil, err
 	}
 	// Assemble sign the data with the wallet
-	signature, err := wallet.SignHashWithPassphrase(account, passwd, signHash(data))
+	var signature []byte
+	err = s.guardPassword(ctx, "personal_sign", addr, func() (err error) {
+		signature, err = wallet.SignHashWithPassphrase(account, passwd, signHash(data))
+		return err
+	})
 	if err != nil {
-		log.Warn("Failed data sign attempt", "address", addr, "err", err)
 		return nil, err
 	}
 	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
@@ -753,6 +772,10 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if err := vmError(); err != nil {
 		return nil, 0, false, err
 	}
+	// Don't pass off an execution cut short as one which ran to its end
+	if evm.Cancelled() {
+		return nil, 0, false, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+	}
 	return res, gas, failed, err
 }
 
@@ -1368,6 +1391,68 @@ func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encod
 	return submitTransaction(ctx, s.b, tx)
 }
 
+// DecodedTransaction is the result of a berith_decodeRawTransaction call.
+type DecodedTransaction struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Value    *hexutil.Big    `json:"value"`
+	Gas      hexutil.Uint64  `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	Data     hexutil.Bytes   `json:"data"`
+	Base     types.JobWallet `json:"base"`
+	Target   types.JobWallet `json:"target"`
+	ChainId  *hexutil.Big    `json:"chainId"`
+	Hash     common.Hash     `json:"hash"`
+	Legacy   bool            `json:"legacy"` // true if encoded without base and target
+}
+
+// DecodeRawTransaction decodes a signed transaction without submitting it to the
+// transaction pool, recovering the sender against the chain id of this node.
+func (s *PublicTransactionPoolAPI) DecodeRawTransaction(encodedTx hexutil.Bytes) (*DecodedTransaction, error) {
+	return decodeRawTransaction(encodedTx, s.b.ChainConfig().ChainID)
+}
+
+// decodeRawTransaction decodes either the extended Berith transaction encoding or
+// the legacy one lacking the base and target wallets.
+func decodeRawTransaction(encodedTx []byte, chainID *big.Int) (*DecodedTransaction, error) {
+	var (
+		tx     types.TransactionInterface = new(types.Transaction)
+		legacy bool
+	)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		origin := new(types.OriginTransaction)
+		if rlp.DecodeBytes(encodedTx, origin) != nil {
+			return nil, fmt.Errorf("malformed transaction rlp: %v", err)
+		}
+		tx, legacy = origin, true
+	}
+	msg, err := tx.AsMessage(types.NewEIP155Signer(chainID))
+	if err != nil {
+		if mismatch := types.DiagnoseChainId(tx, chainID); mismatch != nil {
+			return nil, mismatch
+		}
+		return nil, fmt.Errorf("invalid transaction signature: %v", err)
+	}
+	result := &DecodedTransaction{
+		From:     msg.From(),
+		To:       tx.To(),
+		Value:    (*hexutil.Big)(tx.Value()),
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: (*hexutil.Big)(tx.GasPrice()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     hexutil.Bytes(tx.Data()),
+		Base:     tx.Base(),
+		Target:   tx.Target(),
+		Hash:     tx.Hash(),
+		Legacy:   legacy,
+	}
+	if tx.Protected() {
+		result.ChainId = (*hexutil.Big)(tx.ChainId())
+	}
+	return result, nil
+}
+
 // Sign calculates an ECDSA signature for:
 // keccack256("\x19Berith Signed Message:\n" + len(message) + message).
 //
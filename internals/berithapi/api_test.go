@@ -0,0 +1,68 @@
+package berithapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/crypto"
+	"github.com/BerithFoundation/berith-chain/rlp"
+)
+
+// Tests that raw transactions of every wallet combination, as well as the legacy
+// encoding without wallets, decode back to their signed contents.
+func TestDecodeRawTransaction(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	chainID := big.NewInt(206)
+	signer := types.NewEIP155Signer(chainID)
+
+	tests := []struct {
+		base, target types.JobWallet
+	}{
+		{types.Main, types.Main},
+		{types.Main, types.Stake},
+		{types.Stake, types.Main},
+	}
+	for i, tt := range tests {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), to, big.NewInt(1000), 21000, big.NewInt(1), []byte{0x01}, tt.base, tt.target), signer, key)
+		if err != nil {
+			t.Fatalf("test %d: failed to sign transaction: %v", i, err)
+		}
+		encoded, _ := rlp.EncodeToBytes(tx)
+		decoded, err := decodeRawTransaction(encoded, chainID)
+		if err != nil {
+			t.Fatalf("test %d: failed to decode transaction: %v", i, err)
+		}
+		if decoded.From != from || *decoded.To != to || decoded.Hash != tx.Hash() || decoded.Legacy {
+			t.Errorf("test %d: decoded transaction mismatch: %+v", i, decoded)
+		}
+		if decoded.Base != tt.base || decoded.Target != tt.target {
+			t.Errorf("test %d: wallet mismatch: have %v->%v, want %v->%v", i, decoded.Base, decoded.Target, tt.base, tt.target)
+		}
+		if uint64(decoded.Nonce) != uint64(i) || decoded.Value.ToInt().Int64() != 1000 || decoded.ChainId.ToInt().Cmp(chainID) != 0 {
+			t.Errorf("test %d: field mismatch: %+v", i, decoded)
+		}
+	}
+	// Legacy encoding, signed with the implicit main wallets
+	tx, _ := types.SignTx(types.NewTransaction(7, to, big.NewInt(1000), 21000, big.NewInt(1), nil, types.Main, types.Main), signer, key)
+	encoded, _ := rlp.EncodeToBytes(types.NewOriginTransaction(tx))
+	decoded, err := decodeRawTransaction(encoded, chainID)
+	if err != nil {
+		t.Fatalf("failed to decode legacy transaction: %v", err)
+	}
+	if !decoded.Legacy || decoded.From != from || decoded.Base != types.Main || decoded.Target != types.Main {
+		t.Errorf("legacy transaction mismatch: %+v", decoded)
+	}
+	// Transactions for another chain and corrupted payloads are rejected
+	encoded, _ = rlp.EncodeToBytes(tx)
+	want := "transaction was signed for chain 206, this node is chain 1"
+	if _, err := decodeRawTransaction(encoded, big.NewInt(1)); err == nil || err.Error() != want {
+		t.Errorf("transaction for another chain: error mismatch: have %v, want %q", err, want)
+	}
+	if _, err := decodeRawTransaction(encoded[:len(encoded)-5], chainID); err == nil {
+		t.Errorf("corrupted transaction accepted")
+	}
+}
@@ -0,0 +1,258 @@
+package berithapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/accounts/keystore"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+// auditLog records every password attempt of the personal methods, for the
+// operators to follow who tried to use the accounts of the node.
+var auditLog = log.New("topic", "personal-audit")
+
+// passwordAttemptsExpiry is the time after which the failed password attempts of
+// an account or connection are forgotten, unless the lockout lasts longer.
+const passwordAttemptsExpiry = time.Hour
+
+// PasswordGuardConfig are the limits of the password attempts of the personal
+// methods, per account and per connection. After MaxFailures consecutive failed
+// attempts, the attempts are refused for Backoff, doubled with every further
+// failure up to MaxBackoff. A successful attempt resets the failures.
+type PasswordGuardConfig struct {
+	MaxFailures int           `toml:",omitempty"` // Failed attempts tolerated before backing off, 0 to disable the limits
+	Backoff     time.Duration `toml:",omitempty"` // Lockout after the tolerated failures
+	MaxBackoff  time.Duration `toml:",omitempty"` // Maximum lockout doubled up to, 0 to not double the lockout
+	GuardLocal  bool          `toml:",omitempty"` // Limit the attempts over IPC and in-process connections too
+}
+
+// DefaultPasswordGuardConfig contains the default limits of the password attempts.
+var DefaultPasswordGuardConfig = PasswordGuardConfig{
+	MaxFailures: 5,
+	Backoff:     time.Second,
+	MaxBackoff:  10 * time.Minute,
+}
+
+// passwordAttempts are the consecutive failed password attempts of an account or
+// connection.
+type passwordAttempts struct {
+	failures int
+	inflight int       // Attempts reserved but not recorded yet
+	last     time.Time // Time of the last failure
+	until    time.Time // End of the lockout, zero if not locked out
+}
+
+// passwordGuard limits the password attempts of the personal methods, backing
+// off exponentially after too many failures of an account or a connection.
+type passwordGuard struct {
+	config PasswordGuardConfig
+	now    func() time.Time // Clock of the lockouts, replaced by the tests
+
+	accounts map[common.Address]*passwordAttempts
+	conns    map[string]*passwordAttempts
+	lock     sync.Mutex
+}
+
+// newPasswordGuard creates a guard limiting the password attempts as configured.
+func newPasswordGuard(config PasswordGuardConfig) *passwordGuard {
+	return &passwordGuard{
+		config:   config,
+		now:      time.Now,
+		accounts: make(map[common.Address]*passwordAttempts),
+		conns:    make(map[string]*passwordAttempts),
+	}
+}
+
+// exempt returns whether the attempts over the connection aren't limited.
+func (g *passwordGuard) exempt(info rpc.PeerInfo) bool {
+	return g.config.MaxFailures <= 0 || (info.Local() && !g.config.GuardLocal)
+}
+
+// connKey returns the key the attempts over the connection are counted by, the
+// remote host if known so that reconnecting doesn't reset them.
+func connKey(info rpc.PeerInfo) string {
+	if host, _, err := net.SplitHostPort(info.RemoteAddr); err == nil {
+		return host
+	}
+	return info.String()
+}
+
+// reserve returns an error if the password attempts of the account or those
+// over the connection are locked out, or if the attempts in flight could fail
+// beyond the tolerated failures. Otherwise the attempt is reserved until its
+// outcome is recorded, so that parallel attempts can't bypass the lockout.
+func (g *passwordGuard) reserve(info rpc.PeerInfo, addr common.Address) error {
+	if g.exempt(info) {
+		return nil
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	now := g.now()
+	account, conn := g.accounts[addr], g.conns[connKey(info)]
+
+	wait := time.Duration(0)
+	if account != nil && account.until.After(now) {
+		wait = account.until.Sub(now)
+	}
+	if conn != nil && conn.until.After(now) && conn.until.Sub(now) > wait {
+		wait = conn.until.Sub(now)
+	}
+	if wait > 0 {
+		return fmt.Errorf("too many failed password attempts, retry in %v", common.PrettyDuration(wait))
+	}
+	// Count the attempts in flight as failed, up to the one engaging the lockout
+	crowded := func(attempts *passwordAttempts) bool {
+		return attempts != nil && attempts.inflight > 0 && attempts.failures+attempts.inflight > g.config.MaxFailures
+	}
+	if crowded(account) || crowded(conn) {
+		return errors.New("too many password attempts in progress, retry later")
+	}
+	if account == nil {
+		account = new(passwordAttempts)
+		g.accounts[addr] = account
+	}
+	if conn == nil {
+		conn = new(passwordAttempts)
+		g.conns[connKey(info)] = conn
+	}
+	account.inflight++
+	conn.inflight++
+	return nil
+}
+
+// record counts the outcome of a password attempt of the account over the
+// connection reserved before, locking them out if the password was wrong once
+// too often. Only a successful attempt resets the failures, the attempts failing
+// for other reasons than the password only releasing their reservation.
+func (g *passwordGuard) record(info rpc.PeerInfo, addr common.Address, err error) {
+	if g.exempt(info) {
+		return
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	now := g.now()
+	for _, attempts := range []*passwordAttempts{g.accounts[addr], g.conns[connKey(info)]} {
+		if attempts == nil {
+			continue
+		}
+		if attempts.inflight > 0 {
+			attempts.inflight--
+		}
+		switch err {
+		case nil:
+			attempts.failures, attempts.until = 0, time.Time{}
+		case keystore.ErrDecrypt:
+			g.fail(attempts, now)
+		}
+	}
+	g.prune(now)
+}
+
+// fail counts a failed attempt, locking the attempts out once the tolerated
+// failures are exceeded.
+func (g *passwordGuard) fail(attempts *passwordAttempts, now time.Time) {
+	attempts.failures++
+	attempts.last = now
+	if attempts.failures <= g.config.MaxFailures {
+		return
+	}
+	backoff := g.config.Backoff
+	for i := g.config.MaxFailures + 1; i < attempts.failures && backoff < g.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if g.config.MaxBackoff > 0 && backoff > g.config.MaxBackoff {
+		backoff = g.config.MaxBackoff
+	}
+	attempts.until = now.Add(backoff)
+}
+
+// prune forgets the failed attempts which expired or were reset, so that the
+// attempts of the connections long gone don't pile up.
+func (g *passwordGuard) prune(now time.Time) {
+	expired := func(attempts *passwordAttempts) bool {
+		if attempts.inflight > 0 {
+			return false
+		}
+		return attempts.failures == 0 || (now.Sub(attempts.last) > passwordAttemptsExpiry && !attempts.until.After(now))
+	}
+	for addr, attempts := range g.accounts {
+		if expired(attempts) {
+			delete(g.accounts, addr)
+		}
+	}
+	for conn, attempts := range g.conns {
+		if expired(attempts) {
+			delete(g.conns, conn)
+		}
+	}
+}
+
+// status returns the failed attempts and the remaining lockout of the account
+// and of the connection.
+func (g *passwordGuard) status(info rpc.PeerInfo, addr common.Address) *LockoutStatus {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	now := g.now()
+	report := func(attempts *passwordAttempts) AttemptsStatus {
+		if attempts == nil {
+			return AttemptsStatus{}
+		}
+		status := AttemptsStatus{Failures: attempts.failures}
+		if attempts.until.After(now) {
+			status.Locked = true
+			status.RetryIn = uint64((attempts.until.Sub(now) + time.Second - 1) / time.Second)
+		}
+		return status
+	}
+	return &LockoutStatus{
+		Account:    report(g.accounts[addr]),
+		Connection: report(g.conns[connKey(info)]),
+		Exempt:     g.exempt(info),
+	}
+}
+
+// AttemptsStatus are the consecutive failed password attempts of an account or
+// a connection, and their remaining lockout.
+type AttemptsStatus struct {
+	Failures int    `json:"failures"`
+	Locked   bool   `json:"locked"`
+	RetryIn  uint64 `json:"retryIn"` // Seconds until the lockout ends
+}
+
+// LockoutStatus is the state of the password attempts of an account and of the
+// connection asking for it.
+type LockoutStatus struct {
+	Account    AttemptsStatus `json:"account"`
+	Connection AttemptsStatus `json:"connection"`
+	Exempt     bool           `json:"exempt"` // Whether the attempts over the connection aren't limited
+}
+
+// guardPassword runs a password attempt of a personal method for the account,
+// unless the attempts of the account or over the connection are locked out,
+// and records it in the audit log.
+func (s *PrivateAccountAPI) guardPassword(ctx context.Context, method string, addr common.Address, attempt func() error) error {
+	info := rpc.PeerInfoFromContext(ctx)
+	if err := s.guard.reserve(info, addr); err != nil {
+		auditLog.Warn("Password attempt refused", "method", method, "account", addr, "transport", info.Transport, "remote", info.RemoteAddr, "err", err)
+		return err
+	}
+	err := attempt()
+	s.guard.record(info, addr, err)
+
+	if err != nil {
+		auditLog.Warn("Password attempt failed", "method", method, "account", addr, "transport", info.Transport, "remote", info.RemoteAddr, "err", err)
+	} else {
+		auditLog.Info("Password attempt succeeded", "method", method, "account", addr, "transport", info.Transport, "remote", info.RemoteAddr)
+	}
+	return err
+}
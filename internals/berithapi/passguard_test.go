@@ -0,0 +1,275 @@
+package berithapi
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/accounts"
+	"github.com/BerithFoundation/berith-chain/accounts/keystore"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+// auditRecorder collects the entries of the password audit log.
+type auditRecorder struct {
+	entries []string // Messages followed by the method of the audited attempts
+	lock    sync.Mutex
+}
+
+func (r *auditRecorder) Log(rec *log.Record) error {
+	audit, method := false, ""
+	for i := 0; i+1 < len(rec.Ctx); i += 2 {
+		switch rec.Ctx[i] {
+		case "topic":
+			audit = rec.Ctx[i+1] == "personal-audit"
+		case "method":
+			method, _ = rec.Ctx[i+1].(string)
+		}
+	}
+	if audit {
+		r.lock.Lock()
+		r.entries = append(r.entries, rec.Msg+" "+method)
+		r.lock.Unlock()
+	}
+	return nil
+}
+
+func (r *auditRecorder) count(msg string) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	n := 0
+	for _, entry := range r.entries {
+		if strings.HasPrefix(entry, msg) {
+			n++
+		}
+	}
+	return n
+}
+
+// newGuardedAccountAPI creates a personal API over a keystore holding two
+// accounts, served in-process with the password attempts limited as configured.
+func newGuardedAccountAPI(t *testing.T, config PasswordGuardConfig) (*rpc.Client, *passwordGuard, []common.Address, func()) {
+	dir, err := ioutil.TempDir("", "berithapi-passguard-")
+	if err != nil {
+		t.Fatalf("failed to create temporary keystore: %v", err)
+	}
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	var addrs []common.Address
+	for i := 0; i < 2; i++ {
+		acc, err := ks.NewAccount("secret")
+		if err != nil {
+			t.Fatalf("failed to create account: %v", err)
+		}
+		addrs = append(addrs, acc.Address)
+	}
+	api := &PrivateAccountAPI{am: accounts.NewManager(&accounts.Config{}, ks), nonceLock: new(AddrLocker), guard: newPasswordGuard(config)}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("personal", api); err != nil {
+		t.Fatalf("failed to register personal API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	return client, api.guard, addrs, func() {
+		client.Close()
+		server.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+// Tests that repeated wrong password unlocks lock the account and the connection
+// out with an exponential backoff, and that every attempt is audited.
+func TestPasswordGuardLockout(t *testing.T) {
+	recorder := new(auditRecorder)
+	log.Root().SetHandler(recorder)
+	defer log.Root().SetHandler(log.DiscardHandler())
+
+	client, guard, addrs, teardown := newGuardedAccountAPI(t, PasswordGuardConfig{MaxFailures: 3, Backoff: time.Minute, MaxBackoff: 3 * time.Minute, GuardLocal: true})
+	defer teardown()
+
+	now := time.Unix(1000000, 0)
+	guard.now = func() time.Time { return now }
+
+	unlock := func(addr common.Address, password string) error {
+		var unlocked bool
+		return client.Call(&unlocked, "personal_unlockAccount", addr, password, 1)
+	}
+	status := func(addr common.Address) *LockoutStatus {
+		status := new(LockoutStatus)
+		if err := client.Call(status, "personal_lockoutStatus", addr); err != nil {
+			t.Fatalf("failed to retrieve lockout status: %v", err)
+		}
+		return status
+	}
+	// The tolerated failures are refused by the keystore only
+	for i := 0; i < 3; i++ {
+		if err := unlock(addrs[0], "wrong"); err == nil || err.Error() != keystore.ErrDecrypt.Error() {
+			t.Fatalf("attempt %d: error mismatch: have %v, want %v", i, err, keystore.ErrDecrypt)
+		}
+	}
+	if s := status(addrs[0]); s.Account.Locked || s.Account.Failures != 3 || s.Exempt {
+		t.Fatalf("locked out before exceeding the tolerated failures: %+v", s)
+	}
+	// The next failure locks the account and the connection out
+	if err := unlock(addrs[0], "wrong"); err == nil || err.Error() != keystore.ErrDecrypt.Error() {
+		t.Fatalf("error mismatch: have %v, want %v", err, keystore.ErrDecrypt)
+	}
+	if s := status(addrs[0]); !s.Account.Locked || s.Account.RetryIn != 60 || !s.Connection.Locked {
+		t.Fatalf("lockout not engaged: %+v", s)
+	}
+	for _, addr := range addrs {
+		if err := unlock(addr, "secret"); err == nil || !strings.Contains(err.Error(), "too many failed password attempts") {
+			t.Errorf("attempt during lockout not refused: %v", err)
+		}
+	}
+	// Failing again after the lockout doubles it, up to the maximum
+	now = now.Add(time.Minute)
+	if err := unlock(addrs[0], "wrong"); err == nil || err.Error() != keystore.ErrDecrypt.Error() {
+		t.Fatalf("attempt after lockout not let through: %v", err)
+	}
+	if s := status(addrs[0]); s.Account.RetryIn != 120 {
+		t.Errorf("backoff not doubled: %+v", s)
+	}
+	now = now.Add(2 * time.Minute)
+	unlock(addrs[0], "wrong")
+	now = now.Add(3 * time.Minute)
+	unlock(addrs[0], "wrong")
+	if s := status(addrs[0]); s.Account.RetryIn != 180 || s.Account.Failures != 7 {
+		t.Errorf("backoff not capped: %+v", s)
+	}
+	// A successful attempt after the lockout resets the failures
+	now = now.Add(3 * time.Minute)
+	if err := unlock(addrs[0], "secret"); err != nil {
+		t.Fatalf("failed to unlock after lockout: %v", err)
+	}
+	if s := status(addrs[0]); s.Account != (AttemptsStatus{}) || s.Connection != (AttemptsStatus{}) {
+		t.Errorf("failures not reset: %+v", s)
+	}
+	// Every attempt is audited
+	if have := recorder.count("Password attempt failed personal_unlockAccount"); have != 7 {
+		t.Errorf("failed attempts audited: have %d, want 7", have)
+	}
+	if have := recorder.count("Password attempt refused personal_unlockAccount"); have != 2 {
+		t.Errorf("refused attempts audited: have %d, want 2", have)
+	}
+	if have := recorder.count("Password attempt succeeded personal_unlockAccount"); have != 1 {
+		t.Errorf("successful attempts audited: have %d, want 1", have)
+	}
+}
+
+// Tests that the attempts failing for other reasons than the password, before
+// any decryption, don't reset the failures of the account or the connection.
+func TestPasswordGuardOtherFailures(t *testing.T) {
+	client, guard, addrs, teardown := newGuardedAccountAPI(t, PasswordGuardConfig{MaxFailures: 3, Backoff: time.Hour, GuardLocal: true})
+	defer teardown()
+
+	now := time.Unix(1000000, 0)
+	guard.now = func() time.Time { return now }
+
+	var unlocked bool
+	invalid := map[string]interface{}{
+		"from":     addrs[0],
+		"gas":      "0x5208",
+		"gasPrice": "0x1",
+		"nonce":    "0x0",
+		"data":     "0x01",
+		"input":    "0x02",
+	}
+	for i := 0; i < 4; i++ {
+		if err := client.Call(&unlocked, "personal_unlockAccount", addrs[0], "wrong", 1); err == nil || err.Error() != keystore.ErrDecrypt.Error() {
+			t.Fatalf("attempt %d: error mismatch: have %v, want %v", i, err, keystore.ErrDecrypt)
+		}
+		// An unknown account resets neither the connection nor the account
+		if err := client.Call(&unlocked, "personal_unlockAccount", common.Address{0xff}, "wrong", 1); err == nil {
+			t.Fatalf("attempt %d: unknown account unlocked", i)
+		}
+		// Nor do transactions refused before their key is decrypted
+		var result interface{}
+		for _, method := range []string{"personal_signTransaction", "personal_sendTransaction"} {
+			if err := client.Call(&result, method, invalid, "secret"); err == nil {
+				t.Fatalf("attempt %d: invalid transaction accepted by %s", i, method)
+			}
+		}
+	}
+	status := new(LockoutStatus)
+	if err := client.Call(status, "personal_lockoutStatus", addrs[0]); err != nil {
+		t.Fatalf("failed to retrieve lockout status: %v", err)
+	}
+	if !status.Account.Locked || status.Account.Failures != 4 || !status.Connection.Locked || status.Connection.Failures != 4 {
+		t.Errorf("lockout not engaged: %+v", status)
+	}
+	if err := client.Call(&unlocked, "personal_unlockAccount", addrs[0], "secret", 1); err == nil || !strings.Contains(err.Error(), "too many failed password attempts") {
+		t.Errorf("attempt during lockout not refused: %v", err)
+	}
+}
+
+// Tests that the attempts over local connections aren't limited unless asked to,
+// but are audited nonetheless.
+func TestPasswordGuardLocalExempt(t *testing.T) {
+	recorder := new(auditRecorder)
+	log.Root().SetHandler(recorder)
+	defer log.Root().SetHandler(log.DiscardHandler())
+
+	client, _, addrs, teardown := newGuardedAccountAPI(t, PasswordGuardConfig{MaxFailures: 1, Backoff: time.Hour})
+	defer teardown()
+
+	var unlocked bool
+	for i := 0; i < 5; i++ {
+		if err := client.Call(&unlocked, "personal_unlockAccount", addrs[0], "wrong", 1); err == nil || err.Error() != keystore.ErrDecrypt.Error() {
+			t.Fatalf("attempt %d: error mismatch: have %v, want %v", i, err, keystore.ErrDecrypt)
+		}
+	}
+	status := new(LockoutStatus)
+	if err := client.Call(status, "personal_lockoutStatus", addrs[0]); err != nil {
+		t.Fatalf("failed to retrieve lockout status: %v", err)
+	}
+	if !status.Exempt || status.Account.Failures != 0 {
+		t.Errorf("local attempts limited: %+v", status)
+	}
+	if have := recorder.count("Password attempt failed"); have != 5 {
+		t.Errorf("failed attempts audited: have %d, want 5", have)
+	}
+}
+
+// Tests that concurrent wrong password unlocks can't bypass the lockout, the
+// attempts in flight counting against the tolerated failures.
+func TestPasswordGuardConcurrent(t *testing.T) {
+	client, guard, addrs, teardown := newGuardedAccountAPI(t, PasswordGuardConfig{MaxFailures: 3, Backoff: time.Hour, GuardLocal: true})
+	defer teardown()
+
+	now := time.Unix(1000000, 0)
+	guard.now = func() time.Time { return now }
+
+	var (
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		decrypts int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var unlocked bool
+			err := client.Call(&unlocked, "personal_unlockAccount", addrs[0], "wrong", 1)
+			if err != nil && err.Error() == keystore.ErrDecrypt.Error() {
+				lock.Lock()
+				decrypts++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if decrypts != 4 {
+		t.Errorf("passwords checked by the keystore: have %d, want 4", decrypts)
+	}
+	status := guard.status(rpc.PeerInfo{Transport: rpc.TransportInProc}, addrs[0])
+	if !status.Account.Locked || status.Account.Failures != 4 {
+		t.Errorf("lockout mismatch: %+v", status)
+	}
+}
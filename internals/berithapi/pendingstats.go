@@ -0,0 +1,144 @@
+package berithapi
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/core"
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+const (
+	// pendingStatsTxChanSize is the size of the channel listening to NewTxsEvent,
+	// large enough for the bursts of the pool not to block it.
+	pendingStatsTxChanSize = 4096
+
+	// pendingStatsHeadChanSize is the size of the channel listening to ChainHeadEvent.
+	pendingStatsHeadChanSize = 10
+
+	// defaultPendingStatsCoalesce is the time the events are gathered for before
+	// the pending stats are compared against the last notified ones.
+	defaultPendingStatsCoalesce = 500 * time.Millisecond
+)
+
+// PendingStats is the summary of the transaction pool and the chain head pushed
+// by the pendingStats subscription.
+type PendingStats struct {
+	PendingCount     hexutil.Uint   `json:"pendingCount"`
+	GasPriceFloor    *hexutil.Big   `json:"gasPriceFloor"` // Suggested gas price, the price a transaction should pay at least to be included
+	LastBlockNumber  hexutil.Uint64 `json:"lastBlockNumber"`
+	LastBlockTxCount hexutil.Uint   `json:"lastBlockTxCount"`
+}
+
+// PendingStatsOptions are the thresholds of the pendingStats subscription. The
+// stats are notified once the pending count or the gas price floor changed by
+// at least their threshold since the last notification, or a new block arrived.
+type PendingStatsOptions struct {
+	PendingDelta  uint64       `json:"pendingDelta"`  // Change of the pending count notified, 0 for any change
+	GasPriceDelta *hexutil.Big `json:"gasPriceDelta"` // Change of the gas price floor notified, nil for any change
+	Coalesce      uint64       `json:"coalesce"`      // Milliseconds the events are gathered for, 0 for the default
+}
+
+// exceeded returns whether the stats changed beyond the thresholds since the
+// last notified ones.
+func (opts *PendingStatsOptions) exceeded(last, stats *PendingStats) bool {
+	if last.LastBlockNumber != stats.LastBlockNumber {
+		return true
+	}
+	delta := uint64(last.PendingCount) - uint64(stats.PendingCount)
+	if last.PendingCount < stats.PendingCount {
+		delta = uint64(stats.PendingCount) - uint64(last.PendingCount)
+	}
+	if delta > 0 && delta >= opts.PendingDelta {
+		return true
+	}
+	price := new(big.Int).Sub(last.GasPriceFloor.ToInt(), stats.GasPriceFloor.ToInt())
+	if price.Sign() == 0 {
+		return false
+	}
+	return opts.GasPriceDelta == nil || price.CmpAbs(opts.GasPriceDelta.ToInt()) >= 0
+}
+
+// pendingStats gathers the current summary of the transaction pool and the
+// chain head.
+func (s *PublicBerithAPI) pendingStats(ctx context.Context) (*PendingStats, error) {
+	price, err := s.b.SuggestPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending, _ := s.b.Stats()
+	head := s.b.CurrentBlock()
+
+	return &PendingStats{
+		PendingCount:     hexutil.Uint(pending),
+		GasPriceFloor:    (*hexutil.Big)(price),
+		LastBlockNumber:  hexutil.Uint64(head.NumberU64()),
+		LastBlockTxCount: hexutil.Uint(len(head.Transactions())),
+	}, nil
+}
+
+// PendingStats creates a subscription pushing the summary of the transaction
+// pool and the chain head, first as it is and then whenever it changed beyond
+// the thresholds. The transaction and head events are coalesced, so that busy
+// pools don't flood the subscribers, which only poll it otherwise.
+func (s *PublicBerithAPI) PendingStats(ctx context.Context, options *PendingStatsOptions) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	opts := PendingStatsOptions{}
+	if options != nil {
+		opts = *options
+	}
+	coalesce := defaultPendingStatsCoalesce
+	if opts.Coalesce > 0 {
+		coalesce = time.Duration(opts.Coalesce) * time.Millisecond
+	}
+	last, err := s.pendingStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rpcSub := notifier.CreateSubscription()
+	notifier.Notify(rpcSub.ID, last)
+
+	go func() {
+		txs := make(chan core.NewTxsEvent, pendingStatsTxChanSize)
+		txsSub := s.b.SubscribeNewTxsEvent(txs)
+		defer txsSub.Unsubscribe()
+
+		heads := make(chan core.ChainHeadEvent, pendingStatsHeadChanSize)
+		headsSub := s.b.SubscribeChainHeadEvent(heads)
+		defer headsSub.Unsubscribe()
+
+		// The first event arms the timer, the ones until it fires are coalesced
+		var flush <-chan time.Time
+		for {
+			select {
+			case <-txs:
+				if flush == nil {
+					flush = time.After(coalesce)
+				}
+			case <-heads:
+				if flush == nil {
+					flush = time.After(coalesce)
+				}
+			case <-flush:
+				flush = nil
+				stats, err := s.pendingStats(ctx)
+				if err != nil || !opts.exceeded(last, stats) {
+					continue
+				}
+				notifier.Notify(rpcSub.ID, stats)
+				last = stats
+
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -0,0 +1,168 @@
+package berithapi
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/core"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/event"
+	"github.com/BerithFoundation/berith-chain/rpc"
+)
+
+// statsBackend feeds the pending stats subscription with a pool and a head set
+// by the tests, announcing their changes through the events of a real node.
+type statsBackend struct {
+	Backend // Methods not needed by the subscription panic
+
+	pending int
+	price   *big.Int
+	head    *types.Block
+	lock    sync.Mutex
+
+	txFeed   event.Feed
+	headFeed event.Feed
+}
+
+func (b *statsBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return new(big.Int).Set(b.price), nil
+}
+
+func (b *statsBackend) Stats() (int, int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.pending, 0
+}
+
+func (b *statsBackend) CurrentBlock() *types.Block {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.head
+}
+
+func (b *statsBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return b.txFeed.Subscribe(ch)
+}
+
+func (b *statsBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.headFeed.Subscribe(ch)
+}
+
+// addTxs grows the pending count and announces the new transaction.
+func (b *statsBackend) addTxs(n int) {
+	b.lock.Lock()
+	b.pending += n
+	b.lock.Unlock()
+	b.txFeed.Send(core.NewTxsEvent{})
+}
+
+// setPrice changes the gas price floor, announcing it with a transaction event.
+func (b *statsBackend) setPrice(price int64) {
+	b.lock.Lock()
+	b.price = big.NewInt(price)
+	b.lock.Unlock()
+	b.txFeed.Send(core.NewTxsEvent{})
+}
+
+// newPendingStatsSubscription subscribes in-process to the pending stats of a
+// backend starting with an empty pool at block 10.
+func newPendingStatsSubscription(t *testing.T, options *PendingStatsOptions) (*statsBackend, chan *PendingStats, func()) {
+	backend := &statsBackend{price: big.NewInt(1000), head: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10)})}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("berith", NewPublicBerithAPI(backend)); err != nil {
+		t.Fatalf("failed to register berith API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+
+	stats := make(chan *PendingStats, 100)
+	sub, err := client.Subscribe(context.Background(), "berith", stats, "pendingStats", options)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	select {
+	case first := <-stats:
+		if first.PendingCount != 0 || first.LastBlockNumber != 10 || first.GasPriceFloor.ToInt().Int64() != 1000 {
+			t.Fatalf("initial stats mismatch: %+v", first)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("initial stats not notified")
+	}
+	return backend, stats, func() {
+		sub.Unsubscribe()
+		client.Close()
+		server.Stop()
+	}
+}
+
+// collect returns the stats notified within the duration.
+func collect(stats chan *PendingStats, duration time.Duration) []*PendingStats {
+	var notified []*PendingStats
+	timeout := time.After(duration)
+	for {
+		select {
+		case s := <-stats:
+			notified = append(notified, s)
+		case <-timeout:
+			return notified
+		}
+	}
+}
+
+// Tests that a burst of transaction events is coalesced into few notifications
+// carrying the final stats.
+func TestPendingStatsCoalescing(t *testing.T) {
+	backend, stats, teardown := newPendingStatsSubscription(t, &PendingStatsOptions{Coalesce: 100})
+	defer teardown()
+
+	for i := 0; i < 500; i++ {
+		backend.addTxs(1)
+	}
+	notified := collect(stats, 500*time.Millisecond)
+	if len(notified) == 0 || len(notified) > 3 {
+		t.Fatalf("notifications mismatch: have %d, want 1 to 3", len(notified))
+	}
+	if last := notified[len(notified)-1]; last.PendingCount != 500 {
+		t.Errorf("final pending count mismatch: have %d, want 500", last.PendingCount)
+	}
+}
+
+// Tests that changes below the thresholds aren't notified until they add up to
+// them, and that a new block is notified regardless.
+func TestPendingStatsThresholds(t *testing.T) {
+	backend, stats, teardown := newPendingStatsSubscription(t, &PendingStatsOptions{PendingDelta: 10, GasPriceDelta: (*hexutil.Big)(big.NewInt(100)), Coalesce: 20})
+	defer teardown()
+
+	// Changes below the thresholds are held back
+	backend.addTxs(4)
+	backend.setPrice(1050)
+	if notified := collect(stats, 200*time.Millisecond); len(notified) != 0 {
+		t.Fatalf("change below thresholds notified: %+v", notified[0])
+	}
+	// Changes adding up to a threshold are notified
+	backend.addTxs(6)
+	if notified := collect(stats, 200*time.Millisecond); len(notified) != 1 || notified[0].PendingCount != 10 || notified[0].GasPriceFloor.ToInt().Int64() != 1050 {
+		t.Fatalf("pending threshold notifications mismatch: %+v", notified)
+	}
+	backend.setPrice(950)
+	if notified := collect(stats, 200*time.Millisecond); len(notified) != 1 || notified[0].GasPriceFloor.ToInt().Int64() != 950 {
+		t.Fatalf("gas price threshold notifications mismatch: %+v", notified)
+	}
+	// A new block is notified even if nothing else changed
+	block := types.NewBlock(&types.Header{Number: big.NewInt(11)}, []*types.Transaction{types.NewTransaction(0, common.Address{}, new(big.Int), 21000, big.NewInt(1), nil, types.Main, types.Main)}, nil, nil)
+	backend.lock.Lock()
+	backend.head = block
+	backend.lock.Unlock()
+	backend.headFeed.Send(core.ChainHeadEvent{Block: block})
+
+	if notified := collect(stats, 200*time.Millisecond); len(notified) != 1 || notified[0].LastBlockNumber != 11 || notified[0].LastBlockTxCount != 1 {
+		t.Fatalf("new block notifications mismatch: %+v", notified)
+	}
+}
@@ -20,14 +20,17 @@ package jsre
 import (
 	crand "crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"os"
 	"time"
 
 	"github.com/BerithFoundation/berith-chain/common"
 	"berith-chain/internals/jsre/deps"
+	"github.com/BerithFoundation/berith-chain/log"
 	"github.com/robertkrimen/otto"
 )
 
@@ -36,6 +39,10 @@ var (
 	Web3_JS      = deps.MustAsset("web3.js")
 )
 
+// errScriptTimeout is the panic value used to interrupt a script running for
+// longer than the configured timeout.
+var errScriptTimeout = errors.New("script timeout")
+
 /*
 JSRE is a generic JS runtime environment embedding the otto JS interpreter.
 It provides some helper functions to
@@ -47,6 +54,9 @@ It provides some helper functions to
 type JSRE struct {
 	assetPath     string
 	output        io.Writer
+	maxScriptSize int64                       // Maximum size of script files to execute or load, 0 = unlimited
+	scriptTimeout time.Duration               // Maximum runtime of script files, 0 = unlimited
+	errorHint     func(failure string) string // Hint printed below evaluation errors, if any
 	evalQueue     chan *evalReq
 	stopEventLoop chan bool
 	closed        chan struct{}
@@ -242,21 +252,86 @@ func (re *JSRE) Stop(waitForCallbacks bool) {
 // Exec(file) loads and runs the contents of a file
 // if a relative path is given, the jsre's assetPath is used
 func (re *JSRE) Exec(file string) error {
-	code, err := ioutil.ReadFile(common.AbsolutePath(re.assetPath, file))
+	path := common.AbsolutePath(re.assetPath, file)
+	if err := checkScriptSize(path, file, re.maxScriptSize); err != nil {
+		return err
+	}
+	code, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	var script *otto.Script
+	start := time.Now()
 	re.Do(func(vm *otto.Otto) {
-		script, err = vm.Compile(file, code)
-		if err != nil {
+		if re.scriptTimeout > 0 {
+			defer interruptAfter(vm, re.scriptTimeout)()
+			defer func() {
+				if r := recover(); r != nil {
+					if r != errScriptTimeout {
+						panic(r)
+					}
+					err = fmt.Errorf("script %s interrupted after %v", file, re.scriptTimeout)
+				}
+			}()
+		}
+		var script *otto.Script
+		if script, err = vm.Compile(file, code); err != nil {
 			return
 		}
 		_, err = vm.Run(script)
 	})
+	log.Debug("Executed script", "file", file, "size", len(code), "elapsed", common.PrettyDuration(time.Since(start)), "err", err)
 	return err
 }
 
+// checkScriptSize returns an error if the script file at path exceeds the size
+// limit, if any.
+func checkScriptSize(path, file string, maxSize int64) error {
+	if maxSize <= 0 {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() > maxSize {
+		return fmt.Errorf("script %s too large: %d bytes, limit %d", file, info.Size(), maxSize)
+	}
+	return nil
+}
+
+// SetScriptLimits limits the size of the script files executed by Exec and
+// loadScript, and the time the files executed by Exec may run for. Zero values
+// disable the respective limit.
+func (re *JSRE) SetScriptLimits(maxSize int64, timeout time.Duration) {
+	re.maxScriptSize = maxSize
+	re.scriptTimeout = timeout
+}
+
+// SetErrorHint sets the function returning the hint printed below the errors of
+// Evaluate and EvaluateJSON, nothing being printed if it returns an empty hint.
+func (re *JSRE) SetErrorHint(hint func(failure string) string) {
+	re.errorHint = hint
+}
+
+// interruptAfter arms the interrupt mechanism of the vm to abort the running
+// code with an errScriptTimeout panic once the timeout elapses. The returned
+// function disarms it again.
+func interruptAfter(vm *otto.Otto, timeout time.Duration) func() {
+	interrupt := make(chan func(), 1)
+	vm.Interrupt = interrupt
+
+	timer := time.AfterFunc(timeout, func() {
+		select {
+		case interrupt <- func() { panic(errScriptTimeout) }:
+		default:
+		}
+	})
+	return func() {
+		timer.Stop()
+		vm.Interrupt = nil
+	}
+}
+
 // Bind assigns value v to a variable in the JS environment
 // This method is deprecated, use Set.
 func (re *JSRE) Bind(name string, v interface{}) error {
@@ -289,6 +364,11 @@ func (re *JSRE) loadScript(call otto.FunctionCall) otto.Value {
 		return otto.FalseValue()
 	}
 	file = common.AbsolutePath(re.assetPath, file)
+	if err := checkScriptSize(file, file, re.maxScriptSize); err != nil {
+		// TODO: throw exception
+		log.Warn("Refusing to load script", "err", err)
+		return otto.FalseValue()
+	}
 	source, err := ioutil.ReadFile(file)
 	if err != nil {
 		// TODO: throw exception
@@ -311,7 +391,7 @@ func (re *JSRE) Evaluate(code string, w io.Writer) error {
 	re.Do(func(vm *otto.Otto) {
 		val, err := vm.Run(code)
 		if err != nil {
-			prettyError(vm, err, w)
+			prettyError(vm, err, w, re.errorHint)
 		} else {
 			prettyPrint(vm, val, w)
 		}
@@ -320,6 +400,24 @@ func (re *JSRE) Evaluate(code string, w io.Writer) error {
 	return fail
 }
 
+// EvaluateJSON executes code and prints the result serialized as JSON to the
+// specified output stream, indented by the given number of spaces, or on a single
+// line if indent is 0.
+func (re *JSRE) EvaluateJSON(code string, w io.Writer, indent int) error {
+	var fail error
+
+	re.Do(func(vm *otto.Otto) {
+		val, err := vm.Run(code)
+		if err != nil {
+			prettyError(vm, err, w, re.errorHint)
+		} else {
+			jsonPrint(vm, val, w, indent)
+		}
+		fmt.Fprintln(w)
+	})
+	return fail
+}
+
 // Compile compiles and then runs a piece of JS code.
 func (re *JSRE) Compile(filename string, src interface{}) (err error) {
 	re.Do(func(vm *otto.Otto) { _, err = compileAndRun(vm, filename, src) })
@@ -135,3 +135,43 @@ func TestLoadScript(t *testing.T) {
 	}
 	jsre.Stop(false)
 }
+
+// Tests that script files above the size limit are refused and scripts running
+// past the timeout are interrupted, leaving the runtime usable.
+func TestExecScriptLimits(t *testing.T) {
+	jsre, dir := newWithTestJS(t, "")
+	defer os.RemoveAll(dir)
+	defer jsre.Stop(false)
+
+	if err := ioutil.WriteFile(path.Join(dir, "large.js"), make([]byte, 1024), os.ModePerm); err != nil {
+		t.Fatalf("cannot write large.js: %v", err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "loop.js"), []byte(`while (true) {}`), os.ModePerm); err != nil {
+		t.Fatalf("cannot write loop.js: %v", err)
+	}
+	jsre.SetScriptLimits(512, 100*time.Millisecond)
+
+	if err := jsre.Exec("large.js"); err == nil {
+		t.Errorf("oversized script executed")
+	}
+	if val, err := jsre.Run(`loadScript("large.js")`); err != nil || val != otto.FalseValue() {
+		t.Errorf("oversized script loaded: %v, %v", val, err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- jsre.Exec("loop.js") }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("endless script finished without error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("endless script not interrupted")
+	}
+	val, err := jsre.Run("1 + 1")
+	if err != nil {
+		t.Fatalf("runtime unusable after interrupt: %v", err)
+	}
+	if n, _ := val.ToInteger(); n != 2 {
+		t.Errorf("result mismatch: have %v, want %v", n, 2)
+	}
+}
@@ -38,6 +38,7 @@ var (
 	NumberColor   = color.New(color.FgRed).SprintfFunc()
 	StringColor   = color.New(color.FgGreen).SprintfFunc()
 	ErrorColor    = color.New(color.FgHiRed).SprintfFunc()
+	HintColor     = color.New(color.FgYellow).SprintfFunc()
 )
 
 // these fields are hidden when printing objects.
@@ -56,13 +57,36 @@ func prettyPrint(vm *otto.Otto, value otto.Value, w io.Writer) {
 	ppctx{vm: vm, w: w}.printValue(value, 0, false)
 }
 
-// prettyError writes err to standard output.
-func prettyError(vm *otto.Otto, err error, w io.Writer) {
+// jsonPrint writes value to standard output serialized by JSON.stringify with
+// the given indentation, falling back to pretty printing for values without a
+// JSON representation.
+func jsonPrint(vm *otto.Otto, value otto.Value, w io.Writer, indent int) {
+	json, _ := vm.Object("JSON")
+	out, err := json.Call("stringify", value, nil, indent)
+	if err != nil || !out.IsString() {
+		prettyPrint(vm, value, w)
+		return
+	}
+	fmt.Fprint(w, out.String())
+}
+
+// prettyError writes err to standard output, followed by the hint returned for
+// it by the given function, if any.
+func prettyError(vm *otto.Otto, err error, w io.Writer, hint func(failure string) string) {
 	failure := err.Error()
 	if ottoErr, ok := err.(*otto.Error); ok {
 		failure = ottoErr.String()
 	}
 	fmt.Fprint(w, ErrorColor("%s", failure))
+	if hint == nil {
+		return
+	}
+	if text := hint(failure); text != "" {
+		if !strings.HasSuffix(failure, "\n") {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprint(w, HintColor("%s", text))
+	}
 }
 
 func (re *JSRE) prettyPrintJS(call otto.FunctionCall) otto.Value {
@@ -33,6 +33,8 @@ var Modules = map[string]string{
 	"bsrr":       Bsrr_JS,
 	"debug":      Debug_JS,
 	"berith":     BERITH_JS,
+	"eth":        Eth_JS,
+	"les":        Les_JS,
 	"miner":      Miner_JS,
 	"net":        Net_JS,
 	"personal":   Personal_JS,
@@ -149,6 +151,82 @@ const Bsrr_JS = `
 			name: 'getSigners',
 			call: 'bsrr_getSigners',
 			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'explainRejection',
+			call: 'bsrr_explainRejection',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'compareStakers',
+			call: 'bsrr_compareStakers',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getRewards',
+			call: 'bsrr_getRewards',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'stakersDiff',
+			call: 'bsrr_stakersDiff',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'traceStakerChanges',
+			call: 'bsrr_traceStakerChanges',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'rankStability',
+			call: 'bsrr_rankStability',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'sealHash',
+			call: 'bsrr_sealHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'genesisSigners',
+			call: 'bsrr_genesisSigners'
+		}),
+		new web3._extend.Method({
+			name: 'previewNextBlock',
+			call: 'bsrr_previewNextBlock',
+			params: 1,
+			inputFormatter: [function (addr) { return addr ? web3._extend.formatters.inputAddressFormatter(addr) : null; }]
+		}),
+		new web3._extend.Method({
+			name: 'predictProducers',
+			call: 'bsrr_predictProducers',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'epochInfo',
+			call: 'bsrr_epochInfo',
+			params: 1,
+			inputFormatter: [function (number) { return number == null ? null : web3._extend.formatters.inputBlockNumberFormatter(number); }]
+		}),
+		new web3._extend.Method({
+			name: 'networkParams',
+			call: 'bsrr_networkParams'
+		}),
+		new web3._extend.Method({
+			name: 'getHistoricalVoteResults',
+			call: 'bsrr_getHistoricalVoteResults',
+			params: 1,
+			inputFormatter: [web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'getHistoricalVoteResultsRange',
+			call: 'bsrr_getHistoricalVoteResultsRange',
+			params: 2,
+			inputFormatter: [web3._extend.utils.toHex, web3._extend.utils.toHex]
 		})
  	],
  	properties: []
@@ -169,6 +247,20 @@ web3._extend({
 			call: 'admin_removePeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'addStaticPeer',
+			call: 'admin_addStaticPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removeStaticPeer',
+			call: 'admin_removeStaticPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'staticPeers',
+			call: 'admin_staticPeers'
+		}),
 		new web3._extend.Method({
 			name: 'addTrustedPeer',
 			call: 'admin_addTrustedPeer',
@@ -432,6 +524,12 @@ web3._extend({
 			params: 1,
 			inputFormatter: [null]
 		}),
+		new web3._extend.Method({
+			name: 'blockTimings',
+			call: 'debug_blockTimings',
+			params: 1,
+			inputFormatter: [null]
+		}),
 		new web3._extend.Method({
 			name: 'getBadBlocks',
 			call: 'debug_getBadBlocks',
@@ -468,6 +566,22 @@ web3._extend({
 			call: 'berith_chainId',
 			params: 0
 		}),
+		new web3._extend.Method({
+			name: 'finalizedBlock',
+			call: 'berith_finalizedBlock',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'bloomStatus',
+			call: 'berith_bloomStatus',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getLogsPage',
+			call: 'berith_getLogsPage',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 		new web3._extend.Method({
 			name: 'sign',
 			call: 'berith_sign',
@@ -511,6 +625,24 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'decodeRawTransaction',
+			call: 'berith_decodeRawTransaction',
+			params: 1,
+			outputFormatter: function(tx) {
+				var wallets = ['', 'main', 'stake'];
+				tx.nonce = web3._extend.utils.toDecimal(tx.nonce);
+				tx.gas = web3._extend.utils.toDecimal(tx.gas);
+				tx.gasPrice = web3._extend.formatters.outputBigNumberFormatter(tx.gasPrice);
+				tx.value = web3._extend.formatters.outputBigNumberFormatter(tx.value);
+				if (tx.chainId !== null) {
+					tx.chainId = web3._extend.utils.toDecimal(tx.chainId);
+				}
+				tx.base = wallets[tx.base] || tx.base;
+				tx.target = wallets[tx.target] || tx.target;
+				return tx;
+			}
+		}),
 		new web3._extend.Method({
 			name: 'stake',
 			call: 'berith_stake',
@@ -555,6 +687,16 @@ web3._extend({
         	params: 3,
         	inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null],        	
 		}),
+		new web3._extend.Method({
+			name: 'stakeIntents',
+			call: 'berith_stakeIntents',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'rebroadcastIntent',
+			call: 'berith_rebroadcastIntent',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -569,6 +711,131 @@ web3._extend({
 				return formatted;
 			}
 		}),
+		new web3._extend.Property({
+			name: 'gasPriceOracle',
+			getter: 'berith_gasPriceOracle'
+		}),
+	]
+});
+`
+
+const Eth_JS = `
+web3._extend({
+	property: 'eth',
+	methods: [
+		new web3._extend.Method({
+			name: 'getBalance',
+			call: 'eth_getBalance',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter],
+			outputFormatter: web3._extend.formatters.outputBigNumberFormatter
+		}),
+		new web3._extend.Method({
+			name: 'getCode',
+			call: 'eth_getCode',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getStorageAt',
+			call: 'eth_getStorageAt',
+			params: 3,
+			inputFormatter: [null, web3._extend.utils.toHex, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionCount',
+			call: 'eth_getTransactionCount',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter],
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'getBlockByNumber',
+			call: 'eth_getBlockByNumber',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, function (val) { return !!val; }],
+			outputFormatter: web3._extend.formatters.outputBlockFormatter
+		}),
+		new web3._extend.Method({
+			name: 'getBlockByHash',
+			call: 'eth_getBlockByHash',
+			params: 2,
+			inputFormatter: [null, function (val) { return !!val; }],
+			outputFormatter: web3._extend.formatters.outputBlockFormatter
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionByHash',
+			call: 'eth_getTransactionByHash',
+			params: 1,
+			outputFormatter: web3._extend.formatters.outputTransactionFormatter
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionReceipt',
+			call: 'eth_getTransactionReceipt',
+			params: 1,
+			outputFormatter: web3._extend.formatters.outputTransactionReceiptFormatter
+		}),
+		new web3._extend.Method({
+			name: 'sendRawTransaction',
+			call: 'eth_sendRawTransaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'call',
+			call: 'eth_call',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'estimateGas',
+			call: 'eth_estimateGas',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter],
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'getLogs',
+			call: 'eth_getLogs',
+			params: 1
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'chainId',
+			getter: 'eth_chainId',
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Property({
+			name: 'blockNumber',
+			getter: 'eth_blockNumber',
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Property({
+			name: 'gasPrice',
+			getter: 'eth_gasPrice',
+			outputFormatter: web3._extend.formatters.outputBigNumberFormatter
+		}),
+		new web3._extend.Property({
+			name: 'accounts',
+			getter: 'eth_accounts'
+		}),
+		new web3._extend.Property({
+			name: 'coinbase',
+			getter: 'eth_coinbase'
+		}),
+		new web3._extend.Property({
+			name: 'mining',
+			getter: 'eth_mining'
+		}),
+		new web3._extend.Property({
+			name: 'hashrate',
+			getter: 'eth_hashrate',
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Property({
+			name: 'syncing',
+			getter: 'eth_syncing'
+		}),
 	]
 });
 `
@@ -609,6 +876,34 @@ web3._extend({
 			call: 'miner_setRecommitInterval',
 			params: 1,
 		}),
+		new web3._extend.Method({
+			name: 'recentlyDropped',
+			call: 'miner_recentlyDropped',
+		}),
+		new web3._extend.Method({
+			name: 'setTargetBlockInterval',
+			call: 'miner_setTargetBlockInterval',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'setTxOrdering',
+			call: 'miner_setTxOrdering',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'getTxOrdering',
+			call: 'miner_getTxOrdering'
+		}),
+		new web3._extend.Method({
+			name: 'stats',
+			call: 'miner_stats',
+			outputFormatter: function(stats) {
+				stats.blocks = web3._extend.utils.toDecimal(stats.blocks);
+				stats.txs = web3._extend.utils.toDecimal(stats.txs);
+				stats.fees = web3._extend.formatters.outputBigNumberFormatter(stats.fees);
+				return stats;
+			}
+		}),
 		new web3._extend.Method({
 			name: 'getHashrate',
 			call: 'miner_getHashrate'
@@ -673,6 +968,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'lockoutStatus',
+			call: 'personal_lockoutStatus',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'hasAddress',
 			call: 'personal_hasAddress',
@@ -746,6 +1047,19 @@ web3._extend({
 });
 `
 
+const Les_JS = `
+web3._extend({
+	property: 'les',
+	methods: [
+		new web3._extend.Method({
+			name: 'currentCheckpoint',
+			call: 'les_currentCheckpoint',
+			params: 0
+		}),
+	]
+});
+`
+
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
@@ -0,0 +1,36 @@
+package les
+
+import (
+	"errors"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/params"
+)
+
+var errNoCheckpoint = errors.New("no checkpoint indexed yet")
+
+// PublicLightAPI provides an API to access the light client specific data.
+type PublicLightAPI struct {
+	lber *LightBerith
+}
+
+// NewPublicLightAPI creates a new light client API.
+func NewPublicLightAPI(lber *LightBerith) *PublicLightAPI {
+	return &PublicLightAPI{lber: lber}
+}
+
+// CurrentCheckpoint returns the latest checkpoint trusted by the node, in the
+// form configured as the trusted checkpoint of other light clients.
+func (api *PublicLightAPI) CurrentCheckpoint() (*params.TrustedCheckpoint, error) {
+	chain := api.lber.BlockChain()
+	idx, chtRoot, bloomTrieRoot := chain.CurrentCheckpoint()
+	if chtRoot == (common.Hash{}) {
+		return nil, errNoCheckpoint
+	}
+	return &params.TrustedCheckpoint{
+		SectionIndex: idx,
+		SectionHead:  chain.Odr().ChtIndexer().SectionHead(idx),
+		CHTRoot:      chtRoot,
+		BloomRoot:    bloomTrieRoot,
+	}, nil
+}
@@ -79,6 +79,9 @@ type LightBerith struct {
 }
 
 func New(ctx *node.ServiceContext, config *berith.Config) (*LightBerith, error) {
+	if config.LightMaxServers > 0 && config.LightMinServers > config.LightMaxServers {
+		return nil, fmt.Errorf("minimum of %d light servers exceeds the maximum of %d", config.LightMinServers, config.LightMaxServers)
+	}
 	chainDb, err := berith.CreateDB(ctx, config, "lightchaindata")
 	if err != nil {
 		return nil, err
@@ -92,7 +95,7 @@ func New(ctx *node.ServiceContext, config *berith.Config) (*LightBerith, error)
 	peers := newPeerSet()
 	quitSync := make(chan struct{})
 
-	stakingDB := &staking.StakingDB{NoPruning: config.NoPruning}
+	stakingDB := &staking.StakingDB{NoPruning: config.NoPruning, CommitMode: config.StakingCommit, ReadOnly: config.StakingReadOnly}
 	stakingDBPath := ctx.ResolvePath("stakingDB")
 	if stkErr := stakingDB.CreateDB(stakingDBPath, staking.NewStakers); stkErr != nil {
 		return nil, stkErr
@@ -109,7 +112,7 @@ func New(ctx *node.ServiceContext, config *berith.Config) (*LightBerith, error)
 		peers:          peers,
 		reqDist:        newRequestDistributor(peers, quitSync),
 		accountManager: ctx.AccountManager,
-		engine:         berith.CreateConsensusEngine(chainConfig, chainDb, stakingDB),
+		engine:         berith.CreateConsensusEngine(chainConfig, config, chainDb, stakingDB),
 		shutdownChan:   make(chan bool),
 		networkId:      config.NetworkId,
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
@@ -117,10 +120,10 @@ func New(ctx *node.ServiceContext, config *berith.Config) (*LightBerith, error)
 	}
 
 	lber.relay = NewLesTxRelay(peers, lber.reqDist)
-	lber.serverPool = newServerPool(chainDb, quitSync, &lber.wg)
-	lber.retriever = newRetrieveManager(peers, lber.reqDist, lber.serverPool)
+	lber.serverPool = newServerPool(chainDb, quitSync, &lber.wg, config.LightMaxServers)
+	lber.retriever = newRetrieveManager(peers, lber.reqDist, lber.serverPool, config.LightRetrieveTimeout)
 
-	lber.odr = NewLesOdr(chainDb, light.DefaultClientIndexerConfig, lber.retriever)
+	lber.odr = NewLesOdr(chainDb, light.DefaultClientIndexerConfig, lber.retriever, config.LightMinServers)
 	lber.chtIndexer = light.NewChtIndexer(chainDb, lber.odr, params.CHTFrequencyClient, params.HelperTrieConfirmations)
 	lber.bloomTrieIndexer = light.NewBloomTrieIndexer(chainDb, lber.odr, params.BloomBitsBlocksClient, params.BloomTrieFrequency)
 	lber.odr.SetIndexers(lber.chtIndexer, lber.bloomTrieIndexer, lber.bloomIndexer)
@@ -193,7 +196,7 @@ func (s *LightDummyAPI) Mining() bool {
 // APIs returns the collection of RPC services the berith package offers.
 // NOTE, some of these services probably need to be moved to somewhere else.
 func (s *LightBerith) APIs() []rpc.API {
-	return append(berithapi.GetAPIs(s.ApiBackend), []rpc.API{
+	return append(berithapi.GetAPIs(s.ApiBackend, s.config.PasswordGuard), []rpc.API{
 		{
 			Namespace: "berith",
 			Version:   "1.0",
@@ -207,7 +210,17 @@ func (s *LightBerith) APIs() []rpc.API {
 		}, {
 			Namespace: "berith",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true),
+			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true, s.config.LogLimits),
+			Public:    true,
+		}, {
+			Namespace: "berith",
+			Version:   "1.0",
+			Service:   gasprice.NewPublicOracleAPI(s.ApiBackend.gpo),
+			Public:    true,
+		}, {
+			Namespace: "les",
+			Version:   "1.0",
+			Service:   NewPublicLightAPI(s),
 			Public:    true,
 		}, {
 			Namespace: "net",
@@ -18,6 +18,7 @@ package les
 
 import (
 	"context"
+	"errors"
 
 	"github.com/BerithFoundation/berith-chain/berithdb"
 	"github.com/BerithFoundation/berith-chain/core"
@@ -25,20 +26,26 @@ import (
 	"github.com/BerithFoundation/berith-chain/log"
 )
 
+// errInsufficientServers is returned if data is requested from the network
+// while fewer light servers are connected than configured.
+var errInsufficientServers = errors.New("insufficient light servers")
+
 // LesOdr implements light.OdrBackend
 type LesOdr struct {
 	db                                         berithdb.Database
 	indexerConfig                              *light.IndexerConfig
 	chtIndexer, bloomTrieIndexer, bloomIndexer *core.ChainIndexer
 	retriever                                  *retrieveManager
+	minServers                                 int // Minimum number of servers connected before retrieving
 	stop                                       chan struct{}
 }
 
-func NewLesOdr(db berithdb.Database, config *light.IndexerConfig, retriever *retrieveManager) *LesOdr {
+func NewLesOdr(db berithdb.Database, config *light.IndexerConfig, retriever *retrieveManager, minServers int) *LesOdr {
 	return &LesOdr{
 		db:            db,
 		indexerConfig: config,
 		retriever:     retriever,
+		minServers:    minServers,
 		stop:          make(chan struct{}),
 	}
 }
@@ -100,6 +107,9 @@ type Msg struct {
 // Retrieve tries to fetch an object from the LES network.
 // If the network retrieval was successful, it stores the object in local db.
 func (odr *LesOdr) Retrieve(ctx context.Context, req light.OdrRequest) (err error) {
+	if err = odr.checkServers(); err != nil {
+		return err
+	}
 	lreq := LesRequest(req)
 
 	reqID := genReqID()
@@ -127,3 +137,13 @@ func (odr *LesOdr) Retrieve(ctx context.Context, req light.OdrRequest) (err erro
 	}
 	return
 }
+
+// checkServers returns an error if fewer servers are connected than required,
+// so that a fresh client does not depend on the first server it meets.
+func (odr *LesOdr) checkServers() error {
+	if servers := odr.retriever.peers.Len(); servers < odr.minServers {
+		log.Debug("Refusing retrieval with too few servers", "servers", servers, "required", odr.minServers)
+		return errInsufficientServers
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/light"
+)
+
+// Tests that on-demand retrievals are refused while fewer servers than
+// required are connected, and proceed once enough are.
+func TestRetrieveMinServers(t *testing.T) {
+	peers := newPeerSet()
+	odr := NewLesOdr(nil, light.DefaultClientIndexerConfig, newRetrieveManager(peers, nil, nil, 0), 2)
+
+	for i := 0; i < 3; i++ {
+		err := odr.checkServers()
+		if i < 2 && err != errInsufficientServers {
+			t.Errorf("%d servers: error mismatch: have %v, want %v", i, err, errInsufficientServers)
+		}
+		if i >= 2 && err != nil {
+			t.Errorf("%d servers: retrieval refused: %v", i, err)
+		}
+		if i < 2 {
+			// Refused before any request is sent to the network
+			req := &light.BlockRequest{Hash: common.HexToHash("0x01"), Number: 1}
+			if err := odr.Retrieve(context.Background(), req); err != errInsufficientServers {
+				t.Errorf("%d servers: retrieve error mismatch: have %v, want %v", i, err, errInsufficientServers)
+			}
+		}
+		if err := peers.Register(&peer{id: fmt.Sprint(i)}); err != nil {
+			t.Fatalf("failed to register server %d: %v", i, err)
+		}
+	}
+	// Without a configured minimum retrievals are never refused
+	odr = NewLesOdr(nil, light.DefaultClientIndexerConfig, newRetrieveManager(newPeerSet(), nil, nil, 0), 0)
+	if err := odr.checkServers(); err != nil {
+		t.Errorf("no minimum: retrieval refused: %v", err)
+	}
+}
+
+// silentPeer is a server accepting every request, but never answering.
+type silentPeer struct{}
+
+func (silentPeer) waitBefore(uint64) (time.Duration, float64) { return 0, 0 }
+func (silentPeer) canQueue() bool                             { return true }
+func (silentPeer) queueSend(f func())                         { f() }
+
+// Tests that retrievals without a deadline fail once the default timeout of the
+// retrieve manager expired, while the deadline of the caller takes precedence.
+func TestRetrieveTimeout(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+
+	dist := newRequestDistributor(nil, stop)
+	dist.registerTestPeer(silentPeer{})
+	rm := newRetrieveManager(newPeerSet(), dist, nil, 200*time.Millisecond)
+
+	newReq := func() *distReq {
+		return &distReq{
+			getCost: func(distPeer) uint64 { return 0 },
+			canSend: func(distPeer) bool { return true },
+			request: func(distPeer) func() { return func() {} },
+		}
+	}
+	val := func(distPeer, *Msg) error { return nil }
+
+	start := time.Now()
+	if err := rm.retrieve(context.Background(), genReqID(), newReq(), val, nil); err != light.ErrRetrieveTimeout {
+		t.Errorf("default timeout: error mismatch: have %v, want %v", err, light.ErrRetrieveTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("default timeout: retrieval took %v", elapsed)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start = time.Now()
+	if err := rm.retrieve(ctx, genReqID(), newReq(), val, nil); err != context.DeadlineExceeded {
+		t.Errorf("caller deadline: error mismatch: have %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("caller deadline: retrieval took %v", elapsed)
+	}
+}
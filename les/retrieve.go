@@ -42,6 +42,7 @@ type retrieveManager struct {
 	dist       *requestDistributor
 	peers      *peerSet
 	serverPool peerSelector
+	timeout    time.Duration // Time a retrieval may take if the caller set no deadline, zero for no limit
 
 	lock     sync.RWMutex
 	sentReqs map[uint64]*sentReq
@@ -100,11 +101,12 @@ const (
 )
 
 // newRetrieveManager creates the retrieve manager
-func newRetrieveManager(peers *peerSet, dist *requestDistributor, serverPool peerSelector) *retrieveManager {
+func newRetrieveManager(peers *peerSet, dist *requestDistributor, serverPool peerSelector, timeout time.Duration) *retrieveManager {
 	return &retrieveManager{
 		peers:      peers,
 		dist:       dist,
 		serverPool: serverPool,
+		timeout:    timeout,
 		sentReqs:   make(map[uint64]*sentReq),
 	}
 }
@@ -112,13 +114,22 @@ func newRetrieveManager(peers *peerSet, dist *requestDistributor, serverPool pee
 // retrieve sends a request (to multiple peers if necessary) and waits for an answer
 // that is delivered through the deliver function and successfully validated by the
 // validator callback. It returns when a valid answer is delivered or the context is
-// cancelled.
+// cancelled. If the context has no deadline, the retrieval fails with
+// light.ErrRetrieveTimeout once the default timeout of the manager expired.
 func (rm *retrieveManager) retrieve(ctx context.Context, reqID uint64, req *distReq, val validatorFunc, shutdown chan struct{}) error {
+	var expired <-chan time.Time
+	if _, ok := ctx.Deadline(); !ok && rm.timeout > 0 {
+		timer := time.NewTimer(rm.timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
 	sentReq := rm.sendReq(reqID, req, val)
 	select {
 	case <-sentReq.stopCh:
 	case <-ctx.Done():
 		sentReq.stop(ctx.Err())
+	case <-expired:
+		sentReq.stop(light.ErrRetrieveTimeout)
 	case <-shutdown:
 		sentReq.stop(fmt.Errorf("Client is shutting down"))
 	}
@@ -238,8 +249,11 @@ func (r *sentReq) stateRequesting() reqStateFn {
 // Peers may become suitable for a certain request later or new peers may appear so we
 // keep trying.
 func (r *sentReq) stateNoMorePeers() reqStateFn {
+	retry := time.NewTimer(retryQueue)
+	defer retry.Stop()
+
 	select {
-	case <-time.After(retryQueue):
+	case <-retry.C:
 		go r.tryRequest()
 		r.lastReqQueued = true
 		return r.stateRequesting
@@ -332,7 +346,7 @@ func (r *sentReq) tryRequest() {
 			respTime := time.Duration(mclock.Now() - reqSent)
 			r.rm.serverPool.adjustResponseTime(pp.poolEntry, respTime, srto)
 		}
-		if hrto {
+		if hrto && ok {
 			pp.Log().Debug("Request timed out hard")
 			if r.rm.peers != nil {
 				r.rm.peers.Unregister(pp.id)
@@ -344,6 +358,9 @@ func (r *sentReq) tryRequest() {
 		r.lock.Unlock()
 	}()
 
+	soft := time.NewTimer(softRequestTimeout)
+	defer soft.Stop()
+
 	select {
 	case ok := <-s.valid:
 		if ok {
@@ -352,11 +369,14 @@ func (r *sentReq) tryRequest() {
 			r.eventsCh <- reqPeerEvent{rpDeliveredInvalid, p}
 		}
 		return
-	case <-time.After(softRequestTimeout):
+	case <-soft.C:
 		srto = true
 		r.eventsCh <- reqPeerEvent{rpSoftTimeout, p}
 	}
 
+	hard := time.NewTimer(hardRequestTimeout)
+	defer hard.Stop()
+
 	select {
 	case ok := <-s.valid:
 		if ok {
@@ -364,7 +384,7 @@ func (r *sentReq) tryRequest() {
 		} else {
 			r.eventsCh <- reqPeerEvent{rpDeliveredInvalid, p}
 		}
-	case <-time.After(hardRequestTimeout):
+	case <-hard.C:
 		hrto = true
 		r.eventsCh <- reqPeerEvent{rpHardTimeout, p}
 	}
@@ -138,10 +138,16 @@ type serverPool struct {
 	knownSelect, newSelect     *weightedRandomSelect
 	knownSelected, newSelected int
 	fastDiscover               bool
+	maxServers                 int // Maximum number of servers dialed or connected at once
 }
 
-// newServerPool creates a new serverPool instance
-func newServerPool(db berithdb.Database, quit chan struct{}, wg *sync.WaitGroup) *serverPool {
+// newServerPool creates a new serverPool instance, connecting to at most maxServers
+// servers at once (defaults to targetServerCount). The servers discovered beyond
+// them are kept as backups.
+func newServerPool(db berithdb.Database, quit chan struct{}, wg *sync.WaitGroup, maxServers int) *serverPool {
+	if maxServers <= 0 {
+		maxServers = targetServerCount
+	}
 	pool := &serverPool{
 		db:           db,
 		quit:         quit,
@@ -156,6 +162,7 @@ func newServerPool(db berithdb.Database, quit chan struct{}, wg *sync.WaitGroup)
 		knownSelect:  newWeightedRandomSelect(),
 		newSelect:    newWeightedRandomSelect(),
 		fastDiscover: true,
+		maxServers:   maxServers,
 	}
 	pool.knownQueue = newPoolEntryQueue(maxKnownEntries, pool.removeEntry)
 	pool.newQueue = newPoolEntryQueue(maxNewEntries, pool.removeEntry)
@@ -531,7 +538,11 @@ func (pool *serverPool) updateCheckDial(entry *poolEntry) {
 // based on good statistics and recent discovery.
 func (pool *serverPool) checkDial() {
 	fillWithKnownSelects := !pool.fastDiscover
-	for pool.knownSelected < targetKnownSelect {
+	knownTarget := targetKnownSelect
+	if knownTarget > pool.maxServers {
+		knownTarget = pool.maxServers
+	}
+	for pool.knownSelected < knownTarget {
 		entry := pool.knownSelect.choose()
 		if entry == nil {
 			fillWithKnownSelects = false
@@ -539,7 +550,7 @@ func (pool *serverPool) checkDial() {
 		}
 		pool.dial((*poolEntry)(entry.(*knownEntry)), true)
 	}
-	for pool.knownSelected+pool.newSelected < targetServerCount {
+	for pool.knownSelected+pool.newSelected < pool.maxServers {
 		entry := pool.newSelect.choose()
 		if entry == nil {
 			break
@@ -550,7 +561,7 @@ func (pool *serverPool) checkDial() {
 		// no more newly discovered nodes to select and since fast discover period
 		// is over, we probably won't find more in the near future so select more
 		// known entries if possible
-		for pool.knownSelected < targetServerCount {
+		for pool.knownSelected+pool.newSelected < pool.maxServers {
 			entry := pool.knownSelect.choose()
 			if entry == nil {
 				break
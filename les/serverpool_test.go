@@ -0,0 +1,72 @@
+package les
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/crypto"
+	"github.com/BerithFoundation/berith-chain/p2p"
+	"github.com/BerithFoundation/berith-chain/p2p/enode"
+)
+
+// Tests that the server pool dials no more servers than its maximum while it
+// keeps discovering more, and replaces the servers lost with the backups.
+func TestServerPoolMaxServers(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	server := &p2p.Server{Config: p2p.Config{PrivateKey: key, MaxPeers: 10, NoDiscovery: true, NoDial: true}}
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start p2p server: %v", err)
+	}
+	defer server.Stop()
+
+	quit := make(chan struct{})
+	defer close(quit)
+
+	pool := newServerPool(berithdb.NewMemDatabase(), quit, new(sync.WaitGroup), 2)
+	pool.server = server
+
+	active := func() (count int) {
+		for _, entry := range pool.entries {
+			if entry.state != psNotConnected {
+				count++
+			}
+		}
+		return count
+	}
+	for i := 0; i < 6; i++ {
+		key, _ := crypto.GenerateKey()
+		node := enode.NewV4(&key.PublicKey, net.IP{127, 0, 0, 1}, 30303+i, 30303+i)
+		pool.updateCheckDial(pool.findOrNewNode(node))
+
+		want := i + 1
+		if want > 2 {
+			want = 2
+		}
+		if have := active(); have != want {
+			t.Fatalf("%d servers discovered: active count mismatch: have %d, want %d", i+1, have, want)
+		}
+	}
+	if len(pool.entries) != 6 {
+		t.Errorf("backup servers not kept: have %d entries, want 6", len(pool.entries))
+	}
+	// A server timing out is replaced by a backup
+	for _, entry := range pool.entries {
+		if entry.state == psDialed {
+			pool.checkDialTimeout(entry)
+			break
+		}
+	}
+	if have := active(); have != 1 {
+		t.Fatalf("active count after timeout mismatch: have %d, want 1", have)
+	}
+	pool.checkDial()
+	if have := active(); have != 2 {
+		t.Errorf("active count after replacement mismatch: have %d, want 2", have)
+	}
+	// Without a configured maximum the default applies
+	if pool := newServerPool(berithdb.NewMemDatabase(), quit, new(sync.WaitGroup), 0); pool.maxServers != targetServerCount {
+		t.Errorf("default maximum mismatch: have %d, want %d", pool.maxServers, targetServerCount)
+	}
+}
@@ -0,0 +1,203 @@
+package light
+
+import (
+	"context"
+	"errors"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/bitutil"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/crypto"
+)
+
+// errInvalidHistoryRange is returned if the first block of a history query is
+// after the last one.
+var errInvalidHistoryRange = errors.New("invalid block range")
+
+// AccountTx is a transaction of the history of an account.
+type AccountTx struct {
+	BlockHash   common.Hash
+	BlockNumber uint64
+	Index       uint // Index of the transaction in the block
+	Tx          *types.Transaction
+}
+
+// GetAccountHistory retrieves the transactions of the blocks in the range which
+// were sent from or to the account, or whose logs were emitted by it or carry it
+// as a topic.
+//
+// The blooms index the logs only, so the bodies of all blocks holding
+// transactions are scanned for the senders and recipients. The receipts are
+// retrieved only for the blocks whose blooms match the account, found through
+// the bloom bits of the indexed sections and the header blooms after them.
+//
+// The history is paginated: once at least limit transactions are found (0 = no
+// limit), the transactions of the block reaching the limit are returned along
+// with the number of the block to continue from. Past the range, the number
+// returned is the block following it.
+func GetAccountHistory(ctx context.Context, odr OdrBackend, addr common.Address, begin, end uint64, limit int) ([]*AccountTx, uint64, error) {
+	if begin > end {
+		return nil, 0, errInvalidHistoryRange
+	}
+	var (
+		history []*AccountTx
+		size    = odr.IndexerConfig().BloomSize
+	)
+	for section := begin / size; section <= end/size; section++ {
+		first, last := section*size, (section+1)*size-1
+		if first < begin {
+			first = begin
+		}
+		if last > end {
+			last = end
+		}
+		logged, err := accountBlocks(ctx, odr, addr, section, first, last)
+		if err != nil {
+			return nil, 0, err
+		}
+		for number := first; number <= last; number++ {
+			header, err := GetHeaderByNumber(ctx, odr, number)
+			if err != nil {
+				return nil, 0, err
+			}
+			if header.TxHash == types.EmptyRootHash {
+				continue
+			}
+			txs, err := accountBlockTxs(ctx, odr, addr, header, logged[number])
+			if err != nil {
+				return nil, 0, err
+			}
+			history = append(history, txs...)
+			if limit > 0 && len(history) >= limit {
+				return history, number + 1, nil
+			}
+		}
+	}
+	return history, end + 1, nil
+}
+
+// accountBlocks returns the numbers of the blocks between first and last of the
+// bloom section whose blooms match the account, as the emitter or as a topic of
+// a log. Sections not indexed yet are matched against the header blooms.
+func accountBlocks(ctx context.Context, odr OdrBackend, addr common.Address, section, first, last uint64) (map[uint64]bool, error) {
+	var (
+		size   = odr.IndexerConfig().BloomSize
+		topic  = common.BytesToHash(addr[:])
+		blocks = make(map[uint64]bool)
+	)
+	matches, err := matchBloomBits(ctx, odr, section, addr[:], topic[:])
+	switch err {
+	case nil:
+		for number := first; number <= last; number++ {
+			if offset := number - section*size; matches[offset/8]&(1<<(7-offset%8)) != 0 {
+				blocks[number] = true
+			}
+		}
+	case ErrNoTrustedBloomTrie:
+		// The section isn't indexed yet, test the blooms of the headers
+		for number := first; number <= last; number++ {
+			header, err := GetHeaderByNumber(ctx, odr, number)
+			if err != nil {
+				return nil, err
+			}
+			if types.BloomLookup(header.Bloom, addr) || types.BloomLookup(header.Bloom, topic) {
+				blocks[number] = true
+			}
+		}
+	default:
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// matchBloomBits returns the bit vector of the blocks of the bloom section whose
+// blooms match any of the values.
+func matchBloomBits(ctx context.Context, odr OdrBackend, section uint64, values ...[]byte) ([]byte, error) {
+	matches := make([]byte, odr.IndexerConfig().BloomSize/8)
+	for _, value := range values {
+		match := make([]byte, len(matches))
+		for i := range match {
+			match[i] = 0xff
+		}
+		for _, bit := range bloomBitIndexes(value) {
+			compressed, err := GetBloomBits(ctx, odr, bit, []uint64{section})
+			if err != nil {
+				return nil, err
+			}
+			vector, err := bitutil.DecompressBytes(compressed[0], len(match))
+			if err != nil {
+				return nil, err
+			}
+			bitutil.ANDBytes(match, match, vector)
+		}
+		bitutil.ORBytes(matches, matches, match)
+	}
+	return matches, nil
+}
+
+// bloomBitIndexes returns the indexes of the three bloom bits set by the value,
+// as used by the bloom bits vectors.
+func bloomBitIndexes(value []byte) [3]uint {
+	hash := crypto.Keccak256(value)
+
+	var idxs [3]uint
+	for i := range idxs {
+		idxs[i] = (uint(hash[2*i])<<8)&2047 + uint(hash[2*i+1])
+	}
+	return idxs
+}
+
+// accountBlockTxs retrieves the transactions of the block which were sent from or
+// to the account, or whose logs mention it. The logs are only checked if logged
+// is set, the blooms of the block matching the account.
+func accountBlockTxs(ctx context.Context, odr OdrBackend, addr common.Address, header *types.Header, logged bool) ([]*AccountTx, error) {
+	hash, number := header.Hash(), header.Number.Uint64()
+	body, err := GetBody(ctx, odr, hash, number)
+	if err != nil {
+		return nil, err
+	}
+	var logs [][]*types.Log
+	if logged {
+		if logs, err = GetBlockLogs(ctx, odr, hash, number); err != nil {
+			return nil, err
+		}
+	}
+	var txs []*AccountTx
+	for i, tx := range body.Transactions {
+		if !txTouches(tx, addr) && (i >= len(logs) || !logsMention(logs[i], addr)) {
+			continue
+		}
+		txs = append(txs, &AccountTx{BlockHash: hash, BlockNumber: number, Index: uint(i), Tx: tx})
+	}
+	return txs, nil
+}
+
+// txTouches reports whether the transaction was sent from or to the account.
+func txTouches(tx *types.Transaction, addr common.Address) bool {
+	if to := tx.To(); to != nil && *to == addr {
+		return true
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, err := types.Sender(signer, tx)
+	return err == nil && from == addr
+}
+
+// logsMention reports whether any of the logs was emitted by the account or
+// carries it as a topic.
+func logsMention(logs []*types.Log, addr common.Address) bool {
+	topic := common.BytesToHash(addr[:])
+	for _, log := range logs {
+		if log.Address == addr {
+			return true
+		}
+		for _, t := range log.Topics {
+			if t == topic {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package light
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/common/bitutil"
+	"github.com/BerithFoundation/berith-chain/core/bloombits"
+	"github.com/BerithFoundation/berith-chain/core/rawdb"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/crypto"
+)
+
+// Tests that the transactions of an account are found, whether they leave a log
+// matched by the bloom bits of the indexed sections and the header blooms after
+// them or not, and paged through.
+func TestGetAccountHistory(t *testing.T) {
+	var (
+		accountKey, _ = crypto.GenerateKey()
+		otherKey, _   = crypto.GenerateKey()
+		account       = crypto.PubkeyToAddress(accountKey.PublicKey)
+		recipient     = common.Address{0x01}
+		token         = common.Address{0x02}
+		transfer      = common.Hash{0x03}
+		db            = berithdb.NewMemDatabase()
+		size          = TestClientIndexerConfig.BloomSize
+		nonce         uint64
+	)
+	newTx := func(key *ecdsa.PrivateKey, to common.Address) *types.Transaction {
+		nonce++
+		tx, err := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(1), 21000, big.NewInt(1), nil, types.Main, types.Main), types.HomesteadSigner{}, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		return tx
+	}
+	// Token transfers carry the account as a topic, calls of the account emit
+	// logs from it, plain transfers leave no log
+	tokenLog := &types.Log{Address: token, Topics: []common.Hash{transfer, common.BytesToHash(account[:])}}
+	accountLog := &types.Log{Address: account, Topics: []common.Hash{transfer}}
+
+	blocks := map[uint64][]struct {
+		tx   *types.Transaction
+		logs []*types.Log
+	}{
+		3: {
+			{newTx(otherKey, recipient), nil},
+			{newTx(accountKey, recipient), nil},
+			{newTx(otherKey, token), []*types.Log{tokenLog}},
+		},
+		100: {{newTx(otherKey, account), nil}},
+		200: {{newTx(otherKey, account), []*types.Log{accountLog}}},
+		520: {{newTx(otherKey, token), []*types.Log{tokenLog}}},
+	}
+	gen, err := bloombits.NewGenerator(uint(size))
+	if err != nil {
+		t.Fatalf("failed to create bloom bits generator: %v", err)
+	}
+	for number := uint64(0); number < 600; number++ {
+		var (
+			txs      types.Transactions
+			receipts types.Receipts
+		)
+		for _, entry := range blocks[number] {
+			txs = append(txs, entry.tx)
+			receipt := types.NewReceipt(nil, false, 21000)
+			receipt.Logs = entry.logs
+			receipts = append(receipts, receipt)
+		}
+		header := &types.Header{Number: new(big.Int).SetUint64(number), TxHash: types.DeriveSha(txs), Bloom: types.CreateBloom(receipts)}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), number)
+		if len(txs) > 0 {
+			rawdb.WriteBody(db, header.Hash(), number, &types.Body{Transactions: txs})
+			rawdb.WriteReceipts(db, header.Hash(), number, receipts)
+		}
+		// Index the bloom bits of the first section only
+		if number < size {
+			if err := gen.AddBloom(uint(number), header.Bloom); err != nil {
+				t.Fatalf("failed to add bloom %d: %v", number, err)
+			}
+		}
+	}
+	head := rawdb.ReadCanonicalHash(db, size-1)
+	for bit := uint(0); bit < types.BloomBitLength; bit++ {
+		bits, err := gen.Bitset(bit)
+		if err != nil {
+			t.Fatalf("failed to get bloom bit %d: %v", bit, err)
+		}
+		rawdb.WriteBloomBits(db, bit, 0, head, bitutil.CompressBytes(bits))
+	}
+	odr := &testOdr{ldb: db}
+	if _, err := matchBloomBits(context.Background(), odr, 0, account[:]); err != nil {
+		t.Fatalf("indexed section not matched through the bloom bits: %v", err)
+	}
+
+	// The plain transfer to the account in block 100 isn't matched by the blooms
+	want := [][2]uint64{{3, 1}, {3, 2}, {100, 0}, {200, 0}, {520, 0}}
+	history, next, err := GetAccountHistory(context.Background(), odr, account, 0, 599, 0)
+	if err != nil {
+		t.Fatalf("failed to retrieve history: %v", err)
+	}
+	if next != 600 {
+		t.Errorf("next block mismatch: have %d, want 600", next)
+	}
+	if len(history) != len(want) {
+		t.Fatalf("history length mismatch: have %d, want %d", len(history), len(want))
+	}
+	for i, tx := range history {
+		if tx.BlockNumber != want[i][0] || uint64(tx.Index) != want[i][1] {
+			t.Errorf("transaction %d: position mismatch: have %d/%d, want %d/%d", i, tx.BlockNumber, tx.Index, want[i][0], want[i][1])
+		}
+		if hash := rawdb.ReadCanonicalHash(db, tx.BlockNumber); tx.BlockHash != hash {
+			t.Errorf("transaction %d: block hash mismatch: have %x, want %x", i, tx.BlockHash, hash)
+		}
+		if have := tx.Tx.Hash(); have != blocks[tx.BlockNumber][tx.Index].tx.Hash() {
+			t.Errorf("transaction %d: hash mismatch: have %x", i, have)
+		}
+	}
+	// Page through the history two transactions at a time
+	var pages [][]*AccountTx
+	for begin := uint64(0); begin <= 599; begin = next {
+		var page []*AccountTx
+		if page, next, err = GetAccountHistory(context.Background(), odr, account, begin, 599, 2); err != nil {
+			t.Fatalf("page %d: failed to retrieve history: %v", len(pages), err)
+		}
+		pages = append(pages, page)
+	}
+	if len(pages) != 3 || len(pages[0]) != 2 || len(pages[1]) != 2 || len(pages[2]) != 1 {
+		t.Errorf("pages mismatch: have %v", pages)
+	}
+	if len(pages) > 1 && pages[1][0].BlockNumber != 100 {
+		t.Errorf("second page start mismatch: have block %d, want 100", pages[1][0].BlockNumber)
+	}
+	if odr.requests != 0 {
+		t.Errorf("retrievals from the network: have %d, want 0", odr.requests)
+	}
+	if _, _, err := GetAccountHistory(context.Background(), odr, account, 10, 9, 0); err != errInvalidHistoryRange {
+		t.Errorf("invalid range error mismatch: have %v, want %v", err, errInvalidHistoryRange)
+	}
+}
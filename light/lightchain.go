@@ -133,6 +133,28 @@ func (self *LightChain) addTrustedCheckpoint(cp *params.TrustedCheckpoint) {
 	log.Info("Added trusted checkpoint", "chain", cp.Name, "block", (cp.SectionIndex+1)*self.indexerConfig.ChtSize-1, "hash", cp.SectionHead)
 }
 
+// CurrentCheckpoint returns the latest section covered by both the CHT and the
+// BloomTrie of the chain, with the roots of the tries, which can be distributed
+// as a trusted checkpoint to bootstrap other light clients. The CHT and BloomTrie
+// sections of a light client are of the same size. The roots are zero if there
+// is no such section yet.
+func (self *LightChain) CurrentCheckpoint() (sectionIdx uint64, chtRoot, bloomTrieRoot common.Hash) {
+	if self.odr.ChtIndexer() == nil || self.odr.BloomTrieIndexer() == nil {
+		return 0, common.Hash{}, common.Hash{}
+	}
+	sections, _, _ := self.odr.ChtIndexer().Sections()
+	if bloomSections, _, _ := self.odr.BloomTrieIndexer().Sections(); bloomSections < sections {
+		sections = bloomSections
+	}
+	if sections == 0 {
+		return 0, common.Hash{}, common.Hash{}
+	}
+	sectionIdx = sections - 1
+	chtRoot = GetChtRoot(self.chainDb, sectionIdx, self.odr.ChtIndexer().SectionHead(sectionIdx))
+	bloomTrieRoot = GetBloomTrieRoot(self.chainDb, sectionIdx, self.odr.BloomTrieIndexer().SectionHead(sectionIdx))
+	return sectionIdx, chtRoot, bloomTrieRoot
+}
+
 func (self *LightChain) getProcInterrupt() bool {
 	return atomic.LoadInt32(&self.procInterrupt) == 1
 }
@@ -453,6 +475,12 @@ func (self *LightChain) GetHeaderByNumber(number uint64) *types.Header {
 	return self.hc.GetHeaderByNumber(number)
 }
 
+// HeadersBetween calls fn with the locally stored canonical headers between from
+// and to inclusive in ascending order, until fn returns false.
+func (self *LightChain) HeadersBetween(from, to uint64, fn func(*types.Header) bool) error {
+	return self.hc.HeadersBetween(from, to, fn)
+}
+
 // GetHeaderByNumberOdr retrieves a block header from the database or network
 // by number, caching it (associated with its hash) if found.
 func (self *LightChain) GetHeaderByNumberOdr(ctx context.Context, number uint64) (*types.Header, error) {
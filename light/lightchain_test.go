@@ -0,0 +1,49 @@
+package light
+
+import (
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/params"
+)
+
+// Tests that the exported checkpoint is the latest section indexed by both the
+// CHT and the BloomTrie indexers, with the roots of its tries.
+func TestCurrentCheckpoint(t *testing.T) {
+	db := berithdb.NewMemDatabase()
+	odr := &testOdr{ldb: db}
+	config := TestClientIndexerConfig
+	odr.cht = NewChtIndexer(db, odr, config.ChtSize, config.ChtConfirms)
+	odr.bloomTrie = NewBloomTrieIndexer(db, odr, config.BloomSize, config.BloomTrieSize)
+	defer odr.cht.Close()
+	defer odr.bloomTrie.Close()
+
+	lc := &LightChain{chainDb: db, odr: odr, indexerConfig: config}
+	if idx, chtRoot, bloomTrieRoot := lc.CurrentCheckpoint(); idx != 0 || chtRoot != (common.Hash{}) || bloomTrieRoot != (common.Hash{}) {
+		t.Errorf("checkpoint before indexing mismatch: have %d, %x, %x", idx, chtRoot, bloomTrieRoot)
+	}
+	// Advance both indexers to the third section
+	cp := &params.TrustedCheckpoint{
+		Name:         "test",
+		SectionIndex: 2,
+		SectionHead:  common.Hash{1},
+		CHTRoot:      common.Hash{2},
+		BloomRoot:    common.Hash{3},
+	}
+	lc.addTrustedCheckpoint(cp)
+	if idx, chtRoot, bloomTrieRoot := lc.CurrentCheckpoint(); idx != cp.SectionIndex || chtRoot != cp.CHTRoot || bloomTrieRoot != cp.BloomRoot {
+		t.Errorf("checkpoint mismatch: have %d, %x, %x, want %d, %x, %x", idx, chtRoot, bloomTrieRoot, cp.SectionIndex, cp.CHTRoot, cp.BloomRoot)
+	}
+	// Only the sections covered by both tries are exported
+	StoreChtRoot(db, 3, common.Hash{4}, common.Hash{5})
+	odr.cht.AddCheckpoint(3, common.Hash{4})
+	if idx, chtRoot, _ := lc.CurrentCheckpoint(); idx != cp.SectionIndex || chtRoot != cp.CHTRoot {
+		t.Errorf("checkpoint ahead of the BloomTrie: have %d, %x, want %d, %x", idx, chtRoot, cp.SectionIndex, cp.CHTRoot)
+	}
+	StoreBloomTrieRoot(db, 3, common.Hash{4}, common.Hash{6})
+	odr.bloomTrie.AddCheckpoint(3, common.Hash{4})
+	if idx, chtRoot, bloomTrieRoot := lc.CurrentCheckpoint(); idx != 3 || chtRoot != (common.Hash{5}) || bloomTrieRoot != (common.Hash{6}) {
+		t.Errorf("advanced checkpoint mismatch: have %d, %x, %x", idx, chtRoot, bloomTrieRoot)
+	}
+}
@@ -37,6 +37,10 @@ var NoOdr = context.Background()
 // ErrNoPeers is returned if no peers capable of serving a queued request are available
 var ErrNoPeers = errors.New("no suitable peers available")
 
+// ErrRetrieveTimeout is returned if a retrieval requested without a deadline
+// isn't served by any peer within the default retrieval timeout
+var ErrRetrieveTimeout = errors.New("on-demand retrieval timed out")
+
 // OdrBackend is an interface to a backend service that handles ODR retrievals type
 type OdrBackend interface {
 	Database() berithdb.Database
@@ -19,17 +19,25 @@ package light
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/BerithFoundation/berith-chain/common"
 	"github.com/BerithFoundation/berith-chain/core"
 	"github.com/BerithFoundation/berith-chain/core/rawdb"
+	"github.com/BerithFoundation/berith-chain/core/state"
 	"github.com/BerithFoundation/berith-chain/core/types"
 	"github.com/BerithFoundation/berith-chain/crypto"
 	"github.com/BerithFoundation/berith-chain/rlp"
+	"github.com/BerithFoundation/berith-chain/trie"
 )
 
 var sha3_nil = crypto.Keccak256Hash(nil)
 
+// errCodeHashMismatch is returned if the retrieved contract code doesn't hash
+// to the code hash of the account.
+var errCodeHashMismatch = errors.New("code hash mismatch")
+
 func GetHeaderByNumber(ctx context.Context, odr OdrBackend, number uint64) (*types.Header, error) {
 	db := odr.Database()
 	hash := rawdb.ReadCanonicalHash(db, number)
@@ -232,3 +240,98 @@ func GetBloomBits(ctx context.Context, odr OdrBackend, bitIdx uint, sectionIdxLi
 		return result, nil
 	}
 }
+
+// GetCode retrieves the code of the contract at the given address in the state
+// of a block given by its hash, verifying the account against the state root of
+// the block and the code against the code hash of the account. The code of an
+// account without one is nil.
+func GetCode(ctx context.Context, odr OdrBackend, blockHash common.Hash, number uint64, addr common.Address) ([]byte, error) {
+	header := rawdb.ReadHeader(odr.Database(), blockHash, number)
+	if header == nil {
+		return nil, ErrNoHeader
+	}
+	id := StateTrieID(header)
+	account, err := getAccount(ctx, odr, id, addr)
+	if err != nil || account == nil {
+		return nil, err
+	}
+	codeHash := common.BytesToHash(account.CodeHash)
+	if codeHash == sha3_nil {
+		return nil, nil
+	}
+	if code, err := odr.Database().Get(codeHash[:]); err == nil {
+		return code, nil
+	}
+	r := &CodeRequest{Id: StorageTrieID(id, crypto.Keccak256Hash(addr[:]), account.Root), Hash: codeHash}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	if crypto.Keccak256Hash(r.Data) != codeHash {
+		return nil, errCodeHashMismatch
+	}
+	return r.Data, nil
+}
+
+// GetStorageAt retrieves the value of a storage slot of the contract at the given
+// address in the state of a block given by its hash, verifying the account and
+// the slot against the state root of the block. The value of an unset slot or of
+// a missing account is zero.
+func GetStorageAt(ctx context.Context, odr OdrBackend, blockHash common.Hash, number uint64, addr common.Address, key common.Hash) (common.Hash, error) {
+	header := rawdb.ReadHeader(odr.Database(), blockHash, number)
+	if header == nil {
+		return common.Hash{}, ErrNoHeader
+	}
+	id := StateTrieID(header)
+	account, err := getAccount(ctx, odr, id, addr)
+	if err != nil || account == nil {
+		return common.Hash{}, err
+	}
+	enc, err := getTrieValue(ctx, odr, StorageTrieID(id, crypto.Keccak256Hash(addr[:]), account.Root), key[:])
+	if err != nil || len(enc) == 0 {
+		return common.Hash{}, err
+	}
+	_, content, _, err := rlp.Split(enc)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("invalid storage value: %v", err)
+	}
+	return common.BytesToHash(content), nil
+}
+
+// getAccount retrieves the account at the given address in the state trie of
+// the given id, or nil if there is none.
+func getAccount(ctx context.Context, odr OdrBackend, id *TrieID, addr common.Address) (*state.Account, error) {
+	enc, err := getTrieValue(ctx, odr, id, addr[:])
+	if err != nil || len(enc) == 0 {
+		return nil, err
+	}
+	account := new(state.Account)
+	if err := rlp.DecodeBytes(enc, account); err != nil {
+		return nil, fmt.Errorf("invalid account: %v", err)
+	}
+	return account, nil
+}
+
+// getTrieValue retrieves the value of a key in the secure trie of the given id,
+// from the local database if the trie nodes are stored or otherwise by verifying
+// a retrieved Merkle proof against the root of the trie.
+func getTrieValue(ctx context.Context, odr OdrBackend, id *TrieID, key []byte) ([]byte, error) {
+	key = crypto.Keccak256(key)
+	if t, err := trie.New(id.Root, trie.NewDatabase(odr.Database())); err == nil {
+		value, err := t.TryGet(key)
+		if _, missing := err.(*trie.MissingNodeError); !missing {
+			return value, err
+		}
+	}
+	r := &TrieRequest{Id: id, Key: key}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	if r.Proof == nil {
+		return nil, errors.New("missing merkle proof")
+	}
+	value, _, err := trie.VerifyProof(id.Root, key, r.Proof)
+	if err != nil {
+		return nil, fmt.Errorf("merkle proof verification failed: %v", err)
+	}
+	return value, nil
+}
@@ -0,0 +1,133 @@
+package light
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/core"
+	"github.com/BerithFoundation/berith-chain/core/rawdb"
+	"github.com/BerithFoundation/berith-chain/core/state"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/trie"
+)
+
+// testOdr serves the retrievals from the database of a full node, corrupting
+// the proofs and the code on demand.
+type testOdr struct {
+	sdb, ldb       berithdb.Database
+	cht, bloomTrie *core.ChainIndexer
+	corrupt        bool
+	requests       int
+}
+
+func (odr *testOdr) Database() berithdb.Database          { return odr.ldb }
+func (odr *testOdr) ChtIndexer() *core.ChainIndexer       { return odr.cht }
+func (odr *testOdr) BloomTrieIndexer() *core.ChainIndexer { return odr.bloomTrie }
+func (odr *testOdr) BloomIndexer() *core.ChainIndexer     { return nil }
+func (odr *testOdr) IndexerConfig() *IndexerConfig        { return TestClientIndexerConfig }
+
+func (odr *testOdr) Retrieve(ctx context.Context, req OdrRequest) error {
+	odr.requests++
+	switch req := req.(type) {
+	case *TrieRequest:
+		t, err := trie.New(req.Id.Root, trie.NewDatabase(odr.sdb))
+		if err != nil {
+			return err
+		}
+		req.Proof = NewNodeSet()
+		if err := t.Prove(req.Key, 0, req.Proof); err != nil {
+			return err
+		}
+		if odr.corrupt {
+			// Replace the root node of the proof by garbage
+			req.Proof = NewNodeSet()
+			req.Proof.Put(req.Id.Root[:], []byte{0xde, 0xad})
+			return nil
+		}
+	case *CodeRequest:
+		code, err := odr.sdb.Get(req.Hash[:])
+		if err != nil {
+			return err
+		}
+		req.Data = code
+		if odr.corrupt {
+			req.Data = append(code, 0x00)
+			return nil
+		}
+	}
+	req.StoreResult(odr.ldb)
+	return nil
+}
+
+// Tests that the code and the storage of a contract are retrieved and verified
+// against the state root, and that corrupted retrievals are rejected.
+func TestGetCodeAndStorage(t *testing.T) {
+	var (
+		sdb   = berithdb.NewMemDatabase()
+		addr  = common.Address{1}
+		code  = []byte{0x60, 0x01, 0x60, 0x00, 0x55}
+		key   = common.Hash{2}
+		value = common.Hash{31: 42}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(sdb))
+	statedb.SetCode(addr, code)
+	statedb.SetState(addr, key, value)
+	statedb.AddBalance(common.Address{3}, big.NewInt(1))
+	root, err := statedb.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit tries: %v", err)
+	}
+	header := &types.Header{Number: big.NewInt(1), Root: root}
+
+	newOdr := func(corrupt bool) *testOdr {
+		odr := &testOdr{sdb: sdb, ldb: berithdb.NewMemDatabase(), corrupt: corrupt}
+		rawdb.WriteHeader(odr.ldb, header)
+		return odr
+	}
+	// Retrieve the code and the storage from the network, and then from disk
+	odr := newOdr(false)
+	for i := 0; i < 2; i++ {
+		have, err := GetCode(context.Background(), odr, header.Hash(), 1, addr)
+		if err != nil || !bytes.Equal(have, code) {
+			t.Fatalf("run %d: code mismatch: have %x, %v, want %x", i, have, err, code)
+		}
+		slot, err := GetStorageAt(context.Background(), odr, header.Hash(), 1, addr, key)
+		if err != nil || slot != value {
+			t.Fatalf("run %d: storage mismatch: have %x, %v, want %x", i, slot, err, value)
+		}
+	}
+	if odr.requests != 3 {
+		t.Errorf("retrievals mismatch: have %d, want 3", odr.requests)
+	}
+	// Accounts without code and unset slots are empty
+	if have, err := GetCode(context.Background(), odr, header.Hash(), 1, common.Address{3}); have != nil || err != nil {
+		t.Errorf("code of plain account mismatch: have %x, %v", have, err)
+	}
+	if slot, err := GetStorageAt(context.Background(), odr, header.Hash(), 1, addr, common.Hash{4}); slot != (common.Hash{}) || err != nil {
+		t.Errorf("unset slot mismatch: have %x, %v", slot, err)
+	}
+	if _, err := GetCode(context.Background(), odr, common.Hash{5}, 1, addr); err != ErrNoHeader {
+		t.Errorf("unknown block error mismatch: have %v, want %v", err, ErrNoHeader)
+	}
+	// Corrupted proofs and code fail the verification
+	odr = newOdr(true)
+	if _, err := GetStorageAt(context.Background(), odr, header.Hash(), 1, addr, key); err == nil || !strings.Contains(err.Error(), "merkle proof verification failed") {
+		t.Errorf("corrupted proof error mismatch: have %v", err)
+	}
+	odr = newOdr(false)
+	if _, err := GetStorageAt(context.Background(), odr, header.Hash(), 1, addr, key); err != nil {
+		t.Fatalf("failed to retrieve storage: %v", err)
+	}
+	odr.corrupt = true
+	if _, err := GetCode(context.Background(), odr, header.Hash(), 1, addr); err != errCodeHashMismatch {
+		t.Errorf("corrupted code error mismatch: have %v, want %v", err, errCodeHashMismatch)
+	}
+}
@@ -349,7 +349,7 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 	// Validate the transaction sender and it's sig. Throw
 	// if the from fields is invalid.
 	if from, err = types.Sender(pool.signer, tx); err != nil {
-		return core.ErrInvalidSender
+		return core.SenderError(tx, pool.config.ChainID)
 	}
 	// Last but not least check for nonce errors
 	currentState := pool.currentState(ctx)
@@ -0,0 +1,60 @@
+package miner
+
+import (
+	"sync"
+
+	"github.com/BerithFoundation/berith-chain/common"
+)
+
+// DroppedTx is a transaction left out of the block being filled, with the reason
+// it was left out.
+type DroppedTx struct {
+	Hash   common.Hash    `json:"hash"`
+	From   common.Address `json:"from"`
+	Reason string         `json:"reason"`
+}
+
+// droppedTxs is a ring buffer of the transactions most recently left out of the
+// blocks, holding at most limit of them, and is safe for concurrent use. The same
+// transaction is dropped again by every recommit, so it is only recorded again
+// for a different reason.
+type droppedTxs struct {
+	lock  sync.Mutex
+	txs   []DroppedTx
+	next  int // Index the next transaction is recorded at once the buffer is full
+	limit int
+}
+
+// newDroppedTxs creates an empty buffer holding at most limit transactions.
+func newDroppedTxs(limit int) *droppedTxs {
+	return &droppedTxs{txs: make([]DroppedTx, 0, limit), limit: limit}
+}
+
+// add records the dropped transaction, overwriting the oldest one if the buffer
+// is full.
+func (d *droppedTxs) add(tx DroppedTx) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, dropped := range d.txs {
+		if dropped == tx {
+			return
+		}
+	}
+	if len(d.txs) < d.limit {
+		d.txs = append(d.txs, tx)
+		return
+	}
+	d.txs[d.next] = tx
+	d.next = (d.next + 1) % d.limit
+}
+
+// list returns the recorded transactions, oldest first.
+func (d *droppedTxs) list() []DroppedTx {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	list := make([]DroppedTx, 0, len(d.txs))
+	list = append(list, d.txs[d.next:]...)
+	return append(list, d.txs[:d.next]...)
+}
@@ -40,6 +40,54 @@ type Backend interface {
 	TxPool() *core.TxPool
 }
 
+// Config is the configuration parameters of mining.
+type Config struct {
+	Recommit         time.Duration // The time interval for miner to re-create mining work
+	GasFloor         uint64        // Target gas floor for mined blocks
+	GasCeil          uint64        // Target gas ceiling for mined blocks
+	ExtraData        []byte        // Block extra data set by the miner
+	Ordering         TxOrdering    // Transaction ordering used to fill mined blocks
+	NoEmptyPrecommit bool          // Skip sealing an empty block before the transactions are executed
+	MaxUncles        int           // Maximum number of side blocks kept as possible uncles, per local and remote set
+	DroppedTxs       int           // Number of transactions left out of the blocks recorded with the reason, zero to disable
+	GasLimitDivisor  uint64        // Divisor of the parent gas limit bounding the gas limit drift per block, at least params.GasLimitBoundDivisor
+}
+
+// DefaultConfig contains the default mining settings.
+var DefaultConfig = Config{
+	Recommit:  3 * time.Second,
+	GasFloor:  8000000,
+	GasCeil:   8000000,
+	Ordering:  TxOrderingPrice,
+	MaxUncles: 128,
+
+	GasLimitDivisor: params.GasLimitBoundDivisor,
+}
+
+// Sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) Sanitize() Config {
+	conf := *config
+	if conf.Recommit < minRecommitInterval {
+		log.Warn("Sanitizing miner recommit interval", "provided", conf.Recommit, "updated", minRecommitInterval)
+		conf.Recommit = minRecommitInterval
+	}
+	if conf.Ordering == "" {
+		conf.Ordering = DefaultConfig.Ordering
+	}
+	if conf.MaxUncles <= 0 {
+		conf.MaxUncles = DefaultConfig.MaxUncles
+	}
+	// A faster drift than the protocol allows would produce invalid blocks
+	if conf.GasLimitDivisor < params.GasLimitBoundDivisor {
+		if conf.GasLimitDivisor != 0 {
+			log.Warn("Sanitizing miner gas limit divisor", "provided", conf.GasLimitDivisor, "updated", params.GasLimitBoundDivisor)
+		}
+		conf.GasLimitDivisor = params.GasLimitBoundDivisor
+	}
+	return conf
+}
+
 // Miner creates blocks and searches for proof-of-work values.
 type Miner struct {
 	mux      *event.TypeMux
@@ -53,14 +101,14 @@ type Miner struct {
 	shouldStart int32 // should start indicates whether we should start after sync
 }
 
-func New(e Backend, config *params.ChainConfig, mux *event.TypeMux, engine consensus.Engine, recommit time.Duration, gasFloor, gasCeil uint64, isLocalBlock func(block *types.Block) bool) *Miner {
+func New(e Backend, config Config, chainConfig *params.ChainConfig, mux *event.TypeMux, engine consensus.Engine, isLocalBlock func(block *types.Block) bool) *Miner {
 	fmt.Println("New()*Miner 호출")
 	miner := &Miner{
 		e:        e,
 		mux:      mux,
 		engine:   engine,
 		exitCh:   make(chan struct{}),
-		worker:   newWorker(config, engine, e, mux, recommit, gasFloor, gasCeil, isLocalBlock),
+		worker:   newWorker(config, chainConfig, engine, e, mux, isLocalBlock),
 		canStart: 1,
 	}
 	go miner.update()
@@ -112,6 +160,9 @@ func (self *Miner) update() {
 func (self *Miner) Start(coinbase common.Address) {
 	fmt.Println("Miner.Start() 호출")
 	atomic.StoreInt32(&self.shouldStart, 1)
+	if !self.Mining() {
+		self.checkBerithbase(coinbase)
+	}
 	self.SetBerithbase(coinbase)
 
 	if atomic.LoadInt32(&self.canStart) == 0 {
@@ -155,6 +206,42 @@ func (self *Miner) SetRecommitInterval(interval time.Duration) {
 	self.worker.setRecommitInterval(interval)
 }
 
+// SetTargetBlockInterval sets the interval the blocks are sealed at while the
+// engine seals the blocks of a single signer chain without delays, or restores
+// the timing of the chain if zero.
+func (self *Miner) SetTargetBlockInterval(interval time.Duration) error {
+	return self.worker.setTargetBlockInterval(interval)
+}
+
+// RecentlyDropped returns the transactions recently left out of the mined blocks
+// with the reason, oldest first, or nil if they aren't recorded.
+func (self *Miner) RecentlyDropped() []DroppedTx {
+	return self.worker.recentlyDropped()
+}
+
+// SetTxOrdering sets the strategy used to order pending transactions when
+// filling new blocks. It takes effect from the next sealing work onwards.
+func (self *Miner) SetTxOrdering(ordering TxOrdering) {
+	self.worker.setTxOrdering(ordering)
+}
+
+// TxOrdering returns the strategy used to order pending transactions.
+func (self *Miner) TxOrdering() TxOrdering {
+	return self.worker.txOrdering()
+}
+
+// SetTxOrderer sets a custom factory ordering pending transactions when filling
+// new blocks, overriding the ordering strategy. A nil factory restores the
+// strategy. It takes effect from the next sealing work onwards.
+func (self *Miner) SetTxOrderer(factory TxOrdererFactory) {
+	self.worker.setTxOrderer(factory)
+}
+
+// Stats returns the cumulative statistics of the blocks sealed by the miner.
+func (self *Miner) Stats() MinerStats {
+	return self.worker.Stats()
+}
+
 // Pending returns the currently pending block and associated state.
 func (self *Miner) Pending() (*types.Block, *state.StateDB) {
 	return self.worker.pending()
@@ -169,9 +256,34 @@ func (self *Miner) PendingBlock() *types.Block {
 	return self.worker.pendingBlock()
 }
 
+// SetBerithbase sets the address credited with the mined blocks. If the miner
+// is running, the new address is checked against the consensus engine and a
+// warning is logged if it is not eligible to produce blocks.
 func (self *Miner) SetBerithbase(addr common.Address) {
 	self.coinbase = addr
 	self.worker.setBerithbase(addr)
+	if self.Mining() {
+		self.checkBerithbase(addr)
+	}
+}
+
+// checkBerithbase reports whether the given address is currently allowed to
+// produce blocks, logging a warning if it is not. Engines not restricting block
+// production consider every address eligible.
+func (self *Miner) checkBerithbase(addr common.Address) bool {
+	engine, ok := self.engine.(consensus.Eligibility)
+	if !ok {
+		return true
+	}
+	staker, err := engine.IsStaker(self.e.BlockChain(), addr)
+	if err != nil {
+		log.Warn("Failed to check berithbase eligibility", "address", addr, "err", err)
+		return false
+	}
+	if !staker {
+		log.Warn("Berithbase is not a staker, no blocks will be mined", "address", addr)
+	}
+	return staker
 }
 
 func (self *Miner) GetBerithbase() common.Address {
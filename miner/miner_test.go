@@ -0,0 +1,113 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/consensus"
+	"github.com/BerithFoundation/berith-chain/core"
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/params"
+)
+
+// mockBackend is a Backend without a chain or pool, sufficient for tests not
+// touching either of them.
+type mockBackend struct{}
+
+func (mockBackend) BlockChain() *core.BlockChain { return nil }
+func (mockBackend) TxPool() *core.TxPool         { return nil }
+
+// mockEligibilityEngine is a consensus engine only allowing a fixed set of
+// stakers to produce blocks.
+type mockEligibilityEngine struct {
+	consensus.Engine
+	stakers map[common.Address]bool
+}
+
+func (e *mockEligibilityEngine) IsStaker(chain consensus.ChainReader, address common.Address) (bool, error) {
+	return e.stakers[address], nil
+}
+
+// Tests that setting a non-staker berithbase on a running miner emits a warning,
+// while switching to a staker stays silent.
+func TestSetBerithbaseNonStaker(t *testing.T) {
+	var (
+		staker    = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		nonStaker = common.HexToAddress("0x2000000000000000000000000000000000000002")
+		warnings  []string
+	)
+	log.Root().SetHandler(log.FuncHandler(func(r *log.Record) error {
+		if r.Lvl == log.LvlWarn {
+			warnings = append(warnings, r.Msg)
+		}
+		return nil
+	}))
+	defer log.Root().SetHandler(log.DiscardHandler())
+
+	miner := &Miner{
+		e:      mockBackend{},
+		engine: &mockEligibilityEngine{stakers: map[common.Address]bool{staker: true}},
+		worker: &worker{running: 1},
+	}
+	miner.SetBerithbase(staker)
+	if len(warnings) != 0 {
+		t.Fatalf("warnings emitted for staker berithbase: %v", warnings)
+	}
+	miner.SetBerithbase(nonStaker)
+	if len(warnings) != 1 {
+		t.Fatalf("warning count mismatch: have %d, want %d", len(warnings), 1)
+	}
+	if miner.GetBerithbase() != nonStaker {
+		t.Errorf("berithbase not updated: have %x, want %x", miner.GetBerithbase(), nonStaker)
+	}
+	// Idle miners are not checked until they are started
+	miner.worker.stop()
+	miner.SetBerithbase(staker)
+	miner.SetBerithbase(nonStaker)
+	if len(warnings) != 1 {
+		t.Errorf("idle miner emitted warnings: %v", warnings[1:])
+	}
+}
+
+// Tests that sanitizing the miner configuration applies the defaults to unset
+// options and keeps the valid ones.
+func TestConfigSanitize(t *testing.T) {
+	if have := DefaultConfig.Sanitize(); !reflect.DeepEqual(have, DefaultConfig) {
+		t.Errorf("default config changed: have %+v, want %+v", have, DefaultConfig)
+	}
+	have := new(Config).Sanitize()
+	if have.Recommit != minRecommitInterval {
+		t.Errorf("recommit mismatch: have %v, want %v", have.Recommit, minRecommitInterval)
+	}
+	if have.Ordering != TxOrderingPrice {
+		t.Errorf("ordering mismatch: have %q, want %q", have.Ordering, TxOrderingPrice)
+	}
+	if have.MaxUncles != DefaultConfig.MaxUncles {
+		t.Errorf("max uncles mismatch: have %d, want %d", have.MaxUncles, DefaultConfig.MaxUncles)
+	}
+	if have := (&Config{GasLimitDivisor: 2}).Sanitize(); have.GasLimitDivisor != params.GasLimitBoundDivisor {
+		t.Errorf("gas limit divisor mismatch: have %d, want %d", have.GasLimitDivisor, params.GasLimitBoundDivisor)
+	}
+	config := Config{Recommit: 5 * time.Second, GasFloor: 1, GasCeil: 2, ExtraData: []byte("extra"), Ordering: TxOrderingFIFO, NoEmptyPrecommit: true, MaxUncles: 1, GasLimitDivisor: 2048}
+	if have := config.Sanitize(); !reflect.DeepEqual(have, config) {
+		t.Errorf("valid config changed: have %+v, want %+v", have, config)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"fmt"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/core/types"
+)
+
+// TxOrdering is the strategy the worker uses to order pending transactions
+// when filling a block.
+type TxOrdering string
+
+const (
+	// TxOrderingPrice orders transactions by gas price, honouring nonces.
+	TxOrderingPrice TxOrdering = "price"
+
+	// TxOrderingFIFO orders transactions by the time they were first seen by
+	// the transaction pool, honouring nonces.
+	TxOrderingFIFO TxOrdering = "fifo"
+
+	// TxOrderingRoundRobin hands out one transaction per sender in turn.
+	TxOrderingRoundRobin TxOrdering = "roundrobin"
+)
+
+// ParseTxOrdering converts a user supplied strategy name into a TxOrdering.
+// The empty string selects the default price ordering.
+func ParseTxOrdering(name string) (TxOrdering, error) {
+	switch ordering := TxOrdering(name); ordering {
+	case "":
+		return TxOrderingPrice, nil
+	case TxOrderingPrice, TxOrderingFIFO, TxOrderingRoundRobin:
+		return ordering, nil
+	default:
+		return "", fmt.Errorf("unknown transaction ordering %q", name)
+	}
+}
+
+// TxOrderer is the ordered transaction set commitTransactions consumes. Custom
+// implementations must honour the nonce order of the transactions of each account.
+type TxOrderer interface {
+	// Peek returns the next transaction to execute, nil if all done.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one from the same account.
+	Shift()
+
+	// Pop removes the current transaction together with the rest of its account.
+	Pop()
+}
+
+// TxOrdererFactory creates a TxOrderer for the given pending transactions,
+// grouped by account and sorted by nonce.
+type TxOrdererFactory func(signer types.Signer, txs map[common.Address]types.Transactions) TxOrderer
+
+// newTxIterator creates the transaction set for the given pending transactions
+// using the custom orderer factory if set, or the ordering strategy otherwise.
+//
+// Note, the input map is reowned so the caller should not interact any more with
+// if after providing it to the constructor.
+func (w *worker) newTxIterator(signer types.Signer, txs map[common.Address]types.Transactions) TxOrderer {
+	if factory := w.txOrderer(); factory != nil {
+		return factory(signer, txs)
+	}
+	switch w.txOrdering() {
+	case TxOrderingFIFO:
+		return types.NewTransactionsByTimeAndNonce(signer, txs, w.e.TxPool().FirstSeen)
+	case TxOrderingRoundRobin:
+		return types.NewTransactionsByRoundRobin(signer, txs)
+	default:
+		return types.NewTransactionsByPriceAndNonce(signer, txs)
+	}
+}
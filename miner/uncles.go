@@ -0,0 +1,101 @@
+package miner
+
+import (
+	"sync"
+
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/metrics"
+)
+
+var (
+	localUnclesGauge  = metrics.NewRegisteredGauge("miner/uncles/local", nil)
+	remoteUnclesGauge = metrics.NewRegisteredGauge("miner/uncles/remote", nil)
+)
+
+// uncleSet is a set of side blocks kept as possible uncle blocks. It holds at
+// most limit blocks, evicting the earliest added ones first, and is safe for
+// concurrent use.
+type uncleSet struct {
+	lock   sync.RWMutex
+	blocks map[common.Hash]*types.Block
+	order  []common.Hash // Hashes of the blocks in the order they were added
+	limit  int
+	gauge  metrics.Gauge // Gauge tracking the number of blocks in the set
+}
+
+// newUncleSet creates an empty set holding at most limit blocks.
+func newUncleSet(limit int, gauge metrics.Gauge) *uncleSet {
+	return &uncleSet{
+		blocks: make(map[common.Hash]*types.Block),
+		limit:  limit,
+		gauge:  gauge,
+	}
+}
+
+// add adds the block to the set, evicting the earliest added blocks beyond the
+// limit. It returns false if the block is already in the set.
+func (s *uncleSet) add(block *types.Block) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	hash := block.Hash()
+	if _, exist := s.blocks[hash]; exist {
+		return false
+	}
+	s.blocks[hash] = block
+	s.order = append(s.order, hash)
+	for len(s.order) > s.limit {
+		delete(s.blocks, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.gauge.Update(int64(len(s.blocks)))
+	return true
+}
+
+// get returns the block of the given hash, if it is in the set.
+func (s *uncleSet) get(hash common.Hash) (*types.Block, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	block, exist := s.blocks[hash]
+	return block, exist
+}
+
+// list returns the blocks of the set in the order they were added.
+func (s *uncleSet) list() []*types.Block {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	blocks := make([]*types.Block, 0, len(s.order))
+	for _, hash := range s.order {
+		blocks = append(blocks, s.blocks[hash])
+	}
+	return blocks
+}
+
+// expire removes the blocks too old to be the uncles of a block of the given
+// number.
+func (s *uncleSet) expire(number uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	order := s.order[:0]
+	for _, hash := range s.order {
+		if s.blocks[hash].NumberU64()+staleThreshold <= number {
+			delete(s.blocks, hash)
+			continue
+		}
+		order = append(order, hash)
+	}
+	s.order = order
+	s.gauge.Update(int64(len(s.blocks)))
+}
+
+// len returns the number of blocks in the set.
+func (s *uncleSet) len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return len(s.blocks)
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/BerithFoundation/berith-chain/core/types"
 	"github.com/BerithFoundation/berith-chain/event"
 	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/metrics"
 	"github.com/BerithFoundation/berith-chain/params"
 	mapset "github.com/deckarep/golang-set"
 )
@@ -77,6 +78,15 @@ const (
 	staleThreshold = 7
 )
 
+var (
+	// staleResultCounter counts sealing results discarded for being older than the head.
+	staleResultCounter = metrics.NewRegisteredCounter("miner/result/stale", nil)
+
+	// errNoSoloSealing is returned when a target block interval is set while the
+	// engine doesn't seal the blocks of a single signer chain without delays.
+	errNoSoloSealing = errors.New("target block interval requires solo sealing by the only signer of the chain")
+)
+
 // environment is the worker's current environment and holds all of the current state information.
 // environment는 작업자의 현재 환경이며 모든 현재 상태 정보를 보유하고 있다.
 type environment struct {
@@ -92,6 +102,19 @@ type environment struct {
 	header   *types.Header
 	txs      []*types.Transaction
 	receipts []*types.Receipt
+	steps    []txStep // Selection steps which led to the transactions, replayed by the recommits
+
+	sealID uint64 // Correlation id of the sealing attempt, assigned on prepare
+
+	prepare time.Duration // Time spent preparing the header
+	packing time.Duration // Time spent packing the transactions
+}
+
+// txStep is a step of the transaction selection of a work: the transaction was
+// either executed or skipped, or skipped along with the rest of its account.
+type txStep struct {
+	hash common.Hash
+	pop  bool // Whether the rest of the account was skipped as well
 }
 
 // task contains all information for consensus engine sealing and result submitting.
@@ -99,7 +122,29 @@ type task struct {
 	receipts  []*types.Receipt
 	state     *state.StateDB
 	block     *types.Block
+	fees      *big.Int // Transaction fees paid to the coinbase, in wei
+	sealID    uint64   // Correlation id of the sealing attempt for logging
 	createdAt time.Time
+
+	prepare   time.Duration // Time spent preparing the header
+	packing   time.Duration // Time spent packing the transactions
+	sealStart time.Time     // Time the block was handed to the engine for sealing
+}
+
+// MinerStats contains the cumulative statistics of the blocks sealed by the
+// local miner since startup.
+type MinerStats struct {
+	Blocks uint64   // Number of blocks sealed and written to the chain
+	Txs    uint64   // Number of transactions included in the sealed blocks
+	Fees   *big.Int // Transaction fees earned by the sealed blocks, in wei
+}
+
+// AvgTxs returns the average number of transactions per sealed block.
+func (s MinerStats) AvgTxs() float64 {
+	if s.Blocks == 0 {
+		return 0
+	}
+	return float64(s.Txs) / float64(s.Blocks)
 }
 
 const (
@@ -126,13 +171,11 @@ type intervalAdjust struct {
 // worker is the main object which takes care of submitting new work to consensus engine
 // and gathering the sealing result.
 type worker struct {
-	config *params.ChainConfig
-	engine consensus.Engine
-	e      Backend
-	chain  *core.BlockChain
-
-	gasFloor uint64
-	gasCeil  uint64
+	config      Config
+	chainConfig *params.ChainConfig
+	engine      consensus.Engine
+	e           Backend
+	chain       *core.BlockChain
 
 	// Subscriptions
 	mux          *event.TypeMux
@@ -151,16 +194,25 @@ type worker struct {
 	exitCh             chan struct{}
 	resubmitIntervalCh chan time.Duration
 	resubmitAdjustCh   chan *intervalAdjust
+	targetIntervalCh   chan time.Duration
 
-	current      *environment                 // An environment for current running cycle.
-	localUncles  map[common.Hash]*types.Block // A set of side blocks generated locally as the possible uncle blocks.
-	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
-	unconfirmed  *unconfirmedBlocks           // A set of locally mined blocks pending canonicalness confirmations.
+	current      *environment       // An environment for current running cycle.
+	localUncles  *uncleSet          // A set of side blocks generated locally as the possible uncle blocks.
+	remoteUncles *uncleSet          // A set of side blocks as the possible uncle blocks.
+	unconfirmed  *unconfirmedBlocks // A set of locally mined blocks pending canonicalness confirmations.
 
 	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
 	coinbase common.Address
 	extra    []byte
 
+	ordering atomic.Value // Transaction ordering strategy (TxOrdering) used to fill blocks
+	orderer  atomic.Value // Custom transaction orderer factory (TxOrdererFactory) overriding the strategy
+
+	statsMu sync.RWMutex // The lock used to protect the sealing statistics
+	stats   MinerStats
+
+	dropped *droppedTxs // Transactions recently left out of the blocks, nil if not recorded
+
 	pendingMu    sync.RWMutex
 	pendingTasks map[common.Hash]*task
 
@@ -169,8 +221,9 @@ type worker struct {
 	snapshotState *state.StateDB
 
 	// atomic status counters
-	running int32 // The indicator whether the consensus engine is running or not.
-	newTxs  int32 // New arrival transaction count since last sealing work submitting.
+	running int32  // The indicator whether the consensus engine is running or not.
+	newTxs  int32  // New arrival transaction count since last sealing work submitting.
+	sealSeq uint64 // Last correlation id assigned to a sealing attempt.
 
 	// External functions
 	isLocalBlock func(block *types.Block) bool // Function used to determine whether the specified block is mined by local miner.
@@ -182,19 +235,20 @@ type worker struct {
 	resubmitHook func(time.Duration, time.Duration) // Method to call upon updating resubmitting interval.
 }
 
-func newWorker(config *params.ChainConfig, engine consensus.Engine, e Backend, mux *event.TypeMux, recommit time.Duration, gasFloor, gasCeil uint64, isLocalBlock func(*types.Block) bool) *worker {
+func newWorker(config Config, chainConfig *params.ChainConfig, engine consensus.Engine, e Backend, mux *event.TypeMux, isLocalBlock func(*types.Block) bool) *worker {
 	fmt.Println("newWorker() 호출")
+	config = config.Sanitize()
 	worker := &worker{
 		config:             config,
+		chainConfig:        chainConfig,
 		engine:             engine,
 		e:                  e,
 		mux:                mux,
 		chain:              e.BlockChain(),
-		gasFloor:           gasFloor,
-		gasCeil:            gasCeil,
+		extra:              config.ExtraData,
 		isLocalBlock:       isLocalBlock,
-		localUncles:        make(map[common.Hash]*types.Block),
-		remoteUncles:       make(map[common.Hash]*types.Block),
+		localUncles:        newUncleSet(config.MaxUncles, localUnclesGauge),
+		remoteUncles:       newUncleSet(config.MaxUncles, remoteUnclesGauge),
 		unconfirmed:        newUnconfirmedBlocks(e.BlockChain(), miningLogAtDepth),
 		pendingTasks:       make(map[common.Hash]*task),
 		txsCh:              make(chan core.NewTxsEvent, txChanSize),
@@ -207,21 +261,21 @@ func newWorker(config *params.ChainConfig, engine consensus.Engine, e Backend, m
 		startCh:            make(chan struct{}, 1),
 		resubmitIntervalCh: make(chan time.Duration),
 		resubmitAdjustCh:   make(chan *intervalAdjust, resubmitAdjustChanSize),
+		targetIntervalCh:   make(chan time.Duration),
+	}
+	worker.setTxOrdering(config.Ordering)
+	if config.DroppedTxs > 0 {
+		worker.dropped = newDroppedTxs(config.DroppedTxs)
 	}
+
 	// Subscribe NewTxsEvent for tx pool
 	worker.txsSub = e.TxPool().SubscribeNewTxsEvent(worker.txsCh)
 	// Subscribe events for blockchain
 	worker.chainHeadSub = e.BlockChain().SubscribeChainHeadEvent(worker.chainHeadCh)
 	worker.chainSideSub = e.BlockChain().SubscribeChainSideEvent(worker.chainSideCh)
 
-	// Sanitize recommit interval if the user-specified one is too short.
-	if recommit < minRecommitInterval {
-		log.Warn("Sanitizing miner recommit interval", "provided", recommit, "updated", minRecommitInterval)
-		recommit = minRecommitInterval
-	}
-
 	go worker.mainLoop()
-	go worker.newWorkLoop(recommit)
+	go worker.newWorkLoop(config.Recommit)
 	go worker.resultLoop()
 	go worker.taskLoop()
 
@@ -245,11 +299,81 @@ func (w *worker) setExtra(extra []byte) {
 	w.extra = extra
 }
 
+// setTxOrdering sets the strategy used to order pending transactions.
+func (w *worker) setTxOrdering(ordering TxOrdering) {
+	if ordering == "" {
+		ordering = TxOrderingPrice
+	}
+	w.ordering.Store(ordering)
+}
+
+// txOrdering returns the strategy used to order pending transactions.
+func (w *worker) txOrdering() TxOrdering {
+	return w.ordering.Load().(TxOrdering)
+}
+
+// setTxOrderer sets a custom factory ordering pending transactions, overriding
+// the ordering strategy. A nil factory restores the strategy.
+func (w *worker) setTxOrderer(factory TxOrdererFactory) {
+	w.orderer.Store(factory)
+}
+
+// txOrderer returns the custom transaction orderer factory, nil if none is set.
+func (w *worker) txOrderer() TxOrdererFactory {
+	factory, _ := w.orderer.Load().(TxOrdererFactory)
+	return factory
+}
+
 // setRecommitInterval updates the interval for miner sealing work recommitting.
 func (w *worker) setRecommitInterval(interval time.Duration) {
 	w.resubmitIntervalCh <- interval
 }
 
+// setTargetBlockInterval sets the interval the blocks are sealed at while the
+// engine seals them without delays, overriding the timing derived from the block
+// period. A zero interval restores the timing of the chain.
+func (w *worker) setTargetBlockInterval(interval time.Duration) error {
+	if interval < 0 {
+		return fmt.Errorf("invalid target block interval %v", interval)
+	}
+	if interval > 0 {
+		if !w.soloSealing() {
+			return errNoSoloSealing
+		}
+		// Blocks faster than the period would be timestamped ahead of the clock
+		if period := time.Duration(w.chainConfig.Bsrr.Period) * time.Second; interval < period {
+			return fmt.Errorf("target block interval %v is shorter than the block period %v", interval, period)
+		}
+	}
+	w.targetIntervalCh <- interval
+	return nil
+}
+
+// soloSealing returns whether the engine seals the block on top of the current
+// head without delays.
+func (w *worker) soloSealing() bool {
+	solo, ok := w.engine.(consensus.SoloSealer)
+	return ok && solo.SoloSealing(w.chain)
+}
+
+// recordDropped records the transaction left out of the block for the given
+// reason, if the dropped transactions are recorded.
+func (w *worker) recordDropped(tx *types.Transaction, from common.Address, reason string) {
+	if w.dropped == nil {
+		return
+	}
+	w.dropped.add(DroppedTx{Hash: tx.Hash(), From: from, Reason: reason})
+}
+
+// recentlyDropped returns the transactions recently left out of the blocks,
+// oldest first, or nil if they aren't recorded.
+func (w *worker) recentlyDropped() []DroppedTx {
+	if w.dropped == nil {
+		return nil
+	}
+	return w.dropped.list()
+}
+
 // pending returns the pending state and corresponding block.
 func (w *worker) pending() (*types.Block, *state.StateDB) {
 	// return a snapshot to avoid contention on currentMu mutex
@@ -300,10 +424,15 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 		minRecommit = recommit // minimal resubmit interval specified by user.
 		timestamp   int64      // timestamp for each round of mining.
 		thread      int
+
+		target time.Duration // target block interval of solo sealing, zero if unset
+		slot   time.Time     // time the work of the next block is due at with a target interval
 	)
 
 	timer := time.NewTimer(0)
 	<-timer.C // discard the initial tick
+	slotTimer := time.NewTimer(0)
+	<-slotTimer.C // discard the initial tick
 
 	var interrupt *int32
 	// commit aborts in-flight transaction execution with given signal and resubmits a new one.
@@ -359,11 +488,32 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 			fmt.Println("NewWorkLoop() / worker.startCh 개방 후 하위 로직 실행")
 			clearPending(w.chain.CurrentBlock().NumberU64())
 			timestamp = time.Now().Unix()
+			slot = time.Now()
 			commit(false, commitInterruptNewHead) // const commitInterruptNewHead int32 = 1
 
 		case head := <-w.chainHeadCh:
 			fmt.Println("NewWorkLoop() / worker.chainHeadCh 개방 후 하위 로직 실행")
 			clearPending(head.Block.NumberU64())
+			// Drop the side blocks too old to be included by the next block,
+			// even if no work is committed on top of the new head
+			w.localUncles.expire(head.Block.NumberU64() + 1)
+			w.remoteUncles.expire(head.Block.NumberU64() + 1)
+
+			// Pace the solo sealed blocks on a fixed timeline, so the time spent
+			// creating them doesn't add up. A timeline fallen behind by more than
+			// an interval, after the mining was paused, starts over.
+			if target > 0 && w.soloSealing() {
+				now := time.Now()
+				if slot = slot.Add(target); slot.Before(now.Add(-target)) {
+					slot = now
+				}
+				slotTimer.Reset(slot.Sub(now))
+				continue
+			}
+			timestamp = time.Now().Unix()
+			commit(false, commitInterruptNewHead)
+
+		case <-slotTimer.C:
 			timestamp = time.Now().Unix()
 			commit(false, commitInterruptNewHead)
 
@@ -382,6 +532,10 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 				w.resubmitHook(minRecommit, recommit)
 			}
 
+		case interval := <-w.targetIntervalCh:
+			log.Info("Miner target block interval update", "from", target, "to", interval)
+			target, slot = interval, time.Now()
+
 		case adjust := <-w.resubmitAdjustCh:
 			fmt.Println("newWorkLoop() / resubmitAdjustCh 수신, adjust : ", adjust.inc, adjust.ratio)
 			// Adjust resubmit interval by feedback.
@@ -420,19 +574,9 @@ func (w *worker) mainLoop() {
 			workCnt++
 		case ev := <-w.chainSideCh:
 			fmt.Println("worker.mainLoop() / worker.chainSideCh 수신")
-			// Short circuit for duplicate side blocks
-			if _, exist := w.localUncles[ev.Block.Hash()]; exist {
-				continue
-			}
-			if _, exist := w.remoteUncles[ev.Block.Hash()]; exist {
+			if !w.addSideBlock(ev.Block) {
 				continue
 			}
-			// Add side block to possible uncle block set depending on the author.
-			if w.isLocalBlock != nil && w.isLocalBlock(ev.Block) {
-				w.localUncles[ev.Block.Hash()] = ev.Block
-			} else {
-				w.remoteUncles[ev.Block.Hash()] = ev.Block
-			}
 			// If our mining block contains less than 2 uncle blocks,
 			// add the new uncle block if valid and regenerate a mining block.
 			if w.isRunning() && w.current != nil && w.current.uncles.Cardinality() < 2 {
@@ -444,10 +588,7 @@ func (w *worker) mainLoop() {
 						if !ok {
 							return false
 						}
-						uncle, exist := w.localUncles[hash]
-						if !exist {
-							uncle, exist = w.remoteUncles[hash]
-						}
+						uncle, exist := w.uncle(hash)
 						if !exist {
 							return false
 						}
@@ -477,7 +618,7 @@ func (w *worker) mainLoop() {
 					txs[acc] = append(txs[acc], tx)
 				}
 
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs)
+				txset := w.newTxIterator(w.current.signer, txs)
 				w.commitTransactions(txset, coinbase, nil)
 				w.updateSnapshot()
 			}
@@ -534,12 +675,18 @@ func (w *worker) taskLoop() {
 			if w.skipSealHook != nil && w.skipSealHook(task) {
 				continue
 			}
+			task.sealStart = time.Now()
+
 			w.pendingMu.Lock()
 			w.pendingTasks[w.engine.SealHash(task.block.Header())] = task
 			w.pendingMu.Unlock()
 
+			if tracker, ok := w.engine.(consensus.SealTracker); ok {
+				tracker.TrackSeal(sealHash, task.sealID)
+			}
+			log.Debug("Submitted sealing task", "seal", task.sealID, "number", task.block.Number(), "sealhash", sealHash)
 			if err := w.engine.Seal(w.chain, task.block, w.resultCh, stopCh); err != nil {
-				log.Warn("Block sealing failed", "err", err)
+				log.Warn("Block sealing failed", "seal", task.sealID, "err", err)
 			}
 		case <-w.exitCh:
 			interrupt()
@@ -548,6 +695,34 @@ func (w *worker) taskLoop() {
 	}
 }
 
+// Stats returns the cumulative statistics of the blocks sealed by this worker.
+func (w *worker) Stats() MinerStats {
+	w.statsMu.RLock()
+	defer w.statsMu.RUnlock()
+
+	stats := w.stats
+	stats.Fees = new(big.Int)
+	if w.stats.Fees != nil {
+		stats.Fees.Set(w.stats.Fees)
+	}
+	return stats
+}
+
+// recordSealed accumulates a block sealed by this worker into the statistics.
+func (w *worker) recordSealed(block *types.Block, fees *big.Int) {
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+
+	w.stats.Blocks++
+	w.stats.Txs += uint64(len(block.Transactions()))
+	if w.stats.Fees == nil {
+		w.stats.Fees = new(big.Int)
+	}
+	if fees != nil {
+		w.stats.Fees.Add(w.stats.Fees, fees)
+	}
+}
+
 // resultLoop is a standalone goroutine to handle sealing result submitting
 // and flush relative data to the database.
 func (w *worker) resultLoop() {
@@ -572,6 +747,17 @@ func (w *worker) resultLoop() {
 			w.pendingMu.RLock()
 			task, exist := w.pendingTasks[sealhash]
 			w.pendingMu.RUnlock()
+
+			// Quickly discard results which were overtaken by the chain meanwhile.
+			if head := w.chain.CurrentBlock(); block.NumberU64() < head.NumberU64() {
+				var sealID uint64
+				if exist {
+					sealID = task.sealID
+				}
+				staleResultCounter.Inc(1)
+				log.Debug("Discarded stale sealing result", "seal", sealID, "number", block.Number(), "head", head.Number(), "sealhash", sealhash)
+				continue
+			}
 			if !exist {
 				log.Error("Block found but no relative pending task", "number", block.Number(), "sealhash", sealhash, "hash", hash)
 				continue
@@ -593,13 +779,24 @@ func (w *worker) resultLoop() {
 			}
 			// Commit block and state to database.
 			// 블록과 스테이트를 DB에 기록한다.
+			sealed := time.Now()
 			stat, err := w.chain.WriteBlockWithState(block, receipts, task.state)
 			if err != nil {
 				log.Error("Failed writing block to chain", "err", err)
 				continue
 			}
-			log.Info("Successfully sealed new block", "number", block.Number(), "sealhash", sealhash, "hash", hash,
+			w.chain.RecordBlockTiming(&core.BlockTiming{
+				Number:    block.NumberU64(),
+				Hash:      hash,
+				Mined:     true,
+				Prepare:   task.prepare,
+				Packing:   task.packing,
+				SealDelay: sealed.Sub(task.sealStart),
+				Write:     time.Since(sealed),
+			})
+			log.Info("Successfully sealed new block", "seal", task.sealID, "number", block.Number(), "sealhash", sealhash, "hash", hash,
 				"elapsed", common.PrettyDuration(time.Since(task.createdAt)))
+			w.recordSealed(block, task.fees)
 
 			// Broadcast the block and announce chain insertion event
 			w.mux.Post(core.NewMinedBlockEvent{Block: block})
@@ -634,7 +831,7 @@ func (w *worker) makeCurrent(parent *types.Block, header *types.Header) error {
 		return err
 	}
 	env := &environment{
-		signer: types.NewEIP155Signer(w.config.ChainID),
+		signer: types.NewEIP155Signer(w.chainConfig.ChainID),
 		state:  state,
 		//thread unsafeset
 		ancestors: mapset.NewSet(),
@@ -683,6 +880,27 @@ func (w *worker) commitUncle(env *environment, uncle *types.Header) error {
 	return nil
 }
 
+// addSideBlock adds the side block to the possible uncle block set depending on
+// the author, returns false if the block is already known.
+func (w *worker) addSideBlock(block *types.Block) bool {
+	if _, exist := w.uncle(block.Hash()); exist {
+		return false
+	}
+	if w.isLocalBlock != nil && w.isLocalBlock(block) {
+		return w.localUncles.add(block)
+	}
+	return w.remoteUncles.add(block)
+}
+
+// uncle returns the possible uncle block of the given hash, preferring the
+// locally generated ones.
+func (w *worker) uncle(hash common.Hash) (*types.Block, bool) {
+	if uncle, exist := w.localUncles.get(hash); exist {
+		return uncle, true
+	}
+	return w.remoteUncles.get(hash)
+}
+
 // updateSnapshot updates pending snapshot block and state.
 // Note this function assumes the current variable is thread safe.
 func (w *worker) updateSnapshot() {
@@ -696,10 +914,7 @@ func (w *worker) updateSnapshot() {
 		if !ok {
 			return false
 		}
-		uncle, exist := w.localUncles[hash]
-		if !exist {
-			uncle, exist = w.remoteUncles[hash]
-		}
+		uncle, exist := w.uncle(hash)
 		if !exist {
 			return false
 		}
@@ -723,7 +938,7 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 
 	// current의 state는 이전 블록 root 기반이기 때문에 블록이 추가되지 못한 채
 	// commitNewWork 내부에서 makeCurrent가 다시 실행되면 자동으로 revert 되는 셈이다.
-	receipt, _, err := core.ApplyTransaction(w.config, w.chain, &coinbase, w.current.gasPool, w.current.state, w.current.header, tx, &w.current.header.GasUsed, *w.chain.GetVMConfig())
+	receipt, _, err := core.ApplyTransaction(w.chainConfig, w.chain, &coinbase, w.current.gasPool, w.current.state, w.current.header, tx, &w.current.header.GasUsed, *w.chain.GetVMConfig())
 	if err != nil { // 트랜잭션 실행이 실패할 경우 스냅샷을 되돌린다.
 		w.current.state.RevertToSnapshot(snap)
 		fmt.Println("commitTransaction / Failed apply tx , err : ", err)
@@ -737,7 +952,7 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, interrupt *int32) bool {
+func (w *worker) commitTransactions(txs TxOrderer, coinbase common.Address, interrupt *int32) bool {
 	fmt.Println("worker.commintTransacitons() 호출")
 	// Short circuit if current is nil
 	if w.current == nil {
@@ -749,6 +964,16 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 		w.current.gasPool = new(core.GasPool).AddGas(w.current.header.GasLimit)
 	}
 
+	// Record the selection steps, telling the recommits on the same parent
+	// whether they select the same transactions
+	shift := func(tx *types.Transaction) {
+		w.current.steps = append(w.current.steps, txStep{hash: tx.Hash()})
+		txs.Shift()
+	}
+	pop := func(tx *types.Transaction) {
+		w.current.steps = append(w.current.steps, txStep{hash: tx.Hash(), pop: true})
+		txs.Pop()
+	}
 	var coalescedLogs []*types.Log
 
 	for {
@@ -799,10 +1024,11 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 		from, _ := types.Sender(w.current.signer, tx)
 		// Check whether the tx is replay protected. If we're not in the EIP155 hf
 		// phase, start ignoring the sender until we do.
-		if tx.Protected() && !w.config.IsEIP155(w.current.header.Number) {
-			log.Trace("Ignoring reply protected transaction", "hash", tx.Hash(), "eip155", w.config.EIP155Block)
+		if tx.Protected() && !w.chainConfig.IsEIP155(w.current.header.Number) {
+			log.Trace("Ignoring reply protected transaction", "hash", tx.Hash(), "eip155", w.chainConfig.EIP155Block)
+			w.recordDropped(tx, from, "replay protected before EIP155")
 
-			txs.Pop()
+			pop(tx)
 			continue
 		}
 		// Start executing the transaction
@@ -811,35 +1037,36 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 		logs, err := w.commitTransaction(tx, coinbase)
 		if err != nil {
 			fmt.Println("commitTransaction Err : ", err)
+			w.recordDropped(tx, from, err.Error())
 		}
 		switch err {
 		case core.ErrGasLimitReached:
 			// Pop the current out-of-gas transaction without shifting in the next from the account
 			log.Trace("Gas limit exceeded for current block", "sender", from)
-			txs.Pop()
+			pop(tx)
 
 		case core.ErrNonceTooLow:
 			// New head notification data race between the transaction pool and miner, shift
 			log.Trace("Skipping transaction with low nonce", "sender", from, "nonce", tx.Nonce())
-			txs.Shift()
+			shift(tx)
 
 		case core.ErrNonceTooHigh:
 			// Reorg notification data race between the transaction pool and miner, skip account =
 			log.Trace("Skipping account with hight nonce", "sender", from, "nonce", tx.Nonce())
-			txs.Pop()
+			pop(tx)
 
 		case nil:
 			fmt.Println("commitTransaction Err is nil. Log : ", logs)
 			// Everything ok, collect the logs and shift in the next transaction from the same account
 			coalescedLogs = append(coalescedLogs, logs...)
 			w.current.tcount++
-			txs.Shift()
+			shift(tx)
 
 		default:
 			// Strange error, discard the transaction and get the next in line (note, the
 			// nonce-too-high clause will prevent us from executing in vain).
 			log.Debug("Transaction failed, account skipped", "hash", tx.Hash(), "err", err)
-			txs.Shift()
+			shift(tx)
 		}
 	}
 
@@ -868,6 +1095,27 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 	return false
 }
 
+// boundGasLimit returns the gas limit of a block clamped to the adjustment
+// allowed per block relative to the limit of its parent, the parent's limit
+// divided by divisor (params.GasLimitBoundDivisor if zero), along with an error
+// telling by how much it was beyond it, if it was.
+func boundGasLimit(parent uint64, limit uint64, divisor uint64) (uint64, error) {
+	if divisor == 0 {
+		divisor = params.GasLimitBoundDivisor
+	}
+	max := parent / divisor
+	if max > 0 {
+		max-- // The adjustment must stay below the bound
+	}
+	switch {
+	case limit > parent && limit-parent > max:
+		return parent + max, fmt.Errorf("gas limit %d raised by %d over parent %d, allowed %d", limit, limit-parent, parent, max)
+	case limit < parent && parent-limit > max:
+		return parent - max, fmt.Errorf("gas limit %d lowered by %d under parent %d, allowed %d", limit, parent-limit, parent, max)
+	}
+	return limit, nil
+}
+
 // commitNewWork generates several new sealing tasks based on the parent block.
 // commintNewWork는 부모 블록을 기반하여 여러개의 확정된 새 작업들을 생성한다.
 func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64) {
@@ -891,11 +1139,19 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 	}
 
 	num := parent.Number()
+	// Keep the gas limit within the adjustment allowed per block. CalcGasLimit
+	// bounds it by the protocol's divisor only, and not at all if the parent
+	// limit is below it
+	gasLimit := core.CalcGasLimit(parent, w.config.GasFloor, w.config.GasCeil)
+	if bounded, err := boundGasLimit(parent.GasLimit(), gasLimit, w.config.GasLimitDivisor); err != nil {
+		log.Warn("Gas limit adjustment out of bounds, check the gas floor and ceiling", "number", new(big.Int).Add(num, common.Big1), "floor", w.config.GasFloor, "ceil", w.config.GasCeil, "err", err)
+		gasLimit = bounded
+	}
 	// 새 블록의 헤더 초깃값 세팅
 	header := &types.Header{
 		ParentHash: parent.Hash(),
 		Number:     num.Add(num, common.Big1), // 여기서 다음 블록이 될 헤더의 넘버를 1 증가시킨다.
-		GasLimit:   core.CalcGasLimit(parent, w.gasFloor, w.gasCeil),
+		GasLimit:   gasLimit,
 		Extra:      w.extra,
 		Time:       big.NewInt(timestamp),
 	}
@@ -908,19 +1164,24 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		}
 		header.Coinbase = w.coinbase
 	}
+	pstart := time.Now()
 	if err := w.engine.Prepare(w.chain, header); err != nil {
 		log.Error("Failed to prepare header for mining", "err", err)
 		return
 	}
+	prepare := time.Since(pstart)
+	sealID := atomic.AddUint64(&w.sealSeq, 1)
+	log.Debug("Prepared new sealing work", "seal", sealID, "number", header.Number)
+
 	// If we are care about TheDAO hard-fork check whether to override the extra-data or not
 	// 만약 DAO 하드포크를 고려한다면 추가 데이터를 재정의할지 확인한다.
 	// 그러나 Berith는 MainnetChainConfig에서 DAOForkBlock을 nil로 설정하기 때문에 건너뛴다.
-	if daoBlock := w.config.DAOForkBlock; daoBlock != nil {
+	if daoBlock := w.chainConfig.DAOForkBlock; daoBlock != nil {
 		// Check whether the block is among the fork extra-override range
 		limit := new(big.Int).Add(daoBlock, params.DAOForkExtraRange)
 		if header.Number.Cmp(daoBlock) >= 0 && header.Number.Cmp(limit) < 0 {
 			// Depending whether we support or oppose the fork, override differently
-			if w.config.DAOForkSupport {
+			if w.chainConfig.DAOForkSupport {
 				header.Extra = common.CopyBytes(params.DAOForkBlockExtra)
 			} else if bytes.Equal(header.Extra, params.DAOForkBlockExtra) {
 				header.Extra = []byte{} // If miner opposes, don't let it use the reserved extra-data
@@ -928,32 +1189,32 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		}
 	}
 	// Could potentially happen if starting to mine in an odd state.
+	prev := w.current
 	err := w.makeCurrent(parent, header)
 	if err != nil {
 		log.Error("Failed to create mining context", "err", err)
 		return
 	}
+	w.current.sealID = sealID
+	w.current.prepare = prepare
 	// Create the current work task and check any fork transitions needed
 	// 현재 작업을 생성하고 필요한 포크 전환을 체크한다.
 	env := w.current
-	if w.config.DAOForkSupport && w.config.DAOForkBlock != nil && w.config.DAOForkBlock.Cmp(header.Number) == 0 {
+	if w.chainConfig.DAOForkSupport && w.chainConfig.DAOForkBlock != nil && w.chainConfig.DAOForkBlock.Cmp(header.Number) == 0 {
 		misc.ApplyDAOHardFork(env.state)
 	}
 	// Accumulate the uncles for the current block
 	// 현재 블럭의 엉클블럭을 모은다.
 	uncles := make([]*types.Header, 0, 2)
-	commitUncles := func(blocks map[common.Hash]*types.Block) {
-		fmt.Println("commitNewWork() 내부 commitUncles() 호출, uncles : ", len(blocks))
+	commitUncles := func(blocks *uncleSet) {
+		fmt.Println("commitNewWork() 내부 commitUncles() 호출, uncles : ", blocks.len())
 		// Clean up stale uncle blocks first
-		for hash, uncle := range blocks {
-			if uncle.NumberU64()+staleThreshold <= header.Number.Uint64() {
-				delete(blocks, hash)
-			}
-		}
-		for hash, uncle := range blocks {
+		blocks.expire(header.Number.Uint64())
+		for _, uncle := range blocks.list() {
 			if len(uncles) == 2 {
 				break
 			}
+			hash := uncle.Hash()
 			if err := w.commitUncle(env, uncle.Header()); err != nil {
 				log.Trace("Possible uncle rejected", "hash", hash, "reason", err)
 			} else {
@@ -965,7 +1226,7 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 	// Prefer to locally generated uncle
 	commitUncles(w.localUncles)
 	commitUncles(w.remoteUncles)
-	if !noempty {
+	if !noempty && !w.config.NoEmptyPrecommit {
 		// Create an empty block based on temporary copied state for sealing in advance without waiting block
 		// execution finished.
 		// 블럭 확정 처리를 기다리지 않고 미리 포장을 하기 위해 임시로 복제된 state를 기반으로 빈 블럭을 생성한다.
@@ -996,18 +1257,38 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		}
 	}
 	fmt.Printf("LocalTxs : %d, ReoteTxs : %d\n", len(localTxs), len(remoteTxs))
-	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs)
-		fmt.Printf("worker.commitNewWork / interrupt : %v\n", atomic.LoadInt32(interrupt))
-		if w.commitTransactions(txs, w.coinbase, interrupt) {
-			fmt.Println("commitNewWork / LocalTxs_Return")
-			return
+	// Continue from the transactions executed by the previous work on the same
+	// parent if the selection starts with them, instead of executing them again
+	var (
+		sets    []TxOrderer
+		resumed bool
+	)
+	if w.resumable(prev, header) {
+		sets = w.newTxSets(copyPending(localTxs), copyPending(remoteTxs))
+		if resumed = replayTxSteps(prev.steps, sets); resumed {
+			env.state, env.gasPool, env.tcount = prev.state, prev.gasPool, prev.tcount
+			env.txs, env.receipts, env.steps = prev.txs, prev.receipts, prev.steps
+			env.header.GasUsed = prev.header.GasUsed
+
+			// The transactions were executed at the time of the previous work,
+			// which is still valid on the same parent
+			env.header.Time = new(big.Int).Set(prev.header.Time)
+
+			log.Debug("Resumed previous sealing work", "seal", sealID, "number", header.Number, "txs", env.tcount)
 		}
 	}
-	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs)
-		if w.commitTransactions(txs, w.coinbase, interrupt) {
-			fmt.Println("commitNewWork / RemoteTxs_Return")
+	if !resumed {
+		sets = w.newTxSets(localTxs, remoteTxs)
+	}
+	for _, txs := range sets {
+		if txs == nil {
+			continue
+		}
+		pstart := time.Now()
+		interrupted := w.commitTransactions(txs, w.coinbase, interrupt)
+		w.current.packing += time.Since(pstart)
+		if interrupted {
+			fmt.Println("commitNewWork / Txs_Return")
 			return
 		}
 	}
@@ -1015,6 +1296,72 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 	w.commit(uncles, w.fullTaskHook, true, tstart)
 }
 
+// newTxSets creates the transaction sets filling the block, the local transactions
+// first and the remote ones after them. The sets of no transactions are nil.
+func (w *worker) newTxSets(localTxs, remoteTxs map[common.Address]types.Transactions) []TxOrderer {
+	sets := make([]TxOrderer, 2)
+	for i, txs := range []map[common.Address]types.Transactions{localTxs, remoteTxs} {
+		if len(txs) > 0 {
+			sets[i] = w.newTxIterator(w.current.signer, txs)
+		}
+	}
+	return sets
+}
+
+// copyPending returns a copy of the pending transactions, which the transaction
+// sets may reown.
+func copyPending(txs map[common.Address]types.Transactions) map[common.Address]types.Transactions {
+	cpy := make(map[common.Address]types.Transactions, len(txs))
+	for addr, list := range txs {
+		cpy[addr] = list
+	}
+	return cpy
+}
+
+// resumable reports whether the transactions executed by the previous work can
+// be reused by the work of the header: the work must build on the same parent
+// with the same gas limit and coinbase. The difficulty follows from them, while
+// a resumed work keeps the time of the previous one.
+func (w *worker) resumable(prev *environment, header *types.Header) bool {
+	if prev == nil || len(prev.steps) == 0 {
+		return false
+	}
+	return prev.header.ParentHash == header.ParentHash &&
+		prev.header.Number.Cmp(header.Number) == 0 &&
+		prev.header.GasLimit == header.GasLimit &&
+		prev.header.Coinbase == header.Coinbase &&
+		prev.header.Difficulty.Cmp(header.Difficulty) == 0
+}
+
+// replayTxSteps replays the selection steps of an earlier work on the transaction
+// sets, in order, reporting whether the sets select the very same transactions.
+// The state after the steps is then the state the earlier work ended with, as
+// the skipped transactions are skipped again for the same reasons. The sets are
+// left positioned after the replayed steps.
+func replayTxSteps(steps []txStep, sets []TxOrderer) bool {
+	for _, txs := range sets {
+		if txs == nil {
+			continue
+		}
+		for len(steps) > 0 {
+			tx := txs.Peek()
+			if tx == nil {
+				break
+			}
+			if tx.Hash() != steps[0].hash {
+				return false
+			}
+			if steps[0].pop {
+				txs.Pop()
+			} else {
+				txs.Shift()
+			}
+			steps = steps[1:]
+		}
+	}
+	return len(steps) == 0
+}
+
 // commit runs any post-transaction state modifications, assembles the final block
 // and commits new work if consensus engine is running.
 func (w *worker) commit(uncles []*types.Header, interval func(), update bool, start time.Time) error {
@@ -1034,17 +1381,14 @@ func (w *worker) commit(uncles []*types.Header, interval func(), update bool, st
 		if interval != nil {
 			interval()
 		}
+		feesWei := blockFees(block, receipts)
 		select {
-		case w.taskCh <- &task{receipts: receipts, state: s, block: block, createdAt: time.Now()}:
+		case w.taskCh <- &task{receipts: receipts, state: s, block: block, fees: feesWei, sealID: w.current.sealID, createdAt: time.Now(), prepare: w.current.prepare, packing: w.current.packing}:
 			w.unconfirmed.Shift(block.NumberU64() - 1)
 
-			feesWei := new(big.Int)
-			for i, tx := range block.Transactions() {
-				feesWei.Add(feesWei, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), tx.GasPrice()))
-			}
 			feesBer := new(big.Float).Quo(new(big.Float).SetInt(feesWei), new(big.Float).SetInt(big.NewInt(params.Ber)))
 
-			log.Info("Commit new mining work", "number", block.Number(), "sealhash", w.engine.SealHash(block.Header()),
+			log.Info("Commit new mining work", "seal", w.current.sealID, "number", block.Number(), "sealhash", w.engine.SealHash(block.Header()),
 				"uncles", len(uncles), "txs", w.current.tcount, "gas", block.GasUsed(), "fees", feesBer, "elapsed", common.PrettyDuration(time.Since(start)))
 
 		case <-w.exitCh:
@@ -1056,3 +1400,12 @@ func (w *worker) commit(uncles []*types.Header, interval func(), update bool, st
 	}
 	return nil
 }
+
+// blockFees returns the total transaction fees paid by the block, in wei.
+func blockFees(block *types.Block, receipts []*types.Receipt) *big.Int {
+	fees := new(big.Int)
+	for i, tx := range block.Transactions() {
+		fees.Add(fees, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), tx.GasPrice()))
+	}
+	return fees
+}
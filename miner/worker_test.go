@@ -0,0 +1,1024 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BerithFoundation/berith-chain/accounts"
+	"github.com/BerithFoundation/berith-chain/berith/staking"
+	"github.com/BerithFoundation/berith-chain/berithdb"
+	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/consensus/bsrr"
+	"github.com/BerithFoundation/berith-chain/core"
+	"github.com/BerithFoundation/berith-chain/core/state"
+	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/core/vm"
+	"github.com/BerithFoundation/berith-chain/crypto"
+	"github.com/BerithFoundation/berith-chain/event"
+	"github.com/BerithFoundation/berith-chain/log"
+	"github.com/BerithFoundation/berith-chain/metrics"
+	"github.com/BerithFoundation/berith-chain/params"
+)
+
+// Tests that the fees and transaction counts of sealed blocks accumulate into
+// the worker statistics.
+func TestWorkerStats(t *testing.T) {
+	w, pool, keys, closeTester := newSealingTester(t, 1)
+	defer closeTester()
+
+	if stats := w.Stats(); stats.Blocks != 0 || stats.Fees.Sign() != 0 || stats.AvgTxs() != 0 {
+		t.Fatalf("fresh worker has statistics: %+v", stats)
+	}
+	sub := w.mux.Subscribe(core.NewMinedBlockEvent{})
+	defer sub.Unsubscribe()
+
+	signer := types.NewEIP155Signer(w.chainConfig.ChainID)
+	nonce := uint64(0)
+	for _, prices := range [][]int64{{1, 2, 3}, {4}} {
+		for _, price := range prices {
+			tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{1}, big.NewInt(1), params.TxGas, big.NewInt(price), nil, types.Main, types.Main), signer, keys[0])
+			if err := pool.AddRemote(tx); err != nil {
+				t.Fatalf("failed to add transaction: %v", err)
+			}
+			nonce++
+		}
+		if block := sealBlock(t, w, sub); len(block.Transactions()) != len(prices) {
+			t.Fatalf("block %d: transaction count mismatch: have %d, want %d", block.NumberU64(), len(block.Transactions()), len(prices))
+		}
+	}
+	stats := w.Stats()
+	if stats.Blocks != 2 {
+		t.Errorf("block count mismatch: have %d, want %d", stats.Blocks, 2)
+	}
+	if stats.Txs != 4 {
+		t.Errorf("transaction count mismatch: have %d, want %d", stats.Txs, 4)
+	}
+	if want := big.NewInt(int64(params.TxGas) * (1 + 2 + 3 + 4)); stats.Fees.Cmp(want) != 0 {
+		t.Errorf("fees mismatch: have %v, want %v", stats.Fees, want)
+	}
+	if stats.AvgTxs() != 2 {
+		t.Errorf("average transactions mismatch: have %v, want %v", stats.AvgTxs(), 2)
+	}
+	// The returned fees must not alias the internal counter
+	stats.Fees.SetInt64(0)
+	if w.Stats().Fees.Sign() == 0 {
+		t.Errorf("statistics modified through returned fees")
+	}
+}
+
+// Tests that the blocks sealed by the worker include the pending transactions in
+// the order of the configured strategy.
+func TestWorkerTxOrdering(t *testing.T) {
+	for _, ordering := range []TxOrdering{TxOrderingPrice, TxOrderingFIFO, TxOrderingRoundRobin} {
+		testWorkerTxOrdering(t, ordering)
+	}
+}
+
+func testWorkerTxOrdering(t *testing.T, ordering TxOrdering) {
+	w, pool, keys, closeTester := newSealingTester(t, 3)
+	defer closeTester()
+
+	sub := w.mux.Subscribe(core.NewMinedBlockEvent{})
+	defer sub.Unsubscribe()
+
+	// The first account sends a cheap and an expensive transaction, the others
+	// one of a price in between, in the order they are submitted
+	var (
+		signer = types.NewEIP155Signer(w.chainConfig.ChainID)
+		txs    []*types.Transaction
+	)
+	for i, spec := range []struct {
+		key          int
+		nonce, price int64
+	}{{0, 0, 1}, {0, 1, 5}, {1, 0, 3}, {2, 0, 2}} {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(spec.nonce), common.Address{byte(i + 1)}, big.NewInt(1), params.TxGas, big.NewInt(spec.price), nil, types.Main, types.Main), signer, keys[spec.key])
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("%v: failed to add transaction %d: %v", ordering, i, err)
+		}
+		txs = append(txs, tx)
+		time.Sleep(time.Millisecond) // Tell the submissions apart
+	}
+	var want []*types.Transaction
+	switch ordering {
+	case TxOrderingPrice:
+		want = []*types.Transaction{txs[2], txs[3], txs[0], txs[1]}
+	case TxOrderingFIFO:
+		want = txs
+	case TxOrderingRoundRobin:
+		// One transaction per account in address order, then the rest
+		heads := []*types.Transaction{txs[0], txs[2], txs[3]}
+		sort.Slice(heads, func(i, j int) bool {
+			fromI, _ := types.Sender(signer, heads[i])
+			fromJ, _ := types.Sender(signer, heads[j])
+			return bytes.Compare(fromI[:], fromJ[:]) < 0
+		})
+		want = append(heads, txs[1])
+	}
+	w.setTxOrdering(ordering)
+
+	included := sealBlock(t, w, sub).Transactions()
+	if len(included) != len(want) {
+		t.Fatalf("%v: included transaction count mismatch: have %d, want %d", ordering, len(included), len(want))
+	}
+	for i, tx := range included {
+		if tx.Hash() != want[i].Hash() {
+			t.Errorf("%v: transaction %d: hash mismatch: have %x, want %x", ordering, i, tx.Hash(), want[i].Hash())
+		}
+	}
+}
+
+// fifoOrderer is a TxOrderer handing out transactions in submission order,
+// regardless of their gas price.
+type fifoOrderer struct {
+	signer types.Signer
+	txs    []*types.Transaction
+}
+
+func (o *fifoOrderer) Peek() *types.Transaction {
+	if len(o.txs) == 0 {
+		return nil
+	}
+	return o.txs[0]
+}
+
+func (o *fifoOrderer) Shift() { o.txs = o.txs[1:] }
+
+func (o *fifoOrderer) Pop() {
+	from, _ := types.Sender(o.signer, o.txs[0])
+	o.txs = o.txs[1:]
+	for i := 0; i < len(o.txs); i++ {
+		if sender, _ := types.Sender(o.signer, o.txs[i]); sender == from {
+			o.txs = append(o.txs[:i], o.txs[i+1:]...)
+			i--
+		}
+	}
+}
+
+// Tests that a custom transaction orderer overrides the ordering strategy of
+// the worker when filling blocks.
+func TestCustomTxOrderer(t *testing.T) {
+	var (
+		signer = types.NewEIP155Signer(params.TestnetChainConfig.ChainID)
+		keys   = make([]*ecdsa.PrivateKey, 3)
+		alloc  = make(core.GenesisAlloc)
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = core.GenesisAccount{Balance: big.NewInt(1e18)}
+	}
+	dir, err := ioutil.TempDir("", "miner-stakingdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stakingDB := new(staking.StakingDB)
+	if err := stakingDB.CreateDB(dir, staking.NewStakers); err != nil {
+		t.Fatal(err)
+	}
+	defer stakingDB.Close()
+
+	db := berithdb.NewMemDatabase()
+	genesis := (&core.Genesis{Config: params.TestnetChainConfig, GasLimit: 10000000, Alloc: alloc}).MustCommit(db)
+	engine := bsrr.NewCliqueWithStakingDB(stakingDB, params.TestnetChainConfig.Bsrr, db)
+	chain, err := core.NewBlockChain(stakingDB, db, nil, params.TestnetChainConfig, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	// Submit transactions with increasing gas prices, the price ordering would
+	// include them in reverse.
+	var (
+		submitted []*types.Transaction
+		pending   = make(map[common.Address]types.Transactions)
+	)
+	for i, key := range keys {
+		tx, err := types.SignTx(types.NewTransaction(0, common.Address{byte(i + 1)}, big.NewInt(1), params.TxGas, big.NewInt(int64(i+1)), nil, types.Main, types.Main), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		submitted = append(submitted, tx)
+		pending[crypto.PubkeyToAddress(key.PublicKey)] = types.Transactions{tx}
+	}
+	w := &worker{chainConfig: params.TestnetChainConfig, engine: engine, chain: chain}
+	w.setTxOrdering(TxOrderingPrice)
+	w.setTxOrderer(func(signer types.Signer, _ map[common.Address]types.Transactions) TxOrderer {
+		return &fifoOrderer{signer: signer, txs: append([]*types.Transaction{}, submitted...)}
+	})
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit(),
+		Time:       new(big.Int).Add(genesis.Time(), big.NewInt(1)),
+		Difficulty: big.NewInt(1),
+	}
+	if err := w.makeCurrent(genesis, header); err != nil {
+		t.Fatal(err)
+	}
+	w.commitTransactions(w.newTxIterator(w.current.signer, pending), common.Address{}, nil)
+
+	if len(w.current.txs) != len(submitted) {
+		t.Fatalf("included transaction count mismatch: have %d, want %d", len(w.current.txs), len(submitted))
+	}
+	for i, tx := range w.current.txs {
+		if tx.Hash() != submitted[i].Hash() {
+			t.Errorf("transaction %d: hash mismatch: have %x, want %x", i, tx.Hash(), submitted[i].Hash())
+		}
+	}
+	// Removing the custom orderer must restore the price ordering
+	w.setTxOrderer(nil)
+	if _, ok := w.newTxIterator(signer, pending).(*types.TransactionsByPriceAndNonce); !ok {
+		t.Errorf("ordering strategy not restored")
+	}
+}
+
+// Tests that the transactions left out of a block are recorded with the reason,
+// once for every reason, and that the oldest ones are evicted first.
+func TestRecentlyDropped(t *testing.T) {
+	var (
+		signer  = types.NewEIP155Signer(params.TestnetChainConfig.ChainID)
+		key, _  = crypto.GenerateKey()
+		from    = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = &core.Genesis{
+			Config:   params.TestnetChainConfig,
+			GasLimit: 10000000,
+			Alloc:    core.GenesisAlloc{from: {Balance: big.NewInt(1e18), Nonce: 1}},
+		}
+	)
+	chain, engine, closeNode := newTestNode(t, genesis)
+	defer closeNode()
+
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), params.TxGas, big.NewInt(1), nil, types.Main, types.Main), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &worker{chainConfig: genesis.Config, engine: engine, chain: chain, dropped: newDroppedTxs(2)}
+	w.setTxOrdering(TxOrderingPrice)
+	header := &types.Header{
+		ParentHash: chain.Genesis().Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit,
+		Time:       new(big.Int).Add(chain.Genesis().Time(), big.NewInt(1)),
+		Difficulty: big.NewInt(1),
+	}
+	// Recommitting the same work doesn't record the transaction again
+	for i := 0; i < 2; i++ {
+		if err := w.makeCurrent(chain.Genesis(), header); err != nil {
+			t.Fatal(err)
+		}
+		pending := map[common.Address]types.Transactions{from: {tx}}
+		w.commitTransactions(w.newTxIterator(w.current.signer, pending), common.Address{}, nil)
+	}
+	if len(w.current.txs) != 0 {
+		t.Fatalf("transaction with low nonce included")
+	}
+	want := DroppedTx{Hash: tx.Hash(), From: from, Reason: core.ErrNonceTooLow.Error()}
+	if dropped := w.recentlyDropped(); len(dropped) != 1 || dropped[0] != want {
+		t.Fatalf("dropped transactions mismatch: have %+v, want %+v", dropped, want)
+	}
+	// The oldest transactions are evicted beyond the limit
+	for i := byte(1); i <= 2; i++ {
+		w.dropped.add(DroppedTx{Hash: common.Hash{i}, Reason: "test"})
+	}
+	dropped := w.recentlyDropped()
+	if len(dropped) != 2 || dropped[0].Hash != (common.Hash{1}) || dropped[1].Hash != (common.Hash{2}) {
+		t.Errorf("dropped transactions after eviction mismatch: %+v", dropped)
+	}
+	// Nothing is recorded unless enabled
+	w.dropped = nil
+	w.commitTransactions(w.newTxIterator(w.current.signer, map[common.Address]types.Transactions{from: {tx}}), common.Address{}, nil)
+	if dropped := w.recentlyDropped(); dropped != nil {
+		t.Errorf("dropped transactions recorded while disabled: %+v", dropped)
+	}
+}
+
+// Tests that the side blocks announced by the chain to a worker which isn't
+// mining are kept up to the configured limit per author, and dropped once the
+// announced heads make them stale.
+func TestWorkerUncleSets(t *testing.T) {
+	const limit = 4
+
+	genesis := &core.Genesis{
+		Config:     &params.ChainConfig{ChainID: big.NewInt(1), Bsrr: &params.BSRRConfig{Period: 10, Epoch: 1000}},
+		GasLimit:   10000000,
+		ExtraData:  make([]byte, 32+common.AddressLength+65),
+		Difficulty: big.NewInt(1),
+	}
+	chain, engine, closeNode := newTestNode(t, genesis)
+	defer closeNode()
+
+	// The pool follows a chain of its own, the heads announced below being made up
+	poolChain, _, closePoolNode := newTestNode(t, genesis)
+	defer closePoolNode()
+
+	poolConfig := core.DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := core.NewTxPool(poolConfig, genesis.Config, poolChain)
+	defer pool.Stop()
+
+	isLocal := func(block *types.Block) bool { return block.Coinbase() == common.Address{1} }
+	w := newWorker(Config{Recommit: time.Second, GasFloor: genesis.GasLimit, GasCeil: genesis.GasLimit, MaxUncles: limit}, genesis.Config, engine, &testBackend{chain: chain, pool: pool}, new(event.TypeMux), isLocal)
+	defer w.close()
+
+	// waitUncles waits until the worker holds the given number of side blocks
+	waitUncles := func(count int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for w.localUncles.len()+w.remoteUncles.len() != count {
+			if time.Now().After(deadline) {
+				t.Fatalf("uncle count mismatch: have %d, want %d", w.localUncles.len()+w.remoteUncles.len(), count)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	var blocks []*types.Block
+	for i := 0; i < 3*limit; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i + 1)), Extra: []byte{byte(i)}}
+		if i%2 == 0 {
+			header.Coinbase = common.Address{1}
+		}
+		blocks = append(blocks, types.NewBlockWithHeader(header))
+	}
+	for _, block := range blocks {
+		// Announced twice, the duplicate being ignored
+		chain.PostChainEvents([]interface{}{core.ChainSideEvent{Block: block}, core.ChainSideEvent{Block: block}}, nil)
+	}
+	if w.isRunning() {
+		t.Fatalf("worker mining")
+	}
+	// The side blocks are handled in order, wait for the last one
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, exist := w.uncle(blocks[len(blocks)-1].Hash()); exist {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("side blocks not handled")
+		}
+	}
+
+	// Only the latest side blocks of each author are kept
+	for i, block := range blocks {
+		_, exist := w.uncle(block.Hash())
+		if want := i >= len(blocks)-2*limit; exist != want {
+			t.Errorf("block %d presence mismatch: have %v, want %v", block.NumberU64(), exist, want)
+		}
+	}
+	if w.localUncles.len() != limit || w.remoteUncles.len() != limit {
+		t.Errorf("uncle counts mismatch: have %d/%d, want %d", w.localUncles.len(), w.remoteUncles.len(), limit)
+	}
+	// New heads drop the side blocks too old to be included by the next block
+	head := uint64(len(blocks))
+	chain.PostChainEvents([]interface{}{core.ChainHeadEvent{Block: types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(head)})}}, nil)
+	waitUncles(staleThreshold - 1)
+
+	for _, block := range blocks {
+		if _, exist := w.uncle(block.Hash()); exist && block.NumberU64()+staleThreshold <= head+1 {
+			t.Errorf("stale block %d kept", block.NumberU64())
+		}
+	}
+	chain.PostChainEvents([]interface{}{core.ChainHeadEvent{Block: types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(head + staleThreshold)})}}, nil)
+	waitUncles(0)
+}
+
+// testBackend is a Backend of a chain and its transaction pool.
+type testBackend struct {
+	chain *core.BlockChain
+	pool  *core.TxPool
+}
+
+func (b *testBackend) BlockChain() *core.BlockChain { return b.chain }
+func (b *testBackend) TxPool() *core.TxPool         { return b.pool }
+
+// newTestNode creates a chain of the given genesis on its own databases, and
+// returns a function releasing it.
+func newTestNode(t testing.TB, genesis *core.Genesis) (*core.BlockChain, *bsrr.BSRR, func()) {
+	dir, err := ioutil.TempDir("", "miner-stakingdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stakingDB := new(staking.StakingDB)
+	if err := stakingDB.CreateDB(dir, staking.NewStakers); err != nil {
+		t.Fatal(err)
+	}
+	db := berithdb.NewMemDatabase()
+	genesis.MustCommit(db)
+	engine := bsrr.NewCliqueWithStakingDB(stakingDB, genesis.Config.Bsrr, db)
+	chain, err := core.NewBlockChain(stakingDB, db, nil, genesis.Config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chain, engine, func() {
+		chain.Stop()
+		stakingDB.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// newSealingTester creates a worker of the only signer of a chain without block
+// delays, sealing the work it is given through its task and result loops. It
+// returns the worker along with its transaction pool and the keys of the funded
+// accounts.
+func newSealingTester(t *testing.T, funded int) (*worker, *core.TxPool, []*ecdsa.PrivateKey, func()) {
+	var (
+		key, _ = crypto.GenerateKey()
+		signer = crypto.PubkeyToAddress(key.PublicKey)
+		keys   = make([]*ecdsa.PrivateKey, funded)
+		alloc  = make(core.GenesisAlloc)
+		extra  = make([]byte, 32+common.AddressLength+65)
+	)
+	copy(extra[32:], signer.Bytes())
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = core.GenesisAccount{Balance: big.NewInt(1e18)}
+	}
+	config := *params.TestnetChainConfig
+	config.Bsrr = &params.BSRRConfig{Period: 0, Epoch: 1000}
+	genesis := &core.Genesis{
+		Config:     &config,
+		GasLimit:   10000000,
+		ExtraData:  extra,
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}
+	chain, engine, closeNode := newTestNode(t, genesis)
+	engine.EnableSoloSealing()
+	engine.Authorize(signer, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	poolConfig := core.DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := core.NewTxPool(poolConfig, genesis.Config, chain)
+
+	w := &worker{
+		config:       Config{GasFloor: genesis.GasLimit, GasCeil: genesis.GasLimit, NoEmptyPrecommit: true},
+		chainConfig:  genesis.Config,
+		engine:       engine,
+		e:            &testBackend{chain: chain, pool: pool},
+		chain:        chain,
+		mux:          new(event.TypeMux),
+		coinbase:     signer,
+		localUncles:  newUncleSet(2, new(metrics.StandardGauge)),
+		remoteUncles: newUncleSet(2, new(metrics.StandardGauge)),
+		unconfirmed:  newUnconfirmedBlocks(chain, miningLogAtDepth),
+		pendingTasks: make(map[common.Hash]*task),
+		taskCh:       make(chan *task),
+		resultCh:     make(chan *types.Block, resultQueueSize),
+		exitCh:       make(chan struct{}),
+
+		resubmitAdjustCh: make(chan *intervalAdjust, resubmitAdjustChanSize),
+	}
+	w.setTxOrdering(TxOrderingPrice)
+	atomic.StoreInt32(&w.running, 1)
+	go w.taskLoop()
+	go w.resultLoop()
+
+	return w, pool, keys, func() {
+		close(w.exitCh)
+		pool.Stop()
+		closeNode()
+	}
+}
+
+// sealBlock commits new work on the worker and waits for the block the result
+// loop wrote, announced on the given subscription.
+func sealBlock(t *testing.T, w *worker, sub *event.TypeMuxSubscription) *types.Block {
+	w.commitNewWork(new(int32), false, time.Now().Unix())
+	select {
+	case ev := <-sub.Chan():
+		return ev.Data.(core.NewMinedBlockEvent).Block
+	case <-time.After(5 * time.Second):
+		t.Fatalf("block not sealed")
+	}
+	return nil
+}
+
+// Tests that the timings of the stages of a block are recorded both by the node
+// sealing it and by the node importing it.
+func TestBlockTimings(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		signer = crypto.PubkeyToAddress(key.PublicKey)
+		extra  = make([]byte, 32+common.AddressLength+65)
+	)
+	copy(extra[32:], signer.Bytes())
+	genesis := &core.Genesis{
+		Config:     params.TestnetChainConfig,
+		GasLimit:   10000000,
+		ExtraData:  extra,
+		Difficulty: big.NewInt(1),
+		Alloc:      core.GenesisAlloc{signer: {Balance: big.NewInt(1e18)}},
+	}
+	sealer, engine, closeSealer := newTestNode(t, genesis)
+	defer closeSealer()
+	importer, _, closeImporter := newTestNode(t, genesis)
+	defer closeImporter()
+
+	engine.Authorize(signer, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	config := core.DefaultTxPoolConfig
+	config.Journal = ""
+	pool := core.NewTxPool(config, genesis.Config, sealer)
+	defer pool.Stop()
+
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), params.TxGas, big.NewInt(1), nil, types.Main, types.Main), types.NewEIP155Signer(genesis.Config.ChainID), key)
+	if err := pool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	// Seal a block with the transaction on the first node
+	w := &worker{
+		config:       Config{GasFloor: genesis.GasLimit, GasCeil: genesis.GasLimit, NoEmptyPrecommit: true},
+		chainConfig:  genesis.Config,
+		engine:       engine,
+		e:            &testBackend{chain: sealer, pool: pool},
+		chain:        sealer,
+		mux:          new(event.TypeMux),
+		coinbase:     signer,
+		localUncles:  newUncleSet(2, new(metrics.StandardGauge)),
+		remoteUncles: newUncleSet(2, new(metrics.StandardGauge)),
+		unconfirmed:  newUnconfirmedBlocks(sealer, miningLogAtDepth),
+		pendingTasks: make(map[common.Hash]*task),
+		taskCh:       make(chan *task),
+		resultCh:     make(chan *types.Block, resultQueueSize),
+		exitCh:       make(chan struct{}),
+
+		resubmitAdjustCh: make(chan *intervalAdjust, resubmitAdjustChanSize),
+	}
+	defer close(w.exitCh)
+	w.setTxOrdering(TxOrderingPrice)
+	atomic.StoreInt32(&w.running, 1)
+	go w.taskLoop()
+	go w.resultLoop()
+
+	w.commitNewWork(new(int32), false, time.Now().Unix())
+	for deadline := time.Now().Add(5 * time.Second); sealer.CurrentBlock().NumberU64() == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("block not sealed")
+		}
+	}
+	block := sealer.CurrentBlock()
+	timings := sealer.BlockTimings(-1)
+	if len(timings) != 1 {
+		t.Fatalf("sealed timings mismatch: have %d, want 1", len(timings))
+	}
+	if have := timings[0]; !have.Mined || have.Hash != block.Hash() || have.Prepare == 0 || have.Packing == 0 || have.SealDelay == 0 || have.Write == 0 {
+		t.Errorf("sealed timing incomplete: %+v", have)
+	}
+	// Import the block on the second node, as announced before its body arrived
+	imported := types.NewBlockWithHeader(block.Header()).WithBody(block.Transactions(), block.Uncles())
+	imported.ReceivedAt = time.Now()
+	imported.AnnouncedAt = imported.ReceivedAt.Add(-250 * time.Millisecond)
+	if _, err := importer.InsertChain(types.Blocks{imported}); err != nil {
+		t.Fatalf("failed to import block: %v", err)
+	}
+	timings = importer.BlockTimings(-1)
+	if len(timings) != 1 {
+		t.Fatalf("imported timings mismatch: have %d, want 1", len(timings))
+	}
+	if have := timings[0]; have.Mined || have.Hash != block.Hash() || have.Verification == 0 || have.Execution == 0 || have.Write == 0 {
+		t.Errorf("imported timing incomplete: %+v", have)
+	}
+	if have := timings[0].Propagation; have != 250*time.Millisecond {
+		t.Errorf("propagation mismatch: have %v, want %v", have, 250*time.Millisecond)
+	}
+}
+
+// Tests that the blocks of a solo sealing chain are produced at the target block
+// interval, and that the interval can't be set on a chain of several signers.
+func TestTargetBlockInterval(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping wall clock soak test in short mode")
+	}
+	const (
+		interval = 20 * time.Millisecond
+		blocks   = 250
+	)
+	var (
+		key, _ = crypto.GenerateKey()
+		signer = crypto.PubkeyToAddress(key.PublicKey)
+	)
+	newGenesis := func(signers ...common.Address) *core.Genesis {
+		config := *params.TestnetChainConfig
+		config.Bsrr = &params.BSRRConfig{Period: 0, Epoch: 1000}
+
+		extra := make([]byte, 32, 32+len(signers)*common.AddressLength+65)
+		for _, s := range signers {
+			extra = append(extra, s.Bytes()...)
+		}
+		return &core.Genesis{
+			Config:     &config,
+			GasLimit:   10000000,
+			ExtraData:  append(extra, make([]byte, 65)...),
+			Difficulty: big.NewInt(1),
+			Alloc:      core.GenesisAlloc{signer: {Balance: big.NewInt(1e18)}},
+		}
+	}
+	newSoakWorker := func(genesis *core.Genesis) (*worker, *core.BlockChain, func()) {
+		chain, engine, closeNode := newTestNode(t, genesis)
+		engine.EnableSoloSealing()
+		engine.Authorize(signer, func(account accounts.Account, hash []byte) ([]byte, error) {
+			return crypto.Sign(hash, key)
+		})
+		config := core.DefaultTxPoolConfig
+		config.Journal = ""
+		pool := core.NewTxPool(config, genesis.Config, chain)
+
+		w := newWorker(Config{Recommit: time.Second, GasFloor: genesis.GasLimit, GasCeil: genesis.GasLimit}, genesis.Config, engine, &testBackend{chain: chain, pool: pool}, new(event.TypeMux), func(*types.Block) bool { return true })
+		w.setBerithbase(signer)
+		return w, chain, func() {
+			w.close()
+			pool.Stop()
+			closeNode()
+		}
+	}
+	// Several signers can't seal without delays
+	w, _, closeWorker := newSoakWorker(newGenesis(signer, common.Address{1}))
+	if err := w.setTargetBlockInterval(interval); err != errNoSoloSealing {
+		t.Errorf("multi-signer interval error mismatch: have %v, want %v", err, errNoSoloSealing)
+	}
+	closeWorker()
+
+	// The only signer seals at the target interval
+	w, chain, closeWorker := newSoakWorker(newGenesis(signer))
+	defer closeWorker()
+
+	if err := w.setTargetBlockInterval(interval); err != nil {
+		t.Fatalf("failed to set target block interval: %v", err)
+	}
+	heads := make(chan core.ChainHeadEvent, blocks)
+	sub := chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	w.start()
+
+	// Skip the first blocks, sealed while the worker warms up
+	var start time.Time
+	for i := 0; i < blocks+10; i++ {
+		select {
+		case head := <-heads:
+			if i == 10 {
+				start = time.Now()
+			}
+			if head.Block.NumberU64() != uint64(i+1) {
+				t.Fatalf("head number mismatch: have %d, want %d", head.Block.NumberU64(), i+1)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("block %d not sealed", i+1)
+		}
+	}
+	// The blocks can't come faster than the target, but a loaded machine may
+	// well delay them, so only a gross slowdown is reported
+	elapsed, want := time.Since(start), blocks*interval
+	if elapsed < want*9/10 || elapsed > want*2 {
+		t.Errorf("block interval mismatch: have %v, want %v", elapsed/blocks, interval)
+	}
+	w.stop()
+
+	// The sealed blocks are valid on the chain's own config
+	var sealed types.Blocks
+	for n := uint64(1); n <= blocks; n++ {
+		sealed = append(sealed, chain.GetBlockByNumber(n))
+	}
+	importer, _, closeImporter := newTestNode(t, newGenesis(signer))
+	defer closeImporter()
+	if _, err := importer.InsertChain(sealed); err != nil {
+		t.Errorf("failed to import sealed blocks: %v", err)
+	}
+}
+
+// newRecommitTester creates a chain of a single signer, whose blocks are due an
+// hour after their parent so that the works of the same parent share their
+// header. It returns a constructor of idle workers filling blocks by gas price
+// on the chain, along with its transaction pool and the keys of the funded
+// accounts.
+func newRecommitTester(t testing.TB, funded int, gasLimit uint64) (func() *worker, *core.TxPool, []*ecdsa.PrivateKey, func()) {
+	var (
+		key, _ = crypto.GenerateKey()
+		signer = crypto.PubkeyToAddress(key.PublicKey)
+		keys   = make([]*ecdsa.PrivateKey, funded)
+		alloc  = make(core.GenesisAlloc)
+		extra  = make([]byte, 32+common.AddressLength+65)
+	)
+	copy(extra[32:], signer.Bytes())
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = core.GenesisAccount{Balance: big.NewInt(1e18)}
+	}
+	config := *params.TestnetChainConfig
+	config.Bsrr = &params.BSRRConfig{Period: 3600, Epoch: 1000}
+
+	genesis := &core.Genesis{
+		Config:     &config,
+		Timestamp:  uint64(time.Now().Unix()) - 1,
+		GasLimit:   gasLimit,
+		ExtraData:  extra,
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}
+	chain, engine, closeNode := newTestNode(t, genesis)
+	engine.Authorize(signer, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	poolConfig := core.DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	poolConfig.GlobalSlots = 8192
+	pool := core.NewTxPool(poolConfig, genesis.Config, chain)
+
+	// Drain the interval adjustments of the works, which no loop consumes
+	var (
+		adjustCh = make(chan *intervalAdjust, resubmitAdjustChanSize)
+		quit     = make(chan struct{})
+	)
+	go func() {
+		for {
+			select {
+			case <-adjustCh:
+			case <-quit:
+				return
+			}
+		}
+	}()
+	newWorker := func() *worker {
+		w := &worker{
+			config:       Config{GasFloor: gasLimit, GasCeil: gasLimit, NoEmptyPrecommit: true},
+			chainConfig:  genesis.Config,
+			engine:       engine,
+			e:            &testBackend{chain: chain, pool: pool},
+			chain:        chain,
+			mux:          new(event.TypeMux),
+			localUncles:  newUncleSet(2, new(metrics.StandardGauge)),
+			remoteUncles: newUncleSet(2, new(metrics.StandardGauge)),
+
+			resubmitAdjustCh: adjustCh,
+		}
+		w.setTxOrdering(TxOrderingPrice)
+		return w
+	}
+	return newWorker, pool, keys, func() {
+		close(quit)
+		pool.Stop()
+		closeNode()
+	}
+}
+
+// Tests that recommitting the work of the same parent continues from the
+// transactions executed by the previous work while the selection starts with
+// them, and that the blocks and states are the same as those of works built
+// from scratch across the recommits.
+func TestRecommitResume(t *testing.T) {
+	newWorker, pool, keys, closeTester := newRecommitTester(t, 4, 200000)
+	defer closeTester()
+
+	var (
+		w      = newWorker()
+		full   = newWorker() // Worker rebuilding every work from scratch
+		signer = types.NewEIP155Signer(w.chainConfig.ChainID)
+		nonces = make([]uint64, len(keys))
+	)
+	newTx := func(account int, price int64, gas uint64) *types.Transaction {
+		tx, err := types.SignTx(types.NewTransaction(nonces[account], common.Address{0xff}, big.NewInt(1), gas, big.NewInt(price), nil, types.Main, types.Main), signer, keys[account])
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonces[account]++
+		return tx
+	}
+	type remoteTx struct {
+		account int
+		price   int64
+		gas     uint64
+	}
+	cycles := []struct {
+		local   []int64 // Gas prices of the local transactions of the first account
+		remotes []remoteTx
+		floor   uint64 // Gas floor and ceiling of the work, unchanged if zero
+		period  uint64 // Block period moving the time of the work, unchanged if zero
+		resume  bool
+		txs     int
+	}{
+		// Work of the head, nothing to resume
+		{local: []int64{100}, remotes: []remoteTx{{1, 90, params.TxGas}, {1, 90, params.TxGas}}, txs: 3},
+		// Cheaper transactions arrived, executed after the previous ones
+		{remotes: []remoteTx{{2, 80, params.TxGas}}, resume: true, txs: 4},
+		// A pricier transaction takes precedence, so the work is rebuilt
+		{remotes: []remoteTx{{3, 200, params.TxGas}}, txs: 5},
+		// A transaction exceeding the gas left is skipped, the next one executed
+		{remotes: []remoteTx{{3, 60, 100000}, {2, 50, params.TxGas}}, resume: true, txs: 6},
+		// A local transaction goes before the remote ones, so the work is rebuilt
+		{local: []int64{40}, txs: 7},
+		// Nothing new, the same transactions are selected again
+		{resume: true, txs: 7},
+		// A later time, the work keeps the time of the previous one
+		{period: 3601, resume: true, txs: 7},
+		// The skipped transaction is skipped again, along with the next of its account
+		{remotes: []remoteTx{{3, 35, params.TxGas}, {1, 30, params.TxGas}}, resume: true, txs: 8},
+		// A new gas limit, so the work is rebuilt
+		{floor: 300000, txs: 8},
+	}
+	for i, cycle := range cycles {
+		for _, price := range cycle.local {
+			if err := pool.AddLocal(newTx(0, price, params.TxGas)); err != nil {
+				t.Fatalf("cycle %d: failed to add local transaction: %v", i, err)
+			}
+		}
+		for _, remote := range cycle.remotes {
+			if err := pool.AddRemote(newTx(remote.account, remote.price, remote.gas)); err != nil {
+				t.Fatalf("cycle %d: failed to add remote transaction: %v", i, err)
+			}
+		}
+		if cycle.floor != 0 {
+			w.config.GasFloor, w.config.GasCeil = cycle.floor, cycle.floor
+			full.config = w.config
+		}
+		period := w.chainConfig.Bsrr.Period
+		if cycle.period != 0 {
+			w.chainConfig.Bsrr.Period = cycle.period
+		}
+		var (
+			prev     *state.StateDB
+			prevTime *big.Int
+		)
+		if w.current != nil {
+			prev, prevTime = w.current.state, w.current.header.Time
+		}
+		w.commitNewWork(new(int32), true, time.Now().Unix())
+
+		full.current = nil
+		full.commitNewWork(new(int32), true, time.Now().Unix())
+		w.chainConfig.Bsrr.Period = period
+
+		if resumed := w.current.state == prev; resumed != cycle.resume {
+			t.Fatalf("cycle %d: resumption mismatch: have %v, want %v", i, resumed, cycle.resume)
+		}
+		if cycle.resume && w.current.header.Time.Cmp(prevTime) != 0 {
+			t.Errorf("cycle %d: time mismatch: have %v, want %v", i, w.current.header.Time, prevTime)
+		}
+		if cycle.period != 0 && full.current.header.Time.Cmp(prevTime) <= 0 {
+			t.Errorf("cycle %d: time not moved: have %v, previous %v", i, full.current.header.Time, prevTime)
+		}
+		have, want := w.current, full.current
+		if len(have.txs) != cycle.txs {
+			t.Fatalf("cycle %d: transaction count mismatch: have %d, want %d", i, len(have.txs), cycle.txs)
+		}
+		if len(have.txs) != len(want.txs) || have.tcount != want.tcount {
+			t.Fatalf("cycle %d: transaction count mismatch: have %d (%d), want %d (%d)", i, len(have.txs), have.tcount, len(want.txs), want.tcount)
+		}
+		for j := range have.txs {
+			if have.txs[j].Hash() != want.txs[j].Hash() {
+				t.Errorf("cycle %d: transaction %d mismatch: have %x, want %x", i, j, have.txs[j].Hash(), want.txs[j].Hash())
+			}
+		}
+		if hash, wantHash := types.DeriveSha(types.Receipts(have.receipts)), types.DeriveSha(types.Receipts(want.receipts)); hash != wantHash {
+			t.Errorf("cycle %d: receipts mismatch: have %x, want %x", i, hash, wantHash)
+		}
+		if have.header.GasUsed != want.header.GasUsed || have.gasPool.Gas() != want.gasPool.Gas() {
+			t.Errorf("cycle %d: gas mismatch: have %d used, %d left, want %d used, %d left", i, have.header.GasUsed, have.gasPool.Gas(), want.header.GasUsed, want.gasPool.Gas())
+		}
+		if root, wantRoot := have.state.Copy().IntermediateRoot(true), want.state.Copy().IntermediateRoot(true); root != wantRoot {
+			t.Errorf("cycle %d: state root mismatch: have %x, want %x", i, root, wantRoot)
+		}
+		if block, wantBlock := w.pendingBlock(), full.pendingBlock(); block.Header().TxHash != wantBlock.Header().TxHash || block.Header().ReceiptHash != wantBlock.Header().ReceiptHash {
+			t.Errorf("cycle %d: pending block mismatch", i)
+		}
+	}
+}
+
+// Benchmarks recommitting the work of the same parent with 3000 pending
+// transactions, from scratch and continuing from the previous work.
+func BenchmarkRecommit(b *testing.B) {
+	const (
+		accounts = 300
+		txs      = 10 // Transactions per account
+	)
+	newWorker, pool, keys, closeTester := newRecommitTester(b, accounts, 100000000)
+	defer closeTester()
+
+	var (
+		w      = newWorker()
+		signer = types.NewEIP155Signer(w.chainConfig.ChainID)
+	)
+	for i, key := range keys {
+		var batch []*types.Transaction
+		for nonce := uint64(0); nonce < txs; nonce++ {
+			tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{0xff}, big.NewInt(1), params.TxGas, big.NewInt(int64(i+1)), nil, types.Main, types.Main), signer, key)
+			if err != nil {
+				b.Fatal(err)
+			}
+			batch = append(batch, tx)
+		}
+		for _, err := range pool.AddRemotes(batch) {
+			if err != nil {
+				b.Fatalf("failed to add transaction: %v", err)
+			}
+		}
+	}
+	for _, resume := range []bool{false, true} {
+		name := "full"
+		if resume {
+			name = "resume"
+		}
+		b.Run(name, func(b *testing.B) {
+			w.current = nil
+			w.commitNewWork(new(int32), true, time.Now().Unix())
+			if len(w.current.txs) != accounts*txs {
+				b.Fatalf("transaction count mismatch: have %d, want %d", len(w.current.txs), accounts*txs)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !resume {
+					w.current = nil
+				}
+				w.commitNewWork(new(int32), true, time.Now().Unix())
+			}
+		})
+	}
+}
+
+// Tests that a gas floor far above the gas limit of the parent moves the limit
+// of the work only by the adjustment allowed per block, and that the limits of
+// CalcGasLimit beyond it, with a divisor stricter than the protocol's or with a
+// parent limit below the divisor, are clamped and reported.
+func TestGasLimitDrift(t *testing.T) {
+	tests := []struct {
+		parent, divisor, want uint64
+		warned                bool
+	}{
+		{200000, 0, 200000 + 200000/params.GasLimitBoundDivisor - 1, false},
+		{200000, 2048, 200000 + 200000/2048 - 1, true},
+		{1000, 0, 1000, true},
+	}
+	for i, tt := range tests {
+		newWorker, _, _, closeTester := newRecommitTester(t, 0, tt.parent)
+
+		var warned int32
+		log.Root().SetHandler(log.FuncHandler(func(r *log.Record) error {
+			if r.Lvl == log.LvlWarn && strings.HasPrefix(r.Msg, "Gas limit adjustment out of bounds") {
+				atomic.StoreInt32(&warned, 1)
+			}
+			return nil
+		}))
+		w := newWorker()
+		w.config.GasFloor, w.config.GasCeil = 1000000000, 1000000000
+		w.config.GasLimitDivisor = tt.divisor
+		w.commitNewWork(new(int32), true, time.Now().Unix())
+
+		if have := w.current.header.GasLimit; have != tt.want {
+			t.Errorf("test %d: gas limit mismatch: have %d, want %d", i, have, tt.want)
+		}
+		if have := atomic.LoadInt32(&warned) == 1; have != tt.warned {
+			t.Errorf("test %d: warning mismatch: have %v, want %v", i, have, tt.warned)
+		}
+		log.Root().SetHandler(log.DiscardHandler())
+		closeTester()
+	}
+}
+
+// Tests that the gas limits beyond the adjustment allowed per block are clamped
+// to it and reported.
+func TestBoundGasLimit(t *testing.T) {
+	tests := []struct {
+		parent, limit, divisor, want uint64
+		fail                         bool
+	}{
+		{200000, 200000, 0, 200000, false},
+		{200000, 200194, 0, 200194, false},
+		{200000, 199806, 0, 199806, false},
+		{200000, 200195, 0, 200194, true},
+		{200000, 1000000, 0, 200194, true},
+		{200000, 199805, 0, 199806, true},
+		{200000, params.MinGasLimit, 0, 199806, true},
+		{1000, 1001, 0, 1000, true},
+		{200000, 200194, 2048, 200096, true},
+		{200000, 199904, 2048, 199904, false},
+	}
+	for i, tt := range tests {
+		limit, err := boundGasLimit(tt.parent, tt.limit, tt.divisor)
+		if limit != tt.want || (err != nil) != tt.fail {
+			t.Errorf("test %d: have %d (%v), want %d (failure %v)", i, limit, err, tt.want, tt.fail)
+		}
+	}
+}
@@ -75,7 +75,78 @@ func (api *PrivateAdminAPI) RemovePeer(url string) (bool, error) {
 	return true, nil
 }
 
-// AddTrustedPeer allows a remote node to always connect, even if slots are full
+// AddStaticPeer adds a remote node to the static peers, which are connected to
+// at all times, and persists it to the static node list of the data directory
+// so that it is kept across restarts.
+func (api *PrivateAdminAPI) AddStaticPeer(url string) (*PeerChange, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	node, err := enode.ParseV4(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid enode: %v", err)
+	}
+	if node.Incomplete() || node.TCP() == 0 {
+		return nil, fmt.Errorf("invalid enode: static peers need an IP address and a TCP port")
+	}
+	persisted, err := api.node.persistNode(datadirStaticNodes, node, true)
+	if err != nil {
+		return nil, err
+	}
+	// Peers already connected to are not dialed again
+	dialing := true
+	for _, peer := range server.Peers() {
+		if peer.ID() == node.ID() {
+			dialing = false
+			break
+		}
+	}
+	server.AddPeer(node)
+	return &PeerChange{Persisted: persisted, Dialing: dialing}, nil
+}
+
+// RemoveStaticPeer removes a remote node from the static peers, disconnecting
+// it, and from the static node list of the data directory.
+func (api *PrivateAdminAPI) RemoveStaticPeer(url string) (*PeerChange, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	node, err := enode.ParseV4(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid enode: %v", err)
+	}
+	persisted, err := api.node.persistNode(datadirStaticNodes, node, false)
+	if err != nil {
+		return nil, err
+	}
+	server.RemovePeer(node)
+	return &PeerChange{Persisted: persisted}, nil
+}
+
+// StaticPeers returns the static node list of the data directory, the static
+// peers connected to after a restart.
+func (api *PrivateAdminAPI) StaticPeers() ([]string, error) {
+	path := api.node.config.ResolvePath(datadirStaticNodes)
+	if path == "" {
+		return []string{}, nil
+	}
+	api.node.nodeListLock.Lock()
+	defer api.node.nodeListLock.Unlock()
+
+	list, err := loadNodeList(path)
+	if list == nil {
+		list = []string{}
+	}
+	return list, err
+}
+
+// AddTrustedPeer allows a remote node to always connect, even if slots are full,
+// and persists it to the trusted node list of the data directory. Trusted peers
+// are not dialed.
 func (api *PrivateAdminAPI) AddTrustedPeer(url string) (bool, error) {
 	// Make sure the server is running, fail otherwise
 	server := api.node.Server()
@@ -86,12 +157,16 @@ func (api *PrivateAdminAPI) AddTrustedPeer(url string) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("invalid enode: %v", err)
 	}
+	if _, err := api.node.persistNode(datadirTrustedNodes, node, true); err != nil {
+		return false, err
+	}
 	server.AddTrustedPeer(node)
 	return true, nil
 }
 
-// RemoveTrustedPeer removes a remote node from the trusted peer set, but it
-// does not disconnect it automatically.
+// RemoveTrustedPeer removes a remote node from the trusted peer set and from the
+// trusted node list of the data directory, but it does not disconnect it
+// automatically.
 func (api *PrivateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	// Make sure the server is running, fail otherwise
 	server := api.node.Server()
@@ -102,6 +177,9 @@ func (api *PrivateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("invalid enode: %v", err)
 	}
+	if _, err := api.node.persistNode(datadirTrustedNodes, node, false); err != nil {
+		return false, err
+	}
 	server.RemoveTrustedPeer(node)
 	return true, nil
 }
@@ -73,6 +73,8 @@ type Node struct {
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
 
+	nodeListLock sync.Mutex // Serializes the changes to the static and trusted node lists
+
 	log log.Logger
 }
 
@@ -0,0 +1,108 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/BerithFoundation/berith-chain/p2p/enode"
+)
+
+// PeerChange is the outcome of adding or removing a static peer.
+type PeerChange struct {
+	Persisted bool `json:"persisted"` // Whether the change was stored in the data directory
+	Dialing   bool `json:"dialing"`   // Whether a connection to the peer was initiated
+}
+
+// loadNodeList reads the enode URLs of a node list file in the format of
+// static-nodes.json, returning nil if the file doesn't exist.
+func loadNodeList(path string) ([]string, error) {
+	blob, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	if err := json.Unmarshal(blob, &list); err != nil {
+		return nil, fmt.Errorf("invalid node list %s: %v", path, err)
+	}
+	return list, nil
+}
+
+// saveNodeList replaces the node list file with the given enode URLs at once,
+// so that a crash never leaves it half written.
+func saveNodeList(path string, list []string) error {
+	if list == nil {
+		list = []string{}
+	}
+	blob, err := json.MarshalIndent(list, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// updateNodeList adds the node to the node list file or removes it from there.
+// Entries of the same node ID are replaced, entries that can't be parsed are
+// left untouched. It returns whether the file changed.
+func updateNodeList(path string, node *enode.Node, add bool) (bool, error) {
+	list, err := loadNodeList(path)
+	if err != nil {
+		return false, err
+	}
+	var (
+		url     = node.String()
+		updated = make([]string, 0, len(list)+1)
+		found   bool
+		changed bool
+	)
+	for _, entry := range list {
+		if n, err := enode.ParseV4(entry); err == nil && n.ID() == node.ID() {
+			// Keep the first entry of an added node if it is up to date
+			if add && !found && entry == url {
+				updated = append(updated, entry)
+				found = true
+			} else {
+				changed = true
+			}
+			continue
+		}
+		updated = append(updated, entry)
+	}
+	if add && !found {
+		updated = append(updated, url)
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, saveNodeList(path, updated)
+}
+
+// persistNode adds the node to the node list file of the data directory or
+// removes it from there. It returns whether the list is persisted, which it is
+// not without a data directory.
+func (n *Node) persistNode(file string, node *enode.Node, add bool) (bool, error) {
+	path := n.config.ResolvePath(file)
+	if path == "" {
+		return false, nil
+	}
+	n.nodeListLock.Lock()
+	defer n.nodeListLock.Unlock()
+
+	if _, err := updateNodeList(path, node, add); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,133 @@
+package node
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/BerithFoundation/berith-chain/crypto"
+	"github.com/BerithFoundation/berith-chain/p2p/enode"
+)
+
+// newTestNodes creates n nodes listening on consecutive local ports.
+func newTestNodes(t *testing.T, n int) []*enode.Node {
+	nodes := make([]*enode.Node, n)
+	for i := range nodes {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate node key: %v", err)
+		}
+		nodes[i] = enode.NewV4(&key.PublicKey, net.IP{127, 0, 0, 1}, 30303+i, 30303+i)
+	}
+	return nodes
+}
+
+// Tests that changes to the node lists are persisted in the format read back
+// on startup, replacing entries of the same node and keeping unparsable ones.
+func TestNodeListPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-list-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	n := &Node{config: &Config{Name: "test", DataDir: dir}}
+	path := n.config.ResolvePath(datadirStaticNodes)
+	if err := os.MkdirAll(n.config.instanceDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(`["bogus"]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	nodes := newTestNodes(t, 2)
+	moved := enode.NewV4(nodes[0].Pubkey(), net.IP{127, 0, 0, 2}, 30303, 30303)
+
+	for _, change := range []struct {
+		node *enode.Node
+		add  bool
+	}{
+		{nodes[0], true},
+		{nodes[1], true},
+		{nodes[0], true},
+		{moved, true},
+		{nodes[1], false},
+	} {
+		if persisted, err := n.persistNode(datadirStaticNodes, change.node, change.add); err != nil || !persisted {
+			t.Fatalf("failed to persist node: persisted %v, error %v", persisted, err)
+		}
+	}
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read node list: %v", err)
+	}
+	var list []string
+	if err := json.Unmarshal(blob, &list); err != nil {
+		t.Fatalf("node list is not a JSON array: %v", err)
+	}
+	if len(list) != 2 || list[0] != "bogus" || list[1] != moved.String() {
+		t.Errorf("node list mismatch: have %v, want [bogus %s]", list, moved)
+	}
+	if static := n.config.StaticNodes(); len(static) != 1 || static[0].String() != moved.String() {
+		t.Errorf("static nodes mismatch: have %v, want [%s]", static, moved)
+	}
+	if trusted := n.config.TrustedNodes(); len(trusted) != 0 {
+		t.Errorf("trusted nodes mismatch: have %v, want none", trusted)
+	}
+	// Without a data directory nothing is persisted
+	n = &Node{config: &Config{Name: "test"}}
+	if persisted, err := n.persistNode(datadirStaticNodes, nodes[0], true); err != nil || persisted {
+		t.Errorf("persisted without data directory: persisted %v, error %v", persisted, err)
+	}
+}
+
+// Tests that concurrent additions and removals don't overwrite each other.
+func TestNodeListConcurrentChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-list-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	n := &Node{config: &Config{Name: "test", DataDir: dir}}
+	nodes := newTestNodes(t, 20)
+	for _, node := range nodes[:10] {
+		if _, err := n.persistNode(datadirTrustedNodes, node, true); err != nil {
+			t.Fatalf("failed to persist node: %v", err)
+		}
+	}
+	// Remove the first half of the nodes while adding the second half
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(node *enode.Node, add bool) {
+			defer wg.Done()
+			if _, err := n.persistNode(datadirTrustedNodes, node, add); err != nil {
+				t.Errorf("failed to persist node: %v", err)
+			}
+		}(node, i >= 10)
+	}
+	wg.Wait()
+
+	list, err := loadNodeList(n.config.ResolvePath(datadirTrustedNodes))
+	if err != nil {
+		t.Fatalf("failed to load node list: %v", err)
+	}
+	want := make([]string, 0, 10)
+	for _, node := range nodes[10:] {
+		want = append(want, node.String())
+	}
+	sort.Strings(list)
+	sort.Strings(want)
+	if len(list) != len(want) {
+		t.Fatalf("node count mismatch: have %d, want %d", len(list), len(want))
+	}
+	for i := range want {
+		if list[i] != want[i] {
+			t.Errorf("node %d mismatch: have %s, want %s", i, list[i], want[i])
+		}
+	}
+}
@@ -117,3 +117,9 @@ func sntpDrift(measurements int) (time.Duration, error) {
 	}
 	return drift / time.Duration(measurements), nil
 }
+
+// ClockDrift measures the drift of the local clock against an NTP server, how
+// far it is ahead of the server time, negative if it is behind.
+func ClockDrift() (time.Duration, error) {
+	return sntpDrift(ntpChecks)
+}
@@ -149,17 +149,60 @@ func (ExtIP) DeleteMapping(string, int, int) error                     { return
 func Any() Interface {
 	// TODO: attempt to discover whether the local machine has an
 	// Internet-class address. Return ExtIP in this case.
-	return startautodisc("UPnP or NAT-PMP", func() Interface {
-		found := make(chan Interface, 2)
-		go func() { found <- discoverUPnP() }()
-		go func() { found <- discoverPMP() }()
-		for i := 0; i < cap(found); i++ {
-			if c := <-found; c != nil {
-				return c
+	return startautodisc("UPnP or NAT-PMP", discoverAny(
+		mechanism{"UPnP", discoverUPnP},
+		mechanism{"NAT-PMP", discoverPMP},
+	))
+}
+
+// mechanism is a port mapping mechanism which can be discovered by Any.
+type mechanism struct {
+	name     string
+	discover func() Interface
+}
+
+// discoverAny returns a function discovering the given mechanisms concurrently,
+// which returns the first one discovered. Its String reports the mechanisms
+// tried alongside it, so that the logs show how the discovery went.
+func discoverAny(mechanisms ...mechanism) func() Interface {
+	return func() Interface {
+		type result struct {
+			name  string
+			found Interface
+		}
+		results := make(chan result, len(mechanisms))
+		for _, m := range mechanisms {
+			go func(m mechanism) { results <- result{m.name, m.discover()} }(m)
+		}
+		for range mechanisms {
+			r := <-results
+			if r.found == nil {
+				continue
+			}
+			var tried []string
+			for _, m := range mechanisms {
+				if m.name != r.name {
+					tried = append(tried, m.name)
+				}
 			}
+			if len(tried) == 0 {
+				return r.found
+			}
+			return &discovered{Interface: r.found, tried: tried}
 		}
 		return nil
-	})
+	}
+}
+
+// discovered is a mechanism found by Any, along with the names of the other
+// mechanisms tried during the discovery.
+type discovered struct {
+	Interface
+	tried []string
+}
+
+func (n *discovered) String() string {
+	return fmt.Sprintf("%v (tried %s)", n.Interface, strings.Join(n.tried, ", "))
 }
 
 // UPnP returns a port mapper that uses UPnP. It will attempt to
@@ -61,3 +61,30 @@ func TestAutoDiscRace(t *testing.T) {
 		}
 	}
 }
+
+// This test checks that the mechanism found by Any reports the other
+// mechanisms tried during the discovery.
+func TestDiscoverAnyString(t *testing.T) {
+	ad := startautodisc("UPnP or NAT-PMP", discoverAny(
+		mechanism{"UPnP", func() Interface { return &upnp{service: "IGDv1-IP1"} }},
+		mechanism{"NAT-PMP", func() Interface { return nil }},
+	))
+	if have, want := ad.String(), "UPnP or NAT-PMP"; have != want {
+		t.Errorf("string before discovery mismatch: got %q, want %q", have, want)
+	}
+	if err := ad.(*autodisc).wait(); err != nil {
+		t.Fatalf("discovery failed: %v", err)
+	}
+	if have, want := ad.String(), "UPNP IGDv1-IP1 (tried NAT-PMP)"; have != want {
+		t.Errorf("string after discovery mismatch: got %q, want %q", have, want)
+	}
+
+	// Without any mechanism discovered, all of them are reported.
+	ad = startautodisc("UPnP or NAT-PMP", discoverAny(
+		mechanism{"UPnP", func() Interface { return nil }},
+		mechanism{"NAT-PMP", func() Interface { return nil }},
+	))
+	if _, err := ad.ExternalIP(); err == nil || err.Error() != "no UPnP or NAT-PMP router discovered" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -17,10 +17,15 @@
 package params
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"math/big"
+	"sort"
 
 	"github.com/BerithFoundation/berith-chain/common"
+	"github.com/BerithFoundation/berith-chain/crypto/sha3"
+	"github.com/BerithFoundation/berith-chain/rlp"
 )
 
 const (
@@ -141,6 +146,7 @@ type ChainConfig struct {
 	BIP2Block *big.Int    `json:"bip2Block,omitempty"`
 	BIP3Block *big.Int    `json:"bip3Block,omitempty"`
 	BIP4Block *big.Int    `json:"bip4Block,omitempty"`
+	BIP5Block *big.Int    `json:"bip5Block,omitempty"` // Epoch boundary selection point recalculation (nil = no fork)
 }
 
 type BSRRConfig struct {
@@ -151,12 +157,177 @@ type BSRRConfig struct {
 	LimitStakeBalance *big.Int `json:"limitStakeBalance"` // Limit of stake in WEI
 	SlashRound        uint64   `json:"slashRound"`        // Reward after block proceed
 	ForkFactor        float64  `json:"forkfactor"`        // Number of mining candidates given stake holders
+	FinalityHorizon   uint64   `json:"finalityHorizon"`   // Maximum reorg depth below the local head in blocks (0 = unlimited)
+	FutureBlockDrift  uint64   `json:"futureBlockDrift"`  // Seconds a block may be ahead of the local clock (0 = default)
+	CommitEvery       uint64   `json:"commitEvery"`       // Interval in blocks of forced staking list commits (0 = on cache misses only)
+	MaxReorgDepth     uint64   `json:"maxReorgDepth"`     // Maximum number of blocks replayed to rebuild a staking list (0 = unlimited)
+	MaxCandidates     uint64   `json:"maxCandidates"`     // Maximum number of candidates ranked by an election (0 = default)
 }
 
 func (b *BSRRConfig) String() string {
 	return "bsrr"
 }
 
+var (
+	// ErrConsensusRemoteStale is returned by CheckConsensusID if a remote node
+	// syncing the chain doesn't know a fork the local node passed already.
+	ErrConsensusRemoteStale = errors.New("remote doesn't know a passed fork")
+
+	// ErrConsensusLocalIncompatible is returned by CheckConsensusID if the chain
+	// parameters or the passed forks differ, or the local node passed a fork the
+	// remote one schedules.
+	ErrConsensusLocalIncompatible = errors.New("local incompatible or stale")
+)
+
+// ConsensusID identifies the consensus rules of a node at its head, in the way
+// of the fork identifiers of EIP-2124. The forks scheduled past the head are
+// left out of the digest, so that the nodes differing only by them agree on
+// the chain up to there.
+type ConsensusID struct {
+	Digest common.Hash // Hash of the chain parameters and the forks passed
+	Next   uint64      // Block number of the next scheduled fork, 0 if none
+}
+
+// consensusFork is the canonical encoding of a fork passed, the index telling
+// apart the forks scheduled at the same block.
+type consensusFork struct {
+	Index  uint64
+	Number uint64
+}
+
+// consensusParams is the canonical encoding of the chain parameters affecting
+// the validity of the blocks.
+type consensusParams struct {
+	ChainID           *big.Int
+	Forks             []consensusFork
+	DAOForkSupport    bool
+	Period            uint64
+	Epoch             uint64
+	Rewards           *big.Int
+	StakeMinimum      *big.Int
+	LimitStakeBalance *big.Int
+	SlashRound        uint64
+	ForkFactor        uint64 // IEEE 754 bits of the factor
+	MaxCandidates     uint64
+}
+
+// consensusForks returns the fork blocks of the chain, nil if not scheduled.
+// New forks are appended, the index of a fork is part of the digest.
+func (c *ChainConfig) consensusForks() []*big.Int {
+	return []*big.Int{
+		c.HomesteadBlock, c.DAOForkBlock, c.EIP150Block, c.EIP155Block, c.EIP158Block,
+		c.ByzantiumBlock, c.ConstantinopleBlock, c.EWASMBlock,
+		c.BIP1Block, c.BIP2Block, c.BIP3Block, c.BIP4Block, c.BIP5Block,
+	}
+}
+
+// consensusDigest returns the hash of the chain parameters affecting the
+// validity of the blocks at the given head: the chain id, the forks passed and
+// the BSRR parameters short of the local tuning (finality horizon, future block
+// drift, staking list commits and replays).
+func (c *ChainConfig) consensusDigest(head uint64) (h common.Hash) {
+	bsrr := c.Bsrr
+	if bsrr == nil {
+		bsrr = new(BSRRConfig)
+	}
+	params := consensusParams{
+		ChainID:           c.ChainID,
+		DAOForkSupport:    c.DAOForkSupport,
+		Period:            bsrr.Period,
+		Epoch:             bsrr.Epoch,
+		Rewards:           bsrr.Rewards,
+		StakeMinimum:      bsrr.StakeMinimum,
+		LimitStakeBalance: bsrr.LimitStakeBalance,
+		SlashRound:        bsrr.SlashRound,
+		ForkFactor:        math.Float64bits(bsrr.ForkFactor),
+		MaxCandidates:     bsrr.MaxCandidates,
+	}
+	for i, number := range c.consensusForks() {
+		if number != nil && number.Uint64() <= head {
+			params.Forks = append(params.Forks, consensusFork{Index: uint64(i), Number: number.Uint64()})
+		}
+	}
+	hw := sha3.NewKeccak256()
+	rlp.Encode(hw, &params)
+	hw.Sum(h[:0])
+	return h
+}
+
+// consensusIDs returns the consensus identifiers of the chain in block order,
+// one per span of blocks between two forks.
+func (c *ChainConfig) consensusIDs() (ids []ConsensusID, starts []uint64) {
+	var forks []uint64
+	for _, number := range c.consensusForks() {
+		if number != nil && number.Sign() > 0 {
+			forks = append(forks, number.Uint64())
+		}
+	}
+	sort.Slice(forks, func(i, j int) bool { return forks[i] < forks[j] })
+
+	starts = []uint64{0}
+	for _, fork := range forks {
+		if fork != starts[len(starts)-1] {
+			starts = append(starts, fork)
+		}
+	}
+	for i, start := range starts {
+		id := ConsensusID{Digest: c.consensusDigest(start)}
+		if i+1 < len(starts) {
+			id.Next = starts[i+1]
+		}
+		ids = append(ids, id)
+	}
+	return ids, starts
+}
+
+// ConsensusID returns the consensus identifier of the chain at the given head.
+// Nodes with different digests can't agree on a chain, even when they share a
+// genesis block, unless one of them didn't reach a fork of the other yet.
+func (c *ChainConfig) ConsensusID(head uint64) ConsensusID {
+	ids, starts := c.consensusIDs()
+	for i := len(starts) - 1; i > 0; i-- {
+		if head >= starts[i] {
+			return ids[i]
+		}
+	}
+	return ids[0]
+}
+
+// CheckConsensusID returns whether a remote node advertising the given consensus
+// identifier agrees on the chain with the local node at the given head. The
+// nodes differing only by the forks scheduled past both heads agree.
+func (c *ChainConfig) CheckConsensusID(head uint64, remote ConsensusID) error {
+	ids, starts := c.consensusIDs()
+
+	local := 0
+	for local+1 < len(starts) && head >= starts[local+1] {
+		local++
+	}
+	for i, id := range ids {
+		if id.Digest != remote.Digest {
+			continue
+		}
+		switch {
+		case i == local:
+			// Same rules at both heads, the remote's next fork mustn't be passed
+			if remote.Next > 0 && remote.Next <= head {
+				return ErrConsensusLocalIncompatible
+			}
+			return nil
+		case i < local:
+			// The remote is syncing, it has to know the fork that follows
+			if remote.Next != id.Next {
+				return ErrConsensusRemoteStale
+			}
+			return nil
+		default:
+			// The remote passed forks the local node didn't reach yet
+			return nil
+		}
+	}
+	return ErrConsensusLocalIncompatible
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -166,7 +337,7 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v BIP1: %v BIP2: %v BIP3: %v BIP4: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v BIP1: %v BIP2: %v BIP3: %v BIP4: %v BIP5: %v Engine: %v}",
 		c.ChainID,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -180,6 +351,7 @@ func (c *ChainConfig) String() string {
 		c.BIP2Block,
 		c.BIP3Block,
 		c.BIP4Block,
+		c.BIP5Block,
 		engine,
 	)
 }
@@ -235,6 +407,12 @@ func (c *ChainConfig) IsBIP4(num *big.Int) bool {
 	return isForked(c.BIP4Block, num)
 }
 
+// IsBIP5 returns whether num is either equal to the BIP5 fork block or greater.
+// From BIP5 on, selection points of all stakers are recalculated at every epoch boundary.
+func (c *ChainConfig) IsBIP5(num *big.Int) bool {
+	return isForked(c.BIP5Block, num)
+}
+
 func (c *ChainConfig) IsBIP1Block(num *big.Int) bool {
 	if c.BIP1Block == nil || num == nil {
 		return false
@@ -327,6 +505,9 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.BIP4Block, newcfg.BIP4Block, head) {
 		return newCompatError("bip4 fork block", c.BIP4Block, newcfg.BIP4Block)
 	}
+	if isForkIncompatible(c.BIP5Block, newcfg.BIP5Block, head) {
+		return newCompatError("bip5 fork block", c.BIP5Block, newcfg.BIP5Block)
+	}
 	return nil
 }
 
@@ -79,3 +79,99 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the consensus identifier tells apart the parameters affecting the
+// validity of the blocks, but not the local tuning nor the future forks.
+func TestConsensusID(t *testing.T) {
+	config := func(modify func(*ChainConfig)) *ChainConfig {
+		bsrr := *TestnetChainConfig.Bsrr
+		config := *TestnetChainConfig
+		config.Bsrr = &bsrr
+		modify(&config)
+		return &config
+	}
+	base := TestnetChainConfig.ConsensusID(0)
+
+	same := map[string]*ChainConfig{
+		"finality horizon":   config(func(c *ChainConfig) { c.Bsrr.FinalityHorizon = 100 }),
+		"future block drift": config(func(c *ChainConfig) { c.Bsrr.FutureBlockDrift = 10 }),
+		"commit interval":    config(func(c *ChainConfig) { c.Bsrr.CommitEvery = 50 }),
+		"max reorg depth":    config(func(c *ChainConfig) { c.Bsrr.MaxReorgDepth = 1000 }),
+	}
+	for name, c := range same {
+		if c.ConsensusID(0) != base {
+			t.Errorf("%s: identifier changed by local tuning", name)
+		}
+	}
+	different := map[string]*ChainConfig{
+		"epoch":          config(func(c *ChainConfig) { c.Bsrr.Epoch = 360 }),
+		"period":         config(func(c *ChainConfig) { c.Bsrr.Period = 10 }),
+		"fork factor":    config(func(c *ChainConfig) { c.Bsrr.ForkFactor = 0.5 }),
+		"max candidates": config(func(c *ChainConfig) { c.Bsrr.MaxCandidates = 10 }),
+		"genesis fork":   config(func(c *ChainConfig) { c.BIP5Block = big.NewInt(0) }),
+		"chain id":       config(func(c *ChainConfig) { c.ChainID = big.NewInt(1) }),
+	}
+	for name, c := range different {
+		if c.ConsensusID(0).Digest == base.Digest {
+			t.Errorf("%s: digest not changed", name)
+		}
+	}
+	// Future forks are announced, but only change the digest once passed
+	forked := config(func(c *ChainConfig) { c.BIP5Block = big.NewInt(100) })
+	if id := forked.ConsensusID(99); id.Digest != base.Digest || id.Next != 100 {
+		t.Errorf("before the fork: have %x/%d, want %x/100", id.Digest[:8], id.Next, base.Digest[:8])
+	}
+	if id := forked.ConsensusID(100); id.Digest == base.Digest || id.Next != 0 {
+		t.Errorf("after the fork: have %x/%d, want a new digest and no next fork", id.Digest[:8], id.Next)
+	}
+}
+
+// Tests the validation of the consensus identifiers of remote nodes, accepting
+// those differing only by future forks.
+func TestCheckConsensusID(t *testing.T) {
+	config := func(forks ...int64) *ChainConfig {
+		config := *TestnetChainConfig
+		config.BIP1Block, config.BIP2Block = nil, nil
+		if len(forks) > 0 {
+			config.BIP1Block = big.NewInt(forks[0])
+		}
+		if len(forks) > 1 {
+			config.BIP2Block = big.NewInt(forks[1])
+		}
+		return &config
+	}
+	tests := []struct {
+		local, remote *ChainConfig
+		head, rhead   uint64
+		err           error
+	}{
+		// Identical configurations, whatever the heads
+		{config(100, 200), config(100, 200), 0, 0, nil},
+		{config(100, 200), config(100, 200), 150, 150, nil},
+		{config(100, 200), config(100, 200), 250, 50, nil},
+		{config(100, 200), config(100, 200), 50, 250, nil},
+
+		// Future forks unknown to either node, neither passed it yet
+		{config(100), config(100, 200), 150, 150, nil},
+		{config(100, 200), config(100), 150, 150, nil},
+		{config(100, 200), config(100, 300), 150, 150, nil},
+		{config(), config(100), 50, 50, nil},
+
+		// The local node passed a fork the remote one schedules elsewhere
+		{config(100), config(100, 200), 250, 150, ErrConsensusLocalIncompatible},
+		{config(100, 300), config(100, 200), 250, 150, ErrConsensusLocalIncompatible},
+		{config(100, 200), config(100, 300), 250, 150, ErrConsensusRemoteStale},
+
+		// The remote node is ahead, the local one doesn't know its fork yet
+		{config(100), config(100, 200), 150, 250, ErrConsensusLocalIncompatible},
+
+		// The fork passed is different
+		{config(100), config(150), 200, 200, ErrConsensusLocalIncompatible},
+		{config(100), config(), 200, 200, ErrConsensusRemoteStale},
+	}
+	for i, tt := range tests {
+		if err := tt.local.CheckConsensusID(tt.head, tt.remote.ConsensusID(tt.rhead)); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
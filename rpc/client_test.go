@@ -589,3 +589,58 @@ func (l *flakeyListener) Accept() (net.Conn, error) {
 	}
 	return c, err
 }
+
+func TestDialIPCWithTimeoutRetries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on unix socket endpoints")
+	}
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+
+	endpoint := fmt.Sprintf("%s/go-ethereum-test-ipc-%d-%d", os.TempDir(), os.Getpid(), rand.Int63())
+	defer os.Remove(endpoint)
+
+	// A single attempt must fail as long as the socket doesn't exist.
+	if _, err := DialIPC(context.Background(), endpoint); err == nil {
+		t.Fatal("DialIPC succeeded without a listening socket")
+	}
+	// Create the socket only after the retrying dialer has started.
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		l, err := ipcListen(endpoint)
+		if err != nil {
+			panic(err)
+		}
+		go server.ServeListener(l)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := DialIPCWithTimeout(ctx, endpoint, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("retrying dial failed: %v", err)
+	}
+	defer client.Close()
+
+	var resp Result
+	if err := client.Call(&resp, "service_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if resp.String != "hello" {
+		t.Errorf("incorrect result %#v", resp)
+	}
+}
+
+func TestDialIPCWithTimeoutDeadline(t *testing.T) {
+	endpoint := fmt.Sprintf("%s/go-ethereum-test-ipc-%d-%d", os.TempDir(), os.Getpid(), rand.Int63())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := DialIPCWithTimeout(ctx, endpoint, 20*time.Millisecond); err == nil {
+		t.Fatal("retrying dial succeeded without a listening socket")
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("retrying dial gave up before the deadline: %v", elapsed)
+	}
+}
@@ -240,6 +240,7 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// untilEOF and writes the response to w and order the server to process a
 	// single request.
 	ctx := r.Context()
+	ctx = withPeerInfo(ctx, PeerInfo{Transport: TransportHTTP, RemoteAddr: r.RemoteAddr})
 	ctx = context.WithValue(ctx, "remote", r.RemoteAddr)
 	ctx = context.WithValue(ctx, "scheme", r.Proto)
 	ctx = context.WithValue(ctx, "local", r.Host)
@@ -33,7 +33,7 @@ func DialInProc(handler *Server) *Client {
 		p1, p2 := net.Pipe()
 		// node의 inprocHandler가 p1으로 수신되는 코덱에 대한 serveCodec을 수행함
 		// p1가 수신했던 데이터는 p2에서 전송되었음
-		go handler.ServeCodec(NewJSONCodec(p1), OptionMethodInvocation|OptionSubscriptions)
+		go handler.serveCodec(NewJSONCodec(p1), OptionMethodInvocation|OptionSubscriptions, PeerInfo{Transport: TransportInProc})
 		return p2, nil
 	})
 	return c
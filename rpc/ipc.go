@@ -20,11 +20,16 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/BerithFoundation/berith-chain/log"
 	"github.com/BerithFoundation/berith-chain/p2p/netutil"
 )
 
+// defaultIPCRetryInterval is the delay between two connection attempts of the
+// retrying IPC dialer if the caller does not specify one.
+const defaultIPCRetryInterval = 100 * time.Millisecond
+
 // ServeListener accepts connections on l, serving JSON-RPC on them.
 func (srv *Server) ServeListener(l net.Listener) error {
 	fmt.Println("Server.ServeListner () 호출")
@@ -39,7 +44,7 @@ func (srv *Server) ServeListener(l net.Listener) error {
 			return err
 		}
 		log.Trace("IPC accepted connection")
-		go srv.ServeCodec(NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
+		go srv.serveCodec(NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions, PeerInfo{Transport: TransportIPC})
 	}
 }
 
@@ -55,3 +60,40 @@ func DialIPC(ctx context.Context, endpoint string) (*Client, error) {
 		return newIPCConnection(ctx, endpoint)
 	})
 }
+
+// DialIPCWithTimeout creates a new IPC client like DialIPC, but instead of giving
+// up on the first failure it keeps retrying every retryInterval until the
+// connection is established or the context is done. This is useful when client
+// and server are started concurrently and the endpoint may not exist yet.
+//
+// The context bounds the initial connection establishment only; without a
+// deadline or cancellation the dialer retries indefinitely.
+func DialIPCWithTimeout(ctx context.Context, endpoint string, retryInterval time.Duration) (*Client, error) {
+	if retryInterval <= 0 {
+		retryInterval = defaultIPCRetryInterval
+	}
+	return newClient(ctx, func(ctx context.Context) (net.Conn, error) {
+		return newIPCConnectionWithRetry(ctx, endpoint, retryInterval)
+	})
+}
+
+// newIPCConnectionWithRetry repeatedly tries to connect to the given endpoint
+// until it succeeds or the context is done, returning the last dial error in
+// the latter case.
+func newIPCConnectionWithRetry(ctx context.Context, endpoint string, retryInterval time.Duration) (net.Conn, error) {
+	for {
+		conn, err := newIPCConnection(ctx, endpoint)
+		if err == nil {
+			return conn, nil
+		}
+		log.Trace("IPC dial failed, retrying", "endpoint", endpoint, "err", err)
+
+		timer := time.NewTimer(retryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+)
+
+// Transports a request can be served over, as reported by PeerInfo.
+const (
+	TransportIPC    = "ipc"
+	TransportHTTP   = "http"
+	TransportWS     = "ws"
+	TransportInProc = "inproc"
+)
+
+// PeerInfo describes the connection a request is served over, for the methods
+// taking a context to tell where a call comes from.
+type PeerInfo struct {
+	Transport  string // Transport of the connection, empty if unknown
+	RemoteAddr string // Address of the remote end, empty if unknown or local
+	ConnID     uint64 // Identifier of the connection, unique within the server
+}
+
+// Local returns whether the connection can only be opened from the machine of
+// the node, which is the case of IPC and in-process connections.
+func (info PeerInfo) Local() bool {
+	return info.Transport == TransportIPC || info.Transport == TransportInProc
+}
+
+// String returns the remote address of the connection if known, else the
+// transport and identifier of the connection.
+func (info PeerInfo) String() string {
+	if info.RemoteAddr != "" {
+		return info.RemoteAddr
+	}
+	return fmt.Sprintf("%s#%d", info.Transport, info.ConnID)
+}
+
+type peerInfoKey struct{}
+
+// withPeerInfo returns a copy of the context carrying the connection information.
+func withPeerInfo(ctx context.Context, info PeerInfo) context.Context {
+	return context.WithValue(ctx, peerInfoKey{}, info)
+}
+
+// PeerInfoFromContext returns the information of the connection the request of
+// the context is served over, the zero value if the context carries none.
+func PeerInfoFromContext(ctx context.Context) PeerInfo {
+	info, _ := ctx.Value(peerInfoKey{}).(PeerInfo)
+	return info
+}
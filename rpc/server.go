@@ -144,6 +144,11 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	// Identify the connection for the callbacks telling the callers apart
+	info := PeerInfoFromContext(ctx)
+	info.ConnID = atomic.AddUint64(&s.connID, 1)
+	ctx = withPeerInfo(ctx, info)
+
 	// if the codec supports notification include a notifier that callbacks can use
 	// to send notification to clients. It is tied to the codec/connection. If the
 	// connection is closed the notifier will stop and cancels all active subscriptions.
@@ -218,8 +223,14 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 //
 // ServeCode은 코덱으로부터 들어오는 요청을 읽고 적절한 콜백 함수를 호출하여 주어진 코덱을 통해 응답한다.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(codec, options, PeerInfo{})
+}
+
+// serveCodec is ServeCodec telling the callbacks the connection the requests are
+// served over.
+func (s *Server) serveCodec(codec ServerCodec, options CodecOption, info PeerInfo) {
 	defer codec.Close()
-	s.serveRequest(context.Background(), codec, false, options)
+	s.serveRequest(withPeerInfo(context.Background(), info), codec, false, options)
 }
 
 // ServeSingleRequest reads and processes a single RPC request from the given codec. It will not
@@ -448,7 +459,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 		for _, arg := range requests[i].args {
 			log.Warn("readRequest", "Args", arg)
 		}
-		log.Warn("readRequest", "FuncName", requests[i].callb.method.Name, "temp Method Name", requests[i].callb.method)
+		log.Warn("readRequest", "method", r.service+serviceMethodSeparator+r.method)
 	}
 
 	return requests, batch, nil
@@ -23,6 +23,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/BerithFoundation/berith-chain/common"
 	"github.com/BerithFoundation/berith-chain/common/hexutil"
 	mapset "github.com/deckarep/golang-set"
 )
@@ -69,6 +70,8 @@ type subscriptions map[string]*callback  // collection of subscription callbacks
 
 // Server represents a RPC server
 type Server struct {
+	connID uint64 // Identifier of the last connection served (accessed atomically, first for alignment)
+
 	services serviceRegistry
 
 	run      int32
@@ -163,3 +166,52 @@ func (bn *BlockNumber) UnmarshalJSON(data []byte) error {
 func (bn BlockNumber) Int64() int64 {
 	return (int64)(bn)
 }
+
+// BlockNumberOrHash identifies a block either by its number, including the
+// "latest", "earliest" and "pending" tags, or by its hash.
+type BlockNumberOrHash struct {
+	BlockNumber *BlockNumber
+	BlockHash   *common.Hash
+}
+
+// UnmarshalJSON parses the given JSON fragment into a BlockNumberOrHash. A 32
+// byte hex string is taken as a block hash, anything else as a BlockNumber.
+func (bnh *BlockNumberOrHash) UnmarshalJSON(data []byte) error {
+	var hash common.Hash
+	if err := hash.UnmarshalJSON(data); err == nil {
+		bnh.BlockHash = &hash
+		return nil
+	}
+	var number BlockNumber
+	if err := number.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	bnh.BlockNumber = &number
+	return nil
+}
+
+// Number returns the block number, if the block is identified by one.
+func (bnh BlockNumberOrHash) Number() (BlockNumber, bool) {
+	if bnh.BlockNumber != nil {
+		return *bnh.BlockNumber, true
+	}
+	return BlockNumber(0), false
+}
+
+// Hash returns the block hash, if the block is identified by one.
+func (bnh BlockNumberOrHash) Hash() (common.Hash, bool) {
+	if bnh.BlockHash != nil {
+		return *bnh.BlockHash, true
+	}
+	return common.Hash{}, false
+}
+
+// BlockNumberOrHashWithNumber returns a BlockNumberOrHash identifying the block by number.
+func BlockNumberOrHashWithNumber(number BlockNumber) BlockNumberOrHash {
+	return BlockNumberOrHash{BlockNumber: &number}
+}
+
+// BlockNumberOrHashWithHash returns a BlockNumberOrHash identifying the block by hash.
+func BlockNumberOrHashWithHash(hash common.Hash) BlockNumberOrHash {
+	return BlockNumberOrHash{BlockHash: &hash}
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/BerithFoundation/berith-chain/common"
 	"github.com/BerithFoundation/berith-chain/common/math"
 )
 
@@ -64,3 +65,45 @@ func TestBlockNumberJSONUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockNumberOrHashJSONUnmarshal(t *testing.T) {
+	hash := common.HexToHash("0x1234")
+	tests := []struct {
+		input    string
+		mustFail bool
+		expected BlockNumberOrHash
+	}{
+		0: {`"0x1"`, false, BlockNumberOrHashWithNumber(1)},
+		1: {`"latest"`, false, BlockNumberOrHashWithNumber(LatestBlockNumber)},
+		2: {`"` + hash.Hex() + `"`, false, BlockNumberOrHashWithHash(hash)},
+		3: {`"0x12345678"`, false, BlockNumberOrHashWithNumber(0x12345678)},
+		4: {`"0x` + hash.Hex()[3:] + `"`, true, BlockNumberOrHash{}},
+		5: {`"ff"`, true, BlockNumberOrHash{}},
+	}
+
+	for i, test := range tests {
+		var bnh BlockNumberOrHash
+		err := json.Unmarshal([]byte(test.input), &bnh)
+		if test.mustFail && err == nil {
+			t.Errorf("Test %d should fail", i)
+			continue
+		}
+		if !test.mustFail && err != nil {
+			t.Errorf("Test %d should pass but got err: %v", i, err)
+			continue
+		}
+		if test.mustFail {
+			continue
+		}
+		number, isNumber := bnh.Number()
+		wantNumber, wantIsNumber := test.expected.Number()
+		if number != wantNumber || isNumber != wantIsNumber {
+			t.Errorf("Test %d got unexpected number, want %d, got %d", i, wantNumber, number)
+		}
+		hash, isHash := bnh.Hash()
+		wantHash, wantIsHash := test.expected.Hash()
+		if hash != wantHash || isHash != wantIsHash {
+			t.Errorf("Test %d got unexpected hash, want %x, got %x", i, wantHash, hash)
+		}
+	}
+}
@@ -18,6 +18,8 @@ package core
 
 import (
 	"context"
+	"crypto/ecdsa"
+	crand "crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,6 +27,7 @@ import (
 	"math/big"
 	"os"
 	"reflect"
+	"sync"
 
 	"berith-chain/internals/berithapi"
 	"berith-chain/signer/storage"
@@ -35,6 +38,7 @@ import (
 	"github.com/BerithFoundation/berith-chain/accounts/usbwallet"
 	"github.com/BerithFoundation/berith-chain/common"
 	"github.com/BerithFoundation/berith-chain/common/hexutil"
+	"github.com/BerithFoundation/berith-chain/core/types"
 	"github.com/BerithFoundation/berith-chain/crypto"
 	"github.com/BerithFoundation/berith-chain/log"
 	"github.com/BerithFoundation/berith-chain/rlp"
@@ -43,12 +47,20 @@ import (
 // numberOfAccountsToDerive For hardware wallets, the number of accounts to derive
 const numberOfAccountsToDerive = 10
 
+// maxAccountsToDerive is the maximum number of accounts listed by a single
+// DeriveAccounts request.
+const maxAccountsToDerive = 100
+
 // ExternalAPI defines the external API through which signing requests are made.
 type ExternalAPI interface {
 	// List available accounts
 	List(ctx context.Context) ([]common.Address, error)
 	// New request to create a new account
 	New(ctx context.Context) (accounts.Account, error)
+	// NewWithEntropy request to create a new account, mixing in extra entropy
+	NewWithEntropy(ctx context.Context, extraEntropy hexutil.Bytes) (accounts.Account, error)
+	// DeriveAccounts request to derive and list accounts of HD wallets
+	DeriveAccounts(ctx context.Context, pathPrefix string, count int) ([]Account, error)
 	// SignTransaction request to sign the specified transaction
 	SignTransaction(ctx context.Context, args SendTxArgs, methodSelector *string) (*berithapi.SignTransactionResult, error)
 	// Sign - request to sign the given data (plus prefix)
@@ -112,6 +124,9 @@ type SignerAPI struct {
 	validator   Validator
 	rejectMode  bool
 	credentials storage.Storage
+
+	pathsMu sync.RWMutex
+	paths   map[common.Address]accounts.DerivationPath // Derivation paths of accounts derived from HD wallets
 }
 
 // Metadata about a request
@@ -220,6 +235,7 @@ type (
 		Transaction SendTxArgs       `json:"transaction"`
 		Callinfo    []ValidationInfo `json:"call_info"`
 		Meta        Metadata         `json:"meta"`
+		Account     *Account         `json:"account,omitempty"` // Signing account, if known to the signer
 	}
 	// SignTxResponse result from SignTxRequest
 	SignTxResponse struct {
@@ -303,7 +319,15 @@ func NewSignerAPI(am *accounts.Manager, chainID int64, noUSB bool, ui SignerUI,
 	if advancedMode {
 		log.Info("Clef is in advanced mode: will warn instead of reject")
 	}
-	signer := &SignerAPI{big.NewInt(chainID), am, ui, validator, !advancedMode, credentials}
+	signer := &SignerAPI{
+		chainID:     big.NewInt(chainID),
+		am:          am,
+		UI:          ui,
+		validator:   validator,
+		rejectMode:  !advancedMode,
+		credentials: credentials,
+		paths:       make(map[common.Address]accounts.DerivationPath),
+	}
 	if !noUSB {
 		signer.startUSBListener()
 	}
@@ -341,6 +365,16 @@ func (api *SignerAPI) openTrezor(url accounts.URL) {
 
 }
 
+// isHardwareWallet returns whether the wallet is a hardware wallet, which asks
+// the user to confirm requests on the device instead of for a password.
+func isHardwareWallet(wallet accounts.Wallet) bool {
+	switch wallet.URL().Scheme {
+	case usbwallet.LedgerScheme, usbwallet.TrezorScheme:
+		return true
+	}
+	return false
+}
+
 // startUSBListener starts a listener for USB events, for hardware wallet interaction
 func (api *SignerAPI) startUSBListener() {
 	events := make(chan accounts.WalletEvent, 16)
@@ -375,14 +409,15 @@ func (api *SignerAPI) startUSBListener() {
 				if event.Wallet.URL().Scheme == "ledger" {
 					derivationPath = accounts.DefaultLedgerBaseDerivationPath
 				}
-				var nextPath = derivationPath
+				var nextPath = append(accounts.DerivationPath{}, derivationPath...)
 				// Derive first N accounts, hardcoded for now
 				for i := 0; i < numberOfAccountsToDerive; i++ {
 					acc, err := event.Wallet.Derive(nextPath, true)
 					if err != nil {
 						log.Warn("account derivation failed", "error", err)
 					} else {
-						log.Info("derived account", "address", acc.Address)
+						log.Info("derived account", "address", acc.Address, "path", nextPath)
+						api.setPath(acc.Address, nextPath)
 					}
 					nextPath[len(nextPath)-1]++
 				}
@@ -400,8 +435,7 @@ func (api *SignerAPI) List(ctx context.Context) ([]common.Address, error) {
 	var accs []Account
 	for _, wallet := range api.am.Wallets() {
 		for _, acc := range wallet.Accounts() {
-			acc := Account{Typ: "Account", URL: wallet.URL(), Address: acc.Address}
-			accs = append(accs, acc)
+			accs = append(accs, api.account(wallet, acc.Address))
 		}
 	}
 	result, err := api.UI.ApproveListing(&ListRequest{Accounts: accs, Meta: MetadataFromContext(ctx)})
@@ -421,10 +455,137 @@ func (api *SignerAPI) List(ctx context.Context) ([]common.Address, error) {
 	return addresses, nil
 }
 
+// DeriveAccounts derives count accounts from every HD wallet, at the paths made
+// of the given prefix (e.g. m/44'/60'/0'/0, the Berith root path if empty)
+// followed by the indices 0 to count-1, and returns those approved by the user.
+// Only the approved accounts are pinned, so their wallets can sign with them
+// afterwards.
+func (api *SignerAPI) DeriveAccounts(ctx context.Context, pathPrefix string, count int) ([]Account, error) {
+	if count <= 0 || count > maxAccountsToDerive {
+		return nil, fmt.Errorf("invalid number of accounts to derive: %d, expected 1 to %d", count, maxAccountsToDerive)
+	}
+	base := accounts.BerithRootDerivationPath
+	if pathPrefix != "" {
+		var err error
+		if base, err = accounts.ParseDerivationPath(pathPrefix); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		accs    []Account
+		wallets = make(map[common.Address]accounts.Wallet)
+		paths   = make(map[common.Address]accounts.DerivationPath)
+	)
+	for _, wallet := range api.am.Wallets() {
+		for i := 0; i < count; i++ {
+			path := append(append(accounts.DerivationPath{}, base...), uint32(i))
+			acc, err := wallet.Derive(path, false)
+			if err == accounts.ErrNotSupported {
+				break
+			}
+			if err != nil {
+				log.Warn("Account derivation failed", "wallet", wallet.URL(), "path", path, "err", err)
+				break
+			}
+			wallets[acc.Address], paths[acc.Address] = wallet, path
+			accs = append(accs, Account{Typ: "Account", URL: wallet.URL(), Address: acc.Address, Path: path.String()})
+		}
+	}
+	result, err := api.UI.ApproveListing(&ListRequest{Accounts: accs, Meta: MetadataFromContext(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	if result.Accounts == nil {
+		return nil, ErrRequestDenied
+	}
+	// Pin the approved accounts, ignoring any the UI made up
+	var approved []Account
+	for _, acc := range result.Accounts {
+		wallet, ok := wallets[acc.Address]
+		if !ok {
+			continue
+		}
+		if _, err := wallet.Derive(paths[acc.Address], true); err != nil {
+			return nil, err
+		}
+		api.setPath(acc.Address, paths[acc.Address])
+		approved = append(approved, acc)
+	}
+	return approved, nil
+}
+
+// setPath remembers the derivation path the given account was derived at.
+func (api *SignerAPI) setPath(address common.Address, path accounts.DerivationPath) {
+	api.pathsMu.Lock()
+	defer api.pathsMu.Unlock()
+
+	api.paths[address] = append(accounts.DerivationPath{}, path...)
+}
+
+// path returns the derivation path the given account was derived at, if any.
+func (api *SignerAPI) path(address common.Address) (accounts.DerivationPath, bool) {
+	api.pathsMu.RLock()
+	defer api.pathsMu.RUnlock()
+
+	path, ok := api.paths[address]
+	return path, ok
+}
+
+// account assembles the UI representation of an account of the given wallet,
+// including its derivation path if it was derived from an HD wallet.
+func (api *SignerAPI) account(wallet accounts.Wallet, address common.Address) Account {
+	acc := Account{Typ: "Account", URL: wallet.URL(), Address: address}
+	if path, ok := api.path(address); ok {
+		acc.Path = path.String()
+	}
+	return acc
+}
+
 // New creates a new password protected Account. The private key is protected with
 // the given password. Users are responsible to backup the private key that is stored
 // in the keystore location thas was specified when this API was created.
 func (api *SignerAPI) New(ctx context.Context) (accounts.Account, error) {
+	return api.newAccount(ctx, func(ks *keystore.KeyStore, password string) (accounts.Account, error) {
+		return ks.NewAccount(password)
+	})
+}
+
+// NewWithEntropy creates a new password protected Account like New, generating
+// its key from system randomness mixed with the given extra entropy. As the
+// system randomness is kept, the same entropy yields different accounts. It
+// fails if an account of the generated address already exists.
+func (api *SignerAPI) NewWithEntropy(ctx context.Context, extraEntropy hexutil.Bytes) (accounts.Account, error) {
+	return api.newAccount(ctx, func(ks *keystore.KeyStore, password string) (accounts.Account, error) {
+		key, err := entropyKey(extraEntropy)
+		if err != nil {
+			return accounts.Account{}, err
+		}
+		address := crypto.PubkeyToAddress(key.PublicKey)
+		if _, err := api.am.Find(accounts.Account{Address: address}); err == nil {
+			return accounts.Account{}, fmt.Errorf("account %s already exists", address.Hex())
+		}
+		return ks.ImportECDSA(key, password)
+	})
+}
+
+// entropyKey generates a private key from 32 bytes of system randomness hashed
+// together with the extra entropy, which therefore can't weaken the key.
+func entropyKey(extraEntropy []byte) (*ecdsa.PrivateKey, error) {
+	seed := make([]byte, 32)
+	for {
+		if _, err := crand.Read(seed); err != nil {
+			return nil, err
+		}
+		// Retry in the unlikely case the hash is not a valid key
+		if key, err := crypto.ToECDSA(crypto.Keccak256(seed, extraEntropy)); err == nil {
+			return key, nil
+		}
+	}
+}
+
+// newAccount asks the user to approve the creation of an account and for its
+// password, and creates the account in the keystore with create.
+func (api *SignerAPI) newAccount(ctx context.Context, create func(ks *keystore.KeyStore, password string) (accounts.Account, error)) (accounts.Account, error) {
 	be := api.am.Backends(keystore.KeyStoreType)
 	if len(be) == 0 {
 		return accounts.Account{}, errors.New("password based accounts not supported")
@@ -446,7 +607,7 @@ func (api *SignerAPI) New(ctx context.Context) (accounts.Account, error) {
 			api.UI.ShowError(fmt.Sprintf("Account creation attempt #%d failed due to password requirements: %v", (i + 1), pwErr))
 		} else {
 			// No error
-			return be[0].(*keystore.KeyStore).NewAccount(resp.Password)
+			return create(be[0].(*keystore.KeyStore), resp.Password)
 		}
 	}
 	// Otherwise fail, with generic error message
@@ -545,6 +706,10 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs, meth
 		Meta:        MetadataFromContext(ctx),
 		Callinfo:    msgs.Messages,
 	}
+	if wallet, err := api.am.Find(accounts.Account{Address: args.From.Address()}); err == nil {
+		acc := api.account(wallet, args.From.Address())
+		req.Account = &acc
+	}
 	// Process approval
 	result, err = api.UI.ApproveTx(&req)
 	if err != nil {
@@ -565,16 +730,29 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs, meth
 		return nil, err
 	}
 	// Convert fields into a real transaction
-	var unsignedTx = result.Transaction.toTransaction()
-	// Get the password for the transaction
-	pw, err := api.lookupOrQueryPassword(acc.Address, "Account password",
-		fmt.Sprintf("Please enter the password for account %s", acc.Address.String()))
-	if err != nil {
-		return nil, err
+	var (
+		unsignedTx = result.Transaction.toTransaction()
+		signedTx   *types.Transaction
+	)
+	hardware := isHardwareWallet(wallet)
+	if hardware {
+		api.UI.ShowInfo(fmt.Sprintf("Please confirm the transaction on your %s device", wallet.URL().Scheme))
+	}
+	if _, derived := api.path(acc.Address); derived || hardware {
+		// Accounts derived from HD wallets are pinned and signed for by the wallet
+		// with the child key of their path, without any password
+		signedTx, err = wallet.SignTx(acc, unsignedTx, api.chainID)
+	} else {
+		// Get the password for the transaction
+		var pw string
+		pw, err = api.lookupOrQueryPassword(acc.Address, "Account password",
+			fmt.Sprintf("Please enter the password for account %s", acc.Address.String()))
+		if err != nil {
+			return nil, err
+		}
+		// The one to sign is the one that was returned from the UI
+		signedTx, err = wallet.SignTxWithPassphrase(acc, pw, unsignedTx, api.chainID)
 	}
-
-	// The one to sign is the one that was returned from the UI
-	signedTx, err := wallet.SignTxWithPassphrase(acc, pw, unsignedTx, api.chainID)
 	if err != nil {
 		api.UI.ShowError(err.Error())
 		return nil, err
@@ -618,6 +796,11 @@ func (api *SignerAPI) Sign(ctx context.Context, addr common.MixedcaseAddress, da
 	if err != nil {
 		return nil, err
 	}
+	// Hardware wallets can't sign arbitrary data, don't prompt for the device
+	if isHardwareWallet(wallet) {
+		api.UI.ShowError(fmt.Sprintf("Signing data is not supported by %s devices", wallet.URL().Scheme))
+		return nil, accounts.ErrNotSupported
+	}
 	// Assemble sign the data with the wallet
 	signature, err := wallet.SignHashWithPassphrase(account, res.Password, sighash)
 	if err != nil {
@@ -18,6 +18,7 @@ package core
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -29,11 +30,15 @@ import (
 
 	"berith-chain/internals/berithapi"
 
+	ethereum "github.com/BerithFoundation/berith-chain"
 	"github.com/BerithFoundation/berith-chain/accounts"
 	"github.com/BerithFoundation/berith-chain/accounts/keystore"
+	"github.com/BerithFoundation/berith-chain/accounts/usbwallet"
 	"github.com/BerithFoundation/berith-chain/common"
 	"github.com/BerithFoundation/berith-chain/common/hexutil"
 	"github.com/BerithFoundation/berith-chain/core/types"
+	"github.com/BerithFoundation/berith-chain/crypto"
+	"github.com/BerithFoundation/berith-chain/event"
 	"github.com/BerithFoundation/berith-chain/rlp"
 	"github.com/BerithFoundation/berith-chain/signer/storage"
 )
@@ -238,6 +243,41 @@ func TestNewAcc(t *testing.T) {
 	}
 }
 
+func TestNewAccWithEntropy(t *testing.T) {
+	api, control := setup(t)
+	ks := api.am.Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	// The same entropy must not yield the same account twice
+	entropy := hexutil.Bytes("not so random")
+	var created []common.Address
+	for i := 0; i < 2; i++ {
+		control.approveCh <- "Y"
+		control.approveCh <- "a_long_password"
+		acc, err := api.NewWithEntropy(context.Background(), entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if acc.Address == (common.Address{}) {
+			t.Fatalf("account %d: no address reported", i)
+		}
+		if !ks.HasAddress(acc.Address) {
+			t.Errorf("account %d: %x not in keystore", i, acc.Address)
+		}
+		created = append(created, acc.Address)
+	}
+	if created[0] == created[1] {
+		t.Errorf("same account created twice: %x", created[0])
+	}
+	// Denied requests must not create an account
+	control.approveCh <- "N"
+	if _, err := api.NewWithEntropy(context.Background(), entropy); err != ErrRequestDenied {
+		t.Errorf("expected ErrRequestDenied, got %v", err)
+	}
+	if have := len(ks.Accounts()); have != 2 {
+		t.Errorf("account count mismatch: have %d, want 2", have)
+	}
+}
+
 func TestSignData(t *testing.T) {
 	api, control := setup(t)
 	//Create two accounts
@@ -404,3 +444,310 @@ func TestAsyncronousResponses(t *testing.T){
 
 	}
 */
+
+// testHDWallet is a software wallet deterministically deriving its keys from a
+// seed and the derivation path, standing in for a hardware wallet.
+type testHDWallet struct {
+	seed []byte
+	keys map[common.Address]*ecdsa.PrivateKey
+	accs []accounts.Account
+}
+
+func newTestHDWallet(seed string) *testHDWallet {
+	return &testHDWallet{seed: []byte(seed), keys: make(map[common.Address]*ecdsa.PrivateKey)}
+}
+
+func (w *testHDWallet) Wallets() []accounts.Wallet { return []accounts.Wallet{w} }
+func (w *testHDWallet) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+func (w *testHDWallet) URL() accounts.URL            { return accounts.URL{Scheme: "hd", Path: string(w.seed)} }
+func (w *testHDWallet) Status() (string, error)      { return "ok", nil }
+func (w *testHDWallet) Open(passphrase string) error { return nil }
+func (w *testHDWallet) Close() error                 { return nil }
+func (w *testHDWallet) Accounts() []accounts.Account { return w.accs }
+func (w *testHDWallet) Contains(acc accounts.Account) bool {
+	_, ok := w.keys[acc.Address]
+	return ok
+}
+
+func (w *testHDWallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	key, err := crypto.ToECDSA(crypto.Keccak256(w.seed, []byte(path.String())))
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	acc := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey), URL: w.URL()}
+	if _, ok := w.keys[acc.Address]; !ok && pin {
+		w.keys[acc.Address] = key
+		w.accs = append(w.accs, acc)
+	}
+	return acc, nil
+}
+
+func (w *testHDWallet) SelfDerive(base accounts.DerivationPath, chain ethereum.ChainStateReader) {}
+
+func (w *testHDWallet) SignHash(acc accounts.Account, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+func (w *testHDWallet) SignTx(acc accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, ok := w.keys[acc.Address]
+	if !ok {
+		return nil, accounts.ErrUnknownAccount
+	}
+	return types.SignTx(tx, types.NewEIP155Signer(chainID), key)
+}
+
+func (w *testHDWallet) SignHashWithPassphrase(acc accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+func (w *testHDWallet) SignTxWithPassphrase(acc accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+func setupHD(t *testing.T, seed string) (*SignerAPI, *headlessUi) {
+	db, err := NewFourbytes()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ui := &headlessUi{make(chan string, 20), make(chan string, 20)}
+	am := accounts.NewManager(&accounts.Config{InsecureUnlockAllowed: false}, newTestHDWallet(seed))
+	api := NewSignerAPI(am, 1337, true, ui, db, true, &storage.NoStorage{})
+	return api, ui
+}
+
+func TestDeriveAccounts(t *testing.T) {
+	api, ui := setupHD(t, "test seed")
+
+	// Invalid requests must be rejected before bothering the user
+	if _, err := api.DeriveAccounts(context.Background(), "m/44'/60'/0'/0", 0); err == nil {
+		t.Errorf("expected error for zero accounts")
+	}
+	if _, err := api.DeriveAccounts(context.Background(), "m/44'/60'/0'/0", maxAccountsToDerive+1); err == nil {
+		t.Errorf("expected error for too many accounts")
+	}
+	if _, err := api.DeriveAccounts(context.Background(), "m/44'/foo", 1); err == nil {
+		t.Errorf("expected error for invalid path")
+	}
+	// Denied listings must not leak nor pin any accounts
+	wallet := api.am.Wallets()[0]
+	ui.approveCh <- "N"
+	if _, err := api.DeriveAccounts(context.Background(), "m/44'/60'/0'/0", 3); err != ErrRequestDenied {
+		t.Errorf("expected ErrRequestDenied, got %v", err)
+	}
+	if pinned := wallet.Accounts(); len(pinned) != 0 {
+		t.Errorf("expected no pinned accounts after denial, got %d", len(pinned))
+	}
+	// Partially approved listings must only pin the approved accounts
+	ui.approveCh <- "1"
+	partial, err := api.DeriveAccounts(context.Background(), "m/44'/60'/0'/0", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pinned := wallet.Accounts(); len(pinned) != 1 || len(partial) != 1 || pinned[0].Address != partial[0].Address {
+		t.Errorf("expected only the approved account pinned, got %v", pinned)
+	}
+	// Derivation must be deterministic and carry the paths
+	ui.approveCh <- "A"
+	first, err := api.DeriveAccounts(context.Background(), "m/44'/60'/0'/0", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ui.approveCh <- "A"
+	second, err := api.DeriveAccounts(context.Background(), "m/44'/60'/0'/0", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 3 || len(second) != 3 {
+		t.Fatalf("expected 3 accounts, got %d and %d", len(first), len(second))
+	}
+	for i := range first {
+		if first[i].Address != second[i].Address {
+			t.Errorf("account %d: address mismatch: %x != %x", i, first[i].Address, second[i].Address)
+		}
+		if want := fmt.Sprintf("m/44'/60'/0'/0/%d", i); first[i].Path != want {
+			t.Errorf("account %d: path mismatch: have %s, want %s", i, first[i].Path, want)
+		}
+	}
+	// The Berith coin type, used by default, must derive different accounts
+	ui.approveCh <- "A"
+	other, err := api.DeriveAccounts(context.Background(), "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other[0].Address == first[0].Address {
+		t.Errorf("expected different account for different coin type")
+	}
+	if want := accounts.BerithRootDerivationPath.String() + "/0"; other[0].Path != want {
+		t.Errorf("path mismatch: have %s, want %s", other[0].Path, want)
+	}
+	// Listing must include the derivation paths of the pinned accounts
+	ui.approveCh <- "A"
+	listed, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 4 {
+		t.Fatalf("expected 4 listed accounts, got %d", len(listed))
+	}
+}
+
+func TestSignTxDerived(t *testing.T) {
+	api, ui := setupHD(t, "test seed")
+
+	ui.approveCh <- "A"
+	accs, err := api.DeriveAccounts(context.Background(), "m/44'/60'/0'/0", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := accs[1].Address
+	// No password is needed to sign with derived accounts
+	ui.approveCh <- "Y"
+	res, err := api.SignTransaction(context.Background(), mkTestTx(common.NewMixedcaseAddress(from)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx types.Transaction
+	if err := rlp.DecodeBytes(res.Raw, &tx); err != nil {
+		t.Fatal(err)
+	}
+	sender, err := types.Sender(types.NewEIP155Signer(big.NewInt(1337)), &tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender != from {
+		t.Errorf("sender mismatch: have %x, want %x", sender, from)
+	}
+}
+
+// testDeviceWallet is a testHDWallet posing as a hardware wallet, counting the
+// requests sent to the device.
+type testDeviceWallet struct {
+	*testHDWallet
+	requests int
+}
+
+func (w *testDeviceWallet) Wallets() []accounts.Wallet { return []accounts.Wallet{w} }
+func (w *testDeviceWallet) URL() accounts.URL {
+	return accounts.URL{Scheme: usbwallet.LedgerScheme, Path: string(w.seed)}
+}
+
+// SignHash fails like the usbwallet ones, as devices can't sign arbitrary data.
+func (w *testDeviceWallet) SignHash(acc accounts.Account, hash []byte) ([]byte, error) {
+	w.requests++
+	return nil, accounts.ErrNotSupported
+}
+
+func (w *testDeviceWallet) SignTx(acc accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	w.requests++
+	return w.testHDWallet.SignTx(acc, tx, chainID)
+}
+
+// deviceUi is a headlessUi recording the info messages shown to the user.
+type deviceUi struct {
+	*headlessUi
+	infos []string
+}
+
+func (ui *deviceUi) ShowInfo(message string) {
+	ui.infos = append(ui.infos, message)
+}
+
+func TestSignHardware(t *testing.T) {
+	db, err := NewFourbytes()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ui := &deviceUi{headlessUi: &headlessUi{make(chan string, 20), make(chan string, 20)}}
+	wallet := &testDeviceWallet{testHDWallet: newTestHDWallet("test seed")}
+	am := accounts.NewManager(&accounts.Config{InsecureUnlockAllowed: false}, wallet)
+	api := NewSignerAPI(am, 1337, true, ui, db, true, &storage.NoStorage{})
+
+	// Accounts of hardware wallets are signed for by the device without a
+	// password, even if not derived through the signer
+	acc, err := wallet.Derive(accounts.DefaultBaseDerivationPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ui.approveCh <- "Y"
+	res, err := api.SignTransaction(context.Background(), mkTestTx(common.NewMixedcaseAddress(acc.Address)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx types.Transaction
+	if err := rlp.DecodeBytes(res.Raw, &tx); err != nil {
+		t.Fatal(err)
+	}
+	sender, err := types.Sender(types.NewEIP155Signer(big.NewInt(1337)), &tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender != acc.Address {
+		t.Errorf("sender mismatch: have %x, want %x", sender, acc.Address)
+	}
+	// Data can't be signed by the device, the request must fail without bothering it
+	ui.approveCh <- "Y"
+	ui.approveCh <- ""
+	if _, err := api.Sign(context.Background(), common.NewMixedcaseAddress(acc.Address), hexutil.Bytes("hello")); err != accounts.ErrNotSupported {
+		t.Errorf("expected ErrNotSupported for data signing, got %v", err)
+	}
+	if wallet.requests != 1 {
+		t.Errorf("device request count mismatch: have %d, want 1", wallet.requests)
+	}
+	if len(ui.infos) != 1 {
+		t.Errorf("device prompt count mismatch: have %d, want 1: %q", len(ui.infos), ui.infos)
+	}
+}
+
+// Tests that a signer using the encrypted storage finds the values stored by a
+// previous instance, and that the storage backend is validated.
+func TestEncryptedCredentials(t *testing.T) {
+	dir := tmpDirName(t)
+	defer os.RemoveAll(dir)
+
+	seed := bytes.Repeat([]byte{0x42}, storage.MinMasterSeedLength)
+	config := storage.Config{Backend: storage.BackendEncrypted, Path: filepath.Join(dir, "credentials.json"), MasterSeed: seed}
+	newSigner := func(config storage.Config) *SignerAPI {
+		credentials, err := storage.New(config)
+		if err != nil {
+			t.Fatalf("failed to create storage: %v", err)
+		}
+		db, err := NewFourbytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ui := &headlessUi{make(chan string, 20), make(chan string, 20)}
+		return NewSignerAPI(StartClefAccountManager(dir, true, true, ""), 1337, true, ui, db, true, credentials)
+	}
+	address := common.HexToAddress("0x0123456789abcdef0123456789abcdef01234567")
+	newSigner(config).credentials.Put(address.Hex(), "a_long_password")
+
+	// A restarted signer finds the password, one with another seed doesn't
+	if password, err := newSigner(config).lookupPassword(address); err != nil || password != "a_long_password" {
+		t.Errorf("password not restored: have %q, error %v", password, err)
+	}
+	other := config
+	other.MasterSeed = bytes.Repeat([]byte{0x43}, storage.MinMasterSeedLength)
+	if password, err := newSigner(other).lookupPassword(address); err == nil {
+		t.Errorf("password decrypted with another seed: %q", password)
+	}
+	for _, config := range []storage.Config{
+		{Backend: storage.BackendEncrypted, Path: config.Path, MasterSeed: seed[:storage.MinMasterSeedLength-1]},
+		{Backend: storage.BackendEncrypted, MasterSeed: seed},
+		{Backend: "plaintext"},
+	} {
+		if _, err := storage.New(config); err == nil {
+			t.Errorf("invalid storage config accepted: backend %q, path %q, seed of %d bytes", config.Backend, config.Path, len(config.MasterSeed))
+		}
+	}
+	if credentials, err := storage.New(storage.Config{}); err != nil {
+		t.Errorf("failed to create default storage: %v", err)
+	} else if _, ok := credentials.(*storage.NoStorage); !ok {
+		t.Errorf("default storage mismatch: have %T, want *storage.NoStorage", credentials)
+	}
+}
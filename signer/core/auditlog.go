@@ -45,6 +45,22 @@ func (l *AuditLogger) New(ctx context.Context) (accounts.Account, error) {
 	return l.api.New(ctx)
 }
 
+func (l *AuditLogger) NewWithEntropy(ctx context.Context, extraEntropy hexutil.Bytes) (accounts.Account, error) {
+	l.log.Info("NewWithEntropy", "type", "request", "metadata", MetadataFromContext(ctx).String(),
+		"entropy", len(extraEntropy))
+	res, e := l.api.NewWithEntropy(ctx, extraEntropy)
+	l.log.Info("NewWithEntropy", "type", "response", "address", res.Address, "error", e)
+	return res, e
+}
+
+func (l *AuditLogger) DeriveAccounts(ctx context.Context, pathPrefix string, count int) ([]Account, error) {
+	l.log.Info("DeriveAccounts", "type", "request", "metadata", MetadataFromContext(ctx).String(),
+		"prefix", pathPrefix, "count", count)
+	res, e := l.api.DeriveAccounts(ctx, pathPrefix, count)
+	l.log.Info("DeriveAccounts", "type", "response", "data", res, "error", e)
+	return res, e
+}
+
 func (l *AuditLogger) SignTransaction(ctx context.Context, args SendTxArgs, methodSelector *string) (*berithapi.SignTransactionResult, error) {
 	sel := "<nil>"
 	if methodSelector != nil {
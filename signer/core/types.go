@@ -43,6 +43,7 @@ type Account struct {
 	Typ     string         `json:"type"`
 	URL     accounts.URL   `json:"url"`
 	Address common.Address `json:"address"`
+	Path    string         `json:"path,omitempty"` // Derivation path of accounts from HD wallets
 }
 
 func (a Account) String() string {
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/BerithFoundation/berith-chain/crypto"
+)
+
+const (
+	// BackendNone selects the storage that doesn't remember anything.
+	BackendNone = "none"
+
+	// BackendEncrypted selects the AES-encrypted storage backed by a file.
+	BackendEncrypted = "encrypted"
+
+	// MinMasterSeedLength is the minimum length in bytes of the master seed the
+	// key of the encrypted storage is derived from.
+	MinMasterSeedLength = 256
+)
+
+// Config selects the storage backend of the signer.
+type Config struct {
+	Backend    string // Storage backend, BackendNone if empty
+	Path       string // File holding the values of the encrypted storage
+	MasterSeed []byte // Secret the key of the encrypted storage is derived from
+}
+
+// New creates the storage backend selected by the configuration.
+func New(config Config) (Storage, error) {
+	switch config.Backend {
+	case "", BackendNone:
+		return &NoStorage{}, nil
+
+	case BackendEncrypted:
+		if config.Path == "" {
+			return nil, errors.New("encrypted storage requires a file path")
+		}
+		if len(config.MasterSeed) < MinMasterSeedLength {
+			return nil, fmt.Errorf("master seed of insufficient length, expected at least %d bytes, got %d", MinMasterSeedLength, len(config.MasterSeed))
+		}
+		// Derive the key from the seed, so that the seed is never used as the key
+		key := crypto.Keccak256([]byte("credentials"), config.MasterSeed)
+		return NewAESEncryptedStorage(config.Path, key), nil
+
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", config.Backend)
+	}
+}